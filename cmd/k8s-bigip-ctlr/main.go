@@ -21,12 +21,14 @@ import (
 	"encoding/json"
 	"fmt"
 	configclient "github.com/openshift/client-go/config/clientset/versioned/typed/config/v1"
+	"net"
 	"net/http"
 
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
 
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/controller"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/health"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/metrics"
 	bigIPPrometheus "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/prometheus"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/writer"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -40,6 +42,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -115,14 +118,49 @@ var (
 	gtmBigIPFlags *pflag.FlagSet
 
 	// Custom Resource
-	customResourceMode *bool
-	controllerMode     *string
-	defaultRouteDomain *int
+	customResourceMode    *bool
+	controllerMode        *string
+	defaultRouteDomain    *int
+	namespaceRouteDomains *[]string
+	enableRollback        *bool
+	rollbackHistoryDepth  *int
+	checkpointCM          *string
+	allowedVSCIDRs        *string
+	shutdownTimeout       *time.Duration
+	resyncInterval        *time.Duration
+	partitionDefaultsCM   *string
+	protectPartitions     *string
+	minPartitionsPerPost  *int
+	validateBigIPCiphers  *bool
+	cipherRefreshInterval *time.Duration
+	syncBigIPNodeState    *bool
+	ipamLabelRefresh      *time.Duration
+	ipamLabelNamespaces   *[]string
+	controllerIdentifier  *string
+	quotaCM               *string
+	federationCM          *string
+	as3AppLabel           *string
+	autoScaleMonitor      *bool
+	monitorPoolSizeBase   *int
+	certValidationTimeout *time.Duration
+	skipCertHostCheck     *bool
+	respectNetPolicies    *bool
+	bigipPodCIDR          *string
+	retryWarnThreshold    *int
+	maxRetries            *int
+	dryRun                *bool
+	certReconcileInterval *time.Duration
+	metricsPort           *int
+
+	admissionWebhookAddress *string
+	admissionWebhookCert    *string
+	admissionWebhookKey     *string
 
 	pythonBaseDir    *string
 	logLevel         *string
 	ccclLogLevel     *string
 	logFile          *string
+	logFormat        *string
 	verifyInterval   *int
 	nodePollInterval *int
 	syncInterval     *int
@@ -133,7 +171,11 @@ var (
 	enableIPV6       *bool
 
 	namespaces             *[]string
+	excludeNamespaces      *string
 	useNodeInternal        *bool
+	excludeTaintedNodes    *bool
+	topologyAwareRouting   *bool
+	priorityGroupLabel     *string
 	poolMemberType         *string
 	inCluster              *bool
 	kubeConfig             *string
@@ -164,6 +206,26 @@ var (
 	ciphers                   *string
 	trustedCerts              *string
 	as3PostDelay              *int
+	as3SchemaVersion          *string
+	autoNegotiateAS3Version   *bool
+	encryptAS3Secrets         *bool
+	postRateLimit             *float64
+	postBufferDepth           *int
+	bigIPResourceLabels       *map[string]string
+	bigIPCredentialNSMap      *[]string
+	as3PostTimeout            *time.Duration
+	as3ConnectTimeout         *time.Duration
+	as3ReadTimeout            *time.Duration
+	as3PostRetries            *int
+	as3MaxBackoff             *time.Duration
+	compressAS3               *bool
+	compressAS3Threshold      *int
+	as3DeclSizeWarnBytes      *int
+	as3DeclSizeLimitBytes     *int
+	selfHealOnPostError       *bool
+	bigIPClientCert           *string
+	bigIPClientKey            *string
+	bigIPCACert               *string
 
 	trustedCertsCfgmap     *string
 	agent                  *string
@@ -172,6 +234,7 @@ var (
 	shareNodes             *bool
 	overriderAS3CfgmapName *string
 	filterTenants          *bool
+	grpcMonitorScriptPath  *string
 
 	vxlanMode        string
 	openshiftSDNName *string
@@ -231,6 +294,8 @@ func _init() {
 		"Optional, logging level for cccl")
 	logFile = globalFlags.String("log-file", "",
 		"Optional, filepath to store the CIS logs")
+	logFormat = globalFlags.String("log-format", "text",
+		"Optional, format of the CIS logs, one of text or json")
 	verifyInterval = globalFlags.Int("verify-interval", 30,
 		"Optional, interval (in seconds) at which to verify the BIG-IP configuration.")
 	nodePollInterval = globalFlags.Int("node-poll-interval", 30,
@@ -252,6 +317,94 @@ func _init() {
 		"Optional, to put the controller to process desired resources.")
 	defaultRouteDomain = globalFlags.Int("default-route-domain", 0,
 		"Optional, CIS uses this value as default Route Domain in BIG-IP ")
+	namespaceRouteDomains = globalFlags.StringSlice("namespace-route-domain-map", []string{},
+		"Optional, comma separated namespace:routeDomainId mapping namespaces to the BIG-IP Route Domains "+
+			"of their VirtualServers, TransportServers and pool members.")
+	enableRollback = globalFlags.Bool("enable-rollback", false,
+		"Optional, when set to true, VirtualServers can be rolled back to an earlier declaration "+
+			"using the annotation cis.f5.com/rollback-to")
+	rollbackHistoryDepth = globalFlags.Int("rollback-history-depth", controller.DefaultRollbackHistoryDepth,
+		"Optional, number of declarations retained for rollback")
+	checkpointCM = globalFlags.String("checkpoint-configmap", "",
+		"Optional, <namespace>/<configmap-name> of the ConfigMap persisting the declarations retained "+
+			"for rollback, required with --enable-rollback")
+	allowedVSCIDRs = globalFlags.String("allowed-virtual-server-cidrs", "",
+		"Optional, comma separated CIDRs from which virtual server addresses are allowed")
+	shutdownTimeout = globalFlags.Duration("shutdown-timeout", controller.DefaultShutdownTimeout,
+		"Optional, time to wait for pending resource updates to be posted to BIG-IP before exiting")
+	resyncInterval = globalFlags.Duration("resync-interval", 0,
+		"Optional, interval to re-post the configuration of all partitions to BIG-IP to revert the changes "+
+			"made outside CIS, disabled by default")
+	partitionDefaultsCM = globalFlags.String("partition-defaults-configmap", "",
+		"Optional, namespace/name of the ConfigMap with the defaults of the BIG-IP partitions, "+
+			"partitions not existing on BIG-IP are created with these defaults on startup")
+	protectPartitions = globalFlags.String("protect-partitions", "",
+		"Optional, comma separated BIG-IP partitions whose virtuals are not deleted by CIS, "+
+			"a warning event is recorded instead")
+	minPartitionsPerPost = globalFlags.Int("min-partitions-per-post", 0,
+		"Optional, minimum number of partitions with content required to post the configuration to BIG-IP, "+
+			"disabled by default")
+	validateBigIPCiphers = globalFlags.Bool("validate-bigip-ciphers", false,
+		"Optional, when set to true, ciphers and cipher groups of TLSProfiles are validated against "+
+			"the ones of client SSL profiles on BIG-IP")
+	cipherRefreshInterval = globalFlags.Duration("cipher-refresh-interval", controller.DefaultCipherRefreshInterval,
+		"Optional, interval to refresh the ciphers of BIG-IP used by validate-bigip-ciphers")
+	syncBigIPNodeState = globalFlags.Bool("sync-bigip-node-state", false,
+		"Optional, when set to true, states of the BIG-IP pool members are set as the "+
+			"f5.io/bigip-member-health condition of their pods in cluster mode")
+	ipamLabelRefresh = globalFlags.Duration("ipam-label-refresh-interval", controller.DefaultIPAMLabelRefreshInterval,
+		"Optional, interval to refresh the IPAM labels of IPAM CR, labels not served by the IPAM controller are rejected")
+	ipamLabelNamespaces = globalFlags.StringSlice("ipam-label-namespace-map", []string{},
+		"Optional, comma separated namespace:ipamLabel mapping the IPAM labels to the namespaces of "+
+			"the IPAM CRs served by separate IPAM controllers, other labels use the IPAM CR of kube-system.")
+	controllerIdentifier = globalFlags.String("controller-identifier", "",
+		"Optional, identifier of the controller added to the route admit statuses and events, "+
+			"required to distinguish multiple controllers sharing the same cluster")
+	quotaCM = globalFlags.String("quota-configmap", "",
+		"Optional, namespace/name of the ConfigMap with the VirtualServer and TransportServer quotas of namespaces")
+	federationCM = globalFlags.String("ingresslink-federation-configmap", "",
+		"Optional, namespace/name of the ConfigMap where the CIS instances of multiple clusters share "+
+			"the GTM pool members of IngressLinks with clusterMember")
+	as3AppLabel = globalFlags.String("as3-application-label", "",
+		"Optional, label of VirtualServers whose value is the AS3 Application of their virtual servers "+
+			"within the partition, VirtualServers without the label are in the Shared application")
+	autoScaleMonitor = globalFlags.Bool("auto-scale-monitor-interval", false,
+		"Optional, scale the monitor interval of VirtualServer pools with the pool size, by a multiple "+
+			"for each --monitor-interval-pool-size-base pool members")
+	monitorPoolSizeBase = globalFlags.Int("monitor-interval-pool-size-base", controller.DefaultMonitorIntervalPoolSizeBase,
+		"Optional, pool size per multiple of the monitor interval with --auto-scale-monitor-interval")
+	certValidationTimeout = globalFlags.Duration("cert-validation-timeout", controller.DefaultCertValidationTimeout,
+		"Optional, timeout to validate the hostname of certificates, certificates not validated in time are rejected")
+	skipCertHostCheck = globalFlags.Bool("skip-cert-hostname-validation", false,
+		"Optional, when set to true, hostname of certificates is not validated against the host of resources, "+
+			"used with self-signed certificates where the hostname mismatch is acceptable")
+	respectNetPolicies = globalFlags.Bool("respect-network-policies", false,
+		"Optional, when set to true, pool members whose pods are not allowed by the NetworkPolicies to receive "+
+			"ingress traffic from --bigip-pod-cidr are disabled until the policies allow it")
+	bigipPodCIDR = globalFlags.String("bigip-pod-cidr", "",
+		"Optional, CIDR of the BIG-IP addresses sending traffic to pods, required with --respect-network-policies")
+	retryWarnThreshold = globalFlags.Int("resource-retry-warning-threshold", controller.DefaultRetryWarningThreshold,
+		"Optional, number of retries of a resource failed with a retryable error from which warnings are logged "+
+			"and recorded as events on the resource, 0 disables the warnings")
+	maxRetries = globalFlags.Int("resource-max-retries", controller.DefaultMaxRetries,
+		"Optional, number of retries of a resource failed with a retryable error after which it is dropped "+
+			"until its next update, 0 retries it indefinitely")
+	dryRun = globalFlags.Bool("dry-run", false,
+		"Optional, when set to true, VirtualServers are processed without posting the configuration to BIG-IP, "+
+			"the names of their BIG-IP objects are logged and recorded as events on the VirtualServers")
+	certReconcileInterval = globalFlags.Duration("cert-reconcile-interval", controller.DefaultCertReconcileInterval,
+		"Optional, interval to reconcile the secrets of TLSProfiles with the processed ones, the virtuals of "+
+			"secrets rotated without their events being processed are reprocessed, 0 disables it")
+	metricsPort = globalFlags.Int("metrics-port", metrics.DefaultMetricsPort,
+		"Optional, port to serve the Prometheus metrics at /metrics, the metrics are served by "+
+			"--http-listen-address when it listens on the same port")
+	admissionWebhookAddress = globalFlags.String("admission-webhook-address", "",
+		"Optional, address to serve the validating admission webhook for VirtualServers and TransportServers, "+
+			"e.g. 0.0.0.0:8443")
+	admissionWebhookCert = globalFlags.String("admission-webhook-cert", "",
+		"Optional, path of the TLS certificate used by admission webhook")
+	admissionWebhookKey = globalFlags.String("admission-webhook-key", "",
+		"Optional, path of the TLS key used by admission webhook")
 
 	globalFlags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "  Global:\n%s\n", globalFlags.FlagUsagesWrapped(width))
@@ -279,6 +432,56 @@ func _init() {
 		"Optional, time (in seconds) that CIS waits to post the available AS3 declaration.")
 	logAS3Response = bigIPFlags.Bool("log-as3-response", false,
 		"Optional, when set to true, add the body of AS3 API response in Controller logs.")
+	as3SchemaVersion = bigIPFlags.String("as3-schema-version", controller.DefaultAS3SchemaVersion,
+		"Optional, schemaVersion used in AS3 declaration posted to BIG-IP.")
+	autoNegotiateAS3Version = bigIPFlags.Bool("auto-negotiate-as3-version", false,
+		"Optional, when set to true, features not supported by the AS3 version installed on BIG-IP are "+
+			"removed from AS3 declaration.")
+	encryptAS3Secrets = bigIPFlags.Bool("encrypt-as3-secrets", false,
+		"Optional, when set to true, TLS keys are installed on BIG-IP SecureVault and referred in AS3 declaration.")
+	postRateLimit = bigIPFlags.Float64("post-rate-limit", 0,
+		"Optional, maximum number of configuration posts per second to BIG-IP. 0 means unlimited.")
+	postBufferDepth = bigIPFlags.Int("post-buffer-depth", controller.DefaultPostBufferDepth,
+		"Optional, number of configuration posts buffered when post-rate-limit is exceeded, "+
+			"oldest post is dropped when the buffer is full.")
+	as3PostTimeout = bigIPFlags.Duration("as3-post-timeout", controller.DefaultAS3PostTimeout,
+		"Optional, timeout of AS3 declaration posts to BIG-IP.")
+	as3ConnectTimeout = bigIPFlags.Duration("as3-connect-timeout", controller.DefaultAS3ConnectTimeout,
+		"Optional, timeout to establish connection with BIG-IP.")
+	as3ReadTimeout = bigIPFlags.Duration("as3-read-timeout", 0,
+		"Optional, timeout to receive the response of BIG-IP after posting AS3 declarations, "+
+			"0 waits upto as3-post-timeout.")
+	as3PostRetries = bigIPFlags.Int("as3-post-retries", controller.DefaultAS3PostRetries,
+		"Optional, number of retries of AS3 declaration posts failed with timeout or temporary network errors "+
+			"or 502, 503 and 504 responses of BIG-IP.")
+	as3MaxBackoff = bigIPFlags.Duration("as3-max-backoff", controller.DefaultAS3MaxBackoff,
+		"Optional, maximum delay between the retries of AS3 declaration posts, the delay is doubled on "+
+			"every retry upto the maximum.")
+	compressAS3 = bigIPFlags.Bool("compress-as3", false,
+		"Optional, post gzip compressed AS3 declarations to BIG-IP.")
+	compressAS3Threshold = bigIPFlags.Int("compress-as3-threshold-bytes", controller.DefaultCompressAS3Threshold,
+		"Optional, size of AS3 declarations in bytes from which they are compressed when compress-as3 is enabled.")
+	as3DeclSizeWarnBytes = bigIPFlags.Int("as3-declaration-size-warn-bytes", controller.DefaultAS3DeclarationSizeWarnBytes,
+		"Optional, size of AS3 declarations in bytes from which a warning event is recorded on the CIS pod, 0 disables the warning.")
+	as3DeclSizeLimitBytes = bigIPFlags.Int("as3-declaration-size-limit-bytes", 0,
+		"Optional, maximum size of AS3 declarations in bytes posted to BIG-IP, larger declarations are not posted. "+
+			"Default is 0, which means unlimited.")
+	selfHealOnPostError = bigIPFlags.Bool("self-heal-on-post-error", false,
+		"Optional, when set to true, tenants rejected by BIG-IP with AS3 schema validation errors (422) are removed "+
+			"from the declaration and the remaining tenants are reposted, instead of retrying the same declaration.")
+	bigIPClientCert = bigIPFlags.String("bigip-client-cert", "",
+		"Optional, path of the client certificate for mutual TLS with BIG-IP REST API, used along with basic auth.")
+	bigIPClientKey = bigIPFlags.String("bigip-client-key", "",
+		"Optional, path of the key of client certificate for mutual TLS with BIG-IP REST API.")
+	bigIPCACert = bigIPFlags.String("bigip-ca-cert", "",
+		"Optional, path of the CA certificate to verify the server certificate of BIG-IP REST API.")
+	bigIPCredentialNSMap = bigIPFlags.StringSlice("bigip-credential-namespace-map", []string{},
+		"Optional, comma separated namespace/secretName mapping namespaces to secrets with username and password "+
+			"keys, used to post the partitions of the namespace resources to BIG-IP instead of the global credentials. "+
+			"Partitions whose secret is missing or invalid are not posted.")
+	bigIPResourceLabels = bigIPFlags.StringToString("bigip-resource-labels", map[string]string{},
+		"Optional, labels in key=value format added to the BIG-IP Virtual, Pool and Monitor objects "+
+			"along with namespace, name of the resource and CIS version.")
 	shareNodes = bigIPFlags.Bool("share-nodes", false,
 		"Optional, when set to true, node will be shared among partition.")
 	enableTLS = bigIPFlags.String("tls-version", "1.2",
@@ -296,6 +499,9 @@ func _init() {
 	overrideAS3UsageStr := "Optional, provide Namespace and Name of that ConfigMap as <namespace>/<configmap-name>." +
 		"The JSON key/values from this ConfigMap will override key/values from internally generated AS3 declaration."
 	overriderAS3CfgmapName = bigIPFlags.String("override-as3-declaration", "", overrideAS3UsageStr)
+	grpcMonitorScriptPath = bigIPFlags.String("grpc-monitor-script-path", "",
+		"Optional, full path of the external monitor script on BIG-IP used for gRPC health checks, e.g. /Common/grpc_health_check, "+
+			"required for VirtualServers with gRPC monitor")
 	filterTenants = kubeFlags.Bool("filter-tenants", false,
 		"Optional, specify whether or not to use tenant filtering API for AS3 declaration")
 	bigIPFlags.Usage = func() {
@@ -306,8 +512,17 @@ func _init() {
 	namespaces = kubeFlags.StringArray("namespace", []string{},
 		"Optional, Kubernetes namespace(s) to watch."+
 			"If left blank controller will watch all k8s namespaces")
+	excludeNamespaces = kubeFlags.String("exclude-namespaces", "",
+		"Optional, comma separated list of namespaces to be excluded from watched namespaces, e.g. kube-system,kube-public")
 	useNodeInternal = kubeFlags.Bool("use-node-internal", true,
 		"Optional, provide kubernetes InternalIP addresses to pool")
+	excludeTaintedNodes = kubeFlags.Bool("exclude-tainted-nodes", false,
+		"Optional, when set to true, nodes with taints are excluded from NodePort pool members")
+	topologyAwareRouting = kubeFlags.Bool("topology-aware-routing", false,
+		"Optional, when set to true, pool members in the zone of controller are preferred for services with topologyKeys")
+	priorityGroupLabel = kubeFlags.String("priority-group-label", "",
+		"Optional, node label key to set priority group of pool members, e.g. topology.kubernetes.io/zone. "+
+			"Members on nodes with the label value of the controller node get the highest priority.")
 	poolMemberType = kubeFlags.String("pool-member-type", "nodeport",
 		"Optional, type of BIG-IP pool members to create. "+
 			"'nodeport' will use k8s service NodePort. "+
@@ -436,13 +651,21 @@ func _init() {
 	}
 }
 
-func initLogger(logLevel, logFile string) error {
+func initLogger(logLevel, logFile, logFormat string) error {
 	var logger log.Logger
 	if len(logFile) > 0 {
 		logger = log.NewFileLogger(logFile)
 	} else {
 		logger = log.NewConsoleLogger()
 	}
+	switch strings.ToLower(logFormat) {
+	case "", "text":
+	case "json":
+		logger = log.NewJSONLogger(logger)
+	default:
+		return fmt.Errorf("Unknown log format requested: %s\n"+
+			"    Valid log formats are: text, json", logFormat)
+	}
 	log.RegisterLogger(
 		log.LL_MIN_LEVEL, log.LL_MAX_LEVEL, logger)
 
@@ -471,7 +694,7 @@ func hasCommonPartition(partitions []string) bool {
 
 func verifyArgs() error {
 	*logLevel = strings.ToUpper(*logLevel)
-	logErr := initLogger(*logLevel, *logFile)
+	logErr := initLogger(*logLevel, *logFile, *logFormat)
 	if nil != logErr {
 		return logErr
 	}
@@ -569,6 +792,121 @@ func verifyArgs() error {
 	default:
 		return fmt.Errorf("invalid controller-mode is provided")
 	}
+	if *enableRollback && *rollbackHistoryDepth <= 0 {
+		return fmt.Errorf("rollback-history-depth must be greater than 0")
+	}
+	if *enableRollback && len(strings.Split(*checkpointCM, "/")) != 2 {
+		return fmt.Errorf("invalid value provided for --checkpoint-configmap " +
+			"Usage: --checkpoint-configmap=<namespace>/<configmap-name>")
+	}
+	if *admissionWebhookAddress != "" && (*admissionWebhookCert == "" || *admissionWebhookKey == "") {
+		return fmt.Errorf("admission-webhook-cert and admission-webhook-key are required for admission webhook")
+	}
+	if !controller.IsValidAS3SchemaVersion(*as3SchemaVersion) {
+		return fmt.Errorf("unsupported as3-schema-version %v is provided", *as3SchemaVersion)
+	}
+	if *shutdownTimeout < 0 {
+		return fmt.Errorf("shutdown-timeout must not be negative")
+	}
+	if *resyncInterval < 0 {
+		return fmt.Errorf("resync-interval must not be negative")
+	}
+	if *partitionDefaultsCM != "" && len(strings.Split(*partitionDefaultsCM, "/")) != 2 {
+		return fmt.Errorf("invalid value provided for --partition-defaults-configmap " +
+			"Usage: --partition-defaults-configmap=<namespace>/<configmap-name>")
+	}
+	if *quotaCM != "" && len(strings.Split(*quotaCM, "/")) != 2 {
+		return fmt.Errorf("invalid value provided for --quota-configmap " +
+			"Usage: --quota-configmap=<namespace>/<configmap-name>")
+	}
+	if *federationCM != "" && len(strings.Split(*federationCM, "/")) != 2 {
+		return fmt.Errorf("invalid value provided for --ingresslink-federation-configmap " +
+			"Usage: --ingresslink-federation-configmap=<namespace>/<configmap-name>")
+	}
+	if *autoScaleMonitor && *monitorPoolSizeBase < 1 {
+		return fmt.Errorf("monitor-interval-pool-size-base must be greater than 0")
+	}
+	if *respectNetPolicies {
+		if *bigipPodCIDR == "" {
+			return fmt.Errorf("bigip-pod-cidr is required with respect-network-policies")
+		}
+		if _, _, err := net.ParseCIDR(*bigipPodCIDR); err != nil {
+			return fmt.Errorf("invalid bigip-pod-cidr %v: %v", *bigipPodCIDR, err)
+		}
+	}
+	if *retryWarnThreshold < 0 || *maxRetries < 0 {
+		return fmt.Errorf("resource-retry-warning-threshold and resource-max-retries must not be negative")
+	}
+	if *cipherRefreshInterval < 0 {
+		return fmt.Errorf("cipher-refresh-interval must not be negative")
+	}
+	if *certReconcileInterval < 0 {
+		return fmt.Errorf("cert-reconcile-interval must not be negative")
+	}
+	if *metricsPort < 1 || *metricsPort > 65535 {
+		return fmt.Errorf("invalid metrics-port %v, it must be between 1 and 65535", *metricsPort)
+	}
+	if *ipamLabelRefresh < 0 {
+		return fmt.Errorf("ipam-label-refresh-interval must not be negative")
+	}
+	if *certValidationTimeout <= 0 {
+		return fmt.Errorf("cert-validation-timeout must be greater than 0")
+	}
+	if *minPartitionsPerPost < 0 {
+		return fmt.Errorf("min-partitions-per-post must not be negative")
+	}
+	if (*bigIPClientCert == "") != (*bigIPClientKey == "") {
+		return fmt.Errorf("bigip-client-cert and bigip-client-key are required for mutual TLS with BIG-IP")
+	}
+	if *postRateLimit < 0 {
+		return fmt.Errorf("post-rate-limit must not be negative")
+	}
+	if *postBufferDepth < 1 {
+		return fmt.Errorf("post-buffer-depth must be greater than 0")
+	}
+	if *as3PostTimeout <= 0 {
+		return fmt.Errorf("as3-post-timeout must be greater than 0")
+	}
+	if *as3ConnectTimeout <= 0 {
+		return fmt.Errorf("as3-connect-timeout must be greater than 0")
+	}
+	if *as3PostRetries < 0 {
+		return fmt.Errorf("as3-post-retries must not be negative")
+	}
+	if *as3ReadTimeout < 0 {
+		return fmt.Errorf("as3-read-timeout must not be negative")
+	}
+	if *as3MaxBackoff <= 0 {
+		return fmt.Errorf("as3-max-backoff must be greater than 0")
+	}
+	if *compressAS3Threshold < 0 {
+		return fmt.Errorf("compress-as3-threshold-bytes must not be negative")
+	}
+	if *as3DeclSizeWarnBytes < 0 {
+		return fmt.Errorf("as3-declaration-size-warn-bytes must not be negative")
+	}
+	if *as3DeclSizeLimitBytes < 0 {
+		return fmt.Errorf("as3-declaration-size-limit-bytes must not be negative")
+	}
+	if _, err := getBigIPCredentialSecrets(); err != nil {
+		return err
+	}
+	if _, err := getNamespaceRouteDomains(); err != nil {
+		return err
+	}
+	if _, err := getIPAMLabelNamespaces(); err != nil {
+		return err
+	}
+	for k, v := range *bigIPResourceLabels {
+		if !controller.IsValidResourceLabel(k) || !controller.IsValidResourceLabel(v) {
+			return fmt.Errorf("invalid bigip-resource-labels %v=%v", k, v)
+		}
+	}
+	for _, cidr := range getAllowedVirtualServerCIDRs() {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid CIDR %v in allowed-virtual-server-cidrs: %v", cidr, err)
+		}
+	}
 	return nil
 }
 
@@ -751,13 +1089,31 @@ func initController(
 ) *controller.Controller {
 
 	postMgrParams := controller.PostParams{
-		BIGIPUsername: *bigIPUsername,
-		BIGIPPassword: *bigIPPassword,
-		BIGIPURL:      *bigIPURL,
-		TrustedCerts:  "",
-		SSLInsecure:   true,
-		AS3PostDelay:  *as3PostDelay,
-		LogResponse:   *logAS3Response,
+		BIGIPUsername:           *bigIPUsername,
+		BIGIPPassword:           *bigIPPassword,
+		BIGIPURL:                *bigIPURL,
+		TrustedCerts:            "",
+		SSLInsecure:             true,
+		AS3PostDelay:            *as3PostDelay,
+		LogResponse:             *logAS3Response,
+		AS3SchemaVersion:        *as3SchemaVersion,
+		EncryptAS3Secrets:       *encryptAS3Secrets,
+		PostRateLimit:           *postRateLimit,
+		PostBufferDepth:         *postBufferDepth,
+		AS3PostTimeout:          *as3PostTimeout,
+		AS3ConnectTimeout:       *as3ConnectTimeout,
+		AS3ReadTimeout:          *as3ReadTimeout,
+		AS3PostRetries:          *as3PostRetries,
+		AS3MaxBackoff:           *as3MaxBackoff,
+		CompressAS3:             *compressAS3,
+		CompressThreshold:       *compressAS3Threshold,
+		BIGIPClientCert:         *bigIPClientCert,
+		BIGIPClientKey:          *bigIPClientKey,
+		BIGIPCACert:             *bigIPCACert,
+		AutoNegotiateAS3Version: *autoNegotiateAS3Version,
+		DeclarationSizeWarn:     *as3DeclSizeWarnBytes,
+		DeclarationSizeLimit:    *as3DeclSizeLimitBytes,
+		SelfHealOnPostError:     *selfHealOnPostError,
 	}
 
 	GtmParams := controller.GTMParams{
@@ -778,6 +1134,8 @@ func initController(
 		HttpAddress:    *httpAddress,
 		EnableIPV6:     *enableIPV6,
 		CCCLGTMAgent:   *ccclGtmAgent,
+		ResourceLabels: *bigIPResourceLabels,
+		CISVersion:     version,
 	}
 
 	// When CIS is configured in OCP cluster mode disable ARP in globalSection
@@ -787,25 +1145,71 @@ func initController(
 
 	agent := controller.NewAgent(agentParams)
 
+	// bigip-credential-namespace-map is validated in verifyArgs
+	credentialSecrets, _ := getBigIPCredentialSecrets()
+	// namespace-route-domain-map is validated in verifyArgs
+	namespaceRouteDomains, _ := getNamespaceRouteDomains()
+	// ipam-label-namespace-map is validated in verifyArgs
+	ipamLabelNamespaces, _ := getIPAMLabelNamespaces()
+
 	ctlr := controller.NewController(
 		controller.Params{
-			Config:             config,
-			Namespaces:         *namespaces,
-			NamespaceLabel:     *namespaceLabel,
-			Partition:          (*bigIPPartitions)[0],
-			Agent:              agent,
-			PoolMemberType:     *poolMemberType,
-			VXLANName:          vxlanName,
-			VXLANMode:          vxlanMode,
-			UseNodeInternal:    *useNodeInternal,
-			NodePollInterval:   *nodePollInterval,
-			NodeLabelSelector:  *nodeLabelSelector,
-			IPAM:               *ipam,
-			ShareNodes:         *shareNodes,
-			DefaultRouteDomain: *defaultRouteDomain,
-			Mode:               controller.ControllerMode(*controllerMode),
-			RouteSpecConfigmap: *routeSpecConfigmap,
-			RouteLabel:         *routeLabel,
+			Config:                    config,
+			Namespaces:                *namespaces,
+			ExcludeNamespaces:         getExcludedNamespaces(),
+			NamespaceLabel:            *namespaceLabel,
+			Partition:                 (*bigIPPartitions)[0],
+			Agent:                     agent,
+			PoolMemberType:            *poolMemberType,
+			VXLANName:                 vxlanName,
+			VXLANMode:                 vxlanMode,
+			UseNodeInternal:           *useNodeInternal,
+			NodePollInterval:          *nodePollInterval,
+			NodeLabelSelector:         *nodeLabelSelector,
+			IPAM:                      *ipam,
+			ShareNodes:                *shareNodes,
+			DefaultRouteDomain:        *defaultRouteDomain,
+			NamespaceRouteDomains:     namespaceRouteDomains,
+			Mode:                      controller.ControllerMode(*controllerMode),
+			RouteSpecConfigmap:        *routeSpecConfigmap,
+			RouteLabel:                *routeLabel,
+			ExcludeTaintedNodes:       *excludeTaintedNodes,
+			TopologyAwareRouting:      *topologyAwareRouting,
+			PriorityGroupLabel:        *priorityGroupLabel,
+			BigIPCredentialSecrets:    credentialSecrets,
+			EnableRollback:            *enableRollback,
+			RollbackHistoryDepth:      *rollbackHistoryDepth,
+			CheckpointCM:              *checkpointCM,
+			ShutdownTimeout:           *shutdownTimeout,
+			ResyncInterval:            *resyncInterval,
+			PartitionDefaultsCM:       *partitionDefaultsCM,
+			ProtectedPartitions:       getProtectedPartitions(),
+			MinPartitionsPerPost:      *minPartitionsPerPost,
+			ValidateBigIPCiphers:      *validateBigIPCiphers,
+			CipherRefreshInterval:     *cipherRefreshInterval,
+			SyncBigIPNodeState:        *syncBigIPNodeState,
+			IPAMLabelRefreshInterval:  *ipamLabelRefresh,
+			IPAMLabelNamespaces:       ipamLabelNamespaces,
+			ControllerIdentifier:      *controllerIdentifier,
+			QuotaCM:                   *quotaCM,
+			IngressLinkFederationCM:   *federationCM,
+			AS3ApplicationLabel:       *as3AppLabel,
+			AutoScaleMonitorInterval:  *autoScaleMonitor,
+			MonitorPoolSizeBase:       *monitorPoolSizeBase,
+			CertValidationTimeout:     *certValidationTimeout,
+			SkipCertHostCheck:         *skipCertHostCheck,
+			RespectNetworkPolicies:    *respectNetPolicies,
+			BigIPPodCIDR:              *bigipPodCIDR,
+			RetryWarningThreshold:     *retryWarnThreshold,
+			MaxRetries:                *maxRetries,
+			DryRun:                    *dryRun,
+			CertReconcileInterval:     *certReconcileInterval,
+			MetricsPort:               getMetricsPort(),
+			AllowedVirtualServerCIDRs: getAllowedVirtualServerCIDRs(),
+			GRPCMonitorScriptPath:     *grpcMonitorScriptPath,
+			AdmissionWebhookAddress:   *admissionWebhookAddress,
+			AdmissionWebhookCert:      *admissionWebhookCert,
+			AdmissionWebhookKey:       *admissionWebhookKey,
 		},
 	)
 
@@ -1331,3 +1735,91 @@ func setSDNTypeForOpenshift(rconfigclient *configclient.ConfigV1Client) string {
 	}
 	return "openshiftSDN"
 }
+
+// getExcludedNamespaces returns the namespaces provided with exclude-namespaces
+func getExcludedNamespaces() []string {
+	var excludedNamespaces []string
+	for _, ns := range strings.Split(*excludeNamespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			excludedNamespaces = append(excludedNamespaces, ns)
+		}
+	}
+	return excludedNamespaces
+}
+
+// getBigIPCredentialSecrets returns the namespace to secret mapping provided with bigip-credential-namespace-map
+func getBigIPCredentialSecrets() (map[string]string, error) {
+	secrets := make(map[string]string)
+	for _, entry := range *bigIPCredentialNSMap {
+		nsSecret := strings.Split(strings.TrimSpace(entry), "/")
+		if len(nsSecret) != 2 || nsSecret[0] == "" || nsSecret[1] == "" {
+			return nil, fmt.Errorf("invalid bigip-credential-namespace-map entry %v, must be namespace/secretName", entry)
+		}
+		secrets[nsSecret[0]] = nsSecret[1]
+	}
+	return secrets, nil
+}
+
+// getNamespaceRouteDomains returns the namespace to route domain mapping provided with namespace-route-domain-map
+func getNamespaceRouteDomains() (map[string]int, error) {
+	routeDomains := make(map[string]int)
+	for _, entry := range *namespaceRouteDomains {
+		nsRouteDomain := strings.Split(strings.TrimSpace(entry), ":")
+		if len(nsRouteDomain) != 2 || nsRouteDomain[0] == "" {
+			return nil, fmt.Errorf("invalid namespace-route-domain-map entry %v, must be namespace:routeDomainId", entry)
+		}
+		routeDomain, err := strconv.Atoi(nsRouteDomain[1])
+		if err != nil || routeDomain < 0 || routeDomain > 65534 {
+			return nil, fmt.Errorf("invalid route domain id in namespace-route-domain-map entry %v", entry)
+		}
+		routeDomains[nsRouteDomain[0]] = routeDomain
+	}
+	return routeDomains, nil
+}
+
+// getIPAMLabelNamespaces returns the IPAM label to namespace mapping provided with ipam-label-namespace-map
+func getIPAMLabelNamespaces() (map[string]string, error) {
+	labelNamespaces := make(map[string]string)
+	for _, entry := range *ipamLabelNamespaces {
+		nsLabel := strings.Split(strings.TrimSpace(entry), ":")
+		if len(nsLabel) != 2 || nsLabel[0] == "" || nsLabel[1] == "" {
+			return nil, fmt.Errorf("invalid ipam-label-namespace-map entry %v, must be namespace:ipamLabel", entry)
+		}
+		if ns, ok := labelNamespaces[nsLabel[1]]; ok && ns != nsLabel[0] {
+			return nil, fmt.Errorf("IPAM label %v is mapped to multiple namespaces in ipam-label-namespace-map", nsLabel[1])
+		}
+		labelNamespaces[nsLabel[1]] = nsLabel[0]
+	}
+	return labelNamespaces, nil
+}
+
+// getProtectedPartitions returns the partitions provided with protect-partitions
+func getProtectedPartitions() []string {
+	var partitions []string
+	for _, partition := range strings.Split(*protectPartitions, ",") {
+		if partition = strings.TrimSpace(partition); partition != "" {
+			partitions = append(partitions, partition)
+		}
+	}
+	return partitions
+}
+
+// getMetricsPort returns the port provided with metrics-port, or 0 when http-listen-address
+// listens on the same port and already serves the metrics
+func getMetricsPort() int {
+	if _, port, err := net.SplitHostPort(*httpAddress); err == nil && port == strconv.Itoa(*metricsPort) {
+		return 0
+	}
+	return *metricsPort
+}
+
+// getAllowedVirtualServerCIDRs returns the CIDRs provided with allowed-virtual-server-cidrs
+func getAllowedVirtualServerCIDRs() []string {
+	var cidrs []string
+	for _, cidr := range strings.Split(*allowedVSCIDRs, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs
+}
@@ -288,6 +288,31 @@ var _ = Describe("Main Tests", func() {
 			Expect(hasCommon).To(BeTrue())
 		})
 
+		It("verifies log format", func() {
+			defer func() {
+				_init()
+				initLogger("INFO", "", "text")
+			}()
+			os.Args = []string{
+				"./bin/k8s-bigip-ctlr",
+				"--namespace=testing",
+				"--bigip-partition=velcro1",
+				"--bigip-password=admin",
+				"--bigip-url=bigip.example.com",
+				"--bigip-username=admin",
+				"--vs-snat-pool-name=test-snat-pool",
+				"--log-format=yaml"}
+			flags.Parse(os.Args)
+			argError := verifyArgs()
+			Expect(argError).ToNot(BeNil())
+
+			os.Args[len(os.Args)-1] = "--log-format=json"
+			flags.Parse(os.Args)
+			argError = verifyArgs()
+			Expect(argError).To(BeNil())
+			Expect(*logFormat).To(Equal("json"))
+		})
+
 		It("verifies args labels", func() {
 			defer _init()
 			os.Args = []string{
@@ -349,6 +374,31 @@ var _ = Describe("Main Tests", func() {
 			Expect(err).ToNot(BeNil())
 		})
 
+		It("verifies metrics port", func() {
+			defer _init()
+			os.Args = []string{
+				"./bin/k8s-bigip-ctlr",
+				"--namespace=testing",
+				"--bigip-partition=velcro1",
+				"--bigip-password=admin",
+				"--bigip-url=bigip.example.com",
+				"--bigip-username=admin",
+				"--pool-member-type=cluster",
+				"--openshift-sdn-name=vxlan500",
+			}
+
+			flags.Parse(os.Args)
+			Expect(verifyArgs()).To(BeNil())
+			Expect(getMetricsPort()).To(Equal(0), "Metrics should be served by http-listen-address on the same port")
+
+			*metricsPort = 9090
+			Expect(verifyArgs()).To(BeNil())
+			Expect(getMetricsPort()).To(Equal(9090))
+
+			*metricsPort = 0
+			Expect(verifyArgs()).ToNot(BeNil())
+		})
+
 		It("gets credentials from a file", func() {
 			defer _init()
 			defer os.RemoveAll("/tmp/k8s-test-creds")
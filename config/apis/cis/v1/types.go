@@ -2,6 +2,7 @@ package v1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // +genclient
@@ -50,6 +51,40 @@ type VirtualServerSpec struct {
 	Profiles               ProfileSpec      `json:"profiles,omitempty"`
 	AllowSourceRange       []string         `json:"allowSourceRange,omitempty"`
 	HttpMrfRoutingEnabled  bool             `json:"httpMrfRoutingEnabled,omitempty"`
+	DNS64Prefix            string           `json:"dns64Prefix,omitempty"`
+	CookieRoutes           []CookieRoute    `json:"cookieRoutes,omitempty"`
+	InsertHeaders          []HeaderSpec     `json:"insertHeaders,omitempty"`
+	WebSocketEnabled       bool             `json:"webSocketEnabled,omitempty"`
+	// RequestBodyRewrite substitutes the regex matches in the request body
+	RequestBodyRewrite *BodyRewriteSpec `json:"requestBodyRewrite,omitempty"`
+	// ProfileWebAcceleration refers the BIG-IP web acceleration profile of the virtual
+	ProfileWebAcceleration string `json:"profileWebAcceleration,omitempty"`
+	// HostGroupPersistenceProfile overrides the persistence of Policies for all VirtualServers of the HostGroup
+	HostGroupPersistenceProfile string `json:"hostGroupPersistenceProfile,omitempty"`
+	// IPAMAnnotations are passed to the IPAM controller along with the IPAMLabel
+	IPAMAnnotations map[string]string `json:"ipamAnnotations,omitempty"`
+	// VirtualServerAddresses creates a BIG-IP virtual server with the same config for each of the addresses
+	VirtualServerAddresses []string `json:"virtualServerAddresses,omitempty"`
+}
+
+// BodyRewriteSpec substitutes all the matches of the regex in the HTTP body with the replacement.
+type BodyRewriteSpec struct {
+	Match   string `json:"match"`
+	Replace string `json:"replace"`
+}
+
+// HeaderSpec is a header inserted in the requests to the pools of VirtualServer.
+// Value can refer BIG-IP TCL commands like [IP::client_addr].
+type HeaderSpec struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CookieRoute routes the requests with the cookie value to a pool of VirtualServer.
+type CookieRoute struct {
+	CookieName  string `json:"cookieName"`
+	CookieValue string `json:"cookieValue"`
+	Pool        string `json:"pool"`
 }
 
 // ServiceAddress Service IP address definition (BIG-IP virtual-address).
@@ -63,30 +98,57 @@ type ServiceAddress struct {
 
 // Pool defines a pool object in BIG-IP.
 type Pool struct {
-	Name              string    `json:"name,omitempty"`
-	Path              string    `json:"path,omitempty"`
-	Service           string    `json:"service"`
-	ServicePort       int32     `json:"servicePort"`
-	NodeMemberLabel   string    `json:"nodeMemberLabel,omitempty"`
-	Monitor           Monitor   `json:"monitor"`
-	Monitors          []Monitor `json:"monitors"`
-	Rewrite           string    `json:"rewrite,omitempty"`
-	Balance           string    `json:"loadBalancingMethod,omitempty"`
-	ServiceNamespace  string    `json:"serviceNamespace,omitempty"`
-	ReselectTries     int32     `json:"reselectTries,omitempty"`
-	ServiceDownAction string    `json:"serviceDownAction,omitempty"`
+	Name              string             `json:"name,omitempty"`
+	Path              string             `json:"path,omitempty"`
+	Service           string             `json:"service"`
+	ServicePort       intstr.IntOrString `json:"servicePort"`
+	NodeMemberLabel   string             `json:"nodeMemberLabel,omitempty"`
+	Monitor           Monitor            `json:"monitor"`
+	Monitors          []Monitor          `json:"monitors"`
+	Rewrite           string             `json:"rewrite,omitempty"`
+	Balance           string             `json:"loadBalancingMethod,omitempty"`
+	ServiceNamespace  string             `json:"serviceNamespace,omitempty"`
+	ReselectTries     int32              `json:"reselectTries,omitempty"`
+	ServiceDownAction string             `json:"serviceDownAction,omitempty"`
+	WarmupTime        int32              `json:"warmupTime,omitempty"`
+	RequestTimeout    int32              `json:"requestTimeout,omitempty"`
+	HostHeaderRewrite string             `json:"hostHeaderRewrite,omitempty"`
+	// ExternalMembers are the pool members outside the cluster resolved by their FQDN on BIG-IP
+	ExternalMembers []ExternalMember `json:"externalMembers,omitempty"`
+	// SharedPool shares the BIG-IP pool of the service port with the other VirtualServers of partition
+	SharedPool bool `json:"sharedPool,omitempty"`
+	// SRVRecord is the DNS SRV record resolved by BIG-IP for the pool members instead of the
+	// endpoints of service, SRVPort is the port of pool members
+	SRVRecord string `json:"srvRecord,omitempty"`
+	SRVPort   int32  `json:"srvPort,omitempty"`
+	// MonitorDisabled creates the pool without health monitors, e.g. for UDP or raw TCP services
+	MonitorDisabled bool `json:"monitorDisabled,omitempty"`
+	// PersistenceProfile overrides the persistence of VirtualServer for the requests forwarded to the pool
+	PersistenceProfile string `json:"persistenceProfile,omitempty"`
+	// NodeMemberSelector selects the nodes of pool members, it takes precedence over NodeMemberLabel
+	NodeMemberSelector *metav1.LabelSelector `json:"nodeMemberSelector,omitempty"`
+	// DrainPeriod is the time in seconds for which the pool members of a deleted service
+	// are retained as disabled members, serving the existing connections
+	DrainPeriod int32 `json:"drainPeriod,omitempty"`
+}
+
+// ExternalMember defines a FQDN based pool member of a service outside the cluster.
+type ExternalMember struct {
+	FQDN string `json:"fqdn"`
+	Port int32  `json:"port"`
 }
 
 // Monitor defines a monitor object in BIG-IP.
 type Monitor struct {
-	Type       string `json:"type"`
-	Send       string `json:"send"`
-	Recv       string `json:"recv"`
-	Interval   int    `json:"interval"`
-	Timeout    int    `json:"timeout"`
-	TargetPort int32  `json:"targetPort"`
-	Name       string `json:"name,omitempty"`
-	Reference  string `json:"reference,omitempty"`
+	Type        string `json:"type"`
+	Send        string `json:"send"`
+	Recv        string `json:"recv"`
+	Interval    int    `json:"interval"`
+	Timeout     int    `json:"timeout"`
+	TargetPort  int32  `json:"targetPort"`
+	Name        string `json:"name,omitempty"`
+	Reference   string `json:"reference,omitempty"`
+	GRPCService string `json:"grpcService,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -124,6 +186,21 @@ type TLS struct {
 	ServerSSL   string   `json:"serverSSL"`
 	ServerSSLs  []string `json:"serverSSLs"`
 	Reference   string   `json:"reference"`
+	// Cipher and CipherGroup of the SSL profiles created from secrets, CipherGroup enables TLS 1.3
+	Cipher      string `json:"cipher,omitempty"`
+	CipherGroup string `json:"cipherGroup,omitempty"`
+	// AccessPolicy maps the termination type to the BIG-IP access policy of VirtualServers
+	AccessPolicy map[string]string `json:"accessPolicy,omitempty"`
+	// OCSPStapling configures the OCSP stapling of the client SSL profiles created from secrets
+	OCSPStapling OCSPStapling `json:"ocspStapling,omitempty"`
+}
+
+// OCSPStapling defines the OCSP responder to staple the OCSP responses in TLS handshakes
+type OCSPStapling struct {
+	Enabled          bool   `json:"enabled,omitempty"`
+	OCSPResponderURL string `json:"ocspResponderURL,omitempty"`
+	// StaplingTimeout is the timeout in seconds of the OCSP responder
+	StaplingTimeout int `json:"staplingTimeout,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -160,6 +237,10 @@ type IngressLinkSpec struct {
 	Selector             *metav1.LabelSelector `json:"selector"`
 	IRules               []string              `json:"iRules,omitempty"`
 	IPAMLabel            string                `json:"ipamLabel"`
+	Monitors             []Monitor             `json:"monitors,omitempty"`
+	// ClusterMember shares the virtual server of IngressLink as a member of the GTM pool of host
+	// with the CIS instances of other clusters
+	ClusterMember bool `json:"clusterMember,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -242,6 +323,19 @@ type ExternalDNSSpec struct {
 	DNSRecordType     string    `json:"dnsRecordType"`
 	LoadBalanceMethod string    `json:"loadBalanceMethod"`
 	Pools             []DNSPool `json:"pools"`
+	// DNSListener is the DNS listener of BIG-IP for authoritative DNS service of the domain
+	DNSListener *DNSListenerSpec `json:"dnsListener,omitempty"`
+	// OrderedFailover fails over the pools in the ascending order of pools with global-availability load balance method
+	OrderedFailover bool `json:"orderedFailover,omitempty"`
+	// TopologyEnabled load balances the WideIP with the topology records of pools, overriding LoadBalanceMethod
+	TopologyEnabled bool `json:"topologyEnabled,omitempty"`
+}
+
+// DNSListenerSpec is the BIG-IP DNS listener which answers the DNS queries
+type DNSListenerSpec struct {
+	Address    string `json:"address"`
+	Port       int32  `json:"port,omitempty"`
+	UDPProfile string `json:"udpProfile,omitempty"`
 }
 
 type DNSPool struct {
@@ -251,6 +345,19 @@ type DNSPool struct {
 	PriorityOrder     int       `json:"order"`
 	Monitor           Monitor   `json:"monitor"`
 	Monitors          []Monitor `json:"monitors"`
+	// TopologyRecords are the GTM topology records of pool used with topology load balance method
+	TopologyRecords []TopologyRecord `json:"topologyRecords,omitempty"`
+	// CNAMETargets are the domain names of members of CNAME pool, used instead of the virtuals of domain
+	CNAMETargets []string `json:"cnameTargets,omitempty"`
+}
+
+// TopologyRecord routes the DNS queries from Source subnet to the pool or to its Destination subnet
+type TopologyRecord struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination,omitempty"`
+	Order       int    `json:"order,omitempty"`
+	// Region is the GSLB topology region of the Source subnet, unique in the records of pool
+	Region string `json:"region,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -264,12 +371,46 @@ type ExternalDNSList struct {
 }
 
 type PolicySpec struct {
-	L7Policies  L7PolicySpec  `json:"l7Policies,omitempty"`
-	L3Policies  L3PolicySpec  `json:"l3Policies,omitempty"`
-	LtmPolicies LtmIRulesSpec `json:"ltmPolicies,omitempty"`
-	IRules      LtmIRulesSpec `json:"iRules,omitempty"`
-	Profiles    ProfileSpec   `json:"profiles,omitempty"`
-	SNAT        string        `json:"snat,omitempty"`
+	L7Policies         L7PolicySpec            `json:"l7Policies,omitempty"`
+	L3Policies         L3PolicySpec            `json:"l3Policies,omitempty"`
+	LtmPolicies        LtmIRulesSpec           `json:"ltmPolicies,omitempty"`
+	IRules             LtmIRulesSpec           `json:"iRules,omitempty"`
+	Profiles           ProfileSpec             `json:"profiles,omitempty"`
+	SNAT               string                  `json:"snat,omitempty"`
+	PersistenceHashKey *PersistenceHashKeySpec `json:"persistenceHashKey,omitempty"`
+	SecurityHeaders    *SecurityHeadersSpec    `json:"securityHeaders,omitempty"`
+	// HardwareAcceleration uses the FastL4 profile for hardware offload of TransportServers,
+	// HardwareAccelerationOffloadMode is one of auto, none and force
+	HardwareAcceleration            bool   `json:"hardwareAcceleration,omitempty"`
+	HardwareAccelerationOffloadMode string `json:"hardwareAccelerationOffloadMode,omitempty"`
+	// SSLOServiceChain attaches the HTTPS VirtualServers to the SSL Orchestrator topology of the service chain,
+	// SSLOTopologyType is one of l3-inbound, l3-outbound and l2-inbound
+	SSLOServiceChain     string   `json:"ssloServiceChain,omitempty"`
+	SSLOTopologyType     string   `json:"ssloTopologyType,omitempty"`
+	SSLOSecurityServices []string `json:"ssloSecurityServices,omitempty"`
+	// PersistenceCookieEncrypt encrypts the persistence cookies with the key of the
+	// Secret referred by PersistenceCookieEncryptKey as namespace/name
+	PersistenceCookieEncrypt    bool   `json:"persistenceCookieEncrypt,omitempty"`
+	PersistenceCookieEncryptKey string `json:"persistenceCookieEncryptKey,omitempty"`
+}
+
+// SecurityHeadersSpec defines the security headers inserted in HTTP responses
+type SecurityHeadersSpec struct {
+	HSTS          *HSTSSpec `json:"hsts,omitempty"`
+	XFrameOptions string    `json:"xFrameOptions,omitempty"`
+}
+
+// HSTSSpec defines the HTTP Strict Transport Security header
+type HSTSSpec struct {
+	MaxAge            int  `json:"maxAge,omitempty"`
+	IncludeSubDomains bool `json:"includeSubDomains,omitempty"`
+}
+
+// PersistenceHashKeySpec defines the key used for hash based persistence
+type PersistenceHashKeySpec struct {
+	Type       string `json:"type,omitempty"`
+	HeaderName string `json:"headerName,omitempty"`
+	Length     int    `json:"length,omitempty"`
 }
 
 type L7PolicySpec struct {
@@ -282,6 +423,10 @@ type L3PolicySpec struct {
 	FirewallPolicy   string   `json:"firewallPolicy,omitempty"`
 	AllowSourceRange []string `json:"allowSourceRange,omitempty"`
 	AllowVlans       []string `json:"allowVlans,omitempty"`
+	// DOSNetworkProfile and DOSApplicationProfile are the DoS profiles protecting
+	// from network and application level attacks, they take precedence over DOS
+	DOSNetworkProfile     string `json:"dosNetworkProfile,omitempty"`
+	DOSApplicationProfile string `json:"dosApplicationProfile,omitempty"`
 }
 
 type LtmIRulesSpec struct {
@@ -300,6 +445,13 @@ type ProfileSpec struct {
 	LogProfiles        []string   `json:"logProfiles,omitempty"`
 	ProfileL4          string     `json:"profileL4,omitempty"`
 	ProfileMultiplex   string     `json:"profileMultiplex,omitempty"`
+	// ProfileMultiplexSourceMask creates a OneConnect profile with the source mask instead of referring profileMultiplex
+	ProfileMultiplexSourceMask string `json:"profileMultiplexSourceMask,omitempty"`
+	// AnalyticsProfile refers the BIG-IP analytics profile of VirtualServers
+	AnalyticsProfile string `json:"analyticsProfile,omitempty"`
+	// AnalyticsEnabled creates an analytics profile for VirtualServers, which logs to AnalyticsLoggingDestination
+	AnalyticsEnabled            bool   `json:"analyticsEnabled,omitempty"`
+	AnalyticsLoggingDestination string `json:"analyticsLoggingDestination,omitempty"`
 }
 type ProfileTCP struct {
 	Client string `json:"client,omitempty"`
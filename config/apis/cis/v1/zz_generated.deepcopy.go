@@ -26,6 +26,54 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BodyRewriteSpec) DeepCopyInto(out *BodyRewriteSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BodyRewriteSpec.
+func (in *BodyRewriteSpec) DeepCopy() *BodyRewriteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BodyRewriteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CookieRoute) DeepCopyInto(out *CookieRoute) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CookieRoute.
+func (in *CookieRoute) DeepCopy() *CookieRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(CookieRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSListenerSpec) DeepCopyInto(out *DNSListenerSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSListenerSpec.
+func (in *DNSListenerSpec) DeepCopy() *DNSListenerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSListenerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DNSPool) DeepCopyInto(out *DNSPool) {
 	*out = *in
@@ -35,6 +83,16 @@ func (in *DNSPool) DeepCopyInto(out *DNSPool) {
 		*out = make([]Monitor, len(*in))
 		copy(*out, *in)
 	}
+	if in.TopologyRecords != nil {
+		in, out := &in.TopologyRecords, &out.TopologyRecords
+		*out = make([]TopologyRecord, len(*in))
+		copy(*out, *in)
+	}
+	if in.CNAMETargets != nil {
+		in, out := &in.CNAMETargets, &out.CNAMETargets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -118,6 +176,11 @@ func (in *ExternalDNSSpec) DeepCopyInto(out *ExternalDNSSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DNSListener != nil {
+		in, out := &in.DNSListener, &out.DNSListener
+		*out = new(DNSListenerSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -131,6 +194,54 @@ func (in *ExternalDNSSpec) DeepCopy() *ExternalDNSSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalMember) DeepCopyInto(out *ExternalMember) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalMember.
+func (in *ExternalMember) DeepCopy() *ExternalMember {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalMember)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HSTSSpec) DeepCopyInto(out *HSTSSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HSTSSpec.
+func (in *HSTSSpec) DeepCopy() *HSTSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HSTSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HeaderSpec) DeepCopyInto(out *HeaderSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HeaderSpec.
+func (in *HeaderSpec) DeepCopy() *HeaderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HeaderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressLink) DeepCopyInto(out *IngressLink) {
 	*out = *in
@@ -205,6 +316,11 @@ func (in *IngressLinkSpec) DeepCopyInto(out *IngressLinkSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Monitors != nil {
+		in, out := &in.Monitors, &out.Monitors
+		*out = make([]Monitor, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -308,6 +424,22 @@ func (in *Monitor) DeepCopy() *Monitor {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OCSPStapling) DeepCopyInto(out *OCSPStapling) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OCSPStapling.
+func (in *OCSPStapling) DeepCopy() *OCSPStapling {
+	if in == nil {
+		return nil
+	}
+	out := new(OCSPStapling)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Policy) DeepCopyInto(out *Policy) {
 	*out = *in
@@ -368,6 +500,22 @@ func (in *PolicyList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistenceHashKeySpec) DeepCopyInto(out *PersistenceHashKeySpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistenceHashKeySpec.
+func (in *PersistenceHashKeySpec) DeepCopy() *PersistenceHashKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistenceHashKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PolicySpec) DeepCopyInto(out *PolicySpec) {
 	*out = *in
@@ -376,6 +524,21 @@ func (in *PolicySpec) DeepCopyInto(out *PolicySpec) {
 	out.LtmPolicies = in.LtmPolicies
 	out.IRules = in.IRules
 	in.Profiles.DeepCopyInto(&out.Profiles)
+	if in.PersistenceHashKey != nil {
+		in, out := &in.PersistenceHashKey, &out.PersistenceHashKey
+		*out = new(PersistenceHashKeySpec)
+		**out = **in
+	}
+	if in.SecurityHeaders != nil {
+		in, out := &in.SecurityHeaders, &out.SecurityHeaders
+		*out = new(SecurityHeadersSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SSLOSecurityServices != nil {
+		in, out := &in.SSLOSecurityServices, &out.SSLOSecurityServices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -392,12 +555,23 @@ func (in *PolicySpec) DeepCopy() *PolicySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Pool) DeepCopyInto(out *Pool) {
 	*out = *in
+	out.ServicePort = in.ServicePort
 	out.Monitor = in.Monitor
 	if in.Monitors != nil {
 		in, out := &in.Monitors, &out.Monitors
 		*out = make([]Monitor, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExternalMembers != nil {
+		in, out := &in.ExternalMembers, &out.ExternalMembers
+		*out = make([]ExternalMember, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeMemberSelector != nil {
+		in, out := &in.NodeMemberSelector, &out.NodeMemberSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -449,6 +623,27 @@ func (in *ProfileTCP) DeepCopy() *ProfileTCP {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityHeadersSpec) DeepCopyInto(out *SecurityHeadersSpec) {
+	*out = *in
+	if in.HSTS != nil {
+		in, out := &in.HSTS, &out.HSTS
+		*out = new(HSTSSpec)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityHeadersSpec.
+func (in *SecurityHeadersSpec) DeepCopy() *SecurityHeadersSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityHeadersSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceAddress) DeepCopyInto(out *ServiceAddress) {
 	*out = *in
@@ -478,6 +673,13 @@ func (in *TLS) DeepCopyInto(out *TLS) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.AccessPolicy != nil {
+		in, out := &in.AccessPolicy, &out.AccessPolicy
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -573,6 +775,22 @@ func (in *TLSProfileSpec) DeepCopy() *TLSProfileSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyRecord) DeepCopyInto(out *TopologyRecord) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyRecord.
+func (in *TopologyRecord) DeepCopy() *TopologyRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TransportServer) DeepCopyInto(out *TransportServer) {
 	*out = *in
@@ -775,6 +993,33 @@ func (in *VirtualServerSpec) DeepCopyInto(out *VirtualServerSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.CookieRoutes != nil {
+		in, out := &in.CookieRoutes, &out.CookieRoutes
+		*out = make([]CookieRoute, len(*in))
+		copy(*out, *in)
+	}
+	if in.InsertHeaders != nil {
+		in, out := &in.InsertHeaders, &out.InsertHeaders
+		*out = make([]HeaderSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequestBodyRewrite != nil {
+		in, out := &in.RequestBodyRewrite, &out.RequestBodyRewrite
+		*out = new(BodyRewriteSpec)
+		**out = **in
+	}
+	if in.IPAMAnnotations != nil {
+		in, out := &in.IPAMAnnotations, &out.IPAMAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.VirtualServerAddresses != nil {
+		in, out := &in.VirtualServerAddresses, &out.VirtualServerAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
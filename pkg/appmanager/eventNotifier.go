@@ -36,6 +36,8 @@ type (
 		mutex           sync.Mutex
 		notifierMap     map[string]*NamespaceEventNotifier
 		broadcasterFunc NewBroadcasterFunc
+		// sourceComponent is the source component of the recorded events
+		sourceComponent string
 	}
 
 	NamespaceEventNotifier struct {
@@ -52,9 +54,25 @@ func NewEventNotifier(bfunc NewBroadcasterFunc) *EventNotifier {
 	return &EventNotifier{
 		notifierMap:     make(map[string]*NamespaceEventNotifier),
 		broadcasterFunc: bfunc,
+		sourceComponent: "k8s-bigip-ctlr",
 	}
 }
 
+// SourceComponent returns the source component of the recorded events
+func (en *EventNotifier) SourceComponent() string {
+	en.mutex.Lock()
+	defer en.mutex.Unlock()
+	return en.sourceComponent
+}
+
+// SetSourceComponent sets the source component of the events recorded
+// by the notifiers created afterwards
+func (en *EventNotifier) SetSourceComponent(component string) {
+	en.mutex.Lock()
+	defer en.mutex.Unlock()
+	en.sourceComponent = component
+}
+
 // Create a notifier for a namespace, or return the existing one
 func (en *EventNotifier) CreateNotifierForNamespace(
 	namespace string,
@@ -66,7 +84,7 @@ func (en *EventNotifier) CreateNotifierForNamespace(
 
 	evNotifier, found := en.notifierMap[namespace]
 	if !found {
-		source := v1.EventSource{Component: "k8s-bigip-ctlr"}
+		source := v1.EventSource{Component: en.sourceComponent}
 		broadcaster := en.broadcasterFunc()
 		recorder := broadcaster.NewRecorder(scheme.Scheme, source)
 		evNotifier = &NamespaceEventNotifier{
@@ -0,0 +1,195 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// VirtualServerAdmissionPath is the path served by admission webhook to validate VirtualServers
+	VirtualServerAdmissionPath = "/validate-virtualserver"
+	// TransportServerAdmissionPath is the path served by admission webhook to validate TransportServers
+	TransportServerAdmissionPath = "/validate-transportserver"
+)
+
+// startAdmissionWebhook serves the validating admission webhook over TLS
+func (ctlr *Controller) startAdmissionWebhook(address, certFile, keyFile string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(VirtualServerAdmissionPath, ctlr.handleVirtualServerAdmission)
+	mux.HandleFunc(TransportServerAdmissionPath, ctlr.handleTransportServerAdmission)
+	server := &http.Server{Addr: address, Handler: mux}
+	log.Infof("[Webhook] Starting admission webhook on %v", address)
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != nil {
+		log.Errorf("[Webhook] Admission webhook stopped: %v", err)
+	}
+}
+
+// handleVirtualServerAdmission handles the AdmissionReview requests for VirtualServers
+func (ctlr *Controller) handleVirtualServerAdmission(w http.ResponseWriter, r *http.Request) {
+	handleAdmission(w, r, ctlr.validateVirtualServerAdmission)
+}
+
+// handleTransportServerAdmission handles the AdmissionReview requests for TransportServers
+func (ctlr *Controller) handleTransportServerAdmission(w http.ResponseWriter, r *http.Request) {
+	handleAdmission(w, r, ctlr.validateTransportServerAdmission)
+}
+
+// handleAdmission responds to the AdmissionReview request with the response of validate
+func handleAdmission(
+	w http.ResponseWriter,
+	r *http.Request,
+	validate func(*admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse,
+) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to read request: %v", err), http.StatusBadRequest)
+		return
+	}
+	review := admissionv1.AdmissionReview{}
+	if err = json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(w, "invalid AdmissionReview request", http.StatusBadRequest)
+		return
+	}
+	review.Response = validate(review.Request)
+	review.Response.UID = review.Request.UID
+	resp, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+// validateVirtualServerAdmission rejects VirtualServers exceeding the quota of their namespace
+// and the ones whose paths conflict with other VirtualServers of the same HostGroup
+func (ctlr *Controller) validateVirtualServerAdmission(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Kind.Kind != VirtualServer ||
+		(req.Operation != admissionv1.Create && req.Operation != admissionv1.Update) {
+		return allowed
+	}
+	vs := &cisapiv1.VirtualServer{}
+	if err := json.Unmarshal(req.Object.Raw, vs); err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("unable to decode VirtualServer: %v", err),
+			},
+		}
+	}
+	if req.Operation == admissionv1.Create {
+		if err := ctlr.checkQuota(VirtualServer, vs.ObjectMeta); err != nil {
+			return quotaExceededResponse(err)
+		}
+	}
+	if vs.Spec.HostGroup == "" {
+		return allowed
+	}
+	if conflictVS, path := ctlr.getConflictingVirtualServer(vs); conflictVS != nil {
+		message := fmt.Sprintf("path %v of host %v in VirtualServer %v/%v conflicts with VirtualServer %v/%v in hostGroup %v",
+			path, vs.Spec.Host, vs.Namespace, vs.Name, conflictVS.Namespace, conflictVS.Name, vs.Spec.HostGroup)
+		log.Errorf("[Webhook] %v", message)
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Code:    http.StatusConflict,
+				Reason:  metav1.StatusReasonConflict,
+				Message: message,
+			},
+		}
+	}
+	return allowed
+}
+
+// validateTransportServerAdmission rejects TransportServers exceeding the quota of their namespace
+func (ctlr *Controller) validateTransportServerAdmission(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	allowed := &admissionv1.AdmissionResponse{Allowed: true}
+	if req.Kind.Kind != TransportServer || req.Operation != admissionv1.Create {
+		return allowed
+	}
+	ts := &cisapiv1.TransportServer{}
+	if err := json.Unmarshal(req.Object.Raw, ts); err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("unable to decode TransportServer: %v", err),
+			},
+		}
+	}
+	if err := ctlr.checkQuota(TransportServer, ts.ObjectMeta); err != nil {
+		return quotaExceededResponse(err)
+	}
+	return allowed
+}
+
+// quotaExceededResponse rejects the admission of virtual exceeding the quota of its namespace,
+// or whose quota can not be read
+func quotaExceededResponse(err error) *admissionv1.AdmissionResponse {
+	log.Errorf("[Webhook] %v", err)
+	status := quotaStatus(err)
+	code := http.StatusForbidden
+	if status == QuotaUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Code:    int32(code),
+			Reason:  metav1.StatusReason(status),
+			Message: err.Error(),
+		},
+	}
+}
+
+// getConflictingVirtualServer returns the VirtualServer in the same HostGroup which
+// already serves one of the host paths of given VirtualServer along with the path
+func (ctlr *Controller) getConflictingVirtualServer(vs *cisapiv1.VirtualServer) (*cisapiv1.VirtualServer, string) {
+	for _, vrt := range ctlr.getAllVSFromMonitoredNamespaces() {
+		if vrt.Namespace == vs.Namespace && vrt.Name == vs.Name {
+			continue
+		}
+		if vrt.Spec.HostGroup != vs.Spec.HostGroup || vrt.Spec.Host != vs.Spec.Host {
+			continue
+		}
+		// virtuals with different custom HTTP/HTTPS ports are not grouped together
+		if skipVirtual(vs, vrt) {
+			continue
+		}
+		paths := make(map[string]struct{})
+		for _, pool := range vrt.Spec.Pools {
+			paths[pool.Path] = struct{}{}
+		}
+		for _, pool := range vs.Spec.Pools {
+			if _, ok := paths[pool.Path]; ok {
+				return vrt, pool.Path
+			}
+		}
+	}
+	return nil, ""
+}
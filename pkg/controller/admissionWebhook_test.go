@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Admission Webhook Tests", func() {
+	var mockCtlr *mockController
+	var vs1, vs2 *cisapiv1.VirtualServer
+	namespace := "default"
+
+	newAdmissionRequest := func(vs *cisapiv1.VirtualServer) *admissionv1.AdmissionRequest {
+		raw, _ := json.Marshal(vs)
+		return &admissionv1.AdmissionRequest{
+			UID:       "uid1",
+			Kind:      metav1.GroupVersionKind{Group: "cis.f5.com", Version: "v1", Kind: VirtualServer},
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		}
+	}
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = make(map[string]bool)
+		mockCtlr.namespaces[namespace] = true
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+
+		vs1 = test.NewVirtualServer("SampleVS1", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			HostGroup:            "test",
+			VirtualServerAddress: "1.2.3.4",
+			Pools:                []cisapiv1.Pool{{Path: "/foo", Service: "svc1"}},
+		})
+		vs2 = test.NewVirtualServer("SampleVS2", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			HostGroup:            "test",
+			VirtualServerAddress: "1.2.3.4",
+			Pools:                []cisapiv1.Pool{{Path: "/bar", Service: "svc2"}},
+		})
+		mockCtlr.addVirtualServer(vs1)
+	})
+
+	It("Allows VirtualServer with unique paths", func() {
+		resp := mockCtlr.validateVirtualServerAdmission(newAdmissionRequest(vs2))
+		Expect(resp.Allowed).To(BeTrue(), "VirtualServer with unique path should be allowed")
+
+		// Updating the same VirtualServer should not conflict with itself
+		resp = mockCtlr.validateVirtualServerAdmission(newAdmissionRequest(vs1))
+		Expect(resp.Allowed).To(BeTrue(), "VirtualServer should not conflict with itself")
+	})
+
+	It("Rejects VirtualServer with duplicate path in HostGroup", func() {
+		vs2.Spec.Pools[0].Path = "/foo"
+		resp := mockCtlr.validateVirtualServerAdmission(newAdmissionRequest(vs2))
+		Expect(resp.Allowed).To(BeFalse(), "VirtualServer with duplicate path should be rejected")
+		Expect(resp.Result.Code).To(BeEquivalentTo(http.StatusConflict))
+		Expect(resp.Result.Message).To(ContainSubstring("default/SampleVS1"))
+
+		vs2.Spec.HostGroup = ""
+		resp = mockCtlr.validateVirtualServerAdmission(newAdmissionRequest(vs2))
+		Expect(resp.Allowed).To(BeTrue(), "VirtualServer without HostGroup should be allowed")
+	})
+
+	It("Rejects virtuals exceeding the quota of namespace", func() {
+		mockCtlr.quotaCMKey = "kube-system/cis-quota"
+		cm := test.NewConfigMap("cis-quota", "v1", "kube-system", map[string]string{
+			QuotasKey: `{"default": {"virtualServerQuota": 1, "transportServerQuota": 0}}`,
+		})
+		mockCtlr.quotaCMInformer, _ = mockCtlr.newConfigMapInformer(mockCtlr.quotaCMKey)
+		_ = mockCtlr.quotaCMInformer.GetStore().Add(cm)
+
+		resp := mockCtlr.validateVirtualServerAdmission(newAdmissionRequest(vs2))
+		Expect(resp.Allowed).To(BeFalse(), "VirtualServer exceeding quota should be rejected")
+		Expect(resp.Result.Code).To(BeEquivalentTo(http.StatusForbidden))
+		Expect(resp.Result.Message).To(ContainSubstring(QuotaExceeded))
+
+		// Updates are allowed as they do not add virtuals
+		req := newAdmissionRequest(vs1)
+		req.Operation = admissionv1.Update
+		Expect(mockCtlr.validateVirtualServerAdmission(req).Allowed).To(BeTrue())
+
+		// Deleting a VirtualServer frees the quota
+		mockCtlr.deleteVirtualServer(vs1)
+		Expect(mockCtlr.validateVirtualServerAdmission(newAdmissionRequest(vs2)).Allowed).To(BeTrue())
+
+		ts := test.NewTransportServer("SampleTS", namespace, cisapiv1.TransportServerSpec{
+			VirtualServerAddress: "1.2.3.5",
+			VirtualServerPort:    8080,
+		})
+		raw, _ := json.Marshal(ts)
+		resp = mockCtlr.validateTransportServerAdmission(&admissionv1.AdmissionRequest{
+			UID:       "uid2",
+			Kind:      metav1.GroupVersionKind{Group: "cis.f5.com", Version: "v1", Kind: TransportServer},
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		})
+		Expect(resp.Allowed).To(BeFalse(), "TransportServer exceeding quota should be rejected")
+		Expect(resp.Result.Reason).To(BeEquivalentTo(QuotaExceeded))
+
+		// virtuals are rejected while the quotas can not be read
+		_ = mockCtlr.quotaCMInformer.GetStore().Delete(cm)
+		resp = mockCtlr.validateVirtualServerAdmission(newAdmissionRequest(vs2))
+		Expect(resp.Allowed).To(BeFalse(), "VirtualServer without readable quota should be rejected")
+		Expect(resp.Result.Code).To(BeEquivalentTo(http.StatusServiceUnavailable))
+		Expect(resp.Result.Reason).To(BeEquivalentTo(QuotaUnavailable))
+	})
+
+	It("Serves AdmissionReview requests", func() {
+		vs2.Spec.Pools[0].Path = "/foo"
+		review := admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request:  newAdmissionRequest(vs2),
+		}
+		body, _ := json.Marshal(review)
+		recorder := httptest.NewRecorder()
+		mockCtlr.handleVirtualServerAdmission(recorder,
+			httptest.NewRequest(http.MethodPost, VirtualServerAdmissionPath, bytes.NewReader(body)))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		response := admissionv1.AdmissionReview{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(BeNil())
+		Expect(response.Response.UID).To(BeEquivalentTo("uid1"))
+		Expect(response.Response.Allowed).To(BeFalse())
+
+		recorder = httptest.NewRecorder()
+		mockCtlr.handleVirtualServerAdmission(recorder,
+			httptest.NewRequest(http.MethodPost, VirtualServerAdmissionPath, bytes.NewReader([]byte("invalid"))))
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -0,0 +1,175 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+)
+
+// as3ApplicationRegex matches the names allowed for AS3 Applications
+var as3ApplicationRegex = regexp.MustCompile(`^[A-Za-z][0-9A-Za-z_.-]*$`)
+
+// as3TenantProperties are the properties of AS3 Tenant which can not be used as Application names
+var as3TenantProperties = map[string]bool{
+	"class":              true,
+	"label":              true,
+	"remark":             true,
+	"controls":           true,
+	"constants":          true,
+	"defaultRouteDomain": true,
+	"enable":             true,
+	"optimisticLockKey":  true,
+}
+
+// getAS3Application returns the AS3 Application of the virtuals from the value of the label
+// configured with --as3-application-label. Virtuals grouped on the same address use the first
+// label found, virtuals without a valid label are in the Shared application.
+func (ctlr *Controller) getAS3Application(virtuals []*cisapiv1.VirtualServer) string {
+	if ctlr.as3ApplicationLabel == "" {
+		return ""
+	}
+	var application string
+	for _, vs := range virtuals {
+		appName, ok := vs.Labels[ctlr.as3ApplicationLabel]
+		if !ok {
+			continue
+		}
+		if !isValidAS3Application(appName) {
+			log.Warningf("Ignoring invalid AS3 Application %q of VirtualServer %v/%v",
+				appName, vs.Namespace, vs.Name)
+			continue
+		}
+		if application == "" {
+			application = appName
+		} else if application != appName {
+			log.Warningf("Ignoring AS3 Application %v of VirtualServer %v/%v as it shares the virtual with "+
+				"VirtualServers of AS3 Application %v", appName, vs.Namespace, vs.Name, application)
+		}
+	}
+	if application == as3SharedApplication {
+		return ""
+	}
+	return application
+}
+
+func isValidAS3Application(appName string) bool {
+	return as3ApplicationRegex.MatchString(appName) && !as3TenantProperties[appName]
+}
+
+// as3Application returns the AS3 Application of the virtual of resource config
+func (rsCfg *ResourceConfig) as3Application() string {
+	if rsCfg.MetaData.Application == "" {
+		return as3SharedApplication
+	}
+	return rsCfg.MetaData.Application
+}
+
+// processApplicationsForAS3 moves the AS3 Services of resource configs with an AS3 Application from the
+// Shared application to their own applications. Pools, monitors, policies, profiles and iRules of the
+// virtuals remain in the Shared application, which the moved services refer with full path.
+func processApplicationsForAS3(rsMap ResourceMap, sharedApp as3Application, tenant string) map[string]as3Application {
+	apps := make(map[string]as3Application)
+	for _, cfg := range rsMap {
+		appName := cfg.as3Application()
+		if appName == as3SharedApplication {
+			continue
+		}
+		svc, ok := sharedApp[cfg.Virtual.Name].(*as3Service)
+		if !ok {
+			continue
+		}
+		app, ok := apps[appName]
+		if !ok {
+			app = as3Application{
+				"class":    "Application",
+				"template": "generic",
+			}
+			apps[appName] = app
+		}
+		referSharedApplication(svc, sharedApp, tenant)
+		app[cfg.Virtual.Name] = svc
+		delete(sharedApp, cfg.Virtual.Name)
+	}
+	return apps
+}
+
+// referSharedApplication replaces the references of AS3 Service to the objects of Shared application
+// with their full path, as AS3 resolves the relative references within the application of the service
+func referSharedApplication(svc *as3Service, sharedApp as3Application, tenant string) {
+	refer := func(param as3MultiTypeParam) as3MultiTypeParam {
+		return referSharedObject(param, sharedApp, tenant)
+	}
+	for i, va := range svc.VirtualAddresses {
+		svc.VirtualAddresses[i] = refer(va)
+	}
+	svc.SNAT = refer(svc.SNAT)
+	svc.PolicyEndpoint = refer(svc.PolicyEndpoint)
+	svc.ClientTLS = refer(svc.ClientTLS)
+	svc.ServerTLS = refer(svc.ServerTLS)
+	svc.IRules = refer(svc.IRules)
+	svc.ProfileL4 = refer(svc.ProfileL4)
+	svc.ProfileTCP = refer(svc.ProfileTCP)
+	svc.ProfileUDP = refer(svc.ProfileUDP)
+	svc.ProfileHTTP = refer(svc.ProfileHTTP)
+	svc.ProfileHTTP2 = refer(svc.ProfileHTTP2)
+	svc.ProfileMultiplex = refer(svc.ProfileMultiplex)
+	svc.ProfileAnalytics = refer(svc.ProfileAnalytics)
+	if svc.PersistenceMethods != nil {
+		for i, method := range *svc.PersistenceMethods {
+			(*svc.PersistenceMethods)[i] = refer(method)
+		}
+	}
+}
+
+// referSharedObject returns the reference with full path when it refers an object of Shared application
+func referSharedObject(param as3MultiTypeParam, sharedApp as3Application, tenant string) as3MultiTypeParam {
+	sharedPath := func(name string) string {
+		return fmt.Sprintf("/%s/%s/%s", tenant, as3SharedApplication, name)
+	}
+	switch ref := param.(type) {
+	case string:
+		if _, ok := sharedApp[ref]; ok && !strings.HasPrefix(ref, "/") {
+			return sharedPath(ref)
+		}
+	case as3ResourcePointer:
+		if ref.Use != "" && !strings.HasPrefix(ref.Use, "/") {
+			ref.Use = sharedPath(ref.Use)
+		}
+		return ref
+	case *as3ResourcePointer:
+		if ref.Use != "" && !strings.HasPrefix(ref.Use, "/") {
+			return &as3ResourcePointer{Use: sharedPath(ref.Use)}
+		}
+	case []interface{}:
+		refs := make([]interface{}, len(ref))
+		for i, r := range ref {
+			refs[i] = referSharedObject(r, sharedApp, tenant)
+		}
+		return refs
+	case []as3ResourcePointer:
+		refs := make([]as3ResourcePointer, len(ref))
+		for i, r := range ref {
+			refs[i] = referSharedObject(r, sharedApp, tenant).(as3ResourcePointer)
+		}
+		return refs
+	}
+	return param
+}
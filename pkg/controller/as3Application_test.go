@@ -0,0 +1,105 @@
+package controller
+
+import (
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AS3 Application Tests", func() {
+	var mockCtlr *mockController
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.as3ApplicationLabel = "app"
+	})
+
+	newVirtualServer := func(name string, labels map[string]string) *cisapiv1.VirtualServer {
+		vs := test.NewVirtualServer(name, namespace, cisapiv1.VirtualServerSpec{
+			Host:                 name + ".com",
+			VirtualServerAddress: "10.1.1.1",
+		})
+		vs.Labels = labels
+		return vs
+	}
+
+	It("Gets AS3 Application of VirtualServers", func() {
+		frontend := newVirtualServer("frontend", map[string]string{"app": "frontend"})
+		backend := newVirtualServer("backend", map[string]string{"app": "backend"})
+		unlabelled := newVirtualServer("unlabelled", nil)
+
+		Expect(mockCtlr.getAS3Application([]*cisapiv1.VirtualServer{frontend})).To(Equal("frontend"))
+		Expect(mockCtlr.getAS3Application([]*cisapiv1.VirtualServer{unlabelled})).To(BeEmpty())
+		Expect(mockCtlr.getAS3Application([]*cisapiv1.VirtualServer{unlabelled, frontend, backend})).
+			To(Equal("frontend"), "First label of virtuals sharing the address should be used")
+
+		invalid := newVirtualServer("invalid", map[string]string{"app": "1frontend"})
+		Expect(mockCtlr.getAS3Application([]*cisapiv1.VirtualServer{invalid})).To(BeEmpty())
+		reserved := newVirtualServer("reserved", map[string]string{"app": "class"})
+		Expect(mockCtlr.getAS3Application([]*cisapiv1.VirtualServer{reserved})).To(BeEmpty())
+		shared := newVirtualServer("shared", map[string]string{"app": as3SharedApplication})
+		Expect(mockCtlr.getAS3Application([]*cisapiv1.VirtualServer{shared})).To(BeEmpty())
+
+		mockCtlr.as3ApplicationLabel = ""
+		Expect(mockCtlr.getAS3Application([]*cisapiv1.VirtualServer{frontend})).To(BeEmpty())
+	})
+
+	It("Groups virtuals in AS3 Applications", func() {
+		newResourceConfig := func(name, address, application string) *ResourceConfig {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.Active = true
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.MetaData.Application = application
+			rsCfg.Virtual.Name = name
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.SetVirtualAddress(address, 80)
+			rsCfg.Virtual.PoolName = name + "_pool"
+			rsCfg.Pools = Pools{{Name: name + "_pool", Partition: "test"}}
+			rsCfg.ServiceAddress = []ServiceAddress{{ArpEnabled: true}}
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+			iRuleName := getRSCfgResName(name, CSPIRuleName)
+			rsCfg.addIRule(iRuleName, "test", "when HTTP_RESPONSE {}")
+			rsCfg.Virtual.AddIRule(JoinBigipPath("test", iRuleName))
+			return rsCfg
+		}
+		config := ResourceConfigRequest{ltmConfig: make(LTMConfig)}
+		config.ltmConfig["test"] = &PartitionConfig{ResourceMap: ResourceMap{
+			"frontend_vs": newResourceConfig("frontend_vs", "10.1.1.1", "frontend"),
+			"shared_vs":   newResourceConfig("shared_vs", "10.1.1.2", ""),
+		}}
+
+		agent := newMockAgent(nil)
+		adc := agent.createAS3LTMConfigADC(config)
+		tenant := adc["test"].(as3Tenant)
+		sharedApp := tenant[as3SharedApplication].(as3Application)
+		Expect(tenant).To(HaveKey("frontend"))
+		frontendApp := tenant["frontend"].(as3Application)
+		Expect(frontendApp["class"]).To(Equal("Application"))
+		Expect(frontendApp["template"]).To(Equal("generic"))
+
+		Expect(sharedApp).To(HaveKey("shared_vs"))
+		Expect(sharedApp).NotTo(HaveKey("frontend_vs"), "Virtual should be moved to its AS3 Application")
+		Expect(sharedApp).To(HaveKey("frontend_vs_pool"), "Pool should remain in Shared application")
+
+		svc := frontendApp["frontend_vs"].(*as3Service)
+		Expect(svc.Pool).To(Equal("/test/Shared/frontend_vs_pool"))
+		Expect(svc.IRules).To(Equal([]interface{}{"/test/Shared/" + getRSCfgResName("frontend_vs", CSPIRuleName)}))
+		Expect(svc.VirtualAddresses).To(HaveLen(1))
+		Expect(svc.VirtualAddresses[0].(*as3ResourcePointer).Use).To(HavePrefix("/test/Shared/"))
+
+		// virtual in Shared application refers the objects with relative references
+		sharedSvc := sharedApp["shared_vs"].(*as3Service)
+		Expect(sharedSvc.IRules).To(Equal([]interface{}{getRSCfgResName("shared_vs", CSPIRuleName)}))
+	})
+
+	It("Gets AS3 Application of resource config", func() {
+		rsCfg := &ResourceConfig{}
+		Expect(rsCfg.as3Application()).To(Equal(as3SharedApplication))
+		rsCfg.MetaData.Application = "frontend"
+		Expect(rsCfg.as3Application()).To(Equal("frontend"))
+	})
+})
@@ -0,0 +1,81 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+)
+
+// AS3 features which are not supported by the older AS3 versions
+const (
+	as3FeatureHTTPMRFRouting = "httpMrfRoutingEnabled"
+	as3FeatureNAT64          = "nat64Enabled"
+	as3FeatureHTTP2Profile   = "profileHTTP2"
+)
+
+// as3FeatureMinVersions is the compatibility matrix of AS3 features with the minimum AS3 version supporting them
+var as3FeatureMinVersions = map[string]float64{
+	as3FeatureHTTPMRFRouting: 3.30,
+	as3FeatureNAT64:          3.30,
+	as3FeatureHTTP2Profile:   3.30,
+}
+
+// negotiateAS3Features disables the AS3 features not supported by the AS3 version of BIG-IP
+func (agent *Agent) negotiateAS3Features(bigIPAS3Version float64) {
+	agent.unsupportedAS3Features = make(map[string]bool)
+	for feature, minVersion := range as3FeatureMinVersions {
+		if bigIPAS3Version < minVersion {
+			agent.unsupportedAS3Features[feature] = true
+			log.Warningf("[AS3] Disabling %v as AS3 version %v of BIG-IP is older than %v",
+				feature, bigIPAS3Version, minVersion)
+		}
+	}
+}
+
+// removeUnsupportedAS3Features removes the features not supported by AS3 of BIG-IP from the virtuals of adc
+func (agent *Agent) removeUnsupportedAS3Features(adc as3ADC) {
+	if len(agent.unsupportedAS3Features) == 0 {
+		return
+	}
+	for _, tenant := range adc {
+		tenantDecl, ok := tenant.(as3Tenant)
+		if !ok {
+			continue
+		}
+		for _, app := range tenantDecl {
+			sharedApp, ok := app.(as3Application)
+			if !ok {
+				continue
+			}
+			for _, obj := range sharedApp {
+				svc, ok := obj.(*as3Service)
+				if !ok {
+					continue
+				}
+				if agent.unsupportedAS3Features[as3FeatureHTTPMRFRouting] {
+					svc.HttpMrfRoutingEnabled = false
+				}
+				if agent.unsupportedAS3Features[as3FeatureNAT64] {
+					svc.Nat64Enabled = false
+				}
+				if agent.unsupportedAS3Features[as3FeatureHTTP2Profile] {
+					svc.ProfileHTTP2 = nil
+				}
+			}
+		}
+	}
+}
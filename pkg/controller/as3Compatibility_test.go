@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AS3 Compatibility Tests", func() {
+	var agent *Agent
+	var server *httptest.Server
+	var as3Version string
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"version":"%v","release":"1","schemaCurrent":"%v"}`, as3Version, as3Version)
+		}))
+		agent = newMockAgent(nil)
+		agent.PostManager = &PostManager{PostParams: PostParams{BIGIPURL: server.URL, AutoNegotiateAS3Version: true}}
+		agent.setupBIGIPRESTClient()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newADC := func() (as3ADC, *as3Service) {
+		svc := &as3Service{
+			Class:                 "Service_HTTP",
+			HttpMrfRoutingEnabled: true,
+			Nat64Enabled:          true,
+			ProfileHTTP2:          &as3ResourcePointer{BigIP: "/Common/http2"},
+		}
+		return as3ADC{"test": as3Tenant{
+			"class":              "Tenant",
+			as3SharedApplication: as3Application{"class": "Application", "vs": svc},
+		}}, svc
+	}
+
+	It("Disables features not supported by AS3 of BIG-IP", func() {
+		as3Version = "3.28.0"
+		Expect(agent.IsBigIPAppServicesAvailable()).To(Succeed())
+		Expect(agent.unsupportedAS3Features).To(Equal(map[string]bool{
+			as3FeatureHTTPMRFRouting: true,
+			as3FeatureNAT64:          true,
+			as3FeatureHTTP2Profile:   true,
+		}))
+
+		adc, svc := newADC()
+		agent.removeUnsupportedAS3Features(adc)
+		Expect(svc.HttpMrfRoutingEnabled).To(BeFalse())
+		Expect(svc.Nat64Enabled).To(BeFalse())
+		Expect(svc.ProfileHTTP2).To(BeNil())
+	})
+
+	It("Retains features supported by AS3 of BIG-IP", func() {
+		as3Version = "3.36.0"
+		Expect(agent.IsBigIPAppServicesAvailable()).To(Succeed())
+		Expect(agent.unsupportedAS3Features).To(BeEmpty())
+
+		adc, svc := newADC()
+		agent.removeUnsupportedAS3Features(adc)
+		Expect(svc.HttpMrfRoutingEnabled).To(BeTrue())
+		Expect(svc.ProfileHTTP2).NotTo(BeNil())
+
+		agent.AutoNegotiateAS3Version = false
+		as3Version = "3.28.0"
+		Expect(agent.IsBigIPAppServicesAvailable()).To(Succeed())
+		Expect(agent.unsupportedAS3Features).To(BeEmpty(), "Features should not be negotiated unless enabled")
+	})
+})
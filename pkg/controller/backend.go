@@ -17,24 +17,37 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/irules"
+	bigIPPrometheus "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/prometheus"
 	rsc "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/resource"
 	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/writer"
+	"golang.org/x/time/rate"
 )
 
 const (
 	as3SharedApplication = "Shared"
 	gtmPartition         = "Common"
+	// as3GSLBTopologyRecordsName is the name of GSLB_Topology_Records in the shared application of GTM partition
+	as3GSLBTopologyRecordsName = "gslb_topology_records"
+	// DefaultPostBufferDepth is the number of configs buffered when post rate limit is exceeded
+	DefaultPostBufferDepth = 10
+	// ResourceLabelRemark is the remark of AS3 objects tagged with resource labels
+	ResourceLabelRemark = "managed-by: k8s-bigip-ctlr"
+	as3LabelMaxLength   = 64
 )
 
 var baseAS3Config = `{
@@ -56,6 +69,9 @@ var baseAS3Config = `{
 
 var DEFAULT_PARTITION string
 
+// invalidAS3LabelChars matches the chars not allowed in AS3 label
+var invalidAS3LabelChars = regexp.MustCompile("[\\x00-\\x1f\"#&*<>?\\[\\]\\\\`\\x7f]")
+
 func NewAgent(params AgentParams) *Agent {
 	DEFAULT_PARTITION = params.Partition
 	postMgr := NewPostManager(params.PostParams)
@@ -78,6 +94,22 @@ func NewAgent(params AgentParams) *Agent {
 		userAgent:             params.UserAgent,
 		HttpAddress:           params.HttpAddress,
 		ccclGTMAgent:          params.CCCLGTMAgent,
+		resourceLabels:        params.ResourceLabels,
+		cisVersion:            params.CISVersion,
+	}
+	if params.PostParams.EncryptAS3Secrets {
+		agent.secureVault = NewSecureVaultClient(postMgr)
+	}
+	if params.PostParams.PostRateLimit > 0 {
+		bufferDepth := params.PostParams.PostBufferDepth
+		if bufferDepth < 1 {
+			bufferDepth = DefaultPostBufferDepth
+		}
+		agent.postLimiter = rate.NewLimiter(rate.Limit(params.PostParams.PostRateLimit), 1)
+		agent.throttleChan = make(chan ResourceConfigRequest, bufferDepth)
+		// throttleWorker runs as a separate go routine
+		// forwards the buffered configs to agentWorker at the configured rate
+		go agent.throttleWorker()
 	}
 	// agentWorker runs as a separate go routine
 	// blocks on postChan to get new/updated configuration to be posted to BIG-IP
@@ -171,7 +203,7 @@ func (agent *Agent) IsBigIPAppServicesAvailable() error {
 	}
 	am := as3VersionInfo{
 		as3Version:       version,
-		as3SchemaVersion: schemaVersion,
+		as3SchemaVersion: agent.getAS3SchemaVersion(schemaVersion),
 		as3Release:       version + "-" + build,
 	}
 	agent.AS3VersionInfo = am
@@ -181,6 +213,11 @@ func (agent *Agent) IsBigIPAppServicesAvailable() error {
 		log.Errorf("[AS3] Error while converting AS3 version to float")
 		return err
 	}
+	bigIPPrometheus.BigIPAS3Version.Reset()
+	bigIPPrometheus.BigIPAS3Version.WithLabelValues(version).Set(1)
+	if agent.AutoNegotiateAS3Version {
+		agent.negotiateAS3Features(bigIPAS3Version)
+	}
 	if bigIPAS3Version >= as3SupportedVersion && bigIPAS3Version <= as3Version {
 		log.Debugf("[AS3] BIGIP is serving with AS3 version: %v", version)
 		return nil
@@ -188,7 +225,7 @@ func (agent *Agent) IsBigIPAppServicesAvailable() error {
 
 	if bigIPAS3Version > as3Version {
 		am.as3Version = defaultAS3Version
-		am.as3SchemaVersion = fmt.Sprintf("%.2f.0", as3Version)
+		am.as3SchemaVersion = agent.getAS3SchemaVersion(fmt.Sprintf("%.2f.0", as3Version))
 		as3Build := defaultAS3Build
 		am.as3Release = am.as3Version + "-" + as3Build
 		log.Debugf("[AS3] BIGIP is serving with AS3 version: %v", bigIPAS3Version)
@@ -201,19 +238,66 @@ func (agent *Agent) IsBigIPAppServicesAvailable() error {
 		bigIPAS3Version, as3SupportedVersion)
 }
 
+// getAS3SchemaVersion returns the configured AS3 schema version, falls back to the given schema version
+func (agent *Agent) getAS3SchemaVersion(schemaVersion string) string {
+	if agent.PostManager.AS3SchemaVersion != "" {
+		return agent.PostManager.AS3SchemaVersion
+	}
+	return schemaVersion
+}
+
 func (agent *Agent) PostConfig(rsConfig ResourceConfigRequest) {
 	// Always push latest activeConfig to channel
 	// Case1: Put latest config into the channel
 	// Case2: If channel is blocked because of earlier config, pop out earlier config and push latest config
 	// Either Case1 or Case2 executes, which ensures the above
+	agent.postWg.Add(1)
+	if agent.postLimiter != nil {
+		// configs are passed through the buffer to retain their order
+		agent.throttlePostConfig(rsConfig)
+		return
+	}
+	agent.pushPostConfig(rsConfig)
+}
+
+func (agent *Agent) pushPostConfig(rsConfig ResourceConfigRequest) {
 	select {
 	case agent.postChan <- rsConfig:
 	case <-agent.postChan:
+		// earlier config is superseded by the latest config
+		agent.postWg.Done()
 		agent.postChan <- rsConfig
 
 	}
 }
 
+// throttlePostConfig buffers the config to be posted within the post rate limit,
+// when the buffer is full the oldest config is dropped as the latest config supersedes it
+func (agent *Agent) throttlePostConfig(rsConfig ResourceConfigRequest) {
+	for {
+		select {
+		case agent.throttleChan <- rsConfig:
+			return
+		default:
+		}
+		select {
+		case <-agent.throttleChan:
+			agent.postWg.Done()
+			bigIPPrometheus.ThrottledPosts.Inc()
+			log.Debugf("[AS3] Post rate limit exceeded, dropped the oldest pending config")
+		default:
+		}
+	}
+}
+
+// throttleWorker posts the buffered configs as allowed by the post rate limit
+func (agent *Agent) throttleWorker() {
+	for rsConfig := range agent.throttleChan {
+		_ = agent.postLimiter.Wait(context.Background())
+		agent.pushPostConfig(rsConfig)
+	}
+}
+
 // agentWorker blocks on postChan
 // whenever it gets unblocked, it creates an as3 declaration for modified tenants and posts the request
 func (agent *Agent) agentWorker() {
@@ -225,6 +309,8 @@ func (agent *Agent) agentWorker() {
 		// Fetch the latest config from channel
 		select {
 		case rsConfig = <-agent.postChan:
+			// earlier config is superseded by the latest config
+			agent.postWg.Done()
 		case <-time.After(1 * time.Microsecond):
 		}
 
@@ -233,9 +319,11 @@ func (agent *Agent) agentWorker() {
 		}
 
 		decl := agent.createTenantAS3Declaration(rsConfig)
+		agent.tenantCredentials = rsConfig.credentials
 
 		if len(agent.incomingTenantDeclMap) == 0 {
 			agent.declUpdate.Unlock()
+			agent.postWg.Done()
 			continue
 		}
 
@@ -267,19 +355,25 @@ func (agent *Agent) agentWorker() {
 		agent.postTenantsDeclaration(decl, rsConfig, updatedTenants)
 
 		agent.declUpdate.Unlock()
+		agent.postWg.Done()
 	}
 }
 
 // Post the tenants declaration
 func (agent *Agent) postTenantsDeclaration(decl as3Declaration, rsConfig ResourceConfigRequest, tenants []string) {
-	cfg := agentConfig{
-		data:      string(decl),
-		as3APIURL: agent.getAS3APIURL(tenants),
-		id:        rsConfig.reqId,
+	cfgs := agent.createAgentConfigs(agent.incomingTenantDeclMap, decl, tenants, rsConfig.reqId)
+	declarationSizes := make(map[string]int)
+	removedTenants := make(map[string]struct{})
+	for _, cfg := range cfgs {
+		if !agent.checkDeclarationSize(cfg, declarationSizes) {
+			continue
+		}
+		agent.publishConfig(cfg)
+		if agent.SelfHealOnPostError {
+			agent.selfHealPost(cfg, removedTenants)
+		}
 	}
 
-	agent.publishConfig(cfg)
-
 	go agent.updatePoolMembers(rsConfig)
 
 	agent.updateTenantResponse(true)
@@ -300,18 +394,28 @@ func (agent *Agent) postTenantsDeclaration(decl as3Declaration, rsConfig Resourc
 	agent.pollTenantStatus()
 
 	// notify resourceStatusUpdate response handler on successful tenant update
-	agent.notifyRscStatusHandler(cfg.id, true)
+	agent.notifyRscStatusHandler(rsConfig.reqId, true, declarationSizes, removedTenants)
 }
 
-func (agent *Agent) notifyRscStatusHandler(id int, overwriteCfg bool) {
+func (agent *Agent) notifyRscStatusHandler(
+	id int,
+	overwriteCfg bool,
+	declarationSizes map[string]int,
+	removedTenants map[string]struct{},
+) {
 
 	rscUpdateMeta := resourceStatusMeta{
 		id,
 		make(map[string]struct{}),
+		declarationSizes,
+		removedTenants,
 	}
 	for tenant := range agent.retryTenantDeclMap {
 		rscUpdateMeta.failedTenants[tenant] = struct{}{}
 	}
+	for tenant := range removedTenants {
+		rscUpdateMeta.failedTenants[tenant] = struct{}{}
+	}
 	// If triggerred from retry block, process the previous successful request completely
 	if !overwriteCfg {
 		agent.respChan <- rscUpdateMeta
@@ -353,7 +457,12 @@ func (agent *Agent) updatePoolMembers(rsConfig ResourceConfigRequest) {
 	for _, poolMem := range allPoolMembers {
 		allPoolMems = append(
 			allPoolMems,
-			rsc.Member(poolMem),
+			rsc.Member{
+				Address: poolMem.Address,
+				Port:    poolMem.Port,
+				SvcPort: poolMem.SvcPort,
+				Session: poolMem.Session,
+			},
 		)
 	}
 	if agent.EventChan != nil {
@@ -428,7 +537,7 @@ func (agent *Agent) retryWorker() {
 			//If there are any failed tenants, retry posting them
 			agent.retryFailedTenant()
 
-			agent.notifyRscStatusHandler(0, false)
+			agent.notifyRscStatusHandler(0, false, nil, nil)
 
 			agent.declUpdate.Unlock()
 		}
@@ -454,15 +563,13 @@ func (agent *Agent) retryFailedTenant() {
 
 	if len(retryTenants) > 0 {
 		// Until all accepted tenants are not processed, we do not want to re-post failed tenants since we will anyways get a 503
-		cfg := agentConfig{
-			data:      string(agent.createAS3Declaration(retryDecl)),
-			as3APIURL: agent.getAS3APIURL(retryTenants),
-			id:        0,
-		}
+		cfgs := agent.createAgentConfigs(retryDecl, agent.createAS3Declaration(retryDecl), retryTenants, 0)
 		// Ignoring timeouts for custom errors
 		<-time.After(timeoutMedium)
 
-		agent.postConfig(&cfg)
+		for i := range cfgs {
+			agent.postConfig(&cfgs[i])
+		}
 
 		agent.updateTenantResponse(false)
 	}
@@ -542,8 +649,20 @@ func (agent *Agent) createTenantAS3Declaration(config ResourceConfigRequest) as3
 	// Re-initialise incomingTenantDeclMap map and tenantPriorityMap for each new config request
 	agent.incomingTenantDeclMap = make(map[string]as3Tenant)
 	agent.tenantPriorityMap = make(map[string]int)
-	for tenant, cfg := range agent.createAS3LTMAndGTMConfigADC(config) {
-		if !reflect.DeepEqual(cfg, agent.cachedTenantDeclMap[tenant]) {
+	adc := as3ADC{}
+	if config.rollbackTenants != nil {
+		for tenant, tenantDecl := range config.rollbackTenants {
+			adc[tenant] = tenantDecl
+		}
+	} else {
+		adc = agent.createAS3LTMAndGTMConfigADC(config)
+	}
+	if agent.declHistory != nil {
+		agent.declHistory.store(config.reqId, adc)
+	}
+	for tenant, cfg := range adc {
+		// on resync, unchanged tenants are also posted to revert the changes made outside CIS
+		if config.resync || !reflect.DeepEqual(cfg, agent.cachedTenantDeclMap[tenant]) {
 			agent.incomingTenantDeclMap[tenant] = cfg.(as3Tenant)
 		} else {
 			// cachedTenantDeclMap always holds the current configuration on BigIP(lets say A)
@@ -597,6 +716,7 @@ func (agent *Agent) createAS3LTMAndGTMConfigADC(config ResourceConfigRequest) as
 	if !agent.ccclGTMAgent {
 		adc = agent.createAS3GTMConfigADC(config, adc)
 	}
+	agent.removeUnsupportedAS3Features(adc)
 
 	return adc
 }
@@ -624,7 +744,15 @@ func (agent *Agent) createAS3GTMConfigADC(config ResourceConfigRequest, adc as3A
 			}
 		}
 
-		for domainName, wideIP := range gtmPartitionConfig.WideIPs {
+		var domainNames []string
+		for domainName := range gtmPartitionConfig.WideIPs {
+			domainNames = append(domainNames, domainName)
+		}
+		// topology records are evaluated in the order of declaration
+		sort.Strings(domainNames)
+		var topologyRecords []as3GSLBTopologyRecord
+		for _, domainName := range domainNames {
+			wideIP := gtmPartitionConfig.WideIPs[domainName]
 
 			gslbDomain := as3GLSBDomain{
 				Class:      "GSLB_Domain",
@@ -638,11 +766,18 @@ func (agent *Agent) createAS3GTMConfigADC(config ResourceConfigRequest, adc as3A
 					Class:      "GSLB_Pool",
 					RecordType: pool.RecordType,
 					LBMode:     pool.LBMethod,
-					Members:    make([]as3GSLBPoolMemberA, 0, len(pool.Members)),
+					Members:    make([]interface{}, 0, len(pool.Members)),
 					Monitors:   make([]as3ResourcePointer, 0, len(pool.Monitors)),
 				}
 
 				for _, mem := range pool.Members {
+					if pool.RecordType == CNAMERecordType {
+						gslbPool.Members = append(gslbPool.Members, as3GSLBPoolMemberCNAME{
+							Enabled:    true,
+							DomainName: mem,
+						})
+						continue
+					}
 					gslbPool.Members = append(gslbPool.Members, as3GSLBPoolMemberA{
 						Enabled: true,
 						Server: as3ResourcePointer{
@@ -651,6 +786,15 @@ func (agent *Agent) createAS3GTMConfigADC(config ResourceConfigRequest, adc as3A
 						VirtualServer: mem,
 					})
 				}
+				for _, mem := range pool.FederatedMembers {
+					gslbPool.Members = append(gslbPool.Members, as3GSLBPoolMemberA{
+						Enabled: true,
+						Server: as3ResourcePointer{
+							BigIP: mem.Server,
+						},
+						VirtualServer: mem.VirtualServer,
+					})
+				}
 
 				for _, mon := range pool.Monitors {
 					gslbMon := as3GSLBMonitor{
@@ -670,16 +814,108 @@ func (agent *Agent) createAS3GTMConfigADC(config ResourceConfigRequest, adc as3A
 				}
 				gslbDomain.Pools = append(gslbDomain.Pools, as3GSLBDomainPool{Use: pool.Name})
 				sharedApp[pool.Name] = gslbPool
+				topologyRecords = append(topologyRecords, createGSLBTopologyRecords(pool)...)
+				createGSLBTopologyRegions(pool, sharedApp)
 			}
 
 			sharedApp[domainName] = gslbDomain
 		}
+		if len(topologyRecords) > 0 {
+			sharedApp[as3GSLBTopologyRecordsName] = as3GSLBTopologyRecords{
+				Class:               "GSLB_Topology_Records",
+				LongestMatchEnabled: false,
+				Records:             topologyRecords,
+			}
+		}
 		adc[pn] = tenantDecl
 	}
+	agent.createAS3DNSListeners(config, adc)
 
 	return adc
 }
 
+// createAS3DNSListeners adds the DNS listeners of WideIPs to the shared application of Common partition.
+// Common partition is declared without listeners only to remove the listeners posted earlier.
+func (agent *Agent) createAS3DNSListeners(config ResourceConfigRequest, adc as3ADC) {
+	listeners := make(map[string]*DNSListener)
+	for _, gtmPartitionConfig := range config.gtmConfig {
+		for _, wideIP := range gtmPartitionConfig.WideIPs {
+			if wideIP.DNSListener != nil {
+				listener := wideIP.DNSListener
+				listeners[AS3NameFormatter(fmt.Sprintf("dns_listener_%v_%v", listener.Address, listener.Port))] = listener
+			}
+		}
+	}
+	if _, ok := agent.cachedTenantDeclMap[gtmPartition]; !ok && len(listeners) == 0 {
+		return
+	}
+	var sharedApp as3Application
+	if obj, ok := adc[gtmPartition]; ok {
+		sharedApp = obj.(as3Tenant)[as3SharedApplication].(as3Application)
+	} else {
+		sharedApp = as3Application{}
+		sharedApp["class"] = "Application"
+		sharedApp["template"] = "shared"
+		adc[gtmPartition] = as3Tenant{
+			"class":              "Tenant",
+			as3SharedApplication: sharedApp,
+		}
+	}
+	for name, listener := range listeners {
+		sharedApp[name] = &as3Service{
+			Class:            "Service_UDP",
+			VirtualAddresses: []as3MultiTypeParam{listener.Address},
+			VirtualPort:      int(listener.Port),
+			ProfileUDP:       &as3ResourcePointer{BigIP: listener.UDPProfile},
+			ProfileDNS:       &as3ResourcePointer{BigIP: "/Common/dns"},
+		}
+	}
+}
+
+// createGSLBTopologyRecords creates the topology records routing the DNS queries to the GSLB pool
+func createGSLBTopologyRecords(pool GSLBPool) []as3GSLBTopologyRecord {
+	var records []as3GSLBTopologyRecord
+	for _, record := range pool.TopologyRecords {
+		destination := as3GSLBTopologyMatch{
+			MatchType:  "pool",
+			MatchValue: as3ResourcePointer{Use: pool.Name},
+		}
+		if record.Destination != "" {
+			destination = as3GSLBTopologyMatch{MatchType: "subnet", MatchValue: record.Destination}
+		}
+		source := as3GSLBTopologyMatch{MatchType: "subnet", MatchValue: record.Source}
+		if record.Region != "" {
+			source = as3GSLBTopologyMatch{
+				MatchType:  "region",
+				MatchValue: as3ResourcePointer{Use: gslbTopologyRegionName(pool.Name, record.Region)},
+			}
+		}
+		records = append(records, as3GSLBTopologyRecord{
+			Source:      source,
+			Destination: destination,
+		})
+	}
+	return records
+}
+
+// createGSLBTopologyRegions creates the regions of the source subnets of topology records of pool,
+// the regions are scoped to the pool as the regions of the same name in other pools may have other subnets
+func createGSLBTopologyRegions(pool GSLBPool, sharedApp as3Application) {
+	for _, record := range pool.TopologyRecords {
+		if record.Region == "" {
+			continue
+		}
+		sharedApp[gslbTopologyRegionName(pool.Name, record.Region)] = &as3GSLBTopologyRegion{
+			Class:   "GSLB_Topology_Region",
+			Members: []as3GSLBTopologyMatch{{MatchType: "subnet", MatchValue: record.Source}},
+		}
+	}
+}
+
+func gslbTopologyRegionName(poolName, region string) string {
+	return "gslb_region_" + AS3NameFormatter(poolName+"_"+region)
+}
+
 func (agent *Agent) createAS3LTMConfigADC(config ResourceConfigRequest) as3ADC {
 	adc := as3ADC{}
 	for tenantName, partitionConfig := range config.ltmConfig {
@@ -715,9 +951,23 @@ func (agent *Agent) createAS3LTMConfigADC(config ResourceConfigRequest) as3ADC {
 		// Process rscfg to create AS3 Resources
 		processResourcesForAS3(partitionConfig.ResourceMap, sharedApp, config.shareNodes, tenantName)
 
+		// Tag the AS3 Resources managed by CIS
+		if len(agent.resourceLabels) > 0 {
+			agent.processResourceLabelsForAS3(partitionConfig.ResourceMap, sharedApp)
+		}
+
 		// Process CustomProfiles
 		processCustomProfilesForAS3(partitionConfig.ResourceMap, sharedApp)
 
+		// Replace the TLS keys with references to keys installed on BIG-IP, the tenant
+		// is not posted when its keys are not installed
+		if agent.secureVault != nil {
+			if err := agent.secureVault.replaceCertificateKeys(tenantName, sharedApp); err != nil {
+				log.Errorf("[AS3] Skipping the post of tenant %v: %v", tenantName, err)
+				continue
+			}
+		}
+
 		// Process Profiles
 		processProfilesForAS3(partitionConfig.ResourceMap, sharedApp)
 
@@ -731,6 +981,10 @@ func (agent *Agent) createAS3LTMConfigADC(config ResourceConfigRequest) as3ADC {
 			"defaultRouteDomain": config.defaultRouteDomain,
 			as3SharedApplication: sharedApp,
 		}
+		// Move the virtuals labelled with AS3 Application out of the Shared application
+		for appName, app := range processApplicationsForAS3(partitionConfig.ResourceMap, sharedApp, tenantName) {
+			tenantDecl[appName] = app
+		}
 		adc[tenantName] = tenantDecl
 	}
 	return adc
@@ -802,6 +1056,84 @@ func processResourcesForAS3(rsMap ResourceMap, sharedApp as3Application, shareNo
 	}
 }
 
+// processResourceLabelsForAS3 adds label and remark to the Virtual, Pool and Monitor
+// objects of the resource configs. Label holds the user provided labels along with
+// namespace, name of the resources and CIS version
+func (agent *Agent) processResourceLabelsForAS3(rsMap ResourceMap, sharedApp as3Application) {
+	for _, cfg := range rsMap {
+		label := agent.getResourceLabel(cfg)
+		if svc, ok := sharedApp[cfg.Virtual.Name].(*as3Service); ok {
+			svc.Label = label
+			svc.Remark = ResourceLabelRemark
+		}
+		for _, pl := range cfg.Pools {
+			if pool, ok := sharedApp[pl.Name].(*as3Pool); ok {
+				pool.Label = label
+				pool.Remark = ResourceLabelRemark
+			}
+		}
+		for _, mon := range cfg.Monitors {
+			if monitor, ok := sharedApp[mon.Name].(*as3Monitor); ok {
+				monitor.Label = label
+				monitor.Remark = ResourceLabelRemark
+			}
+		}
+	}
+}
+
+// getResourceLabel serializes the labels of resource config as comma separated key=value pairs
+// AS3 label allows upto 64 chars excluding few special chars, hence label is sanitized and truncated
+func (agent *Agent) getResourceLabel(cfg *ResourceConfig) string {
+	labels := make(map[string]string)
+	for k, v := range agent.resourceLabels {
+		labels[k] = v
+	}
+	var namespaces, names []string
+	for rscKey := range cfg.MetaData.baseResources {
+		if nsName := strings.SplitN(rscKey, "/", 2); len(nsName) == 2 {
+			namespaces = appendUniqueString(namespaces, nsName[0])
+			names = appendUniqueString(names, nsName[1])
+		}
+	}
+	sort.Strings(namespaces)
+	sort.Strings(names)
+	if len(namespaces) > 0 {
+		labels["namespace"] = strings.Join(namespaces, "+")
+		labels["name"] = strings.Join(names, "+")
+	}
+	if agent.cisVersion != "" {
+		labels["cis-version"] = agent.cisVersion
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	label := invalidAS3LabelChars.ReplaceAllString(strings.Join(pairs, ","), "")
+	if len(label) > as3LabelMaxLength {
+		label = label[:as3LabelMaxLength]
+	}
+	return label
+}
+
+func appendUniqueString(list []string, str string) []string {
+	for _, s := range list {
+		if s == str {
+			return list
+		}
+	}
+	return append(list, str)
+}
+
+// IsValidResourceLabel checks whether the label key or value can be used in AS3 label
+func IsValidResourceLabel(str string) bool {
+	return str != "" && !invalidAS3LabelChars.MatchString(str) && !strings.ContainsAny(str, ",=")
+}
+
 // Create policy declaration
 func createPoliciesDecl(cfg *ResourceConfig, sharedApp as3Application) {
 	_, port := extractVirtualAddressAndPort(cfg.Virtual.Destination)
@@ -838,16 +1170,60 @@ func createPoolDecl(cfg *ResourceConfig, sharedApp as3Application, shareNodes bo
 		pool.Class = "Pool"
 		pool.ReselectTries = v.ReselectTries
 		pool.ServiceDownAction = v.ServiceDownAction
+		hasBackupMembers := false
 		for _, val := range v.Members {
 			var member as3PoolMember
 			member.AddressDiscovery = "static"
 			member.ServicePort = val.Port
-			member.ServerAddresses = append(member.ServerAddresses, val.Address)
+			if val.RouteDomain > 0 {
+				member.ServerAddresses = append(member.ServerAddresses, fmt.Sprintf("%v%%%v", val.Address, val.RouteDomain))
+			} else {
+				member.ServerAddresses = append(member.ServerAddresses, val.Address)
+			}
 			if shareNodes {
 				member.ShareNodes = shareNodes
 			}
+			member.PriorityGroup = val.PriorityGroup
+			if val.Backup {
+				hasBackupMembers = true
+			}
+			// draining member serves the existing connections only, as well as the member
+			// blocked by NetworkPolicies
+			if val.Session == DrainingMemberSession {
+				member.AdminState = "disable"
+			}
+			// ratio of members is ramped up during the warm-up time
+			if v.WarmupTime > 0 {
+				ratio := val.Ratio
+				member.Ratio = &ratio
+			}
+			pool.Members = append(pool.Members, member)
+		}
+		// FQDN of external members is resolved by BIG-IP to create the pool members
+		for _, val := range v.ExternalMembers {
+			member := as3PoolMember{
+				AddressDiscovery: "fqdn",
+				Hostname:         val.FQDN,
+				AutoPopulate:     true,
+				ServicePort:      val.Port,
+				ShareNodes:       shareNodes,
+			}
+			// external members are primary members of the pool with backup members
+			if hasBackupMembers {
+				member.PriorityGroup = 1
+			}
 			pool.Members = append(pool.Members, member)
 		}
+		// SRV record is resolved by BIG-IP to create the pool members
+		if v.SRVRecord != "" {
+			pool.Members = append(pool.Members, as3PoolMember{
+				AddressDiscovery: "fqdn",
+				Hostname:         v.SRVRecord,
+				AutoPopulate:     true,
+				ServicePort:      v.SRVPort,
+				ShareNodes:       shareNodes,
+			})
+		}
 		for _, val := range v.MonitorNames {
 			var monitor as3ResourcePointer
 			//Reference existing health monitor from BIGIP
@@ -890,7 +1266,12 @@ func processIrulesForCRD(cfg *ResourceConfig, svc *as3Service) {
 		if strings.HasSuffix(iRuleNoPort, HttpRedirectIRuleName) ||
 			strings.HasSuffix(iRuleNoPort, HttpRedirectNoHostIRuleName) ||
 			strings.HasSuffix(iRuleName, TLSIRuleName) ||
-			strings.HasSuffix(iRuleName, ABPathIRuleName) {
+			strings.HasSuffix(iRuleName, ABPathIRuleName) ||
+			strings.HasSuffix(iRuleName, SecurityHeadersIRuleName) ||
+			strings.HasSuffix(iRuleName, CSPIRuleName) ||
+			strings.HasSuffix(iRuleName, BodyRewriteIRuleName) ||
+			strings.HasSuffix(iRuleName, PassthroughPersistIRuleName) ||
+			iRuleName == irules.WebSocketUpgradeIRuleName {
 
 			IRules = append(IRules, iRuleName)
 		} else {
@@ -903,6 +1284,24 @@ func processIrulesForCRD(cfg *ResourceConfig, svc *as3Service) {
 	}
 }
 
+// getDOSProfiles returns the DoS profile of the virtual, or the list of the application and network
+// DoS profiles when both are configured
+func getDOSProfiles(virtual Virtual) as3MultiTypeParam {
+	var profiles []as3ResourcePointer
+	for _, profile := range []string{virtual.ProfileDOS, virtual.ProfileDOSNetwork} {
+		if profile != "" && (len(profiles) == 0 || profiles[0].BigIP != profile) {
+			profiles = append(profiles, as3ResourcePointer{BigIP: profile})
+		}
+	}
+	switch len(profiles) {
+	case 0:
+		return nil
+	case 1:
+		return &profiles[0]
+	}
+	return profiles
+}
+
 // Create AS3 Service for CRD
 func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant string) {
 	svc := &as3Service{}
@@ -954,16 +1353,40 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 
 	svc.addPersistenceMethod(cfg.Virtual.PersistenceProfile)
 
-	if len(cfg.Virtual.ProfileDOS) > 0 {
-		svc.ProfileDOS = &as3ResourcePointer{
-			BigIP: cfg.Virtual.ProfileDOS,
-		}
+	// inline hash persistence takes precedence over the encrypted cookie persistence
+	if cfg.Virtual.PersistenceHashKey != nil {
+		createPersistDecl(cfg, svc, sharedApp)
+	} else if cfg.Virtual.PersistenceCookie != nil && cfg.Virtual.TLSTermination != TLSPassthrough {
+		createCookiePersistDecl(cfg, svc, sharedApp)
+	}
+
+	if profileDOS := getDOSProfiles(cfg.Virtual); profileDOS != nil {
+		svc.ProfileDOS = profileDOS
 	}
 	if len(cfg.Virtual.ProfileBotDefense) > 0 {
 		svc.ProfileBotDefense = &as3ResourcePointer{
 			BigIP: cfg.Virtual.ProfileBotDefense,
 		}
 	}
+	if len(cfg.Virtual.ProfileWebAcceleration) > 0 {
+		svc.ProfileHTTPAccel = &as3ResourcePointer{
+			BigIP: cfg.Virtual.ProfileWebAcceleration,
+		}
+	}
+	// access profile of SSL Orchestrator topology takes precedence over the access policy of TLSProfile
+	if cfg.Virtual.SSLO != nil {
+		svc.ProfileAccess = &as3ResourcePointer{
+			BigIP: cfg.Virtual.SSLO.accessProfile(),
+		}
+		svc.PolicyPerRequestAccess = &as3ResourcePointer{
+			BigIP: cfg.Virtual.SSLO.perRequestPolicy(),
+		}
+		sharedApp[ssloDataGroupName(cfg.Virtual.Name)] = cfg.Virtual.SSLO.dataGroup()
+	} else if len(cfg.Virtual.PolicyEndpointAccess) > 0 {
+		svc.ProfileAccess = &as3ResourcePointer{
+			BigIP: cfg.Virtual.PolicyEndpointAccess,
+		}
+	}
 
 	if len(cfg.Virtual.TCP.Client) > 0 || len(cfg.Virtual.TCP.Server) > 0 {
 		if cfg.Virtual.TCP.Client == "" {
@@ -987,9 +1410,24 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 	}
 
 	if len(cfg.Virtual.ProfileMultiplex) > 0 {
-		svc.ProfileMultiplex = &as3ResourcePointer{
-			BigIP: cfg.Virtual.ProfileMultiplex,
+		if cfg.Virtual.ProfileMultiplexEnable && cfg.Virtual.TLSTermination != TLSPassthrough {
+			if cfg.Virtual.MultiplexSourceMask != "" {
+				createMultiplexProfileDecl(cfg, svc, sharedApp)
+			} else {
+				svc.ProfileMultiplex = &as3ResourcePointer{
+					BigIP: cfg.Virtual.ProfileMultiplex,
+				}
+			}
+		} else {
+			log.Errorf("[AS3] Skipping multiplex profile on %v as it requires an HTTP profile", cfg.Virtual.Name)
+		}
+	}
+	if cfg.Virtual.ProfileAnalytics != "" {
+		svc.ProfileAnalytics = &as3ResourcePointer{
+			BigIP: cfg.Virtual.ProfileAnalytics,
 		}
+	} else if cfg.Virtual.Analytics != nil {
+		createAnalyticsProfileDecl(cfg, svc, sharedApp)
 	}
 	// updating the virtual server to https if a passthrough datagroup is found
 	name := getRSCfgResName(cfg.Virtual.Name, PassthroughHostsDgName)
@@ -1027,6 +1465,10 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 		}
 	}
 
+	if cfg.Virtual.HSTS != nil || len(cfg.Virtual.InsertHeaders) > 0 || cfg.Virtual.RequestTimeout > 0 {
+		createHTTPProfileDecl(cfg, svc, sharedApp)
+	}
+
 	//Attaching WAF policy
 	if cfg.Virtual.WAF != "" {
 		svc.WAF = &as3ResourcePointer{
@@ -1053,6 +1495,9 @@ func createServiceDecl(cfg *ResourceConfig, sharedApp as3Application, tenant str
 	}
 	//set HttpMrfRoutingEnabled
 	svc.HttpMrfRoutingEnabled = cfg.Virtual.HttpMrfRoutingEnabled
+	if cfg.Virtual.DNS64Prefix != "" {
+		createDNS64ProfileDecl(cfg, svc, sharedApp)
+	}
 	processCommonDecl(cfg, svc)
 	sharedApp[cfg.Virtual.Name] = svc
 }
@@ -1075,6 +1520,130 @@ func createServiceAddressDecl(cfg *ResourceConfig, virtualAddress string, shared
 	return name
 }
 
+// Create AS3 HTTP Profile for the security headers defined in Policy CRD,
+// the request headers inserted by VirtualServer and the request timeout of its pools
+func createHTTPProfileDecl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	if svc.ProfileHTTP != nil {
+		log.Warningf("[AS3] Skipping HSTS, inserted headers and request timeout on %v as HTTP profile "+
+			"is already configured", cfg.Virtual.Name)
+		return
+	}
+	profileName := fmt.Sprintf("%s_http_profile", cfg.Virtual.Name)
+	profile := &as3HTTPProfile{Class: "HTTP_Profile", RequestTimeout: cfg.Virtual.RequestTimeout}
+	if cfg.Virtual.HSTS != nil {
+		profile.HSTSInsert = true
+		profile.HSTSPeriod = cfg.Virtual.HSTS.MaxAge
+		profile.HSTSIncludeSubdomains = cfg.Virtual.HSTS.IncludeSubDomains
+	}
+	if len(cfg.Virtual.InsertHeaders) > 0 {
+		// HTTP profile inserts a single header, the other headers are
+		// appended to its value separated by CRLF as BIG-IP inserts them as is
+		value := cfg.Virtual.InsertHeaders[0].Value
+		for _, header := range cfg.Virtual.InsertHeaders[1:] {
+			value += "\r\n" + header.Name + ": " + header.Value
+		}
+		profile.InsertHeader = &as3HTTPInsertHeader{
+			Name:  cfg.Virtual.InsertHeaders[0].Name,
+			Value: value,
+		}
+	}
+	sharedApp[profileName] = profile
+	svc.ProfileHTTP = &as3ResourcePointer{
+		Use: profileName,
+	}
+}
+
+// Create AS3 Multiplex Profile for the OneConnect source mask defined in Policy CRD
+func createMultiplexProfileDecl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	profileName := fmt.Sprintf("%s_multiplex_profile", cfg.Virtual.Name)
+	sharedApp[profileName] = &as3MultiplexProfile{
+		Class:      "Multiplex_Profile",
+		SourceMask: cfg.Virtual.MultiplexSourceMask,
+	}
+	svc.ProfileMultiplex = &as3ResourcePointer{
+		Use: profileName,
+	}
+}
+
+// Create AS3 Analytics Profile for the analytics enabled in Policy CRD, statistics are
+// logged to the logging destination when specified or else to BIG-IP
+func createAnalyticsProfileDecl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	profileName := fmt.Sprintf("%s_analytics_profile", cfg.Virtual.Name)
+	profile := &as3AnalyticsProfile{
+		Class:                         "Analytics_Profile",
+		CollectedStatsInternalLogging: true,
+	}
+	if dest := cfg.Virtual.Analytics.LoggingDestination; dest != "" {
+		profile.CollectedStatsInternalLogging = false
+		profile.CollectedStatsExternalLogging = true
+		profile.ExternalLoggingPublisher = &as3ResourcePointer{
+			BigIP: dest,
+		}
+	}
+	sharedApp[profileName] = profile
+	svc.ProfileAnalytics = &as3ResourcePointer{
+		Use: profileName,
+	}
+}
+
+// Create AS3 DNS Profile for the DNS64 prefix of Virtual Server and enable NAT64
+// on the virtual to translate IPv6 clients to IPv4 pool members
+func createDNS64ProfileDecl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	prefix, _, err := net.ParseCIDR(cfg.Virtual.DNS64Prefix)
+	if err != nil {
+		log.Errorf("[AS3] Invalid DNS64 prefix %v on %v", cfg.Virtual.DNS64Prefix, cfg.Virtual.Name)
+		return
+	}
+	profileName := fmt.Sprintf("%s_dns64_profile", cfg.Virtual.Name)
+	sharedApp[profileName] = &as3DNSProfile{
+		Class:       "DNS_Profile",
+		DNS64Mode:   "secondary",
+		DNS64Prefix: prefix.String(),
+	}
+	svc.ProfileDNS = &as3ResourcePointer{
+		Use: profileName,
+	}
+	svc.Nat64Enabled = true
+}
+
+// Create AS3 Persist for the hash key defined in Policy CRD
+func createPersistDecl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	hashKey := cfg.Virtual.PersistenceHashKey
+	persist := &as3Persist{
+		Class: "Persist",
+	}
+	switch hashKey.Type {
+	case "header":
+		if hashKey.HeaderName == "" {
+			log.Errorf("[AS3] headerName is required for header based persistence hash key on %v", cfg.Virtual.Name)
+			return
+		}
+		persist.PersistenceMethod = "hash"
+		persist.HashStartPattern = hashKey.HeaderName + ": "
+		persist.HashEndPattern = "\r\n"
+		persist.HashLength = hashKey.Length
+	case "uri":
+		persist.PersistenceMethod = "hash"
+		persist.HashStartPattern = " /"
+		persist.HashEndPattern = " HTTP/"
+		persist.HashLength = hashKey.Length
+	case "source-address":
+		persist.PersistenceMethod = "source-address"
+	default:
+		log.Errorf("[AS3] Invalid persistence hash key type %v on %v", hashKey.Type, cfg.Virtual.Name)
+		return
+	}
+	persistName := fmt.Sprintf("%s_persist", cfg.Virtual.Name)
+	sharedApp[persistName] = persist
+	svc.PersistenceMethods = &[]as3MultiTypeParam{
+		as3MultiTypeParam(
+			as3ResourcePointer{
+				Use: persistName,
+			},
+		),
+	}
+}
+
 // Create AS3 Rule Condition for CRD
 func createRuleCondition(rl *Rule, rulesData *as3Rule, port int) {
 	for _, c := range rl.Conditions {
@@ -1135,6 +1704,13 @@ func createRuleCondition(rl *Rule, rulesData *as3Rule, port int) {
 			if c.Equals {
 				condition.Path.Operand = "equals"
 			}
+		} else if c.HTTPCookie {
+			condition.Type = "httpCookie"
+			condition.Name = c.CookieName
+			condition.All = &as3PolicyCompareString{
+				Values:  c.Values,
+				Operand: "equals",
+			}
 		} else if c.Tcp {
 			if c.Address && len(c.Values) > 0 {
 				condition.Type = "tcp"
@@ -1186,6 +1762,10 @@ func createRuleAction(rl *Rule, rulesData *as3Rule) {
 				Value: v.Value,
 			}
 		}
+		if v.Persist != "" {
+			action.Type = "persist"
+			setPersistAction(action, v.Persist, v.Value)
+		}
 		p := strings.Split(v.Pool, "/")
 		if v.Pool != "" {
 			action.Select = &as3ActionForwardSelect{
@@ -1198,19 +1778,36 @@ func createRuleAction(rl *Rule, rulesData *as3Rule) {
 	}
 }
 
+// setPersistAction sets the AS3 persistence method of persist action with the defaults of
+// the corresponding BIG-IP persistence profiles, i.e. session cookie and host address for 180 seconds
+func setPersistAction(action *as3Action, persist, cookieName string) {
+	switch persist {
+	case "cookie":
+		action.CookieInsert = &as3PersistCookie{Name: cookieName, Expiry: PersistCookieExpiry}
+	case "source-address":
+		action.SourceAddress = &as3PersistAddress{Netmask: PersistAddressNetmask, Timeout: PersistAddressTimeout}
+	case "destination-address":
+		action.DestinationAddress = &as3PersistAddress{Netmask: PersistAddressNetmask, Timeout: PersistAddressTimeout}
+	default:
+		action.Disable = &struct{}{}
+	}
+}
+
 // Extract virtual address and port from host URL
 func extractVirtualAddressAndPort(str string) (string, int) {
 
 	destination := strings.Split(str, "/")
-	// split separator is in accordance with SetVirtualAddress function - ipv4/6 format
-	ipPort := strings.Split(destination[len(destination)-1], ":")
-	if len(ipPort) != 2 {
-		ipPort = strings.Split(destination[len(destination)-1], ".")
+	ipPort := destination[len(destination)-1]
+	// split separator is in accordance with SetVirtualAddress function - ipv4/6 format,
+	// the last "." separates the port of IPv6 address which may have an embedded IPv4 address
+	separator := ":"
+	if strings.Count(ipPort, ":") > 1 {
+		separator = "."
 	}
 	// verify that ip address and port exists else log error.
-	if len(ipPort) == 2 {
-		port, _ := strconv.Atoi(ipPort[1])
-		return ipPort[0], port
+	if index := strings.LastIndex(ipPort, separator); index > 0 {
+		port, _ := strconv.Atoi(ipPort[index+1:])
+		return ipPort[:index], port
 	} else {
 		log.Error("Invalid Virtual Server Destination IP address/Port.")
 		return "", 0
@@ -1310,6 +1907,9 @@ func processCustomProfilesForAS3(rsMap ResourceMap, sharedApp as3Application) {
 			if ok := createUpdateTLSServer(prof, svcName, sharedApp); ok {
 				// Create Certificate only if the corresponding TLSServer is created
 				createCertificateDecl(prof, sharedApp)
+				if rsCfg.Virtual.ClientCACert != "" {
+					createClientAuthDecl(rsCfg.Virtual.ClientCACert, svcName, sharedApp)
+				}
 			} else {
 				createUpdateCABundle(prof, caBundleName, sharedApp)
 				tlsClient = createTLSClient(prof, svcName, caBundleName, sharedApp)
@@ -1347,6 +1947,7 @@ func createUpdateTLSServer(prof CustomProfile, svcName string, sharedApp as3Appl
 			} else {
 				tlsServer.Ciphers = prof.Ciphers
 			}
+			tlsServer.StaplerOCSPEnabled = prof.OCSPResponderURL != ""
 
 			sharedApp[tlsServerName] = tlsServer
 			svc.ServerTLS = tlsServerName
@@ -1372,6 +1973,16 @@ func createUpdateTLSServer(prof CustomProfile, svcName string, sharedApp as3Appl
 }
 
 func createCertificateDecl(prof CustomProfile, sharedApp as3Application) {
+	var staplerOCSP *as3ResourcePointer
+	if prof.OCSPResponderURL != "" {
+		ocspName := fmt.Sprintf("%s_ocsp", prof.Name)
+		sharedApp[ocspName] = &as3CertificateValidatorOCSP{
+			Class:        "Certificate_Validator_OCSP",
+			ResponderURL: prof.OCSPResponderURL,
+			Timeout:      prof.OCSPTimeout,
+		}
+		staplerOCSP = &as3ResourcePointer{Use: ocspName}
+	}
 	for index, certificate := range prof.Certificates {
 		if len(certificate.Cert) > 0 && len(certificate.Key) > 0 {
 			cert := &as3Certificate{
@@ -1379,12 +1990,31 @@ func createCertificateDecl(prof CustomProfile, sharedApp as3Application) {
 				Certificate: certificate.Cert,
 				PrivateKey:  certificate.Key,
 				ChainCA:     prof.CAFile,
+				StaplerOCSP: staplerOCSP,
 			}
 			sharedApp[fmt.Sprintf("%s_%d", prof.Name, index)] = cert
 		}
 	}
 }
 
+// createClientAuthDecl creates CA Bundle of the client CA certificate and configures
+// the TLSServer of service to require client certificates signed by it
+func createClientAuthDecl(caCert, svcName string, sharedApp as3Application) {
+	tlsServer, ok := sharedApp[fmt.Sprintf("%s_tls_server", svcName)].(*as3TLSServer)
+	if !ok {
+		return
+	}
+	caBundleName := fmt.Sprintf("%s_client_ca_bundle", svcName)
+	sharedApp[caBundleName] = &as3CABundle{
+		Class:  "CA_Bundle",
+		Bundle: caCert,
+	}
+	tlsServer.AuthenticationMode = "require"
+	tlsServer.AuthenticationTrustCA = &as3ResourcePointer{
+		Use: caBundleName,
+	}
+}
+
 func createUpdateCABundle(prof CustomProfile, caBundleName string, sharedApp as3Application) {
 	for _, cert := range prof.Certificates {
 		// For TLSClient only Cert (DestinationCACertificate) is given and key is empty string
@@ -1478,6 +2108,11 @@ func createMonitorDecl(cfg *ResourceConfig, sharedApp as3Application) {
 			monitor.Adaptive = &adaptiveFalse
 			monitor.Receive = v.Recv
 			monitor.Send = v.Send
+		case ExternalMonitorType:
+			monitor.Pathname = v.ScriptPath
+			if v.GRPCService != "" {
+				monitor.EnvironmentVariables = map[string]string{"GRPC_SERVICE": v.GRPCService}
+			}
 		}
 		sharedApp[v.Name] = monitor
 	}
@@ -1515,10 +2150,8 @@ func createTransportServiceDecl(cfg *ResourceConfig, sharedApp as3Application) {
 
 	svc.addPersistenceMethod(cfg.Virtual.PersistenceProfile)
 
-	if len(cfg.Virtual.ProfileDOS) > 0 {
-		svc.ProfileDOS = &as3ResourcePointer{
-			BigIP: cfg.Virtual.ProfileDOS,
-		}
+	if profileDOS := getDOSProfiles(cfg.Virtual); profileDOS != nil {
+		svc.ProfileDOS = profileDOS
 	}
 
 	if len(cfg.Virtual.ProfileBotDefense) > 0 {
@@ -1560,6 +2193,24 @@ func createTransportServiceDecl(cfg *ResourceConfig, sharedApp as3Application) {
 					BigIP: fmt.Sprintf("%v", profile.Name),
 				}
 			}
+		case "http":
+			if !profile.BigIPProfile {
+				svc.ProfileHTTP = name
+			} else {
+				svc.ProfileHTTP = &as3ResourcePointer{
+					BigIP: fmt.Sprintf("%v", profile.Name),
+				}
+			}
+		}
+	}
+
+	if len(cfg.Virtual.ProfileMultiplex) > 0 {
+		if cfg.Virtual.ProfileMultiplexEnable {
+			svc.ProfileMultiplex = &as3ResourcePointer{
+				BigIP: cfg.Virtual.ProfileMultiplex,
+			}
+		} else {
+			log.Errorf("[AS3] Skipping multiplex profile on %v as it requires an HTTP profile", cfg.Virtual.Name)
 		}
 	}
 
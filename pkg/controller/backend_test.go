@@ -2,10 +2,13 @@ package controller
 
 import (
 	"encoding/json"
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/ghttp"
+	"golang.org/x/time/rate"
+	"strings"
 )
 
 var _ = Describe("Backend Tests", func() {
@@ -292,6 +295,24 @@ var _ = Describe("Backend Tests", func() {
 			Expect(agent.incomingTenantDeclMap["default"]).To(Equal(deletedTenantDecl), "Failed to Create AS3 Declaration for deleted tenant")
 			Expect(adc["default"]).To(Equal(map[string]interface{}(deletedTenantDecl)), "Failed to Create AS3 Declaration for deleted tenant")
 		})
+		It("Resync posts unchanged partitions", func() {
+			config := ResourceConfigRequest{
+				ltmConfig:          make(LTMConfig),
+				shareNodes:         true,
+				gtmConfig:          GTMConfig{},
+				defaultRouteDomain: 1,
+			}
+			config.ltmConfig["default"] = &PartitionConfig{make(ResourceMap), 0}
+
+			agent.createTenantAS3Declaration(config)
+			agent.cachedTenantDeclMap = agent.incomingTenantDeclMap
+			agent.createTenantAS3Declaration(config)
+			Expect(agent.incomingTenantDeclMap).To(BeEmpty(), "Unchanged partition should not be posted")
+
+			config.resync = true
+			agent.createTenantAS3Declaration(config)
+			Expect(agent.incomingTenantDeclMap).To(HaveKey("default"), "Unchanged partition should be posted on resync")
+		})
 		It("Handles Persistence Methods", func() {
 			svc := &as3Service{}
 			// Default persistence methods
@@ -315,6 +336,396 @@ var _ = Describe("Backend Tests", func() {
 			svc.addPersistenceMethod("pm2")
 			Expect(svc.PersistenceMethods).To(Equal(&[]as3MultiTypeParam{as3ResourcePointer{BigIP: "pm2"}}))
 		})
+		It("Handles Persistence Hash Key", func() {
+			mockCtlr := newMockController()
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Destination = "/test/172.13.14.15:8080"
+			plc := test.NewPolicy("plc1", "default", cisapiv1.PolicySpec{
+				Profiles: cisapiv1.ProfileSpec{PersistenceProfile: "/Common/pm1"},
+				PersistenceHashKey: &cisapiv1.PersistenceHashKeySpec{
+					Type:       "header",
+					HeaderName: "X-Session-ID",
+					Length:     32,
+				},
+			})
+			err := mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle VirtualServer for policy")
+
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.PersistenceMethods).To(Equal(&[]as3MultiTypeParam{
+				as3ResourcePointer{Use: "crd_vs_172.13.14.15_persist"}}))
+			Expect(sharedApp["crd_vs_172.13.14.15_persist"]).To(Equal(&as3Persist{
+				Class:             "Persist",
+				PersistenceMethod: "hash",
+				HashStartPattern:  "X-Session-ID: ",
+				HashEndPattern:    "\r\n",
+				HashLength:        32,
+			}), "Invalid hash persistence")
+
+			// header based hash key without a header name is ignored
+			rsCfg.Virtual.PersistenceHashKey.HeaderName = ""
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.PersistenceMethods).To(Equal(&[]as3MultiTypeParam{as3ResourcePointer{BigIP: "/Common/pm1"}}))
+			Expect(sharedApp).NotTo(HaveKey("crd_vs_172.13.14.15_persist"))
+		})
+
+		It("Security Headers from Policy", func() {
+			mockCtlr := newMockController()
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Destination = "/test/172.13.14.15:8080"
+			rsCfg.IRulesMap = make(IRulesMap)
+			plc := test.NewPolicy("plc1", "default", cisapiv1.PolicySpec{
+				SecurityHeaders: &cisapiv1.SecurityHeadersSpec{
+					HSTS:          &cisapiv1.HSTSSpec{MaxAge: 31536000, IncludeSubDomains: true},
+					XFrameOptions: "DENY",
+				},
+			})
+			err := mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle VirtualServer for policy")
+			Expect(rsCfg.Virtual.IRules).To(ContainElement("/test/crd_vs_172.13.14.15_security_headers_irule"))
+
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileHTTP).To(Equal(&as3ResourcePointer{Use: "crd_vs_172.13.14.15_http_profile"}))
+			Expect(sharedApp["crd_vs_172.13.14.15_http_profile"]).To(Equal(&as3HTTPProfile{
+				Class:                 "HTTP_Profile",
+				HSTSInsert:            true,
+				HSTSPeriod:            31536000,
+				HSTSIncludeSubdomains: true,
+			}), "Invalid HTTP profile")
+			Expect(svc.IRules).To(ContainElement("crd_vs_172.13.14.15_security_headers_irule"))
+
+			// HSTS is not applied over a custom HTTP profile
+			rsCfg.Virtual.Profiles = append(rsCfg.Virtual.Profiles, ProfileRef{
+				Name: "/Common/http1", Context: "http", BigIPProfile: true})
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileHTTP).To(Equal(&as3ResourcePointer{BigIP: "/Common/http1"}))
+			Expect(sharedApp).NotTo(HaveKey("crd_vs_172.13.14.15_http_profile"))
+		})
+
+		It("Insert Headers from VirtualServer", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Destination = "/test/172.13.14.15:8080"
+			vs1 := test.NewVirtualServer("vs1", "default", cisapiv1.VirtualServerSpec{
+				InsertHeaders: []cisapiv1.HeaderSpec{
+					{Name: "X-Real-IP", Value: "[IP::client_addr]"},
+					{Name: "X-Env", Value: "prod"},
+				},
+			})
+			vs2 := test.NewVirtualServer("vs2", "default", cisapiv1.VirtualServerSpec{
+				InsertHeaders: []cisapiv1.HeaderSpec{
+					{Name: "x-env", Value: "dev"},
+					{Name: "X-Request-Source", Value: "cis"},
+				},
+			})
+			handleInsertHeaders(rsCfg, vs1)
+			handleInsertHeaders(rsCfg, vs2)
+			Expect(rsCfg.Virtual.InsertHeaders).To(Equal([]HTTPHeader{
+				{Name: "X-Real-IP", Value: "[IP::client_addr]"},
+				{Name: "X-Env", Value: "prod"},
+				{Name: "X-Request-Source", Value: "cis"},
+			}), "Header of the VirtualServer processed first should be inserted")
+
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileHTTP).To(Equal(&as3ResourcePointer{Use: "crd_vs_172.13.14.15_http_profile"}))
+			Expect(sharedApp["crd_vs_172.13.14.15_http_profile"]).To(Equal(&as3HTTPProfile{
+				Class: "HTTP_Profile",
+				InsertHeader: &as3HTTPInsertHeader{
+					Name:  "X-Real-IP",
+					Value: "[IP::client_addr]\r\nX-Env: prod\r\nX-Request-Source: cis",
+				},
+			}), "Invalid HTTP profile")
+		})
+
+		It("Request timeout in HTTP profile", func() {
+			sharedApp := as3Application{}
+			for vsName, timeout := range map[string]int32{"crd_vs_upload": 600, "crd_vs_stream": 3600} {
+				rsCfg := &ResourceConfig{}
+				rsCfg.MetaData.ResourceType = VirtualServer
+				rsCfg.Virtual.Name = vsName
+				rsCfg.Virtual.Partition = "test"
+				rsCfg.Virtual.RequestTimeout = timeout
+				createServiceDecl(rsCfg, sharedApp, "test")
+				svc := sharedApp[vsName].(*as3Service)
+				Expect(svc.ProfileHTTP).To(Equal(&as3ResourcePointer{Use: vsName + "_http_profile"}))
+			}
+			Expect(sharedApp["crd_vs_upload_http_profile"]).To(Equal(&as3HTTPProfile{
+				Class:          "HTTP_Profile",
+				RequestTimeout: 600,
+			}), "Invalid HTTP profile")
+			Expect(sharedApp["crd_vs_stream_http_profile"]).To(Equal(&as3HTTPProfile{
+				Class:          "HTTP_Profile",
+				RequestTimeout: 3600,
+			}), "Invalid HTTP profile")
+		})
+
+		It("OneConnect Source Mask from Policy", func() {
+			mockCtlr := newMockController()
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Destination = "/test/172.13.14.15:8080"
+			rsCfg.IRulesMap = make(IRulesMap)
+			plc := test.NewPolicy("plc1", "default", cisapiv1.PolicySpec{
+				Profiles: cisapiv1.ProfileSpec{ProfileMultiplex: "/Common/oneconnect"},
+			})
+			err := mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle VirtualServer for policy")
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileMultiplex).To(Equal(&as3ResourcePointer{BigIP: "/Common/oneconnect"}))
+
+			plc.Spec.Profiles.ProfileMultiplexSourceMask = "255.255.255.0"
+			err = mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle VirtualServer for policy")
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileMultiplex).To(Equal(&as3ResourcePointer{Use: "crd_vs_172.13.14.15_multiplex_profile"}))
+			Expect(sharedApp["crd_vs_172.13.14.15_multiplex_profile"]).To(Equal(&as3MultiplexProfile{
+				Class:      "Multiplex_Profile",
+				SourceMask: "255.255.255.0",
+			}), "Invalid Multiplex profile")
+
+			plc.Spec.Profiles.ProfileMultiplexSourceMask = "255.0.255.0"
+			err = mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).NotTo(BeNil(), "Non contiguous source mask should be rejected")
+			Expect(isValidIPv4Netmask("255.255.255.255")).To(BeTrue())
+			Expect(isValidIPv4Netmask("0.0.0.0")).To(BeTrue())
+			Expect(isValidIPv4Netmask("ffff:ffff::")).To(BeFalse())
+			Expect(isValidIPv4Netmask("10.1.0.0/24")).To(BeFalse())
+		})
+
+		It("Analytics Profile from Policy", func() {
+			mockCtlr := newMockController()
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.MetaData.Protocol = "http"
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Destination = "/test/172.13.14.15:80"
+			rsCfg.IRulesMap = make(IRulesMap)
+			plc := test.NewPolicy("plc1", "default", cisapiv1.PolicySpec{
+				Profiles: cisapiv1.ProfileSpec{
+					HTTP:             "/Common/http",
+					AnalyticsProfile: "/Common/analytics",
+					AnalyticsEnabled: true,
+				},
+			})
+			err := mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle VirtualServer for policy")
+			Expect(rsCfg.Virtual.ProfileAnalytics).To(Equal("/Common/analytics"))
+			Expect(rsCfg.Virtual.Analytics).To(BeNil(), "BIG-IP analytics profile should take precedence")
+			Expect(rsCfg.Virtual.Profiles).To(ContainElement(ProfileRef{
+				Name: "/Common/http", Context: "http", BigIPProfile: true,
+			}))
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileAnalytics).To(Equal(&as3ResourcePointer{BigIP: "/Common/analytics"}))
+			Expect(svc.ProfileHTTP).To(Equal(&as3ResourcePointer{BigIP: "/Common/http"}))
+
+			rsCfg.Virtual.Profiles = nil
+			rsCfg.Virtual.ProfileAnalytics = ""
+			plc.Spec.Profiles.AnalyticsProfile = ""
+			plc.Spec.Profiles.AnalyticsLoggingDestination = "/Common/dos-bigiq-logging"
+			err = mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle VirtualServer for policy")
+			Expect(rsCfg.Virtual.Analytics).To(Equal(&Analytics{LoggingDestination: "/Common/dos-bigiq-logging"}))
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileAnalytics).To(Equal(&as3ResourcePointer{Use: "crd_vs_172.13.14.15_analytics_profile"}))
+			Expect(sharedApp["crd_vs_172.13.14.15_analytics_profile"]).To(Equal(&as3AnalyticsProfile{
+				Class:                         "Analytics_Profile",
+				CollectedStatsExternalLogging: true,
+				ExternalLoggingPublisher:      &as3ResourcePointer{BigIP: "/Common/dos-bigiq-logging"},
+			}), "Invalid Analytics profile")
+		})
+
+		It("Client certificate authentication for Route Group", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.ClientCACert = "-----BEGIN CERTIFICATE-----\nclient-ca\n-----END CERTIFICATE-----"
+			rsCfg.customProfiles = map[SecretKey]CustomProfile{
+				{Name: "route1-clientssl", ResourceName: rsCfg.Virtual.Name}: {
+					Name:         "route1-clientssl",
+					Context:      CustomProfileClient,
+					Certificates: []certificate{{Cert: "cert", Key: "key"}},
+				},
+			}
+			sharedApp := as3Application{rsCfg.Virtual.Name: &as3Service{}}
+			processCustomProfilesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp)
+			tlsServer := sharedApp["crd_vs_172.13.14.15_tls_server"].(*as3TLSServer)
+			Expect(tlsServer.AuthenticationMode).To(Equal("require"))
+			Expect(tlsServer.AuthenticationTrustCA).To(Equal(&as3ResourcePointer{Use: "crd_vs_172.13.14.15_client_ca_bundle"}))
+			Expect(sharedApp["crd_vs_172.13.14.15_client_ca_bundle"]).To(Equal(&as3CABundle{
+				Class:  "CA_Bundle",
+				Bundle: rsCfg.Virtual.ClientCACert,
+			}), "Invalid client CA bundle")
+
+			// client certificates are not authenticated without client CA certificate
+			rsCfg.Virtual.ClientCACert = ""
+			sharedApp = as3Application{rsCfg.Virtual.Name: &as3Service{}}
+			processCustomProfilesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp)
+			tlsServer = sharedApp["crd_vs_172.13.14.15_tls_server"].(*as3TLSServer)
+			Expect(tlsServer.AuthenticationMode).To(BeEmpty())
+			Expect(sharedApp).NotTo(HaveKey("crd_vs_172.13.14.15_client_ca_bundle"))
+		})
+
+		It("OCSP stapling of client SSL profiles", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			profKey := SecretKey{Name: "vs-clientssl", ResourceName: rsCfg.Virtual.Name}
+			rsCfg.customProfiles = map[SecretKey]CustomProfile{
+				profKey: {
+					Name:             "vs-clientssl",
+					Context:          CustomProfileClient,
+					Certificates:     []certificate{{Cert: "cert", Key: "key"}},
+					OCSPResponderURL: "http://ocsp.example.com",
+					OCSPTimeout:      10,
+				},
+			}
+			sharedApp := as3Application{rsCfg.Virtual.Name: &as3Service{}}
+			processCustomProfilesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp)
+			tlsServer := sharedApp["crd_vs_172.13.14.15_tls_server"].(*as3TLSServer)
+			Expect(tlsServer.StaplerOCSPEnabled).To(BeTrue())
+			cert := sharedApp["vs-clientssl_0"].(*as3Certificate)
+			Expect(cert.StaplerOCSP).To(Equal(&as3ResourcePointer{Use: "vs-clientssl_ocsp"}))
+			Expect(sharedApp["vs-clientssl_ocsp"]).To(Equal(&as3CertificateValidatorOCSP{
+				Class:        "Certificate_Validator_OCSP",
+				ResponderURL: "http://ocsp.example.com",
+				Timeout:      10,
+			}), "Invalid OCSP validator")
+
+			// OCSP stapling is not configured without responder
+			prof := rsCfg.customProfiles[profKey]
+			prof.OCSPResponderURL = ""
+			rsCfg.customProfiles[profKey] = prof
+			sharedApp = as3Application{rsCfg.Virtual.Name: &as3Service{}}
+			processCustomProfilesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp)
+			Expect(sharedApp["crd_vs_172.13.14.15_tls_server"].(*as3TLSServer).StaplerOCSPEnabled).To(BeFalse())
+			Expect(sharedApp["vs-clientssl_0"].(*as3Certificate).StaplerOCSP).To(BeNil())
+			Expect(sharedApp).NotTo(HaveKey("vs-clientssl_ocsp"))
+		})
+
+		It("DNS64 Profile for VirtualServer", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Destination = "/test/172.13.14.15:8080"
+			rsCfg.Virtual.DNS64Prefix = "64:ff9b::/96"
+
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.Nat64Enabled).To(BeTrue(), "NAT64 not enabled on virtual")
+			Expect(sharedApp["crd_vs_172.13.14.15_dns64_profile"]).To(Equal(&as3DNSProfile{
+				Class:       "DNS_Profile",
+				DNS64Mode:   "secondary",
+				DNS64Prefix: "64:ff9b::",
+			}), "Invalid DNS64 profile")
+			Expect(svc.ProfileDNS).To(Equal(&as3ResourcePointer{Use: "crd_vs_172.13.14.15_dns64_profile"}),
+				"DNS64 profile not attached to virtual")
+
+			rsCfg.Virtual.DNS64Prefix = ""
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.Nat64Enabled).To(BeFalse())
+			Expect(svc.ProfileDNS).To(BeNil())
+			Expect(sharedApp).NotTo(HaveKey("crd_vs_172.13.14.15_dns64_profile"))
+		})
+		It("Cookie condition of policy rule", func() {
+			rl := &Rule{
+				Conditions: []*condition{{
+					Equals:     true,
+					HTTPCookie: true,
+					CookieName: "version",
+					Request:    true,
+					Values:     []string{"beta"},
+				}},
+			}
+			rulesData := &as3Rule{}
+			createRuleCondition(rl, rulesData, 80)
+			Expect(rulesData.Conditions).To(Equal([]*as3Condition{{
+				Type:  "httpCookie",
+				Name:  "version",
+				Event: "request",
+				All:   &as3PolicyCompareString{Values: []string{"beta"}, Operand: "equals"},
+			}}))
+		})
+		It("Resource labels for Virtual, Pool and Monitor", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.MetaData.baseResources = map[string]string{"default/vs1": VirtualServer, "default/vs2": VirtualServer}
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Destination = "/test/172.13.14.15:8080"
+			rsCfg.Pools = Pools{Pool{Name: "pool1", Members: []PoolMember{mem1}}}
+			rsCfg.Monitors = []Monitor{{Name: "pool1_monitor", Type: "http", Interval: 5}}
+
+			sharedApp := as3Application{}
+			processResourcesForAS3(ResourceMap{"crd_vs_172.13.14.15": rsCfg}, sharedApp, false, "test")
+			agent.resourceLabels = map[string]string{"team": "web"}
+			agent.cisVersion = "2.11.0"
+			agent.processResourceLabelsForAS3(ResourceMap{"crd_vs_172.13.14.15": rsCfg}, sharedApp)
+
+			label := "cis-version=2.11.0,name=vs1+vs2,namespace=default,team=web"
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.Label).To(Equal(label))
+			Expect(svc.Remark).To(Equal(ResourceLabelRemark))
+			pool := sharedApp["pool1"].(*as3Pool)
+			Expect(pool.Label).To(Equal(label))
+			Expect(pool.Remark).To(Equal(ResourceLabelRemark))
+			monitor := sharedApp["pool1_monitor"].(*as3Monitor)
+			Expect(monitor.Label).To(Equal(label))
+			Expect(monitor.Remark).To(Equal(ResourceLabelRemark))
+
+			// label is truncated to the AS3 limit
+			agent.resourceLabels = map[string]string{"description": strings.Repeat("a", 64)}
+			Expect(len(agent.getResourceLabel(rsCfg))).To(Equal(as3LabelMaxLength))
+
+			Expect(IsValidResourceLabel("team")).To(BeTrue())
+			Expect(IsValidResourceLabel("team#1")).To(BeFalse())
+			Expect(IsValidResourceLabel("a=b")).To(BeFalse())
+		})
+		It("Draining pool members", func() {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.Destination = "/test/172.13.14.15:8080"
+			draining := PoolMember{Address: "1.2.3.6", Port: 8080, Session: DrainingMemberSession}
+			rsCfg.Pools = Pools{Pool{Name: "pool1", Members: []PoolMember{mem1, draining}}}
+
+			sharedApp := as3Application{}
+			processResourcesForAS3(ResourceMap{"crd_vs_172.13.14.15": rsCfg}, sharedApp, false, "test")
+			pool := sharedApp["pool1"].(*as3Pool)
+			Expect(pool.Members).To(HaveLen(2))
+			Expect(pool.Members[0].AdminState).To(BeEmpty())
+			Expect(pool.Members[1].AdminState).To(Equal("disable"), "Draining member should not accept new connections")
+		})
 	})
 
 	Describe("GTM Config", func() {
@@ -401,6 +812,101 @@ var _ = Describe("Backend Tests", func() {
 
 			Expect(sharedApp).To(HaveKey("pool1_monitor"))
 			Expect(sharedApp["pool1_monitor"].(as3GSLBMonitor).Class).To(Equal("GSLB_Monitor"))
+			Expect(adc).NotTo(HaveKey(gtmPartition), "Common partition should not be declared without listeners")
+		})
+
+		It("GTM Config with ordered pools", func() {
+			gtmConfig := GTMConfig{
+				DEFAULT_PARTITION: GTMPartitionConfig{
+					WideIPs: map[string]WideIP{
+						"test.com": {
+							DomainName: "test.com",
+							RecordType: "A",
+							LBMethod:   GlobalAvailabilityLBMethod,
+							Pools: []GSLBPool{
+								{Name: "pool_dc1", RecordType: "A", LBMethod: "round-robin", PriorityOrder: 1},
+								{Name: "pool_dc2", RecordType: "A", LBMethod: "round-robin", PriorityOrder: 2},
+								{Name: "pool_dc3", RecordType: "A", LBMethod: "round-robin", PriorityOrder: 3},
+							},
+						},
+					},
+				},
+			}
+			adc := agent.createAS3GTMConfigADC(ResourceConfigRequest{gtmConfig: gtmConfig}, as3ADC{})
+			data, err := json.Marshal(adc)
+			Expect(err).To(BeNil())
+			declaration := string(data)
+			Expect(declaration).To(ContainSubstring(
+				`"pools":[{"use":"pool_dc1"},{"use":"pool_dc2"},{"use":"pool_dc3"}]`),
+				"Order of pools should be preserved in the declaration")
+			Expect(declaration).To(ContainSubstring(`"poolLbMode":"global-availability"`))
+		})
+
+		It("GTM Config with CNAME Pool", func() {
+			gtmConfig := GTMConfig{
+				DEFAULT_PARTITION: GTMPartitionConfig{
+					WideIPs: map[string]WideIP{
+						"test.com": {
+							DomainName: "test.com",
+							RecordType: CNAMERecordType,
+							LBMethod:   "round-robin",
+							Pools: []GSLBPool{
+								{
+									Name:       "pool1",
+									RecordType: CNAMERecordType,
+									LBMethod:   "round-robin",
+									Members:    []string{"app.example.com"},
+								},
+							},
+						},
+					},
+				},
+			}
+			adc := agent.createAS3GTMConfigADC(ResourceConfigRequest{gtmConfig: gtmConfig}, as3ADC{})
+			sharedApp := adc[DEFAULT_PARTITION].(as3Tenant)[as3SharedApplication].(as3Application)
+			Expect(sharedApp["pool1"].(as3GSLBPool).RecordType).To(Equal(CNAMERecordType))
+			Expect(sharedApp["pool1"].(as3GSLBPool).Members).To(Equal([]interface{}{
+				as3GSLBPoolMemberCNAME{Enabled: true, DomainName: "app.example.com"},
+			}))
+		})
+
+		It("GTM Config with DNS Listener", func() {
+			gtmConfig := GTMConfig{
+				DEFAULT_PARTITION: GTMPartitionConfig{
+					WideIPs: map[string]WideIP{
+						"test.com": {
+							DomainName: "test.com",
+							RecordType: "A",
+							LBMethod:   "round-robin",
+							DNSListener: &DNSListener{
+								Address:    "10.1.1.53",
+								Port:       DefaultDNSListenerPort,
+								UDPProfile: DefaultDNSListenerUDPProfile,
+							},
+						},
+					},
+				},
+			}
+			adc := agent.createAS3GTMConfigADC(ResourceConfigRequest{gtmConfig: gtmConfig}, as3ADC{})
+
+			Expect(adc).To(HaveKey(gtmPartition))
+			sharedApp := adc[gtmPartition].(as3Tenant)[as3SharedApplication].(as3Application)
+			Expect(sharedApp).To(HaveKey("dns_listener_10_1_1_53_53"))
+			listener := sharedApp["dns_listener_10_1_1_53_53"].(*as3Service)
+			Expect(listener.Class).To(Equal("Service_UDP"))
+			Expect(listener.VirtualAddresses).To(Equal([]as3MultiTypeParam{"10.1.1.53"}))
+			Expect(listener.VirtualPort).To(Equal(53))
+			Expect(listener.ProfileDNS).To(Equal(&as3ResourcePointer{BigIP: "/Common/dns"}))
+			Expect(listener.ProfileUDP).To(Equal(&as3ResourcePointer{BigIP: DefaultDNSListenerUDPProfile}))
+
+			// Common partition is declared without listeners to remove the posted listeners
+			agent.cachedTenantDeclMap = map[string]as3Tenant{gtmPartition: adc[gtmPartition].(as3Tenant)}
+			wideIP := gtmConfig[DEFAULT_PARTITION].WideIPs["test.com"]
+			wideIP.DNSListener = nil
+			gtmConfig[DEFAULT_PARTITION].WideIPs["test.com"] = wideIP
+			adc = agent.createAS3GTMConfigADC(ResourceConfigRequest{gtmConfig: gtmConfig}, as3ADC{})
+			Expect(adc).To(HaveKey(gtmPartition))
+			Expect(adc[gtmPartition].(as3Tenant)[as3SharedApplication]).To(HaveLen(2))
 		})
 	})
 
@@ -477,6 +983,54 @@ var _ = Describe("Backend Tests", func() {
 			agent.Stop()
 
 		})
+		It("Configured AS3 schema version", func() {
+			var agentParams AgentParams
+			agentParams.EnableIPV6 = true
+			agentParams.Partition = "test"
+			agentParams.VXLANName = "vxlan500"
+			agentParams.PostParams.BIGIPURL = "http://" + server.Addr()
+			agentParams.PostParams.AS3SchemaVersion = "3.36.0"
+			agent := NewAgent(agentParams)
+			Expect(agent.AS3VersionInfo.as3SchemaVersion).To(Equal("3.36.0"))
+
+			decl := agent.createAS3Declaration(map[string]as3Tenant{})
+			var as3Config map[string]interface{}
+			Expect(json.Unmarshal([]byte(decl), &as3Config)).To(BeNil())
+			adc := as3Config["declaration"].(map[string]interface{})
+			Expect(adc["schemaVersion"]).To(Equal("3.36.0"), "Invalid schemaVersion in AS3 declaration")
+			agent.Stop()
+		})
+		It("Validates AS3 schema version", func() {
+			Expect(IsValidAS3SchemaVersion(DefaultAS3SchemaVersion)).To(BeTrue())
+			Expect(IsValidAS3SchemaVersion("3.41.0")).To(BeTrue())
+			Expect(IsValidAS3SchemaVersion("3.10.0")).To(BeFalse())
+			Expect(IsValidAS3SchemaVersion("latest")).To(BeFalse())
+		})
+		It("Throttles configs exceeding post rate limit", func() {
+			agent := &Agent{
+				postChan:     make(chan ResourceConfigRequest, 1),
+				postLimiter:  rate.NewLimiter(rate.Limit(0.001), 0),
+				throttleChan: make(chan ResourceConfigRequest, 2),
+			}
+			for id := 1; id <= 3; id++ {
+				agent.PostConfig(ResourceConfigRequest{reqId: id})
+			}
+			// oldest config is dropped when the buffer is full
+			Expect(len(agent.throttleChan)).To(Equal(2))
+			Expect(len(agent.postChan)).To(Equal(0), "Config posted exceeding the rate limit")
+
+			agent.postLimiter.SetLimit(rate.Inf)
+			go agent.throttleWorker()
+			Eventually(func() int {
+				select {
+				case rsConfig := <-agent.postChan:
+					return rsConfig.reqId
+				default:
+					return 0
+				}
+			}).Should(Equal(3), "Latest config is not posted")
+			close(agent.throttleChan)
+		})
 	})
 
 })
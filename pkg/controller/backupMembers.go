@@ -0,0 +1,77 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// BackupMemberState is the value of PoolMemberStateAnnotation marking the members of Service as backup
+const BackupMemberState = "backup"
+
+// isBackupService checks whether the members of Service are backup members
+func isBackupService(svc *v1.Service) bool {
+	state, ok := svc.Annotations[PoolMemberStateAnnotation]
+	if !ok {
+		return false
+	}
+	if state != BackupMemberState {
+		log.Errorf("Invalid %v annotation %v for service %v/%v, allowed value is %v",
+			PoolMemberStateAnnotation, state, svc.Namespace, svc.Name, BackupMemberState)
+		return false
+	}
+	return true
+}
+
+// markBackupMembers returns the members marked as backup members
+func markBackupMembers(members []PoolMember) []PoolMember {
+	// copy the members as they are shared with pool member cache
+	backupMembers := make([]PoolMember, len(members))
+	for i, member := range members {
+		backupMembers[i] = member
+		backupMembers[i].Backup = true
+	}
+	return backupMembers
+}
+
+// prioritizeBackupMembers sets the lowest priority group to the backup members of pool, so that
+// BIG-IP sends the traffic to them only when the primary members are not available.
+// Priority groups of primary members are raised to keep their order above the backup members.
+func prioritizeBackupMembers(pool *Pool) {
+	hasBackup := false
+	for _, member := range pool.Members {
+		if member.Backup {
+			hasBackup = true
+			break
+		}
+	}
+	if !hasBackup {
+		return
+	}
+	// copy the members as they are shared with pool member cache
+	members := make([]PoolMember, len(pool.Members))
+	for i, member := range pool.Members {
+		members[i] = member
+		if member.Backup {
+			members[i].PriorityGroup = 0
+		} else {
+			members[i].PriorityGroup++
+		}
+	}
+	pool.Members = members
+}
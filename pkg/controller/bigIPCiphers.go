@@ -0,0 +1,94 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+)
+
+// DefaultCipherRefreshInterval is the interval to refresh the ciphers of BIG-IP
+const DefaultCipherRefreshInterval = time.Hour
+
+// update replaces the cached ciphers and cipher groups
+func (bc *BigIPCiphers) update(ciphers, cipherGroups map[string]bool) {
+	bc.Lock()
+	defer bc.Unlock()
+	bc.ciphers = ciphers
+	bc.cipherGroups = cipherGroups
+}
+
+// validate checks whether the cipher and cipher group are supported by BIG-IP.
+// Validation is skipped until the ciphers are fetched from BIG-IP.
+func (bc *BigIPCiphers) validate(cipher, cipherGroup string) error {
+	bc.RLock()
+	defer bc.RUnlock()
+	if bc.ciphers == nil && bc.cipherGroups == nil {
+		return nil
+	}
+	if cipher != "" && !bc.ciphers[cipher] {
+		return fmt.Errorf("cipher %q is not supported by BIG-IP", cipher)
+	}
+	if cipherGroup != "" && !bc.cipherGroups[cipherGroup] {
+		return fmt.Errorf("cipher group %q does not exist on BIG-IP", cipherGroup)
+	}
+	return nil
+}
+
+// refreshBigIPCiphers fetches the ciphers of client SSL profiles from BIG-IP
+func (ctlr *Controller) refreshBigIPCiphers() {
+	if ctlr.Agent == nil || ctlr.Agent.PostManager == nil {
+		return
+	}
+	ciphers, cipherGroups, err := ctlr.Agent.getClientSSLCiphers()
+	if err != nil {
+		log.Errorf("Unable to get the ciphers of BIG-IP: %v", err)
+		return
+	}
+	ctlr.bigIPCiphers.update(ciphers, cipherGroups)
+	log.Debugf("Fetched %v ciphers and %v cipher groups from BIG-IP", len(ciphers), len(cipherGroups))
+}
+
+// cipherRefreshWorker periodically refreshes the ciphers of BIG-IP
+func (ctlr *Controller) cipherRefreshWorker(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(ctlr.cipherRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctlr.refreshBigIPCiphers()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// validateTLSProfileCiphers validates the ciphers of TLSProfile of VirtualServer against BIG-IP
+func (ctlr *Controller) validateTLSProfileCiphers(crInf *CRInformer, vs *cisapiv1.VirtualServer) error {
+	if ctlr.bigIPCiphers == nil || vs.Spec.TLSProfileName == "" {
+		return nil
+	}
+	obj, found, _ := crInf.tlsInformer.GetIndexer().GetByKey(vs.Namespace + "/" + vs.Spec.TLSProfileName)
+	if !found {
+		return nil
+	}
+	tls := obj.(*cisapiv1.TLSProfile).Spec.TLS
+	return ctlr.bigIPCiphers.validate(tls.Cipher, tls.CipherGroup)
+}
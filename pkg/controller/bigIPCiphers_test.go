@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("BIG-IP Ciphers Tests", func() {
+	var mockCtlr *mockController
+	var server *httptest.Server
+	namespace := "default"
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/mgmt/tm/ltm/profile/client-ssl" {
+				fmt.Fprint(w, `{"items":[
+					{"name":"clientssl","ciphers":"DEFAULT","cipherGroup":"none"},
+					{"name":"clientssl-secure","ciphers":"none","cipherGroup":"/Common/f5-secure"}
+				]}`)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":404}`)
+		}))
+
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.Agent = newMockAgent(nil)
+		mockCtlr.Agent.PostManager = &PostManager{PostParams: PostParams{BIGIPURL: server.URL}}
+		mockCtlr.Agent.setupBIGIPRESTClient()
+		mockCtlr.bigIPCiphers = &BigIPCiphers{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Validates ciphers of TLSProfile against BIG-IP", func() {
+		Expect(mockCtlr.bigIPCiphers.validate("INVALID", "")).To(Succeed(),
+			"Validation should be skipped before ciphers are fetched")
+
+		mockCtlr.refreshBigIPCiphers()
+		Expect(mockCtlr.bigIPCiphers.validate("DEFAULT", "")).To(Succeed())
+		Expect(mockCtlr.bigIPCiphers.validate("", "/Common/f5-secure")).To(Succeed())
+		Expect(mockCtlr.bigIPCiphers.validate("none", "")).ToNot(Succeed())
+		Expect(mockCtlr.bigIPCiphers.validate("", "/Common/f5-missing")).ToNot(Succeed())
+
+		tlsProfile := test.NewTLSProfile("tls", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "clientssl-secret", Reference: "secret",
+				CipherGroup: "/Common/f5-secure"},
+		})
+		mockCtlr.addTLSProfile(tlsProfile)
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			TLSProfileName:       "tls",
+		})
+		_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), vs, metav1.CreateOptions{})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+		Expect(getTLSCipherOfTLSProfile(tlsProfile)).To(Equal(
+			TLSCipher{TLSVersion: string(TLSVerion1_3), CipherGroup: "/Common/f5-secure"}))
+
+		tlsProfile.Spec.TLS.CipherGroup = ""
+		tlsProfile.Spec.TLS.Cipher = "RC4"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse())
+		vs, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), vs.Name, metav1.GetOptions{})
+		Expect(vs.Status.StatusOk).To(Equal(InvalidCipher))
+	})
+})
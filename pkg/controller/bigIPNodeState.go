@@ -0,0 +1,131 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+)
+
+// DefaultNodeStateSyncInterval is the interval to sync the states of BIG-IP pool members to their pods
+const DefaultNodeStateSyncInterval = 30 * time.Second
+
+// nodeStateMember is a pool member whose BIG-IP state is propagated to its pod
+type nodeStateMember struct {
+	pool      string
+	namespace string
+	address   string
+}
+
+// nodeStateSyncWorker periodically enqueues the sync of the states of BIG-IP pool members
+func (ctlr *Controller) nodeStateSyncWorker(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(DefaultNodeStateSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctlr.resourceQueue.Add(&rqKey{kind: BigIPNodeState})
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// syncBigIPNodeStates collects the pod members of the pools and propagates their BIG-IP states
+// in the background, as the resources are not to be accessed outside the resource worker
+func (ctlr *Controller) syncBigIPNodeStates() {
+	if ctlr.Agent == nil || ctlr.Agent.PostManager == nil ||
+		ctlr.PoolMemberType == NodePort || ctlr.PoolMemberType == NodePortLocal {
+		return
+	}
+	members := make(map[string][]nodeStateMember)
+	for partition, partitionConfig := range ctlr.resources.ltmConfig {
+		for _, rsCfg := range partitionConfig.ResourceMap {
+			for _, pool := range rsCfg.Pools {
+				for _, member := range pool.Members {
+					members[partition] = append(members[partition], nodeStateMember{
+						pool:      "/" + partition + "/" + as3SharedApplication + "/" + pool.Name,
+						namespace: pool.ServiceNamespace,
+						address:   member.Address,
+					})
+				}
+			}
+		}
+	}
+	if len(members) > 0 {
+		go ctlr.propagateBigIPNodeStates(members)
+	}
+}
+
+// propagateBigIPNodeStates sets the health score of the pods of pool members from their states on BIG-IP,
+// the members available on BIG-IP score 100 and the others 0
+func (ctlr *Controller) propagateBigIPNodeStates(members map[string][]nodeStateMember) {
+	for partition, partitionMembers := range members {
+		states, err := ctlr.Agent.getPoolMemberStates(partition)
+		if err != nil {
+			log.Errorf("Unable to get the pool member states of partition %v: %v", partition, err)
+			continue
+		}
+		for _, member := range partitionMembers {
+			state, ok := states[member.pool][member.address]
+			if !ok {
+				continue
+			}
+			pod := ctlr.getPodForMemberIP(member.namespace, member.address)
+			if pod == nil {
+				continue
+			}
+			var score float64
+			if state == "up" || state == "unchecked" {
+				score = 100
+			}
+			_ = ctlr.propagateHealthScore(pod, score)
+		}
+	}
+}
+
+// getPoolMemberStates returns the states of the members of the pools of partition by their addresses
+func (postMgr *PostManager) getPoolMemberStates(partition string) (map[string]map[string]string, error) {
+	items, err := postMgr.getItems(postMgr.getPoolURL() + "?expandSubcollections=true&$filter=partition+eq+" + partition)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]map[string]string)
+	for _, item := range items {
+		fullPath, _ := item["fullPath"].(string)
+		if fullPath == "" {
+			continue
+		}
+		states[fullPath] = make(map[string]string)
+		membersRef, _ := item["membersReference"].(map[string]interface{})
+		memberItems, _ := membersRef["items"].([]interface{})
+		for _, memberItem := range memberItems {
+			member, ok := memberItem.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			address, _ := member["address"].(string)
+			state, _ := member["state"].(string)
+			// address is suffixed with the route domain
+			address = strings.Split(address, "%")[0]
+			states[fullPath][address] = state
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,191 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/resource"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DefaultCertReconcileInterval is the interval to reconcile the secrets of TLSProfiles
+const DefaultCertReconcileInterval = 10 * time.Minute
+
+// certVersionCache holds the resource versions of the secrets of the SSL profiles processed by the controller
+type certVersionCache struct {
+	sync.Mutex
+	versions map[string]string
+}
+
+func newCertVersionCache() *certVersionCache {
+	return &certVersionCache{versions: make(map[string]string)}
+}
+
+// set stores the resource version of the processed secret
+func (c *certVersionCache) set(secret *v1.Secret) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.versions[secret.Namespace+"/"+secret.Name] = secret.ResourceVersion
+}
+
+// refresh updates the resource version of the secret, if it is already processed
+func (c *certVersionCache) refresh(secret *v1.Secret) {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	key := secret.Namespace + "/" + secret.Name
+	if _, ok := c.versions[key]; ok {
+		c.versions[key] = secret.ResourceVersion
+	}
+}
+
+// prune removes the secrets other than the given ones
+func (c *certVersionCache) prune(secrets map[string]bool) {
+	c.Lock()
+	defer c.Unlock()
+	for key := range c.versions {
+		if !secrets[key] {
+			delete(c.versions, key)
+		}
+	}
+}
+
+// isRotated checks whether the processed secret is changed to another resource version
+func (c *certVersionCache) isRotated(secret *v1.Secret) bool {
+	c.Lock()
+	defer c.Unlock()
+	version, ok := c.versions[secret.Namespace+"/"+secret.Name]
+	return ok && version != secret.ResourceVersion
+}
+
+// certReconcileWorker periodically reconciles the secrets of TLSProfiles
+func (ctlr *Controller) certReconcileWorker(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(ctlr.certReconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctlr.reconcileCertSecrets()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// reconcileCertSecrets compares the resource versions of the secrets of SSL profiles in the informers
+// with the processed ones, and enqueues the secrets rotated without their events being processed.
+// Secrets deleted or no longer referenced by the SSL profiles are removed from the processed ones.
+func (ctlr *Controller) reconcileCertSecrets() {
+	namespaces := ctlr.getWatchingNamespaces()
+	if namespaces == nil {
+		return
+	}
+	inUse := ctlr.getCertSecretsInUse(namespaces)
+	live := make(map[string]bool)
+	for _, namespace := range namespaces {
+		comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
+		if !ok || comInf.secretsInformer == nil {
+			continue
+		}
+		objs, err := comInf.secretsInformer.GetIndexer().ByIndex("namespace", namespace)
+		if err != nil {
+			log.Errorf("Unable to get list of Secrets for namespace '%v': %v", namespace, err)
+			// secrets of the namespace are retained until they are listed
+			for key := range inUse {
+				if strings.HasPrefix(key, namespace+"/") {
+					live[key] = true
+				}
+			}
+			continue
+		}
+		for _, obj := range objs {
+			secret := obj.(*v1.Secret)
+			key := secret.Namespace + "/" + secret.Name
+			if !inUse[key] {
+				continue
+			}
+			live[key] = true
+			if ctlr.certVersions.isRotated(secret) {
+				log.Infof("Secret %v/%v of TLSProfile is rotated to version %v, reprocessing the virtuals",
+					secret.Namespace, secret.Name, secret.ResourceVersion)
+				ctlr.enqueueSecret(secret, Update)
+			}
+		}
+	}
+	ctlr.certVersions.prune(live)
+}
+
+// getCertSecretsInUse returns the namespace/name of the secrets referenced by the SSL profiles
+// of the VirtualServers, or of the Routes in OpenShift mode
+func (ctlr *Controller) getCertSecretsInUse(namespaces []string) map[string]bool {
+	secrets := make(map[string]bool)
+	addSecrets := func(namespace string, names ...string) {
+		for _, name := range names {
+			if name != "" {
+				secrets[namespace+"/"+name] = true
+			}
+		}
+	}
+	for _, namespace := range namespaces {
+		switch ctlr.mode {
+		case OpenShiftMode:
+			for _, route := range ctlr.getOrderedRoutes(namespace) {
+				for _, annotation := range []string{
+					resource.F5ClientSslProfileAnnotation,
+					resource.F5ServerSslProfileAnnotation,
+				} {
+					// profiles with partition are BIG-IP references
+					if name, ok := route.Annotations[annotation]; ok && !strings.Contains(name, "/") {
+						addSecrets(route.Namespace, name)
+					}
+				}
+			}
+		default:
+			crInf, ok := ctlr.getNamespacedCRInformer(namespace)
+			if !ok {
+				continue
+			}
+			for _, vs := range ctlr.getAllVirtualServers(namespace) {
+				if vs.Spec.TLSProfileName == "" {
+					continue
+				}
+				obj, found, _ := crInf.tlsInformer.GetIndexer().GetByKey(vs.Namespace + "/" + vs.Spec.TLSProfileName)
+				if !found {
+					continue
+				}
+				tls := obj.(*cisapiv1.TLSProfile).Spec.TLS
+				if tls.Reference != Secret {
+					continue
+				}
+				addSecrets(vs.Namespace, tls.ClientSSLs...)
+				addSecrets(vs.Namespace, tls.ServerSSLs...)
+				addSecrets(vs.Namespace, tls.ClientSSL, tls.ServerSSL)
+			}
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Certificate Rotation Tests", func() {
+	var mockCtlr *mockController
+	var secret *v1.Secret
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.Partition = "test"
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.TeemData = &teem.TeemsData{ResourceType: teem.ResourceTypes{
+			VirtualServer:   make(map[string]int),
+			TransportServer: make(map[string]int),
+			IngressLink:     make(map[string]int),
+		}}
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+		mockCtlr.certVersions = newCertVersionCache()
+		mockCtlr.skipCertHostCheck = true
+
+		secret = test.NewSecret("clientssl-secret", namespace, "cert-v1", "key-v1")
+		secret.ResourceVersion = "1"
+		mockCtlr.addSecret(secret)
+		mockCtlr.addTLSProfile(test.NewTLSProfile("tls", namespace, cisapiv1.TLSProfileSpec{
+			Hosts: []string{"foo.com"},
+			TLS:   cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "clientssl-secret", Reference: "secret"},
+		}))
+		mockCtlr.addVirtualServer(test.NewVirtualServer("vs", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "foo.com",
+			VirtualServerAddress: "10.1.1.1",
+			TLSProfileName:       "tls",
+		}))
+	})
+
+	AfterEach(func() {
+		mockCtlr.resourceQueue.ShutDown()
+	})
+
+	processQueue := func() {
+		for mockCtlr.resourceQueue.Len() > 0 {
+			key, _ := mockCtlr.resourceQueue.Get()
+			mockCtlr.processResourceKey(key)
+		}
+	}
+
+	// rotateSecret updates the secret in the informer without its event, as if the event is missed
+	rotateSecret := func(version, cert string) {
+		rotated := test.NewSecret("clientssl-secret", namespace, cert, "key-"+version)
+		rotated.ResourceVersion = version
+		comInf, _ := mockCtlr.getNamespacedCommonInformer(namespace)
+		Expect(comInf.secretsInformer.GetStore().Update(rotated)).To(Succeed())
+	}
+
+	It("Records the versions of the processed secrets", func() {
+		processQueue()
+		Expect(mockCtlr.certVersions.versions).To(HaveKeyWithValue(namespace+"/clientssl-secret", "1"))
+
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.resourceQueue.Len()).To(BeZero(), "Unchanged secret should not be enqueued")
+	})
+
+	It("Enqueues the secrets rotated without their events", func() {
+		processQueue()
+		rotateSecret("2", "cert-v2")
+
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(1), "Rotated secret should be enqueued")
+		key, _ := mockCtlr.resourceQueue.Get()
+		Expect(key.(*rqKey).kind).To(Equal(K8sSecret))
+		Expect(key.(*rqKey).rscName).To(Equal("clientssl-secret"))
+		mockCtlr.processResourceKey(key)
+		mockCtlr.resourceQueue.Done(key)
+
+		Expect(mockCtlr.certVersions.versions).To(HaveKeyWithValue(namespace+"/clientssl-secret", "2"))
+		rsCfg := mockCtlr.getVirtualServer("test", "crd_10_1_1_1_443")
+		Expect(rsCfg).NotTo(BeNil())
+		var certs []string
+		for _, prof := range rsCfg.customProfiles {
+			for _, cert := range prof.Certificates {
+				certs = append(certs, cert.Cert)
+			}
+		}
+		Expect(certs).To(ConsistOf("cert-v2"), "Profile should be updated with the rotated certificate")
+
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.resourceQueue.Len()).To(BeZero(), "Reprocessed secret should not be enqueued again")
+	})
+
+	It("Ignores the secrets not referenced by SSL profiles", func() {
+		processQueue()
+		other := test.NewSecret("other-secret", namespace, "cert", "key")
+		other.ResourceVersion = "5"
+		comInf, _ := mockCtlr.getNamespacedCommonInformer(namespace)
+		Expect(comInf.secretsInformer.GetStore().Add(other)).To(Succeed())
+
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.resourceQueue.Len()).To(BeZero())
+	})
+
+	It("Updates the version of the rotated secret not used by any virtual", func() {
+		processQueue()
+		// VirtualServer host no longer matches the TLSProfile, so it is not reprocessed with the secret
+		crInf, _ := mockCtlr.getNamespacedCRInformer(namespace)
+		Expect(crInf.tlsInformer.GetStore().Update(test.NewTLSProfile("tls", namespace, cisapiv1.TLSProfileSpec{
+			Hosts: []string{"bar.com"},
+			TLS:   cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "clientssl-secret", Reference: "secret"},
+		}))).To(Succeed())
+		rotateSecret("2", "cert-v2")
+
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(1), "Rotated secret should be enqueued")
+		processQueue()
+		Expect(mockCtlr.certVersions.versions).To(HaveKeyWithValue(namespace+"/clientssl-secret", "2"))
+
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.resourceQueue.Len()).To(BeZero(), "Processed secret should not be enqueued again")
+	})
+
+	It("Removes the secrets deleted or no longer referenced", func() {
+		processQueue()
+		Expect(mockCtlr.certVersions.versions).To(HaveLen(1))
+
+		// secret deleted
+		comInf, _ := mockCtlr.getNamespacedCommonInformer(namespace)
+		Expect(comInf.secretsInformer.GetStore().Delete(secret)).To(Succeed())
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.certVersions.versions).To(BeEmpty())
+
+		// secret no longer referenced by the deleted VirtualServer
+		Expect(comInf.secretsInformer.GetStore().Add(secret)).To(Succeed())
+		mockCtlr.certVersions.set(secret)
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.certVersions.versions).To(HaveLen(1))
+		crInf, _ := mockCtlr.getNamespacedCRInformer(namespace)
+		Expect(crInf.vsInformer.GetStore().Delete(test.NewVirtualServer("vs", namespace,
+			cisapiv1.VirtualServerSpec{}))).To(Succeed())
+		mockCtlr.reconcileCertSecrets()
+		Expect(mockCtlr.certVersions.versions).To(BeEmpty())
+	})
+})
@@ -0,0 +1,196 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DrainingMemberSession is the session of pool members removed from endpoints,
+// which serve the existing connections but do not accept new connections
+const DrainingMemberSession = "user-disabled"
+
+// getConnectionDrainingTimeout returns the connection draining timeout of service
+func getConnectionDrainingTimeout(svc *v1.Service) (time.Duration, bool) {
+	timeoutStr, ok := svc.Annotations[ConnectionDrainingTimeoutAnnotation]
+	if !ok {
+		return 0, false
+	}
+	timeout, err := strconv.Atoi(timeoutStr)
+	if err != nil || timeout <= 0 {
+		log.Errorf("Invalid %v annotation %v for service %v/%v",
+			ConnectionDrainingTimeoutAnnotation, timeoutStr, svc.Namespace, svc.Name)
+		return 0, false
+	}
+	return time.Duration(timeout) * time.Second, true
+}
+
+// updateDrainingMembers retains the members removed from endpoints of service as draining
+// members for the connection draining timeout, and schedules their removal after the timeout
+func (ctlr *Controller) updateDrainingMembers(svc *v1.Service, pmi *poolMembersInfo) {
+	svcKey := svc.Namespace + "/" + svc.Name
+	timeout, ok := getConnectionDrainingTimeout(svc)
+	if !ok {
+		delete(ctlr.resources.drainingMembers, svcKey)
+		return
+	}
+	if ctlr.resources.drainingMembers == nil {
+		ctlr.resources.drainingMembers = make(map[string]map[drainingMemberKey]drainingMember)
+	}
+	current := make(map[drainingMemberKey]struct{})
+	for portKey, members := range pmi.memberMap {
+		for _, member := range members {
+			current[newDrainingMemberKey(portKey, member)] = struct{}{}
+		}
+	}
+
+	now := time.Now()
+	draining := make(map[drainingMemberKey]drainingMember)
+	for key, dm := range ctlr.resources.drainingMembers[svcKey] {
+		// members added back to endpoints or drained for the timeout are not retained
+		if _, ok := current[key]; ok || !now.Before(dm.expiry) {
+			continue
+		}
+		draining[key] = dm
+	}
+	if prevPmi, ok := ctlr.resources.poolMemCache[svcKey]; ok {
+		for portKey, members := range prevPmi.memberMap {
+			for _, member := range members {
+				key := newDrainingMemberKey(portKey, member)
+				if _, ok := current[key]; ok || member.Session == DrainingMemberSession {
+					continue
+				}
+				log.Debugf("Draining pool member %v of service %v for %v", key.member, svcKey, timeout)
+				draining[key] = drainingMember{member: member, expiry: now.Add(timeout)}
+				// timer of member to remove it after the timeout
+				ctlr.enqueuePoolMembersUpdate(svc.Namespace, svc.Name, timeout)
+			}
+		}
+	}
+	if len(draining) == 0 {
+		delete(ctlr.resources.drainingMembers, svcKey)
+		return
+	}
+	ctlr.resources.drainingMembers[svcKey] = draining
+
+	// draining members are sorted to keep the order of pool members consistent
+	keys := make([]drainingMemberKey, 0, len(draining))
+	for key := range draining {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].portKey != keys[j].portKey {
+			return fmt.Sprintf("%v", keys[i].portKey) < fmt.Sprintf("%v", keys[j].portKey)
+		}
+		return keys[i].member < keys[j].member
+	})
+	for _, key := range keys {
+		member := draining[key].member
+		member.Session = DrainingMemberSession
+		pmi.memberMap[key.portKey] = append(pmi.memberMap[key.portKey], member)
+	}
+}
+
+func newDrainingMemberKey(portKey portRef, member PoolMember) drainingMemberKey {
+	return drainingMemberKey{portKey: portKey, member: fmt.Sprintf("%v:%v", member.Address, member.Port)}
+}
+
+// getServiceDrainPeriod returns the largest drain period of the pools referring the service
+func (ctlr *Controller) getServiceDrainPeriod(namespace, svcName string) time.Duration {
+	var drainPeriod int32
+	for _, partitionConfig := range ctlr.resources.ltmConfig {
+		for _, rsCfg := range partitionConfig.ResourceMap {
+			for _, pool := range rsCfg.Pools {
+				if pool.ServiceNamespace == namespace && pool.ServiceName == svcName && pool.DrainPeriod > drainPeriod {
+					drainPeriod = pool.DrainPeriod
+				}
+			}
+		}
+	}
+	return time.Duration(drainPeriod) * time.Second
+}
+
+// drainDeletedService retains the pool members of the deleted service as disabled members
+// for the drain period of its pools, and schedules the removal of members after the drain period.
+// It returns true if the pool members of service are retained
+func (ctlr *Controller) drainDeletedService(svc *v1.Service) bool {
+	svcKey := svc.Namespace + "/" + svc.Name
+	if expiry, ok := ctlr.resources.drainingServices[svcKey]; ok {
+		if time.Now().Before(expiry) {
+			return true
+		}
+		delete(ctlr.resources.drainingServices, svcKey)
+		log.Debugf("Drain period of deleted service %v expired", svcKey)
+		return false
+	}
+	// service is recreated before processing of the delete event
+	if ctlr.serviceExists(svc.Namespace, svc.Name) {
+		return true
+	}
+	drainPeriod := ctlr.getServiceDrainPeriod(svc.Namespace, svc.Name)
+	pmi, ok := ctlr.resources.poolMemCache[svcKey]
+	if drainPeriod <= 0 || !ok {
+		return false
+	}
+
+	drainingPmi := poolMembersInfo{
+		svcType:   pmi.svcType,
+		portSpec:  pmi.portSpec,
+		memberMap: make(map[portRef][]PoolMember),
+	}
+	for portKey, members := range pmi.memberMap {
+		for _, member := range members {
+			member.Session = DrainingMemberSession
+			drainingPmi.memberMap[portKey] = append(drainingPmi.memberMap[portKey], member)
+		}
+	}
+	ctlr.resources.poolMemCache[svcKey] = drainingPmi
+	delete(ctlr.resources.drainingMembers, svcKey)
+	if ctlr.resources.drainingServices == nil {
+		ctlr.resources.drainingServices = make(map[string]time.Time)
+	}
+	ctlr.resources.drainingServices[svcKey] = time.Now().Add(drainPeriod)
+	log.Debugf("Draining pool members of deleted service %v for %v", svcKey, drainPeriod)
+
+	key := &rqKey{
+		namespace: svc.Namespace,
+		kind:      Service,
+		rscName:   svc.Name,
+		rsc:       svc,
+		event:     Delete,
+	}
+	time.AfterFunc(drainPeriod, func() {
+		ctlr.resourceQueue.Add(key)
+	})
+	return true
+}
+
+// serviceExists checks whether the service is present in the informer of its namespace
+func (ctlr *Controller) serviceExists(namespace, svcName string) bool {
+	comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
+	if !ok || comInf.svcInformer == nil {
+		return false
+	}
+	_, found, _ := comInf.svcInformer.GetIndexer().GetByKey(namespace + "/" + svcName)
+	return found
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"time"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Service Draining Tests", func() {
+	var mockCtlr *mockController
+	var svc *v1.Service
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.Partition = "test"
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.TeemData = &teem.TeemsData{ResourceType: teem.ResourceTypes{
+			VirtualServer:   make(map[string]int),
+			TransportServer: make(map[string]int),
+			IngressLink:     make(map[string]int),
+		}}
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+
+		svc = test.NewService("svc", "1", namespace, v1.ServiceTypeClusterIP,
+			[]v1.ServicePort{{Name: "http", Port: 80}})
+		svc.Spec.ClusterIP = "None"
+		mockCtlr.addService(svc)
+		mockCtlr.addEndpoints(&v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: namespace},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: []v1.EndpointAddress{{IP: "10.244.1.1"}, {IP: "10.244.1.2"}},
+				Ports:     []v1.EndpointPort{{Name: "http", Port: 80}},
+			}},
+		})
+	})
+
+	AfterEach(func() {
+		mockCtlr.resourceQueue.ShutDown()
+	})
+
+	processQueue := func() {
+		for mockCtlr.resourceQueue.Len() > 0 {
+			key, _ := mockCtlr.resourceQueue.Get()
+			mockCtlr.processResourceKey(key)
+		}
+	}
+
+	addVirtualServer := func(drainPeriod int32) {
+		mockCtlr.addVirtualServer(test.NewVirtualServer("vs", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "foo.com",
+			VirtualServerAddress: "10.1.1.1",
+			Pools: []cisapiv1.Pool{{
+				Path:        "/",
+				Service:     "svc",
+				ServicePort: intstr.FromInt(80),
+				DrainPeriod: drainPeriod,
+			}},
+		}))
+		processQueue()
+	}
+
+	poolMembers := func() []PoolMember {
+		rsCfg := mockCtlr.getVirtualServer(mockCtlr.Partition, "crd_10_1_1_1_80")
+		Expect(rsCfg).NotTo(BeNil())
+		return rsCfg.Pools[0].Members
+	}
+
+	It("Drains the pool members of deleted service for the drain period", func() {
+		addVirtualServer(1)
+		Expect(poolMembers()).To(HaveLen(2))
+		for _, member := range poolMembers() {
+			Expect(member.Session).To(Equal("user-enabled"))
+		}
+
+		mockCtlr.deleteService(svc)
+		processQueue()
+		Expect(poolMembers()).To(HaveLen(2), "Members of deleted service should be retained")
+		for _, member := range poolMembers() {
+			Expect(member.Session).To(Equal(DrainingMemberSession))
+		}
+		Expect(mockCtlr.resources.drainingServices).To(HaveKey("default/svc"))
+
+		// service key is enqueued after the drain period
+		Eventually(mockCtlr.resourceQueue.Len, 3*time.Second).Should(Equal(1))
+		processQueue()
+		Expect(poolMembers()).To(BeEmpty(), "Members should be removed after the drain period")
+		Expect(mockCtlr.resources.drainingServices).NotTo(HaveKey("default/svc"))
+		Expect(mockCtlr.resources.poolMemCache).NotTo(HaveKey("default/svc"))
+	})
+
+	It("Removes the pool members of deleted service without drain period", func() {
+		addVirtualServer(0)
+		Expect(poolMembers()).To(HaveLen(2))
+
+		mockCtlr.deleteService(svc)
+		processQueue()
+		Expect(poolMembers()).To(BeEmpty())
+		Expect(mockCtlr.resources.drainingServices).To(BeEmpty())
+	})
+
+	It("Retains the members of service recreated within the drain period", func() {
+		addVirtualServer(1)
+		mockCtlr.deleteService(svc)
+		processQueue()
+		Expect(mockCtlr.resources.drainingServices).To(HaveKey("default/svc"))
+
+		mockCtlr.addService(svc)
+		processQueue()
+		Expect(mockCtlr.resources.drainingServices).NotTo(HaveKey("default/svc"))
+		for _, member := range poolMembers() {
+			Expect(member.Session).To(Equal("user-enabled"))
+		}
+
+		Eventually(mockCtlr.resourceQueue.Len, 3*time.Second).Should(Equal(1))
+		processQueue()
+		Expect(poolMembers()).To(HaveLen(2), "Members of recreated service should not be removed")
+	})
+})
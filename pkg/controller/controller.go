@@ -19,6 +19,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"strings"
 	"time"
@@ -27,7 +28,9 @@ import (
 	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
 	"github.com/F5Networks/f5-ipam-controller/pkg/ipammachinery"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned"
+	cisscheme "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/scheme"
 	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/metrics"
 	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
 	routeclient "github.com/openshift/client-go/route/clientset/versioned/typed/route/v1"
 	v1 "k8s.io/api/core/v1"
@@ -37,7 +40,9 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
 
@@ -72,6 +77,10 @@ const (
 	ConfigMap = "ConfigMap"
 	// Route is OpenShift Route
 	Route = "Route"
+	// Resync re-posts the current configuration to BIG-IP
+	Resync = "Resync"
+	// BigIPNodeState syncs the states of BIG-IP pool members to their pods
+	BigIPNodeState = "BigIPNodeState"
 
 	NodePort = "nodeport"
 
@@ -90,10 +99,66 @@ const (
 	TLSAllowInsecure    = "allow"
 	TLSNoInsecure       = "none"
 
+	// Health monitor types
+	GRPCMonitorType     = "grpc"
+	ExternalMonitorType = "external"
+
+	// TopologyLBMethod is the GTM load balance method based on topology records
+	TopologyLBMethod = "topology"
+	// GlobalAvailabilityLBMethod is the GTM load balance method sending the requests to the first available pool
+	GlobalAvailabilityLBMethod = "global-availability"
+	// FastL4Profile is the BIG-IP profile used for hardware acceleration of TransportServers
+	FastL4Profile = "/Common/fastL4"
+	// Hardware acceleration offload modes of Policy
+	OffloadModeAuto  = "auto"
+	OffloadModeNone  = "none"
+	OffloadModeForce = "force"
+	// CNAMERecordType is the DNS record type of GSLB pools with domain names as members
+	CNAMERecordType = "CNAME"
+	// DefaultDNSListenerPort is the port of DNS listener of ExternalDNS
+	DefaultDNSListenerPort = 53
+	// DefaultDNSListenerUDPProfile is the UDP profile of DNS listener of ExternalDNS
+	DefaultDNSListenerUDPProfile = "/Common/udp_gtm_dns"
+
 	LBServiceIPAMLabelAnnotation  = "cis.f5.com/ipamLabel"
 	HealthMonitorAnnotation       = "cis.f5.com/health"
 	LBServicePolicyNameAnnotation = "cis.f5.com/policyName"
 	LegacyHealthMonitorAnnotation = "virtual-server.f5.com/health"
+	RollbackToAnnotation          = "cis.f5.com/rollback-to"
+	TargetPortOverrideAnnotation  = "cis.f5.com/target-port-override"
+	// ContentSecurityPolicyAnnotation sets the Content-Security-Policy header in responses of VirtualServer
+	ContentSecurityPolicyAnnotation = "cis.f5.com/content-security-policy"
+	// ConnectionDrainingTimeoutAnnotation sets the time in seconds for which the members removed
+	// from endpoints of Service are retained to drain the existing connections
+	ConnectionDrainingTimeoutAnnotation = "cis.f5.com/connection-draining-timeout"
+	// PoolMemberStateAnnotation marks the members of Service as backup members of pools
+	PoolMemberStateAnnotation = "cis.f5.com/pool-member-state"
+	// PartitionAnnotation overrides the BIG-IP partition of Routes in the annotated Namespace
+	// and of the annotated VirtualServers and TransportServers
+	PartitionAnnotation = "cis.f5.com/bigip-partition"
+
+	// Route health monitor override annotations
+	HealthMonitorTypeAnnotation     = "cis.f5.com/health-monitor-type"
+	HealthMonitorIntervalAnnotation = "cis.f5.com/health-monitor-interval"
+	HealthMonitorTimeoutAnnotation  = "cis.f5.com/health-monitor-timeout"
+
+	// BigIPMemberHealthCondition is the pod condition carrying BIG-IP pool member health score
+	BigIPMemberHealthCondition v1.PodConditionType = "f5.io/bigip-member-health"
+
+	// AddressNotAllowed is the status of virtuals with address outside the allowed CIDRs
+	AddressNotAllowed = "AddressNotAllowed"
+	// InvalidCipher is the status of virtuals with TLSProfile ciphers not supported by BIG-IP
+	InvalidCipher = "InvalidCipher"
+	// QuotaExceeded is the status of virtuals exceeding the quota of their namespace
+	QuotaExceeded = "QuotaExceeded"
+	// QuotaUnavailable is the status of virtuals rejected as the quota of their namespace can not be read
+	QuotaUnavailable = "QuotaUnavailable"
+	// DeclarationTooLarge is the status of virtuals whose AS3 declaration exceeds the size limit
+	DeclarationTooLarge = "DeclarationTooLarge"
+	// InvalidIPAMLabel is the status of virtuals with IPAM label not served by the IPAM controller
+	InvalidIPAMLabel = "InvalidIPAMLabel"
+	// AddressConflict is the status of virtuals whose address and port is used by a virtual server of other resources
+	AddressConflict = "AddressConflict"
 
 	//Antrea NodePortLocal support
 	NPLPodAnnotation = "nodeportlocal.antrea.io"
@@ -107,32 +172,97 @@ const (
 	as3Version        = 3.41
 	defaultAS3Version = "3.41.0"
 	defaultAS3Build   = "1"
+	// DefaultAS3SchemaVersion is the schemaVersion used in AS3 declaration unless configured
+	DefaultAS3SchemaVersion = "3.36.0"
 )
 
+// supportedAS3SchemaVersions is the list of AS3 schema versions validated with CIS
+var supportedAS3SchemaVersions = []string{
+	"3.18.0", "3.19.0", "3.20.0", "3.21.0", "3.22.0", "3.23.0", "3.24.0", "3.25.0", "3.26.0", "3.27.0",
+	"3.28.0", "3.29.0", "3.30.0", "3.31.0", "3.32.0", "3.33.0", "3.34.0", "3.35.0", "3.36.0", "3.37.0",
+	"3.38.0", "3.39.0", "3.40.0", "3.41.0",
+}
+
+// IsValidAS3SchemaVersion checks whether the AS3 schema version is validated with CIS
+func IsValidAS3SchemaVersion(version string) bool {
+	for _, v := range supportedAS3SchemaVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 // NewController creates a new Controller Instance.
 func NewController(params Params) *Controller {
 
 	ctlr := &Controller{
-		namespaces:         make(map[string]bool),
-		resources:          NewResourceStore(),
-		Agent:              params.Agent,
-		PoolMemberType:     params.PoolMemberType,
-		UseNodeInternal:    params.UseNodeInternal,
-		Partition:          params.Partition,
-		initState:          true,
-		dgPath:             strings.Join([]string{DEFAULT_PARTITION, "Shared"}, "/"),
-		shareNodes:         params.ShareNodes,
-		eventNotifier:      apm.NewEventNotifier(nil),
-		defaultRouteDomain: params.DefaultRouteDomain,
-		mode:               params.Mode,
-		namespaceLabel:     params.NamespaceLabel,
-		nodeLabelSelector:  params.NodeLabelSelector,
-		vxlanName:          params.VXLANName,
-		vxlanMode:          params.VXLANMode,
+		namespaces:            make(map[string]bool),
+		resources:             NewResourceStore(),
+		Agent:                 params.Agent,
+		PoolMemberType:        params.PoolMemberType,
+		UseNodeInternal:       params.UseNodeInternal,
+		Partition:             params.Partition,
+		initState:             true,
+		dgPath:                strings.Join([]string{DEFAULT_PARTITION, "Shared"}, "/"),
+		shareNodes:            params.ShareNodes,
+		eventNotifier:         apm.NewEventNotifier(nil),
+		defaultRouteDomain:    params.DefaultRouteDomain,
+		mode:                  params.Mode,
+		namespaceLabel:        params.NamespaceLabel,
+		nodeLabelSelector:     params.NodeLabelSelector,
+		vxlanName:             params.VXLANName,
+		vxlanMode:             params.VXLANMode,
+		excludeTaintedNodes:   params.ExcludeTaintedNodes,
+		enableRollback:        params.EnableRollback,
+		grpcMonitorScriptPath: params.GRPCMonitorScriptPath,
+		shutdownTimeout:       params.ShutdownTimeout,
+		resyncInterval:        params.ResyncInterval,
+		topologyAwareRouting:  params.TopologyAwareRouting,
+		priorityGroupLabel:    params.PriorityGroupLabel,
+		credentialSecrets:     params.BigIPCredentialSecrets,
+		namespaceRouteDomains: params.NamespaceRouteDomains,
+		ipamLabelNamespaces:   params.IPAMLabelNamespaces,
+		resourceQueueDrained:  make(chan struct{}),
+		protectedPartitions:   make(map[string]bool),
+		minPartitionsPerPost:  params.MinPartitionsPerPost,
+		cipherRefreshInterval: params.CipherRefreshInterval,
+		quotaCMKey:            params.QuotaCM,
+		certValidationTimeout: params.CertValidationTimeout,
+		skipCertHostCheck:     params.SkipCertHostCheck,
+	}
+
+	ctlr.controllerIdentifier = params.ControllerIdentifier
+	ctlr.federationCMKey = params.IngressLinkFederationCM
+	ctlr.as3ApplicationLabel = params.AS3ApplicationLabel
+	if params.AutoScaleMonitorInterval {
+		ctlr.monitorIntervalPoolSizeBase = params.MonitorPoolSizeBase
+	}
+	ctlr.retryWarningThreshold = params.RetryWarningThreshold
+	ctlr.maxRetries = params.MaxRetries
+	ctlr.dryRun = params.DryRun
+	ctlr.nodeStateSync = params.SyncBigIPNodeState
+	if params.CertReconcileInterval > 0 {
+		ctlr.certVersions = newCertVersionCache()
+		ctlr.certReconcileInterval = params.CertReconcileInterval
+	}
+	if params.RespectNetworkPolicies {
+		if _, cidr, err := net.ParseCIDR(params.BigIPPodCIDR); err != nil {
+			log.Errorf("Invalid BIG-IP pod CIDR %v: %v", params.BigIPPodCIDR, err)
+		} else {
+			ctlr.bigipPodCIDR = cidr
+		}
+	}
+	if ctlr.controllerIdentifier != "" {
+		ctlr.eventNotifier.SetSourceComponent(
+			fmt.Sprintf("%v/%v", ctlr.eventNotifier.SourceComponent(), ctlr.controllerIdentifier))
 	}
 
 	log.Debug("Controller Created")
 
+	// Register custom resources to record events against them
+	utilruntime.Must(cisscheme.AddToScheme(scheme.Scheme))
+
 	ctlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
 		workqueue.DefaultControllerRateLimiter(), "nextgen-resource-controller")
 	ctlr.comInformers = make(map[string]*CommonInformer)
@@ -161,6 +291,78 @@ func NewController(params Params) *Controller {
 		log.Errorf("Failed to Setup Clients: %v", err)
 	}
 
+	if ctlr.federationCMKey != "" {
+		if inf, err := ctlr.newConfigMapInformer(ctlr.federationCMKey); err != nil {
+			log.Errorf("Failed to watch IngressLink federation ConfigMap: %v", err)
+		} else {
+			inf.AddEventHandler(&cache.ResourceEventHandlerFuncs{
+				AddFunc:    func(obj interface{}) { ctlr.handleFederationConfigMapUpdate(nil, obj) },
+				UpdateFunc: ctlr.handleFederationConfigMapUpdate,
+				DeleteFunc: func(obj interface{}) { ctlr.handleFederationConfigMapUpdate(obj, nil) },
+			})
+			ctlr.federationCMInformer = inf
+		}
+	}
+
+	if ctlr.enableRollback {
+		ctlr.declHistory = newDeclarationHistory(ctlr.kubeClient, params.CheckpointCM, params.RollbackHistoryDepth)
+		if ctlr.Agent != nil {
+			ctlr.Agent.declHistory = ctlr.declHistory
+		}
+	}
+
+	if ctlr.quotaCMKey != "" {
+		if inf, err := ctlr.newConfigMapInformer(ctlr.quotaCMKey); err != nil {
+			log.Errorf("Failed to watch quota ConfigMap: %v", err)
+		} else {
+			ctlr.quotaCMInformer = inf
+		}
+	}
+
+	if ctlr.mode == OpenShiftMode && ctlr.namespaceLabel == "" {
+		ctlr.nsPartitionInformer = ctlr.newNamespacePartitionInformer()
+	}
+
+	// partitions are created before posting any virtual to get the right route domains
+	if params.PartitionDefaultsCM != "" {
+		ctlr.createPartitionsWithDefaults(params.PartitionDefaultsCM)
+	}
+
+	if params.ValidateBigIPCiphers {
+		ctlr.bigIPCiphers = &BigIPCiphers{}
+		ctlr.refreshBigIPCiphers()
+	}
+
+	for _, partition := range params.ProtectedPartitions {
+		ctlr.protectedPartitions[partition] = true
+	}
+
+	ctlr.excludedNamespaces = make(map[string]bool)
+	for _, ns := range params.ExcludeNamespaces {
+		ctlr.excludedNamespaces[ns] = true
+	}
+
+	if ctlr.topologyAwareRouting {
+		ctlr.controllerZone = ctlr.getControllerZone(os.Getenv("HOSTNAME"), getControllerNamespace())
+		log.Infof("Topology zone of controller: %v", ctlr.controllerZone)
+	}
+
+	if ctlr.priorityGroupLabel != "" {
+		ctlr.controllerPriorityValue = ctlr.getControllerNodeLabels(
+			os.Getenv("HOSTNAME"), getControllerNamespace())[ctlr.priorityGroupLabel]
+		log.Infof("Pool members with node label %v=%v get the highest priority group",
+			ctlr.priorityGroupLabel, ctlr.controllerPriorityValue)
+	}
+
+	for _, cidr := range params.AllowedVirtualServerCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Errorf("Invalid CIDR %v in allowed virtual server CIDRs: %v", cidr, err)
+			continue
+		}
+		ctlr.allowedVSCIDRs = append(ctlr.allowedVSCIDRs, ipNet)
+	}
+
 	if ctlr.namespaceLabel == "" {
 		if len(params.Namespaces) == 0 {
 			ctlr.namespaces[""] = true
@@ -191,11 +393,13 @@ func NewController(params Params) *Controller {
 		ipamParams := ipammachinery.Params{
 			Config:        params.Config,
 			EventHandlers: ctlr.getEventHandlerForIPAM(),
-			Namespaces:    []string{IPAMNamespace},
+			Namespaces:    ctlr.ipamNamespaces(),
 		}
 
 		ipamClient := ipammachinery.NewIPAMClient(ipamParams)
 		ctlr.ipamCli = ipamClient
+		ctlr.ipamLabels = &IPAMLabelCache{}
+		ctlr.ipamLabelRefreshInterval = params.IPAMLabelRefreshInterval
 
 		ctlr.registerIPAMCRD()
 		time.Sleep(3 * time.Second)
@@ -206,6 +410,18 @@ func NewController(params Params) *Controller {
 
 	go ctlr.Start()
 
+	if ctlr.mode == CustomResourceMode && params.AdmissionWebhookAddress != "" {
+		go ctlr.startAdmissionWebhook(params.AdmissionWebhookAddress, params.AdmissionWebhookCert, params.AdmissionWebhookKey)
+	}
+
+	// metrics of the default registry are also served by the http-listen-address of Agent
+	metrics.RegisterMetrics()
+	if params.MetricsPort > 0 {
+		go func() {
+			log.Errorf("[CORE] Prometheus metrics server stopped: %v", metrics.ListenAndServe(params.MetricsPort))
+		}()
+	}
+
 	go ctlr.setOtherSDNType()
 
 	return ctlr
@@ -274,10 +490,22 @@ func (ctlr *Controller) createIPAMResource() error {
 	}
 
 	crName := frameIPAMResourceName()
+	ctlr.ipamCR = IPAMNamespace + "/" + crName
+	// IPAM CR is created in the namespace of each IPAM controller
+	for _, namespace := range ctlr.ipamNamespaces() {
+		if err := ctlr.createNamespacedIPAMResource(namespace, crName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createNamespacedIPAMResource creates the IPAM CR in the namespace, recreating the existing one
+func (ctlr *Controller) createNamespacedIPAMResource(namespace, crName string) error {
 	f5ipam := &ficV1.IPAM{
 		ObjectMeta: metaV1.ObjectMeta{
 			Name:      crName,
-			Namespace: IPAMNamespace,
+			Namespace: namespace,
 		},
 		Spec: ficV1.IPAMSpec{
 			HostSpecs: make([]*ficV1.HostSpec, 0),
@@ -286,7 +514,6 @@ func (ctlr *Controller) createIPAMResource() error {
 			IPStatus: make([]*ficV1.IPSpec, 0),
 		},
 	}
-	ctlr.ipamCR = IPAMNamespace + "/" + crName
 
 	ipamCR, err := ctlr.ipamCli.Create(f5ipam)
 	if err == nil {
@@ -295,7 +522,7 @@ func (ctlr *Controller) createIPAMResource() error {
 	}
 
 	if strings.Contains(err.Error(), "already exists") {
-		err = ctlr.ipamCli.Delete(IPAMNamespace, crName, metaV1.DeleteOptions{})
+		err = ctlr.ipamCli.Delete(namespace, crName, metaV1.DeleteOptions{})
 		if err != nil {
 			log.Debugf("[ipam] Delete failed. Error: %s", err.Error())
 		}
@@ -413,8 +640,49 @@ func (ctlr *Controller) Start() {
 
 	stopChan := make(chan struct{})
 
+	if ctlr.federationCMInformer != nil {
+		go ctlr.federationCMInformer.Run(stopChan)
+		if !cache.WaitForCacheSync(stopChan, ctlr.federationCMInformer.HasSynced) {
+			log.Error("Timed out waiting for IngressLink federation ConfigMap to sync")
+		}
+	}
+
+	if ctlr.quotaCMInformer != nil {
+		go ctlr.quotaCMInformer.Run(stopChan)
+		if !cache.WaitForCacheSync(stopChan, ctlr.quotaCMInformer.HasSynced) {
+			log.Error("Timed out waiting for quota ConfigMap to sync")
+		}
+	}
+
+	if ctlr.nsPartitionInformer != nil {
+		go ctlr.nsPartitionInformer.Run(stopChan)
+		if !cache.WaitForCacheSync(stopChan, ctlr.nsPartitionInformer.HasSynced) {
+			log.Error("Timed out waiting for Namespace partition annotations to sync")
+		}
+	}
+
 	go wait.Until(ctlr.nextGenResourceWorker, time.Second, stopChan)
 
+	if ctlr.resyncInterval > 0 {
+		go ctlr.resyncWorker(stopChan)
+	}
+
+	if ctlr.bigIPCiphers != nil && ctlr.cipherRefreshInterval > 0 {
+		go ctlr.cipherRefreshWorker(stopChan)
+	}
+
+	if ctlr.ipamLabels != nil && ctlr.ipamLabelRefreshInterval > 0 {
+		go ctlr.ipamLabelRefreshWorker(stopChan)
+	}
+
+	if ctlr.certVersions != nil {
+		go ctlr.certReconcileWorker(stopChan)
+	}
+
+	if ctlr.nodeStateSync {
+		go ctlr.nodeStateSyncWorker(stopChan)
+	}
+
 	<-stopChan
 	ctlr.Stop()
 }
@@ -442,8 +710,48 @@ func (ctlr *Controller) Stop() {
 		nsInf.stop()
 	}
 
+	// wait for the queued resources to be processed and posted to BIG-IP
+	ctlr.drainPendingUpdates()
+
 	ctlr.Agent.Stop()
 	if ctlr.ipamCli != nil {
 		ctlr.ipamCli.Stop()
 	}
 }
+
+// resyncWorker periodically queues a resync to re-post the current configuration,
+// so that the changes made on BIG-IP outside CIS are reverted
+func (ctlr *Controller) resyncWorker(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(ctlr.resyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctlr.resourceQueue.Add(&rqKey{kind: Resync})
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// DefaultShutdownTimeout is the time to wait for pending updates to be posted on shutdown
+const DefaultShutdownTimeout = 30 * time.Second
+
+// drainPendingUpdates shuts down the resource queue and waits till the queued
+// resources are processed and the pending configs are posted to BIG-IP
+func (ctlr *Controller) drainPendingUpdates() {
+	drained := make(chan struct{})
+	go func() {
+		// resource queue stops accepting new items, but processes the queued ones
+		ctlr.resourceQueue.ShutDown()
+		<-ctlr.resourceQueueDrained
+		ctlr.Agent.postWg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		log.Infof("Posted all pending updates to BIG-IP")
+	case <-time.After(ctlr.shutdownTimeout):
+		log.Warningf("Shutdown timeout of %v expired before posting pending updates to BIG-IP", ctlr.shutdownTimeout)
+	}
+}
@@ -1,12 +1,15 @@
 package controller
 
 import (
+	"time"
+
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
 )
 
 var _ = Describe("OtherSDNType", func() {
@@ -41,3 +44,53 @@ var _ = Describe("OtherSDNType", func() {
 		Expect(mockCtlr.TeemData.SDNType).To(Equal("other"), "SDNType should be other")
 	})
 })
+
+var _ = Describe("Graceful Shutdown", func() {
+	var mockCtlr *mockController
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.Agent = newMockAgent(nil)
+		mockCtlr.shutdownTimeout = 5 * time.Second
+		mockCtlr.resourceQueueDrained = make(chan struct{})
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+	})
+
+	It("Waits for queued resources and pending posts", func() {
+		mockCtlr.resourceQueue.Add(&rqKey{namespace: "default", kind: VirtualServer, rscName: "vs1"})
+		// simulate the resource worker posting the processed resources
+		go func() {
+			for {
+				key, quit := mockCtlr.resourceQueue.Get()
+				if quit {
+					close(mockCtlr.resourceQueueDrained)
+					return
+				}
+				mockCtlr.Agent.PostConfig(ResourceConfigRequest{reqId: 1})
+				mockCtlr.resourceQueue.Done(key)
+			}
+		}()
+		// simulate the agent worker posting to BIG-IP
+		go func() {
+			for range mockCtlr.Agent.postChan {
+				time.Sleep(100 * time.Millisecond)
+				mockCtlr.Agent.postWg.Done()
+			}
+		}()
+		start := time.Now()
+		mockCtlr.drainPendingUpdates()
+		Expect(time.Since(start)).To(BeNumerically("<", mockCtlr.shutdownTimeout))
+		Expect(mockCtlr.resourceQueue.ShuttingDown()).To(BeTrue())
+		Expect(mockCtlr.resourceQueue.Len()).To(BeZero())
+	})
+
+	It("Exits on shutdown timeout", func() {
+		mockCtlr.shutdownTimeout = 100 * time.Millisecond
+		close(mockCtlr.resourceQueueDrained)
+		// config is never posted as agent worker is not running
+		mockCtlr.Agent.PostConfig(ResourceConfigRequest{reqId: 1})
+		start := time.Now()
+		mockCtlr.drainPendingUpdates()
+		Expect(time.Since(start)).To(BeNumerically(">=", mockCtlr.shutdownTimeout))
+	})
+})
@@ -0,0 +1,121 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CookieEncryptKeySecretKey is the key of the Secret holding the passphrase to encrypt persistence cookies
+	CookieEncryptKeySecretKey = "key"
+	// as3PlaintextSecretProtected marks the ciphertext of an AS3 secret as base64 encoded plaintext
+	as3PlaintextSecretProtected = "eyJhbGciOiJkaXIiLCJlbmMiOiJub25lIn0="
+)
+
+// parseCookieEncryptKeyRef returns the namespace and name of the Secret referred by the
+// persistenceCookieEncryptKey of Policy, the namespace defaults to the namespace of Policy
+func parseCookieEncryptKeyRef(plc *cisapiv1.Policy) (string, string) {
+	ref := plc.Spec.PersistenceCookieEncryptKey
+	if nsName := strings.SplitN(ref, "/", 2); len(nsName) == 2 {
+		return nsName[0], nsName[1]
+	}
+	return plc.Namespace, ref
+}
+
+// getPersistenceCookie returns the cookie persistence of the Policy, nil when cookie encryption is not enabled
+func (ctlr *Controller) getPersistenceCookie(plc *cisapiv1.Policy) (*PersistenceCookie, error) {
+	if !plc.Spec.PersistenceCookieEncrypt {
+		return nil, nil
+	}
+	if plc.Spec.PersistenceCookieEncryptKey == "" {
+		return nil, fmt.Errorf("persistenceCookieEncryptKey is required to encrypt persistence cookies "+
+			"in Policy %v/%v", plc.Namespace, plc.Name)
+	}
+	namespace, secretName := parseCookieEncryptKeyRef(plc)
+	var secret *v1.Secret
+	if comInf, ok := ctlr.getNamespacedCommonInformer(namespace); ok && comInf.secretsInformer != nil {
+		obj, found, err := comInf.secretsInformer.GetIndexer().GetByKey(namespace + "/" + secretName)
+		if err == nil && found {
+			secret = obj.(*v1.Secret)
+		}
+	}
+	if secret == nil {
+		var err error
+		secret, err = ctlr.kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("secret %v/%v of Policy %v/%v not found: %v",
+				namespace, secretName, plc.Namespace, plc.Name, err)
+		}
+	}
+	key := string(secret.Data[CookieEncryptKeySecretKey])
+	if key == "" {
+		return nil, fmt.Errorf("secret %v/%v of Policy %v/%v must have %v",
+			namespace, secretName, plc.Namespace, plc.Name, CookieEncryptKeySecretKey)
+	}
+	return &PersistenceCookie{Encrypt: true, Passphrase: key}, nil
+}
+
+// getPoliciesForSecret returns the Policies encrypting persistence cookies with the key of the Secret
+func (ctlr *Controller) getPoliciesForSecret(secret *v1.Secret) []*cisapiv1.Policy {
+	var policies []*cisapiv1.Policy
+	for _, comInf := range ctlr.comInformers {
+		if comInf.plcInformer == nil {
+			continue
+		}
+		for _, obj := range comInf.plcInformer.GetIndexer().List() {
+			plc := obj.(*cisapiv1.Policy)
+			if !plc.Spec.PersistenceCookieEncrypt || plc.Spec.PersistenceCookieEncryptKey == "" {
+				continue
+			}
+			if namespace, name := parseCookieEncryptKeyRef(plc); namespace == secret.Namespace && name == secret.Name {
+				policies = append(policies, plc)
+			}
+		}
+	}
+	return policies
+}
+
+// Create AS3 Persist for the encrypted cookie persistence defined in Policy CRD
+func createCookiePersistDecl(cfg *ResourceConfig, svc *as3Service, sharedApp as3Application) {
+	cookie := cfg.Virtual.PersistenceCookie
+	persistName := fmt.Sprintf("%s_persist", cfg.Virtual.Name)
+	sharedApp[persistName] = &as3Persist{
+		Class:             "Persist",
+		PersistenceMethod: "cookie",
+		CookieMethod:      "insert",
+		Encrypt:           cookie.Encrypt,
+		CookieEncryption:  "required",
+		Passphrase: &as3Secret{
+			Ciphertext: base64.StdEncoding.EncodeToString([]byte(cookie.Passphrase)),
+			Protected:  as3PlaintextSecretProtected,
+		},
+	}
+	svc.PersistenceMethods = &[]as3MultiTypeParam{
+		as3MultiTypeParam(
+			as3ResourcePointer{
+				Use: persistName,
+			},
+		),
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Persistence Cookie Encryption Tests", func() {
+	var mockCtlr *mockController
+	var secret *v1.Secret
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		secret = &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cookie-key", Namespace: namespace},
+			Data:       map[string][]byte{CookieEncryptKeySecretKey: []byte("s3cr3t")},
+		}
+	})
+
+	It("Persistence cookie of Policy", func() {
+		plc := test.NewPolicy("plc", namespace, cisapiv1.PolicySpec{})
+		persistCookie, err := mockCtlr.getPersistenceCookie(plc)
+		Expect(err).To(BeNil())
+		Expect(persistCookie).To(BeNil(), "Cookie encryption not enabled")
+
+		plc.Spec.PersistenceCookieEncrypt = true
+		_, err = mockCtlr.getPersistenceCookie(plc)
+		Expect(err).NotTo(BeNil(), "Key is required")
+
+		plc.Spec.PersistenceCookieEncryptKey = "default/cookie-key"
+		_, err = mockCtlr.getPersistenceCookie(plc)
+		Expect(err).NotTo(BeNil(), "Secret not found")
+
+		mockCtlr.addSecret(secret)
+		persistCookie, err = mockCtlr.getPersistenceCookie(plc)
+		Expect(err).To(BeNil())
+		Expect(persistCookie).To(Equal(&PersistenceCookie{Encrypt: true, Passphrase: "s3cr3t"}))
+
+		// namespace of the Secret defaults to the namespace of Policy
+		plc.Spec.PersistenceCookieEncryptKey = "cookie-key"
+		persistCookie, err = mockCtlr.getPersistenceCookie(plc)
+		Expect(err).To(BeNil())
+		Expect(persistCookie.Passphrase).To(Equal("s3cr3t"))
+
+		secret.Data = map[string][]byte{}
+		_, err = mockCtlr.getPersistenceCookie(plc)
+		Expect(err).NotTo(BeNil(), "Secret without key")
+	})
+
+	It("Policies for Secret", func() {
+		plc1 := test.NewPolicy("plc1", namespace, cisapiv1.PolicySpec{
+			PersistenceCookieEncrypt:    true,
+			PersistenceCookieEncryptKey: "cookie-key",
+		})
+		plc2 := test.NewPolicy("plc2", namespace, cisapiv1.PolicySpec{
+			PersistenceCookieEncrypt:    true,
+			PersistenceCookieEncryptKey: "default/other-key",
+		})
+		plc3 := test.NewPolicy("plc3", namespace, cisapiv1.PolicySpec{
+			PersistenceCookieEncryptKey: "default/cookie-key",
+		})
+		mockCtlr.addPolicy(plc1)
+		mockCtlr.addPolicy(plc2)
+		mockCtlr.addPolicy(plc3)
+
+		policies := mockCtlr.getPoliciesForSecret(secret)
+		Expect(policies).To(HaveLen(1))
+		Expect(policies[0].Name).To(Equal("plc1"))
+	})
+
+	It("AS3 declaration of encrypted persistence cookie", func() {
+		rsCfg := &ResourceConfig{}
+		rsCfg.Virtual.Name = "crd_vs_172.13.14.15"
+		rsCfg.Virtual.PersistenceCookie = &PersistenceCookie{Encrypt: true, Passphrase: "s3cr3t"}
+		svc := &as3Service{}
+		sharedApp := as3Application{}
+		createCookiePersistDecl(rsCfg, svc, sharedApp)
+
+		Expect(sharedApp).To(HaveKey("crd_vs_172.13.14.15_persist"))
+		persist := sharedApp["crd_vs_172.13.14.15_persist"].(*as3Persist)
+		Expect(persist.PersistenceMethod).To(Equal("cookie"))
+		Expect(persist.CookieMethod).To(Equal("insert"))
+		Expect(persist.Encrypt).To(BeTrue())
+		Expect(persist.Passphrase.Ciphertext).To(Equal("czNjcjN0"))
+		Expect(*svc.PersistenceMethods).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,54 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+)
+
+// getCRPartition returns the BIG-IP partition of a custom resource, the partition
+// of controller is overridden with the BIG-IP partition annotation of resource
+func (ctlr *Controller) getCRPartition(annotations map[string]string) string {
+	if partition := annotations[PartitionAnnotation]; partition != "" {
+		return partition
+	}
+	return ctlr.Partition
+}
+
+// isValidPartition checks whether the partition can be posted as an AS3 Tenant
+func isValidPartition(partition string) bool {
+	return partition != "Common" && as3ApplicationRegex.MatchString(partition)
+}
+
+// filterVirtualServersOfPartition returns the virtuals in the BIG-IP partition, virtuals
+// sharing the address in other partitions are processed with their own partition
+func (ctlr *Controller) filterVirtualServersOfPartition(
+	virtuals []*cisapiv1.VirtualServer,
+	partition string,
+) []*cisapiv1.VirtualServer {
+	var filtered []*cisapiv1.VirtualServer
+	for _, vrt := range virtuals {
+		if vrtPartition := ctlr.getCRPartition(vrt.Annotations); vrtPartition != partition {
+			log.Debugf("Skipping VirtualServer %v/%v of partition %v while processing partition %v",
+				vrt.Namespace, vrt.Name, vrtPartition, partition)
+			continue
+		}
+		filtered = append(filtered, vrt)
+	}
+	return filtered
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Custom Resource Partition Tests", func() {
+	var mockCtlr *mockController
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.Partition = "test"
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.TeemData = &teem.TeemsData{ResourceType: teem.ResourceTypes{
+			VirtualServer:   make(map[string]int),
+			TransportServer: make(map[string]int),
+		}}
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+	})
+
+	AfterEach(func() {
+		mockCtlr.resourceQueue.ShutDown()
+	})
+
+	processQueue := func() {
+		for mockCtlr.resourceQueue.Len() > 0 {
+			key, _ := mockCtlr.resourceQueue.Get()
+			mockCtlr.processResourceKey(key)
+		}
+	}
+
+	newVirtualServer := func(name, address, partition string) *cisapiv1.VirtualServer {
+		vs := test.NewVirtualServer(name, namespace, cisapiv1.VirtualServerSpec{
+			Host:                 name + ".com",
+			VirtualServerAddress: address,
+			Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc", ServicePort: intstr.FromInt(80)}},
+		})
+		if partition != "" {
+			vs.Annotations = map[string]string{PartitionAnnotation: partition}
+		}
+		return vs
+	}
+
+	It("Processes VirtualServers in the partitions of their annotation", func() {
+		mockCtlr.addVirtualServer(newVirtualServer("dev", "10.1.1.1", "dev"))
+		mockCtlr.addVirtualServer(newVirtualServer("default", "10.1.1.2", ""))
+		processQueue()
+
+		Expect(mockCtlr.getVirtualServer("dev", "crd_10_1_1_1_80")).NotTo(BeNil())
+		Expect(mockCtlr.getVirtualServer("dev", "crd_10_1_1_2_80")).To(BeNil())
+		Expect(mockCtlr.getVirtualServer("test", "crd_10_1_1_2_80")).NotTo(BeNil())
+		Expect(mockCtlr.getVirtualServer("test", "crd_10_1_1_1_80")).To(BeNil())
+
+		// partitions are posted as separate AS3 tenants
+		config := ResourceConfigRequest{ltmConfig: mockCtlr.resources.getLTMConfigDeepCopy()}
+		adc := newMockAgent(nil).createAS3LTMConfigADC(config)
+		Expect(adc).To(HaveKey("dev"))
+		Expect(adc).To(HaveKey("test"))
+		Expect(adc["dev"].(as3Tenant)[as3SharedApplication]).To(HaveKey("crd_10_1_1_1_80"))
+		Expect(adc["dev"].(as3Tenant)[as3SharedApplication]).NotTo(HaveKey("crd_10_1_1_2_80"))
+	})
+
+	It("Moves VirtualServer to the partition of updated annotation", func() {
+		oldVS := newVirtualServer("dev", "10.1.1.1", "dev")
+		mockCtlr.addVirtualServer(oldVS)
+		processQueue()
+		Expect(mockCtlr.getVirtualServer("dev", "crd_10_1_1_1_80")).NotTo(BeNil())
+
+		newVS := newVirtualServer("dev", "10.1.1.1", "prod")
+		mockCtlr.updateVirtualServer(oldVS, newVS)
+		processQueue()
+		Expect(mockCtlr.getVirtualServer("dev", "crd_10_1_1_1_80")).To(BeNil(),
+			"Virtual should be removed from the previous partition")
+		Expect(mockCtlr.getVirtualServer("prod", "crd_10_1_1_1_80")).NotTo(BeNil())
+	})
+
+	It("Rejects VirtualServer sharing the address of a VirtualServer in another partition", func() {
+		foo := newVirtualServer("foo", "10.1.1.1", "dev")
+		mockCtlr.addVirtualServer(foo)
+		processQueue()
+		bar := newVirtualServer("bar", "10.1.1.1", "")
+		_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), bar, metav1.CreateOptions{})
+		mockCtlr.addVirtualServer(bar)
+		processQueue()
+
+		devVS := mockCtlr.getVirtualServer("dev", "crd_10_1_1_1_80")
+		Expect(devVS).NotTo(BeNil())
+		Expect(devVS.MetaData.baseResources).To(HaveLen(1))
+		Expect(devVS.MetaData.baseResources).To(HaveKey(namespace + "/foo"))
+		Expect(mockCtlr.getVirtualServer("test", "crd_10_1_1_1_80")).To(BeNil(),
+			"Virtual with the destination of other partition should not be created")
+		bar, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), "bar", metav1.GetOptions{})
+		Expect(bar.Status.StatusOk).To(Equal(AddressConflict))
+	})
+
+	It("Processes TransportServers in the partitions of their annotation", func() {
+		ts := test.NewTransportServer("ts", namespace, cisapiv1.TransportServerSpec{
+			VirtualServerAddress: "10.1.1.3",
+			VirtualServerPort:    8080,
+			Pool:                 cisapiv1.Pool{Service: "svc", ServicePort: intstr.FromInt(80)},
+		})
+		ts.Annotations = map[string]string{PartitionAnnotation: "dev"}
+		mockCtlr.addTransportServer(ts)
+		processQueue()
+		Expect(mockCtlr.getVirtualServer("dev", "crd_10_1_1_3_8080")).NotTo(BeNil())
+		Expect(mockCtlr.getVirtualServer("test", "crd_10_1_1_3_8080")).To(BeNil())
+	})
+
+	It("Rejects invalid partition annotation", func() {
+		Expect(isValidPartition("dev")).To(BeTrue())
+		Expect(isValidPartition("Common")).To(BeFalse())
+		Expect(isValidPartition("1dev")).To(BeFalse())
+		Expect(isValidPartition("dev/prod")).To(BeFalse())
+
+		mockCtlr.addVirtualServer(newVirtualServer("common", "10.1.1.4", "Common"))
+		processQueue()
+		Expect(mockCtlr.getVirtualServer("Common", "crd_10_1_1_4_80")).To(BeNil())
+		Expect(mockCtlr.getVirtualServer("test", "crd_10_1_1_4_80")).To(BeNil())
+	})
+})
@@ -0,0 +1,159 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// PartitionCredentialsErrorReason is the reason of the event recorded on the VirtualServers of
+	// a partition not posted as its BIG-IP credentials secret is missing or invalid
+	PartitionCredentialsErrorReason = "PartitionCredentialsError"
+)
+
+// setPartitionCredentials sets the BIG-IP credentials of partitions of the config, partitions whose
+// credentials secret is missing or invalid are removed from the config so that they are not posted
+// with the global credentials until the secret is fixed
+func (ctlr *Controller) setPartitionCredentials(config *ResourceConfigRequest) {
+	credentials, errs := ctlr.getPartitionCredentials(config.ltmConfig)
+	config.credentials = credentials
+	for partition, err := range errs {
+		message := fmt.Sprintf("Skipping the post of partition %v: %v", partition, err)
+		log.Errorf("%v", message)
+		for _, rsCfg := range config.ltmConfig[partition].ResourceMap {
+			for rscKey, kind := range rsCfg.MetaData.baseResources {
+				if kind != VirtualServer {
+					continue
+				}
+				if vs := ctlr.getVirtualServerByKey(rscKey); vs != nil {
+					ctlr.recordVirtualServerEvent(vs, v1.EventTypeWarning, PartitionCredentialsErrorReason, message)
+				}
+			}
+		}
+		delete(config.ltmConfig, partition)
+	}
+}
+
+// getPartitionCredentials returns the BIG-IP credentials of partitions holding resources of
+// a namespace mapped to a credential secret and the errors of partitions whose secret is missing
+// or invalid. Partitions with resources of multiple namespaces or of unmapped namespaces are
+// posted with the global credentials.
+func (ctlr *Controller) getPartitionCredentials(ltmConfig LTMConfig) (map[string]BigIPCredentials, map[string]error) {
+	if len(ctlr.credentialSecrets) == 0 {
+		return nil, nil
+	}
+	credentials := make(map[string]BigIPCredentials)
+	errs := make(map[string]error)
+	for partition, partitionConfig := range ltmConfig {
+		namespaces := make(map[string]struct{})
+		for _, rsCfg := range partitionConfig.ResourceMap {
+			for rscKey := range rsCfg.MetaData.baseResources {
+				namespaces[strings.SplitN(rscKey, "/", 2)[0]] = struct{}{}
+			}
+		}
+		if len(namespaces) != 1 {
+			continue
+		}
+		for namespace := range namespaces {
+			secretName, ok := ctlr.credentialSecrets[namespace]
+			if !ok {
+				continue
+			}
+			creds, err := ctlr.getCredentialsFromSecret(namespace, secretName)
+			if err != nil {
+				errs[partition] = fmt.Errorf("unable to get BIG-IP credentials: %v", err)
+				continue
+			}
+			credentials[partition] = creds
+		}
+	}
+	return credentials, errs
+}
+
+// getCredentialsFromSecret reads the username and password keys of the secret from the informer
+func (ctlr *Controller) getCredentialsFromSecret(namespace, secretName string) (BigIPCredentials, error) {
+	comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
+	if !ok || comInf.secretsInformer == nil {
+		return BigIPCredentials{}, fmt.Errorf("informer not found for namespace %v", namespace)
+	}
+	obj, found, err := comInf.secretsInformer.GetIndexer().GetByKey(namespace + "/" + secretName)
+	if err != nil || !found {
+		return BigIPCredentials{}, fmt.Errorf("secret %v/%v not found", namespace, secretName)
+	}
+	secret := obj.(*v1.Secret)
+	username, password := string(secret.Data["username"]), string(secret.Data["password"])
+	if username == "" || password == "" {
+		return BigIPCredentials{}, fmt.Errorf("secret %v/%v must have username and password", namespace, secretName)
+	}
+	return BigIPCredentials{Username: username, Password: password}, nil
+}
+
+// createAgentConfigs creates the configs to post the tenants grouped by their BIG-IP credentials.
+// Tenants with global credentials are posted with the declaration, whereas the tenants
+// posted with their own credentials get a declaration holding only the tenants of the credentials.
+func (agent *Agent) createAgentConfigs(
+	tenantDeclMap map[string]as3Tenant,
+	decl as3Declaration,
+	tenants []string,
+	id int,
+) []agentConfig {
+	var globalTenants []string
+	credTenants := make(map[BigIPCredentials][]string)
+	for _, tenant := range tenants {
+		if creds, ok := agent.tenantCredentials[tenant]; ok {
+			credTenants[creds] = append(credTenants[creds], tenant)
+		} else {
+			globalTenants = append(globalTenants, tenant)
+		}
+	}
+
+	var cfgs []agentConfig
+	if len(globalTenants) > 0 || len(credTenants) == 0 {
+		cfgs = append(cfgs, agentConfig{
+			data:      string(decl),
+			as3APIURL: agent.getAS3APIURL(globalTenants),
+			id:        id,
+			tenants:   globalTenants,
+		})
+	}
+	for creds, tenants := range credTenants {
+		sort.Strings(tenants)
+		tenantDecl := make(map[string]as3Tenant)
+		for _, tenant := range tenants {
+			tenantDecl[tenant] = tenantDeclMap[tenant]
+		}
+		creds := creds
+		cfgs = append(cfgs, agentConfig{
+			data:        string(agent.createAS3Declaration(tenantDecl)),
+			as3APIURL:   agent.getAS3APIURL(tenants),
+			id:          id,
+			credentials: &creds,
+			tenants:     tenants,
+		})
+	}
+	// post the tenants in the same order on every request
+	sort.SliceStable(cfgs, func(i, j int) bool {
+		return cfgs[i].as3APIURL < cfgs[j].as3APIURL
+	})
+	return cfgs
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("BIG-IP Credentials Tests", func() {
+	It("Gets credentials of partitions from namespace secrets", func() {
+		mockCtlr := newMockController()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.namespaces = map[string]bool{"tenant1": true, "tenant2": true}
+		for ns := range mockCtlr.namespaces {
+			_ = mockCtlr.addNamespacedInformers(ns, false)
+		}
+		mockCtlr.credentialSecrets = map[string]string{"tenant1": "bigip-creds", "tenant2": "bigip-creds"}
+		secret := test.NewSecret("bigip-creds", "tenant1", "", "")
+		secret.Data = map[string][]byte{"username": []byte("user1"), "password": []byte("pass1")}
+		// secret is read from the informer
+		_ = mockCtlr.comInformers["tenant1"].secretsInformer.GetIndexer().Add(secret)
+
+		newPartitionConfig := func(baseResources ...string) *PartitionConfig {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.baseResources = make(map[string]string)
+			for _, rsc := range baseResources {
+				rsCfg.MetaData.baseResources[rsc] = VirtualServer
+			}
+			return &PartitionConfig{ResourceMap: ResourceMap{"vs": rsCfg}}
+		}
+		ltmConfig := LTMConfig{
+			"partition1": newPartitionConfig("tenant1/vs1", "tenant1/vs2"),
+			"partition2": newPartitionConfig("tenant1/vs3", "default/vs4"),
+			"partition3": newPartitionConfig("default/vs5"),
+			// secret of tenant2 does not exist
+			"partition4": newPartitionConfig("tenant2/vs6"),
+		}
+		credentials, errs := mockCtlr.getPartitionCredentials(ltmConfig)
+		Expect(credentials).To(Equal(map[string]BigIPCredentials{
+			"partition1": {Username: "user1", Password: "pass1"},
+		}))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs).To(HaveKey("partition4"))
+
+		secret = secret.DeepCopy()
+		secret.Data = map[string][]byte{"username": []byte("user1")}
+		_ = mockCtlr.comInformers["tenant1"].secretsInformer.GetIndexer().Update(secret)
+		credentials, errs = mockCtlr.getPartitionCredentials(ltmConfig)
+		Expect(credentials).To(BeEmpty(), "Secret without password should be rejected")
+		Expect(errs).To(HaveLen(2))
+		Expect(errs).To(HaveKey("partition1"))
+
+		// partitions with invalid credentials are not posted with the global credentials
+		config := ResourceConfigRequest{ltmConfig: ltmConfig}
+		mockCtlr.setPartitionCredentials(&config)
+		Expect(config.ltmConfig).To(HaveLen(2))
+		Expect(config.ltmConfig).NotTo(HaveKey("partition1"))
+		Expect(config.ltmConfig).NotTo(HaveKey("partition4"))
+		Expect(config.credentials).To(BeEmpty())
+
+		mockCtlr.credentialSecrets = nil
+		credentials, errs = mockCtlr.getPartitionCredentials(ltmConfig)
+		Expect(credentials).To(BeNil())
+		Expect(errs).To(BeNil())
+	})
+
+	It("Switches credentials between posts", func() {
+		var postedAuth []string
+		var postedTenants [][]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, pass, _ := r.BasicAuth()
+			postedAuth = append(postedAuth, user+":"+pass)
+			body, _ := ioutil.ReadAll(r.Body)
+			var decl map[string]map[string]interface{}
+			_ = json.Unmarshal(body, &decl)
+			var tenants []string
+			for name, obj := range decl["declaration"] {
+				if tenant, ok := obj.(map[string]interface{}); ok && tenant["class"] == "Tenant" {
+					tenants = append(tenants, name)
+				}
+			}
+			postedTenants = append(postedTenants, tenants)
+			fmt.Fprint(w, `{"results":[{"code":200,"message":"success","tenant":"test"}]}`)
+		}))
+		defer server.Close()
+
+		agent := newMockAgent(nil)
+		agent.PostManager = &PostManager{PostParams: PostParams{
+			BIGIPURL:      server.URL,
+			BIGIPUsername: "admin",
+			BIGIPPassword: "admin",
+		}}
+		agent.setupBIGIPRESTClient()
+		agent.tenantResponseMap = make(map[string]tenantResponse)
+		tenantDeclMap := map[string]as3Tenant{
+			"partition1": {"class": "Tenant"},
+			"partition2": {"class": "Tenant"},
+		}
+		decl := agent.createAS3Declaration(tenantDeclMap)
+		tenants := []string{"partition1", "partition2"}
+
+		// all the tenants are posted with global credentials
+		for _, cfg := range agent.createAgentConfigs(tenantDeclMap, decl, tenants, 1) {
+			agent.postConfig(&cfg)
+		}
+		Expect(postedAuth).To(Equal([]string{"admin:admin"}))
+		Expect(postedTenants[0]).To(ConsistOf("partition1", "partition2"))
+
+		// tenant with credentials is posted separately with only its declaration
+		postedAuth, postedTenants = nil, nil
+		agent.tenantCredentials = map[string]BigIPCredentials{"partition2": {Username: "user2", Password: "pass2"}}
+		for _, cfg := range agent.createAgentConfigs(tenantDeclMap, decl, tenants, 2) {
+			agent.postConfig(&cfg)
+		}
+		Expect(postedAuth).To(Equal([]string{"admin:admin", "user2:pass2"}))
+		Expect(postedTenants[1]).To(ConsistOf("partition2"))
+
+		// credentials are switched back when the mapping is removed
+		postedAuth, postedTenants = nil, nil
+		agent.tenantCredentials = nil
+		for _, cfg := range agent.createAgentConfigs(tenantDeclMap, decl, []string{"partition2"}, 3) {
+			agent.postConfig(&cfg)
+		}
+		Expect(postedAuth).To(Equal([]string{"admin:admin"}))
+	})
+})
@@ -0,0 +1,94 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// LargeDeclarationReason is the reason of events on CIS pod for AS3 declarations
+// exceeding the size warning threshold
+const LargeDeclarationReason = "LargeAS3Declaration"
+
+// checkDeclarationSize checks the size of AS3 declaration of the config, the sizes of declarations
+// exceeding the warning threshold or the limit are recorded for the tenants of config.
+// Returns false if the declaration exceeds the size limit, which must not be posted to BIG-IP.
+func (agent *Agent) checkDeclarationSize(cfg agentConfig, declarationSizes map[string]int) bool {
+	size := len(cfg.data)
+	exceedsLimit := agent.DeclarationSizeLimit > 0 && size > agent.DeclarationSizeLimit
+	if !exceedsLimit && (agent.DeclarationSizeWarn <= 0 || size <= agent.DeclarationSizeWarn) {
+		return true
+	}
+	for _, tenant := range cfg.tenants {
+		declarationSizes[tenant] = size
+	}
+	if !exceedsLimit {
+		log.Warningf("[AS3] Declaration of tenants %v is %v bytes, exceeds the warning threshold of %v bytes",
+			cfg.tenants, size, agent.DeclarationSizeWarn)
+		return true
+	}
+	log.Errorf("[AS3] Skipping the post of tenants %v, declaration of %v bytes exceeds the limit of %v bytes",
+		cfg.tenants, size, agent.DeclarationSizeLimit)
+	// tenants are neither cached nor retried until their declaration is within the limit
+	for _, tenant := range cfg.tenants {
+		delete(agent.tenantResponseMap, tenant)
+		delete(agent.retryTenantDeclMap, tenant)
+	}
+	return false
+}
+
+// getDeclarationStatus returns the status of virtuals in the partition based on its declaration size
+func (ctlr *Controller) getDeclarationStatus(declarationSizes map[string]int, partition string) string {
+	size, ok := declarationSizes[partition]
+	if ok && ctlr.Agent != nil && ctlr.Agent.PostManager != nil && ctlr.Agent.DeclarationSizeLimit > 0 &&
+		size > ctlr.Agent.DeclarationSizeLimit {
+		return DeclarationTooLarge
+	}
+	return "Ok"
+}
+
+// recordDeclarationSizeEvents records warning events on CIS pod for the AS3 declarations
+// exceeding the size warning threshold or the limit
+func (ctlr *Controller) recordDeclarationSizeEvents(declarationSizes map[string]int) {
+	podName := os.Getenv("HOSTNAME")
+	namespace := getControllerNamespace()
+	if ctlr.eventNotifier == nil || ctlr.kubeClient == nil || podName == "" || namespace == "" {
+		return
+	}
+	ref := &v1.ObjectReference{Kind: "Pod", Namespace: namespace, Name: podName}
+	evNotifier := ctlr.eventNotifier.CreateNotifierForNamespace(namespace, ctlr.kubeClient.CoreV1())
+	tenantsBySize := make(map[int][]string)
+	for tenant, size := range declarationSizes {
+		tenantsBySize[size] = append(tenantsBySize[size], tenant)
+	}
+	for size, tenants := range tenantsBySize {
+		sort.Strings(tenants)
+		reason := LargeDeclarationReason
+		message := fmt.Sprintf("AS3 declaration of tenants %v is %v bytes", strings.Join(tenants, ","), size)
+		if ctlr.getDeclarationStatus(declarationSizes, tenants[0]) == DeclarationTooLarge {
+			reason = DeclarationTooLarge
+			message += ", not posted to BIG-IP as it exceeds the size limit"
+		}
+		evNotifier.RecordEvent(ref, v1.EventTypeWarning, reason, message)
+	}
+}
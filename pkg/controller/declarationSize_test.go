@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("AS3 Declaration Size Tests", func() {
+	var agent *Agent
+
+	BeforeEach(func() {
+		agent = newMockAgent(nil)
+		agent.PostManager = &PostManager{PostParams: PostParams{DeclarationSizeWarn: 10, DeclarationSizeLimit: 20}}
+		agent.tenantResponseMap = map[string]tenantResponse{"test": {}, "prod": {}}
+		agent.retryTenantDeclMap = map[string]*tenantParams{"test": {}}
+	})
+
+	It("Checks the size of declarations", func() {
+		declarationSizes := make(map[string]int)
+		Expect(agent.checkDeclarationSize(agentConfig{data: "0123456789", tenants: []string{"test"}},
+			declarationSizes)).To(BeTrue())
+		Expect(declarationSizes).To(BeEmpty())
+
+		Expect(agent.checkDeclarationSize(agentConfig{data: "0123456789012345", tenants: []string{"test"}},
+			declarationSizes)).To(BeTrue(), "Declaration exceeding the warning threshold should be posted")
+		Expect(declarationSizes).To(Equal(map[string]int{"test": 16}))
+
+		Expect(agent.checkDeclarationSize(agentConfig{data: "012345678901234567890", tenants: []string{"test"}},
+			declarationSizes)).To(BeFalse(), "Declaration exceeding the limit should not be posted")
+		Expect(declarationSizes).To(Equal(map[string]int{"test": 21}))
+		Expect(agent.tenantResponseMap).To(HaveLen(1))
+		Expect(agent.tenantResponseMap).To(HaveKey("prod"))
+		Expect(agent.retryTenantDeclMap).To(BeEmpty())
+
+		agent.DeclarationSizeWarn = 0
+		agent.DeclarationSizeLimit = 0
+		declarationSizes = make(map[string]int)
+		Expect(agent.checkDeclarationSize(agentConfig{data: "012345678901234567890", tenants: []string{"prod"}},
+			declarationSizes)).To(BeTrue())
+		Expect(declarationSizes).To(BeEmpty())
+	})
+
+	It("Updates the status of VirtualServers with declaration exceeding the limit", func() {
+		namespace := "default"
+		mockCtlr := newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.Agent = agent
+		respChan := make(chan resourceStatusMeta, 1)
+		go mockCtlr.responseHandler(respChan)
+		time.Sleep(10 * time.Millisecond)
+
+		for _, name := range []string{"vs1", "vs2"} {
+			vs := test.NewVirtualServer(name, namespace, cisapiv1.VirtualServerSpec{})
+			_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), vs, metav1.CreateOptions{})
+			mockCtlr.addVirtualServer(vs)
+		}
+		newPartitionConfig := func(baseResource string) *PartitionConfig {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.baseResources = map[string]string{baseResource: VirtualServer}
+			return &PartitionConfig{ResourceMap: ResourceMap{"vs": rsCfg}}
+		}
+		id := mockCtlr.enqueueReq(ResourceConfigRequest{ltmConfig: LTMConfig{
+			"test": newPartitionConfig(namespace + "/vs1"),
+			"prod": newPartitionConfig(namespace + "/vs2"),
+		}})
+		Expect(mockCtlr.getDeclarationStatus(map[string]int{"test": 16}, "test")).To(Equal("Ok"))
+		Expect(mockCtlr.getDeclarationStatus(map[string]int{"test": 21}, "prod")).To(Equal("Ok"))
+
+		respChan <- resourceStatusMeta{id, make(map[string]struct{}), map[string]int{"test": 21}, nil}
+		defer close(respChan)
+
+		getStatus := func(name string) func() string {
+			return func() string {
+				vs, _ := mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+				return vs.Status.StatusOk
+			}
+		}
+		Eventually(getStatus("vs1")).Should(Equal(DeclarationTooLarge))
+		Eventually(getStatus("vs2")).Should(Equal("Ok"))
+	})
+})
@@ -0,0 +1,276 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// CircularDependencyReason is the reason of events on resources with circular dependencies
+const CircularDependencyReason = "CircularDependency"
+
+// dependencyGraph maps each resource to its direct dependencies
+type dependencyGraph map[resourceRef][]resourceRef
+
+func (ref resourceRef) String() string {
+	return ref.kind + "/" + ref.namespace + "/" + ref.name
+}
+
+// addDependency adds the dependency of resource to the graph
+func (graph dependencyGraph) addDependency(rsc, dependency resourceRef) {
+	for _, dep := range graph[rsc] {
+		if dep == dependency {
+			return
+		}
+	}
+	graph[rsc] = append(graph[rsc], dependency)
+	if _, ok := graph[dependency]; !ok {
+		graph[dependency] = nil
+	}
+}
+
+// topologicalSort returns the resources ordered with the dependencies before their dependents,
+// and the resources which can not be ordered because of a circular dependency.
+// Resources without an order between them are sorted by their kind, namespace and name.
+func (graph dependencyGraph) topologicalSort() (sorted []resourceRef, cyclic []resourceRef) {
+	resolved := make(map[resourceRef]bool, len(graph))
+	for len(resolved) < len(graph) {
+		var ready []resourceRef
+		for rsc, deps := range graph {
+			if resolved[rsc] {
+				continue
+			}
+			isReady := true
+			for _, dep := range deps {
+				if !resolved[dep] {
+					isReady = false
+					break
+				}
+			}
+			if isReady {
+				ready = append(ready, rsc)
+			}
+		}
+		if len(ready) == 0 {
+			break
+		}
+		sortResourceRefs(ready)
+		for _, rsc := range ready {
+			resolved[rsc] = true
+		}
+		sorted = append(sorted, ready...)
+	}
+	for rsc := range graph {
+		if !resolved[rsc] {
+			cyclic = append(cyclic, rsc)
+		}
+	}
+	sortResourceRefs(cyclic)
+	return sorted, cyclic
+}
+
+func sortResourceRefs(refs []resourceRef) {
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].String() < refs[j].String()
+	})
+}
+
+// addResourceDependencies adds the resource of key to the graph along with its direct dependencies.
+// VirtualServers and TransportServers depend on their Policies, TLSProfiles, Services and Endpoints,
+// and TLSProfiles depend on their Secrets.
+func (ctlr *Controller) addResourceDependencies(graph dependencyGraph, rKey *rqKey) {
+	ref := resourceRef{kind: rKey.kind, namespace: rKey.namespace, name: rKey.rscName}
+	if _, ok := graph[ref]; !ok {
+		graph[ref] = nil
+	}
+	addServiceDependency := func(namespace, name string) {
+		graph.addDependency(ref, resourceRef{kind: Service, namespace: namespace, name: name})
+		graph.addDependency(ref, resourceRef{kind: Endpoints, namespace: namespace, name: name})
+	}
+	switch rsc := rKey.rsc.(type) {
+	case *cisapiv1.VirtualServer:
+		if rsc.Spec.PolicyName != "" {
+			graph.addDependency(ref, resourceRef{kind: CustomPolicy, namespace: rsc.Namespace, name: rsc.Spec.PolicyName})
+		}
+		if rsc.Spec.TLSProfileName != "" {
+			tlsRef := resourceRef{kind: TLSProfile, namespace: rsc.Namespace, name: rsc.Spec.TLSProfileName}
+			graph.addDependency(ref, tlsRef)
+			for _, secret := range ctlr.getSecretsOfTLSProfile(rsc.Namespace, rsc.Spec.TLSProfileName) {
+				graph.addDependency(tlsRef, resourceRef{kind: K8sSecret, namespace: rsc.Namespace, name: secret})
+			}
+		}
+		for _, pool := range rsc.Spec.Pools {
+			svcNamespace := rsc.Namespace
+			if pool.ServiceNamespace != "" {
+				svcNamespace = pool.ServiceNamespace
+			}
+			addServiceDependency(svcNamespace, pool.Service)
+		}
+	case *cisapiv1.TransportServer:
+		if rsc.Spec.PolicyName != "" {
+			graph.addDependency(ref, resourceRef{kind: CustomPolicy, namespace: rsc.Namespace, name: rsc.Spec.PolicyName})
+		}
+		svcNamespace := rsc.Namespace
+		if rsc.Spec.Pool.ServiceNamespace != "" {
+			svcNamespace = rsc.Spec.Pool.ServiceNamespace
+		}
+		addServiceDependency(svcNamespace, rsc.Spec.Pool.Service)
+	case *cisapiv1.TLSProfile:
+		for _, secret := range getSecretsOfTLS(rsc.Spec.TLS) {
+			graph.addDependency(ref, resourceRef{kind: K8sSecret, namespace: rsc.Namespace, name: secret})
+		}
+	}
+}
+
+// addSharedHostDependencies adds the dependencies between the queued VirtualServers of a host.
+// A VirtualServer without a Policy or TLSProfile depends on the VirtualServers of the same host
+// defining them, as it gets the Policy or TLSProfile of the host from them.
+func addSharedHostDependencies(graph dependencyGraph, keys []interface{}) {
+	virtuals := make(map[resourceRef]*cisapiv1.VirtualServer)
+	for _, key := range keys {
+		rKey := key.(*rqKey)
+		if vs, ok := rKey.rsc.(*cisapiv1.VirtualServer); ok {
+			virtuals[resourceRef{kind: VirtualServer, namespace: rKey.namespace, name: rKey.rscName}] = vs
+		}
+	}
+	for ref, vs := range virtuals {
+		for otherRef, other := range virtuals {
+			if otherRef == ref || vs.Spec.Host == "" || other.Spec.Host != vs.Spec.Host {
+				continue
+			}
+			if (vs.Spec.PolicyName == "" && other.Spec.PolicyName != "") ||
+				(vs.Spec.TLSProfileName == "" && other.Spec.TLSProfileName != "") {
+				graph.addDependency(ref, otherRef)
+			}
+		}
+	}
+}
+
+// getSecretsOfTLSProfile returns the names of the secrets referred by the TLSProfile
+func (ctlr *Controller) getSecretsOfTLSProfile(namespace, name string) []string {
+	crInf, ok := ctlr.getNamespacedCRInformer(namespace)
+	if !ok || crInf.tlsInformer == nil {
+		return nil
+	}
+	obj, found, err := crInf.tlsInformer.GetIndexer().GetByKey(namespace + "/" + name)
+	if err != nil || !found {
+		return nil
+	}
+	return getSecretsOfTLS(obj.(*cisapiv1.TLSProfile).Spec.TLS)
+}
+
+func getSecretsOfTLS(tls cisapiv1.TLS) []string {
+	if tls.Reference != "secret" {
+		return nil
+	}
+	names := append([]string{tls.ClientSSL, tls.ServerSSL}, tls.ClientSSLs...)
+	names = append(names, tls.ServerSSLs...)
+	var secrets []string
+	for _, secret := range names {
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// orderResourceKeys orders the keys so that the resources are processed after their dependencies
+// queued along with them, the keys are otherwise processed in the order they are queued.
+// Keys of the resources with circular dependencies are processed last, and a CircularDependency
+// event is recorded on their VirtualServers.
+func (ctlr *Controller) orderResourceKeys(keys []interface{}) []interface{} {
+	if len(keys) < 2 {
+		return keys
+	}
+	graph := make(dependencyGraph)
+	pending := make(map[resourceRef]int)
+	for _, key := range keys {
+		rKey := key.(*rqKey)
+		ctlr.addResourceDependencies(graph, rKey)
+		pending[resourceRef{kind: rKey.kind, namespace: rKey.namespace, name: rKey.rscName}]++
+	}
+	addSharedHostDependencies(graph, keys)
+	_, cyclic := graph.topologicalSort()
+	isCyclic := make(map[resourceRef]bool, len(cyclic))
+	for _, ref := range cyclic {
+		isCyclic[ref] = true
+	}
+
+	// isReady checks whether none of the transitive dependencies of the resource is pending
+	isReady := func(ref resourceRef) bool {
+		visited := map[resourceRef]bool{ref: true}
+		stack := append([]resourceRef{}, graph[ref]...)
+		for len(stack) > 0 {
+			dep := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if pending[dep] > 0 {
+				return false
+			}
+			stack = append(stack, graph[dep]...)
+		}
+		return true
+	}
+
+	ordered := make([]interface{}, 0, len(keys))
+	remaining := keys
+	for len(remaining) > 0 {
+		var blocked []interface{}
+		for _, key := range remaining {
+			rKey := key.(*rqKey)
+			ref := resourceRef{kind: rKey.kind, namespace: rKey.namespace, name: rKey.rscName}
+			if isCyclic[ref] || !isReady(ref) {
+				blocked = append(blocked, key)
+				continue
+			}
+			ordered = append(ordered, key)
+			pending[ref]--
+		}
+		if len(blocked) == len(remaining) {
+			break
+		}
+		remaining = blocked
+	}
+	// remaining keys are of the resources with circular dependencies or depending on them
+	var cyclicVSs []string
+	for _, ref := range cyclic {
+		if ref.kind == VirtualServer {
+			cyclicVSs = append(cyclicVSs, ref.namespace+"/"+ref.name)
+		}
+	}
+	for _, key := range remaining {
+		rKey := key.(*rqKey)
+		ref := resourceRef{kind: rKey.kind, namespace: rKey.namespace, name: rKey.rscName}
+		if vs, ok := rKey.rsc.(*cisapiv1.VirtualServer); ok && isCyclic[ref] {
+			message := fmt.Sprintf("VirtualServer %v/%v has circular dependency with VirtualServers %v",
+				vs.Namespace, vs.Name, strings.Join(cyclicVSs, ", "))
+			log.Warning(message)
+			ctlr.recordVirtualServerEvent(vs, v1.EventTypeWarning, CircularDependencyReason, message)
+		}
+		ordered = append(ordered, key)
+	}
+	return ordered
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Dependency Graph Tests", func() {
+	var mockCtlr *mockController
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+	})
+
+	It("Sorts resources topologically", func() {
+		vsA := resourceRef{kind: VirtualServer, namespace: namespace, name: "vsA"}
+		vsB := resourceRef{kind: VirtualServer, namespace: namespace, name: "vsB"}
+		tls := resourceRef{kind: TLSProfile, namespace: namespace, name: "tls"}
+		secret := resourceRef{kind: K8sSecret, namespace: namespace, name: "secret"}
+		graph := make(dependencyGraph)
+		graph.addDependency(vsA, vsB)
+		graph.addDependency(vsB, tls)
+		graph.addDependency(tls, secret)
+		graph.addDependency(tls, secret)
+		Expect(graph[tls]).To(Equal([]resourceRef{secret}), "Dependency should be added once")
+
+		sorted, cyclic := graph.topologicalSort()
+		Expect(sorted).To(Equal([]resourceRef{secret, tls, vsB, vsA}))
+		Expect(cyclic).To(BeEmpty())
+
+		graph.addDependency(tls, vsA)
+		sorted, cyclic = graph.topologicalSort()
+		Expect(sorted).To(Equal([]resourceRef{secret}))
+		Expect(cyclic).To(Equal([]resourceRef{tls, vsA, vsB}))
+	})
+
+	It("Orders the queued keys by their dependencies", func() {
+		tlsProfile := test.NewTLSProfile("tls", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "clientssl-secret", Reference: "secret"},
+		})
+		mockCtlr.addTLSProfile(tlsProfile)
+		vsA := test.NewVirtualServer("vsA", namespace, cisapiv1.VirtualServerSpec{
+			Host:           "foo.com",
+			PolicyName:     "plc",
+			TLSProfileName: "tls",
+			Pools:          []cisapiv1.Pool{{Path: "/a", Service: "svc-a", ServiceNamespace: "other", ServicePort: intstr.FromInt(80)}},
+		})
+		vsC := test.NewVirtualServer("vsC", namespace, cisapiv1.VirtualServerSpec{
+			Host:  "bar.com",
+			Pools: []cisapiv1.Pool{{Path: "/c", Service: "svc-c", ServicePort: intstr.FromInt(80)}},
+		})
+		graph := make(dependencyGraph)
+		mockCtlr.addResourceDependencies(graph, &rqKey{kind: VirtualServer, namespace: namespace, rscName: "vsA", rsc: vsA})
+		Expect(graph[resourceRef{kind: VirtualServer, namespace: namespace, name: "vsA"}]).To(ConsistOf(
+			resourceRef{kind: CustomPolicy, namespace: namespace, name: "plc"},
+			resourceRef{kind: TLSProfile, namespace: namespace, name: "tls"},
+			resourceRef{kind: Service, namespace: "other", name: "svc-a"},
+			resourceRef{kind: Endpoints, namespace: "other", name: "svc-a"},
+		))
+		Expect(graph[resourceRef{kind: TLSProfile, namespace: namespace, name: "tls"}]).To(Equal(
+			[]resourceRef{{kind: K8sSecret, namespace: namespace, name: "clientssl-secret"}}))
+
+		vsAKey := &rqKey{kind: VirtualServer, namespace: namespace, rscName: "vsA", rsc: vsA}
+		vsCKey := &rqKey{kind: VirtualServer, namespace: namespace, rscName: "vsC", rsc: vsC}
+		secretKey := &rqKey{kind: K8sSecret, namespace: namespace, rscName: "clientssl-secret"}
+		svcKey := &rqKey{kind: Service, namespace: "other", rscName: "svc-a"}
+		tlsKey := &rqKey{kind: TLSProfile, namespace: namespace, rscName: "tls", rsc: tlsProfile}
+		Expect(mockCtlr.orderResourceKeys([]interface{}{vsAKey, vsCKey, tlsKey, secretKey, svcKey})).To(Equal(
+			[]interface{}{vsCKey, secretKey, svcKey, tlsKey, vsAKey}),
+			"VirtualServer should be processed after its dependencies, other keys in the queued order")
+	})
+
+	It("Orders VirtualServers sharing a host and reports circular dependencies", func() {
+		// vsB gets the TLSProfile of the host from vsA
+		vsA := test.NewVirtualServer("vsA", namespace, cisapiv1.VirtualServerSpec{
+			Host:           "foo.com",
+			TLSProfileName: "tls",
+		})
+		vsB := test.NewVirtualServer("vsB", namespace, cisapiv1.VirtualServerSpec{Host: "foo.com"})
+		vsAKey := &rqKey{kind: VirtualServer, namespace: namespace, rscName: "vsA", rsc: vsA}
+		vsBKey := &rqKey{kind: VirtualServer, namespace: namespace, rscName: "vsB", rsc: vsB}
+		Expect(mockCtlr.orderResourceKeys([]interface{}{vsBKey, vsAKey})).To(Equal([]interface{}{vsAKey, vsBKey}),
+			"VirtualServer should be processed after the VirtualServer defining the TLSProfile of its host")
+
+		// vsC gets the TLSProfile of the host from vsD, whereas vsD gets the Policy from vsC
+		vsC := test.NewVirtualServer("vsC", namespace, cisapiv1.VirtualServerSpec{
+			Host:       "bar.com",
+			PolicyName: "plc",
+		})
+		vsD := test.NewVirtualServer("vsD", namespace, cisapiv1.VirtualServerSpec{
+			Host:           "bar.com",
+			TLSProfileName: "tls",
+		})
+		vsCKey := &rqKey{kind: VirtualServer, namespace: namespace, rscName: "vsC", rsc: vsC}
+		vsDKey := &rqKey{kind: VirtualServer, namespace: namespace, rscName: "vsD", rsc: vsD}
+		Expect(mockCtlr.orderResourceKeys([]interface{}{vsCKey, vsDKey, vsBKey})).To(Equal(
+			[]interface{}{vsBKey, vsCKey, vsDKey}), "VirtualServers with circular dependency should be processed last")
+
+		Eventually(func() []string {
+			events, _ := mockCtlr.kubeClient.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{})
+			var objects []string
+			for _, event := range events.Items {
+				if event.Reason == CircularDependencyReason {
+					objects = append(objects, event.InvolvedObject.Name)
+				}
+			}
+			return objects
+		}).Should(ConsistOf("vsC", "vsD"))
+	})
+})
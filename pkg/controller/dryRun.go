@@ -0,0 +1,53 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DryRunReason is the reason of the event recorded on a VirtualServer with the names
+	// of its BIG-IP objects computed in dry-run mode
+	DryRunReason = "DryRun"
+)
+
+// reportDryRunNames logs the names of BIG-IP virtual servers and pools of the virtual
+// computed in dry-run mode and records them as an event on the virtual
+func (ctlr *Controller) reportDryRunNames(virtual *cisapiv1.VirtualServer, vsMap ResourceMap) {
+	var virtualNames, poolNames []string
+	for rsName, rsCfg := range vsMap {
+		virtualNames = append(virtualNames, JoinBigipPath(rsCfg.Virtual.Partition, rsName))
+	}
+	partition := ctlr.getCRPartition(virtual.Annotations)
+	for _, pl := range virtual.Spec.Pools {
+		poolName := JoinBigipPath(partition, ctlr.framePoolName(virtual.Namespace, pl, virtual.Spec.Host))
+		poolNames = appendUniqueString(poolNames, poolName)
+	}
+	sort.Strings(virtualNames)
+	sort.Strings(poolNames)
+	message := fmt.Sprintf("BIG-IP virtual servers: %v, pools: %v",
+		strings.Join(virtualNames, ","), strings.Join(poolNames, ","))
+	log.Infof("[DryRun] VirtualServer %v/%v %v", virtual.Namespace, virtual.Name, message)
+	ctlr.recordVirtualServerEvent(virtual, v1.EventTypeNormal, DryRunReason, message)
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Dry Run Tests", func() {
+	var mockCtlr *mockController
+	var vs *cisapiv1.VirtualServer
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.Partition = "test"
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.Agent = newMockAgent(nil)
+		mockCtlr.TeemData = &teem.TeemsData{ResourceType: teem.ResourceTypes{
+			VirtualServer: make(map[string]int),
+			IPAMVS:        make(map[string]int),
+		}}
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+		mockCtlr.dryRun = true
+
+		vs = test.NewVirtualServer("vs1", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "foo.com",
+			VirtualServerAddress: "10.1.1.1",
+			Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc", ServicePort: intstr.FromInt(80)}},
+		})
+		_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), vs, metav1.CreateOptions{})
+	})
+
+	AfterEach(func() {
+		mockCtlr.resourceQueue.ShutDown()
+	})
+
+	It("Records the names of BIG-IP objects without posting them", func() {
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.processResources()).To(BeTrue())
+
+		Expect(mockCtlr.getVirtualServer(mockCtlr.Partition, "crd_10_1_1_1_80")).NotTo(BeNil(),
+			"ResourceConfig should be built in dry-run mode")
+		Expect(mockCtlr.Agent.postChan).To(BeEmpty(), "Configuration should not be posted in dry-run mode")
+
+		Eventually(func() []string {
+			var messages []string
+			events, _ := mockCtlr.kubeClient.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{})
+			for _, event := range events.Items {
+				if event.Reason == DryRunReason && event.InvolvedObject.Name == vs.Name {
+					messages = append(messages, event.Message)
+				}
+			}
+			return messages
+		}).Should(ConsistOf("BIG-IP virtual servers: /test/crd_10_1_1_1_80, pools: /test/svc_80_default_foo_com"))
+	})
+})
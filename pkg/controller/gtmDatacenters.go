@@ -0,0 +1,136 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DatacenterPollInterval is the interval to poll the GTM datacenters of BIG-IP
+const DatacenterPollInterval = time.Minute
+
+// DatacenterChangedReason is the reason of events on ExternalDNS when its GTM datacenter changes
+const DatacenterChangedReason = "DatacenterChanged"
+
+// startDatacenterWatch starts watching the GTM datacenters of BIG-IP, once ExternalDNS is processed.
+// GTM servers posted by CCCL are on the GTM BIG-IP, which is not polled.
+func (ctlr *Controller) startDatacenterWatch() {
+	if ctlr.Agent == nil || ctlr.Agent.PostManager == nil || ctlr.Agent.httpClient == nil || ctlr.Agent.ccclGTMAgent {
+		return
+	}
+	ctlr.datacenterWatch.Do(func() {
+		go ctlr.DatacenterWatch()
+	})
+}
+
+// DatacenterWatch periodically polls the GTM datacenters of BIG-IP for changes
+func (ctlr *Controller) DatacenterWatch() {
+	ctlr.refreshGTMDatacenters()
+	ticker := time.NewTicker(DatacenterPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctlr.refreshGTMDatacenters()
+	}
+}
+
+// refreshGTMDatacenters fetches the GTM datacenters of BIG-IP and reprocesses the
+// ExternalDNS resources referring the GTM servers of changed datacenters
+func (ctlr *Controller) refreshGTMDatacenters() {
+	datacenters, err := ctlr.Agent.getGTMDatacenters()
+	if err != nil {
+		log.Errorf("Unable to get the GTM datacenters of BIG-IP: %v", err)
+		return
+	}
+	prevDatacenters := ctlr.resources.gtmDatacenters
+	ctlr.resources.gtmDatacenters = datacenters
+	// datacenters fetched for the first time are not compared
+	if prevDatacenters == nil {
+		return
+	}
+	changed := changedGTMDatacenters(prevDatacenters, datacenters)
+	if len(changed) == 0 {
+		return
+	}
+	log.Infof("GTM datacenters %v of BIG-IP changed", strings.Join(changed, ", "))
+	// GTM servers of changed datacenters, before and after the change e.g. of a renamed datacenter
+	servers := make(map[string]string)
+	for _, dcName := range changed {
+		for server := range prevDatacenters[dcName].Servers {
+			servers[server] = dcName
+		}
+		for server := range datacenters[dcName].Servers {
+			servers[server] = dcName
+		}
+	}
+	reprocessed := false
+	for _, edns := range ctlr.getAllMonitoredExternalDNS() {
+		for _, pl := range edns.Spec.Pools {
+			dcName, ok := servers[pl.DataServerName]
+			if !ok {
+				continue
+			}
+			message := fmt.Sprintf("GTM datacenter %v of server %v changed", dcName, pl.DataServerName)
+			log.Debugf("%v, reprocessing EDNS %v", message, edns.Spec.DomainName)
+			ctlr.recordExternalDNSEvent(edns, v1.EventTypeNormal, DatacenterChangedReason, message)
+			if ctlr.resourceQueue != nil {
+				ctlr.enqueueExternalDNS(edns)
+			}
+			reprocessed = true
+			break
+		}
+	}
+	// GTM config is re-posted even if unchanged to update the GTM servers on BIG-IP
+	if reprocessed && ctlr.resourceQueue != nil {
+		ctlr.resourceQueue.Add(&rqKey{kind: Resync})
+	}
+}
+
+// changedGTMDatacenters returns the sorted names of datacenters added, removed or updated
+func changedGTMDatacenters(prevDatacenters, datacenters GTMDatacenters) []string {
+	var changed []string
+	for name, dc := range datacenters {
+		if prevDC, ok := prevDatacenters[name]; !ok || !reflect.DeepEqual(prevDC, dc) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range prevDatacenters {
+		if _, ok := datacenters[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// getAllMonitoredExternalDNS returns the ExternalDNS resources of the monitored namespaces
+func (ctlr *Controller) getAllMonitoredExternalDNS() []*cisapiv1.ExternalDNS {
+	if ctlr.watchingAllNamespaces() {
+		return ctlr.getAllExternalDNS("")
+	}
+	var allEDNS []*cisapiv1.ExternalDNS
+	for ns := range ctlr.namespaces {
+		allEDNS = append(allEDNS, ctlr.getAllExternalDNS(ns)...)
+	}
+	return allEDNS
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("GTM Datacenter Watch Tests", func() {
+	var mockCtlr *mockController
+	var server *httptest.Server
+	var datacenters, servers string
+	namespace := "default"
+
+	BeforeEach(func() {
+		datacenters = `{"items":[{"fullPath":"/Common/DC1","enabled":true}]}`
+		servers = `{"items":[{"fullPath":"/Common/GSLBServer","datacenter":"/Common/DC1",
+			"addresses":[{"name":"10.1.1.1"}]}]}`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/mgmt/tm/gtm/datacenter":
+				fmt.Fprint(w, datacenters)
+			case "/mgmt/tm/gtm/server":
+				fmt.Fprint(w, servers)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"code":404}`)
+			}
+		}))
+
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.Agent = newMockAgent(nil)
+		mockCtlr.Agent.PostManager = &PostManager{PostParams: PostParams{BIGIPURL: server.URL}}
+		mockCtlr.Agent.setupBIGIPRESTClient()
+
+		mockCtlr.addEDNS(test.NewExternalDNS("edns1", namespace, cisapiv1.ExternalDNSSpec{
+			DomainName: "foo.com",
+			Pools:      []cisapiv1.DNSPool{{DataServerName: "/Common/GSLBServer"}},
+		}))
+		mockCtlr.addEDNS(test.NewExternalDNS("edns2", namespace, cisapiv1.ExternalDNSSpec{
+			DomainName: "bar.com",
+			Pools:      []cisapiv1.DNSPool{{DataServerName: "/Common/OtherServer"}},
+		}))
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	datacenterEvents := func() []string {
+		var objects []string
+		events, _ := mockCtlr.kubeClient.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{})
+		for _, event := range events.Items {
+			if event.Reason == DatacenterChangedReason {
+				objects = append(objects, event.InvolvedObject.Name)
+			}
+		}
+		return objects
+	}
+
+	It("Fetches the GTM datacenters of BIG-IP", func() {
+		mockCtlr.refreshGTMDatacenters()
+		Expect(mockCtlr.resources.gtmDatacenters).To(Equal(GTMDatacenters{
+			"/Common/DC1": {Enabled: true, Servers: map[string][]string{"/Common/GSLBServer": {"10.1.1.1"}}},
+		}))
+		Expect(mockCtlr.resourceQueue.Len()).To(BeZero(), "EDNS should not be reprocessed on first poll")
+
+		mockCtlr.refreshGTMDatacenters()
+		Expect(mockCtlr.resourceQueue.Len()).To(BeZero(), "EDNS should not be reprocessed without changes")
+		Expect(datacenterEvents()).To(BeEmpty())
+	})
+
+	It("Reprocesses the ExternalDNS of changed datacenter", func() {
+		mockCtlr.refreshGTMDatacenters()
+
+		// IP address of GTM server changed
+		servers = `{"items":[{"fullPath":"/Common/GSLBServer","datacenter":"/Common/DC1",
+			"addresses":[{"name":"10.1.1.2"}]}]}`
+		mockCtlr.refreshGTMDatacenters()
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(2), "EDNS and resync should be queued")
+		key, _ := mockCtlr.resourceQueue.Get()
+		Expect(key.(*rqKey).kind).To(Equal(ExternalDNS))
+		Expect(key.(*rqKey).rscName).To(Equal("edns1"))
+		mockCtlr.resourceQueue.Done(key)
+		key, _ = mockCtlr.resourceQueue.Get()
+		Expect(key.(*rqKey).kind).To(Equal(Resync))
+		mockCtlr.resourceQueue.Done(key)
+		Eventually(datacenterEvents).Should(Equal([]string{"edns1"}))
+
+		// datacenter renamed
+		datacenters = `{"items":[{"fullPath":"/Common/DC2","enabled":true}]}`
+		servers = `{"items":[{"fullPath":"/Common/GSLBServer","datacenter":"/Common/DC2",
+			"addresses":[{"name":"10.1.1.2"}]}]}`
+		mockCtlr.refreshGTMDatacenters()
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(2), "EDNS and resync should be queued")
+		Expect(changedGTMDatacenters(GTMDatacenters{"/Common/DC1": {}},
+			mockCtlr.resources.gtmDatacenters)).To(Equal([]string{"/Common/DC1", "/Common/DC2"}))
+	})
+
+	It("Fails to fetch the GTM datacenters", func() {
+		mockCtlr.Agent.PostManager.BIGIPURL = server.URL + "/invalid"
+		mockCtlr.refreshGTMDatacenters()
+		Expect(mockCtlr.resources.gtmDatacenters).To(BeNil())
+	})
+})
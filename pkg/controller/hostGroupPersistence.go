@@ -0,0 +1,95 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// PersistenceConflictReason is the reason of events on VirtualServers of a HostGroup
+// whose Policies have different persistence profiles
+const PersistenceConflictReason = "PersistenceConflict"
+
+// getHostGroupPersistenceProfile returns the persistence profile overriding the persistence
+// of the Policies for all the VirtualServers of the HostGroup
+func getHostGroupPersistenceProfile(virtuals []*cisapiv1.VirtualServer) string {
+	var profile string
+	for _, vrt := range virtuals {
+		if vrt.Spec.HostGroup == "" || vrt.Spec.HostGroupPersistenceProfile == "" {
+			continue
+		}
+		if profile == "" {
+			profile = vrt.Spec.HostGroupPersistenceProfile
+		} else if profile != vrt.Spec.HostGroupPersistenceProfile {
+			log.Warningf("Ignoring hostGroupPersistenceProfile %v of VirtualServer %v/%v, "+
+				"hostGroup %v uses %v", vrt.Spec.HostGroupPersistenceProfile, vrt.Namespace, vrt.Name,
+				vrt.Spec.HostGroup, profile)
+		}
+	}
+	return profile
+}
+
+// checkHostGroupPersistenceConflict records an event on the VirtualServers of a HostGroup when
+// their Policies in different namespaces have different persistence profiles, unless the
+// persistence of the HostGroup is overridden with hostGroupPersistenceProfile
+func (ctlr *Controller) checkHostGroupPersistenceConflict(virtuals []*cisapiv1.VirtualServer) {
+	if len(virtuals) == 0 || virtuals[0].Spec.HostGroup == "" || getHostGroupPersistenceProfile(virtuals) != "" {
+		return
+	}
+	persistence := make(map[string][]string)
+	var withPolicy []*cisapiv1.VirtualServer
+	for _, vrt := range virtuals {
+		if vrt.Spec.PolicyName == "" {
+			continue
+		}
+		crInf, ok := ctlr.getNamespacedCommonInformer(vrt.Namespace)
+		if !ok {
+			continue
+		}
+		obj, found, _ := crInf.plcInformer.GetIndexer().GetByKey(vrt.Namespace + "/" + vrt.Spec.PolicyName)
+		if !found {
+			continue
+		}
+		profile := obj.(*cisapiv1.Policy).Spec.Profiles.PersistenceProfile
+		if profile == "" {
+			continue
+		}
+		persistence[profile] = append(persistence[profile], vrt.Namespace+"/"+vrt.Spec.PolicyName)
+		withPolicy = append(withPolicy, vrt)
+	}
+	if len(persistence) < 2 {
+		return
+	}
+	var conflicts []string
+	for profile, policies := range persistence {
+		conflicts = append(conflicts, fmt.Sprintf("%v (%v)", profile, strings.Join(policies, ", ")))
+	}
+	sort.Strings(conflicts)
+	message := fmt.Sprintf("Policies of hostGroup %v have conflicting persistence profiles: %v, "+
+		"use hostGroupPersistenceProfile to set the persistence of the hostGroup",
+		virtuals[0].Spec.HostGroup, strings.Join(conflicts, "; "))
+	log.Warning(message)
+	for _, vrt := range withPolicy {
+		ctlr.recordVirtualServerEvent(vrt, v1.EventTypeWarning, PersistenceConflictReason, message)
+	}
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("HostGroup Persistence Tests", func() {
+	var mockCtlr *mockController
+	var virtuals []*cisapiv1.VirtualServer
+	namespaces := []string{"ns1", "ns2", "ns3"}
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = make(map[string]bool)
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.TeemData = &teem.TeemsData{
+			ResourceType: teem.ResourceTypes{
+				VirtualServer: make(map[string]int),
+			},
+		}
+
+		// policies of ns1 and ns2 use cookie persistence whereas ns3 uses source address persistence
+		persistence := map[string]string{"ns1": "cookie", "ns2": "cookie", "ns3": "source-address"}
+		virtuals = nil
+		for _, ns := range namespaces {
+			mockCtlr.namespaces[ns] = true
+			_ = mockCtlr.addNamespacedInformers(ns, false)
+			mockCtlr.addPolicy(test.NewPolicy("plc", ns, cisapiv1.PolicySpec{
+				Profiles: cisapiv1.ProfileSpec{PersistenceProfile: persistence[ns]},
+			}))
+			vs := test.NewVirtualServer("vs", ns, cisapiv1.VirtualServerSpec{
+				Host:                 ns + ".foo.com",
+				HostGroup:            "hg",
+				VirtualServerAddress: "10.1.1.1",
+				PolicyName:           "plc",
+				Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc", ServicePort: intstr.FromInt(80)}},
+			})
+			_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(ns).Create(context.TODO(), vs, metav1.CreateOptions{})
+			mockCtlr.addVirtualServer(vs)
+			virtuals = append(virtuals, vs)
+		}
+	})
+
+	persistenceConflicts := func() func() []string {
+		return func() []string {
+			var objects []string
+			for _, ns := range namespaces {
+				events, _ := mockCtlr.kubeClient.CoreV1().Events(ns).List(context.TODO(), metav1.ListOptions{})
+				for _, event := range events.Items {
+					if event.Reason == PersistenceConflictReason {
+						objects = append(objects, event.InvolvedObject.Namespace+"/"+event.InvolvedObject.Name)
+					}
+				}
+			}
+			return objects
+		}
+	}
+
+	It("Reports conflicting persistence of Policies in a HostGroup", func() {
+		plc, err := mockCtlr.getPolicyFromVirtuals(virtuals)
+		Expect(err).To(BeNil())
+		Expect(plc.Spec.Profiles.PersistenceProfile).To(Equal("cookie"))
+		Eventually(persistenceConflicts()).Should(ConsistOf("ns1/vs", "ns2/vs", "ns3/vs"))
+	})
+
+	It("Overrides persistence of Policies with HostGroup persistence", func() {
+		virtuals[2].Spec.HostGroupPersistenceProfile = "/Common/universal"
+		mockCtlr.addVirtualServer(virtuals[2])
+		Expect(getHostGroupPersistenceProfile(virtuals)).To(Equal("/Common/universal"))
+
+		Expect(mockCtlr.processVirtualServers(virtuals[0], false)).To(BeNil())
+		rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+		Expect(rsMap).To(HaveLen(1))
+		for _, rsCfg := range rsMap {
+			Expect(rsCfg.Virtual.PersistenceProfile).To(Equal("/Common/universal"))
+		}
+		Consistently(persistenceConflicts()).Should(BeEmpty(), "Overridden persistence should not be reported")
+	})
+})
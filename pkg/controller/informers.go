@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	routeapi "github.com/openshift/api/route/v1"
@@ -31,7 +32,9 @@ import (
 	cisinfv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/informers/externalversions/cis/v1"
 	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -142,6 +145,10 @@ func (comInfr *CommonInformer) start() {
 		go comInfr.secretsInformer.Run(comInfr.stopCh)
 		cacheSyncs = append(cacheSyncs, comInfr.secretsInformer.HasSynced)
 	}
+	if comInfr.netPolInformer != nil {
+		go comInfr.netPolInformer.Run(comInfr.stopCh)
+		cacheSyncs = append(cacheSyncs, comInfr.netPolInformer.HasSynced)
+	}
 	cache.WaitForNamedCacheSync(
 		"F5 CIS Ingress Controller",
 		comInfr.stopCh,
@@ -211,16 +218,45 @@ func (ctlr *Controller) getWatchingNamespaces() []string {
 			return nil
 		}
 		for _, ns := range nss.Items {
+			if ctlr.isExcludedNamespace(ns.Name) {
+				continue
+			}
 			namespaces = append(namespaces, ns.Name)
 		}
 		return namespaces
 	}
 	for ns, _ := range ctlr.namespaces {
+		if ctlr.isExcludedNamespace(ns) {
+			continue
+		}
 		namespaces = append(namespaces, ns)
 	}
 	return namespaces
 }
 
+// isExcludedNamespace checks whether the namespace is excluded from the watched namespaces
+func (ctlr *Controller) isExcludedNamespace(namespace string) bool {
+	return ctlr.excludedNamespaces[namespace]
+}
+
+// namespaceFilteredHandler wraps the event handler to skip the resources from excluded namespaces
+func (ctlr *Controller) namespaceFilteredHandler(handler cache.ResourceEventHandlerFuncs) cache.ResourceEventHandler {
+	return cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			if len(ctlr.excludedNamespaces) == 0 {
+				return true
+			}
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err != nil {
+				return true
+			}
+			namespace, _, _ := cache.SplitMetaNamespaceKey(key)
+			return !ctlr.isExcludedNamespace(namespace)
+		},
+		Handler: handler,
+	}
+}
+
 func (ctlr *Controller) addNamespacedInformers(
 	namespace string,
 	startInformer bool,
@@ -452,8 +488,9 @@ func (ctlr *Controller) newNamespacedCommonResourceInformer(
 		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 		crOptions,
 	)
-	//enable pod informer for nodeport local mode and openshift mode
-	if ctlr.PoolMemberType == NodePortLocal || ctlr.mode == OpenShiftMode {
+	//enable pod informer for nodeport local mode and openshift mode, and for the pod labels
+	//selected by NetworkPolicies
+	if ctlr.PoolMemberType == NodePortLocal || ctlr.mode == OpenShiftMode || ctlr.bigipPodCIDR != nil {
 		comInf.podInformer = cache.NewSharedIndexInformer(
 			cache.NewFilteredListWatchFromClient(
 				restClientv1,
@@ -466,47 +503,60 @@ func (ctlr *Controller) newNamespacedCommonResourceInformer(
 			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
 		)
 	}
+	if ctlr.bigipPodCIDR != nil {
+		comInf.netPolInformer = cache.NewSharedIndexInformer(
+			cache.NewFilteredListWatchFromClient(
+				ctlr.kubeClient.NetworkingV1().RESTClient(),
+				"networkpolicies",
+				namespace,
+				everything,
+			),
+			&networkingv1.NetworkPolicy{},
+			resyncPeriod,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
+	}
 	return comInf
 }
 
 func (ctlr *Controller) addCustomResourceEventHandlers(crInf *CRInformer) {
 	if crInf.vsInformer != nil {
 		crInf.vsInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueVirtualServer(obj) },
 				UpdateFunc: func(old, cur interface{}) { ctlr.enqueueUpdatedVirtualServer(old, cur) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedVirtualServer(obj) },
-			},
+			}),
 		)
 	}
 
 	if crInf.tlsInformer != nil {
 		crInf.tlsInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueTLSProfile(obj, Create) },
 				UpdateFunc: func(old, cur interface{}) { ctlr.enqueueTLSProfile(cur, Update) },
 				// DeleteFunc: func(obj interface{}) { ctlr.enqueueTLSProfile(obj) },
-			},
+			}),
 		)
 	}
 
 	if crInf.tsInformer != nil {
 		crInf.tsInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueTransportServer(obj) },
 				UpdateFunc: func(old, cur interface{}) { ctlr.enqueueUpdatedTransportServer(old, cur) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedTransportServer(obj) },
-			},
+			}),
 		)
 	}
 
 	if crInf.ilInformer != nil {
 		crInf.ilInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueIngressLink(obj) },
 				UpdateFunc: func(oldObj, newObj interface{}) { ctlr.enqueueUpdatedIngressLink(oldObj, newObj) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedIngressLink(obj) },
-			},
+			}),
 		)
 	}
 }
@@ -514,50 +564,50 @@ func (ctlr *Controller) addCustomResourceEventHandlers(crInf *CRInformer) {
 func (ctlr *Controller) addCommonResourceEventHandlers(comInf *CommonInformer) {
 	if comInf.svcInformer != nil {
 		comInf.svcInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueService(obj) },
 				UpdateFunc: func(obj, cur interface{}) { ctlr.enqueueUpdatedService(obj, cur) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedService(obj) },
-			},
+			}),
 		)
 	}
 
 	if comInf.epsInformer != nil {
 		comInf.epsInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueEndpoints(obj, Create) },
 				UpdateFunc: func(obj, cur interface{}) { ctlr.enqueueEndpoints(cur, Update) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueEndpoints(obj, Delete) },
-			},
+			}),
 		)
 	}
 
 	if comInf.ednsInformer != nil {
 		comInf.ednsInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueExternalDNS(obj) },
 				UpdateFunc: func(oldObj, newObj interface{}) { ctlr.enqueueUpdatedExternalDNS(oldObj, newObj) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedExternalDNS(obj) },
-			})
+			}))
 	}
 
 	if comInf.plcInformer != nil {
 		comInf.plcInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueuePolicy(obj, Create) },
 				UpdateFunc: func(obj, cur interface{}) { ctlr.enqueuePolicy(cur, Update) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedPolicy(obj) },
-			},
+			}),
 		)
 	}
 
 	if comInf.podInformer != nil {
 		comInf.podInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueuePod(obj) },
 				UpdateFunc: func(obj, cur interface{}) { ctlr.enqueuePod(cur) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedPod(obj) },
-			},
+			}),
 		)
 	}
 
@@ -573,11 +623,21 @@ func (ctlr *Controller) addCommonResourceEventHandlers(comInf *CommonInformer) {
 
 	if comInf.secretsInformer != nil {
 		comInf.secretsInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueSecret(obj, Create) },
 				UpdateFunc: func(obj, cur interface{}) { ctlr.enqueueSecret(cur, Update) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueSecret(obj, Delete) },
-			},
+			}),
+		)
+	}
+
+	if comInf.netPolInformer != nil {
+		comInf.netPolInformer.AddEventHandler(
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
+				AddFunc:    func(obj interface{}) { ctlr.enqueueNetworkPolicy(obj) },
+				UpdateFunc: func(obj, cur interface{}) { ctlr.enqueueNetworkPolicy(cur) },
+				DeleteFunc: func(obj interface{}) { ctlr.enqueueNetworkPolicy(obj) },
+			}),
 		)
 	}
 
@@ -597,11 +657,11 @@ func (ctlr *Controller) addNativeResourceEventHandlers(nrInf *NRInformer) {
 
 	if nrInf.routeInformer != nil {
 		nrInf.routeInformer.AddEventHandler(
-			&cache.ResourceEventHandlerFuncs{
+			ctlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
 				AddFunc:    func(obj interface{}) { ctlr.enqueueRoute(obj, Create) },
 				UpdateFunc: func(old, cur interface{}) { ctlr.enqueueUpdatedRoute(old, cur) },
 				DeleteFunc: func(obj interface{}) { ctlr.enqueueRoute(obj, Delete) },
-			},
+			}),
 		)
 	}
 }
@@ -617,7 +677,7 @@ func (ctlr *Controller) getEventHandlerForIPAM() *cache.ResourceEventHandlerFunc
 func (ctlr *Controller) enqueueIPAM(obj interface{}) {
 	ipamObj := obj.(*ficV1.IPAM)
 
-	if ipamObj.Namespace+"/"+ipamObj.Name != ctlr.ipamCR {
+	if !ctlr.isIPAMCR(ipamObj) {
 		return
 	}
 
@@ -637,7 +697,7 @@ func (ctlr *Controller) enqueueUpdatedIPAM(oldObj, newObj interface{}) {
 	oldIpam := oldObj.(*ficV1.IPAM)
 	curIpam := newObj.(*ficV1.IPAM)
 
-	if curIpam.Namespace+"/"+curIpam.Name != ctlr.ipamCR {
+	if !ctlr.isIPAMCR(curIpam) {
 		return
 	}
 
@@ -660,7 +720,7 @@ func (ctlr *Controller) enqueueUpdatedIPAM(oldObj, newObj interface{}) {
 func (ctlr *Controller) enqueueDeletedIPAM(obj interface{}) {
 	ipamObj := obj.(*ficV1.IPAM)
 
-	if ipamObj.Namespace+"/"+ipamObj.Name != ctlr.ipamCR {
+	if !ctlr.isIPAMCR(ipamObj) {
 		return
 	}
 
@@ -695,12 +755,14 @@ func (ctlr *Controller) enqueueUpdatedVirtualServer(oldObj, newObj interface{})
 	newVS := newObj.(*cisapiv1.VirtualServer)
 	updateEvent := true
 	if oldVS.Spec.VirtualServerAddress != newVS.Spec.VirtualServerAddress ||
+		!reflect.DeepEqual(oldVS.Spec.VirtualServerAddresses, newVS.Spec.VirtualServerAddresses) ||
 		oldVS.Spec.VirtualServerHTTPPort != newVS.Spec.VirtualServerHTTPPort ||
 		oldVS.Spec.VirtualServerHTTPSPort != newVS.Spec.VirtualServerHTTPSPort ||
 		oldVS.Spec.VirtualServerName != newVS.Spec.VirtualServerName ||
 		oldVS.Spec.Host != newVS.Spec.Host ||
 		oldVS.Spec.IPAMLabel != newVS.Spec.IPAMLabel ||
-		oldVS.Spec.HostGroup != newVS.Spec.HostGroup {
+		oldVS.Spec.HostGroup != newVS.Spec.HostGroup ||
+		oldVS.Annotations[PartitionAnnotation] != newVS.Annotations[PartitionAnnotation] {
 		log.Debugf("Enqueueing Old VirtualServer: %v", oldVS)
 		key := &rqKey{
 			namespace: oldVS.ObjectMeta.Namespace,
@@ -777,7 +839,8 @@ func (ctlr *Controller) enqueueUpdatedTransportServer(oldObj, newObj interface{}
 		oldVS.Spec.VirtualServerPort != newVS.Spec.VirtualServerPort ||
 		oldVS.Spec.VirtualServerName != newVS.Spec.VirtualServerName ||
 		oldVS.Spec.IPAMLabel != newVS.Spec.IPAMLabel ||
-		oldVS.Spec.HostGroup != newVS.Spec.HostGroup {
+		oldVS.Spec.HostGroup != newVS.Spec.HostGroup ||
+		oldVS.Annotations[PartitionAnnotation] != newVS.Annotations[PartitionAnnotation] {
 		log.Debugf("Enqueueing TransportServer: %v", oldVS)
 		key := &rqKey{
 			namespace: oldVS.ObjectMeta.Namespace,
@@ -888,6 +951,10 @@ func (ctlr *Controller) enqueueUpdatedIngressLink(oldObj, newObj interface{}) {
 		ctlr.resourceQueue.Add(key)
 	}
 
+	if !newIngLink.Spec.ClusterMember || oldIngLink.Spec.Host != newIngLink.Spec.Host {
+		ctlr.requeueFederatedExternalDNS(oldIngLink)
+	}
+
 	log.Infof("Enqueueing IngressLink: %v on Update", newIngLink)
 	key := &rqKey{
 		namespace: newIngLink.ObjectMeta.Namespace,
@@ -1215,6 +1282,7 @@ func (ctlr *Controller) createNamespaceLabeledInformer(label string) error {
 	ctlr.nsInformers[label].nsInformer.AddEventHandlerWithResyncPeriod(
 		&cache.ResourceEventHandlerFuncs{
 			AddFunc:    func(obj interface{}) { ctlr.enqueueNamespace(obj) },
+			UpdateFunc: func(oldObj, newObj interface{}) { ctlr.enqueueUpdatedNamespace(oldObj, newObj) },
 			DeleteFunc: func(obj interface{}) { ctlr.enqueueDeletedNamespace(obj) },
 		},
 		resyncPeriod,
@@ -1223,6 +1291,26 @@ func (ctlr *Controller) createNamespaceLabeledInformer(label string) error {
 	return nil
 }
 
+// newNamespacePartitionInformer creates an informer of all namespaces to read their BIG-IP partition
+// annotations when the namespaces are not watched with namespace label informers
+func (ctlr *Controller) newNamespacePartitionInformer() cache.SharedIndexInformer {
+	nsInformer := cache.NewSharedIndexInformer(
+		cache.NewListWatchFromClient(
+			ctlr.kubeClient.CoreV1().RESTClient(),
+			"namespaces",
+			"",
+			fields.Everything(),
+		),
+		&corev1.Namespace{},
+		0*time.Second,
+		cache.Indexers{},
+	)
+	nsInformer.AddEventHandler(&cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) { ctlr.enqueueUpdatedNamespace(oldObj, newObj) },
+	})
+	return nsInformer
+}
+
 func (ctlr *Controller) enqueueNamespace(obj interface{}) {
 	ns := obj.(*corev1.Namespace)
 	log.Infof("Enqueueing Namespace: %v", ns)
@@ -1236,6 +1324,24 @@ func (ctlr *Controller) enqueueNamespace(obj interface{}) {
 	ctlr.resourceQueue.Add(key)
 }
 
+func (ctlr *Controller) enqueueUpdatedNamespace(oldObj, newObj interface{}) {
+	oldNS := oldObj.(*corev1.Namespace)
+	newNS := newObj.(*corev1.Namespace)
+	// Routes are processed again only for the change of their BIG-IP partition
+	if oldNS.Annotations[PartitionAnnotation] == newNS.Annotations[PartitionAnnotation] {
+		return
+	}
+	log.Infof("Enqueueing Namespace: %v on Update", newNS)
+	key := &rqKey{
+		namespace: newNS.ObjectMeta.Namespace,
+		kind:      Namespace,
+		rscName:   newNS.ObjectMeta.Name,
+		rsc:       newObj,
+		event:     Update,
+	}
+	ctlr.resourceQueue.Add(key)
+}
+
 func (ctlr *Controller) enqueueDeletedNamespace(obj interface{}) {
 	ns := obj.(*corev1.Namespace)
 	log.Infof("Enqueueing Namespace: %v on Delete", ns)
@@ -1257,3 +1363,26 @@ func (ctlr *Controller) checkCoreserviceLabels(labels map[string]string) bool {
 	}
 	return false
 }
+
+// newConfigMapInformer creates an informer watching only the ConfigMap with the namespace/name cmKey
+func (ctlr *Controller) newConfigMapInformer(cmKey string) (cache.SharedIndexInformer, error) {
+	splits := strings.Split(cmKey, "/")
+	if len(splits) != 2 {
+		return nil, fmt.Errorf("invalid ConfigMap %v, expected namespace/name", cmKey)
+	}
+	namespace, name := splits[0], splits[1]
+	optionsModifier := func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+	return cache.NewSharedIndexInformer(
+		cache.NewFilteredListWatchFromClient(
+			ctlr.kubeClient.CoreV1().RESTClient(),
+			"configmaps",
+			namespace,
+			optionsModifier,
+		),
+		&corev1.ConfigMap{},
+		0*time.Second,
+		cache.Indexers{},
+	), nil
+}
@@ -14,6 +14,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"sync"
 )
@@ -486,6 +487,41 @@ var _ = Describe("Informers Tests", func() {
 			//Expect(mockCtlr.processResources()).To(Equal(true))
 		})
 
+		It("Excluded Namespace", func() {
+			mockCtlr.namespaces["kube-system"] = true
+			mockCtlr.excludedNamespaces = map[string]bool{"kube-system": true}
+			Expect(mockCtlr.isExcludedNamespace("kube-system")).To(BeTrue())
+			Expect(mockCtlr.isExcludedNamespace(namespace)).To(BeFalse())
+			Expect(mockCtlr.getWatchingNamespaces()).To(Equal([]string{namespace}),
+				"Excluded namespace should not be watched")
+
+			handler := mockCtlr.namespaceFilteredHandler(cache.ResourceEventHandlerFuncs{
+				AddFunc:    func(obj interface{}) { mockCtlr.enqueueVirtualServer(obj) },
+				UpdateFunc: func(old, cur interface{}) { mockCtlr.enqueueUpdatedVirtualServer(old, cur) },
+				DeleteFunc: func(obj interface{}) { mockCtlr.enqueueDeletedVirtualServer(obj) },
+			})
+			excludedVS := test.NewVirtualServer(
+				"SampleVS",
+				"kube-system",
+				cisapiv1.VirtualServerSpec{
+					Host:                 "test.com",
+					VirtualServerAddress: "1.2.3.4",
+				})
+			handler.OnAdd(excludedVS)
+			handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "kube-system/SampleVS", Obj: excludedVS})
+			Expect(mockCtlr.resourceQueue.Len()).To(BeZero(), "VS in excluded namespace should not be enqueued")
+
+			vs := test.NewVirtualServer(
+				"SampleVS",
+				namespace,
+				cisapiv1.VirtualServerSpec{
+					Host:                 "test.com",
+					VirtualServerAddress: "1.2.3.4",
+				})
+			handler.OnAdd(vs)
+			Expect(mockCtlr.resourceQueue.Len()).To(Equal(1), "VS in watched namespace should be enqueued")
+		})
+
 		It("IPAM", func() {
 			mockCtlr.ipamCR = "default/SampleIPAM"
 
@@ -0,0 +1,236 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// federationUpdateRetries is the number of attempts to update the federation ConfigMap on conflicts
+const federationUpdateRetries = 5
+
+// federationInstanceKey returns the key of federation ConfigMap data owned by this CIS instance
+func (ctlr *Controller) federationInstanceKey() string {
+	if ctlr.controllerIdentifier != "" {
+		return AS3NameFormatter(ctlr.controllerIdentifier)
+	}
+	var bigIPURL string
+	if ctlr.Agent != nil && ctlr.Agent.PostManager != nil {
+		bigIPURL = strings.TrimPrefix(ctlr.Agent.BIGIPURL, "https://")
+	}
+	return AS3NameFormatter(bigIPURL + "_" + ctlr.Partition)
+}
+
+// isFederatedHost checks whether an IngressLink with clusterMember serves the domain
+func (ctlr *Controller) isFederatedHost(domainName string) bool {
+	var ingLinks []*cisapiv1.IngressLink
+	if ctlr.watchingAllNamespaces() {
+		ingLinks = ctlr.getAllIngressLinks("")
+	} else {
+		for ns := range ctlr.namespaces {
+			ingLinks = append(ingLinks, ctlr.getAllIngressLinks(ns)...)
+		}
+	}
+	for _, ingLink := range ingLinks {
+		if ingLink.Spec.ClusterMember && ingLink.Spec.Host != "" && matchesDomainName(domainName, ingLink.Spec.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// requeueFederatedExternalDNS requeues the ExternalDNS resources of the host of IngressLink with clusterMember,
+// so that the GTM pool members shared with other CIS instances are updated on deleting the IngressLink,
+// unsetting its clusterMember or changing its host
+func (ctlr *Controller) requeueFederatedExternalDNS(ingLink *cisapiv1.IngressLink) {
+	if ctlr.federationCMKey == "" || !ingLink.Spec.ClusterMember || ingLink.Spec.Host == "" {
+		return
+	}
+	for _, edns := range ctlr.getAllMonitoredExternalDNS() {
+		if matchesDomainName(edns.Spec.DomainName, ingLink.Spec.Host) {
+			ctlr.enqueueExternalDNS(edns)
+		}
+	}
+}
+
+// federateWideIP shares the members of the first pool of WideIP with the CIS instances of other clusters
+// and adds the members shared by them to the pool, when the WideIP serves an IngressLink with clusterMember
+func (ctlr *Controller) federateWideIP(wip *WideIP) {
+	if ctlr.federationCMKey == "" || (ctlr.Agent != nil && ctlr.Agent.ccclGTMAgent) {
+		return
+	}
+	var local []FederatedMember
+	federated := len(wip.Pools) > 0 && wip.Pools[0].RecordType != CNAMERecordType && ctlr.isFederatedHost(wip.DomainName)
+	if federated {
+		pool := &wip.Pools[0]
+		for _, mem := range pool.Members {
+			local = append(local, FederatedMember{Server: pool.DataServer, VirtualServer: mem})
+		}
+	}
+	remote, err := ctlr.updateFederatedMembers(wip.DomainName, local)
+	if err != nil {
+		log.Errorf("Unable to share the GTM pool members of %v in ConfigMap %v: %v",
+			wip.DomainName, ctlr.federationCMKey, err)
+	}
+	if federated {
+		wip.Pools[0].FederatedMembers = remote
+	}
+}
+
+// updateFederatedMembers replaces the members of the domain shared by this CIS instance in the
+// federation ConfigMap, and returns the members of the domain shared by the other CIS instances.
+// Each CIS instance updates only its own key, the update is retried on conflicting updates.
+func (ctlr *Controller) updateFederatedMembers(domainName string, local []FederatedMember) ([]FederatedMember, error) {
+	splits := strings.Split(ctlr.federationCMKey, "/")
+	if len(splits) != 2 {
+		return nil, fmt.Errorf("invalid federation ConfigMap %v", ctlr.federationCMKey)
+	}
+	namespace, name := splits[0], splits[1]
+	cmClient := ctlr.kubeClient.CoreV1().ConfigMaps(namespace)
+	instanceKey := ctlr.federationInstanceKey()
+	var err error
+	for i := 0; i < federationUpdateRetries; i++ {
+		// ConfigMap is read from the API server only to retry the conflicting updates
+		var cm *v1.ConfigMap
+		cm, err = ctlr.getFederationConfigMap(namespace, name, i > 0)
+		notFound := k8serrors.IsNotFound(err)
+		if notFound {
+			if len(local) == 0 {
+				return nil, nil
+			}
+			cm, err = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		remote := getFederatedMembers(cm, instanceKey)[domainName]
+
+		members := make(FederatedMembers)
+		if data, ok := cm.Data[instanceKey]; ok {
+			if err = json.Unmarshal([]byte(data), &members); err != nil {
+				log.Errorf("Invalid GTM pool members %v in ConfigMap %v: %v", instanceKey, ctlr.federationCMKey, err)
+				members = make(FederatedMembers)
+			}
+		}
+		if reflect.DeepEqual(members[domainName], local) || (len(local) == 0 && members[domainName] == nil) {
+			return remote, nil
+		}
+		if len(local) == 0 {
+			delete(members, domainName)
+		} else {
+			members[domainName] = local
+		}
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		if len(members) == 0 {
+			delete(cm.Data, instanceKey)
+		} else {
+			data, _ := json.Marshal(members)
+			cm.Data[instanceKey] = string(data)
+		}
+		var updated *v1.ConfigMap
+		if notFound {
+			updated, err = cmClient.Create(context.TODO(), cm, metav1.CreateOptions{})
+		} else {
+			updated, err = cmClient.Update(context.TODO(), cm, metav1.UpdateOptions{})
+		}
+		if err == nil {
+			// informer cache is updated right away, so that the next WideIP does not read the stale ConfigMap
+			if ctlr.federationCMInformer != nil {
+				_ = ctlr.federationCMInformer.GetStore().Update(updated)
+			}
+			return remote, nil
+		}
+		if !k8serrors.IsConflict(err) && !k8serrors.IsAlreadyExists(err) {
+			return remote, err
+		}
+		log.Debugf("Retrying the conflicting update of ConfigMap %v: %v", ctlr.federationCMKey, err)
+	}
+	return nil, err
+}
+
+// getFederationConfigMap returns a copy of the federation ConfigMap from the informer, or from the API
+// server when live is set or the informer is not created
+func (ctlr *Controller) getFederationConfigMap(namespace, name string, live bool) (*v1.ConfigMap, error) {
+	if live || ctlr.federationCMInformer == nil {
+		return ctlr.kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	}
+	obj, found, err := ctlr.federationCMInformer.GetStore().GetByKey(namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, k8serrors.NewNotFound(v1.Resource("configmaps"), name)
+	}
+	return obj.(*v1.ConfigMap).DeepCopy(), nil
+}
+
+// getFederatedMembers returns the members shared by the CIS instances other than the given one,
+// members of each domain are sorted to keep the declaration stable
+func getFederatedMembers(cm *v1.ConfigMap, instanceKey string) FederatedMembers {
+	var instanceKeys []string
+	for key := range cm.Data {
+		if key != instanceKey {
+			instanceKeys = append(instanceKeys, key)
+		}
+	}
+	sort.Strings(instanceKeys)
+	federatedMembers := make(FederatedMembers)
+	for _, key := range instanceKeys {
+		members := make(FederatedMembers)
+		if err := json.Unmarshal([]byte(cm.Data[key]), &members); err != nil {
+			log.Errorf("Invalid GTM pool members %v in ConfigMap %v/%v: %v", key, cm.Namespace, cm.Name, err)
+			continue
+		}
+		for domainName, mems := range members {
+			federatedMembers[domainName] = append(federatedMembers[domainName], mems...)
+		}
+	}
+	return federatedMembers
+}
+
+// handleFederationConfigMapUpdate enqueues the ExternalDNS resources of the domains whose GTM pool members
+// shared by other CIS instances changed with the update of federation ConfigMap
+func (ctlr *Controller) handleFederationConfigMapUpdate(oldObj, newObj interface{}) {
+	instanceKey := ctlr.federationInstanceKey()
+	prevMembers := make(FederatedMembers)
+	if cm, ok := oldObj.(*v1.ConfigMap); ok {
+		prevMembers = getFederatedMembers(cm, instanceKey)
+	}
+	members := make(FederatedMembers)
+	if cm, ok := newObj.(*v1.ConfigMap); ok {
+		members = getFederatedMembers(cm, instanceKey)
+	}
+	for _, edns := range ctlr.getAllMonitoredExternalDNS() {
+		domainName := edns.Spec.DomainName
+		if !reflect.DeepEqual(prevMembers[domainName], members[domainName]) {
+			log.Debugf("GTM pool members of %v shared by other CIS instances changed", domainName)
+			ctlr.enqueueExternalDNS(edns)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("IngressLink Federation Tests", func() {
+	var mockCtlr *mockController
+	var ingLink *cisapiv1.IngressLink
+	namespace := "default"
+	remoteMember := FederatedMember{Server: "/Common/Server2", VirtualServer: "/Common/Shared/ingress_link_10_2_2_2_443"}
+
+	newWideIP := func() WideIP {
+		return WideIP{
+			DomainName: "foo.com",
+			RecordType: "A",
+			Pools: []GSLBPool{{
+				Name:       "foo.com_pool",
+				RecordType: "A",
+				DataServer: "/Common/Server1",
+				Members:    []string{"/Common/Shared/ingress_link_10_1_1_1_443"},
+			}},
+		}
+	}
+
+	sharedMembers := func(instanceKey string) FederatedMembers {
+		cm, err := mockCtlr.kubeClient.CoreV1().ConfigMaps("kube-system").Get(
+			context.TODO(), "federation", metav1.GetOptions{})
+		Expect(err).To(BeNil())
+		data, ok := cm.Data[instanceKey]
+		if !ok {
+			return nil
+		}
+		members := make(FederatedMembers)
+		Expect(json.Unmarshal([]byte(data), &members)).To(BeNil())
+		return members
+	}
+
+	queuedExternalDNS := func() int {
+		count := 0
+		for mockCtlr.resourceQueue.Len() > 0 {
+			key, _ := mockCtlr.resourceQueue.Get()
+			if key.(*rqKey).kind == ExternalDNS {
+				count++
+			}
+			mockCtlr.resourceQueue.Done(key)
+		}
+		return count
+	}
+
+	BeforeEach(func() {
+		remoteData, _ := json.Marshal(FederatedMembers{"foo.com": {remoteMember}})
+		federationCM := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "federation", Namespace: "kube-system"},
+			Data:       map[string]string{"cluster2": string(remoteData)},
+		}
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset(federationCM)
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.Agent = newMockAgent(nil)
+		mockCtlr.controllerIdentifier = "cluster1"
+		mockCtlr.federationCMKey = "kube-system/federation"
+		mockCtlr.federationCMInformer, _ = mockCtlr.newConfigMapInformer(mockCtlr.federationCMKey)
+		_ = mockCtlr.federationCMInformer.GetStore().Add(federationCM.DeepCopy())
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+		mockCtlr.TeemData = &teem.TeemsData{ResourceType: teem.ResourceTypes{
+			IngressLink: make(map[string]int),
+		}}
+
+		ingLink = test.NewIngressLink("ingresslink1", namespace, "1", cisapiv1.IngressLinkSpec{
+			Host:          "foo.com",
+			ClusterMember: true,
+		})
+		mockCtlr.addIngressLink(ingLink)
+	})
+
+	It("Shares and aggregates the GTM pool members", func() {
+		wip := newWideIP()
+		mockCtlr.federateWideIP(&wip)
+		Expect(wip.Pools[0].FederatedMembers).To(Equal([]FederatedMember{remoteMember}))
+		Expect(sharedMembers("cluster1")).To(Equal(FederatedMembers{"foo.com": {{
+			Server:        "/Common/Server1",
+			VirtualServer: "/Common/Shared/ingress_link_10_1_1_1_443",
+		}}}))
+		Expect(sharedMembers("cluster2")).To(Equal(FederatedMembers{"foo.com": {remoteMember}}))
+
+		// ConfigMap is read from the informer and left unchanged without any changes in the members
+		fakeClient := mockCtlr.kubeClient.(*k8sfake.Clientset)
+		fakeClient.ClearActions()
+		wip = newWideIP()
+		mockCtlr.federateWideIP(&wip)
+		Expect(wip.Pools[0].FederatedMembers).To(Equal([]FederatedMember{remoteMember}))
+		Expect(fakeClient.Actions()).To(BeEmpty())
+
+		// Federated members are added to the GSLB pool of AS3 declaration
+		config := ResourceConfigRequest{gtmConfig: GTMConfig{DEFAULT_PARTITION: GTMPartitionConfig{
+			WideIPs: map[string]WideIP{wip.DomainName: wip}}}}
+		adc := mockCtlr.Agent.createAS3GTMConfigADC(config, as3ADC{})
+		tenant := adc[DEFAULT_PARTITION].(as3Tenant)
+		app := tenant[as3SharedApplication].(as3Application)
+		pool := app["foo.com_pool"].(as3GSLBPool)
+		Expect(len(pool.Members)).To(Equal(2))
+		member := pool.Members[1].(as3GSLBPoolMemberA)
+		Expect(member.Server.BigIP).To(Equal(remoteMember.Server))
+		Expect(member.VirtualServer).To(Equal(remoteMember.VirtualServer))
+	})
+
+	It("Does not federate WideIP without clusterMember IngressLink", func() {
+		wip := newWideIP()
+		wip.DomainName = "bar.com"
+		mockCtlr.federateWideIP(&wip)
+		Expect(wip.Pools[0].FederatedMembers).To(BeNil())
+		Expect(sharedMembers("cluster1")).To(BeNil())
+	})
+
+	It("Removes the shared members", func() {
+		wip := newWideIP()
+		mockCtlr.federateWideIP(&wip)
+		Expect(sharedMembers("cluster1")).NotTo(BeNil())
+
+		// ExternalDNS deleted
+		mockCtlr.federateWideIP(&WideIP{DomainName: "foo.com"})
+		Expect(sharedMembers("cluster1")).To(BeNil())
+		Expect(sharedMembers("cluster2")).NotTo(BeNil())
+	})
+
+	It("Retries conflicting updates of ConfigMap", func() {
+		conflicts := 0
+		mockCtlr.kubeClient.(*k8sfake.Clientset).PrependReactor("update", "configmaps",
+			func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if conflicts == 0 {
+					conflicts++
+					return true, nil, k8serrors.NewConflict(
+						schema.GroupResource{Resource: "configmaps"}, "federation", nil)
+				}
+				return false, nil, nil
+			})
+		wip := newWideIP()
+		mockCtlr.federateWideIP(&wip)
+		Expect(conflicts).To(Equal(1))
+		Expect(sharedMembers("cluster1")).NotTo(BeNil())
+	})
+
+	It("Creates the ConfigMap if not found", func() {
+		mockCtlr.federationCMKey = "kube-system/federation-new"
+		wip := newWideIP()
+		mockCtlr.federateWideIP(&wip)
+		Expect(len(wip.Pools[0].FederatedMembers)).To(Equal(0))
+		cm, err := mockCtlr.kubeClient.CoreV1().ConfigMaps("kube-system").Get(
+			context.TODO(), "federation-new", metav1.GetOptions{})
+		Expect(err).To(BeNil())
+		Expect(cm.Data).To(HaveKey("cluster1"))
+	})
+
+	It("Enqueues ExternalDNS on changes of members shared by other clusters", func() {
+		mockCtlr.addEDNS(test.NewExternalDNS("edns1", namespace, cisapiv1.ExternalDNSSpec{
+			DomainName: "foo.com",
+		}))
+		Expect(queuedExternalDNS()).To(Equal(1))
+		obj, _, _ := mockCtlr.federationCMInformer.GetStore().GetByKey("kube-system/federation")
+		oldCM := obj.(*v1.ConfigMap)
+
+		mockCtlr.handleFederationConfigMapUpdate(oldCM, oldCM.DeepCopy())
+		Expect(queuedExternalDNS()).To(Equal(0), "No changes in shared members")
+
+		newCM := oldCM.DeepCopy()
+		newCM.Data["cluster1"] = "{}"
+		mockCtlr.handleFederationConfigMapUpdate(oldCM, newCM)
+		Expect(queuedExternalDNS()).To(Equal(0), "Members shared by this cluster are ignored")
+
+		newCM.Data["cluster2"] = "{}"
+		mockCtlr.handleFederationConfigMapUpdate(oldCM, newCM)
+		Expect(queuedExternalDNS()).To(Equal(1))
+
+		// ConfigMap deleted
+		mockCtlr.handleFederationConfigMapUpdate(oldCM, nil)
+		Expect(queuedExternalDNS()).To(Equal(1))
+	})
+
+	It("Requeues ExternalDNS on deleting IngressLink with clusterMember", func() {
+		mockCtlr.addEDNS(test.NewExternalDNS("edns1", namespace, cisapiv1.ExternalDNSSpec{
+			DomainName: "foo.com",
+		}))
+		Expect(queuedExternalDNS()).To(Equal(1))
+		ingLink.Spec.VirtualServerAddress = "10.1.1.1"
+		mockCtlr.deleteIngressLink(ingLink)
+		Expect(mockCtlr.processIngressLink(ingLink, true)).To(BeNil())
+		Expect(queuedExternalDNS()).To(Equal(1))
+
+		// IngressLink without clusterMember
+		ingLink.Spec.ClusterMember = false
+		Expect(mockCtlr.processIngressLink(ingLink, true)).To(BeNil())
+		Expect(queuedExternalDNS()).To(Equal(0))
+	})
+
+	It("Requeues ExternalDNS on unsetting clusterMember or changing host of IngressLink", func() {
+		mockCtlr.addEDNS(test.NewExternalDNS("edns1", namespace, cisapiv1.ExternalDNSSpec{
+			DomainName: "foo.com",
+		}))
+		Expect(queuedExternalDNS()).To(Equal(1))
+		newIngLink := ingLink.DeepCopy()
+		newIngLink.Spec.IPAMLabel = "test"
+		mockCtlr.enqueueUpdatedIngressLink(ingLink, newIngLink)
+		Expect(queuedExternalDNS()).To(Equal(0), "clusterMember and host unchanged")
+
+		newIngLink.Spec.ClusterMember = false
+		mockCtlr.enqueueUpdatedIngressLink(ingLink, newIngLink)
+		Expect(queuedExternalDNS()).To(Equal(1))
+
+		newIngLink.Spec.ClusterMember = true
+		newIngLink.Spec.Host = "bar.com"
+		mockCtlr.enqueueUpdatedIngressLink(ingLink, newIngLink)
+		Expect(queuedExternalDNS()).To(Equal(1))
+	})
+})
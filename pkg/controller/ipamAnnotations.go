@@ -0,0 +1,92 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"encoding/json"
+	"reflect"
+
+	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+)
+
+const (
+	// IPAMHostAnnotations is the annotation of IPAM CR holding the IPAM annotations of virtuals
+	// as a JSON map of HostSpec key to annotations, as the HostSpec does not support annotations
+	IPAMHostAnnotations = "cis.f5.com/ipam-host-annotations"
+)
+
+// getIPAMAnnotations returns the IPAM annotations of the first virtual defining them
+func getIPAMAnnotations(virtuals []*cisapiv1.VirtualServer) map[string]string {
+	for _, vrt := range virtuals {
+		if len(vrt.Spec.IPAMAnnotations) > 0 {
+			return vrt.Spec.IPAMAnnotations
+		}
+	}
+	return nil
+}
+
+// setIPAMHostAnnotations sets the annotations of the HostSpec key in the IPAM CR,
+// empty annotations remove the entry of the key. Returns whether the IPAM CR is changed
+func setIPAMHostAnnotations(ipamCR *ficV1.IPAM, key string, annotations map[string]string) bool {
+	if key == "" {
+		return false
+	}
+	hostAnnotations := make(map[string]map[string]string)
+	if val, ok := ipamCR.Annotations[IPAMHostAnnotations]; ok {
+		if err := json.Unmarshal([]byte(val), &hostAnnotations); err != nil {
+			log.Warningf("[IPAM] Ignoring invalid annotation %v of IPAM CR: %v", IPAMHostAnnotations, err)
+			hostAnnotations = make(map[string]map[string]string)
+		}
+	}
+	current := hostAnnotations[key]
+	if (len(current) == 0 && len(annotations) == 0) || reflect.DeepEqual(current, annotations) {
+		return false
+	}
+	if len(annotations) == 0 {
+		delete(hostAnnotations, key)
+	} else {
+		hostAnnotations[key] = annotations
+	}
+	if len(hostAnnotations) == 0 {
+		delete(ipamCR.Annotations, IPAMHostAnnotations)
+		return true
+	}
+	data, err := json.Marshal(hostAnnotations)
+	if err != nil {
+		log.Errorf("[IPAM] Unable to marshal the IPAM annotations of %v: %v", key, err)
+		return false
+	}
+	if ipamCR.Annotations == nil {
+		ipamCR.Annotations = make(map[string]string)
+	}
+	ipamCR.Annotations[IPAMHostAnnotations] = string(data)
+	return true
+}
+
+// updateIPAMHostAnnotations updates the IPAM CR with the annotations of an already requested HostSpec
+func (ctlr *Controller) updateIPAMHostAnnotations(ipamCR *ficV1.IPAM, key string, annotations map[string]string) {
+	if !setIPAMHostAnnotations(ipamCR, key, annotations) {
+		return
+	}
+	if _, err := ctlr.ipamCli.Update(ipamCR); err != nil {
+		log.Errorf("[ipam] Error updating IPAM annotations of %v: %v", key, err)
+		return
+	}
+	log.Debugf("[ipam] Updated IPAM annotations of %v", key)
+}
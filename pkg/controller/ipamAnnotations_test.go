@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"github.com/F5Networks/f5-ipam-controller/pkg/ipammachinery"
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("IPAM Annotation Tests", func() {
+	var mockCtlr *mockController
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.TeemData = &teem.TeemsData{
+			ResourceType: teem.ResourceTypes{
+				VirtualServer: make(map[string]int),
+			},
+		}
+		mockCtlr.ipamCli = ipammachinery.NewFakeIPAMClient(nil, nil, nil)
+		_ = mockCtlr.createIPAMResource()
+	})
+
+	It("Passes the IPAM annotations of VirtualServer to the IPAM controller", func() {
+		vs := test.NewVirtualServer("vs", namespace, cisapiv1.VirtualServerSpec{
+			Host:            "foo.com",
+			IPAMLabel:       "test",
+			IPAMAnnotations: map[string]string{"vrf": "blue", "tenant": "acme"},
+			Pools:           []cisapiv1.Pool{{Path: "/", Service: "svc", ServicePort: intstr.FromInt(80)}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.processVirtualServers(vs, false)).To(BeNil())
+
+		ipamCR := mockCtlr.getIPAMCR("")
+		Expect(ipamCR.Spec.HostSpecs).To(HaveLen(1))
+		Expect(ipamCR.Spec.HostSpecs[0].Key).To(Equal("default/foo.com_host"))
+		Expect(ipamCR.Annotations).To(HaveKeyWithValue(IPAMHostAnnotations,
+			`{"default/foo.com_host":{"tenant":"acme","vrf":"blue"}}`))
+
+		// annotations updated after requesting the IP
+		ip, status := mockCtlr.requestIPWithAnnotations("test", "foo.com", "default/foo.com_host",
+			map[string]string{"vrf": "red"})
+		Expect(ip).To(BeEmpty())
+		Expect(status).To(Equal(Requested))
+		Expect(mockCtlr.getIPAMCR("").Annotations).To(HaveKeyWithValue(IPAMHostAnnotations,
+			`{"default/foo.com_host":{"vrf":"red"}}`))
+
+		// annotations of TransportServer are kept separately
+		_, _ = mockCtlr.requestIPWithAnnotations("test", "", "default/ts_ts", map[string]string{"pool-type": "l4"})
+		Expect(mockCtlr.getIPAMCR("").Annotations).To(HaveKeyWithValue(IPAMHostAnnotations,
+			`{"default/foo.com_host":{"vrf":"red"},"default/ts_ts":{"pool-type":"l4"}}`))
+
+		// annotations are removed along with the HostSpec
+		mockCtlr.releaseIP("test", "foo.com", "default/foo.com_host")
+		mockCtlr.releaseIP("test", "", "default/ts_ts")
+		ipamCR = mockCtlr.getIPAMCR("")
+		Expect(ipamCR.Spec.HostSpecs).To(BeEmpty())
+		Expect(ipamCR.Annotations).NotTo(HaveKey(IPAMHostAnnotations))
+	})
+})
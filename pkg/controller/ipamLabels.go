@@ -0,0 +1,101 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"time"
+
+	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+)
+
+// DefaultIPAMLabelRefreshInterval is the interval to refresh the IPAM labels of IPAM CR
+const DefaultIPAMLabelRefreshInterval = 5 * time.Minute
+
+// update refreshes the cached labels from the IPAM CRs.
+// IPAM CR carries only the requested labels in its spec and the allocated ones in its status,
+// so a label requested in the previous refresh which is still not allocated is considered
+// not served by the IPAM controller.
+func (lc *IPAMLabelCache) update(ipamCRs ...*ficV1.IPAM) {
+	lc.Lock()
+	defer lc.Unlock()
+	allocated := make(map[string]bool)
+	for _, ipamCR := range ipamCRs {
+		for _, ipst := range ipamCR.Status.IPStatus {
+			allocated[ipst.IPAMLabel] = true
+		}
+	}
+	pending := make(map[string]bool)
+	invalid := make(map[string]bool)
+	for _, ipamCR := range ipamCRs {
+		for _, hst := range ipamCR.Spec.HostSpecs {
+			if hst.IPAMLabel == "" || allocated[hst.IPAMLabel] {
+				continue
+			}
+			if lc.pending[hst.IPAMLabel] || lc.invalid[hst.IPAMLabel] {
+				invalid[hst.IPAMLabel] = true
+			} else {
+				pending[hst.IPAMLabel] = true
+			}
+		}
+	}
+	lc.allocated = allocated
+	lc.pending = pending
+	lc.invalid = invalid
+}
+
+// isInvalid checks whether the label is not served by the IPAM controller,
+// labels are not validated when IPAM is disabled
+func (lc *IPAMLabelCache) isInvalid(label string) bool {
+	if lc == nil {
+		return false
+	}
+	lc.RLock()
+	defer lc.RUnlock()
+	return lc.invalid[label]
+}
+
+// validateIPAMLabels refreshes the IPAM label cache from the IPAM CRs
+func (ctlr *Controller) validateIPAMLabels() {
+	if ctlr.ipamCli == nil || ctlr.ipamLabels == nil {
+		return
+	}
+	ipamCRs := ctlr.getIPAMCRs()
+	if len(ipamCRs) == 0 {
+		return
+	}
+	ctlr.ipamLabels.update(ipamCRs...)
+	ctlr.ipamLabels.RLock()
+	defer ctlr.ipamLabels.RUnlock()
+	for label := range ctlr.ipamLabels.invalid {
+		log.Warningf("[IPAM] IPAM label %v is not served by the IPAM controller", label)
+	}
+}
+
+// ipamLabelRefreshWorker periodically refreshes the IPAM labels of IPAM CR
+func (ctlr *Controller) ipamLabelRefreshWorker(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(ctlr.ipamLabelRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ctlr.validateIPAMLabels()
+		case <-stopCh:
+			return
+		}
+	}
+}
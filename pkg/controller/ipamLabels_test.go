@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+
+	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
+	"github.com/F5Networks/f5-ipam-controller/pkg/ipammachinery"
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("IPAM Label Tests", func() {
+	var mockCtlr *mockController
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.TeemData = &teem.TeemsData{
+			ResourceType: teem.ResourceTypes{
+				VirtualServer: make(map[string]int),
+			},
+		}
+		mockCtlr.Agent = &Agent{
+			PostManager: &PostManager{
+				PostParams: PostParams{
+					BIGIPURL: "10.10.10.1",
+				},
+			},
+		}
+		mockCtlr.ipamCli = ipammachinery.NewFakeIPAMClient(nil, nil, nil)
+		mockCtlr.ipamLabels = &IPAMLabelCache{}
+		_ = mockCtlr.createIPAMResource()
+	})
+
+	It("Rejects IPAM labels not served by the IPAM controller", func() {
+		ipamCR := mockCtlr.getIPAMCR("")
+		ipamCR.Spec.HostSpecs = []*ficV1.HostSpec{
+			{Host: "foo.com", Key: "default/foo.com_host", IPAMLabel: "test"},
+			{Host: "bar.com", Key: "default/bar.com_host", IPAMLabel: "missing"},
+		}
+		ipamCR.Status.IPStatus = []*ficV1.IPSpec{
+			{Host: "foo.com", Key: "default/foo.com_host", IPAMLabel: "test", IP: "10.1.1.1"},
+		}
+		_, _ = mockCtlr.ipamCli.Update(ipamCR)
+
+		mockCtlr.validateIPAMLabels()
+		Expect(mockCtlr.ipamLabels.isInvalid("missing")).To(BeFalse(),
+			"Label should not be rejected before the IPAM controller gets a chance to serve it")
+		mockCtlr.validateIPAMLabels()
+		Expect(mockCtlr.ipamLabels.isInvalid("missing")).To(BeTrue())
+		Expect(mockCtlr.ipamLabels.isInvalid("test")).To(BeFalse())
+		Expect(mockCtlr.ipamLabels.isInvalid("new")).To(BeFalse(), "Unknown labels should be requested")
+
+		ip, status := mockCtlr.requestIP("missing", "baz.com", "default/baz.com_host")
+		Expect(ip).To(BeEmpty())
+		Expect(status).To(Equal(InvalidInput))
+		Expect(mockCtlr.getIPAMCR("").Spec.HostSpecs).To(HaveLen(2), "IPAM CR should not be updated")
+
+		ip, status = mockCtlr.requestIP("test", "foo.com", "default/foo.com_host")
+		Expect(ip).To(Equal("10.1.1.1"))
+		Expect(status).To(Equal(Allocated))
+
+		ipamCR = mockCtlr.getIPAMCR("")
+		ipamCR.Status.IPStatus = append(ipamCR.Status.IPStatus,
+			&ficV1.IPSpec{Host: "bar.com", Key: "default/bar.com_host", IPAMLabel: "missing", IP: "10.1.1.2"})
+		_, _ = mockCtlr.ipamCli.Update(ipamCR)
+		mockCtlr.validateIPAMLabels()
+		Expect(mockCtlr.ipamLabels.isInvalid("missing")).To(BeFalse(), "Allocated label should be valid")
+	})
+
+	It("Updates the status of VirtualServer with invalid IPAM label", func() {
+		ipamCR := mockCtlr.getIPAMCR("")
+		ipamCR.Spec.HostSpecs = []*ficV1.HostSpec{
+			{Host: "bar.com", Key: "default/bar.com_host", IPAMLabel: "missing"},
+		}
+		_, _ = mockCtlr.ipamCli.Update(ipamCR)
+		mockCtlr.validateIPAMLabels()
+		mockCtlr.validateIPAMLabels()
+
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:      "foo.com",
+			IPAMLabel: "missing",
+		})
+		_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), vs, metav1.CreateOptions{})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.processVirtualServers(vs, false)).To(BeNil())
+		Expect(mockCtlr.getIPAMCR("").Spec.HostSpecs).To(HaveLen(1), "IPAM CR should not be updated")
+		vs, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), vs.Name, metav1.GetOptions{})
+		Expect(vs.Status.StatusOk).To(Equal(InvalidIPAMLabel))
+	})
+})
@@ -0,0 +1,94 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
+)
+
+// ipamNamespaces returns the namespaces of the IPAM CRs, the default IPAM namespace first
+// followed by the namespaces of ipam-label-namespace-map
+func (ctlr *Controller) ipamNamespaces() []string {
+	namespaces := []string{IPAMNamespace}
+	if cr := strings.Split(ctlr.ipamCR, "/"); len(cr) == 2 {
+		namespaces[0] = cr[0]
+	}
+	var mapped []string
+	seen := map[string]bool{namespaces[0]: true}
+	for _, namespace := range ctlr.ipamLabelNamespaces {
+		if !seen[namespace] {
+			seen[namespace] = true
+			mapped = append(mapped, namespace)
+		}
+	}
+	sort.Strings(mapped)
+	return append(namespaces, mapped...)
+}
+
+// getIPAMNamespace returns the namespace of the IPAM CR serving the IPAM label,
+// empty for the labels served by the default IPAM CR
+func (ctlr *Controller) getIPAMNamespace(ipamLabel string) string {
+	return ctlr.ipamLabelNamespaces[ipamLabel]
+}
+
+// getIPAMCRs returns the IPAM CRs of all the namespaces
+func (ctlr *Controller) getIPAMCRs() []*ficV1.IPAM {
+	var ipamCRs []*ficV1.IPAM
+	for _, namespace := range ctlr.ipamNamespaces() {
+		if ipamCR := ctlr.getIPAMCR(namespace); ipamCR != nil {
+			ipamCRs = append(ipamCRs, ipamCR)
+		}
+	}
+	return ipamCRs
+}
+
+// isIPAMCR checks whether the IPAM is the IPAM CR of this controller in any of the IPAM namespaces
+func (ctlr *Controller) isIPAMCR(ipam *ficV1.IPAM) bool {
+	cr := strings.Split(ctlr.ipamCR, "/")
+	if len(cr) != 2 || ipam.Name != cr[1] {
+		return false
+	}
+	for _, namespace := range ctlr.ipamNamespaces() {
+		if ipam.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseIPOfOtherIPAMCRs releases the IP requested for the host or key in the IPAM CRs other than ipamCR,
+// as the IPAM label of the host or key is updated to a label served by another IPAM controller
+func (ctlr *Controller) releaseIPOfOtherIPAMCRs(ipamCR *ficV1.IPAM, host string, key string) {
+	if len(ctlr.ipamLabelNamespaces) == 0 {
+		return
+	}
+	for _, otherCR := range ctlr.getIPAMCRs() {
+		if otherCR.Namespace == ipamCR.Namespace {
+			continue
+		}
+		for _, hst := range otherCR.Spec.HostSpecs {
+			if host != "" && hst.Host == host {
+				ctlr.releaseIP(hst.IPAMLabel, hst.Host, "")
+			} else if host == "" && hst.Key == key {
+				ctlr.releaseIP(hst.IPAMLabel, "", hst.Key)
+			}
+		}
+	}
+}
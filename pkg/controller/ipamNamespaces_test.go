@@ -0,0 +1,88 @@
+package controller
+
+import (
+	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
+	"github.com/F5Networks/f5-ipam-controller/pkg/ipammachinery"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("IPAM Namespaces Tests", func() {
+	var mockCtlr *mockController
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.ipamCli = ipammachinery.NewFakeIPAMClient(nil, nil, nil)
+		mockCtlr.ipamLabelNamespaces = map[string]string{
+			"team1": "ns1",
+			"team2": "ns2",
+		}
+		Expect(mockCtlr.createIPAMResource()).To(BeNil())
+	})
+
+	It("Creates the IPAM CRs in the mapped namespaces", func() {
+		Expect(mockCtlr.ipamNamespaces()).To(Equal([]string{IPAMNamespace, "ns1", "ns2"}))
+		Expect(mockCtlr.getIPAMCRs()).To(HaveLen(3))
+		Expect(mockCtlr.getIPAMCR("").Namespace).To(Equal(IPAMNamespace))
+		Expect(mockCtlr.getIPAMCR("ns1").Namespace).To(Equal("ns1"))
+		Expect(mockCtlr.getIPAMCR("ns3")).To(BeNil())
+	})
+
+	It("Requests and releases the IPs with the IPAM CR of the label", func() {
+		_, status := mockCtlr.requestIP("team1", "foo.com", "ns/foo.com_host")
+		Expect(status).To(Equal(Requested))
+		_, status = mockCtlr.requestIP("team2", "bar.com", "ns/bar.com_host")
+		Expect(status).To(Equal(Requested))
+		_, status = mockCtlr.requestIP("test", "baz.com", "ns/baz.com_host")
+		Expect(status).To(Equal(Requested))
+
+		hostSpecs := func(namespace string) []string {
+			var hosts []string
+			for _, hst := range mockCtlr.getIPAMCR(namespace).Spec.HostSpecs {
+				hosts = append(hosts, hst.Host)
+			}
+			return hosts
+		}
+		Expect(hostSpecs("ns1")).To(Equal([]string{"foo.com"}))
+		Expect(hostSpecs("ns2")).To(Equal([]string{"bar.com"}))
+		Expect(hostSpecs("")).To(Equal([]string{"baz.com"}), "Unmapped label should use the default IPAM CR")
+
+		// IP allocated by the IPAM controller of ns1
+		ipamCR := mockCtlr.getIPAMCR("ns1")
+		ipamCR.Status.IPStatus = []*ficV1.IPSpec{
+			{IPAMLabel: "team1", Host: "foo.com", Key: "ns/foo.com_host", IP: "10.1.1.1"},
+		}
+		_, _ = mockCtlr.ipamCli.Update(ipamCR)
+		ip, status := mockCtlr.requestIP("team1", "foo.com", "ns/foo.com_host")
+		Expect(status).To(Equal(Allocated))
+		Expect(ip).To(Equal("10.1.1.1"))
+
+		Expect(mockCtlr.releaseIP("team1", "foo.com", "ns/foo.com_host")).To(Equal("10.1.1.1"))
+		Expect(hostSpecs("ns1")).To(BeEmpty())
+		Expect(hostSpecs("ns2")).To(Equal([]string{"bar.com"}))
+	})
+
+	It("Releases the IP of another IPAM CR when the label is updated", func() {
+		_, status := mockCtlr.requestIP("team1", "", "ns/ts_ts")
+		Expect(status).To(Equal(Requested))
+		_, status = mockCtlr.requestIP("team2", "", "ns/ts_ts")
+		Expect(status).To(Equal(Requested))
+		Expect(mockCtlr.getIPAMCR("ns1").Spec.HostSpecs).To(BeEmpty())
+		Expect(mockCtlr.getIPAMCR("ns2").Spec.HostSpecs).To(HaveLen(1))
+	})
+
+	It("Enqueues the IPAM CRs of the mapped namespaces", func() {
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+		name := mockCtlr.getIPAMCR("").Name
+		mockCtlr.enqueueIPAM(test.NewIPAM(name, "ns1", ficV1.IPAMSpec{}, ficV1.IPAMStatus{}))
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(1))
+		mockCtlr.enqueueIPAM(test.NewIPAM(name, "ns3", ficV1.IPAMSpec{}, ficV1.IPAMStatus{}))
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(1), "IPAM CR of unmapped namespace should be ignored")
+		mockCtlr.enqueueIPAM(test.NewIPAM("other", "ns1", ficV1.IPAMSpec{}, ficV1.IPAMStatus{}))
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(1), "IPAM CR of other controller should be ignored")
+	})
+})
@@ -0,0 +1,122 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultMonitorIntervalPoolSizeBase is the default pool size per multiple of the monitor interval
+	DefaultMonitorIntervalPoolSizeBase = 10
+	// MonitorIntervalScaledReason is the reason of the event recorded on a VirtualServer whose
+	// monitor interval is scaled with the pool size
+	MonitorIntervalScaledReason = "MonitorIntervalScaled"
+	// defaultMonitorInterval is the AS3 default interval of monitors without interval
+	defaultMonitorInterval = 5
+)
+
+// scaleMonitorIntervals scales the interval of the monitors of pools larger than the pool size base
+// to base interval * ceil(pool size / pool size base), so that large pools do not overwhelm BIG-IP.
+// Timeout is scaled alike to keep it longer than the interval. MonitorIntervalScaled event is
+// recorded on the VirtualServers of rsCfg when the interval differs from the one of prevRsCfg.
+func (ctlr *Controller) scaleMonitorIntervals(rsCfg, prevRsCfg *ResourceConfig) {
+	if ctlr.monitorIntervalPoolSizeBase <= 0 || len(rsCfg.Monitors) == 0 {
+		return
+	}
+	// size of the largest pool of each monitor
+	poolSizes := make(map[string]int)
+	for _, pool := range rsCfg.Pools {
+		for _, monitorName := range pool.MonitorNames {
+			if monitorName.Reference == BIGIP {
+				continue
+			}
+			if len(pool.Members) > poolSizes[monitorName.Name] {
+				poolSizes[monitorName.Name] = len(pool.Members)
+			}
+		}
+	}
+	prevIntervals := make(map[string]int)
+	if prevRsCfg != nil {
+		for _, monitor := range prevRsCfg.Monitors {
+			prevIntervals[JoinBigipPath(monitor.Partition, monitor.Name)] = monitor.Interval
+		}
+	}
+	var scaled []string
+	for i := range rsCfg.Monitors {
+		monitor := &rsCfg.Monitors[i]
+		if !monitor.baseRecorded {
+			monitor.baseInterval = monitor.Interval
+			monitor.baseTimeout = monitor.Timeout
+			monitor.baseRecorded = true
+		}
+		monitor.Interval = monitor.baseInterval
+		monitor.Timeout = monitor.baseTimeout
+
+		monitorPath := JoinBigipPath(monitor.Partition, monitor.Name)
+		poolSize := poolSizes[monitorPath]
+		if poolSize <= ctlr.monitorIntervalPoolSizeBase {
+			continue
+		}
+		// ceil(pool size / pool size base)
+		factor := (poolSize + ctlr.monitorIntervalPoolSizeBase - 1) / ctlr.monitorIntervalPoolSizeBase
+		baseInterval := monitor.baseInterval
+		if baseInterval == 0 {
+			baseInterval = defaultMonitorInterval
+		}
+		monitor.Interval = baseInterval * factor
+		if monitor.baseTimeout > 0 {
+			monitor.Timeout = monitor.baseTimeout * factor
+		}
+		log.Debugf("Scaled interval of monitor %v to %v seconds for %v pool members",
+			monitorPath, monitor.Interval, poolSize)
+		if prev, ok := prevIntervals[monitorPath]; !ok || prev != monitor.Interval {
+			scaled = append(scaled, fmt.Sprintf("%v to %v seconds for %v pool members",
+				monitor.Name, monitor.Interval, poolSize))
+		}
+	}
+	if len(scaled) > 0 {
+		ctlr.recordMonitorIntervalScaledEvents(rsCfg, "Scaled the interval of monitors "+strings.Join(scaled, ", "))
+	}
+}
+
+// recordMonitorIntervalScaledEvents records MonitorIntervalScaled event on the VirtualServers of rsCfg
+func (ctlr *Controller) recordMonitorIntervalScaledEvents(rsCfg *ResourceConfig, message string) {
+	for rscKey, kind := range rsCfg.MetaData.baseResources {
+		if kind != VirtualServer {
+			continue
+		}
+		nsName := strings.SplitN(rscKey, "/", 2)
+		if len(nsName) != 2 {
+			continue
+		}
+		crInf, ok := ctlr.getNamespacedCRInformer(nsName[0])
+		if !ok {
+			continue
+		}
+		obj, exist, err := crInf.vsInformer.GetIndexer().GetByKey(rscKey)
+		if err != nil || !exist {
+			continue
+		}
+		ctlr.recordVirtualServerEvent(obj.(*cisapiv1.VirtualServer), v1.EventTypeNormal,
+			MonitorIntervalScaledReason, message)
+	}
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Monitor Interval Scaling Tests", func() {
+	var mockCtlr *mockController
+	var rsCfg *ResourceConfig
+	namespace := "default"
+
+	scaledEvents := func() int {
+		count := 0
+		events, _ := mockCtlr.kubeClient.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{})
+		for _, event := range events.Items {
+			if event.Reason == MonitorIntervalScaledReason {
+				count++
+			}
+		}
+		return count
+	}
+
+	setPoolSize := func(rsCfg *ResourceConfig, size int) {
+		rsCfg.Pools[0].Members = nil
+		for i := 0; i < size; i++ {
+			rsCfg.Pools[0].Members = append(rsCfg.Pools[0].Members,
+				PoolMember{Address: fmt.Sprintf("10.244.0.%v", i+1), Port: 8080})
+		}
+	}
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.monitorIntervalPoolSizeBase = 10
+
+		vs := test.NewVirtualServer("vs", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "foo.com",
+			VirtualServerAddress: "10.1.1.1",
+		})
+		_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), vs, metav1.CreateOptions{})
+		mockCtlr.addVirtualServer(vs)
+
+		rsCfg = &ResourceConfig{}
+		rsCfg.MetaData.baseResources = map[string]string{namespace + "/vs": VirtualServer}
+		rsCfg.Virtual.Partition = "test"
+		rsCfg.Monitors = Monitors{
+			{Name: "svc_monitor", Partition: "test", Interval: 5, Timeout: 16},
+			{Name: "svc_default_monitor", Partition: "test"},
+		}
+		rsCfg.Pools = Pools{{
+			Name:      "svc_pool",
+			Partition: "test",
+			MonitorNames: []MonitorName{
+				{Name: "/test/svc_monitor"},
+				{Name: "/test/svc_default_monitor"},
+				{Name: "/Common/http", Reference: BIGIP},
+			},
+		}}
+	})
+
+	It("Scales the monitor interval with the pool size", func() {
+		setPoolSize(rsCfg, 25)
+		mockCtlr.scaleMonitorIntervals(rsCfg, nil)
+		Expect(rsCfg.Monitors[0].Interval).To(Equal(15))
+		Expect(rsCfg.Monitors[0].Timeout).To(Equal(48))
+		Expect(rsCfg.Monitors[1].Interval).To(Equal(defaultMonitorInterval*3),
+			"Default interval should be scaled for monitors without interval")
+		Expect(rsCfg.Monitors[1].Timeout).To(Equal(0))
+		Eventually(scaledEvents).Should(Equal(1))
+
+		// Pool members updated without change in interval
+		freshRsCfg := &ResourceConfig{}
+		freshRsCfg.copyConfig(rsCfg)
+		setPoolSize(freshRsCfg, 30)
+		mockCtlr.scaleMonitorIntervals(freshRsCfg, rsCfg)
+		Expect(freshRsCfg.Monitors[0].Interval).To(Equal(15))
+		Consistently(scaledEvents).Should(Equal(1), "Event should not be recorded for unchanged interval")
+
+		// Pool scaled up
+		rsCfg = freshRsCfg
+		freshRsCfg = &ResourceConfig{}
+		freshRsCfg.copyConfig(rsCfg)
+		setPoolSize(freshRsCfg, 31)
+		mockCtlr.scaleMonitorIntervals(freshRsCfg, rsCfg)
+		Expect(freshRsCfg.Monitors[0].Interval).To(Equal(20))
+		Expect(freshRsCfg.Monitors[0].Timeout).To(Equal(64))
+		Eventually(scaledEvents).Should(Equal(2))
+
+		// Pool scaled down
+		setPoolSize(freshRsCfg, 5)
+		mockCtlr.scaleMonitorIntervals(freshRsCfg, nil)
+		Expect(freshRsCfg.Monitors[0].Interval).To(Equal(5))
+		Expect(freshRsCfg.Monitors[0].Timeout).To(Equal(16))
+		Expect(freshRsCfg.Monitors[1].Interval).To(Equal(0))
+	})
+
+	It("Does not scale the monitor interval of small pools", func() {
+		setPoolSize(rsCfg, 10)
+		mockCtlr.scaleMonitorIntervals(rsCfg, nil)
+		Expect(rsCfg.Monitors[0].Interval).To(Equal(5))
+		Expect(rsCfg.Monitors[0].Timeout).To(Equal(16))
+		Consistently(scaledEvents).Should(Equal(0))
+	})
+
+	It("Does not scale the monitor interval when disabled", func() {
+		mockCtlr.monitorIntervalPoolSizeBase = 0
+		setPoolSize(rsCfg, 100)
+		mockCtlr.scaleMonitorIntervals(rsCfg, nil)
+		Expect(rsCfg.Monitors[0].Interval).To(Equal(5))
+		Expect(rsCfg.Monitors[0].Timeout).To(Equal(16))
+	})
+})
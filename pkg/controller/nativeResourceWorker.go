@@ -2,7 +2,9 @@ package controller
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -45,6 +47,9 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 			return fmt.Errorf("extended Route Spec not available for RouteGroup/Namespace: %v", routeGroup)
 		}
 	}
+	partition = ctlr.getRouteGroupPartition(routeGroup, partition)
+	// Remove the virtuals of route group moved to another partition
+	ctlr.deleteRouteGroupVirtualsOfOtherPartitions(routeGroup, extdSpec, partition)
 	routes := ctlr.getGroupedRoutes(routeGroup)
 
 	if triggerDelete || len(routes) == 0 {
@@ -136,6 +141,22 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 			break
 		}
 
+		// passthrough connections persist on the pool member selected by the hash of SNI
+		if extdSpec.PassthroughPersistence && portStruct.protocol == "https" && hasPassthroughRoute(routes) {
+			handlePassthroughPersistence(rsCfg)
+		}
+
+		// Client certificates are authenticated with the CA certificate of route group
+		if extdSpec.ClientCACert != "" && portStruct.protocol == "https" {
+			caCert, err := ctlr.getRouteGroupClientCACert(extdSpec, routeGroup)
+			if err != nil {
+				processingError = true
+				log.Errorf("Unable to Process Route Group %s: %v", routeGroup, err)
+				break
+			}
+			rsCfg.Virtual.ClientCACert = caCert
+		}
+
 		// Save ResourceConfig in temporary Map
 		vsMap[rsName] = rsCfg
 		for _, namespace := range ctlr.resources.extdSpecMap[routeGroup].namespaces {
@@ -163,6 +184,85 @@ func (ctlr *Controller) processRoutes(routeGroup string, triggerDelete bool) err
 	return nil
 }
 
+// getRouteGroupPartition returns the partition of route group overridden with the
+// BIG-IP partition annotation of its namespaces. The partition of extended spec is used
+// if the namespaces of route group resolve to different partitions.
+func (ctlr *Controller) getRouteGroupPartition(routeGroup, partition string) string {
+	if _, ok := ctlr.resources.extdSpecMap[routeGroup]; !ok {
+		return partition
+	}
+	rgPartition := ""
+	for _, namespace := range ctlr.resources.extdSpecMap[routeGroup].namespaces {
+		nsPartition := ctlr.getNamespacePartition(namespace)
+		if nsPartition == "" {
+			nsPartition = partition
+		}
+		if rgPartition != "" && rgPartition != nsPartition {
+			log.Errorf("Namespaces of RouteGroup %v have conflicting %v annotations, using partition %v",
+				routeGroup, PartitionAnnotation, partition)
+			return partition
+		}
+		rgPartition = nsPartition
+	}
+	if rgPartition == "" {
+		return partition
+	}
+	return rgPartition
+}
+
+// getNamespacePartition returns the BIG-IP partition annotation of the namespace
+func (ctlr *Controller) getNamespacePartition(namespace string) string {
+	for _, nsInf := range ctlr.nsInformers {
+		obj, found, err := nsInf.nsInformer.GetIndexer().GetByKey(namespace)
+		if err == nil && found {
+			return obj.(*v1.Namespace).Annotations[PartitionAnnotation]
+		}
+	}
+	if ctlr.nsPartitionInformer == nil {
+		return ""
+	}
+	obj, found, err := ctlr.nsPartitionInformer.GetIndexer().GetByKey(namespace)
+	if err != nil || !found {
+		return ""
+	}
+	return obj.(*v1.Namespace).Annotations[PartitionAnnotation]
+}
+
+// deleteRouteGroupVirtualsOfOtherPartitions deletes the virtuals of route group from the partitions
+// other than the current partition of route group
+func (ctlr *Controller) deleteRouteGroupVirtualsOfOtherPartitions(
+	routeGroup string,
+	extdSpec *ExtendedRouteGroupSpec,
+	partition string,
+) {
+	namespaces := make(map[string]struct{})
+	if spec, ok := ctlr.resources.extdSpecMap[routeGroup]; ok {
+		for _, namespace := range spec.namespaces {
+			namespaces[namespace] = struct{}{}
+		}
+	}
+	for otherPartition := range ctlr.resources.ltmConfig {
+		if otherPartition == partition {
+			continue
+		}
+		for _, portStruct := range getBasicVirtualPorts() {
+			rsName := frameRouteVSName(extdSpec.VServerName, extdSpec.VServerAddr, portStruct)
+			vs := ctlr.getVirtualServer(otherPartition, rsName)
+			if vs == nil || vs.MetaData.ResourceType != VirtualServer {
+				continue
+			}
+			for rscKey, kind := range vs.MetaData.baseResources {
+				if _, ok := namespaces[strings.Split(rscKey, "/")[0]]; ok && kind == Route {
+					log.Debugf("Removing virtual %v of RouteGroup %v from partition %v",
+						rsName, routeGroup, otherPartition)
+					ctlr.deleteVirtualServer(otherPartition, rsName)
+					break
+				}
+			}
+		}
+	}
+}
+
 func (ctlr *Controller) getGroupedRoutes(routeGroup string) []*routeapi.Route {
 	var assocRoutes []*routeapi.Route
 	// Get the route group
@@ -368,6 +468,8 @@ func (ctlr *Controller) prepareResourceConfigFromRoute(
 				}
 			}
 		}
+		// Override the pool health monitor with route annotations
+		updateMonitorFromRouteAnnotations(rsCfg, &pool, route)
 
 		rsCfg.Pools = append(rsCfg.Pools, pool)
 		// skip the policy creation for passthrough termination
@@ -387,6 +489,56 @@ func (ctlr *Controller) prepareResourceConfigFromRoute(
 	return nil
 }
 
+// updateMonitorFromRouteAnnotations overrides the type, interval and timeout of pool health monitor
+// with health monitor annotations on route
+func updateMonitorFromRouteAnnotations(rsCfg *ResourceConfig, pool *Pool, route *routeapi.Route) {
+	monType, typeFound := route.Annotations[HealthMonitorTypeAnnotation]
+	interval, intervalFound := route.Annotations[HealthMonitorIntervalAnnotation]
+	timeout, timeoutFound := route.Annotations[HealthMonitorTimeoutAnnotation]
+	if !typeFound && !intervalFound && !timeoutFound {
+		return
+	}
+	monitorName := pool.Name + "_monitor"
+	updateMonitor := func(monitor *Monitor) {
+		if typeFound {
+			monitor.Type = monType
+		}
+		if val, err := strconv.Atoi(interval); intervalFound && err == nil {
+			monitor.Interval = val
+		}
+		if val, err := strconv.Atoi(timeout); timeoutFound && err == nil {
+			monitor.Timeout = val
+		}
+		switch monitor.Type {
+		case "http", "https":
+			if monitor.Send == "" {
+				monitor.Send = "GET /\r\n"
+			}
+		case "tcp", "icmp":
+			monitor.Send = ""
+			monitor.Recv = ""
+		}
+	}
+	found := false
+	for i := range rsCfg.Monitors {
+		if rsCfg.Monitors[i].Name == monitorName {
+			updateMonitor(&rsCfg.Monitors[i])
+			found = true
+		}
+	}
+	if found {
+		return
+	}
+	monitor := Monitor{
+		Name:      monitorName,
+		Partition: rsCfg.Virtual.Partition,
+		Type:      "http",
+	}
+	updateMonitor(&monitor)
+	rsCfg.Monitors = append(rsCfg.Monitors, monitor)
+	pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitor.Name})
+}
+
 // prepareRouteLTMRules prepares LTM Policy rules for VirtualServer
 func (ctlr *Controller) prepareRouteLTMRules(
 	route *routeapi.Route,
@@ -1189,6 +1341,15 @@ func isPassthroughRoute(route *routeapi.Route) bool {
 	return false
 }
 
+func hasPassthroughRoute(routes []*routeapi.Route) bool {
+	for _, route := range routes {
+		if isPassthroughRoute(route) {
+			return true
+		}
+	}
+	return false
+}
+
 func getBasicVirtualPorts() []portStruct {
 	return []portStruct{
 		{
@@ -1252,7 +1413,7 @@ func (ctlr *Controller) updateRouteAdmitStatus(
 		Admitted := false
 		now := metaV1.Now().Rfc3339Copy()
 		for _, routeIngress := range route.Status.Ingress {
-			if routeIngress.RouterName == F5RouterName {
+			if routeIngress.RouterName == ctlr.routerName() {
 				for _, condition := range routeIngress.Conditions {
 					if condition.Status == status {
 						Admitted = true
@@ -1267,7 +1428,7 @@ func (ctlr *Controller) updateRouteAdmitStatus(
 			return
 		}
 		route.Status.Ingress = append(route.Status.Ingress, routeapi.RouteIngress{
-			RouterName: F5RouterName,
+			RouterName: ctlr.routerName(),
 			Host:       route.Spec.Host,
 			Conditions: []routeapi.RouteIngressCondition{{
 				Type:               routeapi.RouteAdmitted,
@@ -1288,7 +1449,8 @@ func (ctlr *Controller) updateRouteAdmitStatus(
 	ctlr.eraseAllRouteAdmitStatus()
 }
 
-// remove the route admit status for routes which are not monitored by CIS anymore
+// remove the route admit status for routes which are not monitored by CIS anymore,
+// only the statuses set with the router name of this controller are erased
 func (ctlr *Controller) eraseAllRouteAdmitStatus() {
 	// Get the list of all unwatched Routes from all NS.
 	unmonitoredOptions := metaV1.ListOptions{
@@ -1326,6 +1488,15 @@ func (ctlr *Controller) GetHostFromHostPath(hostPath string) string {
 	return hostPath
 }
 
+// routerName returns the router name of the route admit status, prefixed with the
+// controller identifier to distinguish the CIS instances sharing the cluster
+func (ctlr *Controller) routerName() string {
+	if ctlr.controllerIdentifier == "" {
+		return F5RouterName
+	}
+	return ctlr.controllerIdentifier + " " + F5RouterName
+}
+
 func (ctlr *Controller) eraseRouteAdmitStatus(rscKey string) {
 	// Fetching the latest copy of route
 	route := ctlr.fetchRoute(rscKey)
@@ -1333,7 +1504,7 @@ func (ctlr *Controller) eraseRouteAdmitStatus(rscKey string) {
 		return
 	}
 	for i := 0; i < len(route.Status.Ingress); i++ {
-		if route.Status.Ingress[i].RouterName == F5RouterName {
+		if route.Status.Ingress[i].RouterName == ctlr.routerName() {
 			route.Status.Ingress = append(route.Status.Ingress[:i], route.Status.Ingress[i+1:]...)
 			erased := false
 			retryCount := 0
@@ -1406,7 +1577,7 @@ func (ctlr *Controller) checkValidRoute(route *routeapi.Route) bool {
 		}
 	case RouteCertificateSSLOption:
 		// Validate vsHostname if certificate is not provided in SSL annotations
-		ok := checkCertificateHost(route.Spec.Host, []byte(route.Spec.TLS.Certificate), []byte(route.Spec.TLS.Key))
+		ok := ctlr.validateCertificateHost(route.Spec.Host, []byte(route.Spec.TLS.Certificate), []byte(route.Spec.TLS.Key))
 		if !ok {
 			//Invalid certificate and key
 			message := fmt.Sprintf("Invalid certificate and key for route: %v", route.ObjectMeta.Name)
@@ -1430,6 +1601,33 @@ func (ctlr *Controller) checkValidRoute(route *routeapi.Route) bool {
 		return false
 	}
 
+	// Validate the health monitor annotations
+	if message := validateRouteMonitorAnnotations(route); message != "" {
+		log.Errorf(message)
+		go ctlr.updateRouteAdmitStatus(fmt.Sprintf("%v/%v", route.Namespace, route.Name), "ExtendedValidationFailed", message, v1.ConditionFalse)
+		return false
+	}
+
+	// Validate the client CA certificate of route group
+	routeGroup := ctlr.resources.invertedNamespaceLabelMap[route.Namespace]
+	var extdSpec *ExtendedRouteGroupSpec
+	if routeGroup == defaultRouteGroupName {
+		if spec, ok := ctlr.resources.extdSpecMap[routeGroup]; ok {
+			extdSpec = spec.defaultrg
+		}
+	} else {
+		extdSpec, _ = ctlr.resources.getExtendedRouteSpec(routeGroup)
+	}
+	if extdSpec != nil && extdSpec.ClientCACert != "" && route.Spec.TLS != nil {
+		if _, err := ctlr.getRouteGroupClientCACert(extdSpec, routeGroup); err != nil {
+			message := fmt.Sprintf("Discarding route %s as %v", route.Name, err)
+			log.Errorf(message)
+			go ctlr.updateRouteAdmitStatus(fmt.Sprintf("%s/%s", route.Namespace, route.Name),
+				"ExtendedValidationFailed", message, v1.ConditionFalse)
+			return false
+		}
+	}
+
 	// Validate the route service exists or not
 	err, _ := ctlr.getServicePort(route)
 	if err != nil {
@@ -1443,6 +1641,66 @@ func (ctlr *Controller) checkValidRoute(route *routeapi.Route) bool {
 	return true
 }
 
+// getRouteGroupClientCACert returns the CA certificate in the secret referred by clientCACert
+// of route group, the secret is looked up in the namespace of route group by default
+func (ctlr *Controller) getRouteGroupClientCACert(extdSpec *ExtendedRouteGroupSpec, routeGroup string) (string, error) {
+	namespace := extdSpec.ClientCACertNamespace
+	if namespace == "" {
+		namespace = routeGroup
+	}
+	comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
+	if !ok || comInf.secretsInformer == nil {
+		return "", fmt.Errorf("informer not found for client CA certificate secret %v/%v",
+			namespace, extdSpec.ClientCACert)
+	}
+	obj, found, err := comInf.secretsInformer.GetIndexer().GetByKey(namespace + "/" + extdSpec.ClientCACert)
+	if err != nil || !found {
+		return "", fmt.Errorf("client CA certificate secret %v/%v not found", namespace, extdSpec.ClientCACert)
+	}
+	caCert := obj.(*v1.Secret).Data["ca.crt"]
+	if !isValidCACertificate(caCert) {
+		return "", fmt.Errorf("client CA certificate secret %v/%v does not contain a valid CA certificate in ca.crt",
+			namespace, extdSpec.ClientCACert)
+	}
+	return string(caCert), nil
+}
+
+// isValidCACertificate checks whether the PEM data carries CA certificates only
+func isValidCACertificate(caCert []byte) bool {
+	var certs int
+	for block, rest := pem.Decode(caCert); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			return false
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || !cert.IsCA {
+			return false
+		}
+		certs++
+	}
+	return certs > 0
+}
+
+// validateRouteMonitorAnnotations returns the validation failure message for health monitor annotations on route
+func validateRouteMonitorAnnotations(route *routeapi.Route) string {
+	if monType, ok := route.Annotations[HealthMonitorTypeAnnotation]; ok {
+		switch monType {
+		case "http", "https", "tcp", "icmp":
+		default:
+			return fmt.Sprintf("Invalid health monitor type %v in annotation %v for route: %v",
+				monType, HealthMonitorTypeAnnotation, route.Name)
+		}
+	}
+	for _, annotation := range []string{HealthMonitorIntervalAnnotation, HealthMonitorTimeoutAnnotation} {
+		if val, ok := route.Annotations[annotation]; ok {
+			if num, err := strconv.Atoi(val); err != nil || num < 0 {
+				return fmt.Sprintf("Invalid value %v in annotation %v for route: %v", val, annotation, route.Name)
+			}
+		}
+	}
+	return ""
+}
+
 func (ctlr *Controller) updateHostPathMap(timestamp metav1.Time, key string) {
 	// This function updates the processedHostPathMap
 	ctlr.processedHostPath.Lock()
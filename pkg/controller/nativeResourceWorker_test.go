@@ -2,6 +2,11 @@ package controller
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/resource"
@@ -15,6 +20,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"math/big"
 	"strings"
 	"time"
 )
@@ -140,6 +146,62 @@ var _ = Describe("Routes", func() {
 			Expect(dg[ns].Records[0].Name).To(BeEquivalentTo("foo.com"), "Invalid vsHostname in datagroup")
 			Expect(dg[ns].Records[0].Data).To(BeEquivalentTo("foo_80_default"), "Invalid vsHostname in datagroup")
 		})
+		It("Passthrough Route with SNI persistence", func() {
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.resources.extdSpecMap[ns] = &extendedParsedSpec{
+				override: false,
+				global: &ExtendedRouteGroupSpec{
+					VServerName:            "samplevs",
+					VServerAddr:            "10.10.10.10",
+					AllowOverride:          "False",
+					PassthroughPersistence: true,
+				},
+				namespaces: []string{ns},
+				partition:  "test",
+			}
+			mockCtlr.resources.invertedNamespaceLabelMap[ns] = ns
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			mockCtlr.addService(test.NewService("foo", "1", ns, "NodePort", fooPorts))
+			persistIRule := NameRef{
+				Name:      getRSCfgResName("samplevs_443", PassthroughPersistIRuleName),
+				Partition: "test",
+			}
+
+			// insecure route is not persisted on SNI
+			route1 := test.NewRoute("route1", "1", ns, routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "foo"},
+			}, nil)
+			mockCtlr.addRoute(route1)
+			Expect(mockCtlr.processRoutes(ns, false)).To(BeNil())
+			for _, rsCfg := range mockCtlr.resources.ltmConfig["test"].ResourceMap {
+				Expect(rsCfg.IRulesMap).NotTo(HaveKey(persistIRule), "iRule should be created only for passthrough routes")
+			}
+
+			route2 := test.NewRoute("route2", "1", ns, routeapi.RouteSpec{
+				Host: "bar.com",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "foo"},
+				TLS:  &routeapi.TLSConfig{Termination: TLSPassthrough},
+			}, nil)
+			mockCtlr.addRoute(route2)
+			Expect(mockCtlr.processRoutes(ns, false)).To(BeNil())
+			rsCfg := mockCtlr.resources.ltmConfig["test"].ResourceMap["samplevs_443"]
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(rsCfg.IRulesMap).To(HaveKey(persistIRule))
+			Expect(rsCfg.IRulesMap[persistIRule].Code).To(ContainSubstring("crc32"))
+			Expect(rsCfg.Virtual.IRules).To(ContainElement(JoinBigipPath("test", persistIRule.Name)))
+
+			// iRule is referred in AS3 service with the CIS iRules
+			svc := &as3Service{}
+			processIrulesForCRD(rsCfg, svc)
+			Expect(svc.IRules).To(ContainElement(persistIRule.Name))
+
+			mockCtlr.resources.extdSpecMap[ns].global.PassthroughPersistence = false
+			Expect(mockCtlr.processRoutes(ns, false)).To(BeNil())
+			rsCfg = mockCtlr.resources.ltmConfig["test"].ResourceMap["samplevs_443"]
+			Expect(rsCfg.IRulesMap).NotTo(HaveKey(persistIRule), "iRule should be removed when persistence is disabled")
+		})
 
 		It("Route Admit Status", func() {
 			spec1 := routeapi.RouteSpec{
@@ -195,6 +257,33 @@ var _ = Describe("Routes", func() {
 			route = mockCtlr.fetchRoute(rskey)
 			Expect(len(route.Status.Ingress)).To(BeEquivalentTo(0), "Incorrect route admit status")
 		})
+		It("Route Admit Status with controller identifier", func() {
+			spec1 := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+			}
+			route1 := test.NewRoute("route1", "1", "default", spec1, nil)
+			mockCtlr.addRoute(route1)
+			rskey := fmt.Sprintf("%v/%v", route1.Namespace, route1.Name)
+			// status set by another controller sharing the cluster
+			mockCtlr.controllerIdentifier = "cis-b"
+			mockCtlr.updateRouteAdmitStatus(rskey, "", "", v1.ConditionTrue)
+			mockCtlr.controllerIdentifier = "cis-a"
+			Expect(mockCtlr.routerName()).To(BeEquivalentTo("cis-a "+F5RouterName), "Incorrect router name")
+			mockCtlr.updateRouteAdmitStatus(rskey, "", "", v1.ConditionTrue)
+			route := mockCtlr.fetchRoute(rskey)
+			Expect(len(route.Status.Ingress)).To(BeEquivalentTo(2), "Incorrect route admit status")
+			Expect(route.Status.Ingress[1].RouterName).To(BeEquivalentTo("cis-a "+F5RouterName), "Incorrect router name")
+			// only the status set by this controller is erased
+			mockCtlr.eraseRouteAdmitStatus(rskey)
+			route = mockCtlr.fetchRoute(rskey)
+			Expect(len(route.Status.Ingress)).To(BeEquivalentTo(1), "Incorrect route admit status")
+			Expect(route.Status.Ingress[0].RouterName).To(BeEquivalentTo("cis-b "+F5RouterName), "Incorrect router name")
+		})
 		It("Check Valid Route", func() {
 			var cm *v1.ConfigMap
 			var data map[string]string
@@ -314,6 +403,123 @@ var _ = Describe("Routes", func() {
 			Expect(route3.Status.Ingress[0].Conditions[0].Status).To(BeEquivalentTo(v1.ConditionFalse), "Incorrect route admit status")
 			Expect(route3.Status.Ingress[0].Conditions[0].Reason).To(BeEquivalentTo("ServiceNotFound"), "Incorrect route admit reason")
 		})
+		It("Route Group Client CA Certificate", func() {
+			newCertificate := func(isCA bool) []byte {
+				key, _ := rsa.GenerateKey(rand.Reader, 2048)
+				template := &x509.Certificate{
+					SerialNumber:          big.NewInt(1),
+					Subject:               pkix.Name{CommonName: "client-ca"},
+					NotBefore:             time.Now(),
+					NotAfter:              time.Now().Add(time.Hour),
+					IsCA:                  isCA,
+					BasicConstraintsValid: true,
+				}
+				der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+				return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+			}
+			caCert := newCertificate(true)
+			Expect(isValidCACertificate(caCert)).To(BeTrue())
+			Expect(isValidCACertificate(newCertificate(false))).To(BeFalse(), "Non CA certificate should be invalid")
+			Expect(isValidCACertificate([]byte("invalid"))).To(BeFalse())
+
+			cmName := "escm"
+			cmNamespace := "system"
+			mockCtlr.routeSpecCMKey = cmNamespace + "/" + cmName
+			mockCtlr.resources = NewResourceStore()
+			data := make(map[string]string)
+			cm := test.NewConfigMap(cmName, "v1", cmNamespace, data)
+			data["extendedSpec"] = `
+extendedRouteSpec:
+   - namespace: default
+     vserverAddr: 10.8.3.11
+     vserverName: nextgenroutes
+     allowOverride: true
+     clientCACert: client-ca
+`
+			_, _ = mockCtlr.processConfigMap(cm, false)
+			extdSpec, _ := mockCtlr.resources.getExtendedRouteSpec("default")
+			Expect(extdSpec).NotTo(BeNil())
+			Expect(extdSpec.ClientCACert).To(Equal("client-ca"))
+
+			route := test.NewRoute("route1", "1", "default", routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "foo"},
+				TLS:  &routeapi.TLSConfig{Termination: TLSEdge},
+			}, nil)
+			Expect(mockCtlr.checkValidRoute(route)).To(BeFalse(), "Route with missing client CA secret should be invalid")
+			_, err := mockCtlr.getRouteGroupClientCACert(extdSpec, "default")
+			Expect(err).NotTo(BeNil())
+
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "client-ca", Namespace: "default"},
+				Data:       map[string][]byte{"ca.crt": newCertificate(false)},
+			}
+			_ = mockCtlr.comInformers["default"].secretsInformer.GetIndexer().Add(secret)
+			_, err = mockCtlr.getRouteGroupClientCACert(extdSpec, "default")
+			Expect(err).NotTo(BeNil(), "Secret without CA certificate should be invalid")
+
+			secret.Data["ca.crt"] = caCert
+			_ = mockCtlr.comInformers["default"].secretsInformer.GetIndexer().Update(secret)
+			cert, err := mockCtlr.getRouteGroupClientCACert(extdSpec, "default")
+			Expect(err).To(BeNil())
+			Expect(cert).To(Equal(string(caCert)))
+
+			// secret is looked up in clientCACertNamespace
+			extdSpec.ClientCACertNamespace = "test"
+			_, err = mockCtlr.getRouteGroupClientCACert(extdSpec, "default")
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("Route Partition from Namespace Annotation", func() {
+			mockCtlr.resources = NewResourceStore()
+			extdSpec := &ExtendedRouteGroupSpec{
+				VServerName:   "samplevs",
+				VServerAddr:   "10.10.10.10",
+				AllowOverride: "false",
+			}
+			mockCtlr.resources.extdSpecMap[ns] = &extendedParsedSpec{
+				override:   false,
+				global:     extdSpec,
+				namespaces: []string{ns},
+				partition:  "test",
+			}
+			route := test.NewRoute("route1", "1", ns, routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To:   routeapi.RouteTargetReference{Kind: "Service", Name: "foo"},
+			}, nil)
+			mockCtlr.addRoute(route)
+			fooPorts := []v1.ServicePort{{Port: 80, NodePort: 30001}}
+			mockCtlr.addService(test.NewService("foo", "1", ns, "NodePort", fooPorts))
+			mockCtlr.resources.invertedNamespaceLabelMap[ns] = ns
+			rsName := frameRouteVSName(extdSpec.VServerName, extdSpec.VServerAddr, portStruct{protocol: "http", port: 80})
+
+			namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:        ns,
+				Annotations: map[string]string{PartitionAnnotation: "tenant1"},
+			}}
+			mockCtlr.nsPartitionInformer = mockCtlr.newNamespacePartitionInformer()
+			_ = mockCtlr.nsPartitionInformer.GetStore().Add(namespace)
+			Expect(mockCtlr.processRoutes(ns, false)).To(BeNil())
+			Expect(mockCtlr.getVirtualServer("tenant1", rsName)).NotTo(BeNil(),
+				"Route should be in the partition of namespace annotation")
+			Expect(mockCtlr.getVirtualServer("test", rsName)).To(BeNil())
+
+			// route group with namespaces of conflicting partitions uses the partition of extended spec
+			mockCtlr.resources.extdSpecMap[ns].namespaces = []string{ns, "test"}
+			Expect(mockCtlr.getRouteGroupPartition(ns, "test")).To(Equal("test"))
+			mockCtlr.resources.extdSpecMap[ns].namespaces = []string{ns}
+
+			// route is moved back to the partition of extended spec on removing the annotation
+			namespace.Annotations = nil
+			_ = mockCtlr.nsPartitionInformer.GetStore().Update(namespace)
+			Expect(mockCtlr.processRoutes(ns, false)).To(BeNil())
+			Expect(mockCtlr.getVirtualServer("test", rsName)).NotTo(BeNil())
+			Expect(mockCtlr.getVirtualServer("tenant1", rsName)).To(BeNil(),
+				"Route should be removed from the partition of namespace annotation")
+		})
+
 		It("Check GSLB Support for Routes", func() {
 			var cm *v1.ConfigMap
 			var data map[string]string
@@ -652,6 +858,56 @@ extendedRouteSpec:
 
 		})
 
+		It("Checks health monitor annotations on Route", func() {
+			spec := routeapi.RouteSpec{
+				Host: "foo.com",
+				Path: "/foo",
+				To: routeapi.RouteTargetReference{
+					Kind: "Service",
+					Name: "foo",
+				},
+			}
+			annotations := map[string]string{
+				HealthMonitorTypeAnnotation:     "tcp",
+				HealthMonitorIntervalAnnotation: "10",
+				HealthMonitorTimeoutAnnotation:  "31",
+			}
+			route := test.NewRoute("route1", "1", "default", spec, annotations)
+			Expect(validateRouteMonitorAnnotations(route)).To(BeEmpty())
+
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Partition = "default"
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = "newroutes_80"
+			rsCfg.MetaData.Protocol = HTTP
+			rsCfg.Virtual.SetVirtualAddress("10.8.3.11", DEFAULT_HTTP_PORT)
+			ps := portStruct{HTTP, DEFAULT_HTTP_PORT}
+			Expect(mockCtlr.prepareResourceConfigFromRoute(rsCfg, route, intstr.IntOrString{IntVal: 80}, ps)).To(BeNil())
+			Expect(len(rsCfg.Monitors)).To(Equal(1), "Monitor should be created from annotations")
+			Expect(rsCfg.Monitors[0].Type).To(Equal("tcp"))
+			Expect(rsCfg.Monitors[0].Interval).To(Equal(10))
+			Expect(rsCfg.Monitors[0].Timeout).To(Equal(31))
+			Expect(rsCfg.Pools[0].MonitorNames).To(Equal([]MonitorName{{Name: rsCfg.Monitors[0].Name}}))
+
+			// Annotations override the monitor from legacy health annotation
+			route.Annotations[LegacyHealthMonitorAnnotation] = `[{"path": "foo.com/foo", "send": "GET /health", "interval": 5, "timeout": 16}]`
+			delete(route.Annotations, HealthMonitorTypeAnnotation)
+			rsCfg.Monitors = nil
+			rsCfg.Pools = nil
+			Expect(mockCtlr.prepareResourceConfigFromRoute(rsCfg, route, intstr.IntOrString{IntVal: 80}, ps)).To(BeNil())
+			Expect(len(rsCfg.Monitors)).To(Equal(1))
+			Expect(rsCfg.Monitors[0].Type).To(Equal("http"))
+			Expect(rsCfg.Monitors[0].Send).To(Equal("GET /health"))
+			Expect(rsCfg.Monitors[0].Interval).To(Equal(10))
+			Expect(rsCfg.Monitors[0].Timeout).To(Equal(31))
+
+			route.Annotations[HealthMonitorTypeAnnotation] = "udp"
+			Expect(validateRouteMonitorAnnotations(route)).NotTo(BeEmpty(), "Invalid monitor type should fail")
+			route.Annotations[HealthMonitorTypeAnnotation] = "icmp"
+			route.Annotations[HealthMonitorIntervalAnnotation] = "ten"
+			Expect(validateRouteMonitorAnnotations(route)).NotTo(BeEmpty(), "Invalid monitor interval should fail")
+		})
 		It("Check Route A/B Deploy", func() {
 			routeGroup := "default"
 			mockCtlr.resources = NewResourceStore()
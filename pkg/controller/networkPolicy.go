@@ -0,0 +1,206 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"net"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/cache"
+)
+
+// enqueueNetworkPolicy enqueues the endpoints of the namespace of NetworkPolicy to
+// update the pool members allowed by the policy
+func (ctlr *Controller) enqueueNetworkPolicy(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+	log.Debugf("Enqueueing endpoints of namespace %v for NetworkPolicy %v", namespace, name)
+	comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
+	if !ok || comInf.epsInformer == nil {
+		return
+	}
+	epsList, _ := comInf.epsInformer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	for _, obj := range epsList {
+		ep := obj.(*v1.Endpoints)
+		ctlr.enqueuePoolMembersUpdate(ep.Namespace, ep.Name, 0)
+	}
+}
+
+// disableMembersBlockedByNetworkPolicy disables the members whose pods are not allowed
+// by the NetworkPolicies to receive ingress traffic from BIG-IP
+func (ctlr *Controller) disableMembersBlockedByNetworkPolicy(
+	members []PoolMember,
+	namespace string,
+) []PoolMember {
+	if ctlr.bigipPodCIDR == nil {
+		return members
+	}
+	comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
+	if !ok || comInf.netPolInformer == nil {
+		return members
+	}
+	policies, _ := comInf.netPolInformer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if len(policies) == 0 {
+		return members
+	}
+	// copy the members as they are shared with pool member cache
+	result := make([]PoolMember, len(members))
+	for i, member := range members {
+		result[i] = member
+		if member.Session == DrainingMemberSession {
+			continue
+		}
+		pod := ctlr.getPodForMemberIP(namespace, member.Address)
+		if pod == nil {
+			continue
+		}
+		if !ctlr.isIngressAllowedByNetworkPolicies(pod, member.Port, policies) {
+			log.Debugf("Disabling pool member %v:%v of pod %v/%v blocked by NetworkPolicies",
+				member.Address, member.Port, pod.Namespace, pod.Name)
+			result[i].Session = DrainingMemberSession
+		}
+	}
+	return result
+}
+
+// isIngressAllowedByNetworkPolicies returns true when the pod is not isolated for ingress by
+// any NetworkPolicy, or any of the policies isolating it allows ingress from BIG-IP on the port
+func (ctlr *Controller) isIngressAllowedByNetworkPolicies(pod *v1.Pod, port int32, policies []interface{}) bool {
+	isolated := false
+	for _, obj := range policies {
+		policy := obj.(*networkingv1.NetworkPolicy)
+		if !isIngressPolicy(policy) {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil {
+			log.Errorf("Invalid pod selector of NetworkPolicy %v/%v: %v", policy.Namespace, policy.Name, err)
+			continue
+		}
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		isolated = true
+		for _, rule := range policy.Spec.Ingress {
+			if ctlr.isIngressRuleAllowingBigIP(rule, pod, port) {
+				return true
+			}
+		}
+	}
+	return !isolated
+}
+
+// isIngressPolicy returns true for the NetworkPolicies isolating the pods for ingress
+func isIngressPolicy(policy *networkingv1.NetworkPolicy) bool {
+	// policies without types affect the ingress
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true
+	}
+	for _, policyType := range policy.Spec.PolicyTypes {
+		if policyType == networkingv1.PolicyTypeIngress {
+			return true
+		}
+	}
+	return false
+}
+
+// isIngressRuleAllowingBigIP returns true when the ingress rule allows the traffic from
+// BIG-IP CIDR to the port of pod
+func (ctlr *Controller) isIngressRuleAllowingBigIP(rule networkingv1.NetworkPolicyIngressRule, pod *v1.Pod, port int32) bool {
+	if !isPortAllowedByRule(rule.Ports, pod, port) {
+		return false
+	}
+	// rule without peers allows all the sources
+	if len(rule.From) == 0 {
+		return true
+	}
+	for _, peer := range rule.From {
+		// BIG-IP is outside the cluster, hence only the IP blocks can select it
+		if peer.IPBlock != nil && isCIDRAllowedByIPBlock(ctlr.bigipPodCIDR, peer.IPBlock) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPortAllowedByRule returns true when the port of pod is one of the ports of rule
+func isPortAllowedByRule(ports []networkingv1.NetworkPolicyPort, pod *v1.Pod, port int32) bool {
+	// rule without ports allows all the ports
+	if len(ports) == 0 {
+		return true
+	}
+	for _, policyPort := range ports {
+		if policyPort.Protocol != nil && *policyPort.Protocol != v1.ProtocolTCP {
+			continue
+		}
+		if policyPort.Port == nil {
+			return true
+		}
+		if policyPort.Port.Type == intstr.String {
+			for _, container := range pod.Spec.Containers {
+				for _, containerPort := range container.Ports {
+					if containerPort.Name == policyPort.Port.StrVal && containerPort.ContainerPort == port {
+						return true
+					}
+				}
+			}
+			continue
+		}
+		endPort := policyPort.Port.IntVal
+		if policyPort.EndPort != nil {
+			endPort = *policyPort.EndPort
+		}
+		if port >= policyPort.Port.IntVal && port <= endPort {
+			return true
+		}
+	}
+	return false
+}
+
+// isCIDRAllowedByIPBlock returns true when the CIDR is within the IP block and
+// does not overlap any of its exceptions
+func isCIDRAllowedByIPBlock(cidr *net.IPNet, ipBlock *networkingv1.IPBlock) bool {
+	_, blockNet, err := net.ParseCIDR(ipBlock.CIDR)
+	if err != nil || !containsCIDR(blockNet, cidr) {
+		return false
+	}
+	for _, except := range ipBlock.Except {
+		_, exceptNet, err := net.ParseCIDR(except)
+		if err != nil {
+			continue
+		}
+		if exceptNet.Contains(cidr.IP) || cidr.Contains(exceptNet.IP) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsCIDR returns true when the CIDR is a subnet of network
+func containsCIDR(network, cidr *net.IPNet) bool {
+	networkOnes, networkBits := network.Mask.Size()
+	cidrOnes, cidrBits := cidr.Mask.Size()
+	return networkBits == cidrBits && networkOnes <= cidrOnes && network.Contains(cidr.IP)
+}
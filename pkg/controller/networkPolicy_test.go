@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"net"
+
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("NetworkPolicy Tests", func() {
+	var mockCtlr *mockController
+	namespace := "default"
+	member := PoolMember{Address: "10.244.1.2", Port: 8080, Session: "user-enabled"}
+
+	newNetworkPolicy := func(name string, ingress []networkingv1.NetworkPolicyIngressRule) *networkingv1.NetworkPolicy {
+		return &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: networkingv1.NetworkPolicySpec{
+				PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Ingress:     ingress,
+			},
+		}
+	}
+
+	ipBlockRule := func(cidr string, except ...string) networkingv1.NetworkPolicyIngressRule {
+		return networkingv1.NetworkPolicyIngressRule{
+			From: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: cidr, Except: except}}},
+		}
+	}
+
+	addPolicy := func(policy *networkingv1.NetworkPolicy) {
+		_ = mockCtlr.comInformers[namespace].netPolInformer.GetIndexer().Add(policy)
+	}
+
+	disabled := func() bool {
+		members := mockCtlr.disableMembersBlockedByNetworkPolicy([]PoolMember{member}, namespace)
+		return members[0].Session == DrainingMemberSession
+	}
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_, mockCtlr.bigipPodCIDR, _ = net.ParseCIDR("10.1.1.0/24")
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.resources = NewResourceStore()
+
+		pod := test.NewPod("pod1", namespace, 8080, map[string]string{"app": "web"})
+		_ = mockCtlr.comInformers[namespace].podInformer.GetIndexer().Add(pod)
+		eps := test.NewEndpoints("svc1", "1", "node1", namespace,
+			[]string{member.Address}, nil, []v1.EndpointPort{{Name: "http", Port: 8080}})
+		eps.Subsets[0].Addresses[0].TargetRef = &v1.ObjectReference{Kind: "Pod", Name: "pod1", Namespace: namespace}
+		_ = mockCtlr.comInformers[namespace].epsInformer.GetIndexer().Add(eps)
+	})
+
+	It("Disables the members blocked by NetworkPolicies", func() {
+		Expect(mockCtlr.comInformers[namespace].netPolInformer).NotTo(BeNil())
+		Expect(disabled()).To(BeFalse(), "Member without NetworkPolicies should be enabled")
+
+		egress := newNetworkPolicy("egress", nil)
+		egress.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}
+		addPolicy(egress)
+		Expect(disabled()).To(BeFalse(), "Egress policy should not isolate the pod")
+
+		addPolicy(newNetworkPolicy("deny-all", nil))
+		Expect(disabled()).To(BeTrue(), "Member isolated for ingress should be disabled")
+
+		addPolicy(newNetworkPolicy("allow-bigip", []networkingv1.NetworkPolicyIngressRule{
+			ipBlockRule("10.0.0.0/8", "10.1.2.0/24")}))
+		Expect(disabled()).To(BeFalse(), "Member allowed by any of the policies should be enabled")
+
+		pod := test.NewPod("pod1", namespace, 8080, map[string]string{"app": "db"})
+		_ = mockCtlr.comInformers[namespace].podInformer.GetIndexer().Update(pod)
+		Expect(disabled()).To(BeFalse(), "Member of pod not selected should be enabled")
+	})
+
+	It("Checks the ingress rules against BIG-IP CIDR", func() {
+		pod := test.NewPod("pod1", namespace, 8080, nil)
+		pod.Spec.Containers[0].Ports[0].Name = "http"
+		Expect(mockCtlr.isIngressRuleAllowingBigIP(ipBlockRule("10.1.0.0/16"), pod, 8080)).To(BeTrue())
+		Expect(mockCtlr.isIngressRuleAllowingBigIP(ipBlockRule("10.1.1.128/25"), pod, 8080)).
+			To(BeFalse(), "IP block within BIG-IP CIDR should not allow it")
+		Expect(mockCtlr.isIngressRuleAllowingBigIP(ipBlockRule("10.0.0.0/8", "10.1.1.64/26"), pod, 8080)).
+			To(BeFalse(), "Exception overlapping BIG-IP CIDR should not allow it")
+		Expect(mockCtlr.isIngressRuleAllowingBigIP(networkingv1.NetworkPolicyIngressRule{
+			From: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}}, pod, 8080)).
+			To(BeFalse(), "Pod selector should not allow BIG-IP")
+		Expect(mockCtlr.isIngressRuleAllowingBigIP(networkingv1.NetworkPolicyIngressRule{}, pod, 8080)).
+			To(BeTrue(), "Rule without peers should allow all the sources")
+
+		httpPort := intstr.FromString("http")
+		rule := ipBlockRule("10.0.0.0/8")
+		rule.Ports = []networkingv1.NetworkPolicyPort{{Port: &httpPort}}
+		Expect(mockCtlr.isIngressRuleAllowingBigIP(rule, pod, 8080)).To(BeTrue(), "Named port should allow it")
+		port := intstr.FromInt(80)
+		endPort := int32(8000)
+		rule.Ports = []networkingv1.NetworkPolicyPort{{Port: &port, EndPort: &endPort}}
+		Expect(mockCtlr.isIngressRuleAllowingBigIP(rule, pod, 8080)).To(BeFalse(), "Port out of range")
+		endPort = 8080
+		Expect(mockCtlr.isIngressRuleAllowingBigIP(rule, pod, 8080)).To(BeTrue(), "Port in range")
+	})
+
+	It("Does not update the pool member cache", func() {
+		addPolicy(newNetworkPolicy("deny-all", nil))
+		memberMap := map[portRef][]PoolMember{{name: "http", port: 8080}: {member}}
+		mockCtlr.resources.poolMemCache[namespace+"/svc1"] = poolMembersInfo{
+			svcType:   v1.ServiceTypeClusterIP,
+			memberMap: memberMap,
+		}
+		rsCfg := &ResourceConfig{Pools: []Pool{{ServiceNamespace: namespace,
+			ServiceName: "svc1",
+			ServicePort: intstr.FromInt(8080)}}}
+		mockCtlr.updatePoolMembersForCluster(rsCfg, namespace)
+		Expect(rsCfg.Pools[0].Members[0].Session).To(Equal(DrainingMemberSession))
+		Expect(memberMap[portRef{name: "http", port: 8080}][0].Session).To(Equal("user-enabled"))
+	})
+})
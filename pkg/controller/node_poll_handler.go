@@ -1,11 +1,15 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
 	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vxlan"
+	"io/ioutil"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"reflect"
 	"sort"
 	"strings"
@@ -86,11 +90,11 @@ func (ctlr *Controller) ProcessNodeUpdate(
 						for _, virtual := range virtuals {
 							vs := virtual.(*cisapiv1.VirtualServer)
 							qKey := &rqKey{
-								vs.ObjectMeta.Namespace,
-								VirtualServer,
-								vs.ObjectMeta.Name,
-								vs,
-								Update,
+								namespace: vs.ObjectMeta.Namespace,
+								kind:      VirtualServer,
+								rscName:   vs.ObjectMeta.Name,
+								rsc:       vs,
+								event:     Update,
 							}
 							ctlr.resourceQueue.Add(qKey)
 						}
@@ -100,11 +104,11 @@ func (ctlr *Controller) ProcessNodeUpdate(
 						for _, virtual := range transportVirtuals {
 							vs := virtual.(*cisapiv1.TransportServer)
 							qKey := &rqKey{
-								vs.ObjectMeta.Namespace,
-								TransportServer,
-								vs.ObjectMeta.Name,
-								vs,
-								Update,
+								namespace: vs.ObjectMeta.Namespace,
+								kind:      TransportServer,
+								rscName:   vs.ObjectMeta.Name,
+								rsc:       vs,
+								event:     Update,
 							}
 							ctlr.resourceQueue.Add(qKey)
 						}
@@ -114,11 +118,11 @@ func (ctlr *Controller) ProcessNodeUpdate(
 						for _, ingressLink := range ingressLinks {
 							il := ingressLink.(*cisapiv1.IngressLink)
 							qKey := &rqKey{
-								il.ObjectMeta.Namespace,
-								IngressLink,
-								il.ObjectMeta.Name,
-								il,
-								Update,
+								namespace: il.ObjectMeta.Namespace,
+								kind:      IngressLink,
+								rscName:   il.ObjectMeta.Name,
+								rsc:       il,
+								event:     Update,
 							}
 							ctlr.resourceQueue.Add(qKey)
 						}
@@ -133,31 +137,31 @@ func (ctlr *Controller) ProcessNodeUpdate(
 						ingressLinks := ctlr.getAllIngressLinks(ns)
 						for _, virtual := range virtuals {
 							qKey := &rqKey{
-								ns,
-								VirtualServer,
-								virtual.ObjectMeta.Name,
-								virtual,
-								Update,
+								namespace: ns,
+								kind:      VirtualServer,
+								rscName:   virtual.ObjectMeta.Name,
+								rsc:       virtual,
+								event:     Update,
 							}
 							ctlr.resourceQueue.Add(qKey)
 						}
 						for _, virtual := range transportVirtuals {
 							qKey := &rqKey{
-								ns,
-								TransportServer,
-								virtual.ObjectMeta.Name,
-								virtual,
-								Update,
+								namespace: ns,
+								kind:      TransportServer,
+								rscName:   virtual.ObjectMeta.Name,
+								rsc:       virtual,
+								event:     Update,
 							}
 							ctlr.resourceQueue.Add(qKey)
 						}
 						for _, ingressLink := range ingressLinks {
 							qKey := &rqKey{
-								ns,
-								IngressLink,
-								ingressLink.ObjectMeta.Name,
-								ingressLink,
-								Update,
+								namespace: ns,
+								kind:      IngressLink,
+								rscName:   ingressLink.ObjectMeta.Name,
+								rsc:       ingressLink,
+								event:     Update,
 							}
 							ctlr.resourceQueue.Add(qKey)
 						}
@@ -173,14 +177,27 @@ func (ctlr *Controller) ProcessNodeUpdate(
 	}
 }
 
-// Return a copy of the node cache
-func (ctlr *Controller) getNodesFromCache() []Node {
-	nodes := make([]Node, len(ctlr.oldNodes))
-	copy(nodes, ctlr.oldNodes)
+// Return a copy of the node cache, filtered by filterFunc if provided
+func (ctlr *Controller) getNodesFromCache(filterFunc func(Node) bool) []Node {
+	nodes := make([]Node, 0, len(ctlr.oldNodes))
+	for _, node := range ctlr.oldNodes {
+		if filterFunc == nil || filterFunc(node) {
+			nodes = append(nodes, node)
+		}
+	}
 
 	return nodes
 }
 
+// Return the nodes from the node cache which do not carry any taints
+func (ctlr *Controller) getUntaintedNodes() []Node {
+	return ctlr.getNodesFromCache(isUntaintedNode)
+}
+
+func isUntaintedNode(node Node) bool {
+	return len(node.Taints) == 0
+}
+
 // Get a list of Node addresses
 func (ctlr *Controller) getNodes(
 	obj interface{},
@@ -214,6 +231,10 @@ func (ctlr *Controller) getNodes(
 				for k, v := range node.ObjectMeta.Labels {
 					n.Labels[k] = v
 				}
+				if len(node.Spec.Taints) > 0 {
+					n.Taints = make([]v1.Taint, len(node.Spec.Taints))
+					copy(n.Taints, node.Spec.Taints)
+				}
 				watchedNodes = append(watchedNodes, n)
 			}
 		}
@@ -222,23 +243,69 @@ func (ctlr *Controller) getNodes(
 	return watchedNodes, nil
 }
 
+// getNodesWithLabel returns the nodes selected by the label selector expression,
+// e.g. role=backend,zone=us-east-1
 func (ctlr *Controller) getNodesWithLabel(
 	nodeMemberLabel string,
 ) []Node {
-	allNodes := ctlr.getNodesFromCache()
-
-	label := strings.Split(nodeMemberLabel, "=")
-	if len(label) != 2 {
-		log.Warningf("Invalid NodeMemberLabel: %v", nodeMemberLabel)
+	selector, err := labels.Parse(nodeMemberLabel)
+	if err != nil {
+		log.Warningf("Invalid NodeMemberLabel %v: %v", nodeMemberLabel, err)
 		return nil
 	}
-	labelKey := label[0]
-	labelValue := label[1]
-	var nodes []Node
-	for _, node := range allNodes {
-		if node.Labels[labelKey] == labelValue {
-			nodes = append(nodes, node)
+	return ctlr.getNodesFromCache(func(node Node) bool {
+		if ctlr.excludeTaintedNodes && !isUntaintedNode(node) {
+			return false
 		}
+		return selector.Matches(labels.Set(node.Labels))
+	})
+}
+
+// getPoolNodeMemberLabel returns the label selector expression of the nodes of pool members,
+// NodeMemberSelector takes precedence over NodeMemberLabel
+func getPoolNodeMemberLabel(pool cisapiv1.Pool) (string, error) {
+	if pool.NodeMemberSelector == nil {
+		if _, err := labels.Parse(pool.NodeMemberLabel); err != nil {
+			return "", err
+		}
+		return pool.NodeMemberLabel, nil
 	}
-	return nodes
+	selector, err := metav1.LabelSelectorAsSelector(pool.NodeMemberSelector)
+	if err != nil {
+		return "", err
+	}
+	return selector.String(), nil
+}
+
+// getControllerZone returns the topology zone of the node running the controller pod
+func (ctlr *Controller) getControllerZone(podName, namespace string) string {
+	return ctlr.getControllerNodeLabels(podName, namespace)[v1.LabelTopologyZone]
+}
+
+// getControllerNodeLabels returns the labels of the node running controller pod
+func (ctlr *Controller) getControllerNodeLabels(podName, namespace string) map[string]string {
+	if podName == "" || namespace == "" {
+		log.Warningf("Unable to find the controller pod to fetch node labels")
+		return nil
+	}
+	pod, err := ctlr.kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+	if err != nil {
+		log.Warningf("Unable to fetch the controller pod %v/%v: %v", namespace, podName, err)
+		return nil
+	}
+	node, err := ctlr.kubeClient.CoreV1().Nodes().Get(context.TODO(), pod.Spec.NodeName, metav1.GetOptions{})
+	if err != nil {
+		log.Warningf("Unable to fetch the node %v of controller pod: %v", pod.Spec.NodeName, err)
+		return nil
+	}
+	return node.Labels
+}
+
+// getControllerNamespace returns the namespace of the controller pod from its service account
+func getControllerNamespace() string {
+	ns, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(ns))
 }
@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"context"
+
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
 	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
 	cisinfv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/informers/externalversions/cis/v1"
@@ -9,6 +11,7 @@ import (
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -69,16 +72,233 @@ var _ = Describe("Node Poller Handler", func() {
 		Expect(nodes).To(BeNil(), "Failed to Validate nodes")
 		Expect(err).ToNot(BeNil(), "Failed to Validate nodes")
 
-		nodes = mockCtlr.getNodesFromCache()
+		nodes = mockCtlr.getNodesFromCache(nil)
 		Expect(nodes).ToNot(BeNil(), "Failed to get nodes from Cache")
 
 		nodes = mockCtlr.getNodesWithLabel("app=test")
 		Expect(nodes).ToNot(BeNil(), "Failed to get Nodes with Label")
 
 		nodes = mockCtlr.getNodesWithLabel("app")
+		Expect(nodes).To(HaveLen(1), "Failed to get Nodes with Label key")
+
+		nodes = mockCtlr.getNodesWithLabel("app in (")
 		Expect(nodes).To(BeNil(), "Failed to Validate Nodes with Label")
 	})
 
+	It("Selects nodes of pool members with label selectors", func() {
+		nodeObjs := []v1.Node{
+			*test.NewNode("worker1", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.4"}}, nil),
+			*test.NewNode("worker2", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.5"}}, nil),
+			*test.NewNode("worker3", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.6"}}, nil),
+		}
+		nodeObjs[0].Labels = map[string]string{"role": "backend", "zone": "us-east-1"}
+		nodeObjs[1].Labels = map[string]string{"role": "backend", "zone": "us-west-1"}
+		nodeObjs[2].Labels = map[string]string{"role": "frontend", "zone": "us-east-1"}
+		mockCtlr.UseNodeInternal = true
+		var err error
+		mockCtlr.oldNodes, err = mockCtlr.getNodes(nodeObjs)
+		Expect(err).To(BeNil(), "Failed to get nodes")
+
+		Expect(mockCtlr.getEndpointsForNodePort(30000, "role=backend")).To(HaveLen(2))
+		Expect(mockCtlr.getEndpointsForNodePort(30000, "role=backend,zone=us-east-1")).To(Equal(
+			[]PoolMember{{Address: "1.2.3.4", Port: 30000, Session: "user-enabled"}}))
+		Expect(mockCtlr.getEndpointsForNodePort(30000, "zone in (us-east-1),role!=backend")).To(Equal(
+			[]PoolMember{{Address: "1.2.3.6", Port: 30000, Session: "user-enabled"}}))
+		Expect(mockCtlr.getEndpointsForNodePort(30000, "role=backend=api")).To(BeEmpty())
+
+		// NodeMemberSelector takes precedence over NodeMemberLabel
+		pool := cisapiv1.Pool{NodeMemberLabel: "role=frontend", NodeMemberSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"role": "backend"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key: "zone", Operator: metav1.LabelSelectorOpIn, Values: []string{"us-west-1"}}},
+		}}
+		nodeMemberLabel, err := getPoolNodeMemberLabel(pool)
+		Expect(err).To(BeNil())
+		Expect(mockCtlr.getEndpointsForNodePort(30000, nodeMemberLabel)).To(Equal(
+			[]PoolMember{{Address: "1.2.3.5", Port: 30000, Session: "user-enabled"}}))
+		Expect(formatPoolName("default", "svc", intstr.IntOrString{IntVal: 80}, nodeMemberLabel, "")).To(
+			Equal("svc_80_default_role_backend_zone_in_us_west_1"))
+
+		pool.NodeMemberSelector = nil
+		nodeMemberLabel, err = getPoolNodeMemberLabel(pool)
+		Expect(err).To(BeNil())
+		Expect(nodeMemberLabel).To(Equal("role=frontend"))
+		pool.NodeMemberLabel = "role in ("
+		_, err = getPoolNodeMemberLabel(pool)
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("Excludes tainted nodes", func() {
+		nodeAddr1 := v1.NodeAddress{
+			Type:    v1.NodeInternalIP,
+			Address: "1.2.3.4",
+		}
+		nodeAddr2 := v1.NodeAddress{
+			Type:    v1.NodeInternalIP,
+			Address: "1.2.3.5",
+		}
+		taint := v1.Taint{
+			Key:    "node-role.kubernetes.io/master",
+			Effect: v1.TaintEffectNoSchedule,
+		}
+		nodeObjs := []v1.Node{
+			*test.NewNode("worker1", "1", false,
+				[]v1.NodeAddress{nodeAddr1}, nil),
+			*test.NewNode("worker2", "1", false,
+				[]v1.NodeAddress{nodeAddr2}, []v1.Taint{taint}),
+		}
+		nodeObjs[0].Labels = map[string]string{"app": "test"}
+		nodeObjs[1].Labels = map[string]string{"app": "test"}
+		mockCtlr.UseNodeInternal = true
+		var err error
+		mockCtlr.oldNodes, err = mockCtlr.getNodes(nodeObjs)
+		Expect(err).To(BeNil(), "Failed to get nodes")
+
+		Expect(mockCtlr.getNodesFromCache(nil)).To(HaveLen(2), "Failed to get nodes from Cache")
+		nodes := mockCtlr.getUntaintedNodes()
+		Expect(nodes).To(HaveLen(1), "Failed to filter tainted nodes")
+		Expect(nodes[0].Name).To(Equal("worker1"), "Failed to filter tainted nodes")
+
+		Expect(mockCtlr.getEndpointsForNodePort(30000, "")).To(HaveLen(2))
+		Expect(mockCtlr.getEndpointsForNodePort(30000, "app=test")).To(HaveLen(2))
+
+		mockCtlr.excludeTaintedNodes = true
+		members := mockCtlr.getEndpointsForNodePort(30000, "")
+		Expect(members).To(Equal([]PoolMember{{Address: "1.2.3.4", Port: 30000, Session: "user-enabled"}}))
+		members = mockCtlr.getEndpointsForNodePort(30000, "app=test")
+		Expect(members).To(Equal([]PoolMember{{Address: "1.2.3.4", Port: 30000, Session: "user-enabled"}}))
+	})
+
+	It("Prefers pool members in the zone of controller", func() {
+		nodeObjs := []v1.Node{
+			*test.NewNode("worker1", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.4"}}, nil),
+			*test.NewNode("worker2", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.5"}}, nil),
+		}
+		nodeObjs[0].Labels = map[string]string{v1.LabelTopologyZone: "zone-a"}
+		nodeObjs[1].Labels = map[string]string{v1.LabelTopologyZone: "zone-b"}
+		for i := range nodeObjs {
+			_, _ = mockCtlr.kubeClient.CoreV1().Nodes().Create(context.TODO(), &nodeObjs[i], metav1.CreateOptions{})
+		}
+		pod := test.NewPod("k8s-bigip-ctlr-1", "kube-system", 8080, nil)
+		pod.Spec.NodeName = "worker2"
+		_, _ = mockCtlr.kubeClient.CoreV1().Pods("kube-system").Create(context.TODO(), pod, metav1.CreateOptions{})
+		Expect(mockCtlr.getControllerZone("k8s-bigip-ctlr-1", "kube-system")).To(Equal("zone-b"))
+		Expect(mockCtlr.getControllerZone("unknown", "kube-system")).To(BeEmpty())
+		Expect(mockCtlr.getControllerZone("", "")).To(BeEmpty())
+
+		mockCtlr.UseNodeInternal = true
+		mockCtlr.oldNodes, _ = mockCtlr.getNodes(nodeObjs)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.topologyAwareRouting = true
+		mockCtlr.controllerZone = "zone-b"
+		svc := test.NewService("svc1", "1", "default", v1.ServiceTypeClusterIP,
+			[]v1.ServicePort{{Name: "http", Port: 80}})
+		svc.Spec.TopologyKeys = []string{v1.LabelTopologyZone, "*"}
+		node1, node2 := "worker1", "worker2"
+		eps := &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "default"},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: []v1.EndpointAddress{
+					{IP: "10.244.1.1", NodeName: &node1},
+					{IP: "10.244.2.1", NodeName: &node2},
+				},
+				Ports: []v1.EndpointPort{{Name: "http", Port: 8080}},
+			}},
+		}
+		Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+		members := mockCtlr.resources.poolMemCache["default/svc1"].memberMap[portRef{name: "http", port: 8080}]
+		Expect(members).To(HaveLen(2))
+		Expect(members[0].Zone).To(Equal("zone-a"))
+		Expect(members[1].Zone).To(Equal("zone-b"))
+
+		rsCfg := &ResourceConfig{}
+		rsCfg.Pools = []Pool{{ServiceName: "svc1", ServiceNamespace: "default", ServicePort: intstr.FromInt(8080)}}
+		mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+		Expect(rsCfg.Pools[0].Members).To(HaveLen(1), "Members in zone of controller are not preferred")
+		Expect(rsCfg.Pools[0].Members[0].Address).To(Equal("10.244.2.1"))
+
+		// members from all zones are used when none is in zone of controller
+		mockCtlr.controllerZone = "zone-c"
+		mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+		Expect(rsCfg.Pools[0].Members).To(HaveLen(2))
+
+		// members are not grouped by zone for services without zone topology key
+		svc.Spec.TopologyKeys = nil
+		Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+		members = mockCtlr.resources.poolMemCache["default/svc1"].memberMap[portRef{name: "http", port: 8080}]
+		Expect(members[0].Zone).To(BeEmpty())
+		Expect(members[1].Zone).To(BeEmpty())
+	})
+
+	It("Sets priority group of pool members by node label", func() {
+		nodeObjs := []v1.Node{
+			*test.NewNode("worker1", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.4"}}, nil),
+			*test.NewNode("worker2", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.5"}}, nil),
+			*test.NewNode("worker3", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.6"}}, nil),
+			*test.NewNode("worker4", "1", false,
+				[]v1.NodeAddress{{Type: v1.NodeInternalIP, Address: "1.2.3.7"}}, nil),
+		}
+		nodeObjs[0].Labels = map[string]string{v1.LabelTopologyZone: "zone-a"}
+		nodeObjs[1].Labels = map[string]string{v1.LabelTopologyZone: "zone-b"}
+		nodeObjs[2].Labels = map[string]string{v1.LabelTopologyZone: "zone-c"}
+		mockCtlr.UseNodeInternal = true
+		mockCtlr.oldNodes, _ = mockCtlr.getNodes(nodeObjs)
+		mockCtlr.priorityGroupLabel = v1.LabelTopologyZone
+		mockCtlr.controllerPriorityValue = "zone-b"
+
+		// zone of controller gets the highest priority, members on nodes without label are not prioritized
+		Expect(mockCtlr.getPriorityGroups()).To(Equal(map[string]int32{"zone-b": 3, "zone-a": 2, "zone-c": 1}))
+		members := mockCtlr.getEndpointsForNodePort(30000, "")
+		Expect(members).To(HaveLen(4))
+		for _, member := range members {
+			switch member.Address {
+			case "1.2.3.4":
+				Expect(member.PriorityGroup).To(BeEquivalentTo(2))
+			case "1.2.3.5":
+				Expect(member.PriorityGroup).To(BeEquivalentTo(3))
+			case "1.2.3.6":
+				Expect(member.PriorityGroup).To(BeEquivalentTo(1))
+			default:
+				Expect(member.PriorityGroup).To(BeZero())
+			}
+		}
+
+		mockCtlr.resources = NewResourceStore()
+		svc := test.NewService("svc1", "1", "default", v1.ServiceTypeClusterIP,
+			[]v1.ServicePort{{Name: "http", Port: 80}})
+		node1, node2 := "worker1", "worker2"
+		eps := &v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "default"},
+			Subsets: []v1.EndpointSubset{{
+				Addresses: []v1.EndpointAddress{
+					{IP: "10.244.1.1", NodeName: &node1},
+					{IP: "10.244.2.1", NodeName: &node2},
+				},
+				Ports: []v1.EndpointPort{{Name: "http", Port: 8080}},
+			}},
+		}
+		Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+		rsCfg := &ResourceConfig{}
+		rsCfg.Pools = []Pool{{Name: "pool1", ServiceName: "svc1", ServiceNamespace: "default", ServicePort: intstr.FromInt(8080)}}
+		mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+		Expect(rsCfg.Pools[0].Members[0].PriorityGroup).To(BeEquivalentTo(2))
+		Expect(rsCfg.Pools[0].Members[1].PriorityGroup).To(BeEquivalentTo(3))
+		cachedMembers := mockCtlr.resources.poolMemCache["default/svc1"].memberMap[portRef{name: "http", port: 8080}]
+		Expect(cachedMembers[0].PriorityGroup).To(BeZero(), "Members of pool member cache should not be updated")
+
+		sharedApp := as3Application{}
+		createPoolDecl(rsCfg, sharedApp, false, "test")
+		Expect(sharedApp["pool1"].(*as3Pool).Members[1].PriorityGroup).To(BeEquivalentTo(3))
+	})
+
 	Describe("Processes CIS monitored resources on node update", func() {
 		BeforeEach(func() {
 			namespace := ""
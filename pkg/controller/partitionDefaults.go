@@ -0,0 +1,74 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PartitionDefaultsKey is the key of ConfigMap data holding the JSON array of partition defaults
+const PartitionDefaultsKey = "partitions"
+
+// getPartitionDefaults reads the partition defaults from the ConfigMap namespace/name
+func (ctlr *Controller) getPartitionDefaults(cmKey string) ([]PartitionDefault, error) {
+	splits := strings.Split(cmKey, "/")
+	if len(splits) != 2 {
+		return nil, fmt.Errorf("invalid partition defaults ConfigMap %v", cmKey)
+	}
+	cm, err := ctlr.kubeClient.CoreV1().ConfigMaps(splits[0]).Get(context.TODO(), splits[1], metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var partitions []PartitionDefault
+	if err = json.Unmarshal([]byte(cm.Data[PartitionDefaultsKey]), &partitions); err != nil {
+		return nil, fmt.Errorf("invalid %v in ConfigMap %v: %v", PartitionDefaultsKey, cmKey, err)
+	}
+	for _, partition := range partitions {
+		if partition.Name == "" || partition.Name == "Common" {
+			return nil, fmt.Errorf("invalid partition name %q in ConfigMap %v", partition.Name, cmKey)
+		}
+	}
+	return partitions, nil
+}
+
+// createPartitionsWithDefaults creates the partitions of the ConfigMap on BIG-IP,
+// so that the partitions have the right route domains before any virtual is posted.
+// AS3 creates the missing partitions, but can not set their defaults.
+func (ctlr *Controller) createPartitionsWithDefaults(cmKey string) {
+	partitions, err := ctlr.getPartitionDefaults(cmKey)
+	if err != nil {
+		log.Errorf("Unable to get partition defaults: %v", err)
+		return
+	}
+	for _, partition := range partitions {
+		created, err := ctlr.Agent.createPartition(partition)
+		if err != nil {
+			log.Errorf("Unable to create partition %v: %v", partition.Name, err)
+			continue
+		}
+		if created {
+			log.Infof("Created partition %v with default route domain %v",
+				partition.Name, partition.DefaultRouteDomain)
+		}
+	}
+}
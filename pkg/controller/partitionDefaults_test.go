@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Partition Defaults Tests", func() {
+	var mockCtlr *mockController
+	var createdPartitions []PartitionDefault
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		createdPartitions = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/mgmt/tm/auth/partition/existing":
+				fmt.Fprint(w, `{"name":"existing","defaultRouteDomain":0}`)
+			case r.Method == http.MethodGet:
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"code":404,"message":"partition not found"}`)
+			case r.Method == http.MethodPost && r.URL.Path == "/mgmt/tm/auth/partition":
+				var partition PartitionDefault
+				_ = json.NewDecoder(r.Body).Decode(&partition)
+				createdPartitions = append(createdPartitions, partition)
+				fmt.Fprint(w, `{}`)
+			}
+		}))
+
+		mockCtlr = newMockController()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.Agent = newMockAgent(nil)
+		mockCtlr.Agent.PostManager = &PostManager{PostParams: PostParams{BIGIPURL: server.URL}}
+		mockCtlr.Agent.setupBIGIPRESTClient()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Creates missing partitions with defaults", func() {
+		cm := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "partition-defaults", Namespace: "kube-system"},
+			Data: map[string]string{PartitionDefaultsKey: `[
+				{"name": "existing", "defaultRouteDomain": 10},
+				{"name": "tenant1", "defaultRouteDomain": 20, "description": "tenant1 partition"}
+			]`},
+		}
+		_, _ = mockCtlr.kubeClient.CoreV1().ConfigMaps("kube-system").Create(context.TODO(), cm, metav1.CreateOptions{})
+
+		mockCtlr.createPartitionsWithDefaults("kube-system/partition-defaults")
+		Expect(createdPartitions).To(Equal([]PartitionDefault{
+			{Name: "tenant1", DefaultRouteDomain: 20, Description: "tenant1 partition"},
+		}), "Only missing partition should be created")
+	})
+
+	It("Validates partition defaults", func() {
+		_, err := mockCtlr.getPartitionDefaults("kube-system/missing")
+		Expect(err).To(HaveOccurred(), "Missing ConfigMap should fail")
+
+		cm := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "partition-defaults", Namespace: "kube-system"},
+			Data:       map[string]string{PartitionDefaultsKey: `[{"name": "Common"}]`},
+		}
+		_, _ = mockCtlr.kubeClient.CoreV1().ConfigMaps("kube-system").Create(context.TODO(), cm, metav1.CreateOptions{})
+		_, err = mockCtlr.getPartitionDefaults("kube-system/partition-defaults")
+		Expect(err).To(HaveOccurred(), "Common partition should not be allowed")
+
+		cm.Data[PartitionDefaultsKey] = `{"name": "tenant1"}`
+		_, _ = mockCtlr.kubeClient.CoreV1().ConfigMaps("kube-system").Update(context.TODO(), cm, metav1.UpdateOptions{})
+		_, err = mockCtlr.getPartitionDefaults("kube-system/partition-defaults")
+		Expect(err).To(HaveOccurred(), "Partition defaults must be a JSON array")
+
+		mockCtlr.createPartitionsWithDefaults("kube-system/partition-defaults")
+		Expect(createdPartitions).To(BeEmpty())
+	})
+})
@@ -0,0 +1,69 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// PartitionProtectedReason is the reason of events on resources whose virtuals are not deleted
+// as they belong to a protected partition
+const PartitionProtectedReason = "PartitionProtected"
+
+// recordPartitionProtectedEvent records a warning event on the resources of the virtual
+// which is not deleted as it belongs to a protected partition
+func (ctlr *Controller) recordPartitionProtectedEvent(partition string, rsCfg *ResourceConfig) {
+	message := fmt.Sprintf("Skipping deletion of virtual %v in protected partition %v",
+		rsCfg.Virtual.Name, partition)
+	log.Warning(message)
+	if ctlr.eventNotifier == nil || ctlr.kubeClient == nil {
+		return
+	}
+	for rsc, kind := range rsCfg.MetaData.baseResources {
+		splits := strings.Split(rsc, "/")
+		if len(splits) != 2 {
+			continue
+		}
+		ref := &v1.ObjectReference{Kind: kind, Namespace: splits[0], Name: splits[1]}
+		evNotifier := ctlr.eventNotifier.CreateNotifierForNamespace(splits[0], ctlr.kubeClient.CoreV1())
+		evNotifier.RecordEvent(ref, v1.EventTypeWarning, PartitionProtectedReason, message)
+	}
+}
+
+// hasMinPartitionsToPost checks whether the config has the minimum number of partitions
+// with content to be posted, which prevents wiping the BIG-IP config on misconfiguration
+func (ctlr *Controller) hasMinPartitionsToPost() bool {
+	if ctlr.minPartitionsPerPost <= 0 {
+		return true
+	}
+	var count int
+	for _, partitionConfig := range ctlr.resources.ltmConfig {
+		if len(partitionConfig.ResourceMap) > 0 {
+			count++
+		}
+	}
+	if count < ctlr.minPartitionsPerPost {
+		log.Errorf("Skipping the post to BIG-IP as only %v partitions have content, minimum required is %v",
+			count, ctlr.minPartitionsPerPost)
+		return false
+	}
+	return true
+}
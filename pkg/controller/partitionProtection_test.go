@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Partition Protection Tests", func() {
+	var mockCtlr *mockController
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		mockCtlr.protectedPartitions = map[string]bool{"prod": true}
+	})
+
+	newResourceConfig := func(name string) *ResourceConfig {
+		rsCfg := &ResourceConfig{}
+		rsCfg.Virtual.Name = name
+		rsCfg.MetaData.baseResources = map[string]string{"default/vs1": VirtualServer}
+		return rsCfg
+	}
+
+	It("Skips deletion of virtuals in protected partitions", func() {
+		mockCtlr.resources.getPartitionResourceMap("prod")["crd_vs_10.1.1.1_80"] = newResourceConfig("crd_vs_10.1.1.1_80")
+		mockCtlr.resources.getPartitionResourceMap("test")["crd_vs_10.1.1.2_80"] = newResourceConfig("crd_vs_10.1.1.2_80")
+
+		mockCtlr.Controller.deleteVirtualServer("prod", "crd_vs_10.1.1.1_80")
+		mockCtlr.Controller.deleteVirtualServer("test", "crd_vs_10.1.1.2_80")
+		Expect(mockCtlr.resources.getPartitionResourceMap("prod")).To(HaveKey("crd_vs_10.1.1.1_80"),
+			"Virtual in protected partition should not be deleted")
+		Expect(mockCtlr.resources.getPartitionResourceMap("test")).To(BeEmpty())
+
+		Eventually(func() []string {
+			events, _ := mockCtlr.kubeClient.CoreV1().Events("default").List(context.TODO(), metav1.ListOptions{})
+			var objects []string
+			for _, event := range events.Items {
+				if event.Reason == PartitionProtectedReason {
+					objects = append(objects, event.InvolvedObject.Kind+"/"+event.InvolvedObject.Name)
+				}
+			}
+			return objects
+		}).Should(Equal([]string{"VirtualServer/vs1"}))
+	})
+
+	It("Posts only with minimum partitions having content", func() {
+		Expect(mockCtlr.hasMinPartitionsToPost()).To(BeTrue(), "Check should be disabled by default")
+
+		mockCtlr.minPartitionsPerPost = 2
+		mockCtlr.resources.getPartitionResourceMap("prod")["crd_vs_10.1.1.1_80"] = newResourceConfig("crd_vs_10.1.1.1_80")
+		mockCtlr.resources.getPartitionResourceMap("test")
+		Expect(mockCtlr.hasMinPartitionsToPost()).To(BeFalse(), "Empty partition should not be counted")
+
+		mockCtlr.resources.getPartitionResourceMap("test")["crd_vs_10.1.1.2_80"] = newResourceConfig("crd_vs_10.1.1.2_80")
+		Expect(mockCtlr.hasMinPartitionsToPost()).To(BeTrue())
+	})
+})
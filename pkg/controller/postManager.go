@@ -18,11 +18,16 @@ package controller
 
 import (
 	"bytes"
+	"compress/gzip"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -34,19 +39,37 @@ const (
 	timeoutSmall  = 3 * time.Second
 	timeoutMedium = 30 * time.Second
 	timeoutLarge  = 60 * time.Second
+
+	// DefaultAS3PostTimeout is the timeout of AS3 requests to BIG-IP
+	DefaultAS3PostTimeout = timeoutLarge
+	// DefaultAS3ConnectTimeout is the timeout to establish connection with BIG-IP
+	DefaultAS3ConnectTimeout = 10 * time.Second
+	// DefaultAS3PostRetries is the number of retries of AS3 posts failed with network errors
+	DefaultAS3PostRetries = 3
+	// DefaultAS3MaxBackoff is the maximum delay between retries of AS3 posts
+	DefaultAS3MaxBackoff = 30 * time.Second
+	// DefaultCompressAS3Threshold is the size of AS3 declarations in bytes from which they are compressed
+	DefaultCompressAS3Threshold = 102400
+	// DefaultAS3DeclarationSizeWarnBytes is the size of AS3 declarations in bytes from which a warning is raised
+	DefaultAS3DeclarationSizeWarnBytes = 1048576
 )
 
+// as3RetryBackoff is the base delay between retries of AS3 posts, doubled on every retry
+var as3RetryBackoff = time.Second
+
 func NewPostManager(params PostParams) *PostManager {
 	pm := &PostManager{
 		PostParams: params,
 		firstPost:  true,
 	}
-	pm.setupBIGIPRESTClient()
+	if err := pm.setupBIGIPRESTClient(); err != nil {
+		log.Fatalf("[AS3] Failed to setup BIG-IP REST client: %v", err)
+	}
 
 	return pm
 }
 
-func (postMgr *PostManager) setupBIGIPRESTClient() {
+func (postMgr *PostManager) setupBIGIPRESTClient() error {
 	// Get the SystemCertPool, continue with an empty pool on error
 	rootCAs, _ := x509.SystemCertPool()
 	if rootCAs == nil {
@@ -59,18 +82,58 @@ func (postMgr *PostManager) setupBIGIPRESTClient() {
 	if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
 		log.Debug("[AS3] No certs appended, using only system certs")
 	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: postMgr.SSLInsecure,
+		RootCAs:            rootCAs,
+	}
+	if err := postMgr.setupMutualTLS(tlsConfig); err != nil {
+		return err
+	}
+
+	connectTimeout := postMgr.AS3ConnectTimeout
+	if connectTimeout <= 0 {
+		connectTimeout = DefaultAS3ConnectTimeout
+	}
+	postTimeout := postMgr.AS3PostTimeout
+	if postTimeout <= 0 {
+		postTimeout = DefaultAS3PostTimeout
+	}
 
 	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: postMgr.SSLInsecure,
-			RootCAs:            rootCAs,
-		},
+		DialContext:           (&net.Dialer{Timeout: connectTimeout}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		ResponseHeaderTimeout: postMgr.AS3ReadTimeout,
 	}
 
 	postMgr.httpClient = &http.Client{
 		Transport: tr,
-		Timeout:   timeoutLarge,
+		Timeout:   postTimeout,
+	}
+	return nil
+}
+
+// setupMutualTLS adds the client certificate to authenticate CIS with BIG-IP along with
+// basic auth, and the CA certificate to verify the server certificate of BIG-IP
+func (postMgr *PostManager) setupMutualTLS(tlsConfig *tls.Config) error {
+	if postMgr.BIGIPClientCert != "" && postMgr.BIGIPClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(postMgr.BIGIPClientCert, postMgr.BIGIPClientKey)
+		if err != nil {
+			return fmt.Errorf("unable to load BIG-IP client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if postMgr.BIGIPCACert != "" {
+		caCert, err := ioutil.ReadFile(postMgr.BIGIPCACert)
+		if err != nil {
+			return fmt.Errorf("unable to read BIG-IP CA certificate: %v", err)
+		}
+		if ok := tlsConfig.RootCAs.AppendCertsFromPEM(caCert); !ok {
+			return fmt.Errorf("no certificates found in BIG-IP CA certificate %v", postMgr.BIGIPCACert)
+		}
+		// server certificate of BIG-IP is verified with the CA certificate
+		tlsConfig.InsecureSkipVerify = false
 	}
+	return nil
 }
 
 func (postMgr *PostManager) getAS3APIURL(tenants []string) string {
@@ -99,14 +162,32 @@ func (postMgr *PostManager) publishConfig(cfg agentConfig) {
 }
 
 func (postMgr *PostManager) postConfig(cfg *agentConfig) {
-	httpReqBody := bytes.NewBuffer([]byte(cfg.data))
+	data := []byte(cfg.data)
+	compressed := false
+	if postMgr.CompressAS3 && len(data) >= postMgr.CompressThreshold {
+		if gzData, err := gzipCompress(data); err != nil {
+			log.Warningf("[AS3] Posting declaration uncompressed, compression failed: %v", err)
+		} else {
+			log.Debugf("[AS3] Compressed declaration from %v to %v bytes", len(data), len(gzData))
+			data = gzData
+			compressed = true
+		}
+	}
+	httpReqBody := bytes.NewBuffer(data)
 	req, err := http.NewRequest("POST", cfg.as3APIURL, httpReqBody)
 	if err != nil {
 		log.Errorf("[AS3] Creating new HTTP request error: %v ", err)
 		return
 	}
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 	log.Debugf("[AS3] posting request to %v", cfg.as3APIURL)
-	req.SetBasicAuth(postMgr.BIGIPUsername, postMgr.BIGIPPassword)
+	if cfg.credentials != nil {
+		req.SetBasicAuth(cfg.credentials.Username, cfg.credentials.Password)
+	} else {
+		req.SetBasicAuth(postMgr.BIGIPUsername, postMgr.BIGIPPassword)
+	}
 
 	httpResp, responseMap := postMgr.httpPOST(req)
 	if httpResp == nil || responseMap == nil {
@@ -128,14 +209,94 @@ func (postMgr *PostManager) postConfig(cfg *agentConfig) {
 		postMgr.handleResponseStatusServiceUnavailable(responseMap)
 	case http.StatusNotFound:
 		postMgr.handleResponseStatusNotFound(responseMap)
+	case http.StatusUnprocessableEntity:
+		postMgr.handleResponseUnprocessableEntity(responseMap, cfg)
 	default:
 		postMgr.handleResponseOthers(responseMap, cfg)
 	}
 
 }
 
+// gzipCompress compresses the data with gzip
+func gzipCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	if _, err := gzWriter.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gzWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// doWithRetry sends the request to BIG-IP, requests failed with timeout or temporary
+// network errors or with 5xx responses of BIG-IP unavailable are retried with a fresh
+// request upto AS3PostRetries times
+func (postMgr *PostManager) doWithRetry(request *http.Request) (*http.Response, error) {
+	req := request
+	for attempt := 0; ; attempt++ {
+		httpResp, err := postMgr.httpClient.Do(req)
+		retriable := isRetriableNetError(err) || (err == nil && isRetriableStatus(httpResp.StatusCode))
+		if !retriable || attempt >= postMgr.AS3PostRetries || request.GetBody == nil {
+			return httpResp, err
+		}
+		delay := postMgr.retryBackoff(attempt)
+		if err == nil {
+			// response of the failed attempt is discarded
+			_, _ = io.Copy(ioutil.Discard, httpResp.Body)
+			httpResp.Body.Close()
+			log.Warningf("[AS3] REST call response %v, retrying in %v (%v/%v)", httpResp.Status, delay,
+				attempt+1, postMgr.AS3PostRetries)
+		} else {
+			log.Warningf("[AS3] REST call error: %v, retrying in %v (%v/%v)", err, delay, attempt+1,
+				postMgr.AS3PostRetries)
+		}
+		time.Sleep(delay)
+		// body of the request is consumed, retry with the full declaration
+		req = request.Clone(request.Context())
+		if req.Body, err = request.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryBackoff returns the delay before the retry of attempt, the delay is doubled on every retry
+// upto AS3MaxBackoff with jitter to avoid retrying in lockstep with BIG-IP restarts
+func (postMgr *PostManager) retryBackoff(attempt int) time.Duration {
+	maxBackoff := postMgr.AS3MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultAS3MaxBackoff
+	}
+	delay := maxBackoff
+	// shift is bounded to avoid the overflow of delay
+	if attempt < 32 && as3RetryBackoff<<uint(attempt) < maxBackoff {
+		delay = as3RetryBackoff << uint(attempt)
+	}
+	delay += time.Duration(rand.Int63n(int64(as3RetryBackoff)))
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// isRetriableStatus checks whether the status code is a 5xx response of BIG-IP being unavailable
+func isRetriableStatus(code int) bool {
+	switch code {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetriableNetError checks whether the error is a timeout or temporary network error
+func isRetriableNetError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && (netErr.Timeout() || netErr.Temporary())
+}
+
 func (postMgr *PostManager) httpPOST(request *http.Request) (*http.Response, map[string]interface{}) {
-	httpResp, err := postMgr.httpClient.Do(request)
+	httpResp, err := postMgr.doWithRetry(request)
 	if err != nil {
 		log.Errorf("[AS3] REST call error: %v ", err)
 		return nil, nil
@@ -348,6 +509,147 @@ func (postMgr *PostManager) GetBigipRegKey() (string, error) {
 	return "", fmt.Errorf("Error response from BIGIP with status code %v", httpResp.StatusCode)
 }
 
+// createPartition creates the partition with the defaults if it does not exist on BIG-IP
+func (postMgr *PostManager) createPartition(partition PartitionDefault) (bool, error) {
+	url := postMgr.getPartitionURL() + "/" + partition.Name
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.SetBasicAuth(postMgr.BIGIPUsername, postMgr.BIGIPPassword)
+	httpResp, _ := postMgr.httpReq(req)
+	if httpResp == nil {
+		return false, fmt.Errorf("Internal Error")
+	}
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		return false, nil
+	case http.StatusNotFound:
+	default:
+		return false, fmt.Errorf("Error response from BIGIP with status code %v", httpResp.StatusCode)
+	}
+
+	data, err := json.Marshal(partition)
+	if err != nil {
+		return false, err
+	}
+	req, err = http.NewRequest("POST", postMgr.getPartitionURL(), bytes.NewBuffer(data))
+	if err != nil {
+		return false, err
+	}
+	log.Debugf("Posting partition %v to %v", partition.Name, postMgr.getPartitionURL())
+	req.SetBasicAuth(postMgr.BIGIPUsername, postMgr.BIGIPPassword)
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, responseMap := postMgr.httpReq(req)
+	if httpResp == nil {
+		return false, fmt.Errorf("Internal Error")
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("Error response from BIGIP with status code %v: %v",
+			httpResp.StatusCode, responseMap["message"])
+	}
+	return true, nil
+}
+
+// getClientSSLCiphers returns the ciphers and cipher groups of the client SSL profiles on BIG-IP
+func (postMgr *PostManager) getClientSSLCiphers() (map[string]bool, map[string]bool, error) {
+	req, err := http.NewRequest("GET", postMgr.getClientSSLProfileURL(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.SetBasicAuth(postMgr.BIGIPUsername, postMgr.BIGIPPassword)
+	httpResp, responseMap := postMgr.httpReq(req)
+	if httpResp == nil || responseMap == nil {
+		return nil, nil, fmt.Errorf("Internal Error")
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("Error response from BIGIP with status code %v", httpResp.StatusCode)
+	}
+	ciphers := make(map[string]bool)
+	cipherGroups := make(map[string]bool)
+	items, _ := responseMap["items"].([]interface{})
+	for _, item := range items {
+		profile, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if cipher, ok := profile["ciphers"].(string); ok && cipher != "" && cipher != "none" {
+			ciphers[cipher] = true
+		}
+		if group, ok := profile["cipherGroup"].(string); ok && group != "" && group != "none" {
+			cipherGroups[group] = true
+		}
+	}
+	return ciphers, cipherGroups, nil
+}
+
+// getGTMDatacenters returns the GTM datacenters of BIG-IP along with their GTM servers
+func (postMgr *PostManager) getGTMDatacenters() (GTMDatacenters, error) {
+	datacenters := make(GTMDatacenters)
+	dcItems, err := postMgr.getItems(postMgr.getGTMDatacenterURL())
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range dcItems {
+		fullPath, _ := item["fullPath"].(string)
+		if fullPath == "" {
+			continue
+		}
+		_, disabled := item["disabled"]
+		datacenters[fullPath] = GTMDatacenter{
+			Enabled: !disabled,
+			Servers: make(map[string][]string),
+		}
+	}
+	serverItems, err := postMgr.getItems(postMgr.getGTMServerURL())
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range serverItems {
+		fullPath, _ := item["fullPath"].(string)
+		dcPath, _ := item["datacenter"].(string)
+		dc, ok := datacenters[dcPath]
+		if fullPath == "" || !ok {
+			continue
+		}
+		addresses := []string{}
+		addrItems, _ := item["addresses"].([]interface{})
+		for _, addrItem := range addrItems {
+			if addr, ok := addrItem.(map[string]interface{}); ok {
+				if name, ok := addr["name"].(string); ok {
+					addresses = append(addresses, name)
+				}
+			}
+		}
+		dc.Servers[fullPath] = addresses
+	}
+	return datacenters, nil
+}
+
+// getItems returns the items of the BIG-IP REST collection
+func (postMgr *PostManager) getItems(url string) ([]map[string]interface{}, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(postMgr.BIGIPUsername, postMgr.BIGIPPassword)
+	httpResp, responseMap := postMgr.httpReq(req)
+	if httpResp == nil || responseMap == nil {
+		return nil, fmt.Errorf("Internal Error")
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error response from BIGIP with status code %v", httpResp.StatusCode)
+	}
+	var items []map[string]interface{}
+	rawItems, _ := responseMap["items"].([]interface{})
+	for _, rawItem := range rawItems {
+		if item, ok := rawItem.(map[string]interface{}); ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
 func (postMgr *PostManager) httpReq(request *http.Request) (*http.Response, map[string]interface{}) {
 	httpResp, err := postMgr.httpClient.Do(request)
 	if err != nil {
@@ -379,6 +681,26 @@ func (postMgr *PostManager) getAS3VersionURL() string {
 
 }
 
+func (postMgr *PostManager) getPartitionURL() string {
+	return postMgr.BIGIPURL + "/mgmt/tm/auth/partition"
+}
+
+func (postMgr *PostManager) getPoolURL() string {
+	return postMgr.BIGIPURL + "/mgmt/tm/ltm/pool"
+}
+
+func (postMgr *PostManager) getClientSSLProfileURL() string {
+	return postMgr.BIGIPURL + "/mgmt/tm/ltm/profile/client-ssl"
+}
+
+func (postMgr *PostManager) getGTMDatacenterURL() string {
+	return postMgr.BIGIPURL + "/mgmt/tm/gtm/datacenter"
+}
+
+func (postMgr *PostManager) getGTMServerURL() string {
+	return postMgr.BIGIPURL + "/mgmt/tm/gtm/server"
+}
+
 func (postMgr *PostManager) getBigipRegKeyURL() string {
 	apiURL := postMgr.BIGIPURL + "/mgmt/tm/shared/licensing/registration"
 	return apiURL
@@ -1,10 +1,28 @@
 package controller
 
 import (
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"net/http"
 )
 
 var _ = Describe("PostManager Tests", func() {
@@ -139,6 +157,234 @@ var _ = Describe("PostManager Tests", func() {
 			mockPM.publishConfig(agentCfg)
 			Expect(len(mockPM.tenantResponseMap)).To(Equal(1), "Posting Failed")
 		})
+
+		It("Retry Post on Timeout", func() {
+			var attempts, fullPosts int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if body, _ := ioutil.ReadAll(r.Body); string(body) == `{"class":"AS3"}` {
+					atomic.AddInt32(&fullPosts, 1)
+				}
+				if atomic.AddInt32(&attempts, 1) < 3 {
+					time.Sleep(200 * time.Millisecond)
+				}
+				fmt.Fprint(w, `{"results":[{"code":200,"message":"none","tenant":"test"}]}`)
+			}))
+			defer server.Close()
+			defer func(backoff time.Duration) { as3RetryBackoff = backoff }(as3RetryBackoff)
+			as3RetryBackoff = time.Millisecond
+
+			mockPM.BIGIPURL = server.URL
+			mockPM.AS3PostTimeout = 100 * time.Millisecond
+			mockPM.AS3PostRetries = 2
+			mockPM.setupBIGIPRESTClient()
+			agentCfg.as3APIURL = mockPM.getAS3APIURL([]string{"test"})
+			agentCfg.data = `{"class":"AS3"}`
+			mockPM.postConfig(&agentCfg)
+			Expect(atomic.LoadInt32(&attempts)).To(BeEquivalentTo(3))
+			Expect(atomic.LoadInt32(&fullPosts)).To(BeEquivalentTo(3), "Declaration not posted on retry")
+			Expect(mockPM.tenantResponseMap["test"].agentResponseCode).To(BeEquivalentTo(http.StatusOK), "Posting Failed")
+
+			// post fails when the retries are exhausted
+			atomic.StoreInt32(&attempts, 0)
+			mockPM.tenantResponseMap = make(map[string]tenantResponse)
+			mockPM.AS3PostRetries = 1
+			mockPM.postConfig(&agentCfg)
+			Expect(atomic.LoadInt32(&attempts)).To(BeEquivalentTo(2))
+			Expect(mockPM.tenantResponseMap).To(BeEmpty())
+		})
+
+		It("Retry Post on 5xx responses with backoff", func() {
+			var attempts []time.Time
+			var mutex sync.Mutex
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				attempts = append(attempts, time.Now())
+				attempt := len(attempts)
+				mutex.Unlock()
+				if attempt <= 4 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					fmt.Fprint(w, `{"code":503,"message":"BIG-IP is under maintenance"}`)
+					return
+				}
+				fmt.Fprint(w, `{"results":[{"code":200,"message":"none","tenant":"test"}]}`)
+			}))
+			defer server.Close()
+			defer func(backoff time.Duration) { as3RetryBackoff = backoff }(as3RetryBackoff)
+			as3RetryBackoff = 20 * time.Millisecond
+
+			mockPM.BIGIPURL = server.URL
+			mockPM.AS3PostRetries = 4
+			mockPM.AS3MaxBackoff = 50 * time.Millisecond
+			mockPM.setupBIGIPRESTClient()
+			agentCfg.as3APIURL = mockPM.getAS3APIURL([]string{"test"})
+			agentCfg.data = `{"class":"AS3"}`
+			mockPM.postConfig(&agentCfg)
+			Expect(attempts).To(HaveLen(5))
+			Expect(mockPM.tenantResponseMap["test"].agentResponseCode).To(BeEquivalentTo(http.StatusOK), "Posting Failed")
+			// delays of 20ms and 40ms with jitter, capped at 50ms
+			for i, minDelay := range []time.Duration{20, 40, 50, 50} {
+				Expect(attempts[i+1].Sub(attempts[i])).To(BeNumerically(">=", minDelay*time.Millisecond))
+			}
+
+			for attempt := 0; attempt < 40; attempt++ {
+				delay := mockPM.retryBackoff(attempt)
+				Expect(delay).To(BeNumerically("<=", mockPM.AS3MaxBackoff))
+				if attempt == 0 {
+					Expect(delay).To(BeNumerically(">=", as3RetryBackoff))
+					Expect(delay).To(BeNumerically("<", 2*as3RetryBackoff))
+				}
+			}
+
+			// 503 response is handled once the retries are exhausted
+			mutex.Lock()
+			attempts = nil
+			mutex.Unlock()
+			mockPM.AS3PostRetries = 1
+			mockPM.postConfig(&agentCfg)
+			Expect(attempts).To(HaveLen(2))
+			Expect(mockPM.tenantResponseMap["test"].agentResponseCode).To(BeEquivalentTo(http.StatusServiceUnavailable))
+		})
+
+		It("Post compressed declaration", func() {
+			var encodings []string
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				encodings = append(encodings, r.Header.Get("Content-Encoding"))
+				var reader io.Reader = r.Body
+				if r.Header.Get("Content-Encoding") == "gzip" {
+					reader, _ = gzip.NewReader(r.Body)
+				}
+				body, _ := ioutil.ReadAll(reader)
+				bodies = append(bodies, string(body))
+				fmt.Fprint(w, `{"results":[{"code":200,"message":"none","tenant":"test"}]}`)
+			}))
+			defer server.Close()
+
+			decl := `{"class":"AS3","declaration":{"class":"ADC","test":{"class":"Tenant"}}}`
+			mockPM.BIGIPURL = server.URL
+			mockPM.CompressAS3 = true
+			mockPM.CompressThreshold = len(decl)
+			mockPM.setupBIGIPRESTClient()
+			agentCfg.as3APIURL = mockPM.getAS3APIURL([]string{"test"})
+			agentCfg.data = decl
+			mockPM.postConfig(&agentCfg)
+
+			// declarations smaller than threshold are not compressed
+			mockPM.CompressThreshold = len(decl) + 1
+			mockPM.postConfig(&agentCfg)
+
+			Expect(encodings).To(Equal([]string{"gzip", ""}))
+			Expect(bodies).To(Equal([]string{decl, decl}), "Compressed body should decompress to the declaration")
+			Expect(mockPM.tenantResponseMap["test"].agentResponseCode).To(BeEquivalentTo(http.StatusOK), "Posting Failed")
+		})
+	})
+
+	Describe("Mutual TLS with BIG-IP", func() {
+		var server *httptest.Server
+		var certDir string
+		var clientCNs []string
+
+		// newCertificate creates a certificate signed by parent, self-signed when parent is nil
+		newCertificate := func(cn string, isCA bool, parent *x509.Certificate, parentKey *rsa.PrivateKey) (
+			*x509.Certificate, *rsa.PrivateKey, []byte, []byte) {
+			key, _ := rsa.GenerateKey(rand.Reader, 2048)
+			template := &x509.Certificate{
+				SerialNumber:          big.NewInt(time.Now().UnixNano()),
+				Subject:               pkix.Name{CommonName: cn},
+				NotBefore:             time.Now().Add(-time.Hour),
+				NotAfter:              time.Now().Add(time.Hour),
+				IsCA:                  isCA,
+				BasicConstraintsValid: true,
+				IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+				ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			}
+			if isCA {
+				template.KeyUsage = x509.KeyUsageCertSign
+			}
+			if parent == nil {
+				parent, parentKey = template, key
+			}
+			der, _ := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+			cert, _ := x509.ParseCertificate(der)
+			certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+			keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+			return cert, key, certPEM, keyPEM
+		}
+
+		BeforeEach(func() {
+			clientCNs = nil
+			certDir, _ = ioutil.TempDir("", "bigip-certs")
+			ca, caKey, caPEM, _ := newCertificate("bigip-ca", true, nil, nil)
+			_, _, serverPEM, serverKeyPEM := newCertificate("bigip", false, ca, caKey)
+			_, _, clientPEM, clientKeyPEM := newCertificate("cis", false, ca, caKey)
+			_ = ioutil.WriteFile(filepath.Join(certDir, "ca.crt"), caPEM, 0600)
+			_ = ioutil.WriteFile(filepath.Join(certDir, "client.crt"), clientPEM, 0600)
+			_ = ioutil.WriteFile(filepath.Join(certDir, "client.key"), clientKeyPEM, 0600)
+
+			serverCert, _ := tls.X509KeyPair(serverPEM, serverKeyPEM)
+			clientCAs := x509.NewCertPool()
+			clientCAs.AddCert(ca)
+			server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, cert := range r.TLS.PeerCertificates {
+					clientCNs = append(clientCNs, cert.Subject.CommonName)
+				}
+				user, _, _ := r.BasicAuth()
+				fmt.Fprintf(w, `{"registrationKey": "%v"}`, user)
+			}))
+			server.TLS = &tls.Config{
+				Certificates: []tls.Certificate{serverCert},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    clientCAs,
+			}
+			server.StartTLS()
+			mockPM.BIGIPURL = server.URL
+			mockPM.BIGIPUsername = "admin"
+		})
+
+		AfterEach(func() {
+			server.Close()
+			_ = os.RemoveAll(certDir)
+		})
+
+		It("Authenticates with client certificate along with basic auth", func() {
+			mockPM.BIGIPClientCert = filepath.Join(certDir, "client.crt")
+			mockPM.BIGIPClientKey = filepath.Join(certDir, "client.key")
+			mockPM.BIGIPCACert = filepath.Join(certDir, "ca.crt")
+			Expect(mockPM.setupBIGIPRESTClient()).To(Succeed())
+			key, err := mockPM.GetBigipRegKey()
+			Expect(err).To(BeNil(), "TLS handshake with client certificate should succeed")
+			Expect(key).To(Equal("admin"), "Basic auth should be sent along with client certificate")
+			Expect(clientCNs).To(Equal([]string{"cis"}))
+		})
+
+		It("Fails TLS handshake without client certificate", func() {
+			mockPM.BIGIPCACert = filepath.Join(certDir, "ca.crt")
+			mockPM.setupBIGIPRESTClient()
+			_, err := mockPM.GetBigipRegKey()
+			Expect(err).NotTo(BeNil())
+			Expect(clientCNs).To(BeEmpty())
+		})
+
+		It("Verifies server certificate with CA certificate", func() {
+			mockPM.BIGIPClientCert = filepath.Join(certDir, "client.crt")
+			mockPM.BIGIPClientKey = filepath.Join(certDir, "client.key")
+			mockPM.setupBIGIPRESTClient()
+			_, err := mockPM.GetBigipRegKey()
+			Expect(err).NotTo(BeNil(), "Server certificate signed by unknown CA should be rejected")
+		})
+
+		It("Fails to setup client with invalid certificates", func() {
+			mockPM.BIGIPClientCert = filepath.Join(certDir, "client.crt")
+			mockPM.BIGIPClientKey = filepath.Join(certDir, "missing.key")
+			Expect(mockPM.setupBIGIPRESTClient()).NotTo(Succeed(), "Missing client key should be rejected")
+
+			mockPM.BIGIPClientKey = filepath.Join(certDir, "client.key")
+			mockPM.BIGIPCACert = filepath.Join(certDir, "missing.crt")
+			Expect(mockPM.setupBIGIPRESTClient()).NotTo(Succeed(), "Missing CA certificate should be rejected")
+
+			mockPM.BIGIPCACert = filepath.Join(certDir, "client.key")
+			Expect(mockPM.setupBIGIPRESTClient()).NotTo(Succeed(), "CA certificate without certificates should be rejected")
+		})
 	})
 
 	Describe("BIGIP Queries", func() {
@@ -0,0 +1,125 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QuotasKey is the key of ConfigMap data holding the JSON object of namespace quotas
+const QuotasKey = "quotas"
+
+// quotaLookupError is the failure to read the quotas, virtuals are rejected until the quotas can be read
+type quotaLookupError struct {
+	err error
+}
+
+func (e *quotaLookupError) Error() string {
+	return fmt.Sprintf("%v: %v", QuotaUnavailable, e.err)
+}
+
+// quotaStatus returns the status of virtuals rejected by checkQuota with err
+func quotaStatus(err error) string {
+	var lookupErr *quotaLookupError
+	if errors.As(err, &lookupErr) {
+		return QuotaUnavailable
+	}
+	return QuotaExceeded
+}
+
+// getNamespaceQuota reads the quota of the namespace from the quota ConfigMap informer
+func (ctlr *Controller) getNamespaceQuota(namespace string) (NamespaceQuota, error) {
+	if ctlr.quotaCMInformer == nil {
+		return NamespaceQuota{}, fmt.Errorf("quota ConfigMap %v is not watched", ctlr.quotaCMKey)
+	}
+	obj, found, err := ctlr.quotaCMInformer.GetStore().GetByKey(ctlr.quotaCMKey)
+	if err != nil {
+		return NamespaceQuota{}, err
+	}
+	if !found {
+		return NamespaceQuota{}, fmt.Errorf("quota ConfigMap %v not found", ctlr.quotaCMKey)
+	}
+	cm := obj.(*v1.ConfigMap)
+	quotas := make(map[string]NamespaceQuota)
+	if err = json.Unmarshal([]byte(cm.Data[QuotasKey]), &quotas); err != nil {
+		return NamespaceQuota{}, fmt.Errorf("invalid %v in ConfigMap %v: %v", QuotasKey, ctlr.quotaCMKey, err)
+	}
+	return quotas[namespace], nil
+}
+
+// checkQuota checks whether the virtual of given kind fits in the quota of its namespace.
+// Virtuals are counted from the informers, so deleted virtuals free their quota, and only
+// the ones created before the virtual are counted to keep admitting the oldest virtuals.
+func (ctlr *Controller) checkQuota(kind string, meta metav1.ObjectMeta) error {
+	if ctlr.quotaCMKey == "" {
+		return nil
+	}
+	quota, err := ctlr.getNamespaceQuota(meta.Namespace)
+	if err != nil {
+		log.Errorf("Unable to read the quota of namespace %v: %v", meta.Namespace, err)
+		return &quotaLookupError{err: err}
+	}
+	var limit *int
+	var virtuals []metav1.ObjectMeta
+	switch kind {
+	case VirtualServer:
+		limit = quota.VirtualServerQuota
+		if limit != nil {
+			for _, vs := range ctlr.getAllVirtualServers(meta.Namespace) {
+				virtuals = append(virtuals, vs.ObjectMeta)
+			}
+		}
+	case TransportServer:
+		limit = quota.TransportServerQuota
+		if limit != nil {
+			for _, ts := range ctlr.getAllTransportServers(meta.Namespace) {
+				virtuals = append(virtuals, ts.ObjectMeta)
+			}
+		}
+	}
+	if limit == nil {
+		return nil
+	}
+	if count := countCreatedBefore(virtuals, meta); count >= *limit {
+		return fmt.Errorf("%v: namespace %v already has %v %vs, quota is %v",
+			QuotaExceeded, meta.Namespace, count, kind, *limit)
+	}
+	return nil
+}
+
+// countCreatedBefore counts the virtuals created before the given one. The virtual being
+// admitted has no creation timestamp yet, so all the other virtuals are counted.
+func countCreatedBefore(virtuals []metav1.ObjectMeta, meta metav1.ObjectMeta) int {
+	var count int
+	for _, vrt := range virtuals {
+		if vrt.Name == meta.Name {
+			continue
+		}
+		if meta.CreationTimestamp.IsZero() ||
+			vrt.CreationTimestamp.Before(&meta.CreationTimestamp) ||
+			(vrt.CreationTimestamp.Equal(&meta.CreationTimestamp) && vrt.Name < meta.Name) {
+			count++
+		}
+	}
+	return count
+}
@@ -21,11 +21,14 @@ import (
 	"fmt"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/resource"
 
+	"hash/crc32"
 	"net"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
 
@@ -35,6 +38,7 @@ import (
 	routeapi "github.com/openshift/api/route/v1"
 
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/irules"
 	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
 	v1 "k8s.io/api/core/v1"
 )
@@ -59,6 +63,13 @@ func (rs *ResourceStore) Init() {
 	rs.svcResourceCache = make(map[string]map[string]struct{})
 	rs.ipamContext = make(map[string]ficV1.IPSpec)
 	rs.processedNativeResources = make(map[resourceRef]struct{})
+	rs.memberWarmupStart = make(map[string]map[string]time.Time)
+	rs.warmupUpdates = make(map[string]*rqKey)
+	rs.initialSyncServices = make(map[string]struct{})
+	rs.sharedPoolRefCount = make(map[string]int)
+	rs.drainingMembers = make(map[string]map[drainingMemberKey]drainingMember)
+	rs.drainingServices = make(map[string]time.Time)
+	rs.conflictingNames = make(map[string]resourceRef)
 }
 
 const (
@@ -92,6 +103,22 @@ const (
 	HttpsRedirectDgName = "https_redirect_dg"
 	TLSIRuleName        = "tls_irule"
 	ABPathIRuleName     = "ab_deployment_path_irule"
+	// iRule inserting security headers in HTTP responses
+	SecurityHeadersIRuleName = "security_headers_irule"
+	// iRule inserting Content-Security-Policy header in HTTP responses
+	CSPIRuleName = "csp_response_rewrite_irule"
+	// iRule substituting the regex matches in request body
+	BodyRewriteIRuleName = "request_body_rewrite_irule"
+	// iRule persisting the passthrough connections on the hash of SNI
+	PassthroughPersistIRuleName = "passthrough_persist_irule"
+	// MaxBodyRewriteSize is the maximum size of request body in bytes collected for the rewrite
+	MaxBodyRewriteSize = 1048576
+	// PersistCookieExpiry is the expiry of the cookie inserted by persist action, 0 expiry is the session cookie
+	PersistCookieExpiry = "0d"
+	// PersistAddressNetmask and PersistAddressTimeout are the netmask and timeout in seconds
+	// of the address persistence of persist action
+	PersistAddressNetmask = "255.255.255.255"
+	PersistAddressTimeout = 180
 )
 
 // constants for TLS references
@@ -254,13 +281,17 @@ func (ctlr *Controller) virtualPorts(input interface{}) []portStruct {
 	return ports
 }
 
+// MaxVirtualServerNameLength leaves room for the partition prefix in the 255 characters of BIG-IP name
+const MaxVirtualServerNameLength = 230
+
 // format the virtual server name for an VirtualServer
 func formatVirtualServerName(ip string, port int32) string {
 	// Strip any bracket characters; replace special characters ". : /"
 	// with "-" and "%" with ".", for naming purposes
 	ip = strings.Trim(ip, "[]")
 	ip = AS3NameFormatter(ip)
-	return fmt.Sprintf("crd_%s_%d", ip, port)
+	portSuffix := fmt.Sprintf("_%d", port)
+	return truncateVSName("crd_"+ip, MaxVirtualServerNameLength-len(portSuffix)) + portSuffix
 }
 
 // format the virtual server name for an VirtualServer
@@ -268,28 +299,53 @@ func formatCustomVirtualServerName(name string, port int32) string {
 	// Replace special characters ". : /"
 	// with "-" and "%" with ".", for naming purposes
 	name = AS3NameFormatter(name)
-	return fmt.Sprintf("%s_%d", name, port)
+	portSuffix := fmt.Sprintf("_%d", port)
+	return truncateVSName(name, MaxVirtualServerNameLength-len(portSuffix)) + portSuffix
+}
+
+// truncateVSName truncates the name longer than maxLen and appends the CRC32 of
+// the full name, so that the truncated names remain unique
+func truncateVSName(name string, maxLen int) string {
+	if len(name) <= maxLen {
+		return name
+	}
+	suffix := fmt.Sprintf("_%08x", crc32.ChecksumIEEE([]byte(name)))
+	return name[:maxLen-len(suffix)] + suffix
 }
 
 func (ctlr *Controller) framePoolName(ns string, pool cisapiv1.Pool, host string) string {
 
+	svcNamespace := ns
+	if pool.ServiceNamespace != "" {
+		svcNamespace = pool.ServiceNamespace
+	}
+	// named service ports that cannot be resolved are rejected by validation of the resources
+	servicePort, _ := ctlr.resolveServicePort(svcNamespace, pool.Service, pool.ServicePort)
+	if pool.SharedPool {
+		return formatSharedPoolName(svcNamespace, pool.Service, servicePort)
+	}
 	poolName := pool.Name
+	// AS3 names begin with a letter unlike the service labels of SRV record, e.g. _http._tcp
+	if poolName == "" && pool.SRVRecord != "" {
+		srvName := strings.TrimLeft(pool.SRVRecord, "_")
+		return formatPoolName(ns, srvName, intstr.IntOrString{IntVal: pool.SRVPort}, "", host)
+	}
 	if poolName == "" {
-		targetPort := intstr.IntOrString{IntVal: pool.ServicePort}
+		targetPort := intstr.IntOrString{IntVal: servicePort}
 
 		if (intstr.IntOrString{}) == targetPort {
-			svcNamespace := ns
-			if pool.ServiceNamespace != "" {
-				svcNamespace = pool.ServiceNamespace
-			}
-			targetPort = ctlr.fetchTargetPort(svcNamespace, pool.Service, pool.ServicePort)
+			targetPort = ctlr.fetchTargetPort(svcNamespace, pool.Service, servicePort)
 		}
-		poolName = formatPoolName(ns, pool.Service, targetPort, pool.NodeMemberLabel, host)
+		nodeMemberLabel, _ := getPoolNodeMemberLabel(pool)
+		poolName = formatPoolName(ns, pool.Service, targetPort, nodeMemberLabel, host)
 	}
 
 	return poolName
 }
 
+// selectorSpecialChars are the characters of label selector expressions not allowed in pool names
+var selectorSpecialChars = regexp.MustCompile(`[^A-Za-z0-9_./:-]+`)
+
 // format the pool name for an VirtualServer
 func formatPoolName(namespace, svc string, port intstr.IntOrString, nodeMemberLabel string, host string) string {
 	servicePort := fetchPortString(port)
@@ -299,11 +355,18 @@ func formatPoolName(namespace, svc string, port intstr.IntOrString, nodeMemberLa
 	}
 	if nodeMemberLabel != "" {
 		nodeMemberLabel = strings.ReplaceAll(nodeMemberLabel, "=", "_")
+		// operators and separators of selector expressions, e.g. role in (backend,api)
+		nodeMemberLabel = strings.TrimRight(selectorSpecialChars.ReplaceAllString(nodeMemberLabel, "_"), "_")
 		poolName = fmt.Sprintf("%s_%s", poolName, nodeMemberLabel)
 	}
 	return AS3NameFormatter(poolName)
 }
 
+// format the name of pool shared by VirtualServers
+func formatSharedPoolName(namespace, svc string, port int32) string {
+	return AS3NameFormatter(fmt.Sprintf("shared_%s_%s_%d", namespace, svc, port))
+}
+
 // format the monitor name for an VirtualServer pool
 func formatMonitorName(namespace, svc string, monitorType string, port int32, hostName string, path string) string {
 	monitorName := fmt.Sprintf("%s_%s", svc, namespace)
@@ -401,6 +464,140 @@ func (ctlr *Controller) fetchTargetPort(namespace, svcName string, servicePort i
 	return targetPort
 }
 
+// resolveServicePort returns the number of the service port referred either by number or by name
+func (ctlr *Controller) resolveServicePort(namespace, svcName string, servicePort intstr.IntOrString) (int32, error) {
+	if servicePort.Type == intstr.Int {
+		return servicePort.IntVal, nil
+	}
+	svc := ctlr.GetService(namespace, svcName)
+	if svc == nil {
+		return 0, fmt.Errorf("service '%v/%v' of port '%v' not found", namespace, svcName, servicePort.StrVal)
+	}
+	return getServicePortNumber(svc, servicePort.StrVal)
+}
+
+// getServicePortNumber returns the number of the service port with the name
+func getServicePortNumber(svc *v1.Service, portName string) (int32, error) {
+	for _, port := range svc.Spec.Ports {
+		if port.Name == portName {
+			return port.Port, nil
+		}
+	}
+	return 0, fmt.Errorf("port '%v' not found in service '%v/%v'", portName, svc.Namespace, svc.Name)
+}
+
+// setGRPCMonitor converts a gRPC health monitor to an external monitor invoking the gRPC health check script,
+// VirtualServers with gRPC monitor are rejected by checkValidVirtualServer when the script is not configured
+func (ctlr *Controller) setGRPCMonitor(monitor *Monitor, grpcService string) {
+	if monitor.Type != GRPCMonitorType {
+		return
+	}
+	monitor.Type = ExternalMonitorType
+	monitor.ScriptPath = ctlr.grpcMonitorScriptPath
+	monitor.GRPCService = grpcService
+}
+
+// getPoolBalance returns the load balancing method of pool. Pools warming up their members default
+// to ratio-member, as the ratio of members ramped up during warm-up is honoured only by ratio methods.
+func getPoolBalance(balance string, warmupTime int32) string {
+	if balance == "" && warmupTime > 0 {
+		return "ratio-member"
+	}
+	return balance
+}
+
+// handlePoolRequestTimeout sets the request timeout of the HTTP profile of virtual from
+// the requestTimeout of its pools. Pools share the HTTP profile of their virtual, so the
+// largest timeout is used to not cut the long-running requests of any pool.
+func (ctlr *Controller) handlePoolRequestTimeout(rsCfg *ResourceConfig) {
+	rsCfg.Virtual.RequestTimeout = 0
+	for _, pool := range rsCfg.Pools {
+		if pool.RequestTimeout <= rsCfg.Virtual.RequestTimeout {
+			continue
+		}
+		if rsCfg.Virtual.RequestTimeout > 0 {
+			log.Debugf("Pools of virtual %v have different requestTimeout, using %v seconds",
+				rsCfg.Virtual.Name, pool.RequestTimeout)
+		}
+		rsCfg.Virtual.RequestTimeout = pool.RequestTimeout
+	}
+}
+
+// handleContentSecurityPolicy inserts the Content-Security-Policy header of the VirtualServer
+// annotation in HTTP responses. Virtuals grouped on the same address use the first policy.
+func handleContentSecurityPolicy(rsCfg *ResourceConfig, vs *cisapiv1.VirtualServer) {
+	csp := vs.Annotations[ContentSecurityPolicyAnnotation]
+	if csp == "" {
+		return
+	}
+	iRuleName := getRSCfgResName(rsCfg.Virtual.Name, CSPIRuleName)
+	if iRule, ok := rsCfg.IRulesMap[NameRef{Name: iRuleName, Partition: rsCfg.Virtual.Partition}]; ok {
+		if iRule.Code != contentSecurityPolicyIRule(csp) {
+			log.Warningf("Ignoring %v of VirtualServer %v/%v as virtual %v has a different policy",
+				ContentSecurityPolicyAnnotation, vs.Namespace, vs.Name, rsCfg.Virtual.Name)
+		}
+		return
+	}
+	rsCfg.addIRule(iRuleName, rsCfg.Virtual.Partition, contentSecurityPolicyIRule(csp))
+	rsCfg.Virtual.AddIRule(JoinBigipPath(rsCfg.Virtual.Partition, iRuleName))
+}
+
+// handleRequestBodyRewrite substitutes the regex matches in the request body of the VirtualServer.
+// BIG-IP LTM policies do not act on the HTTP body, so the body is collected and rewritten with an iRule.
+// Virtuals grouped on the same address use the first rewrite.
+func handleRequestBodyRewrite(rsCfg *ResourceConfig, vs *cisapiv1.VirtualServer) {
+	rewrite := vs.Spec.RequestBodyRewrite
+	if rewrite == nil {
+		return
+	}
+	iRuleName := getRSCfgResName(rsCfg.Virtual.Name, BodyRewriteIRuleName)
+	if iRule, ok := rsCfg.IRulesMap[NameRef{Name: iRuleName, Partition: rsCfg.Virtual.Partition}]; ok {
+		if iRule.Code != requestBodyRewriteIRule(rewrite.Match, rewrite.Replace) {
+			log.Warningf("Ignoring requestBodyRewrite of VirtualServer %v/%v as virtual %v has a different rewrite",
+				vs.Namespace, vs.Name, rsCfg.Virtual.Name)
+		}
+		return
+	}
+	rsCfg.addIRule(iRuleName, rsCfg.Virtual.Partition, requestBodyRewriteIRule(rewrite.Match, rewrite.Replace))
+	rsCfg.Virtual.AddIRule(JoinBigipPath(rsCfg.Virtual.Partition, iRuleName))
+}
+
+// handlePassthroughPersistence attaches the iRule selecting the pool member of passthrough
+// connections by the hash of the SNI, which persists the connections of a hostname on a member
+func handlePassthroughPersistence(rsCfg *ResourceConfig) {
+	iRuleName := getRSCfgResName(rsCfg.Virtual.Name, PassthroughPersistIRuleName)
+	rsCfg.addIRule(iRuleName, rsCfg.Virtual.Partition, passthroughPersistenceIRule())
+	rsCfg.Virtual.AddIRule(JoinBigipPath(rsCfg.Virtual.Partition, iRuleName))
+}
+
+// handleWebSocket attaches the built-in iRule preserving the WebSocket upgrade of HTTP connections
+func handleWebSocket(rsCfg *ResourceConfig) {
+	rsCfg.addIRule(irules.WebSocketUpgradeIRuleName, rsCfg.Virtual.Partition, irules.WebSocketUpgradeIRule)
+	rsCfg.Virtual.AddIRule(JoinBigipPath(rsCfg.Virtual.Partition, irules.WebSocketUpgradeIRuleName))
+}
+
+// handleInsertHeaders adds the headers of VirtualServer to the headers inserted by the virtual.
+// Virtual inserts the header of the VirtualServer processed first for the same header name.
+func handleInsertHeaders(rsCfg *ResourceConfig, vs *cisapiv1.VirtualServer) {
+	for _, header := range vs.Spec.InsertHeaders {
+		found := false
+		for _, inserted := range rsCfg.Virtual.InsertHeaders {
+			if strings.EqualFold(inserted.Name, header.Name) {
+				found = true
+				if inserted.Value != header.Value {
+					log.Warningf("Ignoring header %v of VirtualServer %v/%v as virtual %v inserts a different value",
+						header.Name, vs.Namespace, vs.Name, rsCfg.Virtual.Name)
+				}
+				break
+			}
+		}
+		if !found {
+			rsCfg.Virtual.InsertHeaders = append(rsCfg.Virtual.InsertHeaders,
+				HTTPHeader{Name: header.Name, Value: header.Value})
+		}
+	}
+}
+
 // Prepares resource config based on VirtualServer resource config
 func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 	rsCfg *ResourceConfig,
@@ -434,31 +631,48 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 			continue
 		}
 		framedPools[poolName] = struct{}{}
-		targetPort := ctlr.fetchTargetPort(vs.Namespace, pl.Service, pl.ServicePort)
-
-		if (intstr.IntOrString{}) == targetPort {
-			targetPort = intstr.IntOrString{IntVal: pl.ServicePort}
-		}
 		svcNamespace := vs.Namespace
 		if pl.ServiceNamespace != "" {
 			svcNamespace = pl.ServiceNamespace
 		}
+		servicePort, err := ctlr.resolveServicePort(svcNamespace, pl.Service, pl.ServicePort)
+		if err != nil {
+			log.Errorf("Invalid servicePort in pool %v of VirtualServer %v/%v: %v", pl.Path, vs.Namespace, vs.Name, err)
+			return err
+		}
+		targetPort := ctlr.fetchTargetPort(svcNamespace, pl.Service, servicePort)
+		nodeMemberLabel, _ := getPoolNodeMemberLabel(pl)
+
+		if (intstr.IntOrString{}) == targetPort {
+			targetPort = intstr.IntOrString{IntVal: servicePort}
+		}
 		pool := Pool{
 			Name:              poolName,
 			Partition:         rsCfg.Virtual.Partition,
 			ServiceName:       pl.Service,
 			ServiceNamespace:  svcNamespace,
 			ServicePort:       targetPort,
-			NodeMemberLabel:   pl.NodeMemberLabel,
-			Balance:           pl.Balance,
+			NodeMemberLabel:   nodeMemberLabel,
+			Balance:           getPoolBalance(pl.Balance, pl.WarmupTime),
 			ReselectTries:     pl.ReselectTries,
 			ServiceDownAction: pl.ServiceDownAction,
-		}
-		if pl.Monitor.Name != "" && pl.Monitor.Reference == "bigip" {
+			WarmupTime:        pl.WarmupTime,
+			RequestTimeout:    pl.RequestTimeout,
+			Shared:            pl.SharedPool,
+			SRVRecord:         pl.SRVRecord,
+			SRVPort:           pl.SRVPort,
+			DrainPeriod:       pl.DrainPeriod,
+		}
+		for _, em := range pl.ExternalMembers {
+			pool.ExternalMembers = append(pool.ExternalMembers, ExternalMember{FQDN: em.FQDN, Port: em.Port})
+		}
+		if pl.MonitorDisabled {
+			log.Debugf("Health monitors disabled for pool %v of VirtualServer %v/%v", poolName, vs.Namespace, vs.Name)
+		} else if pl.Monitor.Name != "" && pl.Monitor.Reference == "bigip" {
 			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: pl.Monitor.Name, Reference: pl.Monitor.Reference})
-		} else if pl.Monitor.Send != "" && pl.Monitor.Type != "" {
+		} else if (pl.Monitor.Send != "" || pl.Monitor.Type == GRPCMonitorType) && pl.Monitor.Type != "" {
 			if pl.Name == "" {
-				monitorName = formatMonitorName(vs.ObjectMeta.Namespace, pl.Service, pl.Monitor.Type, pl.ServicePort, vs.Spec.Host, pl.Path)
+				monitorName = formatMonitorName(vs.ObjectMeta.Namespace, pl.Service, pl.Monitor.Type, servicePort, vs.Spec.Host, pl.Path)
 			}
 			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName)})
 			monitor := Monitor{
@@ -471,6 +685,7 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 				Timeout:    pl.Monitor.Timeout,
 				TargetPort: pl.Monitor.TargetPort,
 			}
+			ctlr.setGRPCMonitor(&monitor, pl.Monitor.GRPCService)
 			monitors = append(monitors, monitor)
 		} else if pl.Monitors != nil {
 			for _, monitor := range pl.Monitors {
@@ -481,12 +696,13 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 					if monitor.TargetPort != 0 {
 						formatPort = monitor.TargetPort
 					} else {
-						formatPort = pl.ServicePort
+						formatPort = servicePort
 					}
 					if monitor.Name == "" {
 						monitorName = formatMonitorName(vs.ObjectMeta.Namespace, pl.Service, monitor.Type, formatPort, vs.Spec.Host, pl.Path)
 					}
 					pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName)})
+					grpcService := monitor.GRPCService
 					monitor := Monitor{
 						Name:       monitorName,
 						Partition:  rsCfg.Virtual.Partition,
@@ -497,6 +713,7 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 						Timeout:    monitor.Timeout,
 						TargetPort: monitor.TargetPort,
 					}
+					ctlr.setGRPCMonitor(&monitor, grpcService)
 					rsCfg.Monitors = append(rsCfg.Monitors, monitor)
 				}
 			}
@@ -506,6 +723,10 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 	rsCfg.Pools = append(rsCfg.Pools, pools...)
 	rsCfg.Monitors = append(rsCfg.Monitors, monitors...)
 
+	if !passthroughVS {
+		ctlr.handlePoolRequestTimeout(rsCfg)
+	}
+
 	// set the SNAT policy to auto if it's not defined by end user
 	if vs.Spec.SNAT == "" {
 		if rsCfg.Virtual.SNAT == "" {
@@ -551,8 +772,14 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 		rsCfg.Virtual.ProfileBotDefense = vs.Spec.BotDefense
 	}
 
+	if vs.Spec.ProfileWebAcceleration != "" {
+		rsCfg.Virtual.ProfileWebAcceleration = vs.Spec.ProfileWebAcceleration
+	}
+
 	if vs.Spec.ProfileMultiplex != "" {
 		rsCfg.Virtual.ProfileMultiplex = vs.Spec.ProfileMultiplex
+		// VirtualServers are always configured with an HTTP profile
+		rsCfg.Virtual.ProfileMultiplexEnable = true
 	}
 
 	// Do not Create Virtual Server L7 Forwarding policies if HTTPTraffic is set to None or Redirect
@@ -572,6 +799,7 @@ func (ctlr *Controller) prepareRSConfigFromVirtualServer(
 		policyName := formatPolicyName(vs.Spec.Host, vs.Spec.HostGroup, rsCfg.Virtual.Name)
 
 		rsCfg.AddRuleToPolicy(policyName, vs.Namespace, rules)
+		handleInsertHeaders(rsCfg, vs)
 	}
 
 	// Attach user specified iRules
@@ -595,6 +823,15 @@ func (rsCfg *ResourceConfig) AddRuleToPolicy(policyName, partition string, rules
 	}
 }
 
+// getTLSCipher returns the ciphers of the SSL profiles, ciphers of the resource take
+// precedence over the global ones
+func (ctlr *Controller) getTLSCipher(tlsContext TLSContext) TLSCipher {
+	if tlsContext.bigIPSSLProfiles.tlsCipher != (TLSCipher{}) {
+		return tlsContext.bigIPSSLProfiles.tlsCipher
+	}
+	return ctlr.resources.baseRouteConfig.TLSCipher
+}
+
 // function updates the rscfg as per the passed parameter for routes as well as for virtual server
 func (ctlr *Controller) handleTLS(
 	rsCfg *ResourceConfig,
@@ -650,8 +887,9 @@ func (ctlr *Controller) handleTLS(
 							return false
 						}
 						secrets = append(secrets, obj.(*v1.Secret))
+						ctlr.certVersions.set(obj.(*v1.Secret))
 					}
-					err, _ := ctlr.createSecretClientSSLProfile(rsCfg, secrets, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileClient)
+					err, _ := ctlr.createSecretClientSSLProfile(rsCfg, secrets, ctlr.getTLSCipher(tlsContext), CustomProfileClient)
 					if err != nil {
 						log.Errorf("error %v encountered while creating clientssl profile for '%s' '%s'/'%s'",
 							err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
@@ -673,7 +911,8 @@ func (ctlr *Controller) handleTLS(
 							return false
 						}
 						secrets = append(secrets, obj.(*v1.Secret))
-						err, _ = ctlr.createSecretServerSSLProfile(rsCfg, secrets, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer)
+						ctlr.certVersions.set(obj.(*v1.Secret))
+						err, _ = ctlr.createSecretServerSSLProfile(rsCfg, secrets, ctlr.getTLSCipher(tlsContext), CustomProfileServer)
 						if err != nil {
 							log.Errorf("error %v encountered while creating serverssl profile for '%s' '%s'/'%s'",
 								err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
@@ -687,7 +926,7 @@ func (ctlr *Controller) handleTLS(
 				if tlsContext.bigIPSSLProfiles.key != "" && tlsContext.bigIPSSLProfiles.certificate != "" {
 					cert := certificate{Cert: tlsContext.bigIPSSLProfiles.certificate, Key: tlsContext.bigIPSSLProfiles.key}
 					err, _ := ctlr.createClientSSLProfile(rsCfg, []certificate{cert},
-						fmt.Sprintf("%s-clientssl", tlsContext.name), tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileClient)
+						fmt.Sprintf("%s-clientssl", tlsContext.name), tlsContext.namespace, ctlr.getTLSCipher(tlsContext), CustomProfileClient)
 					if err != nil {
 						log.Debugf("error %v encountered while creating clientssl profile  for '%s' '%s'/'%s'",
 							err, tlsContext.resourceType, tlsContext.namespace, tlsContext.name)
@@ -700,10 +939,10 @@ func (ctlr *Controller) handleTLS(
 					cert := certificate{Cert: tlsContext.bigIPSSLProfiles.destinationCACertificate}
 					if tlsContext.bigIPSSLProfiles.caCertificate != "" {
 						err, _ = ctlr.createServerSSLProfile(rsCfg, []certificate{cert},
-							tlsContext.bigIPSSLProfiles.caCertificate, tlsContext.name, tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer)
+							tlsContext.bigIPSSLProfiles.caCertificate, tlsContext.name, tlsContext.namespace, ctlr.getTLSCipher(tlsContext), CustomProfileServer)
 					} else {
 						err, _ = ctlr.createServerSSLProfile(rsCfg, []certificate{cert},
-							"", fmt.Sprintf("%s-serverssl", tlsContext.name), tlsContext.namespace, ctlr.resources.baseRouteConfig.TLSCipher, CustomProfileServer)
+							"", fmt.Sprintf("%s-serverssl", tlsContext.name), tlsContext.namespace, ctlr.getTLSCipher(tlsContext), CustomProfileServer)
 					}
 					if err != nil {
 						log.Debugf("error %v encountered while creating serverssl profile  for '%s' '%s'/'%s'",
@@ -889,6 +1128,7 @@ func (ctlr *Controller) handleVirtualServerTLS(
 	} else if tls.Spec.TLS.ServerSSL != "" {
 		bigIPSSLProfiles.serverSSLs = append(bigIPSSLProfiles.serverSSLs, tls.Spec.TLS.ServerSSL)
 	}
+	bigIPSSLProfiles.tlsCipher = getTLSCipherOfTLSProfile(tls)
 	var poolPathRefs []poolPathRef
 	for _, pl := range vs.Spec.Pools {
 
@@ -900,7 +1140,7 @@ func (ctlr *Controller) handleVirtualServerTLS(
 
 		poolPathRefs = append(poolPathRefs, poolPathRef{pl.Path, poolName, tls.Spec.Hosts})
 	}
-	return ctlr.handleTLS(rsCfg, TLSContext{vs.ObjectMeta.Name,
+	processed := ctlr.handleTLS(rsCfg, TLSContext{vs.ObjectMeta.Name,
 		vs.ObjectMeta.Namespace,
 		VirtualServer,
 		tls.Spec.TLS.Reference,
@@ -912,6 +1152,46 @@ func (ctlr *Controller) handleVirtualServerTLS(
 		poolPathRefs,
 		bigIPSSLProfiles,
 	})
+	// access policy is attached to the virtual receiving the TLS traffic
+	if processed && rsCfg.MetaData.Protocol == "https" {
+		if accessPolicy, ok := tls.Spec.TLS.AccessPolicy[tls.Spec.TLS.Termination]; ok {
+			rsCfg.Virtual.PolicyEndpointAccess = accessPolicy
+		}
+	}
+	if processed && tls.Spec.TLS.OCSPStapling.Enabled {
+		setOCSPStapling(rsCfg, tls)
+	}
+	return processed
+}
+
+// setOCSPStapling configures the OCSP stapling of TLSProfile on the client SSL profiles of virtual,
+// which are created from secrets, BIG-IP referenced profiles need to configure it on BIG-IP
+func setOCSPStapling(rsCfg *ResourceConfig, tls *cisapiv1.TLSProfile) {
+	if tls.Spec.TLS.Reference == BIGIP {
+		log.Warningf("OCSP stapling of TLSProfile %s/%s is not applicable to BIG-IP referenced profiles",
+			tls.Namespace, tls.Name)
+		return
+	}
+	for key, prof := range rsCfg.customProfiles {
+		// default SNI profile does not carry the certificates
+		if prof.Context != CustomProfileClient || len(prof.Certificates) == 0 {
+			continue
+		}
+		prof.OCSPResponderURL = tls.Spec.TLS.OCSPStapling.OCSPResponderURL
+		prof.OCSPTimeout = tls.Spec.TLS.OCSPStapling.StaplingTimeout
+		rsCfg.customProfiles[key] = prof
+	}
+}
+
+// getTLSCipherOfTLSProfile returns the ciphers of TLSProfile, cipher group is used with TLS 1.3
+func getTLSCipherOfTLSProfile(tls *cisapiv1.TLSProfile) TLSCipher {
+	if tls.Spec.TLS.CipherGroup != "" {
+		return TLSCipher{TLSVersion: string(TLSVerion1_3), CipherGroup: tls.Spec.TLS.CipherGroup}
+	}
+	if tls.Spec.TLS.Cipher != "" {
+		return TLSCipher{Ciphers: tls.Spec.TLS.Cipher}
+	}
+	return TLSCipher{}
 }
 
 // validate TLSProfile
@@ -945,6 +1225,11 @@ func validateTLSProfile(tls *cisapiv1.TLSProfile) bool {
 			return false
 		}
 	}
+	if tls.Spec.TLS.OCSPStapling.Enabled && tls.Spec.TLS.OCSPStapling.OCSPResponderURL == "" {
+		log.Errorf("TLSProfile %s with OCSP stapling enabled should contain ocspResponderURL",
+			tls.ObjectMeta.Name)
+		return false
+	}
 	return true
 }
 
@@ -1016,7 +1301,8 @@ func (v *Virtual) SetVirtualAddress(bindAddr string, port int32) {
 		addr := net.ParseIP(ip)
 		if nil != addr {
 			var format string
-			if nil != addr.To4() {
+			// IPv4-mapped IPv6 literal is formatted as IPv6 address
+			if nil != addr.To4() && !strings.Contains(ip, ":") {
 				format = "/%s/%s%s:%d"
 			} else {
 				format = "/%s/%s%s.%d"
@@ -1026,6 +1312,21 @@ func (v *Virtual) SetVirtualAddress(bindAddr string, port int32) {
 	}
 }
 
+// SetRouteDomain sets the route domain of VirtualAddress, which is skipped
+// when the bind address already has a route domain
+func (v *Virtual) SetRouteDomain(routeDomain int) {
+	if v.VirtualAddress == nil || routeDomain <= 0 {
+		return
+	}
+	bindAddr := v.VirtualAddress.BindAddr
+	if _, rd := split_ip_with_route_domain(bindAddr); rd != "" {
+		return
+	}
+	v.SetVirtualAddress(fmt.Sprintf("%v%%%v", bindAddr, routeDomain), v.VirtualAddress.Port)
+	v.VirtualAddress.BindAddr = bindAddr
+	v.VirtualAddress.RouteDomain = routeDomain
+}
+
 // SetPolicy sets a policy
 func (rc *ResourceConfig) SetPolicy(policy Policy) {
 	toFind := nameRef{
@@ -1158,16 +1459,58 @@ func (rs *ResourceStore) updateCaches() {
 	// No need to deep copy as each RsCfg will be framed in a fresh memory block while creating live ltmConfig
 	rs.ltmConfigCache = rs.getSanitizedLTMConfigCopy()
 	rs.gtmConfigCache = rs.getGTMConfigCopy()
+	rs.resyncPending = false
+}
+
+// markConfigUpdated forces the post of the current config irrespective of the changes
+func (rs *ResourceStore) markConfigUpdated() {
+	rs.resyncPending = true
 }
 
 func (rs *ResourceStore) isConfigUpdated() bool {
-	return !reflect.DeepEqual(rs.ltmConfig, rs.ltmConfigCache) ||
+	return rs.resyncPending || !reflect.DeepEqual(rs.ltmConfig, rs.ltmConfigCache) ||
 		!reflect.DeepEqual(rs.gtmConfig, rs.gtmConfigCache)
 }
 
 // Deletes respective VirtualServer resource configuration from  ResourceStore
 func (rs *ResourceStore) deleteVirtualServer(partition, rsName string) {
-	delete(rs.getPartitionResourceMap(partition), rsName)
+	rsMap := rs.getPartitionResourceMap(partition)
+	if rsCfg, ok := rsMap[rsName]; ok {
+		rs.updateSharedPoolRefCount(rsCfg, nil)
+	}
+	delete(rsMap, rsName)
+}
+
+// updateSharedPoolRefCount updates the reference count of shared pools when the
+// resource config oldCfg is replaced with newCfg, either of them can be nil.
+// Shared pool is created with its first reference and deleted with its last reference.
+func (rs *ResourceStore) updateSharedPoolRefCount(oldCfg, newCfg *ResourceConfig) {
+	if newCfg != nil {
+		for _, pool := range newCfg.Pools {
+			if !pool.Shared {
+				continue
+			}
+			key := pool.Partition + "/" + pool.Name
+			if rs.sharedPoolRefCount[key] == 0 {
+				log.Debugf("Creating shared pool %v", key)
+			}
+			rs.sharedPoolRefCount[key]++
+		}
+	}
+	if oldCfg != nil {
+		for _, pool := range oldCfg.Pools {
+			if !pool.Shared {
+				continue
+			}
+			key := pool.Partition + "/" + pool.Name
+			if rs.sharedPoolRefCount[key] <= 1 {
+				log.Debugf("Deleting shared pool %v", key)
+				delete(rs.sharedPoolRefCount, key)
+				continue
+			}
+			rs.sharedPoolRefCount[key]--
+		}
+	}
 }
 
 // Update the tenant priority in ltmConfigCache
@@ -1239,6 +1582,11 @@ func (rc *ResourceConfig) copyConfig(cfg *ResourceConfig) {
 	//AllowVLANS
 	rc.Virtual.AllowVLANs = make([]string, len(cfg.Virtual.AllowVLANs))
 	copy(rc.Virtual.AllowVLANs, cfg.Virtual.AllowVLANs)
+	//InsertHeaders
+	if cfg.Virtual.InsertHeaders != nil {
+		rc.Virtual.InsertHeaders = make([]HTTPHeader, len(cfg.Virtual.InsertHeaders))
+		copy(rc.Virtual.InsertHeaders, cfg.Virtual.InsertHeaders)
+	}
 
 	// Pools
 	rc.Pools = make(Pools, len(cfg.Pools))
@@ -1249,6 +1597,10 @@ func (rc *ResourceConfig) copyConfig(cfg *ResourceConfig) {
 		copy(rc.Pools[i].Members, cfg.Pools[i].Members)
 		rc.Pools[i].MonitorNames = make([]MonitorName, len(cfg.Pools[i].MonitorNames))
 		copy(rc.Pools[i].MonitorNames, cfg.Pools[i].MonitorNames)
+		if cfg.Pools[i].ExternalMembers != nil {
+			rc.Pools[i].ExternalMembers = make([]ExternalMember, len(cfg.Pools[i].ExternalMembers))
+			copy(rc.Pools[i].ExternalMembers, cfg.Pools[i].ExternalMembers)
+		}
 	}
 
 	// Policies
@@ -1558,6 +1910,12 @@ func (ctlr *Controller) HandlePathBasedABIRule(
 }
 
 func (ctlr *Controller) deleteVirtualServer(partition, rsName string) {
+	if ctlr.protectedPartitions[partition] {
+		if rsCfg, ok := ctlr.resources.getPartitionResourceMap(partition)[rsName]; ok {
+			ctlr.recordPartitionProtectedEvent(partition, rsCfg)
+		}
+		return
+	}
 	ctlr.resources.deleteVirtualServer(partition, rsName)
 }
 
@@ -1584,10 +1942,16 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 	} else {
 		monitorName = poolName + "-monitor"
 	}
-	targetPort := ctlr.fetchTargetPort(vs.Namespace, vs.Spec.Pool.Service, vs.Spec.Pool.ServicePort)
+	servicePort, err := ctlr.resolveServicePort(vs.Namespace, vs.Spec.Pool.Service, vs.Spec.Pool.ServicePort)
+	if err != nil {
+		log.Errorf("Invalid servicePort in pool of TransportServer %v/%v: %v", vs.Namespace, vs.Name, err)
+		return err
+	}
+	targetPort := ctlr.fetchTargetPort(vs.Namespace, vs.Spec.Pool.Service, servicePort)
 	if (intstr.IntOrString{}) == targetPort {
-		targetPort = intstr.IntOrString{IntVal: vs.Spec.Pool.ServicePort}
+		targetPort = intstr.IntOrString{IntVal: servicePort}
 	}
+	nodeMemberLabel, _ := getPoolNodeMemberLabel(vs.Spec.Pool)
 
 	pool := Pool{
 		Name:              poolName,
@@ -1595,16 +1959,18 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 		ServiceName:       vs.Spec.Pool.Service,
 		ServiceNamespace:  vs.ObjectMeta.Namespace,
 		ServicePort:       targetPort,
-		NodeMemberLabel:   vs.Spec.Pool.NodeMemberLabel,
-		Balance:           vs.Spec.Pool.Balance,
+		NodeMemberLabel:   nodeMemberLabel,
+		Balance:           getPoolBalance(vs.Spec.Pool.Balance, vs.Spec.Pool.WarmupTime),
 		ReselectTries:     vs.Spec.Pool.ReselectTries,
 		ServiceDownAction: vs.Spec.Pool.ServiceDownAction,
+		WarmupTime:        vs.Spec.Pool.WarmupTime,
+		DrainPeriod:       vs.Spec.Pool.DrainPeriod,
 	}
 	if vs.Spec.Pool.Monitor.Name != "" && vs.Spec.Pool.Monitor.Reference == BIGIP {
 		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitorName, Reference: vs.Spec.Pool.Monitor.Reference})
 	} else if vs.Spec.Pool.Monitor.Type != "" {
 		if vs.Spec.Pool.Name == "" {
-			monitorName = formatMonitorName(vs.ObjectMeta.Namespace, vs.Spec.Pool.Service, vs.Spec.Pool.Monitor.Type, vs.Spec.Pool.ServicePort, "", "")
+			monitorName = formatMonitorName(vs.ObjectMeta.Namespace, vs.Spec.Pool.Service, vs.Spec.Pool.Monitor.Type, servicePort, "", "")
 		}
 		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: JoinBigipPath(rsCfg.Virtual.Partition, monitorName)})
 
@@ -1613,8 +1979,8 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 			Partition:  rsCfg.Virtual.Partition,
 			Type:       vs.Spec.Pool.Monitor.Type,
 			Interval:   vs.Spec.Pool.Monitor.Interval,
-			Send:       "",
-			Recv:       "",
+			Send:       vs.Spec.Pool.Monitor.Send,
+			Recv:       vs.Spec.Pool.Monitor.Recv,
 			Timeout:    vs.Spec.Pool.Monitor.Timeout,
 			TargetPort: vs.Spec.Pool.Monitor.TargetPort,
 		}
@@ -1629,7 +1995,7 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 				if monitor.TargetPort != 0 {
 					formatPort = monitor.TargetPort
 				} else {
-					formatPort = pl.ServicePort
+					formatPort = servicePort
 				}
 
 				if monitor.Name == "" {
@@ -1641,8 +2007,8 @@ func (ctlr *Controller) prepareRSConfigFromTransportServer(
 					Partition:  rsCfg.Virtual.Partition,
 					Type:       monitor.Type,
 					Interval:   monitor.Interval,
-					Send:       "",
-					Recv:       "",
+					Send:       monitor.Send,
+					Recv:       monitor.Recv,
 					Timeout:    monitor.Timeout,
 					TargetPort: monitor.TargetPort,
 				}
@@ -1775,16 +2141,69 @@ func (ctlr *Controller) handleVSResourceConfigForPolicy(
 	rsCfg.Virtual.Firewall = plc.Spec.L3Policies.FirewallPolicy
 	rsCfg.Virtual.PersistenceProfile = plc.Spec.Profiles.PersistenceProfile
 	rsCfg.Virtual.ProfileMultiplex = plc.Spec.Profiles.ProfileMultiplex
-	rsCfg.Virtual.ProfileDOS = plc.Spec.L3Policies.DOS
+	// VirtualServers are always configured with an HTTP profile
+	rsCfg.Virtual.ProfileMultiplexEnable = true
+	if mask := plc.Spec.Profiles.ProfileMultiplexSourceMask; mask != "" && rsCfg.Virtual.ProfileMultiplex != "" {
+		if !isValidIPv4Netmask(mask) {
+			return fmt.Errorf("invalid profileMultiplexSourceMask %v in Policy %v/%v, it must be an IPv4 netmask",
+				mask, plc.Namespace, plc.Name)
+		}
+		rsCfg.Virtual.MultiplexSourceMask = mask
+	}
+	if err := validateDOSProfiles(plc); err != nil {
+		return err
+	}
+	// application and network DoS profiles take precedence over the dos profile and are both attached
+	l3 := plc.Spec.L3Policies
+	rsCfg.Virtual.ProfileDOS = l3.DOS
+	rsCfg.Virtual.ProfileDOSNetwork = ""
+	if l3.DOSApplicationProfile != "" || l3.DOSNetworkProfile != "" {
+		rsCfg.Virtual.ProfileDOS = l3.DOSApplicationProfile
+		rsCfg.Virtual.ProfileDOSNetwork = l3.DOSNetworkProfile
+	}
 	rsCfg.Virtual.ProfileBotDefense = plc.Spec.L3Policies.BotDefense
 	rsCfg.Virtual.TCP.Client = plc.Spec.Profiles.TCP.Client
 	rsCfg.Virtual.TCP.Server = plc.Spec.Profiles.TCP.Server
 	rsCfg.Virtual.AllowSourceRange = plc.Spec.L3Policies.AllowSourceRange
 	rsCfg.Virtual.AllowVLANs = plc.Spec.L3Policies.AllowVlans
+	// inline hash persistence takes precedence over a BIG-IP persistence profile reference
+	if hk := plc.Spec.PersistenceHashKey; hk != nil {
+		rsCfg.Virtual.PersistenceHashKey = &PersistenceHashKey{
+			Type:       hk.Type,
+			HeaderName: hk.HeaderName,
+			Length:     hk.Length,
+		}
+	}
+	persistCookie, err := ctlr.getPersistenceCookie(plc)
+	if err != nil {
+		return err
+	}
+	rsCfg.Virtual.PersistenceCookie = persistCookie
+	if sh := plc.Spec.SecurityHeaders; sh != nil {
+		if sh.HSTS != nil {
+			rsCfg.Virtual.HSTS = &HSTS{
+				MaxAge:            sh.HSTS.MaxAge,
+				IncludeSubDomains: sh.HSTS.IncludeSubDomains,
+			}
+		}
+		if sh.XFrameOptions != "" {
+			iRuleName := getRSCfgResName(rsCfg.Virtual.Name, SecurityHeadersIRuleName)
+			rsCfg.addIRule(iRuleName, rsCfg.Virtual.Partition, securityHeadersIRule(sh.XFrameOptions))
+			rsCfg.Virtual.AddIRule(JoinBigipPath(rsCfg.Virtual.Partition, iRuleName))
+		}
+	}
 
 	if len(plc.Spec.Profiles.LogProfiles) > 0 {
 		rsCfg.Virtual.LogProfiles = append(rsCfg.Virtual.LogProfiles, plc.Spec.Profiles.LogProfiles...)
 	}
+	// BIG-IP analytics profile takes precedence over the analytics profile created by CIS
+	if plc.Spec.Profiles.AnalyticsProfile != "" {
+		rsCfg.Virtual.ProfileAnalytics = plc.Spec.Profiles.AnalyticsProfile
+	} else if plc.Spec.Profiles.AnalyticsEnabled {
+		rsCfg.Virtual.Analytics = &Analytics{
+			LoggingDestination: plc.Spec.Profiles.AnalyticsLoggingDestination,
+		}
+	}
 	var iRule string
 	// Profiles common for both HTTP and HTTPS
 	// service_HTTP supports profileTCP and profileHTTP
@@ -1807,6 +2226,12 @@ func (ctlr *Controller) handleVSResourceConfigForPolicy(
 				BigIPProfile: true,
 			})
 		}
+		// SSL Orchestrator steers only the decrypted traffic through the security services
+		sslo, err := getSSLOTopology(plc)
+		if err != nil {
+			return fmt.Errorf("%v in Policy %v/%v", err, plc.Namespace, plc.Name)
+		}
+		rsCfg.Virtual.SSLO = sslo
 	case "http":
 		iRule = plc.Spec.IRules.InSecure
 	}
@@ -1836,7 +2261,16 @@ func (ctlr *Controller) handleTSResourceConfigForPolicy(
 	rsCfg.Virtual.Firewall = plc.Spec.L3Policies.FirewallPolicy
 	rsCfg.Virtual.PersistenceProfile = plc.Spec.Profiles.PersistenceProfile
 	rsCfg.Virtual.ProfileL4 = plc.Spec.Profiles.ProfileL4
+	if useHardwareAcceleration(plc, false) {
+		rsCfg.Virtual.ProfileL4 = FastL4Profile
+	}
+	if err := validateDOSProfiles(plc); err != nil {
+		return err
+	}
 	rsCfg.Virtual.ProfileDOS = plc.Spec.L3Policies.DOS
+	if plc.Spec.L3Policies.DOSNetworkProfile != "" {
+		rsCfg.Virtual.ProfileDOS = plc.Spec.L3Policies.DOSNetworkProfile
+	}
 	rsCfg.Virtual.ProfileBotDefense = plc.Spec.L3Policies.BotDefense
 	rsCfg.Virtual.TCP.Client = plc.Spec.Profiles.TCP.Client
 	rsCfg.Virtual.TCP.Server = plc.Spec.Profiles.TCP.Server
@@ -1852,6 +2286,16 @@ func (ctlr *Controller) handleTSResourceConfigForPolicy(
 			BigIPProfile: true,
 		})
 	}
+	// HTTP profile is required for TransportServers to multiplex connections to HTTP/2 backends
+	if len(plc.Spec.Profiles.HTTP) > 0 {
+		rsCfg.Virtual.Profiles = append(rsCfg.Virtual.Profiles, ProfileRef{
+			Name:         plc.Spec.Profiles.HTTP,
+			Context:      "http",
+			BigIPProfile: true,
+		})
+	}
+	rsCfg.Virtual.ProfileMultiplex = plc.Spec.Profiles.ProfileMultiplex
+	rsCfg.Virtual.ProfileMultiplexEnable = len(plc.Spec.Profiles.HTTP) > 0
 
 	var iRule string
 	iRule = plc.Spec.IRules.InSecure
@@ -1875,6 +2319,21 @@ func (ctlr *Controller) handleTSResourceConfigForPolicy(
 	return nil
 }
 
+// useHardwareAcceleration checks whether the FastL4 profile is used for hardware acceleration of policy,
+// the profileL4 of TransportServer is overridden only with the force offload mode
+func useHardwareAcceleration(plc *cisapiv1.Policy, overrideTS bool) bool {
+	if plc == nil || !plc.Spec.HardwareAcceleration {
+		return false
+	}
+	switch plc.Spec.HardwareAccelerationOffloadMode {
+	case "", OffloadModeAuto:
+		return !overrideTS
+	case OffloadModeForce:
+		return true
+	}
+	return false
+}
+
 func getRSCfgResName(rsVSName, resName string) string {
 	return fmt.Sprintf("%s_%s", rsVSName, resName)
 }
@@ -1888,10 +2347,13 @@ func (rs *ResourceStore) getExtendedRouteSpec(routeGroup string) (*ExtendedRoute
 
 	if extdSpec.override && extdSpec.local != nil {
 		ergc := &ExtendedRouteGroupSpec{
-			VServerName:   extdSpec.global.VServerName,
-			VServerAddr:   extdSpec.global.VServerAddr,
-			AllowOverride: extdSpec.global.AllowOverride,
+			VServerName:           extdSpec.global.VServerName,
+			VServerAddr:           extdSpec.global.VServerAddr,
+			AllowOverride:         extdSpec.global.AllowOverride,
+			ClientCACert:          extdSpec.global.ClientCACert,
+			ClientCACertNamespace: extdSpec.global.ClientCACertNamespace,
 		}
+		ergc.PassthroughPersistence = extdSpec.global.PassthroughPersistence || extdSpec.local.PassthroughPersistence
 
 		if extdSpec.local.VServerName != "" {
 			ergc.VServerName = extdSpec.local.VServerName
@@ -1902,6 +2364,10 @@ func (rs *ResourceStore) getExtendedRouteSpec(routeGroup string) (*ExtendedRoute
 		if extdSpec.local.Policy != "" {
 			ergc.Policy = extdSpec.local.Policy
 		}
+		if extdSpec.local.ClientCACert != "" {
+			ergc.ClientCACert = extdSpec.local.ClientCACert
+			ergc.ClientCACertNamespace = extdSpec.local.ClientCACertNamespace
+		}
 
 		return ergc, extdSpec.partition
 	}
@@ -2,13 +2,17 @@ package controller
 
 import (
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
 	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/xeipuuv/gojsonschema"
 	v1 "k8s.io/api/core/v1"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 )
@@ -86,6 +90,25 @@ var _ = Describe("Resource Config Tests", func() {
 		})
 	})
 
+	Describe("Virtual Address", func() {
+		It("Virtual Address with Route Domain", func() {
+			virtual := &Virtual{Partition: "test"}
+			virtual.SetVirtualAddress("1.2.3.4", 80)
+			virtual.SetRouteDomain(0)
+			Expect(virtual.Destination).To(Equal("/test/1.2.3.4:80"))
+
+			virtual.SetRouteDomain(10)
+			Expect(virtual.Destination).To(Equal("/test/1.2.3.4%10:80"), "Route domain not set")
+			Expect(*virtual.VirtualAddress).To(Equal(virtualAddress{BindAddr: "1.2.3.4", Port: 80, RouteDomain: 10}))
+
+			// route domain of bind address takes precedence
+			virtual.SetVirtualAddress("1.2.3.4%2", 80)
+			virtual.SetRouteDomain(10)
+			Expect(virtual.Destination).To(Equal("/test/1.2.3.4%2:80"))
+			Expect(virtual.VirtualAddress.RouteDomain).To(BeZero())
+		})
+	})
+
 	Describe("Name Formatting", func() {
 		It("Replace Unwanted Characters", func() {
 			inputName := "a.b:c/d%e-f=g"
@@ -95,11 +118,29 @@ var _ = Describe("Resource Config Tests", func() {
 		It("VirtualServer Name", func() {
 			name := formatVirtualServerName("1.2.3.4", 80)
 			Expect(name).To(Equal("crd_1_2_3_4_80"), "Invalid VirtualServer Name")
+			name = formatVirtualServerName("2001:db8::1%10", 443)
+			Expect(name).To(Equal("crd_2001_db8__1.10_443"), "Invalid IPv6 VirtualServer Name")
 		})
 		It("VirtualServer Custom Name", func() {
 			name := formatCustomVirtualServerName("My_VS", 80)
 			Expect(name).To(Equal("My_VS_80"), "Invalid VirtualServer Name")
 		})
+		It("Truncated VirtualServer Name", func() {
+			name := strings.Repeat("a", MaxVirtualServerNameLength)
+			Expect(truncateVSName(name, MaxVirtualServerNameLength)).To(Equal(name),
+				"Name within the limit should not be modified")
+			Expect(formatCustomVirtualServerName(strings.Repeat("a", 200), 443)).To(
+				Equal(strings.Repeat("a", 200)+"_443"), "Name within the limit should not be modified")
+
+			long1 := formatCustomVirtualServerName(strings.Repeat("a", 300)+"1", 443)
+			long2 := formatCustomVirtualServerName(strings.Repeat("a", 300)+"2", 443)
+			Expect(long1).To(HaveLen(MaxVirtualServerNameLength))
+			Expect(long2).To(HaveLen(MaxVirtualServerNameLength))
+			Expect(long1).To(HaveSuffix("_443"))
+			Expect(long1).NotTo(Equal(long2), "Truncated names should be unique")
+			Expect(formatCustomVirtualServerName(strings.Repeat("a", 300)+"1", 443)).To(Equal(long1),
+				"Truncated name should be stable")
+		})
 		It("Pool Name", func() {
 			name := formatPoolName(namespace, "svc1", intstr.IntOrString{IntVal: 80}, "app=test", "foo")
 			Expect(name).To(Equal("svc1_80_default_foo_app_test"), "Invalid Pool Name")
@@ -317,6 +358,361 @@ var _ = Describe("Resource Config Tests", func() {
 
 		})
 
+		It("Validate Virtual server config with pool request timeouts", func() {
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = formatCustomVirtualServerName("My_VS", 80)
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+
+			vs := test.NewVirtualServer(
+				"SampleVS",
+				namespace,
+				cisapiv1.VirtualServerSpec{
+					Host: "test.com",
+					Pools: []cisapiv1.Pool{
+						{Name: "upload", Path: "/upload", Service: "svc1", RequestTimeout: 600},
+						{Name: "stream", Path: "/stream", Service: "svc2", RequestTimeout: 3600},
+						{Name: "api", Path: "/", Service: "svc3"},
+					},
+				},
+			)
+			err := mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)
+			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from VirtualServer")
+			Expect(rsCfg.Virtual.RequestTimeout).To(BeEquivalentTo(3600),
+				"Largest request timeout of pools should be used")
+
+			// request timeout is reset when none of the pools have request timeout
+			rsCfg.Pools = nil
+			vs.Spec.Pools[0].RequestTimeout = 0
+			vs.Spec.Pools[1].RequestTimeout = 0
+			err = mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)
+			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from VirtualServer")
+			Expect(rsCfg.Virtual.RequestTimeout).To(BeZero())
+		})
+
+		It("Validate Virtual server config with external pool members", func() {
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = formatCustomVirtualServerName("My_VS", 80)
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+
+			vs := test.NewVirtualServer(
+				"SampleVS",
+				namespace,
+				cisapiv1.VirtualServerSpec{
+					Host: "test.com",
+					Pools: []cisapiv1.Pool{
+						{
+							Path:            "/",
+							Service:         "svc1",
+							ServicePort:     intstr.FromInt(80),
+							ExternalMembers: []cisapiv1.ExternalMember{{FQDN: "api.example.com", Port: 443}},
+						},
+					},
+				},
+			)
+			err := mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)
+			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from VirtualServer")
+			Expect(rsCfg.Pools[0].ExternalMembers).To(Equal([]ExternalMember{{FQDN: "api.example.com", Port: 443}}))
+
+			// external members are posted along with the members of service
+			rsCfg.Pools[0].Members = []PoolMember{{Address: "10.244.0.1", Port: 8080}}
+			sharedApp := as3Application{}
+			createPoolDecl(rsCfg, sharedApp, false, "test")
+			pool := sharedApp[rsCfg.Pools[0].Name].(*as3Pool)
+			Expect(pool.Members).To(Equal([]as3PoolMember{
+				{AddressDiscovery: "static", ServerAddresses: []string{"10.244.0.1"}, ServicePort: 8080},
+				{AddressDiscovery: "fqdn", Hostname: "api.example.com", AutoPopulate: true, ServicePort: 443},
+			}))
+
+			copyCfg := &ResourceConfig{}
+			copyCfg.copyConfig(rsCfg)
+			copyCfg.Pools[0].ExternalMembers[0].Port = 8443
+			Expect(rsCfg.Pools[0].ExternalMembers[0].Port).To(BeEquivalentTo(443), "External members should be copied")
+		})
+
+		It("Validate Virtual server config with Content-Security-Policy", func() {
+			vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+				Host:                 "test.com",
+				VirtualServerAddress: "10.1.0.10",
+			})
+			vs.Annotations = map[string]string{ContentSecurityPolicyAnnotation: "default-src 'self'"}
+			rsCfg.Virtual.Name = "crd_10_1_0_10_80"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.IRulesMap = make(IRulesMap)
+
+			handleContentSecurityPolicy(rsCfg, vs)
+			iRuleName := getRSCfgResName(rsCfg.Virtual.Name, CSPIRuleName)
+			Expect(rsCfg.Virtual.IRules).To(Equal([]string{"/test/" + iRuleName}))
+			iRule := rsCfg.IRulesMap[NameRef{Name: iRuleName, Partition: "test"}]
+			Expect(iRule).NotTo(BeNil())
+			Expect(iRule.Code).To(ContainSubstring(`HTTP::header replace Content-Security-Policy "default-src 'self'"`))
+
+			// policy of the first virtual server is used for the virtual
+			vs2 := vs.DeepCopy()
+			vs2.Annotations[ContentSecurityPolicyAnnotation] = "default-src *"
+			handleContentSecurityPolicy(rsCfg, vs2)
+			Expect(rsCfg.Virtual.IRules).To(HaveLen(1))
+			Expect(rsCfg.IRulesMap[NameRef{Name: iRuleName, Partition: "test"}].Code).To(ContainSubstring("'self'"))
+		})
+
+		It("Validate Virtual server config with request body rewrite", func() {
+			vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+				Host:                   "test.com",
+				VirtualServerAddress:   "10.1.0.10",
+				RequestBodyRewrite:     &cisapiv1.BodyRewriteSpec{Match: `"tenant":\s*"\w*"`, Replace: `"tenant":"t1"`},
+				ProfileWebAcceleration: "/Common/webacceleration",
+			})
+			rsCfg.Virtual.Name = "crd_10_1_0_10_80"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.IRulesMap = make(IRulesMap)
+
+			handleRequestBodyRewrite(rsCfg, vs)
+			iRuleName := getRSCfgResName(rsCfg.Virtual.Name, BodyRewriteIRuleName)
+			Expect(rsCfg.Virtual.IRules).To(Equal([]string{"/test/" + iRuleName}))
+			iRule := rsCfg.IRulesMap[NameRef{Name: iRuleName, Partition: "test"}]
+			Expect(iRule).NotTo(BeNil())
+			Expect(iRule.Code).To(ContainSubstring("HTTP::collect $content_length"))
+			Expect(iRule.Code).To(ContainSubstring(`regsub -all -- {"tenant":\s*"\w*"} [HTTP::payload] {"tenant":"t1"}`))
+
+			// rewrite of the first virtual server is used for the virtual
+			vs2 := vs.DeepCopy()
+			vs2.Spec.RequestBodyRewrite.Replace = `"tenant":"t2"`
+			handleRequestBodyRewrite(rsCfg, vs2)
+			Expect(rsCfg.Virtual.IRules).To(HaveLen(1))
+			Expect(rsCfg.IRulesMap[NameRef{Name: iRuleName, Partition: "test"}].Code).To(ContainSubstring(`"t1"`))
+
+			sharedApp := as3Application{}
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.ProfileWebAcceleration = vs.Spec.ProfileWebAcceleration
+			rsCfg.Virtual.Destination = "/test/10.1.0.10:80"
+			processResourcesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp, false, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileHTTPAccel).To(Equal(&as3ResourcePointer{BigIP: "/Common/webacceleration"}))
+			Expect(svc.IRules).To(ContainElement(iRuleName))
+		})
+
+		It("Validate Virtual server config with cookie routes", func() {
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = formatCustomVirtualServerName("My_VS", 80)
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+
+			vs := test.NewVirtualServer(
+				"SampleVS",
+				namespace,
+				cisapiv1.VirtualServerSpec{
+					Host: "test.com",
+					Pools: []cisapiv1.Pool{
+						{Name: "stable", Path: "/app/api/v1", Service: "svc1"},
+						{Name: "beta", Path: "/", Service: "svc2"},
+					},
+					CookieRoutes: []cisapiv1.CookieRoute{
+						{CookieName: "version", CookieValue: "beta", Pool: "beta"},
+					},
+				},
+			)
+			err := mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)
+			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from VirtualServer")
+			Expect(rsCfg.Policies).To(HaveLen(1))
+			rules := rsCfg.Policies[0].Rules
+			Expect(rules).To(HaveLen(3))
+			// cookie rule is ordered before path based rules
+			Expect(rules[0].Actions[0].Pool).To(Equal(mockCtlr.framePoolName(namespace, vs.Spec.Pools[1], vs.Spec.Host)))
+			Expect(rules[0].Conditions).To(HaveLen(2))
+			Expect(*rules[0].Conditions[1]).To(Equal(condition{
+				Name:       "1",
+				Equals:     true,
+				HTTPCookie: true,
+				CookieName: "version",
+				Index:      1,
+				Request:    true,
+				Values:     []string{"beta"},
+			}))
+
+			rsCfg.Policies = nil
+			vs.Spec.CookieRoutes[0].Pool = "unknown"
+			err = mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)
+			Expect(err).NotTo(BeNil(), "Cookie route with unknown pool should fail")
+		})
+
+		It("Validate Virtual server config with host header rewrite", func() {
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = formatCustomVirtualServerName("My_VS", 80)
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+
+			vs := test.NewVirtualServer(
+				"SampleVS",
+				namespace,
+				cisapiv1.VirtualServerSpec{
+					Host: "test.com",
+					Pools: []cisapiv1.Pool{
+						{Name: "backend", Path: "/api", Service: "svc1", Rewrite: "/v1",
+							HostHeaderRewrite: "backend.svc.cluster.local"},
+						{Name: "web", Path: "/web", Service: "svc2"},
+					},
+					CookieRoutes: []cisapiv1.CookieRoute{
+						{CookieName: "version", CookieValue: "beta", Pool: "backend"},
+					},
+				},
+			)
+			err := mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)
+			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from VirtualServer")
+			Expect(rsCfg.Policies).To(HaveLen(1))
+			backendPool := mockCtlr.framePoolName(namespace, vs.Spec.Pools[0], vs.Spec.Host)
+			hostAction := action{
+				HTTPHost: true,
+				Replace:  true,
+				Request:  true,
+				Value:    "backend.svc.cluster.local",
+			}
+			var rewrittenRules int
+			for _, rl := range rsCfg.Policies[0].Rules {
+				var hostActions []action
+				for _, act := range rl.Actions {
+					if act.HTTPHost {
+						hostActions = append(hostActions, *act)
+					}
+				}
+				if rl.Actions[0].Pool != backendPool {
+					Expect(hostActions).To(BeEmpty(), "Host header of other pools should not be rewritten")
+					continue
+				}
+				rewrittenRules++
+				Expect(hostActions).To(HaveLen(1))
+				hostAction.Name = hostActions[0].Name
+				Expect(hostActions[0]).To(Equal(hostAction))
+				Expect(hostActions[0].Name).To(Equal(strconv.Itoa(len(rl.Actions) - 1)))
+			}
+			Expect(rewrittenRules).To(Equal(2), "Host header should be rewritten in path and cookie rules of pool")
+
+			rulesData := &as3Rule{}
+			createRuleAction(&Rule{Actions: []*action{&hostAction}}, rulesData)
+			Expect(*rulesData.Actions[0]).To(Equal(as3Action{
+				Type:    "httpHeader",
+				Event:   "request",
+				Replace: &as3ActionReplaceMap{Name: "host", Value: "backend.svc.cluster.local"},
+			}))
+		})
+
+		It("Validate Virtual server config with persistence of pools", func() {
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = formatCustomVirtualServerName("My_VS", 80)
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+			plc := test.NewPolicy("plc1", namespace, cisapiv1.PolicySpec{
+				Profiles: cisapiv1.ProfileSpec{PersistenceProfile: "source-address"}})
+			Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).To(BeNil())
+
+			vs := test.NewVirtualServer(
+				"SampleVS",
+				namespace,
+				cisapiv1.VirtualServerSpec{
+					Host: "test.com",
+					Pools: []cisapiv1.Pool{
+						{Path: "/api", Service: "svc1", PersistenceProfile: "cookie"},
+						{Path: "/static", Service: "svc2"},
+					},
+				},
+			)
+			err := mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)
+			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from VirtualServer")
+			Expect(rsCfg.Virtual.PersistenceProfile).To(Equal("source-address"),
+				"Persistence of Policy should remain for the virtual")
+			apiPool := mockCtlr.framePoolName(namespace, vs.Spec.Pools[0], vs.Spec.Host)
+			var persistRules int
+			for _, rl := range rsCfg.Policies[0].Rules {
+				rulesData := &as3Rule{}
+				createRuleAction(rl, rulesData)
+				var persistActions []*as3Action
+				for _, act := range rulesData.Actions {
+					if act.Type == "persist" {
+						persistActions = append(persistActions, act)
+					}
+				}
+				if rl.Actions[0].Pool != apiPool {
+					Expect(persistActions).To(BeEmpty(), "Pool without persistence should use the persistence of virtual")
+					continue
+				}
+				persistRules++
+				Expect(persistActions).To(HaveLen(1), "Persistence of pool should take precedence")
+				Expect(*persistActions[0]).To(Equal(as3Action{
+					Type:         "persist",
+					Event:        "request",
+					CookieInsert: &as3PersistCookie{Name: "BIGipServer" + apiPool, Expiry: PersistCookieExpiry},
+				}))
+			}
+			Expect(persistRules).To(Equal(1))
+
+			// persist actions conform to Policy_Action of AS3 schema
+			schemaPath, _ := filepath.Abs("../../schemas/as3-schema-3.41.0-1-cis.json")
+			schemaLoader := gojsonschema.NewReferenceLoader("file://" + schemaPath + "#/definitions/Policy_Action")
+			for _, persist := range []string{"cookie", "source-address", "destination-address", "none"} {
+				act := &as3Action{Type: "persist", Event: "request"}
+				setPersistAction(act, persist, "BIGipServer"+apiPool)
+				result, err := gojsonschema.Validate(schemaLoader, gojsonschema.NewGoLoader(act))
+				Expect(err).To(BeNil())
+				Expect(result.Errors()).To(BeEmpty(), "Invalid persist action for %v", persist)
+			}
+			result, _ := gojsonschema.Validate(schemaLoader, gojsonschema.NewStringLoader(
+				`{"type":"persist","event":"request","select":{"sourceAddress":{}}}`))
+			Expect(result.Valid()).To(BeFalse(), "Persistence method should not be nested in select")
+			Expect(isValidPoolPersistenceProfile("source-address")).To(BeTrue())
+			Expect(isValidPoolPersistenceProfile("tls-session-id")).To(BeFalse())
+			Expect(isValidPoolPersistenceProfile("/Common/cookie")).To(BeFalse())
+		})
+
+		It("Validate Virtual server config with gRPC monitor", func() {
+			mockCtlr.grpcMonitorScriptPath = "/Common/grpc_health_check"
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = formatCustomVirtualServerName("My_VS", 80)
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+
+			vs := test.NewVirtualServer(
+				"SampleVS",
+				namespace,
+				cisapiv1.VirtualServerSpec{
+					Host: "test.com",
+					Pools: []cisapiv1.Pool{
+						{
+							Path:    "/foo",
+							Service: "svc1",
+							Monitor: cisapiv1.Monitor{
+								Type:        "grpc",
+								GRPCService: "helloworld.Greeter",
+								Interval:    15,
+								Timeout:     10,
+							},
+						},
+					},
+				},
+			)
+			err := mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)
+			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from VirtualServer")
+			Expect(len(rsCfg.Monitors)).To(Equal(1), "Failed to create gRPC monitor")
+			Expect(rsCfg.Monitors[0].Type).To(Equal(ExternalMonitorType), "gRPC monitor should be external monitor")
+			Expect(rsCfg.Monitors[0].ScriptPath).To(Equal("/Common/grpc_health_check"), "Invalid script path")
+			Expect(rsCfg.Monitors[0].GRPCService).To(Equal("helloworld.Greeter"), "Invalid gRPC service")
+			Expect(len(rsCfg.Pools[0].MonitorNames)).To(Equal(1), "Pool should refer the gRPC monitor")
+
+			sharedApp := as3Application{}
+			createMonitorDecl(rsCfg, sharedApp)
+			monitor := sharedApp[rsCfg.Monitors[0].Name].(*as3Monitor)
+			Expect(monitor.MonitorType).To(Equal("external"), "Invalid monitor type")
+			Expect(monitor.Pathname).To(Equal("/Common/grpc_health_check"), "Invalid monitor script")
+			Expect(monitor.EnvironmentVariables["GRPC_SERVICE"]).To(Equal("helloworld.Greeter"), "Invalid gRPC service")
+		})
+
 		It("Prepare Resource Config from a TransportServer", func() {
 			ts := test.NewTransportServer(
 				"SampleTS",
@@ -324,17 +720,19 @@ var _ = Describe("Resource Config Tests", func() {
 				cisapiv1.TransportServerSpec{
 					Pool: cisapiv1.Pool{
 						Service:     "svc1",
-						ServicePort: 80,
+						ServicePort: intstr.FromInt(80),
 						Monitor: cisapiv1.Monitor{
 							Type:     "tcp",
 							Timeout:  10,
 							Interval: 10,
 						},
 					},
+					PersistenceProfile: "/Common/source_addr",
 				},
 			)
 			err := mockCtlr.prepareRSConfigFromTransportServer(rsCfg, ts)
 			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from TransportServer")
+			Expect(rsCfg.Virtual.PersistenceProfile).To(Equal("/Common/source_addr"), "Invalid persistence profile")
 		})
 
 		It("Prepare Resource Config from a TransportServer", func() {
@@ -344,7 +742,7 @@ var _ = Describe("Resource Config Tests", func() {
 				cisapiv1.TransportServerSpec{
 					Pool: cisapiv1.Pool{
 						Service:     "svc1",
-						ServicePort: 80,
+						ServicePort: intstr.FromInt(80),
 						Monitors: []cisapiv1.Monitor{
 							{
 								Type:       "tcp",
@@ -366,6 +764,85 @@ var _ = Describe("Resource Config Tests", func() {
 			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from TransportServer")
 		})
 
+		It("Prepare Resource Config from a TransportServer with HTTP and TCP monitors", func() {
+			ts := test.NewTransportServer(
+				"SampleTS",
+				namespace,
+				cisapiv1.TransportServerSpec{
+					Pool: cisapiv1.Pool{
+						Service:     "svc1",
+						ServicePort: intstr.FromInt(80),
+						Monitors: []cisapiv1.Monitor{
+							{
+								Type:     "http",
+								Send:     "GET /healthz HTTP/1.1\r\nHost: foo.com\r\n\r\n",
+								Recv:     "200 OK",
+								Timeout:  10,
+								Interval: 5,
+							},
+							{
+								Type:       "tcp",
+								Send:       "PING",
+								Recv:       "PONG",
+								Timeout:    10,
+								Interval:   5,
+								TargetPort: 6379,
+							},
+							{
+								Name:      "/Common/gateway_icmp",
+								Reference: BIGIP,
+							},
+						},
+					},
+				},
+			)
+			err := mockCtlr.prepareRSConfigFromTransportServer(rsCfg, ts)
+			Expect(err).To(BeNil(), "Failed to Prepare Resource Config from TransportServer")
+			Expect(rsCfg.Pools[0].MonitorNames).To(HaveLen(3), "Pool should refer all the monitors")
+			Expect(rsCfg.Pools[0].MonitorNames[2]).To(Equal(MonitorName{Name: "/Common/gateway_icmp", Reference: BIGIP}))
+			Expect(rsCfg.Monitors).To(HaveLen(2), "BIG-IP monitor should not be created")
+
+			sharedApp := as3Application{}
+			createMonitorDecl(rsCfg, sharedApp)
+			httpMonitor := sharedApp[rsCfg.Monitors[0].Name].(*as3Monitor)
+			Expect(httpMonitor.MonitorType).To(Equal("http"))
+			Expect(httpMonitor.Send).To(Equal("GET /healthz HTTP/1.1\r\nHost: foo.com\r\n\r\n"), "Invalid send string")
+			Expect(httpMonitor.Receive).To(Equal("200 OK"), "Invalid receive string")
+			tcpMonitor := sharedApp[rsCfg.Monitors[1].Name].(*as3Monitor)
+			Expect(tcpMonitor.MonitorType).To(Equal("tcp"))
+			Expect(tcpMonitor.Send).To(Equal("PING"), "Invalid send string")
+			Expect(tcpMonitor.Receive).To(Equal("PONG"), "Invalid receive string")
+			Expect(tcpMonitor.TargetPort).To(Equal(int32(6379)), "Invalid target port")
+		})
+
+		It("Prepare Resource Config from a VirtualServer with pool service of other namespace", func() {
+			_ = mockCtlr.addNamespacedInformers("ns1", false)
+			svc := test.NewService("svc1", "1", "ns1", v1.ServiceTypeClusterIP, []v1.ServicePort{{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromInt(8080),
+			}})
+			_ = mockCtlr.comInformers["ns1"].svcInformer.GetIndexer().Add(svc)
+			rsCfg.Virtual.Name = formatCustomVirtualServerName("My_VS", 80)
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+
+			vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+				Host: "test.com",
+				Pools: []cisapiv1.Pool{{
+					Path:             "/",
+					Service:          "svc1",
+					ServiceNamespace: "ns1",
+					ServicePort:      intstr.FromInt(80),
+				}},
+			})
+			Expect(mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)).To(Succeed())
+			Expect(rsCfg.Pools).To(HaveLen(1))
+			Expect(rsCfg.Pools[0].ServiceNamespace).To(Equal("ns1"))
+			Expect(rsCfg.Pools[0].ServicePort).To(Equal(intstr.FromInt(8080)),
+				"Target port should be fetched from the service namespace")
+		})
+
 		It("Prepare Resource Config from a Service", func() {
 			svcPort := v1.ServicePort{
 				Name:     "port1",
@@ -626,6 +1103,20 @@ var _ = Describe("Resource Config Tests", func() {
 		Expect(ok).To(BeFalse(), "TLS Edge Validation Failed")
 	})
 
+	It("Validate TLS Profile with OCSP stapling", func() {
+		tlsEdge := test.NewTLSProfile("sampleTLS", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{
+				Termination:  TLSEdge,
+				ClientSSL:    "clientssl",
+				OCSPStapling: cisapiv1.OCSPStapling{Enabled: true},
+			},
+		})
+		Expect(validateTLSProfile(tlsEdge)).To(BeFalse(), "OCSP stapling without responder should be invalid")
+
+		tlsEdge.Spec.TLS.OCSPStapling.OCSPResponderURL = "http://ocsp.example.com"
+		Expect(validateTLSProfile(tlsEdge)).To(BeTrue())
+	})
+
 	It("Validate Multiple TLS Profiles", func() {
 		tlsRenc := test.NewTLSProfile(
 			"sampleTLS",
@@ -762,6 +1253,15 @@ var _ = Describe("Resource Config Tests", func() {
 			Expect(len(ltmCfg)).To(Equal(1), "Wrong number of Partitions")
 			Expect(len(ltmCfg["default"].ResourceMap)).To(Equal(2), "Wrong number of ResourceConfigs")
 		})
+
+		It("Mark Config Updated", func() {
+			rs.updateCaches()
+			Expect(rs.isConfigUpdated()).To(BeFalse())
+			rs.markConfigUpdated()
+			Expect(rs.isConfigUpdated()).To(BeTrue(), "Config should be updated on resync")
+			rs.updateCaches()
+			Expect(rs.isConfigUpdated()).To(BeFalse(), "Resync should be reset after updating caches")
+		})
 	})
 
 	Describe("Handle Virtual Server TLS", func() {
@@ -896,6 +1396,76 @@ var _ = Describe("Resource Config Tests", func() {
 			Expect(rsCfg.Virtual.Profiles[1]).To(Equal(svProfRef), "Failed to Process TLS Termination: Reencrypt")
 		})
 
+		It("Access policy per TLS termination", func() {
+			vs.Spec.TLSProfileName = "SampleTLS"
+			rsCfg.MetaData.Protocol = "https"
+			tlsProf.Spec.TLS.Termination = TLSEdge
+			tlsProf.Spec.TLS.Reference = BIGIP
+			tlsProf.Spec.TLS.ClientSSL = "/Common/clientssl"
+			tlsProf.Spec.TLS.AccessPolicy = map[string]string{
+				TLSEdge:      "/Common/edge-access",
+				TLSReencrypt: "/Common/reencrypt-access",
+			}
+			ok := mockCtlr.handleVirtualServerTLS(rsCfg, vs, tlsProf, ip)
+			Expect(ok).To(BeTrue(), "Failed to Process TLS Termination: Edge")
+			Expect(rsCfg.Virtual.PolicyEndpointAccess).To(Equal("/Common/edge-access"))
+
+			rsCfg.Virtual.PolicyEndpointAccess = ""
+			tlsProf.Spec.TLS.Termination = TLSReencrypt
+			tlsProf.Spec.TLS.ServerSSL = "/Common/serverssl"
+			ok = mockCtlr.handleVirtualServerTLS(rsCfg, vs, tlsProf, ip)
+			Expect(ok).To(BeTrue(), "Failed to Process TLS Termination: Reencrypt")
+			Expect(rsCfg.Virtual.PolicyEndpointAccess).To(Equal("/Common/reencrypt-access"))
+
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileAccess).To(Equal(&as3ResourcePointer{BigIP: "/Common/reencrypt-access"}))
+
+			// access policy is not attached for the termination without access policy
+			rsCfg.Virtual.PolicyEndpointAccess = ""
+			delete(tlsProf.Spec.TLS.AccessPolicy, TLSReencrypt)
+			ok = mockCtlr.handleVirtualServerTLS(rsCfg, vs, tlsProf, ip)
+			Expect(ok).To(BeTrue(), "Failed to Process TLS Termination: Reencrypt")
+			Expect(rsCfg.Virtual.PolicyEndpointAccess).To(BeEmpty())
+		})
+
+		It("OCSP stapling of client SSL profiles", func() {
+			vs.Spec.TLSProfileName = "SampleTLS"
+			rsCfg.MetaData.Protocol = "https"
+			rsCfg.customProfiles = make(map[SecretKey]CustomProfile)
+			tlsProf.Spec.TLS.Termination = TLSEdge
+			tlsProf.Spec.TLS.Reference = Secret
+			tlsProf.Spec.TLS.ClientSSL = "clientsecret"
+			tlsProf.Spec.TLS.OCSPStapling = cisapiv1.OCSPStapling{
+				Enabled:          true,
+				OCSPResponderURL: "http://ocsp.example.com",
+				StaplingTimeout:  10,
+			}
+			mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+			mockCtlr.namespaces = map[string]bool{namespace: true}
+			mockCtlr.crInformers = make(map[string]*CRInformer)
+			mockCtlr.comInformers = make(map[string]*CommonInformer)
+			mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+			_ = mockCtlr.addNamespacedInformers(namespace, false)
+			mockCtlr.addSecret(test.NewSecret("clientsecret", namespace, "### cert ###", "#### key ####"))
+
+			ok := mockCtlr.handleVirtualServerTLS(rsCfg, vs, tlsProf, ip)
+			Expect(ok).To(BeTrue(), "Failed to Process TLS Termination: Edge")
+			prof := rsCfg.customProfiles[SecretKey{Name: "clientsecret", ResourceName: rsCfg.Virtual.Name}]
+			Expect(prof.OCSPResponderURL).To(Equal("http://ocsp.example.com"))
+			Expect(prof.OCSPTimeout).To(Equal(10))
+
+			// OCSP stapling is not configured when disabled
+			rsCfg.customProfiles = make(map[SecretKey]CustomProfile)
+			tlsProf.Spec.TLS.OCSPStapling.Enabled = false
+			ok = mockCtlr.handleVirtualServerTLS(rsCfg, vs, tlsProf, ip)
+			Expect(ok).To(BeTrue(), "Failed to Process TLS Termination: Edge")
+			for _, prof := range rsCfg.customProfiles {
+				Expect(prof.OCSPResponderURL).To(BeEmpty())
+			}
+		})
+
 		It("Validate TLS Reencrypt with AllowInsecure", func() {
 			vs.Spec.TLSProfileName = "SampleTLS"
 			vs.Spec.HTTPTraffic = TLSAllowInsecure
@@ -1098,5 +1668,153 @@ var _ = Describe("Resource Config Tests", func() {
 			Expect(rsCfg.Virtual.SNAT).To(Equal(DEFAULT_SNAT), "Default SNAT should be set "+
 				"to automap")
 		})
+
+		It("Verifies SSLO service chain for VirtualServer", func() {
+			rsCfg.Virtual.Name = "crd_vs_1.2.3.4_443"
+			rsCfg.MetaData.Protocol = "https"
+			plc.Spec.SSLOServiceChain = "inbound"
+			plc.Spec.SSLOSecurityServices = []string{"ips", "icap"}
+			err := mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle VirtualServer for policy")
+			Expect(rsCfg.Virtual.SSLO).To(Equal(&SSLOTopology{
+				ServiceChain:     "inbound",
+				TopologyType:     SSLOTopologyL3Inbound,
+				SecurityServices: []string{"ips", "icap"},
+			}))
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileAccess).To(Equal(
+				&as3ResourcePointer{BigIP: "/Common/sslo_inbound.app/sslo_inbound_accessProfile"}))
+			Expect(svc.PolicyPerRequestAccess).To(Equal(
+				&as3ResourcePointer{BigIP: "/Common/sslo_inbound.app/sslo_inbound_per_req_policy"}))
+			Expect(sharedApp["crd_vs_1.2.3.4_443_sslo_dg"]).To(Equal(&as3DataGroup{
+				Class:       "Data_Group",
+				KeyDataType: "string",
+				Records: []as3Record{
+					{Key: "securityServices", Value: "ips,icap"},
+					{Key: "serviceChain", Value: "inbound"},
+					{Key: "topologyType", Value: SSLOTopologyL3Inbound},
+				},
+			}), "Topology type and security services should be posted")
+
+			plc.Spec.SSLOSecurityServices = []string{"ips", "ips"}
+			Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).NotTo(Succeed(),
+				"Duplicate security services should be rejected")
+
+			// HTTP virtual is not attached to the SSLO topology
+			rsCfg = &ResourceConfig{}
+			rsCfg.MetaData.Protocol = "http"
+			plc.Spec.SSLOSecurityServices = nil
+			Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).To(Succeed())
+			Expect(rsCfg.Virtual.SSLO).To(BeNil())
+		})
+
+		It("Verifies multiplex profile for VirtualServer and TransportServer", func() {
+			rsCfg.Virtual.Name = "crd_vs_1.2.3.4_80"
+			plc.Spec.Profiles.ProfileMultiplex = "/Common/oneconnect"
+			err := mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle VirtualServer for policy")
+			Expect(rsCfg.Virtual.ProfileMultiplex).To(Equal("/Common/oneconnect"), "Invalid multiplex profile")
+			Expect(rsCfg.Virtual.ProfileMultiplexEnable).To(BeTrue())
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileMultiplex).To(Equal(&as3ResourcePointer{BigIP: "/Common/oneconnect"}))
+
+			// multiplex profile is not attached to TransportServer without HTTP profile
+			rsCfg = &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_ts_1.2.3.4_80"
+			err = mockCtlr.handleTSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle TransportServer for policy")
+			Expect(rsCfg.Virtual.ProfileMultiplexEnable).To(BeFalse())
+			sharedApp = as3Application{}
+			createTransportServiceDecl(rsCfg, sharedApp)
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileMultiplex).To(BeNil())
+
+			plc.Spec.Profiles.HTTP = "/Common/http"
+			rsCfg = &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_ts_1.2.3.4_80"
+			err = mockCtlr.handleTSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle TransportServer for policy")
+			Expect(rsCfg.Virtual.ProfileMultiplexEnable).To(BeTrue())
+			sharedApp = as3Application{}
+			createTransportServiceDecl(rsCfg, sharedApp)
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileHTTP).To(Equal(&as3ResourcePointer{BigIP: "/Common/http"}))
+			Expect(svc.ProfileMultiplex).To(Equal(&as3ResourcePointer{BigIP: "/Common/oneconnect"}))
+		})
+
+		It("Verifies DoS profiles for VirtualServer and TransportServer", func() {
+			rsCfg.Virtual.Name = "crd_vs_1.2.3.4_80"
+			plc.Spec.L3Policies.DOS = "/Common/dos"
+			Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).To(Succeed())
+			Expect(rsCfg.Virtual.ProfileDOS).To(Equal("/Common/dos"))
+
+			plc.Spec.L3Policies.DOSNetworkProfile = "/Common/dos-network"
+			Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).To(Succeed())
+			sharedApp := as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc := sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileDOS).To(Equal(&as3ResourcePointer{BigIP: "/Common/dos-network"}),
+				"Network DoS profile should take precedence over dos")
+
+			plc.Spec.L3Policies.DOSApplicationProfile = "/Common/dos-application"
+			Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).To(Succeed())
+			sharedApp = as3Application{}
+			createServiceDecl(rsCfg, sharedApp, "test")
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileDOS).To(Equal([]as3ResourcePointer{
+				{BigIP: "/Common/dos-application"}, {BigIP: "/Common/dos-network"}}),
+				"Both application and network DoS profiles should be attached to VirtualServer")
+
+			rsCfg = &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_ts_1.2.3.4_80"
+			Expect(mockCtlr.handleTSResourceConfigForPolicy(rsCfg, plc)).To(Succeed())
+			Expect(rsCfg.Virtual.ProfileDOS).To(Equal("/Common/dos-network"),
+				"Network DoS profile should be used for TransportServer")
+			sharedApp = as3Application{}
+			createTransportServiceDecl(rsCfg, sharedApp)
+			svc = sharedApp[rsCfg.Virtual.Name].(*as3Service)
+			Expect(svc.ProfileDOS).To(Equal(&as3ResourcePointer{BigIP: "/Common/dos-network"}))
+
+			for _, path := range []string{"Common/dos", "/dos", "/Common//dos", "/Common/"} {
+				plc.Spec.L3Policies.DOSNetworkProfile = path
+				Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).NotTo(Succeed(), path)
+				Expect(mockCtlr.handleTSResourceConfigForPolicy(rsCfg, plc)).NotTo(Succeed(), path)
+			}
+			plc.Spec.L3Policies.DOSNetworkProfile = ""
+			plc.Spec.L3Policies.DOSApplicationProfile = "/dos"
+			Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).NotTo(Succeed())
+			plc.Spec.L3Policies.DOSApplicationProfile = "/Common/app.app/dos"
+			Expect(mockCtlr.handleVSResourceConfigForPolicy(rsCfg, plc)).To(Succeed())
+		})
+
+		It("Verifies hardware acceleration of TransportServer", func() {
+			plc.Spec.Profiles.ProfileL4 = "/Common/security-fastL4"
+			plc.Spec.HardwareAcceleration = true
+			err := mockCtlr.handleTSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle TransportServer for policy")
+			Expect(rsCfg.Virtual.ProfileL4).To(Equal(FastL4Profile), "FastL4 profile should override profileL4")
+
+			plc.Spec.HardwareAcceleration = false
+			err = mockCtlr.handleTSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle TransportServer for policy")
+			Expect(rsCfg.Virtual.ProfileL4).To(Equal("/Common/security-fastL4"), "profileL4 should be preserved")
+
+			plc.Spec.HardwareAcceleration = true
+			plc.Spec.HardwareAccelerationOffloadMode = OffloadModeNone
+			err = mockCtlr.handleTSResourceConfigForPolicy(rsCfg, plc)
+			Expect(err).To(BeNil(), "Failed to handle TransportServer for policy")
+			Expect(rsCfg.Virtual.ProfileL4).To(Equal("/Common/security-fastL4"), "profileL4 should be preserved")
+
+			// profileL4 of TransportServer is overridden only with force offload mode
+			Expect(useHardwareAcceleration(plc, true)).To(BeFalse())
+			plc.Spec.HardwareAccelerationOffloadMode = OffloadModeAuto
+			Expect(useHardwareAcceleration(plc, true)).To(BeFalse())
+			plc.Spec.HardwareAccelerationOffloadMode = OffloadModeForce
+			Expect(useHardwareAcceleration(plc, true)).To(BeTrue())
+		})
 	})
 })
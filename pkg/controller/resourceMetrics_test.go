@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"container/list"
+	"sync"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/metrics"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Resource Metrics Tests", func() {
+	var mockCtlr *mockController
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.Partition = "test"
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.Agent = newMockAgent(nil)
+		mockCtlr.requestQueue = &requestQueue{sync.Mutex{}, list.New()}
+		mockCtlr.TeemData = &teem.TeemsData{ResourceType: teem.ResourceTypes{
+			VirtualServer: make(map[string]int),
+		}}
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+	})
+
+	AfterEach(func() {
+		mockCtlr.resourceQueue.ShutDown()
+	})
+
+	// processedCount returns the number of resources of kind observed by the processing duration histogram
+	processedCount := func(kind string) uint64 {
+		metric := &dto.Metric{}
+		observer := metrics.ResourceProcessingDuration.WithLabelValues(kind)
+		Expect(observer.(prometheus.Metric).Write(metric)).To(BeNil())
+		return metric.GetHistogram().GetSampleCount()
+	}
+
+	newVirtualServer := func(name, address string) *cisapiv1.VirtualServer {
+		return test.NewVirtualServer(name, namespace, cisapiv1.VirtualServerSpec{
+			Host:                 name + ".com",
+			VirtualServerAddress: address,
+			Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc", ServicePort: intstr.FromInt(80)}},
+		})
+	}
+
+	It("Records the queue depth and processing duration of resources", func() {
+		vsCount := processedCount(VirtualServer)
+		mockCtlr.addVirtualServer(newVirtualServer("vs1", "10.1.1.1"))
+		mockCtlr.addVirtualServer(newVirtualServer("vs2", "10.1.1.2"))
+
+		// keys queued so far are processed together
+		Expect(mockCtlr.processResources()).To(BeTrue())
+		Expect(testutil.ToFloat64(metrics.ResourceQueueDepth)).To(Equal(float64(1)))
+		Expect(mockCtlr.resourceQueue.Len()).To(BeZero())
+		Expect(processedCount(VirtualServer)).To(Equal(vsCount + 2))
+	})
+
+	It("Counts the resources failed to process", func() {
+		errors := testutil.ToFloat64(metrics.ResourceProcessingErrors)
+		vs1 := newVirtualServer("vs1", "10.1.1.1")
+		vs1.Spec.HostGroup = "hg"
+		mockCtlr.addVirtualServer(vs1)
+		Expect(mockCtlr.processResources()).To(BeTrue())
+		Expect(testutil.ToFloat64(metrics.ResourceProcessingErrors)).To(Equal(errors))
+
+		// virtuals of host group with different addresses are retried
+		vs2 := newVirtualServer("vs2", "10.1.1.2")
+		vs2.Spec.HostGroup = "hg"
+		mockCtlr.addVirtualServer(vs2)
+		Expect(mockCtlr.processResources()).To(BeTrue())
+		Expect(testutil.ToFloat64(metrics.ResourceProcessingErrors)).To(Equal(errors + 1))
+	})
+})
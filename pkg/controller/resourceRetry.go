@@ -0,0 +1,73 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// DefaultRetryWarningThreshold is the number of retries of a resource from which
+	// warnings are raised for every retry
+	DefaultRetryWarningThreshold = 5
+	// DefaultMaxRetries is the number of retries after which a resource is dropped
+	DefaultMaxRetries = 20
+
+	// RetryingReason is the reason of events on resources retried beyond the warning threshold
+	RetryingReason = "ProcessingRetried"
+	// RetriesExhaustedReason is the reason of events on resources dropped after the max retries
+	RetriesExhaustedReason = "RetriesExhausted"
+)
+
+// retryResource requeues the key of a resource failed with a retryable error. Retries beyond
+// the warning threshold are logged and recorded as events on the resource, the key is dropped
+// once it exceeds the max retries until the resource is updated again.
+func (ctlr *Controller) retryResource(rKey *rqKey) {
+	rKey.retryCount++
+	if ctlr.maxRetries > 0 && rKey.retryCount > ctlr.maxRetries {
+		message := fmt.Sprintf("Dropped %v %v/%v after %v retries, it is processed again on its next update",
+			rKey.kind, rKey.namespace, rKey.rscName, ctlr.maxRetries)
+		log.Errorf("[Retry] %v", message)
+		ctlr.recordRetryEvent(rKey, RetriesExhaustedReason, message)
+		ctlr.resourceQueue.Forget(rKey)
+		return
+	}
+	if ctlr.retryWarningThreshold > 0 && rKey.retryCount > ctlr.retryWarningThreshold {
+		message := fmt.Sprintf("Retrying %v %v/%v, retry %v", rKey.kind, rKey.namespace,
+			rKey.rscName, rKey.retryCount)
+		if ctlr.maxRetries > 0 {
+			message += fmt.Sprintf(" of %v", ctlr.maxRetries)
+		}
+		log.Warningf("[Retry] %v", message)
+		ctlr.recordRetryEvent(rKey, RetryingReason, message)
+	}
+	ctlr.resourceQueue.AddRateLimited(rKey)
+}
+
+// recordRetryEvent records a warning event on the resource of key
+func (ctlr *Controller) recordRetryEvent(rKey *rqKey, reason, message string) {
+	obj, ok := rKey.rsc.(runtime.Object)
+	if !ok || rKey.namespace == "" || ctlr.eventNotifier == nil || ctlr.kubeClient == nil {
+		return
+	}
+	evNotifier := ctlr.eventNotifier.CreateNotifierForNamespace(rKey.namespace, ctlr.kubeClient.CoreV1())
+	evNotifier.RecordEvent(obj, v1.EventTypeWarning, reason, message)
+}
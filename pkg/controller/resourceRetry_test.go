@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Resource Retry Tests", func() {
+	var mockCtlr *mockController
+	var rKey *rqKey
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+		mockCtlr.retryWarningThreshold = 2
+		mockCtlr.maxRetries = 4
+		vs := test.NewVirtualServer("vs1", namespace, cisapiv1.VirtualServerSpec{Host: "foo.com"})
+		rKey = &rqKey{namespace: namespace, kind: VirtualServer, rscName: vs.Name, rsc: vs, event: Update}
+	})
+
+	AfterEach(func() {
+		mockCtlr.resourceQueue.ShutDown()
+	})
+
+	// events are recorded asynchronously
+	retryEvents := func(reason string) int {
+		count := 0
+		events, _ := mockCtlr.kubeClient.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{})
+		for _, event := range events.Items {
+			if event.Reason == reason && event.InvolvedObject.Name == rKey.rscName {
+				count++
+			}
+		}
+		return count
+	}
+
+	It("Warns on the retries beyond the threshold", func() {
+		mockCtlr.retryResource(rKey)
+		mockCtlr.retryResource(rKey)
+		Expect(rKey.retryCount).To(Equal(2))
+		Expect(mockCtlr.resourceQueue.NumRequeues(rKey)).To(Equal(2))
+
+		mockCtlr.retryResource(rKey)
+		Expect(mockCtlr.resourceQueue.NumRequeues(rKey)).To(Equal(3))
+		Eventually(func() int { return retryEvents(RetryingReason) }).Should(Equal(1),
+			"Only the retries beyond the threshold should be recorded")
+		Expect(retryEvents(RetriesExhaustedReason)).To(BeZero())
+	})
+
+	It("Drops the resource after the max retries", func() {
+		for i := 0; i < 4; i++ {
+			mockCtlr.retryResource(rKey)
+		}
+		Expect(mockCtlr.resourceQueue.NumRequeues(rKey)).To(Equal(4))
+
+		mockCtlr.retryResource(rKey)
+		Expect(mockCtlr.resourceQueue.NumRequeues(rKey)).To(BeZero(), "Resource should be forgotten")
+		Eventually(func() int { return retryEvents(RetriesExhaustedReason) }).Should(Equal(1))
+	})
+
+	It("Retries indefinitely without max retries", func() {
+		mockCtlr.retryWarningThreshold = 0
+		mockCtlr.maxRetries = 0
+		for i := 0; i < 30; i++ {
+			mockCtlr.retryResource(rKey)
+		}
+		Expect(mockCtlr.resourceQueue.NumRequeues(rKey)).To(Equal(30))
+		Expect(retryEvents(RetryingReason)).To(BeZero())
+		Expect(retryEvents(RetriesExhaustedReason)).To(BeZero())
+	})
+})
@@ -13,7 +13,8 @@ import (
 
 func (ctlr *Controller) enqueueReq(config ResourceConfigRequest) int {
 	rm := requestMeta{
-		meta: make(map[string]string, len(config.ltmConfig)),
+		meta:       make(map[string]string, len(config.ltmConfig)),
+		partitions: make(map[string]string, len(config.ltmConfig)),
 	}
 	if ctlr.requestQueue.Len() == 0 {
 		rm.id = 1
@@ -25,6 +26,7 @@ func (ctlr *Controller) enqueueReq(config ResourceConfigRequest) int {
 		for _, cfg := range partitionConfig.ResourceMap {
 			for key, val := range cfg.MetaData.baseResources {
 				rm.meta[key] = val
+				rm.partitions[key] = partition
 				rm.partition = partition
 			}
 		}
@@ -42,6 +44,9 @@ func (ctlr *Controller) responseHandler(respChan chan resourceStatusMeta) {
 	ctlr.requestQueue = &requestQueue{sync.Mutex{}, list.New()}
 	for rscUpdateMeta := range respChan {
 
+		if len(rscUpdateMeta.declarationSizes) > 0 {
+			ctlr.recordDeclarationSizeEvents(rscUpdateMeta.declarationSizes)
+		}
 		rm := ctlr.dequeueReq(rscUpdateMeta.id, len(rscUpdateMeta.failedTenants))
 		partition := rm.partition
 		for rscKey, kind := range rm.meta {
@@ -65,7 +70,12 @@ func (ctlr *Controller) responseHandler(respChan chan resourceStatusMeta) {
 				}
 				virtual := obj.(*cisapiv1.VirtualServer)
 				if virtual.Namespace+"/"+virtual.Name == rscKey {
-					ctlr.updateVirtualServerStatus(virtual, virtual.Status.VSAddress, "Ok")
+					status := ctlr.getDeclarationStatus(rscUpdateMeta.declarationSizes, rm.partitions[rscKey])
+					if _, ok := rscUpdateMeta.removedTenants[rm.partitions[rscKey]]; ok {
+						status = PartitionPostFailedReason
+						ctlr.recordPartitionPostFailedEvent(virtual, rm.partitions[rscKey])
+					}
+					ctlr.updateVirtualServerStatus(virtual, virtual.Status.VSAddress, status)
 				}
 				// Update Corresponding Service Status of Type LB
 				for _, pool := range virtual.Spec.Pools {
@@ -92,7 +102,8 @@ func (ctlr *Controller) responseHandler(respChan chan resourceStatusMeta) {
 				}
 				virtual := obj.(*cisapiv1.TransportServer)
 				if virtual.Namespace+"/"+virtual.Name == rscKey {
-					ctlr.updateTransportServerStatus(virtual, virtual.Status.VSAddress, "Ok")
+					ctlr.updateTransportServerStatus(virtual, virtual.Status.VSAddress,
+						ctlr.getDeclarationStatus(rscUpdateMeta.declarationSizes, rm.partitions[rscKey]))
 				}
 			case Route:
 				if _, found := rscUpdateMeta.failedTenants[partition]; found {
@@ -0,0 +1,221 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultRollbackHistoryDepth is the number of declarations retained for rollback
+const DefaultRollbackHistoryDepth = 5
+
+// CheckpointKey is the key of checkpoint ConfigMap data holding the JSON array of retained declarations
+const CheckpointKey = "declarations"
+
+type (
+	// declarationHistory retains the tenant declarations posted to BIG-IP for rollback, the history
+	// is persisted in the checkpoint ConfigMap to roll back to the declarations posted before a restart
+	declarationHistory struct {
+		sync.Mutex
+		depth        int
+		cmKey        string
+		kubeClient   kubernetes.Interface
+		declarations []declarationCheckpoint
+	}
+
+	// declarationCheckpoint is the declaration of tenants posted for the config request reqId
+	declarationCheckpoint struct {
+		ReqId   int             `json:"reqId"`
+		Tenants json.RawMessage `json:"tenants"`
+	}
+)
+
+// newDeclarationHistory creates the declaration history with the declarations of checkpoint ConfigMap
+func newDeclarationHistory(kubeClient kubernetes.Interface, cmKey string, depth int) *declarationHistory {
+	history := &declarationHistory{
+		depth:      depth,
+		cmKey:      cmKey,
+		kubeClient: kubeClient,
+	}
+	namespace, name := history.checkpointName()
+	if kubeClient == nil || name == "" {
+		return history
+	}
+	cm, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Errorf("[Rollback] Unable to read the checkpoint ConfigMap %v: %v", cmKey, err)
+		}
+		return history
+	}
+	if err = json.Unmarshal([]byte(cm.Data[CheckpointKey]), &history.declarations); err != nil {
+		log.Errorf("[Rollback] Invalid %v in checkpoint ConfigMap %v: %v", CheckpointKey, cmKey, err)
+		history.declarations = nil
+	}
+	if len(history.declarations) > depth {
+		history.declarations = history.declarations[len(history.declarations)-depth:]
+	}
+	return history
+}
+
+func (history *declarationHistory) checkpointName() (string, string) {
+	splits := strings.Split(history.cmKey, "/")
+	if len(splits) != 2 {
+		return "", ""
+	}
+	return splits[0], splits[1]
+}
+
+// store retains the declaration of tenants posted for the config request reqId, the history
+// is limited to the depth and persisted in the checkpoint ConfigMap
+func (history *declarationHistory) store(reqId int, adc as3ADC) {
+	tenants, err := json.Marshal(adc)
+	if err != nil {
+		log.Errorf("[Rollback] Unable to retain the declaration of request %v: %v", reqId, err)
+		return
+	}
+	history.Lock()
+	defer history.Unlock()
+	history.declarations = append(history.declarations, declarationCheckpoint{ReqId: reqId, Tenants: tenants})
+	if len(history.declarations) > history.depth {
+		history.declarations = history.declarations[len(history.declarations)-history.depth:]
+	}
+	history.persist()
+}
+
+// persist writes the declarations to the checkpoint ConfigMap
+func (history *declarationHistory) persist() {
+	namespace, name := history.checkpointName()
+	if history.kubeClient == nil || name == "" {
+		return
+	}
+	data, err := json.Marshal(history.declarations)
+	if err != nil {
+		log.Errorf("[Rollback] Unable to persist the declaration history: %v", err)
+		return
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Data:       map[string]string{CheckpointKey: string(data)},
+	}
+	cmClient := history.kubeClient.CoreV1().ConfigMaps(namespace)
+	_, err = cmClient.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if errors.IsNotFound(err) {
+		_, err = cmClient.Create(context.TODO(), cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		log.Errorf("[Rollback] Unable to persist the declaration history in ConfigMap %v: %v", history.cmKey, err)
+	}
+}
+
+// get returns the declaration of tenants posted for the config request reqId
+func (history *declarationHistory) get(reqId int) (map[string]as3Tenant, bool) {
+	history.Lock()
+	defer history.Unlock()
+	for _, checkpoint := range history.declarations {
+		if checkpoint.ReqId != reqId {
+			continue
+		}
+		tenants := make(map[string]as3Tenant)
+		if err := json.Unmarshal(checkpoint.Tenants, &tenants); err != nil {
+			log.Errorf("[Rollback] Invalid declaration of request %v: %v", reqId, err)
+			return nil, false
+		}
+		return tenants, true
+	}
+	return nil, false
+}
+
+// processRollback reposts the config request referred by the rollback-to annotation on VirtualServer
+func (ctlr *Controller) processRollback(vs *cisapiv1.VirtualServer) {
+	rollbackTo := vs.Annotations[RollbackToAnnotation]
+	reqId, err := strconv.Atoi(rollbackTo)
+	if err != nil {
+		message := fmt.Sprintf("Invalid value %v for annotation %v", rollbackTo, RollbackToAnnotation)
+		log.Errorf("[Rollback] %v on VirtualServer %v/%v", message, vs.Namespace, vs.Name)
+		ctlr.recordVirtualServerEvent(vs, v1.EventTypeWarning, "Rollback", message)
+		ctlr.clearRollbackAnnotation(vs)
+		return
+	}
+
+	tenants, found := ctlr.declHistory.get(reqId)
+	if !found {
+		message := fmt.Sprintf("Declaration for request %v not found in rollback history", reqId)
+		log.Errorf("[Rollback] %v, VirtualServer %v/%v", message, vs.Namespace, vs.Name)
+		ctlr.recordVirtualServerEvent(vs, v1.EventTypeWarning, "Rollback", message)
+		ctlr.clearRollbackAnnotation(vs)
+		return
+	}
+
+	log.Infof("[Rollback] Reposting declaration for request %v, VirtualServer %v/%v", reqId, vs.Namespace, vs.Name)
+	config := ResourceConfigRequest{
+		ltmConfig:          ctlr.resources.getLTMConfigDeepCopy(),
+		shareNodes:         ctlr.shareNodes,
+		gtmConfig:          ctlr.resources.getGTMConfigCopy(),
+		defaultRouteDomain: ctlr.defaultRouteDomain,
+		rollbackTenants:    tenants,
+	}
+	ctlr.setPartitionCredentials(&config)
+	config.reqId = ctlr.enqueueReq(config)
+	ctlr.Agent.PostConfig(config)
+
+	message := fmt.Sprintf("Rolled back to declaration for request %v", reqId)
+	ctlr.recordVirtualServerEvent(vs, v1.EventTypeNormal, "Rollback", message)
+	if updatedVS := ctlr.clearRollbackAnnotation(vs); updatedVS != nil {
+		ctlr.updateVirtualServerStatus(updatedVS, updatedVS.Status.VSAddress, message)
+	}
+}
+
+// clearRollbackAnnotation removes the rollback-to annotation from VirtualServer
+func (ctlr *Controller) clearRollbackAnnotation(vs *cisapiv1.VirtualServer) *cisapiv1.VirtualServer {
+	vsCopy := vs.DeepCopy()
+	delete(vsCopy.Annotations, RollbackToAnnotation)
+	updatedVS, err := ctlr.kubeCRClient.CisV1().VirtualServers(vs.Namespace).Update(
+		context.TODO(), vsCopy, metav1.UpdateOptions{})
+	if err != nil {
+		log.Errorf("[Rollback] Error while clearing annotation %v on VirtualServer %v/%v: %v",
+			RollbackToAnnotation, vs.Namespace, vs.Name, err)
+		return nil
+	}
+	return updatedVS
+}
+
+func (ctlr *Controller) recordVirtualServerEvent(
+	vs *cisapiv1.VirtualServer,
+	eventType string,
+	reason string,
+	message string,
+) {
+	if ctlr.eventNotifier == nil || ctlr.kubeClient == nil {
+		return
+	}
+	evNotifier := ctlr.eventNotifier.CreateNotifierForNamespace(
+		vs.Namespace, ctlr.kubeClient.CoreV1())
+	evNotifier.RecordEvent(vs, eventType, reason, message)
+}
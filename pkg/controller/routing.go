@@ -103,6 +103,12 @@ func (ctlr *Controller) prepareVirtualServerRules(
 			}
 			rl.Actions = append(rl.Actions, rewriteActions...)
 		}
+		if pl.HostHeaderRewrite != "" {
+			rl.Actions = append(rl.Actions, getHostHeaderRewriteAction(pl.HostHeaderRewrite, len(rl.Actions)))
+		}
+		if pl.PersistenceProfile != "" {
+			rl.Actions = append(rl.Actions, getPersistAction(pl.PersistenceProfile, poolName, len(rl.Actions)))
+		}
 
 		if pl.Path == "/" {
 			redirects = append(redirects, rl)
@@ -113,6 +119,12 @@ func (ctlr *Controller) prepareVirtualServerRules(
 		}
 	}
 
+	cookieRules, err := ctlr.prepareVirtualServerCookieRules(vs, rsCfg)
+	if nil != err {
+		log.Errorf("Error configuring cookie rule: %v", err)
+		return nil
+	}
+
 	if vs.Spec.RewriteAppRoot != "" && len(redirects) != 2 {
 		log.Error("AppRoot path not found for rewriting")
 		return nil
@@ -157,9 +169,56 @@ func (ctlr *Controller) prepareVirtualServerRules(
 
 	sort.Sort(rls)
 	rls = append(redirects, rls...)
+	rls = append(cookieRules, rls...)
 	return &rls
 }
 
+// prepareVirtualServerCookieRules prepares LTM Policy rules forwarding the requests
+// with cookie value to the pool of VirtualServer
+func (ctlr *Controller) prepareVirtualServerCookieRules(
+	vs *cisapiv1.VirtualServer,
+	rsCfg *ResourceConfig,
+) (Rules, error) {
+	var rls Rules
+	for _, cr := range vs.Spec.CookieRoutes {
+		var poolName, hostHeader, persistenceProfile string
+		for _, pl := range vs.Spec.Pools {
+			if pl.Name == cr.Pool {
+				poolName = ctlr.framePoolName(vs.ObjectMeta.Namespace, pl, vs.Spec.Host)
+				hostHeader = pl.HostHeaderRewrite
+				persistenceProfile = pl.PersistenceProfile
+				break
+			}
+		}
+		if poolName == "" {
+			return nil, fmt.Errorf("pool %v not found for cookie %v", cr.Pool, cr.CookieName)
+		}
+		ruleName := formatVirtualServerRuleName(vs.Spec.Host, vs.Spec.HostGroup,
+			fmt.Sprintf("cookie_%s_%s", cr.CookieName, cr.CookieValue), poolName)
+		rl, err := createRule(vs.Spec.Host, poolName, ruleName, rsCfg.Virtual.AllowSourceRange)
+		if nil != err {
+			return nil, err
+		}
+		if hostHeader != "" {
+			rl.Actions = append(rl.Actions, getHostHeaderRewriteAction(hostHeader, len(rl.Actions)))
+		}
+		if persistenceProfile != "" {
+			rl.Actions = append(rl.Actions, getPersistAction(persistenceProfile, poolName, len(rl.Actions)))
+		}
+		rl.Conditions = append(rl.Conditions, &condition{
+			Equals:     true,
+			HTTPCookie: true,
+			CookieName: cr.CookieName,
+			Name:       strconv.Itoa(len(rl.Conditions)),
+			Index:      len(rl.Conditions),
+			Request:    true,
+			Values:     []string{cr.CookieValue},
+		})
+		rls = append(rls, rl)
+	}
+	return rls, nil
+}
+
 // format the rule name for VirtualServer
 func formatVirtualServerRuleName(hostname, hostGroup, path, pool string) string {
 	var rule string
@@ -292,6 +351,28 @@ func createPolicy(rls Rules, policyName, partition string) *Policy {
 	return &plcy
 }
 
+// getHostHeaderRewriteAction replaces the Host header of the requests forwarded to the pool
+func getHostHeaderRewriteAction(host string, actionNameIndex int) *action {
+	return &action{
+		Name:     fmt.Sprintf("%d", actionNameIndex),
+		HTTPHost: true,
+		Replace:  true,
+		Request:  true,
+		Value:    host,
+	}
+}
+
+// getPersistAction overrides the persistence of virtual for the requests forwarded to the pool,
+// cookie persistence inserts the cookie named after the pool
+func getPersistAction(persistenceProfile, poolName string, actionNameIndex int) *action {
+	return &action{
+		Name:    fmt.Sprintf("%d", actionNameIndex),
+		Persist: persistenceProfile,
+		Request: true,
+		Value:   "BIGipServer" + poolName,
+	}
+}
+
 func getRewriteActions(path, rwPath string, actionNameIndex int) ([]*action, error) {
 
 	if rwPath == "" {
@@ -399,6 +480,19 @@ func (rules Rules) Len() int {
 func (rules Rules) Less(i, j int) bool {
 	ruleI := rules[i]
 	ruleJ := rules[j]
+	// Strategy 0: Rule matching cookie takes precedence over host and path based rules
+	cookieExists := func(rule *Rule) bool {
+		for _, cnd := range rule.Conditions {
+			if cnd.HTTPCookie {
+				return true
+			}
+		}
+		return false
+	}
+	if cookieI, cookieJ := cookieExists(ruleI), cookieExists(ruleJ); cookieI != cookieJ {
+		return cookieI
+	}
+
 	// Strategy 1: Rule with Highest number of conditions
 	l1 := len(ruleI.Conditions)
 	l2 := len(ruleJ.Conditions)
@@ -472,6 +566,66 @@ func httpRedirectIRuleNoHost(port int32) string {
 	return iRuleCode
 }
 
+// securityHeadersIRule inserts the X-Frame-Options header in HTTP responses
+func securityHeadersIRule(xFrameOptions string) string {
+	iRuleCode := fmt.Sprintf(`
+		when HTTP_RESPONSE {
+			HTTP::header replace X-Frame-Options "%s"
+		}`, xFrameOptions)
+	return iRuleCode
+}
+
+// contentSecurityPolicyIRule inserts the Content-Security-Policy header in HTTP responses
+func contentSecurityPolicyIRule(policy string) string {
+	iRuleCode := fmt.Sprintf(`
+		when HTTP_RESPONSE {
+			HTTP::header replace Content-Security-Policy "%s"
+		}`, policy)
+	return iRuleCode
+}
+
+// passthroughPersistenceIRule selects the pool member of passthrough connections by the hash of the SNI
+// of ClientHello. It runs after the TLS iRule, which parses the SNI and selects the passthrough pool.
+func passthroughPersistenceIRule() string {
+	iRuleCode := `
+		when CLIENT_DATA priority 600 {
+			if { ![info exists tls_servername] || ![info exists dflt_pool_passthrough] || $dflt_pool_passthrough equals "" } {
+				return
+			}
+			set passthru_pool [LB::server pool]
+			if { $passthru_pool equals "" } {
+				return
+			}
+			set members [active_members -list $passthru_pool]
+			if { [llength $members] > 0 } {
+				set member [lindex $members [expr {[crc32 [string tolower $tls_servername]] % [llength $members]}]]
+				pool $passthru_pool member [lindex $member 0] [lindex $member 1]
+			}
+		}`
+	return iRuleCode
+}
+
+// requestBodyRewriteIRule collects the request body up to the maximum rewrite size
+// and substitutes all the matches of the regex with the replacement
+func requestBodyRewriteIRule(match, replace string) string {
+	iRuleCode := fmt.Sprintf(`
+		when HTTP_REQUEST {
+			if { [HTTP::header exists "Content-Length"] && [HTTP::header "Content-Length"] > 0 } {
+				set content_length [HTTP::header "Content-Length"]
+				if { $content_length > %d } {
+					set content_length %d
+				}
+				HTTP::collect $content_length
+			}
+		}
+
+		when HTTP_REQUEST_DATA {
+			HTTP::payload replace 0 [HTTP::payload length] [regsub -all -- {%s} [HTTP::payload] {%s}]
+			HTTP::release
+		}`, MaxBodyRewriteSize, MaxBodyRewriteSize, match, replace)
+	return iRuleCode
+}
+
 // httpRedirectIRule redirects traffic to BIG-IP https vs
 // except for the hostLess CRDs.
 func httpRedirectIRule(port int32, rsVSName string, partition string) string {
@@ -0,0 +1,158 @@
+/*-
+ * Copyright (c) 2016-2021, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// bigIPFileUploadDir is the directory of files uploaded with file transfer API of BIG-IP
+const bigIPFileUploadDir = "/var/config/rest/downloads/"
+
+// SecureVaultClient installs TLS keys on BIG-IP, where they are stored encrypted
+// by SecureVault, so that AS3 declarations refer the keys instead of carrying them
+type SecureVaultClient struct {
+	*PostManager
+	// installedKeys holds the checksum of keys installed on BIG-IP
+	installedKeys map[string][32]byte
+	sync.Mutex
+}
+
+// NewSecureVaultClient creates SecureVaultClient using REST client of PostManager
+func NewSecureVaultClient(postMgr *PostManager) *SecureVaultClient {
+	return &SecureVaultClient{
+		PostManager:   postMgr,
+		installedKeys: make(map[string][32]byte),
+	}
+}
+
+// InstallKey uploads the key to BIG-IP and installs it in Common partition, the uploaded
+// key is removed once installed. It returns the BIG-IP path of the key
+func (svc *SecureVaultClient) InstallKey(name, key string) (string, error) {
+	svc.Lock()
+	defer svc.Unlock()
+	keyName := name + ".key"
+	keyPath := JoinBigipPath("Common", keyName)
+	checksum := sha256.Sum256([]byte(key))
+	if sum, ok := svc.installedKeys[keyPath]; ok && sum == checksum {
+		return keyPath, nil
+	}
+
+	// upload the key to file transfer directory of BIG-IP
+	req, err := http.NewRequest("POST", svc.getFileUploadURL(keyName), bytes.NewReader([]byte(key)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Range", fmt.Sprintf("0-%d/%d", len(key)-1, len(key)))
+	if err = svc.secureVaultReq(req); err != nil {
+		return "", fmt.Errorf("failed to upload key %v: %v", keyName, err)
+	}
+
+	// install the uploaded key, BIG-IP encrypts the key with SecureVault master key
+	body, _ := json.Marshal(map[string]string{
+		"command":         "install",
+		"name":            keyPath,
+		"from-local-file": bigIPFileUploadDir + keyName,
+	})
+	req, err = http.NewRequest("POST", svc.getCryptoKeyURL(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	installErr := svc.secureVaultReq(req)
+	// uploaded key is in cleartext, it is removed irrespective of the install result
+	removeErr := svc.removeUploadedFile(keyName)
+	if installErr != nil {
+		return "", fmt.Errorf("failed to install key %v: %v", keyName, installErr)
+	}
+	if removeErr != nil {
+		return "", fmt.Errorf("failed to remove uploaded key %v: %v", keyName, removeErr)
+	}
+	svc.installedKeys[keyPath] = checksum
+	return keyPath, nil
+}
+
+// removeUploadedFile removes the file from the file transfer directory of BIG-IP
+func (svc *SecureVaultClient) removeUploadedFile(fileName string) error {
+	body, _ := json.Marshal(map[string]string{
+		"command":     "run",
+		"utilCmdArgs": bigIPFileUploadDir + fileName,
+	})
+	req, err := http.NewRequest("POST", svc.getUnixRmURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return svc.secureVaultReq(req)
+}
+
+// replaceCertificateKeys replaces the inline private keys of certificates in given AS3
+// application with references to the keys installed on BIG-IP, it returns an error when
+// a key is not installed so that the key is not posted in cleartext
+func (svc *SecureVaultClient) replaceCertificateKeys(tenant string, sharedApp as3Application) error {
+	for name, obj := range sharedApp {
+		cert, ok := obj.(*as3Certificate)
+		if !ok {
+			continue
+		}
+		key, ok := cert.PrivateKey.(string)
+		if !ok || key == "" {
+			continue
+		}
+		keyPath, err := svc.InstallKey(fmt.Sprintf("%s_%s", tenant, name), key)
+		if err != nil {
+			return fmt.Errorf("unable to secure private key of certificate %v: %v", name, err)
+		}
+		cert.PrivateKey = &as3ResourcePointer{
+			BigIP: keyPath,
+		}
+	}
+	return nil
+}
+
+func (svc *SecureVaultClient) secureVaultReq(req *http.Request) error {
+	req.SetBasicAuth(svc.BIGIPUsername, svc.BIGIPPassword)
+	httpResp, _ := svc.httpReq(req)
+	if httpResp == nil {
+		return fmt.Errorf("Internal Error")
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error response from BIGIP with status code %v", httpResp.StatusCode)
+	}
+	return nil
+}
+
+func (svc *SecureVaultClient) getFileUploadURL(fileName string) string {
+	apiURL := svc.BIGIPURL + "/mgmt/shared/file-transfer/uploads/" + fileName
+	return apiURL
+}
+
+func (svc *SecureVaultClient) getCryptoKeyURL() string {
+	apiURL := svc.BIGIPURL + "/mgmt/tm/sys/crypto/key"
+	return apiURL
+}
+
+func (svc *SecureVaultClient) getUnixRmURL() string {
+	apiURL := svc.BIGIPURL + "/mgmt/tm/util/unix-rm"
+	return apiURL
+}
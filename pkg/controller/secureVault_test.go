@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SecureVault Tests", func() {
+	var server *httptest.Server
+	var svc *SecureVaultClient
+	var requests []string
+	var removedFiles []string
+	var installStatus int
+
+	BeforeEach(func() {
+		requests, removedFiles = nil, nil
+		installStatus = http.StatusOK
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.URL.Path)
+			switch r.URL.Path {
+			case "/mgmt/tm/sys/crypto/key":
+				w.WriteHeader(installStatus)
+			case "/mgmt/tm/util/unix-rm":
+				var body map[string]string
+				data, _ := ioutil.ReadAll(r.Body)
+				_ = json.Unmarshal(data, &body)
+				removedFiles = append(removedFiles, body["utilCmdArgs"])
+			}
+			_, _ = w.Write([]byte("{}"))
+		}))
+		postMgr := &PostManager{PostParams: PostParams{
+			BIGIPURL:      server.URL,
+			BIGIPUsername: "user",
+			BIGIPPassword: "pswd",
+		}}
+		_ = postMgr.setupBIGIPRESTClient()
+		svc = NewSecureVaultClient(postMgr)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newSharedApp := func() as3Application {
+		sharedApp := as3Application{}
+		sharedApp["crd_tls_0"] = &as3Certificate{
+			Class:       "Certificate",
+			Certificate: "cert",
+			PrivateKey:  "key",
+		}
+		return sharedApp
+	}
+
+	It("Replaces certificate keys with BIG-IP references", func() {
+		sharedApp := newSharedApp()
+		Expect(svc.replaceCertificateKeys("test", sharedApp)).To(Succeed())
+		Expect(sharedApp["crd_tls_0"].(*as3Certificate).PrivateKey).To(Equal(
+			&as3ResourcePointer{BigIP: "/Common/test_crd_tls_0.key"}), "Key not replaced")
+		Expect(requests).To(Equal([]string{
+			"/mgmt/shared/file-transfer/uploads/test_crd_tls_0.key",
+			"/mgmt/tm/sys/crypto/key",
+			"/mgmt/tm/util/unix-rm",
+		}))
+		Expect(removedFiles).To(Equal([]string{"/var/config/rest/downloads/test_crd_tls_0.key"}),
+			"Uploaded key should be removed after install")
+
+		// installed key is not uploaded again
+		requests = nil
+		keyPath, err := svc.InstallKey("test_crd_tls_0", "key")
+		Expect(err).To(BeNil())
+		Expect(keyPath).To(Equal("/Common/test_crd_tls_0.key"))
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("Fails to replace certificate keys not installed", func() {
+		installStatus = http.StatusUnauthorized
+		sharedApp := newSharedApp()
+		Expect(svc.replaceCertificateKeys("test", sharedApp)).NotTo(Succeed())
+		Expect(removedFiles).To(Equal([]string{"/var/config/rest/downloads/test_crd_tls_0.key"}),
+			"Uploaded key should be removed on install failure")
+
+		// tenant is not posted with the key in cleartext
+		agent := newMockAgent(nil)
+		agent.secureVault = svc
+		rsCfg := &ResourceConfig{}
+		rsCfg.MetaData.ResourceType = VirtualServer
+		rsCfg.Virtual.Name = "crd_vs_10.1.1.1"
+		rsCfg.Virtual.Partition = "test"
+		rsCfg.customProfiles = map[SecretKey]CustomProfile{
+			{Name: "crd_tls", ResourceName: "crd_vs_10.1.1.1"}: {
+				Name:         "crd_tls",
+				Partition:    "test",
+				Context:      "clientside",
+				Certificates: []certificate{{Cert: "cert", Key: "key"}},
+			},
+		}
+		config := ResourceConfigRequest{ltmConfig: LTMConfig{
+			"test": &PartitionConfig{ResourceMap: ResourceMap{"crd_vs_10.1.1.1": rsCfg}},
+		}}
+		Expect(agent.createAS3LTMConfigADC(config)).NotTo(HaveKey("test"))
+	})
+})
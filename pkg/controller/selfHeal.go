@@ -0,0 +1,151 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+// PartitionPostFailedReason is the reason of events and status of VirtualServers whose tenant
+// is removed from the AS3 declaration after BIG-IP rejected it with self-heal-on-post-error
+const PartitionPostFailedReason = "PartitionPostFailed"
+
+// handleResponseUnprocessableEntity handles the declaration rejected by AS3 schema validation,
+// the tenants with invalid declaration are identified from the response to repost the others
+func (postMgr *PostManager) handleResponseUnprocessableEntity(responseMap map[string]interface{}, cfg *agentConfig) {
+	if postMgr.SelfHealOnPostError {
+		body, _ := json.Marshal(responseMap)
+		log.Errorf("[AS3] Declaration of tenants %v is rejected by BIG-IP: %s", cfg.tenants, body)
+		postMgr.invalidTenants = getInvalidTenants(responseMap, cfg.tenants)
+		if len(postMgr.invalidTenants) == 0 {
+			log.Warningf("[AS3] Unable to identify the invalid tenants of declaration, tenants %v are retried",
+				cfg.tenants)
+		}
+	}
+	postMgr.handleResponseOthers(responseMap, cfg)
+}
+
+// getInvalidTenants returns the tenants rejected in the response of AS3, which are either the tenants of
+// results with 422 code or the tenants in the paths of errors, e.g. "/test/Shared/vs/pool: should be string".
+// The only tenant of the declaration is invalid when the errors have no tenant.
+func getInvalidTenants(responseMap map[string]interface{}, tenants []string) []string {
+	posted := make(map[string]bool)
+	for _, tenant := range tenants {
+		posted[tenant] = true
+	}
+	invalid := make(map[string]bool)
+	if results, ok := responseMap["results"].([]interface{}); ok {
+		for _, value := range results {
+			v, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if code, _ := v["code"].(float64); int(code) == http.StatusUnprocessableEntity {
+				if tenant, _ := v["tenant"].(string); posted[tenant] {
+					invalid[tenant] = true
+				}
+			}
+		}
+	}
+	var messages []string
+	if errs, ok := responseMap["errors"].([]interface{}); ok {
+		for _, err := range errs {
+			if msg, ok := err.(string); ok {
+				messages = append(messages, msg)
+			}
+		}
+	}
+	if msg, ok := responseMap["message"].(string); ok {
+		messages = append(messages, msg)
+	}
+	for _, msg := range messages {
+		for _, field := range strings.Fields(msg) {
+			if !strings.HasPrefix(field, "/") {
+				continue
+			}
+			for _, component := range strings.Split(strings.TrimRight(field, ":,"), "/") {
+				if posted[component] {
+					invalid[component] = true
+					break
+				}
+			}
+		}
+	}
+	if len(invalid) == 0 && len(tenants) == 1 {
+		invalid[tenants[0]] = true
+	}
+	var invalidTenants []string
+	for tenant := range invalid {
+		invalidTenants = append(invalidTenants, tenant)
+	}
+	sort.Strings(invalidTenants)
+	return invalidTenants
+}
+
+// selfHealPost removes the tenants of config rejected by BIG-IP and reposts the remaining ones.
+// Removed tenants are neither cached nor retried, they are posted again on the next change or resync.
+func (agent *Agent) selfHealPost(cfg agentConfig, removedTenants map[string]struct{}) {
+	for len(agent.invalidTenants) > 0 {
+		invalid := make(map[string]bool)
+		for _, tenant := range agent.invalidTenants {
+			invalid[tenant] = true
+		}
+		agent.invalidTenants = nil
+		var tenants []string
+		for _, tenant := range cfg.tenants {
+			if !invalid[tenant] {
+				tenants = append(tenants, tenant)
+				continue
+			}
+			removedTenants[tenant] = struct{}{}
+			delete(agent.tenantResponseMap, tenant)
+			delete(agent.retryTenantDeclMap, tenant)
+		}
+		if len(tenants) == len(cfg.tenants) {
+			return
+		}
+		log.Warningf("[AS3] Self-healing removed the tenants rejected by BIG-IP from declaration, remaining tenants: %v",
+			tenants)
+		if len(tenants) == 0 {
+			return
+		}
+		tenantDecl := make(map[string]as3Tenant)
+		for _, tenant := range tenants {
+			tenantDecl[tenant] = agent.incomingTenantDeclMap[tenant]
+		}
+		cfg.data = string(agent.createAS3Declaration(tenantDecl))
+		cfg.as3APIURL = agent.getAS3APIURL(tenants)
+		cfg.tenants = tenants
+		agent.publishConfig(cfg)
+	}
+}
+
+// recordPartitionPostFailedEvent records PartitionPostFailed event on the VirtualServer
+// whose tenant is removed from the declaration
+func (ctlr *Controller) recordPartitionPostFailedEvent(virtual *cisapiv1.VirtualServer, partition string) {
+	ctlr.recordVirtualServerEvent(virtual, v1.EventTypeWarning, PartitionPostFailedReason,
+		fmt.Sprintf("Declaration of partition %v is rejected by BIG-IP, VirtualServer is not posted until "+
+			"the next update or resync, please check the logs for more information", partition))
+}
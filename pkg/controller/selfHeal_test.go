@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Self-heal on Post Error Tests", func() {
+	It("Gets the invalid tenants from AS3 response", func() {
+		Expect(getInvalidTenants(map[string]interface{}{
+			"code":    float64(422),
+			"message": "declaration is invalid",
+			"errors":  []interface{}{"/test/Shared/crd_vs_10_1_1_1_80/pool: should be string"},
+		}, []string{"prod", "test"})).To(Equal([]string{"test"}))
+
+		Expect(getInvalidTenants(map[string]interface{}{
+			"results": []interface{}{
+				map[string]interface{}{"code": float64(200), "tenant": "prod"},
+				map[string]interface{}{"code": float64(422), "tenant": "test"},
+			},
+		}, []string{"prod", "test"})).To(Equal([]string{"test"}))
+
+		Expect(getInvalidTenants(map[string]interface{}{
+			"code":    float64(422),
+			"message": "declaration is invalid",
+		}, []string{"test"})).To(Equal([]string{"test"}), "Only tenant of declaration should be invalid")
+
+		Expect(getInvalidTenants(map[string]interface{}{
+			"code":    float64(422),
+			"message": "declaration is invalid",
+		}, []string{"prod", "test"})).To(BeEmpty())
+	})
+
+	It("Reposts the declaration without the invalid tenants", func() {
+		var urls []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			urls = append(urls, r.URL.Path)
+			if strings.HasSuffix(r.URL.Path, "test") {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				fmt.Fprint(w, `{"code":422,"message":"declaration is invalid",`+
+					`"errors":["/test/Shared/crd_vs_10_1_1_1_80/pool: should be string"]}`)
+				return
+			}
+			fmt.Fprint(w, `{"results":[{"code":200,"message":"success","tenant":"prod"}]}`)
+		}))
+		defer server.Close()
+
+		agent := newMockAgent(nil)
+		agent.PostManager = &PostManager{PostParams: PostParams{BIGIPURL: server.URL, SelfHealOnPostError: true}}
+		agent.setupBIGIPRESTClient()
+		agent.incomingTenantDeclMap = map[string]as3Tenant{
+			"prod": {"class": "Tenant"},
+			"test": {"class": "Tenant"},
+		}
+		agent.tenantResponseMap = map[string]tenantResponse{"prod": {}, "test": {}}
+		agent.retryTenantDeclMap = make(map[string]*tenantParams)
+
+		tenants := []string{"prod", "test"}
+		cfg := agentConfig{
+			data:      string(agent.createAS3Declaration(agent.incomingTenantDeclMap)),
+			as3APIURL: agent.getAS3APIURL(tenants),
+			tenants:   tenants,
+		}
+		removedTenants := make(map[string]struct{})
+		agent.publishConfig(cfg)
+		agent.selfHealPost(cfg, removedTenants)
+
+		Expect(urls).To(Equal([]string{
+			"/mgmt/shared/appsvcs/declare/prod,test",
+			"/mgmt/shared/appsvcs/declare/prod",
+		}))
+		Expect(removedTenants).To(HaveKey("test"))
+		Expect(agent.tenantResponseMap).To(Equal(map[string]tenantResponse{"prod": {http.StatusOK, ""}}))
+		Expect(agent.retryTenantDeclMap).To(BeEmpty(), "Removed tenant should not be retried")
+
+		// declaration is retried as usual without self-heal
+		agent.SelfHealOnPostError = false
+		urls = nil
+		agent.tenantResponseMap = map[string]tenantResponse{"test": {}}
+		cfg.as3APIURL = agent.getAS3APIURL([]string{"test"})
+		cfg.tenants = []string{"test"}
+		agent.publishConfig(cfg)
+		agent.selfHealPost(cfg, removedTenants)
+		Expect(urls).To(HaveLen(1))
+		Expect(agent.tenantResponseMap["test"].agentResponseCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("Records PartitionPostFailed event on the VirtualServers of removed tenants", func() {
+		namespace := "default"
+		mockCtlr := newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = map[string]bool{namespace: true}
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		mockCtlr.Agent = newMockAgent(nil)
+		mockCtlr.Agent.PostManager = &PostManager{}
+		respChan := make(chan resourceStatusMeta, 1)
+		go mockCtlr.responseHandler(respChan)
+		defer close(respChan)
+		time.Sleep(10 * time.Millisecond)
+
+		for _, name := range []string{"vs1", "vs2"} {
+			vs := test.NewVirtualServer(name, namespace, cisapiv1.VirtualServerSpec{})
+			_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), vs, metav1.CreateOptions{})
+			mockCtlr.addVirtualServer(vs)
+		}
+		newPartitionConfig := func(baseResource string) *PartitionConfig {
+			rsCfg := &ResourceConfig{}
+			rsCfg.MetaData.baseResources = map[string]string{baseResource: VirtualServer}
+			return &PartitionConfig{ResourceMap: ResourceMap{"vs": rsCfg}}
+		}
+		id := mockCtlr.enqueueReq(ResourceConfigRequest{ltmConfig: LTMConfig{
+			"test": newPartitionConfig(namespace + "/vs1"),
+			"prod": newPartitionConfig(namespace + "/vs2"),
+		}})
+		respChan <- resourceStatusMeta{id, map[string]struct{}{"test": {}}, nil, map[string]struct{}{"test": {}}}
+
+		getStatus := func(name string) func() string {
+			return func() string {
+				vs, _ := mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+				return vs.Status.StatusOk
+			}
+		}
+		Eventually(getStatus("vs1")).Should(Equal(PartitionPostFailedReason))
+		Eventually(getStatus("vs2")).Should(Equal("Ok"))
+		Eventually(func() []string {
+			var objects []string
+			events, _ := mockCtlr.kubeClient.CoreV1().Events(namespace).List(context.TODO(), metav1.ListOptions{})
+			for _, event := range events.Items {
+				if event.Reason == PartitionPostFailedReason {
+					objects = append(objects, event.InvolvedObject.Name)
+				}
+			}
+			return objects
+		}).Should(ConsistOf("vs1"))
+	})
+})
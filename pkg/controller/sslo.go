@@ -0,0 +1,119 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+)
+
+// SSL Orchestrator topology types
+const (
+	SSLOTopologyL3Inbound  = "l3-inbound"
+	SSLOTopologyL3Outbound = "l3-outbound"
+	SSLOTopologyL2Inbound  = "l2-inbound"
+)
+
+// getSSLOTopology returns the SSL Orchestrator topology of the Policy, nil when not configured
+func getSSLOTopology(plc *cisapiv1.Policy) (*SSLOTopology, error) {
+	if plc.Spec.SSLOServiceChain == "" {
+		return nil, nil
+	}
+	topologyType := plc.Spec.SSLOTopologyType
+	switch topologyType {
+	case "":
+		topologyType = SSLOTopologyL3Inbound
+	case SSLOTopologyL3Inbound, SSLOTopologyL3Outbound, SSLOTopologyL2Inbound:
+	default:
+		return nil, fmt.Errorf("invalid ssloTopologyType %v, allowed values are %v, %v and %v",
+			topologyType, SSLOTopologyL3Inbound, SSLOTopologyL3Outbound, SSLOTopologyL2Inbound)
+	}
+	services := make(map[string]bool)
+	for _, service := range plc.Spec.SSLOSecurityServices {
+		if service == "" || services[service] {
+			return nil, fmt.Errorf("invalid ssloSecurityServices %v, services must be unique and non-empty",
+				plc.Spec.SSLOSecurityServices)
+		}
+		services[service] = true
+	}
+	return &SSLOTopology{
+		ServiceChain:     plc.Spec.SSLOServiceChain,
+		TopologyType:     topologyType,
+		SecurityServices: plc.Spec.SSLOSecurityServices,
+	}, nil
+}
+
+// accessProfile returns the access profile of the SSL Orchestrator topology on BIG-IP
+func (sslo *SSLOTopology) accessProfile() string {
+	return fmt.Sprintf("/Common/sslo_%[1]v.app/sslo_%[1]v_accessProfile", sslo.ServiceChain)
+}
+
+// perRequestPolicy returns the per-request policy of the SSL Orchestrator topology on BIG-IP,
+// which steers the traffic through the security services of the service chain
+func (sslo *SSLOTopology) perRequestPolicy() string {
+	return fmt.Sprintf("/Common/sslo_%[1]v.app/sslo_%[1]v_per_req_policy", sslo.ServiceChain)
+}
+
+// ssloDataGroupName returns the name of data group with the SSL Orchestrator topology of the virtual
+func ssloDataGroupName(vsName string) string {
+	return vsName + "_sslo_dg"
+}
+
+// dataGroup returns the data group with the topology type and the ordered security services of
+// the service chain, which the per-request policy of the topology looks up to steer the traffic
+func (sslo *SSLOTopology) dataGroup() *as3DataGroup {
+	dg := &as3DataGroup{
+		Class:       "Data_Group",
+		KeyDataType: "string",
+	}
+	if len(sslo.SecurityServices) > 0 {
+		dg.Records = append(dg.Records, as3Record{Key: "securityServices", Value: strings.Join(sslo.SecurityServices, ",")})
+	}
+	dg.Records = append(dg.Records,
+		as3Record{Key: "serviceChain", Value: sslo.ServiceChain},
+		as3Record{Key: "topologyType", Value: sslo.TopologyType},
+	)
+	return dg
+}
+
+// validateSSLOServiceChain checks that the SSL Orchestrator service chain of the Policy of VirtualServer
+// is used only with HTTPS VirtualServer, as the traffic is decrypted for the security services
+func (ctlr *Controller) validateSSLOServiceChain(crInf *CRInformer, vs *cisapiv1.VirtualServer) error {
+	if vs.Spec.PolicyName == "" {
+		return nil
+	}
+	comInf, ok := ctlr.getNamespacedCommonInformer(vs.Namespace)
+	if !ok {
+		return nil
+	}
+	obj, found, _ := comInf.plcInformer.GetIndexer().GetByKey(vs.Namespace + "/" + vs.Spec.PolicyName)
+	if !found {
+		return nil
+	}
+	plc := obj.(*cisapiv1.Policy)
+	if plc.Spec.SSLOServiceChain == "" {
+		return nil
+	}
+	if vs.Spec.TLSProfileName == "" || isPassthroughVirtualServer(crInf, vs) {
+		return fmt.Errorf("ssloServiceChain of Policy %v is allowed only for HTTPS VirtualServer "+
+			"with TLS termination", plc.Name)
+	}
+	_, err := getSSLOTopology(plc)
+	return err
+}
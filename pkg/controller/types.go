@@ -19,8 +19,10 @@ package controller
 import (
 	"container/list"
 	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
+	"net"
 	"net/http"
 	"sync"
+	"time"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -35,6 +37,7 @@ import (
 	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/pollers"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/writer"
+	"golang.org/x/time/rate"
 	v1 "k8s.io/api/core/v1"
 	extClient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/apimachinery/pkg/labels"
@@ -78,6 +81,79 @@ type (
 		requestQueue           *requestQueue
 		namespaceLabel         string
 		ipamHostSpecEmpty      bool
+		excludeTaintedNodes    bool
+		enableRollback         bool
+		// declHistory retains the declarations posted to BIG-IP for rollback, nil without rollback
+		declHistory           *declarationHistory
+		grpcMonitorScriptPath string
+		excludedNamespaces    map[string]bool
+		allowedVSCIDRs        []*net.IPNet
+		shutdownTimeout       time.Duration
+		resyncInterval        time.Duration
+		topologyAwareRouting  bool
+		// controllerZone is the topology zone of the node running controller
+		controllerZone string
+		// priorityGroupLabel is the node label setting priority group of pool members,
+		// controllerPriorityValue is its value on the node running controller
+		priorityGroupLabel      string
+		controllerPriorityValue string
+		// credentialSecrets maps namespaces to secrets with BIG-IP credentials of their partitions
+		credentialSecrets map[string]string
+		// namespaceRouteDomains maps namespaces to the route domains of their virtuals and pool members
+		namespaceRouteDomains map[string]int
+		// ipamLabelNamespaces maps IPAM labels to the namespaces of their IPAM CRs
+		ipamLabelNamespaces map[string]string
+		// serviceAccessCache holds the permission of CIS to get the services per namespace
+		serviceAccessCache map[string]serviceAccess
+		serviceAccessMutex sync.Mutex
+		// resourceQueueDrained is closed once the resource queue is shut down and drained
+		resourceQueueDrained chan struct{}
+		// virtuals of protectedPartitions are not deleted
+		protectedPartitions map[string]bool
+		// minPartitionsPerPost is the minimum number of partitions with content to post the config
+		minPartitionsPerPost int
+		// bigIPCiphers caches the ciphers of BIG-IP to validate TLSProfiles, nil when validation is disabled
+		bigIPCiphers          *BigIPCiphers
+		cipherRefreshInterval time.Duration
+		// nodeStateSync propagates the states of BIG-IP pool members to the conditions of their pods
+		nodeStateSync bool
+		// ipamLabels caches the IPAM labels to validate the labels of virtuals, nil without IPAM
+		ipamLabels               *IPAMLabelCache
+		ipamLabelRefreshInterval time.Duration
+		// quotaCMKey is the namespace/name of the ConfigMap with the quotas of namespaces
+		quotaCMKey string
+		// quotaCMInformer watches the ConfigMap of quotaCMKey
+		quotaCMInformer cache.SharedIndexInformer
+		// nsPartitionInformer caches the BIG-IP partition annotations of namespaces without namespace label
+		nsPartitionInformer cache.SharedIndexInformer
+		// certValidationTimeout is the timeout to validate the hostname of certificates, 0 means no timeout
+		certValidationTimeout time.Duration
+		// skipCertHostCheck accepts the certificates without validating their hostname
+		skipCertHostCheck bool
+		// controllerIdentifier distinguishes the route admit statuses and events of multiple CIS instances
+		controllerIdentifier string
+		// datacenterWatch starts watching the GTM datacenters of BIG-IP once on processing ExternalDNS
+		datacenterWatch sync.Once
+		// federationCMKey is the namespace/name of the ConfigMap sharing the IngressLink GTM pool members
+		federationCMKey string
+		// federationCMInformer watches the ConfigMap of federationCMKey
+		federationCMInformer cache.SharedIndexInformer
+		// as3ApplicationLabel is the label of VirtualServers with the name of their AS3 Application
+		as3ApplicationLabel string
+		// monitorIntervalPoolSizeBase is the pool size per multiple of the monitor interval, 0 disables the scaling
+		monitorIntervalPoolSizeBase int
+		// bigipPodCIDR is the CIDR of BIG-IP checked against the NetworkPolicies of pool members, nil disables the check
+		bigipPodCIDR *net.IPNet
+		// resources retried beyond retryWarningThreshold raise warnings, those retried beyond
+		// maxRetries are dropped, 0 disables them
+		retryWarningThreshold int
+		maxRetries            int
+		// dryRun processes the resources without posting the configuration to BIG-IP
+		dryRun bool
+		// certVersions holds the resource versions of the processed secrets of SSL profiles,
+		// which are reconciled with the informers every certReconcileInterval
+		certVersions          *certVersionCache
+		certReconcileInterval time.Duration
 		resourceContext
 	}
 	resourceContext struct {
@@ -95,23 +171,79 @@ type (
 
 	// Params defines parameters
 	Params struct {
-		Config             *rest.Config
-		Namespaces         []string
-		NamespaceLabel     string
-		Partition          string
-		Agent              *Agent
-		PoolMemberType     string
-		VXLANName          string
-		VXLANMode          string
-		UseNodeInternal    bool
-		NodePollInterval   int
-		NodeLabelSelector  string
-		ShareNodes         bool
-		IPAM               bool
-		DefaultRouteDomain int
-		Mode               ControllerMode
-		RouteSpecConfigmap string
-		RouteLabel         string
+		Config                  *rest.Config
+		Namespaces              []string
+		NamespaceLabel          string
+		Partition               string
+		Agent                   *Agent
+		PoolMemberType          string
+		VXLANName               string
+		VXLANMode               string
+		UseNodeInternal         bool
+		NodePollInterval        int
+		NodeLabelSelector       string
+		ShareNodes              bool
+		IPAM                    bool
+		DefaultRouteDomain      int
+		NamespaceRouteDomains   map[string]int
+		IPAMLabelNamespaces     map[string]string
+		Mode                    ControllerMode
+		RouteSpecConfigmap      string
+		RouteLabel              string
+		ExcludeTaintedNodes     bool
+		TopologyAwareRouting    bool
+		PriorityGroupLabel      string
+		BigIPCredentialSecrets  map[string]string
+		EnableRollback          bool
+		RollbackHistoryDepth    int
+		CheckpointCM            string
+		GRPCMonitorScriptPath   string
+		AdmissionWebhookAddress string
+		AdmissionWebhookCert    string
+		AdmissionWebhookKey     string
+		ExcludeNamespaces       []string
+		ShutdownTimeout         time.Duration
+		ResyncInterval          time.Duration
+		PartitionDefaultsCM     string
+		ProtectedPartitions     []string
+		MinPartitionsPerPost    int
+		ValidateBigIPCiphers    bool
+		CipherRefreshInterval   time.Duration
+		SyncBigIPNodeState      bool
+		QuotaCM                 string
+		CertValidationTimeout   time.Duration
+		SkipCertHostCheck       bool
+		// AllowedVirtualServerCIDRs restricts the virtual server addresses to given CIDRs
+		AllowedVirtualServerCIDRs []string
+		// IPAMLabelRefreshInterval is the interval to refresh the IPAM labels of IPAM CR
+		IPAMLabelRefreshInterval time.Duration
+		// ControllerIdentifier identifies this CIS instance among the ones sharing the cluster
+		ControllerIdentifier string
+		// IngressLinkFederationCM is the namespace/name of the ConfigMap where the CIS instances
+		// share the GTM pool members of IngressLinks with clusterMember
+		IngressLinkFederationCM string
+		// AS3ApplicationLabel is the label key of VirtualServers whose value is the AS3 Application
+		// of their virtual servers within the tenant
+		AS3ApplicationLabel string
+		// AutoScaleMonitorInterval scales the monitor interval of VirtualServer pools with the pool size,
+		// by a multiple for each MonitorPoolSizeBase members
+		AutoScaleMonitorInterval bool
+		MonitorPoolSizeBase      int
+		// RespectNetworkPolicies disables the pool members whose pods are not allowed by the
+		// NetworkPolicies to receive ingress traffic from BigIPPodCIDR
+		RespectNetworkPolicies bool
+		BigIPPodCIDR           string
+		// RetryWarningThreshold and MaxRetries are the retries of resources from which warnings
+		// are raised and after which the resources are dropped respectively
+		RetryWarningThreshold int
+		MaxRetries            int
+		// DryRun logs and records the names of BIG-IP objects of VirtualServers without posting them
+		DryRun bool
+		// CertReconcileInterval is the interval to reconcile the secrets of SSL profiles, 0 disables it
+		CertReconcileInterval time.Duration
+		// MetricsPort is the port serving the Prometheus metrics, 0 serves them only on the
+		// http-listen-address of Agent
+		MetricsPort int
 	}
 
 	// CRInformer defines the structure of Custom Resource Informer
@@ -134,6 +266,7 @@ type (
 		podInformer     cache.SharedIndexInformer
 		secretsInformer cache.SharedIndexInformer
 		nodeInformer    cache.SharedIndexInformer
+		netPolInformer  cache.SharedIndexInformer
 	}
 
 	// NRInformer is informer context for Native Resources of Kubernetes/Openshift
@@ -154,6 +287,8 @@ type (
 		rscName   string
 		rsc       interface{}
 		event     string
+		// retryCount is the number of retries of the key on retryable errors
+		retryCount int
 	}
 
 	metaData struct {
@@ -165,6 +300,8 @@ type (
 		hosts         []string
 		Protocol      string
 		httpTraffic   string
+		// Application is the AS3 Application of the virtual, empty for the Shared application
+		Application string
 	}
 
 	// Virtual Server Key - unique server is Name + Port
@@ -194,8 +331,12 @@ type (
 		LogProfiles            []string              `json:"logProfiles,omitempty"`
 		ProfileL4              string                `json:"profileL4,omitempty"`
 		ProfileMultiplex       string                `json:"profileMultiplex,omitempty"`
+		ProfileMultiplexEnable bool                  `json:"-"`
+		MultiplexSourceMask    string                `json:"multiplexSourceMask,omitempty"`
 		ProfileDOS             string                `json:"profileDOS,omitempty"`
+		ProfileDOSNetwork      string                `json:"profileDOSNetwork,omitempty"`
 		ProfileBotDefense      string                `json:"profileBotDefense,omitempty"`
+		ProfileWebAcceleration string                `json:"profileWebAcceleration,omitempty"`
 		TCP                    ProfileTCP            `json:"tcp,omitempty"`
 		Mode                   string                `json:"mode,omitempty"`
 		TranslateServerAddress bool                  `json:"translateServerAddress"`
@@ -206,15 +347,65 @@ type (
 		TLSTermination         string                `json:"-"`
 		AllowSourceRange       []string              `json:"allowSourceRange,omitempty"`
 		HttpMrfRoutingEnabled  bool                  `json:"httpMrfRoutingEnabled,omitempty"`
+		DNS64Prefix            string                `json:"dns64Prefix,omitempty"`
+		ClientCACert           string                `json:"-"`
+		PersistenceHashKey     *PersistenceHashKey   `json:"persistenceHashKey,omitempty"`
+		HSTS                   *HSTS                 `json:"hsts,omitempty"`
+		InsertHeaders          []HTTPHeader          `json:"insertHeaders,omitempty"`
+		RequestTimeout         int32                 `json:"requestTimeout,omitempty"`
+		SSLO                   *SSLOTopology         `json:"sslo,omitempty"`
+		PolicyEndpointAccess   string                `json:"policyEndpointAccess,omitempty"`
+		ProfileAnalytics       string                `json:"profileAnalytics,omitempty"`
+		Analytics              *Analytics            `json:"analytics,omitempty"`
+		// PersistenceCookie holds the encrypted cookie persistence of the virtual
+		PersistenceCookie *PersistenceCookie `json:"persistenceCookie,omitempty"`
 	}
 	// Virtuals is slice of virtuals
 	Virtuals []Virtual
 
+	// Analytics holds the analytics profile created for a virtual
+	Analytics struct {
+		LoggingDestination string `json:"loggingDestination,omitempty"`
+	}
+
 	ProfileTCP struct {
 		Client string `json:"client,omitempty"`
 		Server string `json:"server,omitempty"`
 	}
 
+	// PersistenceCookie holds the passphrase used to encrypt the persistence cookies,
+	// the passphrase is not marshalled to keep it out of the logs
+	PersistenceCookie struct {
+		Encrypt    bool   `json:"encrypt,omitempty"`
+		Passphrase string `json:"-"`
+	}
+
+	// PersistenceHashKey holds the key used by an inline hash persistence profile
+	PersistenceHashKey struct {
+		Type       string `json:"type,omitempty"`
+		HeaderName string `json:"headerName,omitempty"`
+		Length     int    `json:"length,omitempty"`
+	}
+
+	// HTTPHeader is a header inserted in the requests by the HTTP profile of a virtual
+	HTTPHeader struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	// SSLOTopology holds the SSL Orchestrator topology a virtual is attached to
+	SSLOTopology struct {
+		ServiceChain     string   `json:"serviceChain"`
+		TopologyType     string   `json:"topologyType,omitempty"`
+		SecurityServices []string `json:"securityServices,omitempty"`
+	}
+
+	// HSTS holds the HTTP Strict Transport Security settings of a virtual
+	HSTS struct {
+		MaxAge            int  `json:"maxAge,omitempty"`
+		IncludeSubDomains bool `json:"includeSubDomains,omitempty"`
+	}
+
 	// ServiceAddress Service IP address definition (BIG-IP virtual-address).
 	ServiceAddress struct {
 		ArpEnabled         bool   `json:"arpEnabled,omitempty"`
@@ -232,8 +423,9 @@ type (
 
 	// frontend bindaddr and port
 	virtualAddress struct {
-		BindAddr string `json:"bindAddr,omitempty"`
-		Port     int32  `json:"port,omitempty"`
+		BindAddr    string `json:"bindAddr,omitempty"`
+		Port        int32  `json:"port,omitempty"`
+		RouteDomain int    `json:"routeDomain,omitempty"`
 	}
 
 	// nameRef is virtual server policy/profile reference
@@ -264,6 +456,12 @@ type (
 		gtmConfig      GTMConfig
 		gtmConfigCache GTMConfig
 		nplStore       NPLStore
+		// resyncPending forces the post of all partitions on next update
+		resyncPending bool
+		// conflictingNames holds the resources skipped due to a conflicting virtual server name
+		conflictingNames map[string]resourceRef
+		// gtmDatacenters caches the GTM datacenters of BIG-IP, nil until fetched
+		gtmDatacenters GTMDatacenters
 		supplementContextCache
 	}
 
@@ -295,6 +493,25 @@ type (
 	WideIPs struct {
 		WideIPs []WideIP `json:"wideIPs"`
 	}
+	// FederatedMember is a GSLB pool member of a virtual server on the GTM server of a CIS instance
+	FederatedMember struct {
+		Server        string `json:"server"`
+		VirtualServer string `json:"virtualServer"`
+	}
+
+	// FederatedMembers key is the host of IngressLink
+	FederatedMembers map[string][]FederatedMember
+
+	// GTMDatacenters key is the full path of GTM datacenter
+	GTMDatacenters map[string]GTMDatacenter
+
+	// GTMDatacenter is a GTM datacenter of BIG-IP with the addresses of its GTM servers
+	GTMDatacenter struct {
+		Enabled bool
+		// Servers key is the full path of GTM server
+		Servers map[string][]string
+	}
+
 	// GTMConfig key is PartitionName
 	GTMConfig map[string]GTMPartitionConfig
 
@@ -309,6 +526,15 @@ type (
 		LBMethod   string     `json:"LoadBalancingMode"`
 		Pools      []GSLBPool `json:"pools"`
 		UID        string
+		// DNSListener answers the DNS queries of the WideIP, it is created only with AS3
+		DNSListener *DNSListener `json:"-"`
+	}
+
+	// DNSListener is the BIG-IP DNS listener of WideIP
+	DNSListener struct {
+		Address    string
+		Port       int32
+		UDPProfile string
 	}
 
 	GSLBPool struct {
@@ -319,6 +545,18 @@ type (
 		Members       []string  `json:"members"`
 		Monitors      []Monitor `json:"monitors,omitempty"`
 		DataServer    string
+		// FederatedMembers are the members of the pool contributed by the other CIS instances
+		FederatedMembers []FederatedMember `json:"federatedMembers,omitempty"`
+		// TopologyRecords of the pool when WideIP uses topology load balancing
+		TopologyRecords []TopologyRecord `json:"-"`
+	}
+
+	// TopologyRecord is a GTM topology record of GSLB pool
+	TopologyRecord struct {
+		Source      string
+		Destination string
+		Order       int
+		Region      string
 	}
 
 	ResourceConfigRequest struct {
@@ -327,11 +565,57 @@ type (
 		gtmConfig          GTMConfig
 		defaultRouteDomain int
 		reqId              int
+		// resync posts all the partitions including the ones unchanged since last post
+		resync bool
+		// credentials of partitions posted with BIG-IP credentials other than the global ones
+		credentials map[string]BigIPCredentials
+		// rollbackTenants is the declaration of tenants reposted on rollback instead of the one of ltmConfig
+		rollbackTenants map[string]as3Tenant
+	}
+
+	// PartitionDefault is the default configuration of a BIG-IP partition
+	PartitionDefault struct {
+		Name               string `json:"name"`
+		DefaultRouteDomain int    `json:"defaultRouteDomain"`
+		Description        string `json:"description,omitempty"`
+	}
+
+	// BigIPCiphers are the ciphers and cipher groups of the client SSL profiles on BIG-IP
+	BigIPCiphers struct {
+		sync.RWMutex
+		ciphers      map[string]bool
+		cipherGroups map[string]bool
+	}
+
+	// IPAMLabelCache caches the IPAM labels of IPAM CR to reject the labels
+	// that are requested but never served by the IPAM controller
+	IPAMLabelCache struct {
+		sync.RWMutex
+		allocated map[string]bool
+		pending   map[string]bool
+		invalid   map[string]bool
+	}
+
+	// NamespaceQuota is the maximum number of virtuals allowed in a namespace,
+	// nil quota means no limit
+	NamespaceQuota struct {
+		VirtualServerQuota   *int `json:"virtualServerQuota,omitempty"`
+		TransportServerQuota *int `json:"transportServerQuota,omitempty"`
+	}
+
+	// BigIPCredentials are the credentials to post the declaration to BIG-IP
+	BigIPCredentials struct {
+		Username string
+		Password string
 	}
 
 	resourceStatusMeta struct {
 		id            int
 		failedTenants map[string]struct{}
+		// declarationSizes are the sizes of the declarations of tenants exceeding the size warning threshold
+		declarationSizes map[string]int
+		// removedTenants are the tenants removed from the declaration by self-heal-on-post-error
+		removedTenants map[string]struct{}
 	}
 
 	resourceRef struct {
@@ -353,18 +637,52 @@ type (
 		MonitorNames      []MonitorName      `json:"monitors,omitempty"`
 		ReselectTries     int32              `json:"reselectTries,omitempty"`
 		ServiceDownAction string             `json:"serviceDownAction,omitempty"`
+		WarmupTime        int32              `json:"warmupTime,omitempty"`
+		RequestTimeout    int32              `json:"-"`
+		ExternalMembers   []ExternalMember   `json:"externalMembers,omitempty"`
+		// Shared pool is referred by the virtuals of multiple VirtualServers
+		Shared bool `json:"-"`
+		// SRV record resolved by BIG-IP for the pool members
+		SRVRecord string `json:"srvRecord,omitempty"`
+		SRVPort   int32  `json:"srvPort,omitempty"`
+		// DrainPeriod in seconds of the members of deleted service
+		DrainPeriod int32 `json:"-"`
 	}
 	// Pools is slice of pool
 	Pools []Pool
 
+	// ExternalMember is a pool member outside the cluster resolved by its FQDN on BIG-IP
+	ExternalMember struct {
+		FQDN string `json:"fqdn"`
+		Port int32  `json:"port"`
+	}
+
 	portRef struct {
 		name string
 		port int32
 	}
+	// drainingMemberKey identifies the draining member of the port of service
+	drainingMemberKey struct {
+		portKey portRef
+		member  string
+	}
+	// serviceAccess is the permission to get the services of a namespace, denials are
+	// reviewed again after the expiry
+	serviceAccess struct {
+		allowed bool
+		expiry  time.Time
+	}
+	// drainingMember is a pool member removed from endpoints which is retained till the expiry
+	drainingMember struct {
+		member PoolMember
+		expiry time.Time
+	}
 	poolMembersInfo struct {
 		svcType   v1.ServiceType
 		portSpec  []v1.ServicePort
 		memberMap map[portRef][]PoolMember
+		// backup is set for the services with backup members
+		backup bool
 	}
 
 	// Monitor is Pool health monitor
@@ -378,6 +696,13 @@ type (
 		Timeout    int    `json:"timeout,omitempty"`
 		TargetPort int32  `json:"targetPort,omitempty"`
 		Path       string `json:"path,omitempty"`
+		// ScriptPath and GRPCService are used by external gRPC health monitor
+		ScriptPath  string `json:"scriptPath,omitempty"`
+		GRPCService string `json:"grpcService,omitempty"`
+		// baseInterval and baseTimeout are the configured values of the monitor scaled with pool size
+		baseInterval int
+		baseTimeout  int
+		baseRecorded bool
 	}
 	MonitorName struct {
 		Name string `json:"name"`
@@ -397,6 +722,19 @@ type (
 		// key of the map is IPSpec.Key
 		ipamContext              map[string]ficV1.IPSpec
 		processedNativeResources map[resourceRef]struct{}
+		// memberWarmupStart holds the time at which pool members of a service started warming up
+		memberWarmupStart map[string]map[string]time.Time
+		// warmupUpdates holds the pending pool members update of a service warming up its members
+		warmupUpdates map[string]*rqKey
+		// initialSyncServices holds the services found at the initial sync, whose members are
+		// considered as warmed up when their pools are first processed
+		initialSyncServices map[string]struct{}
+		// sharedPoolRefCount holds the number of virtuals referring the shared pool partition/pool
+		sharedPoolRefCount map[string]int
+		// drainingMembers holds the members of a service removed from endpoints, which are draining connections
+		drainingMembers map[string]map[drainingMemberKey]drainingMember
+		// drainingServices holds the expiry of drain period of the deleted services
+		drainingServices map[string]time.Time
 	}
 
 	// key is group identifier
@@ -444,6 +782,8 @@ type (
 		Reset     bool   `json:"reset,omitempty"`
 		Select    bool   `json:"select,omitempty"`
 		Value     string `json:"value,omitempty"`
+		// Persist is the persistence method of the requests matching the rule
+		Persist string `json:"persist,omitempty"`
 	}
 
 	// condition config for a Rule
@@ -455,6 +795,8 @@ type (
 		EndsWith        bool     `json:"endsWith,omitempty"`
 		External        bool     `json:"external,omitempty"`
 		HTTPHost        bool     `json:"httpHost,omitempty"`
+		HTTPCookie      bool     `json:"httpCookie,omitempty"`
+		CookieName      string   `json:"tmName,omitempty"`
 		Host            bool     `json:"host,omitempty"`
 		HTTPURI         bool     `json:"httpUri,omitempty"`
 		Index           int      `json:"index,omitempty"`
@@ -553,6 +895,9 @@ type (
 		CAFile        string `json:"caFile,omitempty"`
 		ChainCA       string `json:"chainCA,omitempty"`
 		Certificates  []certificate
+		// OCSP responder of the OCSP stapling of client SSL profile
+		OCSPResponderURL string `json:"ocspResponderURL,omitempty"`
+		OCSPTimeout      int    `json:"ocspTimeout,omitempty"`
 	}
 
 	certificate struct {
@@ -571,15 +916,18 @@ type (
 	}
 
 	requestMeta struct {
-		meta      map[string]string
-		partition string
-		id        int
+		meta map[string]string
+		// partitions of the resources in meta
+		partitions map[string]string
+		partition  string
+		id         int
 	}
 
 	Node struct {
 		Name   string
 		Addr   string
 		Labels map[string]string
+		Taints []v1.Taint
 	}
 	//NPL information from pod annotation
 	NPLAnnotation struct {
@@ -623,6 +971,8 @@ type (
 		HttpAddress     string
 		EnableIPV6      bool
 		declUpdate      sync.Mutex
+		// postWg tracks the configs pending to be posted to BIG-IP
+		postWg sync.WaitGroup
 		// cachedTenantDeclMap,incomingTenantDeclMap hold tenant names and corresponding AS3 config
 		cachedTenantDeclMap   map[string]as3Tenant
 		incomingTenantDeclMap map[string]as3Tenant
@@ -631,6 +981,21 @@ type (
 		// retryTenantDeclMap holds tenant name and its agent Config,tenant details
 		retryTenantDeclMap map[string]*tenantParams
 		ccclGTMAgent       bool
+		// secureVault installs TLS keys on BIG-IP when AS3 secrets are encrypted
+		secureVault *SecureVaultClient
+		// postLimiter throttles the configs posted to BIG-IP, configs exceeding
+		// the rate are buffered in throttleChan
+		postLimiter  *rate.Limiter
+		throttleChan chan ResourceConfigRequest
+		// resourceLabels tag the AS3 objects managed by CIS
+		resourceLabels map[string]string
+		cisVersion     string
+		// tenantCredentials are the BIG-IP credentials of tenants of the config being posted
+		tenantCredentials map[string]BigIPCredentials
+		// unsupportedAS3Features are removed from the declaration as AS3 on BIG-IP does not support them
+		unsupportedAS3Features map[string]bool
+		// declHistory retains the posted declarations for rollback, nil without rollback
+		declHistory *declarationHistory
 	}
 
 	AgentParams struct {
@@ -647,6 +1012,9 @@ type (
 		EnableIPV6     bool
 		DisableARP     bool
 		CCCLGTMAgent   bool
+		// ResourceLabels are added as label of the AS3 Virtual, Pool and Monitor objects
+		ResourceLabels map[string]string
+		CISVersion     string
 	}
 
 	PostManager struct {
@@ -654,6 +1022,8 @@ type (
 		tenantResponseMap map[string]tenantResponse
 		PostParams
 		firstPost bool
+		// invalidTenants are the tenants of the last post rejected by AS3 schema validation
+		invalidTenants []string
 	}
 
 	PostParams struct {
@@ -665,6 +1035,40 @@ type (
 		AS3PostDelay  int
 		//Log the AS3 response body in Controller logs
 		LogResponse bool
+		// AS3SchemaVersion is the schemaVersion used in AS3 declaration
+		AS3SchemaVersion string
+		// EncryptAS3Secrets installs TLS keys on BIG-IP instead of posting them in AS3 declaration
+		EncryptAS3Secrets bool
+		// PostRateLimit is the maximum number of configs posted per second, 0 means unlimited
+		PostRateLimit float64
+		// PostBufferDepth is the number of configs buffered when PostRateLimit is exceeded
+		PostBufferDepth int
+		// AS3PostTimeout is the timeout of AS3 requests, AS3ConnectTimeout is the timeout to connect BIG-IP,
+		// AS3ReadTimeout is the timeout to receive the response headers of BIG-IP, 0 waits upto AS3PostTimeout
+		AS3PostTimeout    time.Duration
+		AS3ConnectTimeout time.Duration
+		AS3ReadTimeout    time.Duration
+		// AS3PostRetries is the number of retries of AS3 posts failed with network errors or 5xx responses,
+		// AS3MaxBackoff caps the exponential backoff between the retries
+		AS3PostRetries int
+		AS3MaxBackoff  time.Duration
+		// CompressAS3 posts gzip compressed AS3 declarations of size CompressThreshold bytes or more
+		CompressAS3       bool
+		CompressThreshold int
+		// BIGIPClientCert and BIGIPClientKey are the files of client certificate for mutual TLS with BIG-IP,
+		// BIGIPCACert is the file of CA certificate verifying the server certificate of BIG-IP
+		BIGIPClientCert string
+		BIGIPClientKey  string
+		BIGIPCACert     string
+		// AutoNegotiateAS3Version disables the features not supported by AS3 version of BIG-IP
+		AutoNegotiateAS3Version bool
+		// DeclarationSizeWarn is the size of AS3 declarations in bytes from which a warning is raised,
+		// declarations larger than DeclarationSizeLimit bytes are not posted, 0 disables them
+		DeclarationSizeWarn  int
+		DeclarationSizeLimit int
+		// SelfHealOnPostError removes the tenants rejected by AS3 schema validation from the
+		// declaration and reposts the remaining tenants
+		SelfHealOnPostError bool
 	}
 
 	GTMParams struct {
@@ -687,6 +1091,10 @@ type (
 		data      string
 		as3APIURL string
 		id        int
+		// credentials to post the config, global credentials are used when not set
+		credentials *BigIPCredentials
+		// tenants in the config
+		tenants []string
 	}
 
 	globalSection struct {
@@ -760,6 +1168,11 @@ type (
 		Enabled  *bool                   `json:"enabled,omitempty"`
 		Location string                  `json:"location,omitempty"`
 		Replace  *as3ActionReplaceMap    `json:"replace,omitempty"`
+		// persistence methods of persist action
+		CookieInsert       *as3PersistCookie  `json:"cookieInsert,omitempty"`
+		SourceAddress      *as3PersistAddress `json:"sourceAddress,omitempty"`
+		DestinationAddress *as3PersistAddress `json:"destinationAddress,omitempty"`
+		Disable            *struct{}          `json:"disable,omitempty"`
 	}
 
 	as3ActionReplaceMap struct {
@@ -788,6 +1201,18 @@ type (
 		Service *as3ResourcePointer `json:"service,omitempty"`
 	}
 
+	// as3PersistCookie maps to cookieInsert of Policy_Action_Persist in AS3 Resources
+	as3PersistCookie struct {
+		Name   string `json:"name"`
+		Expiry string `json:"expiry"`
+	}
+
+	// as3PersistAddress maps to sourceAddress and destinationAddress of Policy_Action_Persist in AS3 Resources
+	as3PersistAddress struct {
+		Netmask string `json:"netmask"`
+		Timeout int    `json:"timeout"`
+	}
+
 	// as3MultiTypeParam can be used for parameters that accept values of different types
 	// Eg: profileHTTP (string | Service_HTTP_profileHTTP) in Service_HTTP in AS3 Resources
 	as3MultiTypeParam interface{}
@@ -807,6 +1232,8 @@ type (
 	// as3Pool maps to Pool in AS3 Resources
 	as3Pool struct {
 		Class             string               `json:"class,omitempty"`
+		Label             string               `json:"label,omitempty"`
+		Remark            string               `json:"remark,omitempty"`
 		LoadBalancingMode string               `json:"loadBalancingMode,omitempty"`
 		Members           []as3PoolMember      `json:"members,omitempty"`
 		Monitors          []as3ResourcePointer `json:"monitors,omitempty"`
@@ -818,8 +1245,13 @@ type (
 	as3PoolMember struct {
 		AddressDiscovery string   `json:"addressDiscovery,omitempty"`
 		ServerAddresses  []string `json:"serverAddresses,omitempty"`
+		Hostname         string   `json:"hostname,omitempty"`
+		AutoPopulate     bool     `json:"autoPopulate,omitempty"`
 		ServicePort      int32    `json:"servicePort,omitempty"`
 		ShareNodes       bool     `json:"shareNodes,omitempty"`
+		Ratio            *int     `json:"ratio,omitempty"`
+		PriorityGroup    int32    `json:"priorityGroup,omitempty"`
+		AdminState       string   `json:"adminState,omitempty"`
 	}
 
 	// as3ResourcePointer maps to following in AS3 Resources
@@ -839,6 +1271,8 @@ type (
 	// - Service_TCP
 	// - Service_UDP
 	as3Service struct {
+		Label                  string               `json:"label,omitempty"`
+		Remark                 string               `json:"remark,omitempty"`
 		Layer4                 string               `json:"layer4,omitempty"`
 		Source                 string               `json:"source,omitempty"`
 		TranslateServerAddress bool                 `json:"translateServerAddress,omitempty"`
@@ -861,12 +1295,18 @@ type (
 		PersistenceMethods     *[]as3MultiTypeParam `json:"persistenceMethods,omitempty"`
 		ProfileTCP             as3MultiTypeParam    `json:"profileTCP,omitempty"`
 		ProfileUDP             as3MultiTypeParam    `json:"profileUDP,omitempty"`
+		ProfileDNS             as3MultiTypeParam    `json:"profileDNS,omitempty"`
 		ProfileHTTP            as3MultiTypeParam    `json:"profileHTTP,omitempty"`
 		ProfileHTTP2           as3MultiTypeParam    `json:"profileHTTP2,omitempty"`
 		ProfileMultiplex       as3MultiTypeParam    `json:"profileMultiplex,omitempty"`
 		ProfileDOS             as3MultiTypeParam    `json:"profileDOS,omitempty"`
 		ProfileBotDefense      as3MultiTypeParam    `json:"profileBotDefense,omitempty"`
+		ProfileHTTPAccel       as3MultiTypeParam    `json:"profileHTTPAcceleration,omitempty"`
+		ProfileAccess          as3MultiTypeParam    `json:"profileAccess,omitempty"`
+		PolicyPerRequestAccess as3MultiTypeParam    `json:"policyPerRequestAccess,omitempty"`
+		ProfileAnalytics       as3MultiTypeParam    `json:"profileAnalytics,omitempty"`
 		HttpMrfRoutingEnabled  bool                 `json:"httpMrfRoutingEnabled,omitempty"`
+		Nat64Enabled           bool                 `json:"nat64Enabled,omitempty"`
 	}
 
 	// as3ServiceAddress maps to VirtualAddress in AS3 Resources
@@ -886,6 +1326,8 @@ type (
 	// - Monitor_HTTPS
 	as3Monitor struct {
 		Class             string  `json:"class,omitempty"`
+		Label             string  `json:"label,omitempty"`
+		Remark            string  `json:"remark,omitempty"`
 		Interval          int     `json:"interval,omitempty"`
 		MonitorType       string  `json:"monitorType,omitempty"`
 		TargetAddress     *string `json:"targetAddress,omitempty"`
@@ -898,6 +1340,66 @@ type (
 		TargetPort        int32   `json:"targetPort,omitempty"`
 		ClientCertificate string  `json:"clientCertificate,omitempty"`
 		Ciphers           string  `json:"ciphers,omitempty"`
+		// Pathname and EnvironmentVariables are used by external monitor
+		Pathname             string            `json:"pathname,omitempty"`
+		EnvironmentVariables map[string]string `json:"environmentVariables,omitempty"`
+	}
+
+	// as3Persist maps to Persist in AS3 Resources
+	as3Persist struct {
+		Class             string `json:"class,omitempty"`
+		PersistenceMethod string `json:"persistenceMethod,omitempty"`
+		HashStartPattern  string `json:"hashStartPattern,omitempty"`
+		HashEndPattern    string `json:"hashEndPattern,omitempty"`
+		HashLength        int    `json:"hashLength,omitempty"`
+		// cookie persistence with encryption
+		CookieMethod     string     `json:"cookieMethod,omitempty"`
+		Encrypt          bool       `json:"encrypt,omitempty"`
+		CookieEncryption string     `json:"cookieEncryption,omitempty"`
+		Passphrase       *as3Secret `json:"passphrase,omitempty"`
+	}
+
+	// as3Secret maps to Secret in AS3 Resources
+	as3Secret struct {
+		Ciphertext string `json:"ciphertext"`
+		Protected  string `json:"protected,omitempty"`
+	}
+
+	// as3DNSProfile maps to DNS_Profile in AS3 Resources
+	as3DNSProfile struct {
+		Class       string `json:"class,omitempty"`
+		DNS64Mode   string `json:"dns64Mode,omitempty"`
+		DNS64Prefix string `json:"dns64Prefix,omitempty"`
+	}
+
+	// as3MultiplexProfile maps to Multiplex_Profile in AS3 Resources
+	as3MultiplexProfile struct {
+		Class      string `json:"class,omitempty"`
+		SourceMask string `json:"sourceMask,omitempty"`
+	}
+
+	// as3AnalyticsProfile maps to Analytics_Profile in AS3 Resources
+	as3AnalyticsProfile struct {
+		Class                         string              `json:"class,omitempty"`
+		CollectedStatsInternalLogging bool                `json:"collectedStatsInternalLogging"`
+		CollectedStatsExternalLogging bool                `json:"collectedStatsExternalLogging"`
+		ExternalLoggingPublisher      *as3ResourcePointer `json:"externalLoggingPublisher,omitempty"`
+	}
+
+	// as3HTTPProfile maps to HTTP_Profile in AS3 Resources
+	as3HTTPProfile struct {
+		Class                 string               `json:"class,omitempty"`
+		HSTSInsert            bool                 `json:"hstsInsert,omitempty"`
+		HSTSPeriod            int                  `json:"hstsPeriod,omitempty"`
+		HSTSIncludeSubdomains bool                 `json:"hstsIncludeSubdomains,omitempty"`
+		InsertHeader          *as3HTTPInsertHeader `json:"insertHeader,omitempty"`
+		RequestTimeout        int32                `json:"requestTimeout,omitempty"`
+	}
+
+	// as3HTTPInsertHeader maps to insertHeader of HTTP_Profile in AS3 Resources
+	as3HTTPInsertHeader struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
 	}
 
 	// as3CABundle maps to CA_Bundle in AS3 Resources
@@ -908,10 +1410,18 @@ type (
 
 	// as3Certificate maps to Certificate in AS3 Resources
 	as3Certificate struct {
-		Class       string            `json:"class,omitempty"`
-		Certificate as3MultiTypeParam `json:"certificate,omitempty"`
-		PrivateKey  as3MultiTypeParam `json:"privateKey,omitempty"`
-		ChainCA     as3MultiTypeParam `json:"chainCA,omitempty"`
+		Class       string              `json:"class,omitempty"`
+		Certificate as3MultiTypeParam   `json:"certificate,omitempty"`
+		PrivateKey  as3MultiTypeParam   `json:"privateKey,omitempty"`
+		ChainCA     as3MultiTypeParam   `json:"chainCA,omitempty"`
+		StaplerOCSP *as3ResourcePointer `json:"staplerOCSP,omitempty"`
+	}
+
+	// as3CertificateValidatorOCSP maps to Certificate_Validator_OCSP in AS3 Resources
+	as3CertificateValidatorOCSP struct {
+		Class        string `json:"class,omitempty"`
+		ResponderURL string `json:"responderUrl,omitempty"`
+		Timeout      int    `json:"timeout,omitempty"`
 	}
 
 	// as3TLSServer maps to TLS_Server in AS3 Resources
@@ -921,6 +1431,10 @@ type (
 		Ciphers       string                     `json:"ciphers,omitempty"`
 		CipherGroup   *as3ResourcePointer        `json:"cipherGroup,omitempty"`
 		TLS1_3Enabled bool                       `json:"tls1_3Enabled,omitempty"`
+		// AuthenticationMode and AuthenticationTrustCA configure client certificate authentication
+		AuthenticationMode    string              `json:"authenticationMode,omitempty"`
+		AuthenticationTrustCA *as3ResourcePointer `json:"authenticationTrustCA,omitempty"`
+		StaplerOCSPEnabled    bool                `json:"staplerOCSPEnabled,omitempty"`
 	}
 
 	// as3TLSServerCertificates maps to TLS_Server_certificates in AS3 Resources
@@ -958,10 +1472,19 @@ type (
 	}
 
 	PoolMember struct {
-		Address string `json:"address"`
-		Port    int32  `json:"port"`
-		SvcPort int32  `json:"svcPort,omitempty"`
-		Session string `json:"session,omitempty"`
+		Address  string `json:"address"`
+		Port     int32  `json:"port"`
+		SvcPort  int32  `json:"svcPort,omitempty"`
+		Session  string `json:"session,omitempty"`
+		NodeName string `json:"-"`
+		Zone     string `json:"-"`
+		Ratio    int    `json:"ratio,omitempty"`
+		// RouteDomain of member address, which is mapped from the namespace of pod
+		RouteDomain int `json:"-"`
+		// PriorityGroup of member, traffic is sent to the available members of highest priority group
+		PriorityGroup int32 `json:"priorityGroup,omitempty"`
+		// Backup member gets the traffic only when the primary members of pool are not available
+		Backup bool `json:"-"`
 	}
 )
 
@@ -986,10 +1509,35 @@ type (
 		Class      string               `json:"class"`
 		RecordType string               `json:"resourceRecordType"`
 		LBMode     string               `json:"lbModeAlternate"`
-		Members    []as3GSLBPoolMemberA `json:"members"`
+		Members    []interface{}        `json:"members"`
 		Monitors   []as3ResourcePointer `json:"monitors"`
 	}
 
+	// as3GSLBTopologyRecords maps to GSLB_Topology_Records in AS3 Resources
+	as3GSLBTopologyRecords struct {
+		Class               string                  `json:"class"`
+		LongestMatchEnabled bool                    `json:"longestMatchEnabled"`
+		Records             []as3GSLBTopologyRecord `json:"records"`
+	}
+
+	// as3GSLBTopologyRecord maps to GSLB_Topology_Record in AS3 Resources
+	as3GSLBTopologyRecord struct {
+		Source      as3GSLBTopologyMatch `json:"source"`
+		Destination as3GSLBTopologyMatch `json:"destination"`
+	}
+
+	// as3GSLBTopologyRegion maps to GSLB_Topology_Region in AS3 Resources
+	as3GSLBTopologyRegion struct {
+		Class   string                 `json:"class"`
+		Members []as3GSLBTopologyMatch `json:"members"`
+	}
+
+	// as3GSLBTopologyMatch maps to source and destination of GSLB_Topology_Record in AS3 Resources
+	as3GSLBTopologyMatch struct {
+		MatchType  string      `json:"matchType"`
+		MatchValue interface{} `json:"matchValue"`
+	}
+
 	// as3GSLBPoolMemberA maps to GSLB_Pool_Member_A in AS3 Resources
 	as3GSLBPoolMemberA struct {
 		Enabled       bool               `json:"enabled"`
@@ -997,6 +1545,12 @@ type (
 		VirtualServer string             `json:"virtualServer"`
 	}
 
+	// as3GSLBPoolMemberCNAME maps to GSLB_Pool_Member_CNAME in AS3 Resources
+	as3GSLBPoolMemberCNAME struct {
+		Enabled    bool   `json:"enabled"`
+		DomainName string `json:"domainName"`
+	}
+
 	as3GSLBMonitor struct {
 		Class    string `json:"class"`
 		Interval int    `json:"interval"`
@@ -1081,11 +1635,16 @@ type (
 	}
 
 	ExtendedRouteGroupSpec struct {
-		VServerName   string `yaml:"vserverName"`
-		VServerAddr   string `yaml:"vserverAddr"`
-		AllowOverride string `yaml:"allowOverride"`
-		Policy        string `yaml:"policyCR,omitempty"`
-		Meta          Meta
+		VServerName           string `yaml:"vserverName"`
+		VServerAddr           string `yaml:"vserverAddr"`
+		AllowOverride         string `yaml:"allowOverride"`
+		Policy                string `yaml:"policyCR,omitempty"`
+		ClientCACert          string `yaml:"clientCACert,omitempty"`
+		ClientCACertNamespace string `yaml:"clientCACertNamespace,omitempty"`
+		Meta                  Meta
+		// PassthroughPersistence persists the passthrough connections on the pool member selected
+		// by the hash of SNI
+		PassthroughPersistence bool `yaml:"passthroughPersistence,omitempty"`
 	}
 
 	Meta struct {
@@ -17,12 +17,27 @@
 package controller
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
 
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
 	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	authv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+// serviceAccessDeniedTTL is the time for which a denied permission to get the services of
+// a namespace is cached, the permissions granted later are honoured after it
+const serviceAccessDeniedTTL = time.Minute
+
+// srvRecordRegex matches the DNS SRV record names, e.g. _http._tcp.example.com
+var srvRecordRegex = regexp.MustCompile(`^(_?[a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([-a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+
 func (ctlr *Controller) checkValidVirtualServer(
 	vsResource *cisapiv1.VirtualServer,
 ) bool {
@@ -48,7 +63,78 @@ func (ctlr *Controller) checkValidVirtualServer(
 		return false
 	}
 
-	bindAddr := vsResource.Spec.VirtualServerAddress
+	// requestTimeout is applied on HTTP requests, which passthrough VS does not process
+	for _, pool := range vsResource.Spec.Pools {
+		if pool.RequestTimeout == 0 {
+			continue
+		}
+		if isPassthroughVirtualServer(crInf, vsResource) {
+			log.Errorf("requestTimeout not allowed to be set for pools of passthrough VirtualServer: %v", vsName)
+			return false
+		}
+		break
+	}
+
+	// Host header is rewritten in HTTP requests, which passthrough VS does not process
+	for _, pool := range vsResource.Spec.Pools {
+		if pool.HostHeaderRewrite == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(strings.ToLower(pool.HostHeaderRewrite)); len(errs) > 0 {
+			log.Errorf("Invalid hostHeaderRewrite %v in pool %v of virtual server %s: %v",
+				pool.HostHeaderRewrite, pool.Path, vsName, strings.Join(errs, ", "))
+			return false
+		}
+		if isPassthroughVirtualServer(crInf, vsResource) {
+			log.Errorf("hostHeaderRewrite not allowed to be set for pools of passthrough VirtualServer: %v", vsName)
+			return false
+		}
+	}
+
+	// monitors of pool with monitorDisabled are ignored
+	for _, pool := range vsResource.Spec.Pools {
+		if pool.MonitorDisabled && (pool.Monitor.Type != "" || pool.Monitor.Name != "" || len(pool.Monitors) > 0) {
+			log.Warningf("Ignoring monitors of pool %v in VirtualServer %v as monitorDisabled is set",
+				pool.Path, vsName)
+		}
+	}
+
+	// gRPC monitors are external monitors invoking the gRPC health check script on BIG-IP
+	if ctlr.grpcMonitorScriptPath == "" && hasGRPCMonitor(vsResource) {
+		log.Errorf("gRPC monitor not allowed for VirtualServer %v as --grpc-monitor-script-path is not set", vsName)
+		return false
+	}
+
+	// Content-Security-Policy header is inserted in HTTP responses, which passthrough VS does not process
+	if csp, ok := vsResource.Annotations[ContentSecurityPolicyAnnotation]; ok {
+		if isPassthroughVirtualServer(crInf, vsResource) {
+			log.Errorf("%v not allowed to be set for passthrough VirtualServer: %v",
+				ContentSecurityPolicyAnnotation, vsName)
+			return false
+		}
+		if !isValidContentSecurityPolicy(csp) {
+			log.Errorf("Invalid %v %q for VirtualServer: %v", ContentSecurityPolicyAnnotation, csp, vsName)
+			return false
+		}
+	}
+
+	// request body is rewritten in HTTP requests, which passthrough VS does not process
+	if rewrite := vsResource.Spec.RequestBodyRewrite; rewrite != nil {
+		if isPassthroughVirtualServer(crInf, vsResource) {
+			log.Errorf("requestBodyRewrite not allowed to be set for passthrough VirtualServer: %v", vsName)
+			return false
+		}
+		if err := validateBodyRewrite(rewrite); err != nil {
+			log.Errorf("Invalid requestBodyRewrite for VirtualServer %v: %v", vsName, err)
+			return false
+		}
+	}
+
+	var bindAddr string
+	bindAddrs := getVirtualServerAddresses(vsResource)
+	if len(bindAddrs) > 0 {
+		bindAddr = bindAddrs[0]
+	}
 	if ctlr.ipamCli == nil {
 
 		// This ensures that pool-only mode only logs the message below the first
@@ -65,9 +151,201 @@ func (ctlr *Controller) checkValidVirtualServer(
 		}
 	}
 
+	if partition := vsResource.Annotations[PartitionAnnotation]; partition != "" && !isValidPartition(partition) {
+		log.Errorf("Invalid %v annotation %v for virtual server %s", PartitionAnnotation, partition, vsName)
+		return false
+	}
+
+	if vsResource.Spec.DNS64Prefix != "" {
+		if !isValidDNS64Prefix(vsResource.Spec.DNS64Prefix) {
+			log.Errorf("Invalid dns64Prefix %v for virtual server %s, it must be an IPv6 /96 prefix",
+				vsResource.Spec.DNS64Prefix, vsName)
+			return false
+		}
+		if !ctlr.hasIPv4PoolMembersOnly(vsResource) {
+			log.Errorf("Pool members of virtual server %s must be IPv4 for dns64Prefix", vsName)
+			return false
+		}
+	}
+
+	for _, pool := range vsResource.Spec.Pools {
+		if !isValidLoadBalancingMethod(pool.Balance) {
+			log.Errorf("Invalid loadBalancingMethod %v in pool %v of virtual server %s",
+				pool.Balance, pool.Path, vsName)
+			return false
+		}
+		if err := validatePoolWarmup(pool.Balance, pool.WarmupTime); err != nil {
+			log.Errorf("Invalid pool %v of virtual server %s: %v", pool.Path, vsName, err)
+			return false
+		}
+		svcNamespace := vsResource.Namespace
+		if pool.ServiceNamespace != "" {
+			svcNamespace = pool.ServiceNamespace
+		}
+		if _, err := ctlr.resolveServicePort(svcNamespace, pool.Service, pool.ServicePort); err != nil {
+			log.Errorf("Invalid servicePort in pool %v of virtual server %s: %v", pool.Path, vsName, err)
+			return false
+		}
+		if _, err := getPoolNodeMemberLabel(pool); err != nil {
+			log.Errorf("Invalid node member selector in pool %v of virtual server %s: %v", pool.Path, vsName, err)
+			return false
+		}
+		if !isValidPoolPersistenceProfile(pool.PersistenceProfile) {
+			log.Errorf("Invalid persistenceProfile %v in pool %v of virtual server %s. Supported values are "+
+				"cookie, destination-address, source-address and none", pool.PersistenceProfile, pool.Path, vsName)
+			return false
+		}
+		for _, em := range pool.ExternalMembers {
+			if errs := validation.IsDNS1123Subdomain(strings.ToLower(em.FQDN)); len(errs) > 0 {
+				log.Errorf("Invalid FQDN %v of external member in pool %v of virtual server %s: %v",
+					em.FQDN, pool.Path, vsName, strings.Join(errs, ", "))
+				return false
+			}
+			if em.Port < 1 || em.Port > 65535 {
+				log.Errorf("Invalid port %v of external member %v in pool %v of virtual server %s",
+					em.Port, em.FQDN, pool.Path, vsName)
+				return false
+			}
+		}
+		if pool.SRVRecord != "" {
+			if !srvRecordRegex.MatchString(pool.SRVRecord) {
+				log.Errorf("Invalid srvRecord %v in pool %v of virtual server %s", pool.SRVRecord, pool.Path, vsName)
+				return false
+			}
+			if pool.SRVPort < 1 || pool.SRVPort > 65535 {
+				log.Errorf("Invalid srvPort %v of srvRecord %v in pool %v of virtual server %s",
+					pool.SRVPort, pool.SRVRecord, pool.Path, vsName)
+				return false
+			}
+		}
+	}
+
+	// services of other namespaces are referred only when CIS is allowed to get them
+	for _, pool := range vsResource.Spec.Pools {
+		if pool.ServiceNamespace == "" || pool.ServiceNamespace == vsNamespace {
+			continue
+		}
+		if !ctlr.canGetServices(pool.ServiceNamespace) {
+			log.Errorf("Service %v/%v of virtual server %s is not allowed, CIS does not have "+
+				"permission to get services in namespace %v", pool.ServiceNamespace, pool.Service, vsName,
+				pool.ServiceNamespace)
+			return false
+		}
+	}
+
+	// WebSocket upgrade is handled by an iRule on HTTP profile, which passthrough VS does not have
+	if vsResource.Spec.WebSocketEnabled && isPassthroughVirtualServer(crInf, vsResource) {
+		log.Errorf("webSocketEnabled not allowed to be set for passthrough VirtualServer: %v", vsName)
+		return false
+	}
+
+	if len(vsResource.Spec.InsertHeaders) > 0 && isPassthroughVirtualServer(crInf, vsResource) {
+		log.Errorf("insertHeaders not allowed to be set for passthrough VirtualServer: %v", vsName)
+		return false
+	}
+	for _, header := range vsResource.Spec.InsertHeaders {
+		if !isValidHTTPHeaderName(header.Name) {
+			log.Errorf("Invalid header name %q in insertHeaders of virtual server %s", header.Name, vsName)
+			return false
+		}
+		if strings.TrimSpace(header.Value) == "" || strings.ContainsAny(header.Value, "\r\n") {
+			log.Errorf("Invalid value %q of header %v in insertHeaders of virtual server %s",
+				header.Value, header.Name, vsName)
+			return false
+		}
+	}
+
+	for _, cr := range vsResource.Spec.CookieRoutes {
+		if cr.CookieName == "" {
+			log.Errorf("cookieName is required in cookieRoutes of virtual server %s", vsName)
+			return false
+		}
+		if !hasPoolName(vsResource.Spec.Pools, cr.Pool) {
+			log.Errorf("Pool %v of cookie %v not found in pools of virtual server %s",
+				cr.Pool, cr.CookieName, vsName)
+			return false
+		}
+	}
+
+	for _, addr := range bindAddrs {
+		if !ctlr.isAllowedVirtualServerAddress(addr) {
+			log.Errorf("Address %v of virtual server %s is not in allowed CIDRs", addr, vsName)
+			ctlr.updateVirtualServerStatus(vsResource, addr, AddressNotAllowed)
+			return false
+		}
+	}
+
+	if err := ctlr.validateSSLOServiceChain(crInf, vsResource); err != nil {
+		log.Errorf("Invalid virtual server %s: %v", vsName, err)
+		return false
+	}
+
+	if err := ctlr.validateTLSProfileCiphers(crInf, vsResource); err != nil {
+		log.Errorf("Invalid TLSProfile %v of virtual server %s: %v", vsResource.Spec.TLSProfileName, vsName, err)
+		ctlr.updateVirtualServerStatus(vsResource, bindAddr, InvalidCipher)
+		return false
+	}
+
+	// quotas are enforced by admission webhook, checked again for the virtuals created without it
+	if err := ctlr.checkQuota(VirtualServer, vsResource.ObjectMeta); err != nil {
+		log.Errorf("Invalid virtual server %s: %v", vsName, err)
+		ctlr.updateVirtualServerStatus(vsResource, bindAddr, quotaStatus(err))
+		return false
+	}
+
 	return true
 }
 
+// canGetServices checks whether CIS has permission to get the services in the namespace.
+// Allowed namespaces are cached, denied ones for serviceAccessDeniedTTL.
+func (ctlr *Controller) canGetServices(namespace string) bool {
+	ctlr.serviceAccessMutex.Lock()
+	access, ok := ctlr.serviceAccessCache[namespace]
+	ctlr.serviceAccessMutex.Unlock()
+	if ok && (access.allowed || time.Now().Before(access.expiry)) {
+		return access.allowed
+	}
+	review := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "get",
+				Resource:  "services",
+			},
+		},
+	}
+	resp, err := ctlr.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(
+		context.TODO(), review, metav1.CreateOptions{})
+	if err != nil {
+		log.Errorf("Unable to review permission to get services in namespace %v: %v", namespace, err)
+		return false
+	}
+	access = serviceAccess{allowed: resp.Status.Allowed}
+	if !access.allowed {
+		access.expiry = time.Now().Add(serviceAccessDeniedTTL)
+	}
+	ctlr.serviceAccessMutex.Lock()
+	defer ctlr.serviceAccessMutex.Unlock()
+	if ctlr.serviceAccessCache == nil {
+		ctlr.serviceAccessCache = make(map[string]serviceAccess)
+	}
+	ctlr.serviceAccessCache[namespace] = access
+	return access.allowed
+}
+
+// hasPoolName checks whether a pool with the name exists in pools
+func hasPoolName(pools []cisapiv1.Pool, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, pl := range pools {
+		if pl.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (ctlr *Controller) checkValidTransportServer(
 	tsResource *cisapiv1.TransportServer,
 ) bool {
@@ -112,9 +390,120 @@ func (ctlr *Controller) checkValidTransportServer(
 		return false
 	}
 
+	if err := validateTransportServerMonitors(tsResource); err != nil {
+		log.Errorf("Invalid monitors in pool of transport server %s: %v", vsName, err)
+		return false
+	}
+
+	if bindAddr != "" && !ctlr.isAllowedVirtualServerAddress(bindAddr) {
+		log.Errorf("Address %v of transport server %s is not in allowed CIDRs", bindAddr, vsName)
+		ctlr.updateTransportServerStatus(tsResource, bindAddr, AddressNotAllowed)
+		return false
+	}
+
+	if _, err := getPoolNodeMemberLabel(tsResource.Spec.Pool); err != nil {
+		log.Errorf("Invalid node member selector in pool of transport server %s: %v", vsName, err)
+		return false
+	}
+
+	if partition := tsResource.Annotations[PartitionAnnotation]; partition != "" && !isValidPartition(partition) {
+		log.Errorf("Invalid %v annotation %v for transport server %s", PartitionAnnotation, partition, vsName)
+		return false
+	}
+
+	if !isValidPersistenceProfile(tsResource.Spec.PersistenceProfile) {
+		log.Errorf("Invalid persistenceProfile %v for transport server %s. Supported values are BIG-IP profile "+
+			"path or one of the AS3 persistence methods", tsResource.Spec.PersistenceProfile, vsName)
+		return false
+	}
+
+	if err := validatePoolWarmup(tsResource.Spec.Pool.Balance, tsResource.Spec.Pool.WarmupTime); err != nil {
+		log.Errorf("Invalid pool of transport server %s: %v", vsName, err)
+		return false
+	}
+
+	if _, err := ctlr.resolveServicePort(tsResource.Namespace, tsResource.Spec.Pool.Service,
+		tsResource.Spec.Pool.ServicePort); err != nil {
+		log.Errorf("Invalid servicePort in pool of transport server %s: %v", vsName, err)
+		return false
+	}
+
+	if err := ctlr.checkQuota(TransportServer, tsResource.ObjectMeta); err != nil {
+		log.Errorf("Invalid transport server %s: %v", vsName, err)
+		ctlr.updateTransportServerStatus(tsResource, bindAddr, quotaStatus(err))
+		return false
+	}
+
 	return true
 }
 
+// hasGRPCMonitor checks whether any pool of the VirtualServer has a gRPC monitor
+func hasGRPCMonitor(vs *cisapiv1.VirtualServer) bool {
+	for _, pool := range vs.Spec.Pools {
+		if pool.MonitorDisabled {
+			continue
+		}
+		if pool.Monitor.Type == GRPCMonitorType {
+			return true
+		}
+		for _, monitor := range pool.Monitors {
+			if monitor.Type == GRPCMonitorType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isValidPersistenceProfile checks whether the persistence profile is either
+// a persistence method supported by AS3 or a BIG-IP profile path
+func isValidPersistenceProfile(persistenceProfile string) bool {
+	switch persistenceProfile {
+	case "", "none", "cookie", "destination-address", "hash", "msrdp", "sip-info", "source-address",
+		"tls-session-id", "universal":
+		return true
+	}
+	return strings.HasPrefix(persistenceProfile, "/")
+}
+
+// isValidPoolPersistenceProfile checks whether the persistence profile of pool is
+// one of the persistence methods of AS3 persist action
+func isValidPoolPersistenceProfile(persistenceProfile string) bool {
+	switch persistenceProfile {
+	case "", "none", "cookie", "destination-address", "source-address":
+		return true
+	}
+	return false
+}
+
+// validatePoolWarmup checks that the pool warming up its members uses a load balancing method
+// honouring the ratio of pool members, which ramps up during warm-up
+func validatePoolWarmup(balance string, warmupTime int32) error {
+	if warmupTime <= 0 {
+		return nil
+	}
+	switch balance {
+	case "", "ratio-member", "ratio-least-connections-member", "ratio-session":
+		return nil
+	}
+	return fmt.Errorf("warmupTime requires loadBalancingMethod ratio-member, ratio-least-connections-member "+
+		"or ratio-session, found %v", balance)
+}
+
+// isValidLoadBalancingMethod checks whether the load balancing method is
+// one of the BIG-IP LTM pool load balancing algorithms
+func isValidLoadBalancingMethod(method string) bool {
+	switch method {
+	case "", "dynamic-ratio-member", "dynamic-ratio-node", "fastest-app-response", "fastest-node",
+		"least-connections-member", "least-connections-node", "least-sessions", "observed-member",
+		"observed-node", "predictive-member", "predictive-node", "ratio-least-connections-member",
+		"ratio-least-connections-node", "ratio-member", "ratio-node", "ratio-session", "round-robin",
+		"weighted-least-connections-member", "weighted-least-connections-node":
+		return true
+	}
+	return false
+}
+
 func (ctlr *Controller) checkValidIngressLink(
 	il *cisapiv1.IngressLink,
 ) bool {
@@ -151,3 +540,197 @@ func (ctlr *Controller) checkValidIngressLink(
 	}
 	return true
 }
+
+// isAllowedVirtualServerAddress checks whether the virtual server address
+// falls within one of the allowed CIDRs
+func (ctlr *Controller) isAllowedVirtualServerAddress(address string) bool {
+	if len(ctlr.allowedVSCIDRs) == 0 {
+		return true
+	}
+	// strip the route domain
+	ip := net.ParseIP(strings.Split(address, "%")[0])
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range ctlr.allowedVSCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidDNS64Prefix checks whether the DNS64 prefix is an IPv6 /96 prefix
+func isValidDNS64Prefix(dns64Prefix string) bool {
+	ip, ipNet, err := net.ParseCIDR(dns64Prefix)
+	if err != nil || ip.To4() != nil {
+		return false
+	}
+	ones, bits := ipNet.Mask.Size()
+	return ones == 96 && bits == 128
+}
+
+// isValidIPv4Netmask checks whether the mask is an IPv4 netmask with contiguous ones
+func isValidIPv4Netmask(mask string) bool {
+	ip := net.ParseIP(mask).To4()
+	if ip == nil {
+		return false
+	}
+	_, bits := net.IPMask(ip).Size()
+	return bits == 32
+}
+
+// isValidBigIPProfilePath checks whether the path is an absolute BIG-IP path with at least
+// the partition and the profile name, e.g. /Common/dos
+func isValidBigIPProfilePath(path string) bool {
+	if !strings.HasPrefix(path, "/") {
+		return false
+	}
+	components := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(components) < 2 {
+		return false
+	}
+	for _, component := range components {
+		if component == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// validateDOSProfiles checks whether the DoS profiles of the Policy are valid BIG-IP profile paths
+func validateDOSProfiles(plc *cisapiv1.Policy) error {
+	if path := plc.Spec.L3Policies.DOSNetworkProfile; path != "" && !isValidBigIPProfilePath(path) {
+		return fmt.Errorf("invalid dosNetworkProfile %v in Policy %v/%v, it must be a BIG-IP path like /Common/dos",
+			path, plc.Namespace, plc.Name)
+	}
+	if path := plc.Spec.L3Policies.DOSApplicationProfile; path != "" && !isValidBigIPProfilePath(path) {
+		return fmt.Errorf("invalid dosApplicationProfile %v in Policy %v/%v, it must be a BIG-IP path like /Common/dos",
+			path, plc.Namespace, plc.Name)
+	}
+	return nil
+}
+
+// validateTransportServerMonitors checks whether the UDP monitors of the pool of transport server
+// are used only with the udp type transport server
+func validateTransportServerMonitors(ts *cisapiv1.TransportServer) error {
+	monitors := append([]cisapiv1.Monitor{ts.Spec.Pool.Monitor}, ts.Spec.Pool.Monitors...)
+	for _, monitor := range monitors {
+		if monitor.Type == "udp" && monitor.Reference != BIGIP && ts.Spec.Type != "udp" {
+			return fmt.Errorf("udp monitor is not supported with %v type transport server", ts.Spec.Type)
+		}
+	}
+	return nil
+}
+
+// validateExternalDNSPools checks whether the CNAME pools of ExternalDNS have cnameTargets
+// as their members, virtuals of the domain are not valid CNAME members
+func validateExternalDNSPools(edns *cisapiv1.ExternalDNS) error {
+	for i, pool := range edns.Spec.Pools {
+		if pool.DNSRecordType == CNAMERecordType && len(pool.CNAMETargets) == 0 {
+			return fmt.Errorf("pool %d of %v record type requires cnameTargets", i, CNAMERecordType)
+		}
+	}
+	return nil
+}
+
+// isPassthroughVirtualServer checks whether the TLSProfile of virtual server has passthrough termination
+func isPassthroughVirtualServer(crInf *CRInformer, vs *cisapiv1.VirtualServer) bool {
+	if vs.Spec.TLSProfileName == "" {
+		return false
+	}
+	tlsKey := fmt.Sprintf("%s/%s", vs.Namespace, vs.Spec.TLSProfileName)
+	obj, found, _ := crInf.tlsInformer.GetIndexer().GetByKey(tlsKey)
+	return found && obj.(*cisapiv1.TLSProfile).Spec.TLS.Termination == TLSPassthrough
+}
+
+// isValidContentSecurityPolicy checks whether the policy is a non-empty header value
+// without the characters interpreted in the iRule
+func isValidContentSecurityPolicy(policy string) bool {
+	if strings.TrimSpace(policy) == "" || strings.ContainsAny(policy, `"\[]$`) {
+		return false
+	}
+	for _, c := range policy {
+		if c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateBodyRewrite checks whether the regex of body rewrite is valid, and the regex and
+// replacement can be enclosed in braces of the iRule
+func validateBodyRewrite(rewrite *cisapiv1.BodyRewriteSpec) error {
+	if rewrite.Match == "" {
+		return fmt.Errorf("match must not be empty")
+	}
+	if _, err := regexp.Compile(rewrite.Match); err != nil {
+		return fmt.Errorf("invalid match regex %q: %v", rewrite.Match, err)
+	}
+	for _, value := range []string{rewrite.Match, rewrite.Replace} {
+		if !hasBalancedBraces(value) {
+			return fmt.Errorf("braces of %q are not balanced", value)
+		}
+	}
+	return nil
+}
+
+// hasBalancedBraces checks whether the braces of value are balanced, and it does not end with
+// an escape character, so that it does not terminate the braces of TCL word enclosing it
+func hasBalancedBraces(value string) bool {
+	depth := 0
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0 && !escaped
+}
+
+// isValidHTTPHeaderName checks whether the name is a valid HTTP header field name token
+func isValidHTTPHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.ContainsRune("!#$%&'*+-.^_`|~", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasIPv4PoolMembersOnly checks whether the pool members of all the pools
+// of virtual server are IPv4 addresses
+func (ctlr *Controller) hasIPv4PoolMembersOnly(vs *cisapiv1.VirtualServer) bool {
+	for _, pool := range vs.Spec.Pools {
+		svcNamespace := vs.Namespace
+		if pool.ServiceNamespace != "" {
+			svcNamespace = pool.ServiceNamespace
+		}
+		poolMemInfo, ok := ctlr.resources.poolMemCache[svcNamespace+"/"+pool.Service]
+		if !ok {
+			continue
+		}
+		for _, members := range poolMemInfo.memberMap {
+			for _, member := range members {
+				if ip := net.ParseIP(member.Address); ip == nil || ip.To4() == nil {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,560 @@
+package controller
+
+import (
+	"context"
+	"net"
+	"time"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Validation Tests", func() {
+	var mockCtlr *mockController
+	namespace := "default"
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = make(map[string]bool)
+		mockCtlr.namespaces[namespace] = true
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		_ = mockCtlr.addNamespacedInformers(namespace, false)
+		_, cidr, _ := net.ParseCIDR("10.1.0.0/24")
+		mockCtlr.allowedVSCIDRs = []*net.IPNet{cidr}
+	})
+
+	It("Validates virtual server address against allowed CIDRs", func() {
+		Expect(mockCtlr.isAllowedVirtualServerAddress("10.1.0.0")).To(BeTrue())
+		Expect(mockCtlr.isAllowedVirtualServerAddress("10.1.0.255")).To(BeTrue())
+		Expect(mockCtlr.isAllowedVirtualServerAddress("10.1.0.10%10")).To(BeTrue())
+		Expect(mockCtlr.isAllowedVirtualServerAddress("10.1.1.0")).To(BeFalse())
+		Expect(mockCtlr.isAllowedVirtualServerAddress("10.0.255.255")).To(BeFalse())
+		Expect(mockCtlr.isAllowedVirtualServerAddress("invalid")).To(BeFalse())
+
+		mockCtlr.allowedVSCIDRs = nil
+		Expect(mockCtlr.isAllowedVirtualServerAddress("10.1.1.0")).To(BeTrue())
+	})
+
+	It("Rejects VirtualServer and TransportServer outside allowed CIDRs", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.255",
+		})
+		_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), vs, metav1.CreateOptions{})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+		vs.Spec.VirtualServerAddress = "10.1.1.0"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse())
+		vs, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), vs.Name, metav1.GetOptions{})
+		Expect(vs.Status.StatusOk).To(Equal(AddressNotAllowed))
+
+		ts := test.NewTransportServer("SampleTS", namespace, cisapiv1.TransportServerSpec{
+			VirtualServerAddress: "10.1.0.0",
+		})
+		_, _ = mockCtlr.kubeCRClient.CisV1().TransportServers(namespace).Create(context.TODO(), ts, metav1.CreateOptions{})
+		mockCtlr.addTransportServer(ts)
+		Expect(mockCtlr.checkValidTransportServer(ts)).To(BeTrue())
+		ts.Spec.VirtualServerAddress = "10.0.255.255"
+		Expect(mockCtlr.checkValidTransportServer(ts)).To(BeFalse())
+		ts, _ = mockCtlr.kubeCRClient.CisV1().TransportServers(namespace).Get(context.TODO(), ts.Name, metav1.GetOptions{})
+		Expect(ts.Status.StatusOk).To(Equal(AddressNotAllowed))
+	})
+
+	It("Validates DNS64 prefix of VirtualServer", func() {
+		Expect(isValidDNS64Prefix("64:ff9b::/96")).To(BeTrue())
+		Expect(isValidDNS64Prefix("64:ff9b::/64")).To(BeFalse())
+		Expect(isValidDNS64Prefix("10.1.0.0/24")).To(BeFalse())
+		Expect(isValidDNS64Prefix("64:ff9b::")).To(BeFalse())
+
+		mockCtlr.resources = NewResourceStore()
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			DNS64Prefix:          "64:ff9b::/96",
+			Pools:                []cisapiv1.Pool{{Path: "/foo", Service: "svc1", ServicePort: intstr.FromInt(80)}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		mockCtlr.resources.poolMemCache["default/svc1"] = poolMembersInfo{
+			memberMap: map[portRef][]PoolMember{
+				{name: "http", port: 80}: {{Address: "10.244.0.1", Port: 8080}},
+			},
+		}
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		mockCtlr.resources.poolMemCache["default/svc1"].memberMap[portRef{name: "http", port: 80}] =
+			[]PoolMember{{Address: "10.244.0.1", Port: 8080}, {Address: "fd00::1", Port: 8080}}
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "IPv6 pool members should be rejected")
+
+		vs.Spec.DNS64Prefix = "64:ff9b::/64"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid DNS64 prefix should be rejected")
+	})
+
+	It("Rejects pool request timeout for passthrough VirtualServer", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			TLSProfileName:       "passthrough-tls",
+			Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc1", RequestTimeout: 600}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		tlsProfile := test.NewTLSProfile("passthrough-tls", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "/Common/clientssl", Reference: BIGIP},
+		})
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		tlsProfile.Spec.TLS.Termination = TLSPassthrough
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "requestTimeout should be rejected for passthrough")
+	})
+
+	It("Rejects WebSocket for passthrough VirtualServer", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			TLSProfileName:       "passthrough-tls",
+			WebSocketEnabled:     true,
+		})
+		mockCtlr.addVirtualServer(vs)
+		tlsProfile := test.NewTLSProfile("passthrough-tls", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "/Common/clientssl", Reference: BIGIP},
+		})
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		tlsProfile.Spec.TLS.Termination = TLSPassthrough
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "webSocketEnabled should be rejected for passthrough")
+	})
+
+	It("Validates Content-Security-Policy annotation of VirtualServer", func() {
+		Expect(isValidContentSecurityPolicy("default-src 'self'; img-src *")).To(BeTrue())
+		Expect(isValidContentSecurityPolicy(" ")).To(BeFalse())
+		Expect(isValidContentSecurityPolicy(`default-src "self"`)).To(BeFalse())
+		Expect(isValidContentSecurityPolicy("default-src [HTTP::host]")).To(BeFalse())
+		Expect(isValidContentSecurityPolicy("default-src 'self'\nX-Header: value")).To(BeFalse())
+
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			TLSProfileName:       "passthrough-tls",
+		})
+		vs.Annotations = map[string]string{ContentSecurityPolicyAnnotation: "default-src 'self'"}
+		mockCtlr.addVirtualServer(vs)
+		tlsProfile := test.NewTLSProfile("passthrough-tls", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "/Common/clientssl", Reference: BIGIP},
+		})
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Annotations[ContentSecurityPolicyAnnotation] = `default-src "self"`
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid policy should be rejected")
+
+		vs.Annotations[ContentSecurityPolicyAnnotation] = "default-src 'self'"
+		tlsProfile.Spec.TLS.Termination = TLSPassthrough
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Policy should be rejected for passthrough")
+	})
+
+	It("Validates requestBodyRewrite of VirtualServer", func() {
+		Expect(validateBodyRewrite(&cisapiv1.BodyRewriteSpec{Match: `"tenant":\s*"\w+"`, Replace: `"tenant":"t1"`})).To(Succeed())
+		Expect(validateBodyRewrite(&cisapiv1.BodyRewriteSpec{Match: `[0-9]{3}`, Replace: "xxx"})).To(Succeed())
+		Expect(validateBodyRewrite(&cisapiv1.BodyRewriteSpec{Match: `\{id\}`, Replace: "1"})).To(Succeed())
+		Expect(validateBodyRewrite(&cisapiv1.BodyRewriteSpec{Replace: "xxx"})).ToNot(Succeed())
+		Expect(validateBodyRewrite(&cisapiv1.BodyRewriteSpec{Match: "(abc", Replace: "xxx"})).ToNot(Succeed())
+		Expect(validateBodyRewrite(&cisapiv1.BodyRewriteSpec{Match: "abc", Replace: "} exec {"})).ToNot(Succeed())
+		Expect(validateBodyRewrite(&cisapiv1.BodyRewriteSpec{Match: "abc", Replace: `xyz\`})).ToNot(Succeed())
+
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			TLSProfileName:       "passthrough-tls",
+			RequestBodyRewrite:   &cisapiv1.BodyRewriteSpec{Match: "tenant-[0-9]+", Replace: "tenant-1"},
+		})
+		mockCtlr.addVirtualServer(vs)
+		tlsProfile := test.NewTLSProfile("passthrough-tls", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "/Common/clientssl", Reference: BIGIP},
+		})
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Spec.RequestBodyRewrite.Match = "tenant-[0-9"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid regex should be rejected")
+
+		vs.Spec.RequestBodyRewrite.Match = "tenant-[0-9]+"
+		tlsProfile.Spec.TLS.Termination = TLSPassthrough
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Rewrite should be rejected for passthrough")
+	})
+
+	It("Validates load balancing method of VirtualServer pools", func() {
+		Expect(isValidLoadBalancingMethod("")).To(BeTrue())
+		Expect(isValidLoadBalancingMethod("least-connections-member")).To(BeTrue())
+		Expect(isValidLoadBalancingMethod("least-connection")).To(BeFalse())
+
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools: []cisapiv1.Pool{
+				{Path: "/api", Service: "svc1", ServicePort: intstr.FromInt(80), Balance: "least-connections-member"},
+				{Path: "/static", Service: "svc2", ServicePort: intstr.FromInt(80), Balance: "round-robin"},
+			},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Spec.Pools[1].Balance = "fastest"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Unknown load balancing method should be rejected")
+	})
+
+	It("Validates host header rewrite of VirtualServer pools", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			TLSProfileName:       "passthrough-tls",
+			Pools: []cisapiv1.Pool{
+				{Path: "/api", Service: "svc1", ServicePort: intstr.FromInt(80), HostHeaderRewrite: "backend.svc.cluster.local"},
+			},
+		})
+		mockCtlr.addVirtualServer(vs)
+		tlsProfile := test.NewTLSProfile("passthrough-tls", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "/Common/clientssl", Reference: BIGIP},
+		})
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Spec.Pools[0].HostHeaderRewrite = "backend svc"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid host should be rejected")
+
+		vs.Spec.Pools[0].HostHeaderRewrite = "backend.svc.cluster.local"
+		tlsProfile.Spec.TLS.Termination = TLSPassthrough
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Host header rewrite should be rejected for passthrough")
+	})
+
+	It("Validates SSLO service chain of VirtualServer policy", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			PolicyName:           "sslo-policy",
+		})
+		mockCtlr.addVirtualServer(vs)
+		plc := test.NewPolicy("sslo-policy", namespace, cisapiv1.PolicySpec{
+			SSLOServiceChain:     "inbound",
+			SSLOSecurityServices: []string{"ips", "icap"},
+		})
+		mockCtlr.addPolicy(plc)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "SSLO should be rejected for HTTP VirtualServer")
+
+		vs.Spec.TLSProfileName = "edge-tls"
+		tlsProfile := test.NewTLSProfile("edge-tls", namespace, cisapiv1.TLSProfileSpec{
+			TLS: cisapiv1.TLS{Termination: TLSEdge, ClientSSL: "/Common/clientssl", Reference: BIGIP},
+		})
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		plc.Spec.SSLOTopologyType = "l3-transparent"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid topology type should be rejected")
+
+		plc.Spec.SSLOTopologyType = SSLOTopologyL3Outbound
+		tlsProfile.Spec.TLS.Termination = TLSPassthrough
+		mockCtlr.addTLSProfile(tlsProfile)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "SSLO should be rejected for passthrough")
+	})
+
+	It("Validates external members of VirtualServer pools", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools: []cisapiv1.Pool{{
+				Path:            "/",
+				Service:         "svc1",
+				ExternalMembers: []cisapiv1.ExternalMember{{FQDN: "API.example.com", Port: 443}},
+			}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Spec.Pools[0].ExternalMembers[0].FQDN = "api_example.com"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid FQDN should be rejected")
+
+		vs.Spec.Pools[0].ExternalMembers[0].FQDN = "api.example.com"
+		vs.Spec.Pools[0].ExternalMembers[0].Port = 0
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid port should be rejected")
+	})
+
+	It("Validates SRV record of VirtualServer pools", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools: []cisapiv1.Pool{{
+				Path:      "/",
+				SRVRecord: "_http._tcp.mesh.example.com",
+				SRVPort:   8080,
+			}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Spec.Pools[0].SRVRecord = "_http._tcp.mesh example.com"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid SRV record should be rejected")
+
+		vs.Spec.Pools[0].SRVRecord = "_http._tcp.mesh.example.com"
+		vs.Spec.Pools[0].SRVPort = 0
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "SRV record without port should be rejected")
+	})
+
+	It("Validates VirtualServer pools with monitorDisabled", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools: []cisapiv1.Pool{{
+				Path:            "/",
+				Service:         "svc1",
+				MonitorDisabled: true,
+				Monitor:         cisapiv1.Monitor{Type: "http", Send: "GET /"},
+			}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue(), "Monitor with monitorDisabled should only be warned")
+	})
+
+	It("Validates gRPC monitors of VirtualServer pools", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools: []cisapiv1.Pool{{
+				Path:     "/",
+				Service:  "svc1",
+				Monitors: []cisapiv1.Monitor{{Type: GRPCMonitorType, GRPCService: "helloworld.Greeter"}},
+			}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(),
+			"gRPC monitor should be rejected without gRPC health check script")
+
+		mockCtlr.grpcMonitorScriptPath = "/Common/grpc_health_check"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+	})
+
+	It("Validates load balancing method of pools with warm-up", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc1", WarmupTime: 60}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+		Expect(getPoolBalance("", 60)).To(Equal("ratio-member"), "Warm-up pool should default to ratio-member")
+		Expect(getPoolBalance("", 0)).To(Equal(""))
+
+		vs.Spec.Pools[0].Balance = "least-connections-member"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(),
+			"Warm-up should be rejected with non-ratio load balancing method")
+
+		vs.Spec.Pools[0].Balance = "ratio-least-connections-member"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+	})
+
+	It("Validates named service port of pools", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc1", ServicePort: intstr.FromString("http")}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Named port of missing service should be rejected")
+
+		svc := test.NewService("svc1", "1", namespace, v1.ServiceTypeClusterIP,
+			[]v1.ServicePort{{Name: "https", Port: 443}})
+		mockCtlr.addService(svc)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Unknown port name should be rejected")
+
+		svc.Spec.Ports = append(svc.Spec.Ports, v1.ServicePort{Name: "http", Port: 80})
+		mockCtlr.updateService(svc)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+	})
+
+	It("Validates permission to get service of other namespace", func() {
+		allowed := false
+		reviews := 0
+		mockCtlr.kubeClient.(*k8sfake.Clientset).PrependReactor("create", "selfsubjectaccessreviews",
+			func(action k8stesting.Action) (bool, runtime.Object, error) {
+				reviews++
+				review := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+				Expect(review.Spec.ResourceAttributes.Namespace).To(Equal("backend"))
+				Expect(review.Spec.ResourceAttributes.Verb).To(Equal("get"))
+				review.Status.Allowed = allowed
+				return true, review, nil
+			})
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc1", ServiceNamespace: namespace}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Spec.Pools[0].ServiceNamespace = "backend"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Service without permission should be rejected")
+		Expect(reviews).To(Equal(1))
+
+		allowed = true
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Denied permission should be cached")
+		Expect(reviews).To(Equal(1), "Denied permission should not be reviewed again before its expiry")
+
+		access := mockCtlr.serviceAccessCache["backend"]
+		access.expiry = time.Now()
+		mockCtlr.serviceAccessCache["backend"] = access
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue(), "Permission should be reviewed after the expiry")
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+		Expect(reviews).To(Equal(2), "Allowed permission should be cached")
+	})
+
+	It("Validates pools of cookie routes in VirtualServer", func() {
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			Pools: []cisapiv1.Pool{
+				{Name: "stable", Path: "/", Service: "svc1"},
+				{Name: "beta", Path: "/beta", Service: "svc2"},
+			},
+			CookieRoutes: []cisapiv1.CookieRoute{{CookieName: "version", CookieValue: "beta", Pool: "beta"}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Spec.CookieRoutes[0].Pool = "canary"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Cookie route with unknown pool should be rejected")
+
+		vs.Spec.CookieRoutes[0].Pool = "beta"
+		vs.Spec.CookieRoutes[0].CookieName = ""
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Cookie route without cookie name should be rejected")
+	})
+
+	It("Validates insert headers of VirtualServer", func() {
+		Expect(isValidHTTPHeaderName("X-Forwarded-For")).To(BeTrue())
+		Expect(isValidHTTPHeaderName("X_Custom.Header~1")).To(BeTrue())
+		Expect(isValidHTTPHeaderName("")).To(BeFalse())
+		Expect(isValidHTTPHeaderName("X Real IP")).To(BeFalse())
+		Expect(isValidHTTPHeaderName("X-Real-IP:")).To(BeFalse())
+
+		vs := test.NewVirtualServer("SampleVS", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+			InsertHeaders:        []cisapiv1.HeaderSpec{{Name: "X-Real-IP", Value: "[IP::client_addr]"}},
+		})
+		mockCtlr.addVirtualServer(vs)
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeTrue())
+
+		vs.Spec.InsertHeaders[0].Name = "X-Real-IP:"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Invalid header name should be rejected")
+
+		vs.Spec.InsertHeaders[0].Name = "X-Real-IP"
+		vs.Spec.InsertHeaders[0].Value = " "
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Empty header value should be rejected")
+
+		vs.Spec.InsertHeaders[0].Value = "value\r\nX-Injected: value"
+		Expect(mockCtlr.checkValidVirtualServer(vs)).To(BeFalse(), "Header value with CRLF should be rejected")
+	})
+
+	It("Validates UDP monitors of TransportServer pool", func() {
+		ts := test.NewTransportServer("SampleTS", namespace, cisapiv1.TransportServerSpec{
+			VirtualServerAddress: "10.1.0.20",
+			Pool: cisapiv1.Pool{
+				Service:     "svc1",
+				ServicePort: intstr.FromInt(53),
+				Monitors: []cisapiv1.Monitor{
+					{Type: "tcp", Interval: 5, Timeout: 10},
+					{Type: "udp", Interval: 5, Timeout: 10},
+				},
+			},
+		})
+		mockCtlr.addTransportServer(ts)
+		Expect(mockCtlr.checkValidTransportServer(ts)).To(BeFalse(), "UDP monitor should be rejected with tcp type")
+
+		ts.Spec.Type = "udp"
+		Expect(mockCtlr.checkValidTransportServer(ts)).To(BeTrue(), "UDP monitor should be valid with udp type")
+
+		ts.Spec.Type = "sctp"
+		ts.Spec.Pool.Monitors = nil
+		ts.Spec.Pool.Monitor = cisapiv1.Monitor{Type: "udp", Interval: 5, Timeout: 10}
+		Expect(mockCtlr.checkValidTransportServer(ts)).To(BeFalse(), "UDP monitor should be rejected with sctp type")
+
+		ts.Spec.Pool.Monitor = cisapiv1.Monitor{Type: "udp", Name: "/Common/udp", Reference: BIGIP}
+		Expect(mockCtlr.checkValidTransportServer(ts)).To(BeTrue(), "BIG-IP monitor should not be validated")
+	})
+
+	It("Validates CNAME pools of ExternalDNS", func() {
+		edns := test.NewExternalDNS("SampleEDNS", namespace, cisapiv1.ExternalDNSSpec{
+			DomainName: "test.com",
+			Pools: []cisapiv1.DNSPool{{
+				DataServerName: "DataServer",
+				DNSRecordType:  CNAMERecordType,
+			}},
+		})
+		Expect(validateExternalDNSPools(edns)).NotTo(Succeed(), "CNAME pool without cnameTargets should be rejected")
+
+		edns.Spec.Pools[0].CNAMETargets = []string{"app.example.com"}
+		Expect(validateExternalDNSPools(edns)).To(Succeed())
+
+		edns.Spec.Pools[0] = cisapiv1.DNSPool{DataServerName: "DataServer", DNSRecordType: "A"}
+		Expect(validateExternalDNSPools(edns)).To(Succeed(), "A pool members are the virtuals of domain")
+	})
+
+	It("Validates quota of namespace during reconciliation", func() {
+		mockCtlr.quotaCMKey = "kube-system/cis-quota"
+		cm := test.NewConfigMap("cis-quota", "v1", "kube-system", map[string]string{
+			QuotasKey: `{"default": {"virtualServerQuota": 1}}`,
+		})
+		mockCtlr.quotaCMInformer, _ = mockCtlr.newConfigMapInformer(mockCtlr.quotaCMKey)
+		_ = mockCtlr.quotaCMInformer.GetStore().Add(cm)
+
+		created := metav1.Now()
+		vs1 := test.NewVirtualServer("SampleVS1", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "test.com",
+			VirtualServerAddress: "10.1.0.10",
+		})
+		vs1.CreationTimestamp = created
+		vs2 := test.NewVirtualServer("SampleVS2", namespace, cisapiv1.VirtualServerSpec{
+			Host:                 "foo.com",
+			VirtualServerAddress: "10.1.0.11",
+		})
+		vs2.CreationTimestamp = metav1.NewTime(created.Add(time.Second))
+		for _, vs := range []*cisapiv1.VirtualServer{vs1, vs2} {
+			_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Create(context.TODO(), vs, metav1.CreateOptions{})
+			mockCtlr.addVirtualServer(vs)
+		}
+		Expect(mockCtlr.checkValidVirtualServer(vs1)).To(BeTrue(), "Oldest VirtualServer should be within quota")
+		Expect(mockCtlr.checkValidVirtualServer(vs2)).To(BeFalse(), "VirtualServer exceeding quota should be invalid")
+		vs2, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), vs2.Name, metav1.GetOptions{})
+		Expect(vs2.Status.StatusOk).To(Equal(QuotaExceeded))
+
+		ts := test.NewTransportServer("SampleTS", namespace, cisapiv1.TransportServerSpec{
+			VirtualServerAddress: "10.1.0.12",
+		})
+		mockCtlr.addTransportServer(ts)
+		Expect(mockCtlr.checkValidTransportServer(ts)).To(BeTrue(), "TransportServer without quota should be valid")
+
+		cm.Data[QuotasKey] = "invalid"
+		_ = mockCtlr.quotaCMInformer.GetStore().Update(cm)
+		Expect(mockCtlr.checkValidVirtualServer(vs1)).To(BeFalse(), "VirtualServer without readable quota should be invalid")
+		vs1, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), vs1.Name, metav1.GetOptions{})
+		Expect(vs1.Status.StatusOk).To(Equal(QuotaUnavailable))
+	})
+})
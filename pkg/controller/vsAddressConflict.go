@@ -0,0 +1,140 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// VSAddressConflictReason is the reason of the events recorded on the VirtualServers whose
+	// BIG-IP virtual servers of different names have the same address and port
+	VSAddressConflictReason = "VSAddressConflict"
+)
+
+// checkVSAddressConflict checks whether the address and port of rsCfg is already used by a BIG-IP
+// virtual server of any partition owned by other resources, e.g. by the VirtualServers of another
+// hostGroup or partition resolving to the same address. It records the conflict so that the virtual
+// is requeued when the existing virtual server is deleted, updates the virtual with AddressConflict
+// status and records VSAddressConflict events on the VirtualServers of both virtual servers.
+// The status of the owning VirtualServers is left unchanged as their virtual server is still posted.
+func (ctlr *Controller) checkVSAddressConflict(
+	rsName string,
+	rsCfg *ResourceConfig,
+	virtual *cisapiv1.VirtualServer,
+) bool {
+	if rsCfg.Virtual.VirtualAddress == nil {
+		return false
+	}
+	for partition, partitionConfig := range ctlr.resources.ltmConfig {
+		// virtual servers of other partitions conflict irrespective of their name
+		sameName := ""
+		if partition == rsCfg.Virtual.Partition {
+			sameName = rsName
+		}
+		if name, existing := findVSAddressConflict(sameName, rsCfg, partitionConfig.ResourceMap); existing != nil {
+			return ctlr.recordVSAddressConflict(rsName, rsCfg, virtual, partition, name, existing)
+		}
+	}
+	return false
+}
+
+// findVSAddressConflict returns the virtual server of rsMap other than rsName with the address
+// and port of rsCfg owned by other resources
+func findVSAddressConflict(
+	rsName string,
+	rsCfg *ResourceConfig,
+	rsMap ResourceMap,
+) (string, *ResourceConfig) {
+	for name, existing := range rsMap {
+		// virtual server of the same name in the partition is checked by checkVSNameConflict
+		if name == rsName || existing == nil || existing.Virtual.VirtualAddress == nil ||
+			*existing.Virtual.VirtualAddress != *rsCfg.Virtual.VirtualAddress ||
+			isOwnedBySameResources(existing, rsCfg) {
+			continue
+		}
+		return name, existing
+	}
+	return "", nil
+}
+
+// recordVSAddressConflict records the conflict of virtual with the existing virtual server name of partition
+func (ctlr *Controller) recordVSAddressConflict(
+	rsName string,
+	rsCfg *ResourceConfig,
+	virtual *cisapiv1.VirtualServer,
+	partition string,
+	name string,
+	existing *ResourceConfig,
+) bool {
+	var owners []string
+	for rsc := range existing.MetaData.baseResources {
+		owners = append(owners, rsc)
+	}
+	sort.Strings(owners)
+	ctlr.resources.conflictingNames[name] = resourceRef{
+		kind:      VirtualServer,
+		namespace: virtual.Namespace,
+		name:      virtual.Name,
+	}
+	address := fmt.Sprintf("%v:%v", rsCfg.Virtual.VirtualAddress.BindAddr, rsCfg.Virtual.VirtualAddress.Port)
+	message := fmt.Sprintf("Address %v of virtual server %v of VirtualServer %v/%v conflicts with "+
+		"virtual server %v/%v of %v, skipping it", address, rsName, virtual.Namespace, virtual.Name,
+		partition, name, strings.Join(owners, ","))
+	log.Errorf("%v", message)
+	ctlr.recordVirtualServerEvent(virtual, v1.EventTypeWarning, VSAddressConflictReason, message)
+	ctlr.updateVirtualServerStatus(virtual, rsCfg.Virtual.VirtualAddress.BindAddr, AddressConflict)
+	for _, owner := range owners {
+		if existing.MetaData.baseResources[owner] != VirtualServer {
+			continue
+		}
+		if vs := ctlr.getVirtualServerByKey(owner); vs != nil {
+			ctlr.recordVirtualServerEvent(vs, v1.EventTypeWarning, VSAddressConflictReason, message)
+		}
+	}
+	return true
+}
+
+// isOwnedBySameResources checks whether the resource configs have any base resource in common
+func isOwnedBySameResources(existing, rsCfg *ResourceConfig) bool {
+	for rsc := range existing.MetaData.baseResources {
+		if _, found := rsCfg.MetaData.baseResources[rsc]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// getVirtualServerByKey returns the VirtualServer of namespace/name key from the informer
+func (ctlr *Controller) getVirtualServerByKey(key string) *cisapiv1.VirtualServer {
+	namespace := strings.Split(key, "/")[0]
+	crInf, ok := ctlr.getNamespacedCRInformer(namespace)
+	if !ok {
+		return nil
+	}
+	obj, exist, err := crInf.vsInformer.GetIndexer().GetByKey(key)
+	if err != nil || !exist {
+		return nil
+	}
+	return obj.(*cisapiv1.VirtualServer)
+}
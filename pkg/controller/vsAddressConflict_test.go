@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("VirtualServer Address Conflict Tests", func() {
+	var mockCtlr *mockController
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = make(map[string]bool)
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.TeemData = &teem.TeemsData{
+			ResourceType: teem.ResourceTypes{
+				VirtualServer: make(map[string]int),
+			},
+		}
+		for _, ns := range []string{"ns1", "ns2"} {
+			mockCtlr.namespaces[ns] = true
+			_ = mockCtlr.addNamespacedInformers(ns, false)
+		}
+	})
+
+	newHostGroupVirtual := func(ns, hostGroup, address, name string, httpPort int32, partition string) *cisapiv1.VirtualServer {
+		vs := test.NewVirtualServer("vs", ns, cisapiv1.VirtualServerSpec{
+			Host:                  ns + ".foo.com",
+			HostGroup:             hostGroup,
+			VirtualServerAddress:  address,
+			VirtualServerName:     name,
+			VirtualServerHTTPPort: httpPort,
+			Pools:                 []cisapiv1.Pool{{Path: "/", Service: "svc", ServicePort: intstr.FromInt(80)}},
+		})
+		if partition != "" {
+			vs.Annotations = map[string]string{PartitionAnnotation: partition}
+		}
+		_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(ns).Create(context.TODO(), vs, metav1.CreateOptions{})
+		mockCtlr.addVirtualServer(vs)
+		return vs
+	}
+
+	addressConflicts := func(ns string) func() []string {
+		return func() []string {
+			var objects []string
+			events, _ := mockCtlr.kubeClient.CoreV1().Events(ns).List(context.TODO(), metav1.ListOptions{})
+			for _, event := range events.Items {
+				if event.Reason == VSAddressConflictReason {
+					objects = append(objects, event.InvolvedObject.Namespace+"/"+event.InvolvedObject.Name)
+				}
+			}
+			return objects
+		}
+	}
+
+	type hostGroupVirtual struct {
+		address   string
+		name      string
+		httpPort  int32
+		partition string
+	}
+
+	for _, tc := range []struct {
+		description string
+		first       hostGroupVirtual
+		second      hostGroupVirtual
+		conflict    bool
+		rsNames     []string
+	}{
+		{
+			description: "hostGroups with the same address and different virtual server names",
+			first:       hostGroupVirtual{address: "10.1.1.1", name: "app1"},
+			second:      hostGroupVirtual{address: "10.1.1.1", name: "app2"},
+			conflict:    true,
+			rsNames:     []string{"app1_80"},
+		},
+		{
+			description: "hostGroups with the same address and default virtual server names",
+			first:       hostGroupVirtual{address: "10.1.1.1", name: "app1"},
+			second:      hostGroupVirtual{address: "10.1.1.1"},
+			conflict:    true,
+			rsNames:     []string{"app1_80"},
+		},
+		{
+			description: "hostGroups with the same address and virtual server name in different partitions",
+			first:       hostGroupVirtual{address: "10.1.1.1", name: "app1"},
+			second:      hostGroupVirtual{address: "10.1.1.1", name: "app1", partition: "dev"},
+			conflict:    true,
+			rsNames:     []string{"app1_80"},
+		},
+		{
+			description: "hostGroups with different addresses",
+			first:       hostGroupVirtual{address: "10.1.1.1", name: "app1"},
+			second:      hostGroupVirtual{address: "10.1.1.2", name: "app2"},
+			rsNames:     []string{"app1_80", "app2_80"},
+		},
+		{
+			description: "hostGroups with the same address and different ports",
+			first:       hostGroupVirtual{address: "10.1.1.1", name: "app1"},
+			second:      hostGroupVirtual{address: "10.1.1.1", name: "app2", httpPort: 8080},
+			rsNames:     []string{"app1_80", "app2_8080"},
+		},
+	} {
+		tc := tc
+		It("Detects address conflict of "+tc.description, func() {
+			vs1 := newHostGroupVirtual("ns1", "hg1", tc.first.address, tc.first.name, tc.first.httpPort, "")
+			vs2 := newHostGroupVirtual("ns2", "hg2", tc.second.address, tc.second.name, tc.second.httpPort,
+				tc.second.partition)
+			Expect(mockCtlr.processVirtualServers(vs1, false)).To(BeNil())
+			Expect(mockCtlr.processVirtualServers(vs2, false)).To(BeNil())
+
+			rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+			var rsNames []string
+			for rsName := range rsMap {
+				rsNames = append(rsNames, rsName)
+			}
+			Expect(rsNames).To(ConsistOf(tc.rsNames))
+			if tc.second.partition != "" {
+				Expect(mockCtlr.resources.getPartitionResourceMap(tc.second.partition)).To(BeEmpty())
+			}
+			Expect(rsMap["app1_80"].MetaData.baseResources).To(HaveKey("ns1/vs"))
+
+			vs2, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers("ns2").Get(context.TODO(), "vs", metav1.GetOptions{})
+			if tc.conflict {
+				Expect(vs2.Status.StatusOk).To(Equal(AddressConflict))
+				Expect(mockCtlr.resources.conflictingNames).To(HaveKeyWithValue("app1_80",
+					resourceRef{kind: VirtualServer, namespace: "ns2", name: "vs"}))
+				Eventually(addressConflicts("ns1")).Should(ConsistOf("ns1/vs"))
+				Eventually(addressConflicts("ns2")).Should(ConsistOf("ns2/vs"))
+			} else {
+				Expect(vs2.Status.StatusOk).NotTo(Equal(AddressConflict))
+				Expect(mockCtlr.resources.conflictingNames).To(BeEmpty())
+				Consistently(addressConflicts("ns2")).Should(BeEmpty())
+			}
+
+			// reprocessing the owner is not a conflict
+			Expect(mockCtlr.processVirtualServers(vs1, false)).To(BeNil())
+			Expect(rsMap["app1_80"].MetaData.baseResources).To(HaveKey("ns1/vs"))
+		})
+	}
+
+	It("Requeues the conflicting VirtualServer when the owner is deleted", func() {
+		vs1 := newHostGroupVirtual("ns1", "hg1", "10.1.1.1", "app1", 0, "")
+		vs2 := newHostGroupVirtual("ns2", "hg2", "10.1.1.1", "app2", 0, "")
+		Expect(mockCtlr.processVirtualServers(vs1, false)).To(BeNil())
+		Expect(mockCtlr.processVirtualServers(vs2, false)).To(BeNil())
+
+		mockCtlr.deleteVirtualServer(vs1)
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+		Expect(mockCtlr.processVirtualServers(vs1, true)).To(BeNil())
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(1), "Conflicting VirtualServer should be requeued")
+		key, _ := mockCtlr.resourceQueue.Get()
+		Expect(key.(*rqKey).namespace + "/" + key.(*rqKey).rscName).To(Equal("ns2/vs"))
+
+		Expect(mockCtlr.processVirtualServers(vs2, false)).To(BeNil())
+		rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+		Expect(rsMap).To(HaveLen(1))
+		Expect(rsMap["app2_80"].MetaData.baseResources).To(HaveKey("ns2/vs"))
+	})
+})
@@ -0,0 +1,119 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// VSNameConflictReason is the reason of the event recorded on a VirtualServer whose
+	// BIG-IP virtual server name is already used by other resources
+	VSNameConflictReason = "VSNameConflict"
+)
+
+// checkVSNameConflict checks whether the BIG-IP virtual server rsName is already owned by
+// resources other than the ones of rsCfg, it records the conflict and a VSNameConflict
+// event on the virtual so that the existing virtual server is not overwritten
+func (ctlr *Controller) checkVSNameConflict(
+	rsName string,
+	rsCfg *ResourceConfig,
+	virtual *cisapiv1.VirtualServer,
+) bool {
+	rsMap := ctlr.resources.getPartitionResourceMap(rsCfg.Virtual.Partition)
+	existing, ok := rsMap[rsName]
+	if !ok || existing == nil || len(existing.MetaData.baseResources) == 0 {
+		return false
+	}
+	var owners []string
+	for rsc := range existing.MetaData.baseResources {
+		if _, found := rsCfg.MetaData.baseResources[rsc]; found {
+			// virtual server is owned by the same resources
+			return false
+		}
+		owners = append(owners, rsc)
+	}
+	sort.Strings(owners)
+	ctlr.resources.conflictingNames[rsName] = resourceRef{
+		kind:      VirtualServer,
+		namespace: virtual.Namespace,
+		name:      virtual.Name,
+	}
+	message := fmt.Sprintf("Virtual server name %v of VirtualServer %v/%v conflicts with %v, skipping it",
+		rsName, virtual.Namespace, virtual.Name, strings.Join(owners, ","))
+	log.Errorf("%v", message)
+	ctlr.recordVirtualServerEvent(virtual, v1.EventTypeWarning, VSNameConflictReason, message)
+	return true
+}
+
+// isVSNameOwnedByOthers checks whether the BIG-IP virtual server rsName of partition is owned by
+// resources other than the virtual and its associated virtuals
+func (ctlr *Controller) isVSNameOwnedByOthers(
+	partition string,
+	rsName string,
+	virtual *cisapiv1.VirtualServer,
+	virtuals []*cisapiv1.VirtualServer,
+) bool {
+	rsMap := ctlr.resources.getPartitionResourceMap(partition)
+	existing, ok := rsMap[rsName]
+	if !ok || existing == nil || len(existing.MetaData.baseResources) == 0 {
+		return false
+	}
+	if _, found := existing.MetaData.baseResources[virtual.Namespace+"/"+virtual.Name]; found {
+		return false
+	}
+	for _, vrt := range virtuals {
+		if _, found := existing.MetaData.baseResources[vrt.Namespace+"/"+vrt.Name]; found {
+			return false
+		}
+	}
+	return true
+}
+
+// clearVSNameConflicts clears the conflicts of a deleted VirtualServer
+func (ctlr *Controller) clearVSNameConflicts(virtual *cisapiv1.VirtualServer) {
+	for rsName, ref := range ctlr.resources.conflictingNames {
+		if ref.kind == VirtualServer && ref.namespace == virtual.Namespace && ref.name == virtual.Name {
+			delete(ctlr.resources.conflictingNames, rsName)
+		}
+	}
+}
+
+// requeueVSNameConflict requeues the VirtualServer which conflicted with the deleted
+// virtual server rsName, so that it takes over the name
+func (ctlr *Controller) requeueVSNameConflict(rsName string) {
+	ref, ok := ctlr.resources.conflictingNames[rsName]
+	if !ok {
+		return
+	}
+	delete(ctlr.resources.conflictingNames, rsName)
+	crInf, ok := ctlr.getNamespacedCRInformer(ref.namespace)
+	if !ok {
+		return
+	}
+	obj, exist, err := crInf.vsInformer.GetIndexer().GetByKey(ref.namespace + "/" + ref.name)
+	if err != nil || !exist {
+		return
+	}
+	ctlr.enqueueVirtualServer(obj)
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+
+	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	crdfake "github.com/F5Networks/k8s-bigip-ctlr/v2/config/client/clientset/versioned/fake"
+	apm "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/appmanager"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("VirtualServer Name Conflict Tests", func() {
+	var mockCtlr *mockController
+	var vs1, vs2 *cisapiv1.VirtualServer
+
+	BeforeEach(func() {
+		mockCtlr = newMockController()
+		mockCtlr.mode = CustomResourceMode
+		mockCtlr.namespaces = make(map[string]bool)
+		mockCtlr.kubeCRClient = crdfake.NewSimpleClientset()
+		mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+		mockCtlr.crInformers = make(map[string]*CRInformer)
+		mockCtlr.comInformers = make(map[string]*CommonInformer)
+		mockCtlr.nativeResourceSelector, _ = createLabelSelector(DefaultCustomResourceLabel)
+		mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+		mockCtlr.resources = NewResourceStore()
+		mockCtlr.TeemData = &teem.TeemsData{
+			ResourceType: teem.ResourceTypes{
+				VirtualServer: make(map[string]int),
+			},
+		}
+
+		// VirtualServers of different namespaces and addresses with the same virtual server name
+		var virtuals []*cisapiv1.VirtualServer
+		for i, ns := range []string{"ns1", "ns2"} {
+			mockCtlr.namespaces[ns] = true
+			_ = mockCtlr.addNamespacedInformers(ns, false)
+			vs := test.NewVirtualServer("vs", ns, cisapiv1.VirtualServerSpec{
+				Host:                 ns + ".foo.com",
+				VirtualServerAddress: []string{"10.1.1.1", "10.1.1.2"}[i],
+				VirtualServerName:    "app",
+				Pools:                []cisapiv1.Pool{{Path: "/", Service: "svc", ServicePort: intstr.FromInt(80)}},
+			})
+			_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(ns).Create(context.TODO(), vs, metav1.CreateOptions{})
+			mockCtlr.addVirtualServer(vs)
+			virtuals = append(virtuals, vs)
+		}
+		vs1, vs2 = virtuals[0], virtuals[1]
+	})
+
+	nameConflicts := func(ns string) func() []string {
+		return func() []string {
+			var objects []string
+			events, _ := mockCtlr.kubeClient.CoreV1().Events(ns).List(context.TODO(), metav1.ListOptions{})
+			for _, event := range events.Items {
+				if event.Reason == VSNameConflictReason {
+					objects = append(objects, event.InvolvedObject.Namespace+"/"+event.InvolvedObject.Name)
+				}
+			}
+			return objects
+		}
+	}
+
+	It("Does not overwrite the virtual server of other resources", func() {
+		Expect(mockCtlr.processVirtualServers(vs1, false)).To(BeNil())
+		Expect(mockCtlr.processVirtualServers(vs2, false)).To(BeNil())
+
+		rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+		Expect(rsMap).To(HaveLen(1))
+		Expect(rsMap["app_80"].MetaData.baseResources).To(HaveKey("ns1/vs"))
+		Expect(rsMap["app_80"].MetaData.baseResources).NotTo(HaveKey("ns2/vs"))
+		Expect(mockCtlr.resources.conflictingNames).To(HaveKeyWithValue("app_80",
+			resourceRef{kind: VirtualServer, namespace: "ns2", name: "vs"}))
+		Eventually(nameConflicts("ns2")).Should(ConsistOf("ns2/vs"))
+		Consistently(nameConflicts("ns1")).Should(BeEmpty())
+
+		// reprocessing the owner is not a conflict
+		Expect(mockCtlr.processVirtualServers(vs1, false)).To(BeNil())
+		Expect(rsMap["app_80"].MetaData.baseResources).To(HaveKey("ns1/vs"))
+	})
+
+	It("Requeues the conflicting VirtualServer when the owner is deleted", func() {
+		Expect(mockCtlr.processVirtualServers(vs1, false)).To(BeNil())
+		Expect(mockCtlr.processVirtualServers(vs2, false)).To(BeNil())
+
+		mockCtlr.deleteVirtualServer(vs1)
+		mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+			workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+		Expect(mockCtlr.processVirtualServers(vs1, true)).To(BeNil())
+		Expect(mockCtlr.resources.conflictingNames).To(BeEmpty())
+		Expect(mockCtlr.resourceQueue.Len()).To(Equal(1), "Conflicting VirtualServer should be requeued")
+		key, _ := mockCtlr.resourceQueue.Get()
+		Expect(key.(*rqKey).namespace + "/" + key.(*rqKey).rscName).To(Equal("ns2/vs"))
+
+		Expect(mockCtlr.processVirtualServers(vs2, false)).To(BeNil())
+		rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+		Expect(rsMap["app_80"].MetaData.baseResources).To(HaveKey("ns2/vs"))
+	})
+
+	It("Clears the conflicts of a deleted VirtualServer", func() {
+		Expect(mockCtlr.processVirtualServers(vs1, false)).To(BeNil())
+		Expect(mockCtlr.processVirtualServers(vs2, false)).To(BeNil())
+
+		mockCtlr.deleteVirtualServer(vs2)
+		Expect(mockCtlr.processVirtualServers(vs2, true)).To(BeNil())
+		Expect(mockCtlr.resources.conflictingNames).To(BeEmpty())
+		rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+		Expect(rsMap["app_80"].MetaData.baseResources).To(HaveKey("ns1/vs"))
+	})
+})
@@ -23,14 +23,21 @@ import (
 	"encoding/json"
 	"fmt"
 	listerscorev1 "k8s.io/client-go/listers/core/v1"
+	"net"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	ficV1 "github.com/F5Networks/f5-ipam-controller/pkg/ipamapis/apis/fic/v1"
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/metrics"
+	bigIPPrometheus "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/prometheus"
 	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
 	routeapi "github.com/openshift/api/route/v1"
 	v1 "k8s.io/api/core/v1"
@@ -54,11 +61,18 @@ func (ctlr *Controller) nextGenResourceWorker() {
 	log.Debugf("Starting Custom Resource Worker")
 	ctlr.setInitialServiceCount()
 	ctlr.migrateIPAM()
+	ctlr.validateIPAMLabels()
 	if ctlr.mode == OpenShiftMode {
 		ctlr.processGlobalExtendedRouteConfig()
 	}
 	for ctlr.processResources() {
 	}
+	// resource queue is shut down and drained
+	select {
+	case <-ctlr.resourceQueueDrained:
+	default:
+		close(ctlr.resourceQueueDrained)
+	}
 }
 
 func (ctlr *Controller) setInitialServiceCount() {
@@ -95,18 +109,70 @@ func (ctlr *Controller) processResources() bool {
 		log.Debugf("Resource Queue is empty, Going to StandBy Mode")
 		return false
 	}
+	metrics.ResourceQueueDepth.Set(float64(ctlr.resourceQueue.Len()))
+	// keys queued so far are processed together, so that the resources are processed after their dependencies
+	keys := []interface{}{key}
+	for ctlr.resourceQueue.Len() > 0 {
+		key, quit = ctlr.resourceQueue.Get()
+		if quit {
+			break
+		}
+		keys = append(keys, key)
+	}
+	processed := false
+	for _, key := range ctlr.orderResourceKeys(keys) {
+		if ctlr.processResourceKey(key) {
+			processed = true
+		}
+	}
+	if !processed {
+		return true
+	}
+
+	if ctlr.resourceQueue.Len() == 0 && ctlr.resources.isConfigUpdated() && ctlr.hasMinPartitionsToPost() {
+		config := ResourceConfigRequest{
+			ltmConfig:          ctlr.resources.getLTMConfigDeepCopy(),
+			shareNodes:         ctlr.shareNodes,
+			gtmConfig:          ctlr.resources.getGTMConfigCopy(),
+			defaultRouteDomain: ctlr.defaultRouteDomain,
+			resync:             ctlr.resources.resyncPending,
+		}
+		if config.resync {
+			bigIPPrometheus.ResyncTotal.Inc()
+			log.Debugf("Re-syncing the configuration of all partitions to BIG-IP")
+		}
+		ctlr.setPartitionCredentials(&config)
+		go ctlr.TeemData.PostTeemsData()
+		if ctlr.dryRun {
+			log.Infof("[DryRun] Skipping the post of configuration to BIG-IP")
+		} else {
+			config.reqId = ctlr.enqueueReq(config)
+			ctlr.Agent.PostConfig(config)
+		}
+		ctlr.initState = false
+		ctlr.resources.updateCaches()
+	}
+	return true
+}
+
+// processResourceKey processes the resource of key depending on its kind, it returns false
+// when the resource is requeued without processing during the init time
+func (ctlr *Controller) processResourceKey(key interface{}) bool {
 	var isRetryableError bool
 
 	defer ctlr.resourceQueue.Done(key)
 	rKey := key.(*rqKey)
 	log.Debugf("Processing Key: %v", rKey)
+	startTime := time.Now()
 
 	// During Init time, just accumulate all the poolMembers by processing only services
-	if ctlr.initState && rKey.kind != Namespace {
+	if ctlr.initState && rKey.kind != Namespace && rKey.kind != Resync {
 		if rKey.kind != Service {
 			ctlr.resourceQueue.AddRateLimited(key)
-			return true
+			return false
 		}
+		svc := rKey.rsc.(*v1.Service)
+		ctlr.resources.initialSyncServices[svc.Namespace+"/"+svc.Name] = struct{}{}
 		ctlr.initialSvcCount--
 		if ctlr.initialSvcCount <= 0 {
 			ctlr.initState = false
@@ -187,6 +253,10 @@ func (ctlr *Controller) processResources() bool {
 				delete(ctlr.resources.processedNativeResources, rscRefKey)
 			}
 		}
+		if _, ok := virtual.Annotations[RollbackToAnnotation]; ok && ctlr.enableRollback && !rscDelete {
+			ctlr.processRollback(virtual)
+			break
+		}
 
 		err := ctlr.processVirtualServers(virtual, rscDelete)
 		if err != nil {
@@ -214,6 +284,10 @@ func (ctlr *Controller) processResources() bool {
 		}
 	case K8sSecret:
 		secret := rKey.rsc.(*v1.Secret)
+		if ctlr.credentialSecrets[secret.Namespace] == secret.Name {
+			// partitions skipped for their credentials are posted with the updated secret
+			ctlr.resources.markConfigUpdated()
+		}
 		switch ctlr.mode {
 		case OpenShiftMode:
 			routeGroup := ctlr.getRouteGroupForSecret(secret)
@@ -237,7 +311,20 @@ func (ctlr *Controller) processResources() bool {
 					}
 				}
 			}
+			// rotation of the key to encrypt persistence cookies
+			for _, plc := range ctlr.getPoliciesForSecret(secret) {
+				for _, virtual := range ctlr.getVirtualsForCustomPolicy(plc) {
+					err := ctlr.processVirtualServers(virtual, false)
+					if err != nil {
+						// TODO
+						utilruntime.HandleError(fmt.Errorf("Sync %v failed with %v", key, err))
+						isRetryableError = true
+					}
+				}
+			}
 		}
+		// secret is processed even if none of the SSL profiles is updated with it
+		ctlr.certVersions.refresh(secret)
 
 	case TransportServer:
 		if ctlr.mode == OpenShiftMode || ctlr.mode == KubernetesMode {
@@ -316,6 +403,11 @@ func (ctlr *Controller) processResources() bool {
 	case Service:
 		svc := rKey.rsc.(*v1.Service)
 
+		if rscDelete && ctlr.drainDeletedService(svc) {
+			// members of deleted service are disabled till the drain period of pools
+			ctlr.updatePoolMembersForVirtuals(svc)
+			break
+		}
 		_ = ctlr.processService(svc, nil, rscDelete)
 
 		if svc.Spec.Type == v1.ServiceTypeLoadBalancer {
@@ -379,6 +471,10 @@ func (ctlr *Controller) processResources() bool {
 
 	case Endpoints:
 		ep := rKey.rsc.(*v1.Endpoints)
+		// the scheduled update of members warming up is due, pools still warming up schedule the next one
+		if epKey := ep.Namespace + "/" + ep.Name; ctlr.resources.warmupUpdates[epKey] == rKey {
+			delete(ctlr.resources.warmupUpdates, epKey)
+		}
 		svc := ctlr.getServiceForEndpoints(ep)
 		// No Services are effected with the change in service.
 		if nil == svc {
@@ -482,7 +578,8 @@ func (ctlr *Controller) processResources() bool {
 				ctlr.namespacesMutex.Unlock()
 				log.Debugf("Removed Namespace: '%v' from CIS scope", nsName)
 				triggerDelete = true
-			} else {
+			} else if ctlr.namespaceLabelMode || rKey.event != Update {
+				// namespaces without namespace label are updated only for their BIG-IP partition annotation
 				ctlr.namespacesMutex.Lock()
 				ctlr.namespaces[nsName] = true
 				ctlr.namespacesMutex.Unlock()
@@ -531,29 +628,24 @@ func (ctlr *Controller) processResources() bool {
 				log.Debugf("Added Namespace: '%v' to CIS scope", nsName)
 			}
 		}
+	case Resync:
+		// config is partially processed during init, it is posted once the init is complete
+		if !ctlr.initState {
+			ctlr.resources.markConfigUpdated()
+		}
+	case BigIPNodeState:
+		ctlr.syncBigIPNodeStates()
 	default:
 		log.Errorf("Unknown resource Kind: %v", rKey.kind)
 	}
 
+	metrics.ResourceProcessingDuration.WithLabelValues(rKey.kind).Observe(time.Since(startTime).Seconds())
 	if isRetryableError {
-		ctlr.resourceQueue.AddRateLimited(key)
+		metrics.ResourceProcessingErrors.Inc()
+		ctlr.retryResource(rKey)
 	} else {
 		ctlr.resourceQueue.Forget(key)
 	}
-
-	if ctlr.resourceQueue.Len() == 0 && ctlr.resources.isConfigUpdated() {
-		config := ResourceConfigRequest{
-			ltmConfig:          ctlr.resources.getLTMConfigDeepCopy(),
-			shareNodes:         ctlr.shareNodes,
-			gtmConfig:          ctlr.resources.getGTMConfigCopy(),
-			defaultRouteDomain: ctlr.defaultRouteDomain,
-		}
-		go ctlr.TeemData.PostTeemsData()
-		config.reqId = ctlr.enqueueReq(config)
-		ctlr.Agent.PostConfig(config)
-		ctlr.initState = false
-		ctlr.resources.updateCaches()
-	}
 	return true
 }
 
@@ -584,26 +676,29 @@ func (ctlr *Controller) updatePoolMembersForVirtuals(svc *v1.Service) {
 	namespace := svc.Namespace
 	svcName := svc.Name
 	svcDepRscKey := namespace + "_" + svcName
-	partition := ctlr.Partition
 
 	for rsName := range ctlr.getSvcDepResources(svcDepRscKey) {
-		rsCfg := ctlr.getVirtualServer(partition, rsName)
-		if rsCfg == nil {
-			continue
-		}
+		// virtuals of custom resources are in the partitions of their BIG-IP partition annotation
+		for partition := range ctlr.resources.ltmConfig {
+			rsCfg := ctlr.getVirtualServer(partition, rsName)
+			if rsCfg == nil {
+				continue
+			}
 
-		freshRsCfg := &ResourceConfig{}
-		freshRsCfg.copyConfig(rsCfg)
+			freshRsCfg := &ResourceConfig{}
+			freshRsCfg.copyConfig(rsCfg)
 
-		if ctlr.PoolMemberType == NodePort {
-			ctlr.updatePoolMembersForNodePort(freshRsCfg, namespace)
-		} else if ctlr.PoolMemberType == NodePortLocal {
-			//supported with antrea cni.
-			ctlr.updatePoolMembersForNPL(freshRsCfg, namespace)
-		} else {
-			ctlr.updatePoolMembersForCluster(freshRsCfg, namespace)
+			if ctlr.PoolMemberType == NodePort {
+				ctlr.updatePoolMembersForNodePort(freshRsCfg, namespace)
+			} else if ctlr.PoolMemberType == NodePortLocal {
+				//supported with antrea cni.
+				ctlr.updatePoolMembersForNPL(freshRsCfg, namespace)
+			} else {
+				ctlr.updatePoolMembersForCluster(freshRsCfg, namespace)
+			}
+			ctlr.scaleMonitorIntervals(freshRsCfg, rsCfg)
+			_ = ctlr.resources.setResourceConfig(partition, rsName, freshRsCfg)
 		}
-		_ = ctlr.resources.setResourceConfig(partition, rsName, freshRsCfg)
 	}
 }
 
@@ -611,10 +706,11 @@ func (ctlr *Controller) updatePoolMembersForVirtuals(svc *v1.Service) {
 // by the addition/deletion/updation of service.
 func (ctlr *Controller) getVirtualServersForService(svc *v1.Service) []*cisapiv1.VirtualServer {
 
-	allVirtuals := ctlr.getAllVirtualServers(svc.ObjectMeta.Namespace)
+	// VirtualServers of all namespaces are considered as they can refer the service with serviceNamespace
+	allVirtuals := ctlr.getAllWatchedVirtualServers()
 	if nil == allVirtuals {
-		log.Infof("No VirtualServers found in namespace %s",
-			svc.ObjectMeta.Namespace)
+		log.Infof("No VirtualServers found for service %s/%s",
+			svc.ObjectMeta.Namespace, svc.ObjectMeta.Name)
 		return nil
 	}
 
@@ -720,6 +816,15 @@ func (ctlr *Controller) getLBServicesForCustomPolicy(plc *cisapiv1.Policy) []*v1
 }
 
 // getAllVirtualServers returns list of all valid VirtualServers in rkey namespace.
+// getAllWatchedVirtualServers returns the VirtualServers of all the namespaces watched by CIS
+func (ctlr *Controller) getAllWatchedVirtualServers() []*cisapiv1.VirtualServer {
+	var allVirtuals []*cisapiv1.VirtualServer
+	for namespace := range ctlr.crInformers {
+		allVirtuals = append(allVirtuals, ctlr.getAllVirtualServers(namespace)...)
+	}
+	return allVirtuals
+}
+
 func (ctlr *Controller) getAllVirtualServers(namespace string) []*cisapiv1.VirtualServer {
 	var allVirtuals []*cisapiv1.VirtualServer
 
@@ -775,13 +880,13 @@ func filterVirtualServersForService(allVirtuals []*cisapiv1.VirtualServer,
 	svcNamespace := svc.ObjectMeta.Namespace
 
 	for _, vs := range allVirtuals {
-		if vs.ObjectMeta.Namespace != svcNamespace {
-			continue
-		}
-
 		isValidVirtual := false
 		for _, pool := range vs.Spec.Pools {
-			if pool.Service == svcName {
+			poolSvcNamespace := vs.ObjectMeta.Namespace
+			if pool.ServiceNamespace != "" {
+				poolSvcNamespace = pool.ServiceNamespace
+			}
+			if pool.Service == svcName && poolSvcNamespace == svcNamespace {
 				isValidVirtual = true
 				break
 			}
@@ -868,7 +973,7 @@ func (ctlr *Controller) getTLSProfileForVirtualServer(
 				}
 				clientSecret := clientSecretobj.(*v1.Secret)
 				//validate at least one clientSSL certificates matches the VS hostname
-				if checkCertificateHost(vs.Spec.Host, clientSecret.Data["tls.crt"], clientSecret.Data["tls.key"]) {
+				if ctlr.validateCertificateHost(vs.Spec.Host, clientSecret.Data["tls.crt"], clientSecret.Data["tls.key"]) {
 					match = true
 					break
 				}
@@ -882,7 +987,7 @@ func (ctlr *Controller) getTLSProfileForVirtualServer(
 			}
 			clientSecret := clientSecretobj.(*v1.Secret)
 			//validate clientSSL certificates and hostname
-			match = checkCertificateHost(vs.Spec.Host, clientSecret.Data["tls.crt"], clientSecret.Data["tls.key"])
+			match = ctlr.validateCertificateHost(vs.Spec.Host, clientSecret.Data["tls.crt"], clientSecret.Data["tls.key"])
 		}
 		if match == false {
 			return nil
@@ -947,21 +1052,24 @@ func (ctlr *Controller) processVirtualServers(
 	virtual *cisapiv1.VirtualServer,
 	isVSDeleted bool,
 ) error {
+	logCtx := log.LogContext{Kind: VirtualServer, Namespace: virtual.Namespace, Name: virtual.Name}
 
 	startTime := time.Now()
 	defer func() {
 		endTime := time.Now()
-		log.Debugf("Finished syncing virtual servers %+v (%v)",
+		logCtx.Debugf("Finished syncing virtual servers %+v (%v)",
 			virtual, endTime.Sub(startTime))
 	}()
 
 	// Skip validation for a deleted Virtual Server
-	if !isVSDeleted {
+	if isVSDeleted {
+		ctlr.clearVSNameConflicts(virtual)
+	} else {
 		// check if the virutal server matches all the requirements.
 		vkey := virtual.ObjectMeta.Namespace + "/" + virtual.ObjectMeta.Name
 		valid := ctlr.checkValidVirtualServer(virtual)
 		if false == valid {
-			log.Errorf("VirtualServer %s, is not valid",
+			logCtx.Errorf("VirtualServer %s, is not valid",
 				vkey)
 			return nil
 		}
@@ -980,14 +1088,16 @@ func (ctlr *Controller) processVirtualServers(
 
 	// Prepare list of associated VirtualServers to be processed
 	// In the event of deletion, exclude the deleted VirtualServer
-	log.Debugf("Process all the Virtual Servers which share same VirtualServerAddress")
+	logCtx.Debugf("Process all the Virtual Servers which share same VirtualServerAddress")
 
 	virtuals := ctlr.getAssociatedVirtualServers(virtual, allVirtuals, isVSDeleted)
+	partition := ctlr.getCRPartition(virtual.Annotations)
+	virtuals = ctlr.filterVirtualServersOfPartition(virtuals, partition)
 
 	var ip string
 	var status int
 	if ctlr.ipamCli != nil {
-		if isVSDeleted && len(virtuals) == 0 && virtual.Spec.VirtualServerAddress == "" {
+		if isVSDeleted && len(virtuals) == 0 && len(getVirtualServerAddresses(virtual)) == 0 {
 			if virtual.Spec.HostGroup != "" {
 				//hg is unique across namespaces
 				//all virtuals with same hg are grouped together across namespaces
@@ -997,46 +1107,56 @@ func (ctlr *Controller) processVirtualServers(
 				key := virtual.Namespace + "/" + virtual.Spec.Host + "_host"
 				ip = ctlr.releaseIP(virtual.Spec.IPAMLabel, virtual.Spec.Host, key)
 			}
-		} else if virtual.Spec.VirtualServerAddress != "" {
+		} else if vsAddresses := getVirtualServerAddresses(virtual); len(vsAddresses) > 0 {
 			// Prioritise VirtualServerAddress specified over IPAMLabel
-			ip = virtual.Spec.VirtualServerAddress
+			ip = vsAddresses[0]
 		} else {
 			ipamLabel := getIPAMLabel(virtuals)
+			ipamAnnotations := getIPAMAnnotations(virtuals)
 			if virtual.Spec.HostGroup != "" {
 				//hg is unique across namepsaces
 				key := virtual.Spec.HostGroup + "_hg"
-				ip, status = ctlr.requestIP(ipamLabel, "", key)
+				ip, status = ctlr.requestIPWithAnnotations(ipamLabel, "", key, ipamAnnotations)
 			} else {
 				key := virtual.Namespace + "/" + virtual.Spec.Host + "_host"
-				ip, status = ctlr.requestIP(ipamLabel, virtual.Spec.Host, key)
+				ip, status = ctlr.requestIPWithAnnotations(ipamLabel, virtual.Spec.Host, key, ipamAnnotations)
 			}
 
 			switch status {
 			case NotEnabled:
-				log.Debug("IPAM Custom Resource Not Available")
+				logCtx.Debugf("IPAM Custom Resource Not Available")
 				return nil
 			case InvalidInput:
-				log.Debugf("IPAM Invalid IPAM Label: %v for Virtual Server: %s/%s", ipamLabel, virtual.Namespace, virtual.Name)
+				logCtx.Debugf("IPAM Invalid IPAM Label: %v for Virtual Server: %s/%s", ipamLabel, virtual.Namespace, virtual.Name)
+				if ctlr.ipamLabels.isInvalid(ipamLabel) {
+					ctlr.updateVirtualServerStatus(virtual, "", InvalidIPAMLabel)
+				}
 				return nil
 			case NotRequested:
 				return fmt.Errorf("unable make do IPAM Request, will be re-requested soon")
 			case Requested:
-				log.Debugf("IP address requested for service: %s/%s", virtual.Namespace, virtual.Name)
+				logCtx.Debugf("IP address requested for service: %s/%s", virtual.Namespace, virtual.Name)
+				return nil
+			}
+			if !ctlr.isAllowedVirtualServerAddress(ip) {
+				logCtx.Errorf("IPAM address %v of Virtual Server %s/%s is not in allowed CIDRs", ip, virtual.Namespace, virtual.Name)
+				ctlr.updateVirtualServerStatus(virtual, ip, AddressNotAllowed)
 				return nil
 			}
 			virtual.Status.VSAddress = ip
 		}
 	} else {
 		if virtual.Spec.HostGroup == "" {
-			if virtual.Spec.VirtualServerAddress == "" {
+			vsAddresses := getVirtualServerAddresses(virtual)
+			if len(vsAddresses) == 0 {
 				return fmt.Errorf("No VirtualServer address or IPAM found.")
 			}
-			ip = virtual.Spec.VirtualServerAddress
+			ip = vsAddresses[0]
 		} else {
 			var err error
 			ip, err = getVirtualServerAddress(virtuals)
 			if err != nil {
-				log.Errorf("Error in virtualserver address: %s", err.Error())
+				logCtx.Errorf("Error in virtualserver address: %s", err.Error())
 				return err
 			}
 			if ip == "" {
@@ -1049,162 +1169,215 @@ func (ctlr *Controller) processVirtualServers(
 	}
 	// Depending on the ports defined, TLS type or Unsecured we will populate the resource config.
 	portStructs := ctlr.virtualPorts(virtual)
+	// virtual servers with the same config are created for the additional addresses
+	addresses := []string{ip}
+	for _, address := range getVirtualServerAddresses(virtual) {
+		if address != ip {
+			addresses = append(addresses, address)
+		}
+	}
 
 	// vsMap holds Resource Configs of current virtuals temporarily
 	vsMap := make(ResourceMap)
 	processingError := false
-	for _, portStruct := range portStructs {
-		// TODO: Add Route Domain
-		var rsName string
-		if virtual.Spec.VirtualServerName != "" {
-			rsName = formatCustomVirtualServerName(
-				virtual.Spec.VirtualServerName,
-				portStruct.port,
-			)
-		} else {
-			rsName = formatVirtualServerName(
-				ip,
-				portStruct.port,
-			)
-		}
-
-		// Delete rsCfg if no corresponding virtuals exist
-		// Delete rsCfg if it is HTTP rsCfg and the CR VirtualServer does not handle HTTPTraffic
-		if (len(virtuals) == 0) ||
-			(portStruct.protocol == HTTP && !doVSHandleHTTP(virtuals, virtual)) ||
-			(isVSDeleted && portStruct.protocol == HTTPS && !doVSUseSameHTTPSPort(virtuals, virtual)) {
-			var hostnames []string
-			rsMap := ctlr.resources.getPartitionResourceMap(ctlr.Partition)
-
-			if _, ok := rsMap[rsName]; ok {
-				hostnames = rsMap[rsName].MetaData.hosts
-			}
-			ctlr.deleteSvcDepResource(rsName, rsMap[rsName])
-			ctlr.deleteVirtualServer(ctlr.Partition, rsName)
-			if len(hostnames) > 0 {
-				ctlr.ProcessAssociatedExternalDNS(hostnames)
+	for _, ip := range addresses {
+		for _, portStruct := range portStructs {
+			// TODO: Add Route Domain
+			var rsName string
+			if virtual.Spec.VirtualServerName != "" {
+				vsName := virtual.Spec.VirtualServerName
+				if ip != addresses[0] {
+					// custom name is suffixed with the additional address
+					vsName += "_" + ip
+				}
+				rsName = formatCustomVirtualServerName(
+					vsName,
+					portStruct.port,
+				)
+			} else {
+				rsName = formatVirtualServerName(
+					ip,
+					portStruct.port,
+				)
 			}
-			continue
-		}
 
-		rsCfg := &ResourceConfig{}
-		rsCfg.Virtual.Partition = ctlr.Partition
-		rsCfg.MetaData.ResourceType = VirtualServer
-		rsCfg.Virtual.Enabled = true
-		rsCfg.Virtual.Name = rsName
-		rsCfg.MetaData.hosts = append(rsCfg.MetaData.hosts, virtual.Spec.Host)
-		rsCfg.MetaData.Protocol = portStruct.protocol
-		rsCfg.MetaData.httpTraffic = virtual.Spec.HTTPTraffic
-		rsCfg.Virtual.HttpMrfRoutingEnabled = virtual.Spec.HttpMrfRoutingEnabled
-		rsCfg.MetaData.baseResources = make(map[string]string)
-		rsCfg.Virtual.SetVirtualAddress(
-			ip,
-			portStruct.port,
-		)
-		rsCfg.IntDgMap = make(InternalDataGroupMap)
-		rsCfg.IRulesMap = make(IRulesMap)
-		rsCfg.customProfiles = make(map[SecretKey]CustomProfile)
+			// Delete rsCfg if no corresponding virtuals exist
+			// Delete rsCfg if it is HTTP rsCfg and the CR VirtualServer does not handle HTTPTraffic
+			if (len(virtuals) == 0) ||
+				(portStruct.protocol == HTTP && !doVSHandleHTTP(virtuals, virtual)) ||
+				(isVSDeleted && portStruct.protocol == HTTPS && !doVSUseSameHTTPSPort(virtuals, virtual)) {
+				var hostnames []string
+				rsMap := ctlr.resources.getPartitionResourceMap(partition)
 
-		plc, err := ctlr.getPolicyFromVirtuals(virtuals)
-		if plc != nil {
-			err := ctlr.handleVSResourceConfigForPolicy(rsCfg, plc)
-			if err != nil {
-				processingError = true
-				break
+				// virtual server of other resources with the same name is not deleted
+				if ctlr.isVSNameOwnedByOthers(partition, rsName, virtual, virtuals) {
+					continue
+				}
+				if _, ok := rsMap[rsName]; ok {
+					hostnames = rsMap[rsName].MetaData.hosts
+				}
+				ctlr.deleteSvcDepResource(rsName, rsMap[rsName])
+				ctlr.deleteVirtualServer(partition, rsName)
+				ctlr.requeueVSNameConflict(rsName)
+				if len(hostnames) > 0 {
+					ctlr.ProcessAssociatedExternalDNS(hostnames)
+				}
+				continue
 			}
-		}
-		if err != nil {
-			processingError = true
-			log.Errorf("%v", err)
-			break
-		}
 
-		for _, vrt := range virtuals {
-			passthroughVS := false
-			var tlsProf *cisapiv1.TLSProfile
-			if isTLSVirtualServer(vrt) {
-				// Handle TLS configuration for VirtualServer Custom Resource
-				tlsProf = ctlr.getTLSProfileForVirtualServer(vrt, vrt.Namespace)
-				if tlsProf == nil {
-					// Processing failed
-					// Stop processing further virtuals
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Partition = partition
+			rsCfg.MetaData.ResourceType = VirtualServer
+			rsCfg.Virtual.Enabled = true
+			rsCfg.Virtual.Name = rsName
+			rsCfg.MetaData.hosts = append(rsCfg.MetaData.hosts, virtual.Spec.Host)
+			rsCfg.MetaData.Protocol = portStruct.protocol
+			rsCfg.MetaData.httpTraffic = virtual.Spec.HTTPTraffic
+			rsCfg.MetaData.Application = ctlr.getAS3Application(virtuals)
+			rsCfg.Virtual.HttpMrfRoutingEnabled = virtual.Spec.HttpMrfRoutingEnabled
+			rsCfg.Virtual.DNS64Prefix = virtual.Spec.DNS64Prefix
+			rsCfg.MetaData.baseResources = make(map[string]string)
+			rsCfg.Virtual.SetVirtualAddress(
+				ip,
+				portStruct.port,
+			)
+			rsCfg.Virtual.SetRouteDomain(ctlr.namespaceRouteDomains[virtual.Namespace])
+			rsCfg.IntDgMap = make(InternalDataGroupMap)
+			rsCfg.IRulesMap = make(IRulesMap)
+			rsCfg.customProfiles = make(map[SecretKey]CustomProfile)
+
+			plc, err := ctlr.getPolicyFromVirtuals(virtuals)
+			if plc != nil {
+				err := ctlr.handleVSResourceConfigForPolicy(rsCfg, plc)
+				if err != nil {
+					logCtx.Errorf("%v", err)
 					processingError = true
 					break
 				}
-				if tlsProf.Spec.TLS.Termination == TLSPassthrough {
-					passthroughVS = true
-				}
 			}
-
-			log.Debugf("Processing Virtual Server %s for port %v",
-				vrt.ObjectMeta.Name, portStruct.port)
-			rsCfg.MetaData.baseResources[vrt.Namespace+"/"+vrt.Name] = VirtualServer
-			err := ctlr.prepareRSConfigFromVirtualServer(
-				rsCfg,
-				vrt,
-				passthroughVS,
-			)
 			if err != nil {
 				processingError = true
+				logCtx.Errorf("%v", err)
 				break
 			}
 
-			if tlsProf != nil {
-				processed := ctlr.handleVirtualServerTLS(rsCfg, vrt, tlsProf, ip)
-				if !processed {
-					// Processing failed
-					// Stop processing further virtuals
+			for _, vrt := range virtuals {
+				passthroughVS := false
+				var tlsProf *cisapiv1.TLSProfile
+				if isTLSVirtualServer(vrt) {
+					// Handle TLS configuration for VirtualServer Custom Resource
+					tlsProf = ctlr.getTLSProfileForVirtualServer(vrt, vrt.Namespace)
+					if tlsProf == nil {
+						// Processing failed
+						// Stop processing further virtuals
+						processingError = true
+						break
+					}
+					if tlsProf.Spec.TLS.Termination == TLSPassthrough {
+						passthroughVS = true
+					}
+				}
+
+				logCtx.Debugf("Processing Virtual Server %s for port %v",
+					vrt.ObjectMeta.Name, portStruct.port)
+				rsCfg.MetaData.baseResources[vrt.Namespace+"/"+vrt.Name] = VirtualServer
+				err := ctlr.prepareRSConfigFromVirtualServer(
+					rsCfg,
+					vrt,
+					passthroughVS,
+				)
+				if err != nil {
 					processingError = true
 					break
 				}
+				// passthrough virtual does not process HTTP responses
+				if !passthroughVS {
+					handleContentSecurityPolicy(rsCfg, vrt)
+					handleRequestBodyRewrite(rsCfg, vrt)
+					if vrt.Spec.WebSocketEnabled {
+						handleWebSocket(rsCfg)
+					}
+				}
+
+				if tlsProf != nil {
+					processed := ctlr.handleVirtualServerTLS(rsCfg, vrt, tlsProf, ip)
+					if !processed {
+						// Processing failed
+						// Stop processing further virtuals
+						processingError = true
+						break
+					}
+
+					logCtx.Debugf("Updated Virtual %s with TLSProfile %s",
+						vrt.ObjectMeta.Name, vrt.Spec.TLSProfileName)
+				}
+
+				ctlr.updateSvcDepResources(rsName, rsCfg)
+
+				ctlr.resources.processedNativeResources[resourceRef{
+					kind:      VirtualServer,
+					namespace: vrt.Namespace,
+					name:      vrt.Name,
+				}] = struct{}{}
 
-				log.Debugf("Updated Virtual %s with TLSProfile %s",
-					vrt.ObjectMeta.Name, vrt.Spec.TLSProfileName)
 			}
 
-			ctlr.updateSvcDepResources(rsName, rsCfg)
+			if processingError {
+				logCtx.Errorf("Cannot Publish VirtualServer %s", virtual.ObjectMeta.Name)
+				break
+			}
 
-			ctlr.resources.processedNativeResources[resourceRef{
-				kind:      VirtualServer,
-				namespace: vrt.Namespace,
-				name:      vrt.Name,
-			}] = struct{}{}
+			// persistence of HostGroup takes precedence over the persistence of Policies and VirtualServers
+			if profile := getHostGroupPersistenceProfile(virtuals); profile != "" {
+				rsCfg.Virtual.PersistenceProfile = profile
+			}
 
-		}
+			// do not overwrite the virtual server of other resources with the same name
+			if ctlr.checkVSNameConflict(rsName, rsCfg, virtual) {
+				return nil
+			}
+			// do not create another virtual server with the address of other resources
+			if ctlr.checkVSAddressConflict(rsName, rsCfg, virtual) {
+				return nil
+			}
+
+			// Save ResourceConfig in temporary Map
+			vsMap[rsName] = rsCfg
 
+			if ctlr.PoolMemberType == NodePort {
+				ctlr.updatePoolMembersForNodePort(rsCfg, virtual.ObjectMeta.Namespace)
+			} else if ctlr.PoolMemberType == NodePortLocal {
+				//supported with antrea cni.
+				ctlr.updatePoolMembersForNPL(rsCfg, virtual.ObjectMeta.Namespace)
+			} else {
+				ctlr.updatePoolMembersForCluster(rsCfg, virtual.ObjectMeta.Namespace)
+			}
+		}
 		if processingError {
-			log.Errorf("Cannot Publish VirtualServer %s", virtual.ObjectMeta.Name)
 			break
 		}
-
-		// Save ResourceConfig in temporary Map
-		vsMap[rsName] = rsCfg
-
-		if ctlr.PoolMemberType == NodePort {
-			ctlr.updatePoolMembersForNodePort(rsCfg, virtual.ObjectMeta.Namespace)
-		} else if ctlr.PoolMemberType == NodePortLocal {
-			//supported with antrea cni.
-			ctlr.updatePoolMembersForNPL(rsCfg, virtual.ObjectMeta.Namespace)
-		} else {
-			ctlr.updatePoolMembersForCluster(rsCfg, virtual.ObjectMeta.Namespace)
-		}
 	}
 
 	if !processingError {
 		var hostnames []string
-		rsMap := ctlr.resources.getPartitionResourceMap(ctlr.Partition)
+		rsMap := ctlr.resources.getPartitionResourceMap(partition)
 
 		// Update ltmConfig with ResourceConfigs created for the current virtuals
 		for rsName, rsCfg := range vsMap {
 			if _, ok := rsMap[rsName]; !ok {
 				hostnames = rsCfg.MetaData.hosts
 			}
+			ctlr.resources.updateSharedPoolRefCount(rsMap[rsName], rsCfg)
+			ctlr.scaleMonitorIntervals(rsCfg, rsMap[rsName])
 			rsMap[rsName] = rsCfg
 		}
 
 		if len(hostnames) > 0 {
 			ctlr.ProcessAssociatedExternalDNS(hostnames)
 		}
+		if ctlr.dryRun && len(vsMap) > 0 {
+			ctlr.reportDryRunNames(virtual, vsMap)
+		}
 	}
 
 	return nil
@@ -1272,7 +1445,8 @@ func (ctlr *Controller) getAssociatedVirtualServers(
 			}
 
 			// Same host with different VirtualServerAddress is invalid
-			if vrt.Spec.VirtualServerAddress != currentVS.Spec.VirtualServerAddress {
+			if vrt.Spec.VirtualServerAddress != currentVS.Spec.VirtualServerAddress ||
+				!reflect.DeepEqual(vrt.Spec.VirtualServerAddresses, currentVS.Spec.VirtualServerAddresses) {
 				if vrt.Spec.Host != "" {
 					log.Errorf("Same host %v is configured with different VirtualServerAddress : %v ", vrt.Spec.Host, vrt.Spec.VirtualServerName)
 					return nil
@@ -1343,6 +1517,7 @@ func (ctlr *Controller) getPolicyFromVirtuals(virtuals []*cisapiv1.VirtualServer
 	if plcName == "" {
 		return nil, nil
 	}
+	ctlr.checkHostGroupPersistenceConflict(virtuals)
 	crInf, ok := ctlr.getNamespacedCommonInformer(ns)
 	if !ok {
 		return nil, fmt.Errorf("Informer not found for namespace: %v", ns)
@@ -1409,6 +1584,19 @@ func getIPAMLabel(virtuals []*cisapiv1.VirtualServer) string {
 	return ""
 }
 
+// getVirtualServerAddresses returns the unique addresses of VirtualServerAddress and VirtualServerAddresses
+func getVirtualServerAddresses(vs *cisapiv1.VirtualServer) []string {
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, address := range append([]string{vs.Spec.VirtualServerAddress}, vs.Spec.VirtualServerAddresses...) {
+		if address != "" && !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
 func getVirtualServerAddress(virtuals []*cisapiv1.VirtualServer) (string, error) {
 	vsa := ""
 	for _, vrt := range virtuals {
@@ -1426,13 +1614,17 @@ func getVirtualServerAddress(virtuals []*cisapiv1.VirtualServer) (string, error)
 	return vsa, nil
 }
 
-func (ctlr *Controller) getIPAMCR() *ficV1.IPAM {
+// getIPAMCR returns the IPAM CR in the namespace, the default one if namespace is empty
+func (ctlr *Controller) getIPAMCR(namespace string) *ficV1.IPAM {
 	cr := strings.Split(ctlr.ipamCR, "/")
 	if len(cr) != 2 {
 		log.Errorf("[ipam] error while retrieving IPAM namespace and name.")
 		return nil
 	}
-	ipamCR, err := ctlr.ipamCli.Get(cr[0], cr[1])
+	if namespace == "" {
+		namespace = cr[0]
+	}
+	ipamCR, err := ctlr.ipamCli.Get(namespace, cr[1])
 	if err != nil {
 		log.Errorf("[ipam] error while retrieving IPAM custom resource.")
 		return nil
@@ -1445,31 +1637,28 @@ func (ctlr *Controller) migrateIPAM() {
 		return
 	}
 
-	ipamCR := ctlr.getIPAMCR()
-	if ipamCR == nil {
-		return
-	}
-
 	var specsToMigrate []ficV1.IPSpec
 
-	for _, spec := range ipamCR.Status.IPStatus {
-		idx := strings.LastIndex(spec.Key, "_")
-		var rscKind string
-		if idx != -1 {
-			rscKind = spec.Key[idx+1:]
-			switch rscKind {
-			case "host", "ts", "il", "svc":
-				// This entry is fine, process next entry
-				continue
-			case "hg":
-				//Check for format of hg.if key is of format ns/hostgroup_hg
-				//this is stale entry from older version, release ip
-				if !strings.Contains(spec.Key, "/") {
+	for _, ipamCR := range ctlr.getIPAMCRs() {
+		for _, spec := range ipamCR.Status.IPStatus {
+			idx := strings.LastIndex(spec.Key, "_")
+			var rscKind string
+			if idx != -1 {
+				rscKind = spec.Key[idx+1:]
+				switch rscKind {
+				case "host", "ts", "il", "svc":
+					// This entry is fine, process next entry
 					continue
+				case "hg":
+					//Check for format of hg.if key is of format ns/hostgroup_hg
+					//this is stale entry from older version, release ip
+					if !strings.Contains(spec.Key, "/") {
+						continue
+					}
 				}
 			}
+			specsToMigrate = append(specsToMigrate, *spec)
 		}
-		specsToMigrate = append(specsToMigrate, *spec)
 	}
 
 	for _, spec := range specsToMigrate {
@@ -1479,7 +1668,17 @@ func (ctlr *Controller) migrateIPAM() {
 
 // Request IPAM for virtual IP address
 func (ctlr *Controller) requestIP(ipamLabel string, host string, key string) (string, int) {
-	ipamCR := ctlr.getIPAMCR()
+	return ctlr.requestIPWithAnnotations(ipamLabel, host, key, nil)
+}
+
+// requestIPWithAnnotations requests the IP address with the annotations passed to the IPAM controller
+func (ctlr *Controller) requestIPWithAnnotations(
+	ipamLabel string,
+	host string,
+	key string,
+	annotations map[string]string,
+) (string, int) {
+	ipamCR := ctlr.getIPAMCR(ctlr.getIPAMNamespace(ipamLabel))
 	var ip string
 	var ipReleased bool
 	if ipamCR == nil {
@@ -1490,6 +1689,11 @@ func (ctlr *Controller) requestIP(ipamLabel string, host string, key string) (st
 		return "", InvalidInput
 	}
 
+	if ctlr.ipamLabels.isInvalid(ipamLabel) {
+		log.Errorf("[IPAM] IPAM label %v is not served by the IPAM controller", ipamLabel)
+		return "", InvalidInput
+	}
+
 	if host != "" {
 		//For VS server
 		for _, ipst := range ipamCR.Status.IPStatus {
@@ -1502,6 +1706,8 @@ func (ctlr *Controller) requestIP(ipamLabel string, host string, key string) (st
 		for _, hst := range ipamCR.Spec.HostSpecs {
 			if hst.Host == host {
 				if hst.IPAMLabel == ipamLabel {
+					// annotations may be updated after the IP is requested
+					ctlr.updateIPAMHostAnnotations(ipamCR, hst.Key, annotations)
 					if ip != "" {
 						// IP extracted from the corresponding status of the spec
 						return ip, Allocated
@@ -1525,12 +1731,14 @@ func (ctlr *Controller) requestIP(ipamLabel string, host string, key string) (st
 			return "", NotRequested
 		}
 
+		ctlr.releaseIPOfOtherIPAMCRs(ipamCR, host, "")
 		ipamCR.SetResourceVersion(ipamCR.ResourceVersion)
 		ipamCR.Spec.HostSpecs = append(ipamCR.Spec.HostSpecs, &ficV1.HostSpec{
 			Host:      host,
 			Key:       key,
 			IPAMLabel: ipamLabel,
 		})
+		setIPAMHostAnnotations(ipamCR, key, annotations)
 	} else if key != "" {
 		//For Transport Server
 		for _, ipst := range ipamCR.Status.IPStatus {
@@ -1543,6 +1751,8 @@ func (ctlr *Controller) requestIP(ipamLabel string, host string, key string) (st
 		for _, hst := range ipamCR.Spec.HostSpecs {
 			if hst.Key == key {
 				if hst.IPAMLabel == ipamLabel {
+					// annotations may be updated after the IP is requested
+					ctlr.updateIPAMHostAnnotations(ipamCR, hst.Key, annotations)
 					if ip != "" {
 						// IP extracted from the corresponding status of the spec
 						return ip, Allocated
@@ -1566,11 +1776,13 @@ func (ctlr *Controller) requestIP(ipamLabel string, host string, key string) (st
 			return "", NotRequested
 		}
 
+		ctlr.releaseIPOfOtherIPAMCRs(ipamCR, "", key)
 		ipamCR.SetResourceVersion(ipamCR.ResourceVersion)
 		ipamCR.Spec.HostSpecs = append(ipamCR.Spec.HostSpecs, &ficV1.HostSpec{
 			Key:       key,
 			IPAMLabel: ipamLabel,
 		})
+		setIPAMHostAnnotations(ipamCR, key, annotations)
 	} else {
 		log.Debugf("[IPAM] Invalid host and key.")
 		return "", InvalidInput
@@ -1613,6 +1825,7 @@ func (ctlr *Controller) RemoveIPAMCRHostSpec(ipamCR *ficV1.IPAM, key string, ind
 	}
 	if !isExists {
 		delete(ctlr.resources.ipamContext, key)
+		setIPAMHostAnnotations(ipamCR, ipamCR.Spec.HostSpecs[index].Key, nil)
 		ipamCR.Spec.HostSpecs = append(ipamCR.Spec.HostSpecs[:index], ipamCR.Spec.HostSpecs[index+1:]...)
 		ipamCR.SetResourceVersion(ipamCR.ResourceVersion)
 		return ctlr.ipamCli.Update(ipamCR)
@@ -1621,7 +1834,7 @@ func (ctlr *Controller) RemoveIPAMCRHostSpec(ipamCR *ficV1.IPAM, key string, ind
 }
 
 func (ctlr *Controller) releaseIP(ipamLabel string, host string, key string) string {
-	ipamCR := ctlr.getIPAMCR()
+	ipamCR := ctlr.getIPAMCR(ctlr.getIPAMNamespace(ipamLabel))
 	var ip string
 	if ipamCR == nil || ipamLabel == "" {
 		return ip
@@ -1698,6 +1911,11 @@ func (ctlr *Controller) updatePoolMembersForNodePort(
 	}
 
 	for index, pool := range rsCfg.Pools {
+		// members of SRV record pool are resolved by BIG-IP
+		if pool.SRVRecord != "" {
+			rsCfg.MetaData.Active = true
+			continue
+		}
 		svcName := pool.ServiceName
 		svcKey := pool.ServiceNamespace + "/" + svcName
 
@@ -1718,12 +1936,17 @@ func (ctlr *Controller) updatePoolMembersForNodePort(
 				rsCfg.MetaData.Active = true
 				rsCfg.Pools[index].Members =
 					ctlr.getEndpointsForNodePort(svcPort.NodePort, pool.NodeMemberLabel)
+				if poolMemInfo.backup {
+					rsCfg.Pools[index].Members = markBackupMembers(rsCfg.Pools[index].Members)
+				}
 			}
 		}
 		//check if endpoints are found
 		if rsCfg.Pools[index].Members == nil {
 			log.Errorf("[CORE]Endpoints could not be fetched for service %v with targetPort %v", svcName, pool.ServicePort.IntVal)
 		}
+		prioritizeBackupMembers(&rsCfg.Pools[index])
+		ctlr.updatePoolMembersWarmup(&rsCfg.Pools[index])
 	}
 }
 
@@ -1734,6 +1957,11 @@ func (ctlr *Controller) updatePoolMembersForCluster(
 	namespace string,
 ) {
 	for index, pool := range rsCfg.Pools {
+		// members of SRV record pool are resolved by BIG-IP
+		if pool.SRVRecord != "" {
+			rsCfg.MetaData.Active = true
+			continue
+		}
 		svcName := pool.ServiceName
 		svcKey := pool.ServiceNamespace + "/" + svcName
 
@@ -1749,13 +1977,103 @@ func (ctlr *Controller) updatePoolMembersForCluster(
 				continue
 			}
 			rsCfg.MetaData.Active = true
+			if pool.NodeMemberLabel != "" {
+				mems = ctlr.filterMembersByNodeLabel(mems, pool.NodeMemberLabel)
+			}
+			if ctlr.controllerZone != "" {
+				mems = filterMembersByZone(mems, ctlr.controllerZone)
+			}
+			if ctlr.priorityGroupLabel != "" {
+				mems = ctlr.setMemberPriorityGroups(mems)
+			}
+			if ctlr.bigipPodCIDR != nil {
+				mems = ctlr.disableMembersBlockedByNetworkPolicy(mems, pool.ServiceNamespace)
+			}
 			rsCfg.Pools[index].Members = mems
 		}
 		//check if endpoints are found
 		if rsCfg.Pools[index].Members == nil {
 			log.Errorf("[CORE]Endpoints could not be fetched for service %v with targetPort %v", svcName, pool.ServicePort.IntVal)
 		}
+		prioritizeBackupMembers(&rsCfg.Pools[index])
+		ctlr.updatePoolMembersWarmup(&rsCfg.Pools[index])
+	}
+}
+
+// updatePoolMembersWarmup sets the ratio of pool members, which ramps up linearly
+// from 0 to 100 over the warm-up time of pool after the member is added, and
+// schedules the pool members update till all the members are warmed up
+func (ctlr *Controller) updatePoolMembersWarmup(pool *Pool) {
+	if pool.WarmupTime <= 0 {
+		return
+	}
+	svcKey := pool.ServiceNamespace + "/" + pool.ServiceName
+	warmupTime := time.Duration(pool.WarmupTime) * time.Second
+	if ctlr.resources.memberWarmupStart == nil {
+		ctlr.resources.memberWarmupStart = make(map[string]map[string]time.Time)
+	}
+	now := time.Now()
+	prevStart, ok := ctlr.resources.memberWarmupStart[svcKey]
+	// members of services found at the initial sync, e.g. on controller restart, are considered as warmed up
+	if _, initial := ctlr.resources.initialSyncServices[svcKey]; !ok && initial {
+		now = now.Add(-warmupTime)
+	}
+	delete(ctlr.resources.initialSyncServices, svcKey)
+	warmupStart := make(map[string]time.Time)
+	warmingUp := false
+	// copy the members as they are shared with pool member cache
+	members := make([]PoolMember, len(pool.Members))
+	for i, member := range pool.Members {
+		memberKey := fmt.Sprintf("%v:%v", member.Address, member.Port)
+		start, ok := prevStart[memberKey]
+		if !ok {
+			start = now
+		}
+		warmupStart[memberKey] = start
+		member.Ratio = 100
+		if elapsed := time.Since(start); elapsed < warmupTime {
+			member.Ratio = int(elapsed * 100 / warmupTime)
+			warmingUp = true
+		}
+		members[i] = member
+	}
+	pool.Members = members
+	ctlr.resources.memberWarmupStart[svcKey] = warmupStart
+
+	if warmingUp {
+		ctlr.enqueuePoolMembersUpdate(pool.ServiceNamespace, pool.ServiceName, warmupTime/10)
+	}
+}
+
+// enqueuePoolMembersUpdate enqueues the endpoints of service after the given delay
+// to update the pool members, a single update is pending per service however many
+// pools of the service are warming up
+func (ctlr *Controller) enqueuePoolMembersUpdate(namespace, svcName string, delay time.Duration) {
+	svcKey := namespace + "/" + svcName
+	if _, ok := ctlr.resources.warmupUpdates[svcKey]; ok {
+		return
+	}
+	comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
+	if !ok || comInf.epsInformer == nil || ctlr.resourceQueue == nil {
+		return
+	}
+	obj, found, _ := comInf.epsInformer.GetIndexer().GetByKey(svcKey)
+	if !found {
+		return
+	}
+	if delay < time.Second {
+		delay = time.Second
+	}
+	ep := obj.(*v1.Endpoints)
+	rKey := &rqKey{
+		namespace: namespace,
+		kind:      Endpoints,
+		rscName:   ep.Name,
+		rsc:       ep,
+		event:     Update,
 	}
+	ctlr.resources.warmupUpdates[svcKey] = rKey
+	ctlr.resourceQueue.AddAfter(rKey, delay)
 }
 
 // updatePoolMembersForNodePortLocal updates the pool with pool members for a
@@ -1771,6 +2089,11 @@ func (ctlr *Controller) updatePoolMembersForNPL(
 	}
 
 	for index, pool := range rsCfg.Pools {
+		// members of SRV record pool are resolved by BIG-IP
+		if pool.SRVRecord != "" {
+			rsCfg.MetaData.Active = true
+			continue
+		}
 		svcName := pool.ServiceName
 		svcKey := pool.ServiceNamespace + "/" + svcName
 		poolMemInfo := ctlr.resources.poolMemCache[svcKey]
@@ -1779,7 +2102,7 @@ func (ctlr *Controller) updatePoolMembersForNPL(
 				svcKey)
 			return
 		}
-		pods := ctlr.GetPodsForService(namespace, svcName, true)
+		pods := ctlr.GetPodsForService(pool.ServiceNamespace, svcName, true)
 		if pods != nil {
 			for _, svcPort := range poolMemInfo.portSpec {
 				if svcPort.TargetPort == pool.ServicePort {
@@ -1787,10 +2110,14 @@ func (ctlr *Controller) updatePoolMembersForNPL(
 					rsCfg.MetaData.Active = true
 					rsCfg.Pools[index].Members =
 						ctlr.getEndpointsForNPL(podPort, pods)
-
+					if poolMemInfo.backup {
+						rsCfg.Pools[index].Members = markBackupMembers(rsCfg.Pools[index].Members)
+					}
 				}
 			}
 		}
+		prioritizeBackupMembers(&rsCfg.Pools[index])
+		ctlr.updatePoolMembersWarmup(&rsCfg.Pools[index])
 	}
 }
 
@@ -1801,10 +2128,18 @@ func (ctlr *Controller) getEndpointsForNodePort(
 ) []PoolMember {
 	var nodes []Node
 	if nodeMemberLabel == "" {
-		nodes = ctlr.getNodesFromCache()
+		if ctlr.excludeTaintedNodes {
+			nodes = ctlr.getUntaintedNodes()
+		} else {
+			nodes = ctlr.getNodesFromCache(nil)
+		}
 	} else {
 		nodes = ctlr.getNodesWithLabel(nodeMemberLabel)
 	}
+	var priorityGroups map[string]int32
+	if ctlr.priorityGroupLabel != "" {
+		priorityGroups = ctlr.getPriorityGroups()
+	}
 	var members []PoolMember
 	for _, v := range nodes {
 		member := PoolMember{
@@ -1812,12 +2147,112 @@ func (ctlr *Controller) getEndpointsForNodePort(
 			Port:    nodePort,
 			Session: "user-enabled",
 		}
+		if value, ok := v.Labels[ctlr.priorityGroupLabel]; ok {
+			member.PriorityGroup = priorityGroups[value]
+		}
 		members = append(members, member)
 	}
 
 	return members
 }
 
+// getPriorityGroups returns the priority group for each value of the priority group label of nodes.
+// Value of the controller node gets the highest priority, followed by the other values in sorted order
+func (ctlr *Controller) getPriorityGroups() map[string]int32 {
+	var values []string
+	for _, node := range ctlr.getNodesFromCache(nil) {
+		if value, ok := node.Labels[ctlr.priorityGroupLabel]; ok && value != ctlr.controllerPriorityValue {
+			values = appendUniqueString(values, value)
+		}
+	}
+	sort.Strings(values)
+	if ctlr.controllerPriorityValue != "" {
+		values = append([]string{ctlr.controllerPriorityValue}, values...)
+	}
+	priorityGroups := make(map[string]int32)
+	for i, value := range values {
+		priorityGroups[value] = int32(len(values) - i)
+	}
+	return priorityGroups
+}
+
+// setMemberPriorityGroups sets the priority group of members based on the label of their nodes
+func (ctlr *Controller) setMemberPriorityGroups(members []PoolMember) []PoolMember {
+	priorityGroups := ctlr.getPriorityGroups()
+	nodeLabels := make(map[string]map[string]string)
+	for _, node := range ctlr.getNodesFromCache(nil) {
+		nodeLabels[node.Name] = node.Labels
+	}
+	// copy the members as they are shared with pool member cache
+	prioritizedMembers := make([]PoolMember, len(members))
+	for i, member := range members {
+		prioritizedMembers[i] = member
+		if value, ok := nodeLabels[member.NodeName][ctlr.priorityGroupLabel]; ok {
+			prioritizedMembers[i].PriorityGroup = priorityGroups[value]
+		}
+	}
+	return prioritizedMembers
+}
+
+// filterMembersByNodeLabel returns the members running on nodes with the given label
+func (ctlr *Controller) filterMembersByNodeLabel(
+	members []PoolMember,
+	label string,
+) []PoolMember {
+	nodes := ctlr.getNodesWithLabel(label)
+	filteredMembers := []PoolMember{}
+	for _, member := range members {
+		if member.NodeName != "" && containsNode(nodes, member.NodeName) {
+			filteredMembers = append(filteredMembers, member)
+		}
+	}
+	return filteredMembers
+}
+
+// filterMembersByZone returns the members in given topology zone,
+// all the members are returned when none of them are in the zone
+func filterMembersByZone(
+	members []PoolMember,
+	zone string,
+) []PoolMember {
+	filteredMembers := []PoolMember{}
+	for _, member := range members {
+		if member.Zone == zone {
+			filteredMembers = append(filteredMembers, member)
+		}
+	}
+	if len(filteredMembers) == 0 {
+		return members
+	}
+	return filteredMembers
+}
+
+// hasZoneTopologyKey checks whether the service routes by topology zone
+func hasZoneTopologyKey(svc *v1.Service) bool {
+	for _, key := range svc.Spec.TopologyKeys {
+		if key == v1.LabelTopologyZone {
+			return true
+		}
+	}
+	return false
+}
+
+// getTargetPortOverride returns the port of pods provided in service annotation,
+// used when the pods run on a port different from the one in endpoints
+func getTargetPortOverride(svc *v1.Service) (int32, bool) {
+	portStr, ok := svc.Annotations[TargetPortOverrideAnnotation]
+	if !ok {
+		return 0, false
+	}
+	port, err := strconv.ParseInt(portStr, 10, 32)
+	if err != nil || port < 1 || port > 65535 {
+		log.Errorf("Invalid %v annotation %v for service %v/%v",
+			TargetPortOverrideAnnotation, portStr, svc.Namespace, svc.Name)
+		return 0, false
+	}
+	return int32(port), true
+}
+
 // getEndpointsForNPL returns members.
 func (ctlr *Controller) getEndpointsForNPL(
 	targetPort intstr.IntOrString,
@@ -1829,25 +2264,24 @@ func (ctlr *Controller) getEndpointsForNPL(
 		if !found {
 			continue
 		}
-		var podPort int32
+		podPorts := make(map[int32]struct{})
 		//Support for named targetPort
 		if targetPort.StrVal != "" {
 			targetPortStr := targetPort.StrVal
-			//Get the containerPort matching targetPort from pod spec.
+			//Get the containerPorts matching targetPort from all the containers of pod spec.
 			for _, container := range pod.Spec.Containers {
 				for _, port := range container.Ports {
-					portStr := port.Name
-					if targetPortStr == portStr {
-						podPort = port.ContainerPort
+					if targetPortStr == port.Name {
+						podPorts[port.ContainerPort] = struct{}{}
 					}
 				}
 			}
 		} else {
 			// targetPort with int value
-			podPort = targetPort.IntVal
+			podPorts[targetPort.IntVal] = struct{}{}
 		}
 		for _, annotation := range anns {
-			if annotation.PodPort == podPort {
+			if _, ok := podPorts[annotation.PodPort]; ok {
 				member := PoolMember{
 					Address: annotation.NodeIP,
 					Port:    annotation.NodePort,
@@ -1877,10 +2311,11 @@ func (ctlr *Controller) processTransportServers(
 	virtual *cisapiv1.TransportServer,
 	isTSDeleted bool,
 ) error {
+	logCtx := log.LogContext{Kind: TransportServer, Namespace: virtual.Namespace, Name: virtual.Name}
 	startTime := time.Now()
 	defer func() {
 		endTime := time.Now()
-		log.Debugf("Finished syncing transport servers %+v (%v)",
+		logCtx.Debugf("Finished syncing transport servers %+v (%v)",
 			virtual, endTime.Sub(startTime))
 	}()
 
@@ -1890,7 +2325,7 @@ func (ctlr *Controller) processTransportServers(
 		vkey := virtual.ObjectMeta.Namespace + "/" + virtual.ObjectMeta.Name
 		valid := ctlr.checkValidTransportServer(virtual)
 		if false == valid {
-			log.Errorf("TransportServer %s, is not valid",
+			logCtx.Errorf("TransportServer %s, is not valid",
 				vkey)
 			return nil
 		}
@@ -1922,16 +2357,24 @@ func (ctlr *Controller) processTransportServers(
 
 			switch status {
 			case NotEnabled:
-				log.Debug("IPAM Custom Resource Not Available")
+				logCtx.Debugf("IPAM Custom Resource Not Available")
 				return nil
 			case InvalidInput:
-				log.Debugf("IPAM Invalid IPAM Label: %v for Transport Server: %s/%s",
+				logCtx.Debugf("IPAM Invalid IPAM Label: %v for Transport Server: %s/%s",
 					virtual.Spec.IPAMLabel, virtual.Namespace, virtual.Name)
+				if ctlr.ipamLabels.isInvalid(virtual.Spec.IPAMLabel) {
+					ctlr.updateTransportServerStatus(virtual, "", InvalidIPAMLabel)
+				}
 				return nil
 			case NotRequested:
 				return fmt.Errorf("unable to make IPAM Request, will be re-requested soon")
 			case Requested:
-				log.Debugf("IP address requested for Transport Server: %s/%s", virtual.Namespace, virtual.Name)
+				logCtx.Debugf("IP address requested for Transport Server: %s/%s", virtual.Namespace, virtual.Name)
+				return nil
+			}
+			if !ctlr.isAllowedVirtualServerAddress(ip) {
+				logCtx.Errorf("IPAM address %v of Transport Server %s/%s is not in allowed CIDRs", ip, virtual.Namespace, virtual.Name)
+				ctlr.updateTransportServerStatus(virtual, ip, AddressNotAllowed)
 				return nil
 			}
 			virtual.Status.VSAddress = ip
@@ -1943,6 +2386,7 @@ func (ctlr *Controller) processTransportServers(
 		ip = virtual.Spec.VirtualServerAddress
 	}
 
+	partition := ctlr.getCRPartition(virtual.Annotations)
 	var rsName string
 	if virtual.Spec.VirtualServerName != "" {
 		rsName = formatCustomVirtualServerName(
@@ -1957,14 +2401,14 @@ func (ctlr *Controller) processTransportServers(
 	}
 
 	if isTSDeleted {
-		rsMap := ctlr.resources.getPartitionResourceMap(ctlr.Partition)
+		rsMap := ctlr.resources.getPartitionResourceMap(partition)
 		ctlr.deleteSvcDepResource(rsName, rsMap[rsName])
-		ctlr.deleteVirtualServer(ctlr.Partition, rsName)
+		ctlr.deleteVirtualServer(partition, rsName)
 		return nil
 	}
 
 	rsCfg := &ResourceConfig{}
-	rsCfg.Virtual.Partition = ctlr.Partition
+	rsCfg.Virtual.Partition = partition
 	rsCfg.MetaData.ResourceType = TransportServer
 	rsCfg.Virtual.Enabled = true
 	rsCfg.Virtual.Name = rsName
@@ -1976,20 +2420,21 @@ func (ctlr *Controller) processTransportServers(
 		ip,
 		virtual.Spec.VirtualServerPort,
 	)
+	rsCfg.Virtual.SetRouteDomain(ctlr.namespaceRouteDomains[virtual.Namespace])
 	plc, err := ctlr.getPolicyFromTransportServer(virtual)
 	if plc != nil {
 		err := ctlr.handleTSResourceConfigForPolicy(rsCfg, plc)
 		if err != nil {
-			log.Errorf("%v", err)
+			logCtx.Errorf("%v", err)
 			return nil
 		}
 	}
 	if err != nil {
-		log.Errorf("%v", err)
+		logCtx.Errorf("%v", err)
 		return nil
 	}
 
-	log.Debugf("Processing Transport Server %s for port %v",
+	logCtx.Debugf("Processing Transport Server %s for port %v",
 		virtual.ObjectMeta.Name, virtual.Spec.VirtualServerPort)
 	rsCfg.MetaData.baseResources[virtual.ObjectMeta.Namespace+"/"+virtual.ObjectMeta.Name] = TransportServer
 	err = ctlr.prepareRSConfigFromTransportServer(
@@ -1997,9 +2442,12 @@ func (ctlr *Controller) processTransportServers(
 		virtual,
 	)
 	if err != nil {
-		log.Errorf("Cannot Publish TransportServer %s", virtual.ObjectMeta.Name)
+		logCtx.Errorf("Cannot Publish TransportServer %s", virtual.ObjectMeta.Name)
 		return nil
 	}
+	if useHardwareAcceleration(plc, true) {
+		rsCfg.Virtual.ProfileL4 = FastL4Profile
+	}
 
 	ctlr.updateSvcDepResources(rsName, rsCfg)
 
@@ -2009,7 +2457,7 @@ func (ctlr *Controller) processTransportServers(
 		ctlr.updatePoolMembersForCluster(rsCfg, virtual.ObjectMeta.Namespace)
 	}
 
-	rsMap := ctlr.resources.getPartitionResourceMap(ctlr.Partition)
+	rsMap := ctlr.resources.getPartitionResourceMap(partition)
 	rsMap[rsName] = rsCfg
 
 	return nil
@@ -2362,8 +2810,14 @@ func (ctlr *Controller) processService(
 	svcKey := svc.Namespace + "/" + svc.Name
 	if isSVCDeleted {
 		delete(ctlr.resources.poolMemCache, svcKey)
+		delete(ctlr.resources.drainingMembers, svcKey)
+		delete(ctlr.resources.memberWarmupStart, svcKey)
+		delete(ctlr.resources.warmupUpdates, svcKey)
+		delete(ctlr.resources.initialSyncServices, svcKey)
 		return nil
 	}
+	// service recreated within the drain period of its deleted members
+	delete(ctlr.resources.drainingServices, svcKey)
 
 	if eps == nil {
 		comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
@@ -2383,19 +2837,42 @@ func (ctlr *Controller) processService(
 		svcType:   svc.Spec.Type,
 		portSpec:  svc.Spec.Ports,
 		memberMap: make(map[portRef][]PoolMember),
+		backup:    isBackupService(svc),
 	}
 
-	nodes := ctlr.getNodesFromCache()
+	nodes := ctlr.getNodesFromCache(nil)
+	// zones of nodes to group the members by topology zone
+	var nodeZones map[string]string
+	if ctlr.topologyAwareRouting && hasZoneTopologyKey(svc) {
+		nodeZones = make(map[string]string)
+		for _, node := range nodes {
+			nodeZones[node.Name] = node.Labels[v1.LabelTopologyZone]
+		}
+	}
+	// members are keyed by the endpoint port to match the port of pools,
+	// while the traffic is sent to the overridden port of pods
+	overridePort, portOverridden := getTargetPortOverride(svc)
+	routeDomain := ctlr.namespaceRouteDomains[namespace]
 	for _, subset := range eps.Subsets {
 		for _, p := range subset.Ports {
 			var members []PoolMember
+			memberPort := p.Port
+			if portOverridden {
+				memberPort = overridePort
+			}
 			for _, addr := range subset.Addresses {
 				// Checking for headless services
 				if svc.Spec.ClusterIP == "None" || (addr.NodeName != nil && containsNode(nodes, *addr.NodeName)) {
 					member := PoolMember{
-						Address: addr.IP,
-						Port:    p.Port,
-						Session: "user-enabled",
+						Address:     addr.IP,
+						Port:        memberPort,
+						Session:     "user-enabled",
+						RouteDomain: routeDomain,
+						Backup:      pmi.backup,
+					}
+					if addr.NodeName != nil {
+						member.NodeName = *addr.NodeName
+						member.Zone = nodeZones[member.NodeName]
 					}
 					members = append(members, member)
 				}
@@ -2404,12 +2881,38 @@ func (ctlr *Controller) processService(
 			pmi.memberMap[portKey] = members
 		}
 	}
+	ctlr.updateDrainingMembers(svc, &pmi)
 
 	ctlr.resources.poolMemCache[svcKey] = pmi
 
 	return nil
 }
 
+// isOrderedFailover checks whether the pools of ExternalDNS are failed over in their order,
+// which is supported only with global-availability load balance method
+func isOrderedFailover(edns *cisapiv1.ExternalDNS) bool {
+	if !edns.Spec.OrderedFailover {
+		return false
+	}
+	if edns.Spec.LoadBalanceMethod != GlobalAvailabilityLBMethod {
+		log.Warningf("Ignoring orderedFailover of EDNS %v as loadBalanceMethod is not %v",
+			edns.Spec.DomainName, GlobalAvailabilityLBMethod)
+		return false
+	}
+	return true
+}
+
+// sortGSLBPoolsByOrder sorts the pools of WideIP in the ascending order, pools without order are
+// placed after the ordered pools. BIG-IP evaluates the pools of WideIP in the order they are listed.
+func sortGSLBPoolsByOrder(pools []GSLBPool) {
+	sort.SliceStable(pools, func(i, j int) bool {
+		if pools[i].PriorityOrder == 0 || pools[j].PriorityOrder == 0 {
+			return pools[j].PriorityOrder == 0 && pools[i].PriorityOrder != 0
+		}
+		return pools[i].PriorityOrder < pools[j].PriorityOrder
+	})
+}
+
 func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete bool) {
 
 	if gtmPartitionConfig, ok := ctlr.resources.gtmConfig[DEFAULT_PARTITION]; ok {
@@ -2419,6 +2922,22 @@ func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete
 				return
 			}
 		}
+		// Two wildcard domains can match the same hostname, the one processed first is retained
+		if !isDelete && isWildcardDomain(edns.Spec.DomainName) {
+			for domainName, processedWIP := range gtmPartitionConfig.WideIPs {
+				if processedWIP.UID == string(edns.UID) || !isWildcardDomain(domainName) {
+					continue
+				}
+				if matchesDomainName(domainName, edns.Spec.DomainName[1:]) ||
+					matchesDomainName(edns.Spec.DomainName, domainName[1:]) {
+					message := fmt.Sprintf("EDNS domain name %s conflicts with EDNS domain name %s",
+						edns.Spec.DomainName, domainName)
+					log.Error(message)
+					ctlr.recordExternalDNSEvent(edns, v1.EventTypeWarning, "DomainConflict", message)
+					return
+				}
+			}
+		}
 	}
 
 	if isDelete {
@@ -2427,16 +2946,28 @@ func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete
 		}
 
 		delete(ctlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs, edns.Spec.DomainName)
+		// GTM pool members shared with the CIS instances of other clusters are removed
+		ctlr.federateWideIP(&WideIP{DomainName: edns.Spec.DomainName})
 		ctlr.TeemData.Lock()
 		ctlr.TeemData.ResourceType.ExternalDNS[edns.Namespace]--
 		ctlr.TeemData.Unlock()
 		return
 	}
 
+	if err := validateExternalDNSPools(edns); err != nil {
+		message := fmt.Sprintf("Invalid pools of EDNS %s: %v", edns.Spec.DomainName, err)
+		log.Error(message)
+		ctlr.recordExternalDNSEvent(edns, v1.EventTypeWarning, "InvalidPool", message)
+		return
+	}
+
 	ctlr.TeemData.Lock()
 	ctlr.TeemData.ResourceType.ExternalDNS[edns.Namespace] = len(ctlr.getAllExternalDNS(edns.Namespace))
 	ctlr.TeemData.Unlock()
 
+	// GTM servers of EDNS are updated on changing their GTM datacenters
+	ctlr.startDatacenterWatch()
+
 	wip := WideIP{
 		DomainName: edns.Spec.DomainName,
 		RecordType: edns.Spec.DNSRecordType,
@@ -2450,6 +2981,9 @@ func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete
 	if edns.Spec.LoadBalanceMethod == "" {
 		wip.LBMethod = "round-robin"
 	}
+	if edns.Spec.TopologyEnabled {
+		wip.LBMethod = TopologyLBMethod
+	}
 
 	log.Debugf("Processing WideIP: %v", edns.Spec.DomainName)
 
@@ -2461,8 +2995,14 @@ func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete
 		partitions = append(partitions, DEFAULT_PARTITION)
 	}
 
-	for _, pl := range edns.Spec.Pools {
-		UniquePoolName := edns.Spec.DomainName + "_" + AS3NameFormatter(strings.TrimPrefix(ctlr.Agent.BIGIPURL, "https://")) + "_" + ctlr.Partition
+	orderedFailover := isOrderedFailover(edns)
+	for i, pl := range edns.Spec.Pools {
+		// pools of ordered failover are named uniquely, as each of them is a failover target of WideIP
+		var poolSuffix string
+		if orderedFailover && i > 0 {
+			poolSuffix = fmt.Sprintf("_%d", i)
+		}
+		UniquePoolName := edns.Spec.DomainName + "_" + AS3NameFormatter(strings.TrimPrefix(ctlr.Agent.BIGIPURL, "https://")) + "_" + ctlr.Partition + poolSuffix
 		log.Debugf("Processing WideIP Pool: %v", UniquePoolName)
 		pool := GSLBPool{
 			Name:          UniquePoolName,
@@ -2478,13 +3018,26 @@ func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete
 		if pl.LoadBalanceMethod == "" {
 			pool.LBMethod = "round-robin"
 		}
-		for _, partition := range partitions {
+		// members of CNAME pool are the target domain names instead of the virtuals of domain
+		poolPartitions := partitions
+		if pool.RecordType == CNAMERecordType {
+			for _, target := range pl.CNAMETargets {
+				if errs := validation.IsDNS1123Subdomain(strings.ToLower(target)); len(errs) > 0 {
+					log.Errorf("Invalid CNAME target %v of WideIP %v: %v", target, edns.Spec.DomainName,
+						strings.Join(errs, ", "))
+					continue
+				}
+				pool.Members = append(pool.Members, target)
+			}
+			poolPartitions = nil
+		}
+		for _, partition := range poolPartitions {
 			rsMap := ctlr.resources.getPartitionResourceMap(partition)
 
 			for vsName, vs := range rsMap {
 				var found bool
 				for _, host := range vs.MetaData.hosts {
-					if host == edns.Spec.DomainName {
+					if matchesDomainName(edns.Spec.DomainName, host) {
 						found = true
 						break
 					}
@@ -2501,24 +3054,24 @@ func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete
 					// add only one VS member to pool.
 					if len(pool.Members) > 0 && strings.HasPrefix(vsName, "ingress_link_") {
 						if strings.HasSuffix(vsName, "_443") {
-							pool.Members[0] = fmt.Sprintf("%v/%v/Shared/%v", preGTMServerName, partition, vsName)
+							pool.Members[0] = fmt.Sprintf("%v/%v/%v/%v", preGTMServerName, partition, vs.as3Application(), vsName)
 							if partition != ctlr.Partition {
 								// Modify pool name to partition containing VS
-								pool.Name = edns.Spec.DomainName + "_" + AS3NameFormatter(strings.TrimPrefix(ctlr.Agent.BIGIPURL, "https://")) + "_" + partition
+								pool.Name = edns.Spec.DomainName + "_" + AS3NameFormatter(strings.TrimPrefix(ctlr.Agent.BIGIPURL, "https://")) + "_" + partition + poolSuffix
 							}
 						}
 						continue
 					}
-					log.Debugf("Adding WideIP Pool Member: %v", fmt.Sprintf("/%v/Shared/%v",
-						partition, vsName))
+					log.Debugf("Adding WideIP Pool Member: %v", fmt.Sprintf("/%v/%v/%v",
+						partition, vs.as3Application(), vsName))
 					// Modify pool name to partition containing VS
 					if partition != ctlr.Partition {
 						// Modify pool name to partition containing VS
-						pool.Name = edns.Spec.DomainName + "_" + AS3NameFormatter(strings.TrimPrefix(ctlr.Agent.BIGIPURL, "https://")) + "_" + partition
+						pool.Name = edns.Spec.DomainName + "_" + AS3NameFormatter(strings.TrimPrefix(ctlr.Agent.BIGIPURL, "https://")) + "_" + partition + poolSuffix
 					}
 					pool.Members = append(
 						pool.Members,
-						fmt.Sprintf("%v/%v/Shared/%v", preGTMServerName, partition, vsName),
+						fmt.Sprintf("%v/%v/%v/%v", preGTMServerName, partition, vs.as3Application(), vsName),
 					)
 				}
 			}
@@ -2565,8 +3118,31 @@ func (ctlr *Controller) processExternalDNS(edns *cisapiv1.ExternalDNS, isDelete
 			}
 			pool.Monitors = monitors
 		}
+		if wip.LBMethod == TopologyLBMethod {
+			pool.TopologyRecords = ctlr.getTopologyRecords(edns, pl)
+		}
 		wip.Pools = append(wip.Pools, pool)
 	}
+	sortGSLBPoolsByOrder(wip.Pools)
+	// GTM pool members are shared with the CIS instances of other clusters
+	ctlr.federateWideIP(&wip)
+	if listener := edns.Spec.DNSListener; listener != nil {
+		if net.ParseIP(listener.Address) == nil {
+			log.Errorf("Invalid address %v of DNS listener for EDNS %v", listener.Address, edns.Spec.DomainName)
+		} else {
+			wip.DNSListener = &DNSListener{
+				Address:    listener.Address,
+				Port:       listener.Port,
+				UDPProfile: listener.UDPProfile,
+			}
+			if wip.DNSListener.Port == 0 {
+				wip.DNSListener.Port = DefaultDNSListenerPort
+			}
+			if wip.DNSListener.UDPProfile == "" {
+				wip.DNSListener.UDPProfile = DefaultDNSListenerUDPProfile
+			}
+		}
+	}
 	if _, ok := ctlr.resources.gtmConfig[DEFAULT_PARTITION]; !ok {
 		ctlr.resources.gtmConfig[DEFAULT_PARTITION] = GTMPartitionConfig{
 			WideIPs: make(map[string]WideIP),
@@ -2621,24 +3197,115 @@ func (ctlr *Controller) ProcessRouteEDNS(hosts []string) {
 }
 
 func (ctlr *Controller) ProcessAssociatedExternalDNS(hostnames []string) {
-	var allEDNS []*cisapiv1.ExternalDNS
-	if ctlr.watchingAllNamespaces() {
-		allEDNS = ctlr.getAllExternalDNS("")
-	} else {
-		for ns := range ctlr.namespaces {
-			allEDNS = append(allEDNS, ctlr.getAllExternalDNS(ns)...)
-		}
-	}
-	for _, edns := range allEDNS {
+	for _, edns := range ctlr.getAllMonitoredExternalDNS() {
 		for _, hostname := range hostnames {
-			if edns.Spec.DomainName == hostname {
+			if matchesDomainName(edns.Spec.DomainName, hostname) {
 				ctlr.processExternalDNS(edns, false)
+				break
 			}
 		}
 	}
 }
 
+// getTopologyRecords returns the valid topology records of EDNS pool ordered by their order
+func (ctlr *Controller) getTopologyRecords(edns *cisapiv1.ExternalDNS, pl cisapiv1.DNSPool) []TopologyRecord {
+	var records []TopologyRecord
+	regions := make(map[string]struct{})
+	for _, record := range pl.TopologyRecords {
+		if record.Region != "" {
+			if _, ok := regions[record.Region]; ok {
+				message := fmt.Sprintf("Duplicate region %v of topology records of pool %v in EDNS %v",
+					record.Region, pl.DataServerName, edns.Spec.DomainName)
+				log.Warning(message)
+				ctlr.recordExternalDNSEvent(edns, v1.EventTypeWarning, "InvalidTopologyRecord", message)
+				continue
+			}
+			regions[record.Region] = struct{}{}
+		}
+		if _, _, err := net.ParseCIDR(record.Source); err != nil {
+			message := fmt.Sprintf("Invalid source %v of topology record in EDNS %v", record.Source, edns.Spec.DomainName)
+			log.Error(message)
+			ctlr.recordExternalDNSEvent(edns, v1.EventTypeWarning, "InvalidTopologyRecord", message)
+			continue
+		}
+		if record.Destination != "" {
+			if _, _, err := net.ParseCIDR(record.Destination); err != nil {
+				message := fmt.Sprintf("Invalid destination %v of topology record in EDNS %v",
+					record.Destination, edns.Spec.DomainName)
+				log.Error(message)
+				ctlr.recordExternalDNSEvent(edns, v1.EventTypeWarning, "InvalidTopologyRecord", message)
+				continue
+			}
+		}
+		records = append(records, TopologyRecord{
+			Source:      record.Source,
+			Destination: record.Destination,
+			Order:       record.Order,
+			Region:      record.Region,
+		})
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Order < records[j].Order
+	})
+	return records
+}
+
+// isWildcardDomain checks whether the EDNS domain name is a wildcard domain like *.example.com
+func isWildcardDomain(domainName string) bool {
+	return strings.HasPrefix(domainName, "*.")
+}
+
+// matchesDomainName checks whether the hostname belongs to the EDNS domain name
+// A wildcard domain name matches any hostname ending with its suffix
+func matchesDomainName(domainName, hostname string) bool {
+	if isWildcardDomain(domainName) {
+		return strings.HasSuffix(hostname, domainName[1:])
+	}
+	return domainName == hostname
+}
+
+func (ctlr *Controller) recordExternalDNSEvent(
+	edns *cisapiv1.ExternalDNS,
+	eventType string,
+	reason string,
+	message string,
+) {
+	if ctlr.eventNotifier == nil || ctlr.kubeClient == nil {
+		return
+	}
+	evNotifier := ctlr.eventNotifier.CreateNotifierForNamespace(
+		edns.Namespace, ctlr.kubeClient.CoreV1())
+	evNotifier.RecordEvent(edns, eventType, reason, message)
+}
+
 // Validate certificate hostname
+// DefaultCertValidationTimeout is the timeout to validate the hostname of certificates
+const DefaultCertValidationTimeout = 5 * time.Second
+
+// validateCertificateHost checks the hostname of certificate within the validation timeout,
+// certificates not validated in time are rejected
+func (ctlr *Controller) validateCertificateHost(host string, certificate []byte, key []byte) bool {
+	if ctlr.skipCertHostCheck {
+		return true
+	}
+	if ctlr.certValidationTimeout <= 0 {
+		return checkCertificateHost(host, certificate, key)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ctlr.certValidationTimeout)
+	defer cancel()
+	result := make(chan bool, 1)
+	go func() {
+		result <- checkCertificateHost(host, certificate, key)
+	}()
+	select {
+	case ok := <-result:
+		return ok
+	case <-ctx.Done():
+		log.Warningf("Timed out validating the certificate of host %v after %v", host, ctlr.certValidationTimeout)
+		return false
+	}
+}
+
 func checkCertificateHost(host string, certificate []byte, key []byte) bool {
 	cert, certErr := tls.X509KeyPair(certificate, key)
 	if certErr != nil {
@@ -2896,6 +3563,8 @@ func (ctlr *Controller) processIngressLink(
 				ctlr.ProcessAssociatedExternalDNS(hostnames)
 			}
 		}
+		// GTM pool members shared with the CIS instances of other clusters are removed
+		ctlr.requeueFederatedExternalDNS(ingLink)
 		ctlr.TeemData.Lock()
 		ctlr.TeemData.ResourceType.IngressLink[ingLink.Namespace]--
 		ctlr.TeemData.Unlock()
@@ -2964,11 +3633,22 @@ func (ctlr *Controller) processIngressLink(
 			ServiceNamespace: svc.ObjectMeta.Namespace,
 		}
 		monitorName := fmt.Sprintf("%s_monitor", pool.Name)
-		rsCfg.Monitors = append(
-			rsCfg.Monitors,
-			Monitor{Name: monitorName, Partition: rsCfg.Virtual.Partition, Interval: 20,
-				Type: "http", Send: "GET /nginx-ready HTTP/1.1\r\n", Recv: "", Timeout: 10, TargetPort: targetPort})
-		pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitorName})
+		if monitor := getIngressLinkMonitor(ingLink, port.Port); monitor == nil {
+			rsCfg.Monitors = append(
+				rsCfg.Monitors,
+				Monitor{Name: monitorName, Partition: rsCfg.Virtual.Partition, Interval: 20,
+					Type: "http", Send: "GET /nginx-ready HTTP/1.1\r\n", Recv: "", Timeout: 10, TargetPort: targetPort})
+			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitorName})
+		} else if monitor.Name != "" && monitor.Reference == BIGIP {
+			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitor.Name, Reference: monitor.Reference})
+		} else {
+			// monitor probes the port of pool members
+			rsCfg.Monitors = append(
+				rsCfg.Monitors,
+				Monitor{Name: monitorName, Partition: rsCfg.Virtual.Partition, Interval: monitor.Interval,
+					Type: monitor.Type, Send: monitor.Send, Recv: monitor.Recv, Timeout: monitor.Timeout})
+			pool.MonitorNames = append(pool.MonitorNames, MonitorName{Name: monitorName})
+		}
 		rsCfg.Virtual.PoolName = pool.Name
 		rsCfg.Pools = append(rsCfg.Pools, pool)
 		// Update rsMap with ResourceConfigs created for the current ingresslink virtuals
@@ -2994,6 +3674,17 @@ func (ctlr *Controller) processIngressLink(
 	return nil
 }
 
+// getIngressLinkMonitor returns the monitor of IngressLink whose targetPort matches
+// the given port of NGINX Ingress Controller service
+func getIngressLinkMonitor(ingLink *cisapiv1.IngressLink, port int32) *cisapiv1.Monitor {
+	for i := range ingLink.Spec.Monitors {
+		if ingLink.Spec.Monitors[i].TargetPort == port {
+			return &ingLink.Spec.Monitors[i]
+		}
+	}
+	return nil
+}
+
 func (ctlr *Controller) getAllIngressLinks(namespace string) []*cisapiv1.IngressLink {
 	var allIngLinks []*cisapiv1.IngressLink
 
@@ -3411,6 +4102,82 @@ func (ctlr *Controller) matchSvcSelectorPodLabels(svcSelector, podLabel map[stri
 	return true
 }
 
+// getPodForMemberIP returns the pod backing the pool member IP by correlating it with endpoint addresses
+func (ctlr *Controller) getPodForMemberIP(namespace, memberIP string) *v1.Pod {
+	comInf, ok := ctlr.getNamespacedCommonInformer(namespace)
+	if !ok {
+		log.Errorf("Informer not found for namespace: %v", namespace)
+		return nil
+	}
+	for _, obj := range comInf.epsInformer.GetIndexer().List() {
+		eps := obj.(*v1.Endpoints)
+		if eps.Namespace != namespace {
+			continue
+		}
+		for _, subset := range eps.Subsets {
+			for _, addr := range subset.Addresses {
+				if addr.IP != memberIP || addr.TargetRef == nil || addr.TargetRef.Kind != "Pod" {
+					continue
+				}
+				if comInf.podInformer != nil {
+					item, found, _ := comInf.podInformer.GetIndexer().GetByKey(
+						addr.TargetRef.Namespace + "/" + addr.TargetRef.Name)
+					if found {
+						return item.(*v1.Pod)
+					}
+				}
+				pod, err := ctlr.kubeClient.CoreV1().Pods(addr.TargetRef.Namespace).Get(
+					context.TODO(), addr.TargetRef.Name, metav1.GetOptions{})
+				if err != nil {
+					log.Debugf("Unable to fetch pod %v/%v: %v", addr.TargetRef.Namespace, addr.TargetRef.Name, err)
+					return nil
+				}
+				return pod
+			}
+		}
+	}
+	return nil
+}
+
+// propagateHealthScore sets the BIG-IP pool member health score as a condition on the pod status
+func (ctlr *Controller) propagateHealthScore(pod *v1.Pod, score float64) error {
+	status := v1.ConditionTrue
+	if score <= 0 {
+		status = v1.ConditionFalse
+	}
+	condition := v1.PodCondition{
+		Type:               BigIPMemberHealthCondition,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Message:            strconv.FormatFloat(score, 'f', -1, 64),
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == BigIPMemberHealthCondition {
+			if cond.Message == condition.Message && cond.Status == condition.Status {
+				return nil
+			}
+			if cond.Status == condition.Status {
+				condition.LastTransitionTime = cond.LastTransitionTime
+			}
+			break
+		}
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []v1.PodCondition{condition},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ctlr.kubeClient.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name,
+		types.StrategicMergePatchType, patch, metav1.PatchOptions{}, "status")
+	if err != nil {
+		log.Errorf("Error while updating health score on pod %v/%v: %v", pod.Namespace, pod.Name, err)
+	}
+	return err
+}
+
 // processPod populates NPL annotations for a pod in store.
 func (ctlr *Controller) processPod(pod *v1.Pod, ispodDeleted bool) error {
 	podKey := pod.Namespace + "/" + pod.Name
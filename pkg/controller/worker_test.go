@@ -4,6 +4,7 @@ import (
 	"container/list"
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/resource"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/teem"
 	routeapi "github.com/openshift/api/route/v1"
@@ -11,9 +12,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/util/workqueue"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"sort"
-	"strconv"
 	"sync"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	cisapiv1 "github.com/F5Networks/k8s-bigip-ctlr/v2/config/apis/cis/v1"
+	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/irules"
 	"github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/test"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -199,13 +201,13 @@ var _ = Describe("Worker Tests", func() {
 
 		It("Get IPAM Resource", func() {
 			_ = mockCtlr.createIPAMResource()
-			ipamCR := mockCtlr.getIPAMCR()
+			ipamCR := mockCtlr.getIPAMCR("")
 			Expect(ipamCR).NotTo(BeNil(), "Failed to GET IPAM")
 			mockCtlr.ipamCR = mockCtlr.ipamCR + "invalid"
-			ipamCR = mockCtlr.getIPAMCR()
+			ipamCR = mockCtlr.getIPAMCR("")
 			Expect(ipamCR).To(BeNil(), "Failed to GET IPAM")
 			mockCtlr.ipamCR = mockCtlr.ipamCR + "/invalid"
-			ipamCR = mockCtlr.getIPAMCR()
+			ipamCR = mockCtlr.getIPAMCR("")
 			Expect(ipamCR).To(BeNil(), "Failed to GET IPAM")
 		})
 
@@ -231,7 +233,7 @@ var _ = Describe("Worker Tests", func() {
 				ip, status := mockCtlr.requestIP("test", host, key)
 				Expect(status).To(Equal(Requested), errHint+"Failed to Request IP")
 				Expect(ip).To(BeEmpty(), errHint+"IP available even before requesting")
-				ipamCR := mockCtlr.getIPAMCR()
+				ipamCR := mockCtlr.getIPAMCR("")
 				Expect(len(ipamCR.Spec.HostSpecs)).To(Equal(1), errHint+"Invalid number of Host Specs")
 				Expect(ipamCR.Spec.HostSpecs[0].IPAMLabel).To(Equal("test"), errHint+"IPAM Request Failed")
 				Expect(ipamCR.Spec.HostSpecs[0].Host).To(Equal(host), errHint+"IPAM Request Failed")
@@ -240,13 +242,13 @@ var _ = Describe("Worker Tests", func() {
 				ip, status = mockCtlr.requestIP("", host, key)
 				Expect(status).To(Equal(InvalidInput), errHint+"Failed to validate invalid input")
 				Expect(ip).To(BeEmpty(), errHint+"Failed to validate invalid input")
-				newIPAMCR := mockCtlr.getIPAMCR()
+				newIPAMCR := mockCtlr.getIPAMCR("")
 				Expect(reflect.DeepEqual(ipamCR, newIPAMCR)).To(BeTrue(), errHint+"IPAM CR should not be updated")
 
 				ip, status = mockCtlr.requestIP("test", host, key)
 				Expect(status).To(Equal(Requested), errHint+"Wrong status")
 				Expect(ip).To(BeEmpty(), errHint+"Invalid IP")
-				newIPAMCR = mockCtlr.getIPAMCR()
+				newIPAMCR = mockCtlr.getIPAMCR("")
 				Expect(reflect.DeepEqual(ipamCR, newIPAMCR)).To(BeTrue(), errHint+"IPAM CR should not be updated")
 
 				ipamCR.Status.IPStatus = []*ficV1.IPSpec{
@@ -261,7 +263,7 @@ var _ = Describe("Worker Tests", func() {
 				ip, status = mockCtlr.requestIP("test", host, key)
 				Expect(ip).To(Equal("10.10.10.1"), errHint+"Invalid IP")
 				Expect(status).To(Equal(Allocated), "Failed to fetch Allocated IP")
-				ipamCR = mockCtlr.getIPAMCR()
+				ipamCR = mockCtlr.getIPAMCR("")
 				Expect(len(ipamCR.Spec.HostSpecs)).To(Equal(1), errHint+"Invalid number of Host Specs")
 				Expect(ipamCR.Spec.HostSpecs[0].IPAMLabel).To(Equal("test"), errHint+"IPAM Request Failed")
 				Expect(ipamCR.Spec.HostSpecs[0].Host).To(Equal(host), errHint+"IPAM Request Failed")
@@ -270,7 +272,7 @@ var _ = Describe("Worker Tests", func() {
 				ip, status = mockCtlr.requestIP("dev", host, key)
 				Expect(status).To(Equal(Requested), "Failed to Request IP")
 				Expect(ip).To(BeEmpty(), errHint+"Invalid IP")
-				ipamCR = mockCtlr.getIPAMCR()
+				ipamCR = mockCtlr.getIPAMCR("")
 				// TODO: The expected number of Specs is 1. After the bug gets fixed update this to 1 from 2.
 				Expect(len(ipamCR.Spec.HostSpecs)).To(Equal(2), errHint+"Invalid number of Host Specs")
 				Expect(ipamCR.Spec.HostSpecs[0].Host).To(Equal(host), errHint+"IPAM Request Failed")
@@ -279,7 +281,7 @@ var _ = Describe("Worker Tests", func() {
 				ip, status = mockCtlr.requestIP("test", "", "")
 				Expect(status).To(Equal(InvalidInput), errHint+"Failed to validate invalid input")
 				Expect(ip).To(BeEmpty(), errHint+"Invalid IP")
-				newIPAMCR = mockCtlr.getIPAMCR()
+				newIPAMCR = mockCtlr.getIPAMCR("")
 				Expect(reflect.DeepEqual(ipamCR, newIPAMCR)).To(BeTrue(), errHint+"IPAM CR should not be updated")
 
 				ipamCR.Spec.HostSpecs = []*ficV1.HostSpec{}
@@ -320,7 +322,7 @@ var _ = Describe("Worker Tests", func() {
 				ip := mockCtlr.releaseIP("", host, key)
 				Expect(ip).To(BeEmpty(), errHint+"Unexpected IP address released")
 
-				ipamCR := mockCtlr.getIPAMCR()
+				ipamCR := mockCtlr.getIPAMCR("")
 				ipamCR.Spec.HostSpecs = []*ficV1.HostSpec{
 					{
 						IPAMLabel: "test",
@@ -339,7 +341,7 @@ var _ = Describe("Worker Tests", func() {
 				ipamCR, _ = mockCtlr.ipamCli.Update(ipamCR)
 
 				ip = mockCtlr.releaseIP("test", host, key)
-				ipamCR = mockCtlr.getIPAMCR()
+				ipamCR = mockCtlr.getIPAMCR("")
 				Expect(len(ipamCR.Spec.HostSpecs)).To(Equal(0), errHint+"IP Address Not released")
 				Expect(ip).To(Equal("10.10.10.1"), errHint+"Wrong IP Address released")
 			}
@@ -412,6 +414,26 @@ var _ = Describe("Worker Tests", func() {
 			Expect(len(res)).To(Equal(2), "Wrong list of Virtual Servers")
 			Expect(res[0]).To(Equal(vrt2), "Wrong list of Virtual Servers")
 			Expect(res[1]).To(Equal(vrt3), "Wrong list of Virtual Servers")
+
+			// VirtualServer of gateway namespace referring the service with serviceNamespace
+			vrt4 := test.NewVirtualServer(
+				"GatewayVS",
+				"gateway",
+				cisapiv1.VirtualServerSpec{
+					Host:                 "test4.com",
+					VirtualServerAddress: "1.2.3.7",
+					Pools: []cisapiv1.Pool{
+						{
+							Path:             "/path",
+							Service:          "svc",
+							ServiceNamespace: ns,
+						},
+					},
+				})
+			vrt5 := vrt4.DeepCopy()
+			vrt5.Spec.Pools[0].ServiceNamespace = ""
+			res = filterVirtualServersForService([]*cisapiv1.VirtualServer{vrt4, vrt5}, svc)
+			Expect(res).To(Equal([]*cisapiv1.VirtualServer{vrt4}), "Wrong list of Virtual Servers")
 		})
 		It("Filter TS for Service", func() {
 			ns := "temp"
@@ -645,6 +667,16 @@ var _ = Describe("Worker Tests", func() {
 				Expect(virts).To(BeNil(), "Wrong Number of Virtual Servers")
 			})
 
+			It("Virtuals with same Host, but different Virtual Addresses", func() {
+				vrt4.Spec.Host = "test2.com"
+				vrt2.Spec.VirtualServerAddresses = []string{"1.2.3.6"}
+
+				virts := mockCtlr.getAssociatedVirtualServers(vrt2,
+					[]*cisapiv1.VirtualServer{vrt2, vrt4},
+					false)
+				Expect(virts).To(BeNil(), "Wrong Number of Virtual Servers")
+			})
+
 			It("HostGroup", func() {
 				vrt2.Spec.HostGroup = "test"
 				vrt3.Spec.HostGroup = "test"
@@ -818,6 +850,269 @@ var _ = Describe("Worker Tests", func() {
 			Expect(len(mems)).To(Equal(0), "Wrong set of Endpoints for NodePort")
 		})
 
+		It("Cluster with target port override", func() {
+			mockCtlr.resources = NewResourceStore()
+			svc := test.NewService("svc1", "1", "default", v1.ServiceTypeClusterIP,
+				[]v1.ServicePort{{Name: "http", Port: 80}})
+			svc.Annotations = map[string]string{TargetPortOverrideAnnotation: "8080"}
+			nodeName := "worker1"
+			eps := &v1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "default"},
+				Subsets: []v1.EndpointSubset{{
+					Addresses: []v1.EndpointAddress{{IP: "10.244.1.1", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Name: "http", Port: 80}},
+				}},
+			}
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+
+			rsCfg := &ResourceConfig{}
+			rsCfg.Pools = []Pool{{ServiceName: "svc1", ServiceNamespace: "default", ServicePort: intstr.FromInt(80)}}
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(Equal([]PoolMember{{
+				Address:  "10.244.1.1",
+				Port:     8080,
+				Session:  "user-enabled",
+				NodeName: nodeName,
+			}}), "Target port of members is not overridden")
+
+			// invalid annotation is ignored
+			svc.Annotations[TargetPortOverrideAnnotation] = "http"
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members[0].Port).To(Equal(int32(80)))
+		})
+
+		It("Cluster with namespace route domain", func() {
+			mockCtlr.resources = NewResourceStore()
+			mockCtlr.namespaceRouteDomains = map[string]int{"default": 2}
+			svc := test.NewService("svc1", "1", "default", v1.ServiceTypeClusterIP,
+				[]v1.ServicePort{{Name: "http", Port: 80}})
+			nodeName := "worker1"
+			eps := &v1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "default"},
+				Subsets: []v1.EndpointSubset{{
+					Addresses: []v1.EndpointAddress{{IP: "10.244.1.1", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Name: "http", Port: 80}},
+				}},
+			}
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_vs_10.1.1.1_80"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.SetVirtualAddress("10.1.1.1", 80)
+			rsCfg.Pools = []Pool{{Name: "pool1", ServiceName: "svc1", ServiceNamespace: "default", ServicePort: intstr.FromInt(80)}}
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(HaveLen(1))
+			Expect(rsCfg.Pools[0].Members[0].RouteDomain).To(Equal(2), "Route domain of member is not set")
+
+			sharedApp := as3Application{}
+			processResourcesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp, false, "test")
+			Expect(sharedApp["pool1"].(*as3Pool).Members[0].ServerAddresses).To(Equal([]string{"10.244.1.1%2"}))
+		})
+
+		It("Cluster with connection draining timeout", func() {
+			mockCtlr.resources = NewResourceStore()
+			svc := test.NewService("svc1", "1", "default", v1.ServiceTypeClusterIP,
+				[]v1.ServicePort{{Name: "http", Port: 80}})
+			svc.Annotations = map[string]string{ConnectionDrainingTimeoutAnnotation: "30"}
+			nodeName := "worker1"
+			eps := &v1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "default"},
+				Subsets: []v1.EndpointSubset{{
+					Addresses: []v1.EndpointAddress{
+						{IP: "10.244.1.1", NodeName: &nodeName},
+						{IP: "10.244.1.2", NodeName: &nodeName},
+					},
+					Ports: []v1.EndpointPort{{Name: "http", Port: 80}},
+				}},
+			}
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+
+			// removed endpoint is retained as draining member
+			eps.Subsets[0].Addresses = eps.Subsets[0].Addresses[:1]
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+			rsCfg := &ResourceConfig{}
+			rsCfg.Pools = []Pool{{ServiceName: "svc1", ServiceNamespace: "default", ServicePort: intstr.FromInt(80)}}
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(Equal([]PoolMember{
+				{Address: "10.244.1.1", Port: 80, Session: "user-enabled", NodeName: nodeName},
+				{Address: "10.244.1.2", Port: 80, Session: DrainingMemberSession, NodeName: nodeName},
+			}), "Removed member should be draining")
+			Expect(mockCtlr.resources.drainingMembers["default/svc1"]).To(HaveLen(1))
+
+			// draining member is retained till the timeout
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(HaveLen(2))
+
+			// draining member is removed after the timeout
+			for key, dm := range mockCtlr.resources.drainingMembers["default/svc1"] {
+				dm.expiry = time.Now()
+				mockCtlr.resources.drainingMembers["default/svc1"][key] = dm
+			}
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(HaveLen(1))
+			Expect(mockCtlr.resources.drainingMembers).ToNot(HaveKey("default/svc1"))
+
+			// removed endpoint is deleted immediately without the annotation
+			delete(svc.Annotations, ConnectionDrainingTimeoutAnnotation)
+			eps.Subsets[0].Addresses = nil
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(BeEmpty())
+		})
+
+		It("Cluster with backup pool members", func() {
+			mockCtlr.resources = NewResourceStore()
+			svc := test.NewService("svc1", "1", "default", v1.ServiceTypeClusterIP,
+				[]v1.ServicePort{{Name: "http", Port: 80}})
+			svc.Annotations = map[string]string{PoolMemberStateAnnotation: BackupMemberState}
+			nodeName := "worker1"
+			eps := &v1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "default"},
+				Subsets: []v1.EndpointSubset{{
+					Addresses: []v1.EndpointAddress{{IP: "10.244.1.1", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Name: "http", Port: 80}},
+				}},
+			}
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_vs_10.1.1.1_80"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.SetVirtualAddress("10.1.1.1", 80)
+			rsCfg.Pools = []Pool{{
+				Name:             "pool1",
+				ServiceName:      "svc1",
+				ServiceNamespace: "default",
+				ServicePort:      intstr.FromInt(80),
+				ExternalMembers:  []ExternalMember{{FQDN: "api.example.com", Port: 443}},
+			}}
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(HaveLen(1))
+			Expect(rsCfg.Pools[0].Members[0].Backup).To(BeTrue())
+
+			// external members are primary members, backup members get the lower priority group
+			sharedApp := as3Application{}
+			processResourcesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp, false, "test")
+			members := sharedApp["pool1"].(*as3Pool).Members
+			Expect(members).To(HaveLen(2))
+			Expect(members[0].ServerAddresses).To(Equal([]string{"10.244.1.1"}))
+			Expect(members[0].PriorityGroup).To(BeZero())
+			Expect(members[1].Hostname).To(Equal("api.example.com"))
+			Expect(members[1].PriorityGroup).To(BeEquivalentTo(1))
+
+			// priority groups of primary members are raised above the backup members
+			pool := Pool{Members: []PoolMember{
+				{Address: "10.244.1.1", PriorityGroup: 2, Backup: true},
+				{Address: "10.244.1.2"},
+				{Address: "10.244.1.3", PriorityGroup: 2},
+			}}
+			prioritizeBackupMembers(&pool)
+			Expect(pool.Members[0].PriorityGroup).To(BeZero())
+			Expect(pool.Members[1].PriorityGroup).To(BeEquivalentTo(1))
+			Expect(pool.Members[2].PriorityGroup).To(BeEquivalentTo(3))
+
+			// members of service without annotation are primary members
+			delete(svc.Annotations, PoolMemberStateAnnotation)
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members[0].Backup).To(BeFalse())
+			Expect(rsCfg.Pools[0].Members[0].PriorityGroup).To(BeZero())
+		})
+
+		It("Pool with SRV record", func() {
+			mockCtlr.resources = NewResourceStore()
+			svc := test.NewService("svc1", "1", "default", v1.ServiceTypeClusterIP,
+				[]v1.ServicePort{{Name: "http", Port: 80}})
+			nodeName := "worker1"
+			eps := &v1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Name: "svc1", Namespace: "default"},
+				Subsets: []v1.EndpointSubset{{
+					Addresses: []v1.EndpointAddress{{IP: "10.244.1.1", NodeName: &nodeName}},
+					Ports:     []v1.EndpointPort{{Name: "http", Port: 80}},
+				}},
+			}
+			Expect(mockCtlr.processService(svc, eps, false)).To(BeNil())
+
+			vs := test.NewVirtualServer("vs", "default", cisapiv1.VirtualServerSpec{
+				Host: "foo.com",
+				Pools: []cisapiv1.Pool{{
+					Path:        "/",
+					Service:     "svc1",
+					ServicePort: intstr.FromInt(80),
+					SRVRecord:   "_http._tcp.mesh.example.com",
+					SRVPort:     8080,
+				}},
+			})
+			Expect(mockCtlr.framePoolName("default", vs.Spec.Pools[0], "foo.com")).
+				To(Equal("http__tcp_mesh_example_com_8080_default_foo_com"))
+
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_vs_10.1.1.1_80"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.SetVirtualAddress("10.1.1.1", 80)
+			Expect(mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)).To(BeNil())
+			Expect(rsCfg.Pools).To(HaveLen(1))
+			Expect(rsCfg.Pools[0].SRVRecord).To(Equal("_http._tcp.mesh.example.com"))
+
+			// endpoints of service are not the members of SRV record pool
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(BeEmpty())
+			mockCtlr.updatePoolMembersForNodePort(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(BeEmpty())
+			Expect(rsCfg.MetaData.Active).To(BeTrue())
+
+			sharedApp := as3Application{}
+			processResourcesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp, false, "test")
+			members := sharedApp[rsCfg.Pools[0].Name].(*as3Pool).Members
+			Expect(members).To(Equal([]as3PoolMember{{
+				AddressDiscovery: "fqdn",
+				Hostname:         "_http._tcp.mesh.example.com",
+				AutoPopulate:     true,
+				ServicePort:      8080,
+			}}))
+		})
+
+		It("Pool with monitorDisabled", func() {
+			vs := test.NewVirtualServer("vs", "default", cisapiv1.VirtualServerSpec{
+				Host: "foo.com",
+				Pools: []cisapiv1.Pool{
+					{
+						Path:            "/udp",
+						Service:         "svc1",
+						ServicePort:     intstr.FromInt(53),
+						MonitorDisabled: true,
+						Monitor:         cisapiv1.Monitor{Type: "http", Send: "GET /", Interval: 10},
+						Monitors:        []cisapiv1.Monitor{{Name: "/Common/udp", Reference: BIGIP}},
+					},
+					{
+						Path:        "/",
+						Service:     "svc2",
+						ServicePort: intstr.FromInt(80),
+						Monitor:     cisapiv1.Monitor{Type: "http", Send: "GET /", Interval: 10},
+					},
+				},
+			})
+			rsCfg := &ResourceConfig{}
+			rsCfg.Virtual.Name = "crd_vs_10.1.1.1_80"
+			rsCfg.Virtual.Partition = "test"
+			rsCfg.Virtual.SetVirtualAddress("10.1.1.1", 80)
+			Expect(mockCtlr.prepareRSConfigFromVirtualServer(rsCfg, vs, false)).To(BeNil())
+			Expect(rsCfg.Pools).To(HaveLen(2))
+			Expect(rsCfg.Pools[0].MonitorNames).To(BeEmpty())
+			Expect(rsCfg.Pools[1].MonitorNames).To(HaveLen(1))
+			Expect(rsCfg.Monitors).To(HaveLen(1), "Monitor of pool with monitorDisabled should not be created")
+
+			sharedApp := as3Application{}
+			processResourcesForAS3(ResourceMap{rsCfg.Virtual.Name: rsCfg}, sharedApp, false, "test")
+			pool := sharedApp[rsCfg.Pools[0].Name].(*as3Pool)
+			Expect(pool.Monitors).To(BeNil())
+			poolDecl, _ := json.Marshal(pool)
+			Expect(string(poolDecl)).NotTo(ContainSubstring("monitors"))
+		})
 	})
 
 	Describe("Processing Resources", func() {
@@ -854,7 +1149,7 @@ var _ = Describe("Worker Tests", func() {
 			Expect(len(mockCtlr.resources.ltmConfig)).To(Equal(0), "Resource Config should be empty")
 
 			_ = mockCtlr.createIPAMResource()
-			ipamCR := mockCtlr.getIPAMCR()
+			ipamCR := mockCtlr.getIPAMCR("")
 
 			ipamCR.Spec.HostSpecs = []*ficV1.HostSpec{
 				{
@@ -927,11 +1222,557 @@ var _ = Describe("Worker Tests", func() {
 			Expect(len(gtmConfig["test.com"].Pools)).To(Equal(1))
 			Expect(len(gtmConfig["test.com"].Pools[0].Members)).To(Equal(1))
 
+			Expect(gtmConfig["test.com"].DNSListener).To(BeNil())
+
+			newEDNS.Spec.DNSListener = &cisapiv1.DNSListenerSpec{Address: "10.1.1.53"}
+			mockCtlr.processExternalDNS(newEDNS, false)
+			gtmConfig = mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
+			Expect(gtmConfig["test.com"].DNSListener).To(Equal(&DNSListener{
+				Address:    "10.1.1.53",
+				Port:       DefaultDNSListenerPort,
+				UDPProfile: DefaultDNSListenerUDPProfile,
+			}))
+
+			newEDNS.Spec.DNSListener.Address = "invalid"
+			mockCtlr.processExternalDNS(newEDNS, false)
+			gtmConfig = mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
+			Expect(gtmConfig["test.com"].DNSListener).To(BeNil(), "DNS listener with invalid address should be skipped")
+
+			newEDNS.Spec.DNSListener = nil
+			newEDNS.Spec.Pools[0].DNSRecordType = CNAMERecordType
+			newEDNS.Spec.Pools[0].CNAMETargets = []string{"app.example.com", "invalid_target"}
+			mockCtlr.processExternalDNS(newEDNS, false)
+			gtmConfig = mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
+			Expect(gtmConfig["test.com"].Pools[0].RecordType).To(Equal(CNAMERecordType))
+			Expect(gtmConfig["test.com"].Pools[0].Members).To(Equal([]string{"app.example.com"}),
+				"CNAME targets should be the members instead of virtuals")
+
+			// CNAME pool without targets is rejected, the processed WideIP is retained
+			newEDNS.Spec.Pools[0].CNAMETargets = nil
+			mockCtlr.processExternalDNS(newEDNS, false)
+			gtmConfig = mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
+			Expect(gtmConfig["test.com"].Pools[0].Members).To(Equal([]string{"app.example.com"}),
+				"CNAME pool without cnameTargets should not be processed")
+
 			mockCtlr.processExternalDNS(newEDNS, true)
 			gtmConfig = mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
 			Expect(len(gtmConfig)).To(Equal(0))
 		})
 
+		It("Processing External DNS with ordered failover", func() {
+			mockCtlr.resources.Init()
+			DEFAULT_PARTITION = "default"
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					ExternalDNS: make(map[string]int),
+				},
+			}
+			mockCtlr.Partition = "default"
+
+			newEDNS := test.NewExternalDNS(
+				"SampleEDNS",
+				namespace,
+				cisapiv1.ExternalDNSSpec{
+					DomainName:        "test.com",
+					LoadBalanceMethod: GlobalAvailabilityLBMethod,
+					OrderedFailover:   true,
+					Pools: []cisapiv1.DNSPool{
+						{DataServerName: "DataServer3"},
+						{DataServerName: "DataServer2", PriorityOrder: 2},
+						{DataServerName: "DataServer1", PriorityOrder: 1},
+					},
+				})
+			mockCtlr.processExternalDNS(newEDNS, false)
+			pools := mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs["test.com"].Pools
+			Expect(pools).To(HaveLen(3))
+			var dataServers, names []string
+			for _, pool := range pools {
+				dataServers = append(dataServers, pool.DataServer)
+				names = append(names, pool.Name)
+			}
+			Expect(dataServers).To(Equal([]string{"DataServer1", "DataServer2", "DataServer3"}),
+				"Pools should be sorted by order, pools without order should be the last")
+			Expect(names[2]).To(Equal(names[0][:len(names[0])-2]), "First pool of spec should retain the name")
+			Expect(names).To(ConsistOf(names[2], names[2]+"_1", names[2]+"_2"), "Pools should be named uniquely")
+
+			// ordered failover is ignored without global-availability
+			newEDNS.Spec.LoadBalanceMethod = "round-robin"
+			mockCtlr.processExternalDNS(newEDNS, false)
+			pools = mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs["test.com"].Pools
+			Expect(pools[0].DataServer).To(Equal("DataServer1"))
+			Expect(pools[0].Name).To(Equal(pools[2].Name))
+		})
+
+		It("Processing Wildcard External DNS", func() {
+			mockCtlr.resources.Init()
+			DEFAULT_PARTITION = "default"
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					ExternalDNS: make(map[string]int),
+				},
+			}
+			mockCtlr.Partition = "default"
+			mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+			mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+
+			Expect(matchesDomainName("*.example.com", "foo.example.com")).To(BeTrue())
+			Expect(matchesDomainName("*.example.com", "foo.bar.example.com")).To(BeTrue())
+			Expect(matchesDomainName("*.example.com", "example.com")).To(BeFalse())
+			Expect(matchesDomainName("*.example.com", "fooexample.com")).To(BeFalse())
+			Expect(matchesDomainName("example.com", "foo.example.com")).To(BeFalse())
+
+			mockCtlr.resources.ltmConfig["default"] = &PartitionConfig{make(ResourceMap), 0}
+			mockCtlr.resources.ltmConfig["default"].ResourceMap["SampleVS1"] = &ResourceConfig{
+				MetaData: metaData{
+					hosts: []string{"foo.example.com"},
+				},
+			}
+			mockCtlr.resources.ltmConfig["default"].ResourceMap["SampleVS2"] = &ResourceConfig{
+				MetaData: metaData{
+					hosts: []string{"bar.test.com"},
+				},
+			}
+
+			wildcardEDNS := test.NewExternalDNS(
+				"WildcardEDNS",
+				namespace,
+				cisapiv1.ExternalDNSSpec{
+					DomainName: "*.example.com",
+					Pools: []cisapiv1.DNSPool{
+						{
+							DataServerName: "DataServer",
+						},
+					},
+				})
+			wildcardEDNS.UID = "1"
+			mockCtlr.processExternalDNS(wildcardEDNS, false)
+			gtmConfig := mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
+			Expect(len(gtmConfig)).To(Equal(1))
+			Expect(gtmConfig["*.example.com"].Pools[0].Members).To(
+				Equal([]string{"/default/Shared/SampleVS1"}))
+
+			// Conflicting wildcard EDNS is not processed
+			conflictEDNS := test.NewExternalDNS(
+				"ConflictEDNS",
+				namespace,
+				cisapiv1.ExternalDNSSpec{
+					DomainName: "*.foo.example.com",
+					Pools: []cisapiv1.DNSPool{
+						{
+							DataServerName: "DataServer",
+						},
+					},
+				})
+			conflictEDNS.UID = "2"
+			mockCtlr.processExternalDNS(conflictEDNS, false)
+			gtmConfig = mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
+			Expect(len(gtmConfig)).To(Equal(1))
+			Expect(gtmConfig).NotTo(HaveKey("*.foo.example.com"))
+
+			// Non overlapping wildcard EDNS is processed
+			otherEDNS := test.NewExternalDNS(
+				"OtherEDNS",
+				namespace,
+				cisapiv1.ExternalDNSSpec{
+					DomainName: "*.test.com",
+					Pools: []cisapiv1.DNSPool{
+						{
+							DataServerName: "DataServer",
+						},
+					},
+				})
+			otherEDNS.UID = "3"
+			mockCtlr.processExternalDNS(otherEDNS, false)
+			gtmConfig = mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
+			Expect(len(gtmConfig)).To(Equal(2))
+			Expect(gtmConfig["*.test.com"].Pools[0].Members).To(
+				Equal([]string{"/default/Shared/SampleVS2"}))
+		})
+
+		It("Processing External DNS with topology records", func() {
+			mockCtlr.resources.Init()
+			DEFAULT_PARTITION = "default"
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					ExternalDNS: make(map[string]int),
+				},
+			}
+			mockCtlr.Partition = "default"
+			mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+			mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+
+			topologyEDNS := test.NewExternalDNS(
+				"TopologyEDNS",
+				namespace,
+				cisapiv1.ExternalDNSSpec{
+					DomainName:        "topology.com",
+					LoadBalanceMethod: "topology",
+					Pools: []cisapiv1.DNSPool{
+						{
+							DataServerName: "DataServer",
+							TopologyRecords: []cisapiv1.TopologyRecord{
+								{Source: "10.20.0.0/16", Order: 2},
+								{Source: "10.10.0.0/16", Destination: "172.16.0.0/24", Order: 1},
+								{Source: "10.30.0.0", Order: 3},
+							},
+						},
+					},
+				})
+			topologyEDNS.UID = "1"
+			mockCtlr.processExternalDNS(topologyEDNS, false)
+			rrEDNS := test.NewExternalDNS(
+				"RoundRobinEDNS",
+				namespace,
+				cisapiv1.ExternalDNSSpec{
+					DomainName: "test.com",
+					Pools: []cisapiv1.DNSPool{
+						{
+							DataServerName:  "DataServer",
+							TopologyRecords: []cisapiv1.TopologyRecord{{Source: "10.40.0.0/16"}},
+						},
+					},
+				})
+			rrEDNS.UID = "2"
+			mockCtlr.processExternalDNS(rrEDNS, false)
+
+			gtmConfig := mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs
+			Expect(gtmConfig["topology.com"].Pools[0].TopologyRecords).To(Equal([]TopologyRecord{
+				{Source: "10.10.0.0/16", Destination: "172.16.0.0/24", Order: 1},
+				{Source: "10.20.0.0/16", Order: 2},
+			}), "Invalid topology record should be skipped and records should be ordered")
+			Expect(gtmConfig["test.com"].Pools[0].TopologyRecords).To(BeEmpty(),
+				"Topology records should be ignored without topology load balance method")
+
+			adc := mockCtlr.Agent.createAS3GTMConfigADC(
+				ResourceConfigRequest{gtmConfig: mockCtlr.resources.gtmConfig}, as3ADC{})
+			data, _ := json.Marshal(adc)
+			var decl map[string]map[string]interface{}
+			Expect(json.Unmarshal(data, &decl)).To(Succeed())
+			sharedApp := decl[DEFAULT_PARTITION][as3SharedApplication].(map[string]interface{})
+			topologyPool := gtmConfig["topology.com"].Pools[0].Name
+			Expect(sharedApp["topology.com"]).To(HaveKeyWithValue("poolLbMode", "topology"))
+			Expect(sharedApp["topology.com"]).To(HaveKeyWithValue("pools",
+				[]interface{}{map[string]interface{}{"use": topologyPool}}))
+			Expect(sharedApp[as3GSLBTopologyRecordsName]).To(Equal(map[string]interface{}{
+				"class":               "GSLB_Topology_Records",
+				"longestMatchEnabled": false,
+				"records": []interface{}{
+					map[string]interface{}{
+						"source":      map[string]interface{}{"matchType": "subnet", "matchValue": "10.10.0.0/16"},
+						"destination": map[string]interface{}{"matchType": "subnet", "matchValue": "172.16.0.0/24"},
+					},
+					map[string]interface{}{
+						"source": map[string]interface{}{"matchType": "subnet", "matchValue": "10.20.0.0/16"},
+						"destination": map[string]interface{}{
+							"matchType":  "pool",
+							"matchValue": map[string]interface{}{"use": topologyPool},
+						},
+					},
+				},
+			}))
+		})
+
+		It("Processing External DNS with topology regions", func() {
+			mockCtlr.resources.Init()
+			DEFAULT_PARTITION = "default"
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					ExternalDNS: make(map[string]int),
+				},
+			}
+			mockCtlr.Partition = "default"
+			mockCtlr.kubeClient = k8sfake.NewSimpleClientset()
+			mockCtlr.eventNotifier = apm.NewEventNotifier(nil)
+
+			edns := test.NewExternalDNS(
+				"RegionEDNS",
+				namespace,
+				cisapiv1.ExternalDNSSpec{
+					DomainName:        "region.com",
+					LoadBalanceMethod: "round-robin",
+					TopologyEnabled:   true,
+					Pools: []cisapiv1.DNSPool{
+						{
+							DataServerName: "DataServer1",
+							TopologyRecords: []cisapiv1.TopologyRecord{
+								{Source: "10.10.0.0/16", Region: "us-east", Order: 1},
+								{Source: "10.20.0.0/16", Region: "us-east", Order: 2},
+								{Source: "10.30.0.0/16", Order: 3},
+							},
+						},
+					},
+				})
+			edns.UID = "1"
+			mockCtlr.processExternalDNS(edns, false)
+			edns2 := test.NewExternalDNS(
+				"RegionEDNS2",
+				namespace,
+				cisapiv1.ExternalDNSSpec{
+					DomainName:      "region2.com",
+					TopologyEnabled: true,
+					Pools: []cisapiv1.DNSPool{
+						{
+							DataServerName: "DataServer2",
+							TopologyRecords: []cisapiv1.TopologyRecord{
+								{Source: "10.40.0.0/16", Region: "us-east"},
+							},
+						},
+					},
+				})
+			edns2.UID = "2"
+			mockCtlr.processExternalDNS(edns2, false)
+
+			wip := mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs["region.com"]
+			Expect(wip.LBMethod).To(Equal(TopologyLBMethod), "Topology should override the load balance method")
+			Expect(wip.Pools[0].TopologyRecords).To(Equal([]TopologyRecord{
+				{Source: "10.10.0.0/16", Region: "us-east", Order: 1},
+				{Source: "10.30.0.0/16", Order: 3},
+			}), "Duplicate region of pool should be skipped")
+
+			adc := mockCtlr.Agent.createAS3GTMConfigADC(
+				ResourceConfigRequest{gtmConfig: mockCtlr.resources.gtmConfig}, as3ADC{})
+			sharedApp := adc[DEFAULT_PARTITION].(as3Tenant)[as3SharedApplication].(as3Application)
+			region1 := gslbTopologyRegionName(wip.Pools[0].Name, "us-east")
+			wip2 := mockCtlr.resources.gtmConfig[DEFAULT_PARTITION].WideIPs["region2.com"]
+			region2 := gslbTopologyRegionName(wip2.Pools[0].Name, "us-east")
+			Expect(region1).NotTo(Equal(region2), "Regions should be scoped to the pools")
+			Expect(sharedApp[region1]).To(Equal(&as3GSLBTopologyRegion{
+				Class:   "GSLB_Topology_Region",
+				Members: []as3GSLBTopologyMatch{{MatchType: "subnet", MatchValue: "10.10.0.0/16"}},
+			}))
+			Expect(sharedApp[region2]).To(Equal(&as3GSLBTopologyRegion{
+				Class:   "GSLB_Topology_Region",
+				Members: []as3GSLBTopologyMatch{{MatchType: "subnet", MatchValue: "10.40.0.0/16"}},
+			}), "Subnets of region should not be shared by the pools")
+			records := sharedApp[as3GSLBTopologyRecordsName].(as3GSLBTopologyRecords).Records
+			Expect(records).To(HaveLen(3))
+			var sources []as3GSLBTopologyMatch
+			for _, record := range records {
+				sources = append(sources, record.Source)
+			}
+			Expect(sources).To(ConsistOf(
+				as3GSLBTopologyMatch{MatchType: "region", MatchValue: as3ResourcePointer{Use: region1}},
+				as3GSLBTopologyMatch{MatchType: "subnet", MatchValue: "10.30.0.0/16"},
+				as3GSLBTopologyMatch{MatchType: "region", MatchValue: as3ResourcePointer{Use: region2}},
+			))
+		})
+
+		It("Processing VirtualServers with shared pool", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			vrt1.Spec.Pools = []cisapiv1.Pool{{Path: "/path", Service: "svc1", ServicePort: intstr.FromInt(80), SharedPool: true}}
+			vrt2 := test.NewVirtualServer("SampleVS2", namespace, cisapiv1.VirtualServerSpec{
+				Host:                 "test2.com",
+				VirtualServerAddress: "1.2.3.5",
+				Pools:                []cisapiv1.Pool{{Path: "/path2", Service: "svc1", ServicePort: intstr.FromInt(80), SharedPool: true}},
+			})
+			mockCtlr.addVirtualServer(vrt1)
+			mockCtlr.addVirtualServer(vrt2)
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			Expect(mockCtlr.processVirtualServers(vrt2, false)).To(BeNil())
+			// reprocessing a VirtualServer does not add reference to the pool
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+
+			rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+			Expect(rsMap).To(HaveLen(2))
+			for _, rsCfg := range rsMap {
+				Expect(rsCfg.Pools).To(HaveLen(1))
+				Expect(rsCfg.Pools[0].Name).To(Equal("shared_default_svc1_80"))
+			}
+
+			// shared pool is posted once, as long as a virtual refers it
+			postedPools := func() []string {
+				agent := newMockAgent(nil)
+				adc := agent.createAS3LTMConfigADC(ResourceConfigRequest{ltmConfig: mockCtlr.resources.ltmConfig})
+				tenant, ok := adc[mockCtlr.Partition].(as3Tenant)
+				if !ok {
+					return nil
+				}
+				var pools []string
+				for name, obj := range tenant[as3SharedApplication].(as3Application) {
+					if _, ok := obj.(*as3Pool); ok {
+						pools = append(pools, name)
+					}
+				}
+				return pools
+			}
+			Expect(postedPools()).To(Equal([]string{"shared_default_svc1_80"}), "Only one pool should be posted")
+			Expect(mockCtlr.resources.sharedPoolRefCount).To(Equal(map[string]int{"test/shared_default_svc1_80": 2}))
+
+			Expect(mockCtlr.processVirtualServers(vrt2, true)).To(BeNil())
+			Expect(mockCtlr.resources.sharedPoolRefCount).To(Equal(map[string]int{"test/shared_default_svc1_80": 1}))
+			Expect(postedPools()).To(Equal([]string{"shared_default_svc1_80"}),
+				"Pool should be posted while a VirtualServer refers it")
+			Expect(mockCtlr.processVirtualServers(vrt1, true)).To(BeNil())
+			Expect(mockCtlr.resources.sharedPoolRefCount).To(BeEmpty())
+			Expect(postedPools()).To(BeEmpty(), "Pool should be deleted with its last VirtualServer")
+		})
+
+		It("Processing VirtualServers with named service port", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			svc := test.NewService("svc1", "1", namespace, v1.ServiceTypeClusterIP,
+				[]v1.ServicePort{{Name: "http", Port: 80, TargetPort: intstr.FromInt(8080)}})
+			mockCtlr.addService(svc)
+			port, err := getServicePortNumber(svc, "http")
+			Expect(err).To(BeNil())
+			Expect(port).To(BeEquivalentTo(80))
+			_, err = getServicePortNumber(svc, "https")
+			Expect(err).NotTo(BeNil(), "Unknown port name should not be resolved")
+
+			vrt1.Spec.Pools = []cisapiv1.Pool{{Path: "/path", Service: "svc1", ServicePort: intstr.FromString("http")}}
+			mockCtlr.addVirtualServer(vrt1)
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsCfg := mockCtlr.getVirtualServer(mockCtlr.Partition, "crd_1_2_3_4_80")
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(rsCfg.Pools).To(HaveLen(1))
+			Expect(rsCfg.Pools[0].ServicePort).To(Equal(intstr.FromInt(8080)))
+
+			// VirtualServer is reprocessed with the new port of service
+			svc.Spec.Ports = []v1.ServicePort{{Name: "http", Port: 81, TargetPort: intstr.FromInt(9090)}}
+			mockCtlr.updateService(svc)
+			virtuals := mockCtlr.getVirtualServersForService(svc)
+			Expect(virtuals).To(Equal([]*cisapiv1.VirtualServer{vrt1}))
+			Expect(mockCtlr.processVirtualServers(virtuals[0], false)).To(BeNil())
+			rsCfg = mockCtlr.getVirtualServer(mockCtlr.Partition, "crd_1_2_3_4_80")
+			Expect(rsCfg.Pools[0].ServicePort).To(Equal(intstr.FromInt(9090)))
+		})
+
+		It("Processing IPv6 VirtualServers and TransportServers", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer:   make(map[string]int),
+					TransportServer: make(map[string]int),
+				},
+			}
+			vrt1.Spec.VirtualServerAddress = "2001:db8::10"
+			vrt1.Spec.Pools = []cisapiv1.Pool{{Path: "/path", Service: "svc1", ServicePort: intstr.FromInt(80)}}
+			mockCtlr.addVirtualServer(vrt1)
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsCfg := mockCtlr.getVirtualServer(mockCtlr.Partition, "crd_2001_db8__10_80")
+			Expect(rsCfg).NotTo(BeNil(), "Colons of IPv6 address should be escaped in the name")
+			Expect(rsCfg.Virtual.VirtualAddress.BindAddr).To(Equal("2001:db8::10"))
+			Expect(rsCfg.Virtual.Destination).To(Equal("/" + mockCtlr.Partition + "/2001:db8::10.80"))
+
+			ts := test.NewTransportServer("SampleTS", namespace, cisapiv1.TransportServerSpec{
+				VirtualServerAddress: "::ffff:10.1.1.1",
+				VirtualServerPort:    8080,
+				Mode:                 "standard",
+				Pool:                 cisapiv1.Pool{Service: "svc1", ServicePort: intstr.FromInt(80)},
+			})
+			mockCtlr.addTransportServer(ts)
+			Expect(mockCtlr.processTransportServers(ts, false)).To(BeNil())
+			tsCfg := mockCtlr.getVirtualServer(mockCtlr.Partition, "crd___ffff_10_1_1_1_8080")
+			Expect(tsCfg).NotTo(BeNil())
+			Expect(tsCfg.Virtual.Destination).To(Equal("/" + mockCtlr.Partition + "/::ffff:10.1.1.1.8080"))
+
+			// IPv6 addresses are passed through unchanged to AS3 declaration
+			agent := newMockAgent(nil)
+			adc := agent.createAS3LTMConfigADC(ResourceConfigRequest{ltmConfig: mockCtlr.resources.ltmConfig})
+			sharedApp := adc[mockCtlr.Partition].(as3Tenant)[as3SharedApplication].(as3Application)
+			svc := sharedApp["crd_2001_db8__10_80"].(*as3Service)
+			Expect(svc.VirtualAddresses).To(Equal([]as3MultiTypeParam{"2001:db8::10"}))
+			Expect(svc.VirtualPort).To(Equal(80))
+			tsSvc := sharedApp["crd___ffff_10_1_1_1_8080"].(*as3Service)
+			Expect(tsSvc.VirtualAddresses).To(Equal([]as3MultiTypeParam{"::ffff:10.1.1.1"}))
+			Expect(tsSvc.VirtualPort).To(Equal(8080))
+		})
+
+		It("Processing VirtualServers with load balancing method of pools", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			vrt1.Spec.Pools = []cisapiv1.Pool{
+				{Path: "/api", Service: "svc1", ServicePort: intstr.FromInt(80), Balance: "least-connections-member"},
+				{Path: "/static", Service: "svc2", ServicePort: intstr.FromInt(80), Balance: "round-robin"},
+			}
+			mockCtlr.addVirtualServer(vrt1)
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsCfg := mockCtlr.getVirtualServer(mockCtlr.Partition, "crd_1_2_3_4_80")
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(rsCfg.Pools).To(HaveLen(2))
+
+			agent := newMockAgent(nil)
+			adc := agent.createAS3LTMConfigADC(ResourceConfigRequest{ltmConfig: mockCtlr.resources.ltmConfig})
+			sharedApp := adc[mockCtlr.Partition].(as3Tenant)[as3SharedApplication].(as3Application)
+			Expect(sharedApp[rsCfg.Pools[0].Name].(*as3Pool).LoadBalancingMode).To(Equal("least-connections-member"))
+			Expect(sharedApp[rsCfg.Pools[1].Name].(*as3Pool).LoadBalancingMode).To(Equal("round-robin"))
+		})
+
+		It("Processing VirtualServers with WebSocket enabled", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			vrt1.Spec.WebSocketEnabled = true
+			mockCtlr.addVirtualServer(vrt1)
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsCfg := mockCtlr.getVirtualServer(mockCtlr.Partition, "crd_1_2_3_4_80")
+			Expect(rsCfg).NotTo(BeNil())
+			Expect(rsCfg.Virtual.IRules).To(ContainElement(
+				JoinBigipPath(mockCtlr.Partition, irules.WebSocketUpgradeIRuleName)))
+			Expect(rsCfg.IRulesMap).To(HaveKey(NameRef{Name: irules.WebSocketUpgradeIRuleName, Partition: mockCtlr.Partition}))
+
+			agent := newMockAgent(nil)
+			adc := agent.createAS3LTMConfigADC(ResourceConfigRequest{ltmConfig: mockCtlr.resources.ltmConfig})
+			sharedApp := adc[mockCtlr.Partition].(as3Tenant)[as3SharedApplication].(as3Application)
+			Expect(sharedApp).To(HaveKey(irules.WebSocketUpgradeIRuleName))
+			Expect(sharedApp["crd_1_2_3_4_80"].(*as3Service).IRules).To(ContainElement(irules.WebSocketUpgradeIRuleName))
+
+			vrt1.Spec.WebSocketEnabled = false
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsCfg = mockCtlr.getVirtualServer(mockCtlr.Partition, "crd_1_2_3_4_80")
+			Expect(rsCfg.IRulesMap).NotTo(HaveKey(NameRef{Name: irules.WebSocketUpgradeIRuleName, Partition: mockCtlr.Partition}))
+		})
+
+		It("Processing VirtualServers with multiple addresses", func() {
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					VirtualServer: make(map[string]int),
+				},
+			}
+			vrt1.Spec.VirtualServerAddresses = []string{"1.2.3.4", "10.2.3.4"}
+			mockCtlr.addVirtualServer(vrt1)
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsMap := mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+			Expect(rsMap).To(HaveLen(2), "Virtual server should be created for each address")
+			external := rsMap["crd_1_2_3_4_80"]
+			internal := rsMap["crd_10_2_3_4_80"]
+			Expect(external).NotTo(BeNil())
+			Expect(internal).NotTo(BeNil())
+			Expect(internal.Virtual.Destination).To(Equal("/test/10.2.3.4:80"))
+			Expect(internal.Pools).To(Equal(external.Pools))
+			Expect(internal.Policies).To(HaveLen(len(external.Policies)))
+
+			agent := newMockAgent(nil)
+			adc := agent.createAS3LTMConfigADC(ResourceConfigRequest{ltmConfig: mockCtlr.resources.ltmConfig})
+			sharedApp := adc[mockCtlr.Partition].(as3Tenant)[as3SharedApplication].(as3Application)
+			Expect(sharedApp).To(HaveKey("crd_1_2_3_4_80"))
+			Expect(sharedApp).To(HaveKey("crd_10_2_3_4_80"))
+
+			// custom name is suffixed with the additional address
+			mockCtlr.resources = NewResourceStore()
+			vrt1.Spec.VirtualServerName = "app"
+			Expect(mockCtlr.processVirtualServers(vrt1, false)).To(BeNil())
+			rsMap = mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)
+			Expect(rsMap).To(HaveKey("app_80"))
+			Expect(rsMap).To(HaveKey("app_10_2_3_4_80"))
+
+			// virtual servers of all addresses are deleted
+			mockCtlr.deleteVirtualServer(vrt1)
+			Expect(mockCtlr.processVirtualServers(vrt1, true)).To(BeNil())
+			Expect(mockCtlr.resources.getPartitionResourceMap(mockCtlr.Partition)).To(BeEmpty())
+		})
+
 		It("Processing IngressLink", func() {
 			// Creation of IngressLink
 			fooPorts := []v1.ServicePort{
@@ -980,6 +1821,69 @@ var _ = Describe("Worker Tests", func() {
 				"Invalid Resource Config")
 
 		})
+
+		It("Processing IngressLink with per port monitors", func() {
+			fooPorts := []v1.ServicePort{
+				{Port: 80, Name: "http"},
+				{Port: 443, Name: "https"},
+				{Port: 8081, Name: "readiness"},
+			}
+			foo := test.NewService("foo", "1", namespace, v1.ServiceTypeClusterIP, fooPorts)
+			foo.ObjectMeta.Labels = map[string]string{"app": "ingresslink"}
+			IngressLink1 := test.NewIngressLink("ingresslink1", namespace, "1",
+				cisapiv1.IngressLinkSpec{
+					VirtualServerAddress: "1.2.3.4",
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "ingresslink"},
+					},
+					Monitors: []cisapiv1.Monitor{
+						{Type: "https", Send: "GET /healthz HTTP/1.1\r\n", Interval: 5, Timeout: 16, TargetPort: 443},
+					},
+				})
+			_ = mockCtlr.crInformers["default"].ilInformer.GetIndexer().Add(IngressLink1)
+			mockCtlr.TeemData = &teem.TeemsData{
+				ResourceType: teem.ResourceTypes{
+					IngressLink: make(map[string]int),
+				},
+			}
+			_ = mockCtlr.comInformers["default"].svcInformer.GetIndexer().Add(foo)
+			err := mockCtlr.processIngressLink(IngressLink1, false)
+			Expect(err).To(BeNil(), "Failed to process IngressLink while creation")
+			Expect(len(mockCtlr.resources.ltmConfig[mockCtlr.Partition].ResourceMap)).To(Equal(2),
+				"Invalid Resource Config")
+
+			// port without monitor falls back to nginx readiness monitor
+			rsCfg, err := mockCtlr.resources.getResourceConfig(mockCtlr.Partition,
+				"ingress_link_"+formatVirtualServerName("1.2.3.4", 80))
+			Expect(err).To(BeNil())
+			Expect(len(rsCfg.Monitors)).To(Equal(1))
+			Expect(rsCfg.Monitors[0].Send).To(Equal("GET /nginx-ready HTTP/1.1\r\n"))
+			Expect(rsCfg.Monitors[0].TargetPort).To(BeEquivalentTo(nginxMonitorPort))
+			Expect(rsCfg.Pools[0].MonitorNames).To(Equal([]MonitorName{{Name: rsCfg.Monitors[0].Name}}))
+
+			rsCfg, err = mockCtlr.resources.getResourceConfig(mockCtlr.Partition,
+				"ingress_link_"+formatVirtualServerName("1.2.3.4", 443))
+			Expect(err).To(BeNil())
+			Expect(len(rsCfg.Monitors)).To(Equal(1))
+			Expect(rsCfg.Monitors[0]).To(Equal(Monitor{
+				Name:      rsCfg.Pools[0].Name + "_monitor",
+				Partition: mockCtlr.Partition,
+				Type:      "https",
+				Send:      "GET /healthz HTTP/1.1\r\n",
+				Interval:  5,
+				Timeout:   16,
+			}), "Invalid monitor for port 443")
+			Expect(rsCfg.Pools[0].MonitorNames).To(Equal([]MonitorName{{Name: rsCfg.Monitors[0].Name}}))
+
+			// monitor referred from BIG-IP
+			IngressLink1.Spec.Monitors[0] = cisapiv1.Monitor{Name: "/Common/https_nginx", Reference: BIGIP, TargetPort: 443}
+			err = mockCtlr.processIngressLink(IngressLink1, false)
+			Expect(err).To(BeNil(), "Failed to process IngressLink while update")
+			rsCfg, _ = mockCtlr.resources.getResourceConfig(mockCtlr.Partition,
+				"ingress_link_"+formatVirtualServerName("1.2.3.4", 443))
+			Expect(len(rsCfg.Monitors)).To(Equal(0))
+			Expect(rsCfg.Pools[0].MonitorNames).To(Equal([]MonitorName{{Name: "/Common/https_nginx", Reference: BIGIP}}))
+		})
 	})
 
 	It("get node port", func() {
@@ -1086,6 +1990,30 @@ var _ = Describe("Worker Tests", func() {
 			Expect(getNodeport(svc, 81)).To(BeEquivalentTo(0))
 		})
 
+		It("NodePortLocal with named ports of multiple containers", func() {
+			pod := test.NewPod("pod1", namespace, 8080, selectors)
+			pod.Spec.Containers = []v1.Container{
+				{Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9090}}},
+				{Ports: []v1.ContainerPort{{Name: "web", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9091}}},
+			}
+			pod.Annotations = map[string]string{NPLPodAnnotation: "[" +
+				"{\"podPort\":8080,\"nodeIP\":\"10.10.10.1\",\"nodePort\":40000}," +
+				"{\"podPort\":9090,\"nodeIP\":\"10.10.10.1\",\"nodePort\":40001}," +
+				"{\"podPort\":9091,\"nodeIP\":\"10.10.10.1\",\"nodePort\":40002}]"}
+			mockCtlr.resources.Init()
+			mockCtlr.processPod(pod, false)
+			pods := []*v1.Pod{pod}
+
+			member := PoolMember{Address: "10.10.10.1", Port: 40000, Session: "user-enabled"}
+			Expect(mockCtlr.getEndpointsForNPL(intstr.FromString("http"), pods)).To(Equal([]PoolMember{member}))
+			Expect(mockCtlr.getEndpointsForNPL(intstr.FromString("web"), pods)).To(Equal([]PoolMember{member}))
+			Expect(mockCtlr.getEndpointsForNPL(intstr.FromString("metrics"), pods)).To(Equal([]PoolMember{
+				{Address: "10.10.10.1", Port: 40001, Session: "user-enabled"},
+				{Address: "10.10.10.1", Port: 40002, Session: "user-enabled"},
+			}), "Ports of all the containers with the port name should be members")
+			Expect(mockCtlr.getEndpointsForNPL(intstr.FromString("grpc"), pods)).To(BeNil())
+		})
+
 		Describe("Processing Service of type LB with policy", func() {
 			It("Processing ServiceTypeLoadBalancer with Policy", func() {
 				//Policy CR
@@ -1130,7 +2058,7 @@ var _ = Describe("Worker Tests", func() {
 					context.TODO(), svc1, metav1.UpdateOptions{})
 
 				_ = mockCtlr.createIPAMResource()
-				ipamCR := mockCtlr.getIPAMCR()
+				ipamCR := mockCtlr.getIPAMCR("")
 
 				ipamCR.Spec.HostSpecs = []*ficV1.HostSpec{
 					{
@@ -1400,6 +2328,247 @@ var _ = Describe("Worker Tests", func() {
 			Expect(len(rsCfgCopy.Pools[0].Members)).To(Equal(2), "Pool members should be updated to 2")
 		})
 	})
+	Describe("Update Pool Members for cluster", func() {
+		BeforeEach(func() {
+			mockCtlr.resources.poolMemCache = make(map[string]poolMembersInfo)
+			mockCtlr.oldNodes = []Node{
+				{Name: "node-1", Addr: "10.10.10.1", Labels: map[string]string{"ssl": "offload"}},
+				{Name: "node-2", Addr: "10.10.10.2", Labels: map[string]string{}},
+			}
+		})
+		It("verify pool members are filtered by node label", func() {
+			memberMap := make(map[portRef][]PoolMember)
+			members := []PoolMember{
+				{
+					Address:  "10.244.1.2",
+					Port:     443,
+					Session:  "user-enabled",
+					NodeName: "node-1",
+				},
+				{
+					Address:  "10.244.2.2",
+					Port:     443,
+					Session:  "user-enabled",
+					NodeName: "node-2",
+				},
+			}
+			memberMap[portRef{name: "https", port: 443}] = members
+			mockCtlr.resources.poolMemCache["default/svc-1"] = poolMembersInfo{
+				svcType:   v1.ServiceTypeClusterIP,
+				portSpec:  []v1.ServicePort{{Name: "https", Port: 443, TargetPort: intstr.FromInt(443), Protocol: "TCP"}},
+				memberMap: memberMap,
+			}
+			pool := Pool{ServiceNamespace: "default",
+				ServiceName: "svc-1",
+				ServicePort: intstr.FromInt(443)}
+			rsCfg := &ResourceConfig{Pools: []Pool{pool}}
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(Equal(members), "Members should not be filtered")
+
+			rsCfg.Pools[0].NodeMemberLabel = "ssl=offload"
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members).To(Equal([]PoolMember{members[0]}), "Members should be filtered by node label")
+
+			Expect(mockCtlr.filterMembersByNodeLabel(members, "ssl=none")).To(BeEmpty())
+			Expect(mockCtlr.filterMembersByNodeLabel([]PoolMember{{Address: "10.244.3.2", Port: 443}},
+				"ssl=offload")).To(BeEmpty(), "Members without node should be filtered")
+		})
+		It("verify pool members are warmed up", func() {
+			memberMap := make(map[portRef][]PoolMember)
+			members := []PoolMember{{Address: "10.244.1.2", Port: 443}}
+			memberMap[portRef{name: "https", port: 443}] = members
+			mockCtlr.resources.poolMemCache["default/svc-1"] = poolMembersInfo{
+				svcType:   v1.ServiceTypeClusterIP,
+				memberMap: memberMap,
+			}
+			pool := Pool{ServiceNamespace: "default",
+				ServiceName: "svc-1",
+				ServicePort: intstr.FromInt(443),
+				WarmupTime:  100}
+			rsCfg := &ResourceConfig{Pools: []Pool{pool}}
+			// members of services found at the initial sync are considered as warmed up
+			mockCtlr.resources.initialSyncServices["default/svc-1"] = struct{}{}
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members[0].Ratio).To(Equal(100), "Existing member should be warmed up")
+
+			// new member starts with ratio 0 and ramps up
+			memberMap[portRef{name: "https", port: 443}] = append(members, PoolMember{Address: "10.244.2.2", Port: 443})
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members[0].Ratio).To(Equal(100))
+			Expect(rsCfg.Pools[0].Members[1].Ratio).To(Equal(0), "New member should start warming up")
+			Expect(memberMap[portRef{name: "https", port: 443}][1].Ratio).To(Equal(0), "Pool member cache should not be updated")
+
+			mockCtlr.resources.memberWarmupStart["default/svc-1"]["10.244.2.2:443"] = time.Now().Add(-50 * time.Second)
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members[1].Ratio).To(Equal(50), "Member ratio should ramp up linearly")
+
+			mockCtlr.resources.memberWarmupStart["default/svc-1"]["10.244.2.2:443"] = time.Now().Add(-100 * time.Second)
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members[1].Ratio).To(Equal(100), "Member should be warmed up")
+
+			sharedApp := as3Application{}
+			createPoolDecl(rsCfg, sharedApp, false, "test")
+			Expect(*sharedApp[""].(*as3Pool).Members[1].Ratio).To(Equal(100))
+		})
+		It("verify members of new services warm up with a single scheduled update", func() {
+			mockCtlr.addEndpoints(&v1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "svc-1", Namespace: "default"}})
+			mockCtlr.resourceQueue = workqueue.NewNamedRateLimitingQueue(
+				workqueue.DefaultControllerRateLimiter(), "custom-resource-controller")
+			defer mockCtlr.resourceQueue.ShutDown()
+			memberMap := make(map[portRef][]PoolMember)
+			memberMap[portRef{name: "https", port: 443}] = []PoolMember{{Address: "10.244.1.2", Port: 443}}
+			mockCtlr.resources.poolMemCache["default/svc-1"] = poolMembersInfo{
+				svcType:   v1.ServiceTypeClusterIP,
+				memberMap: memberMap,
+			}
+			pool := Pool{ServiceNamespace: "default",
+				ServiceName: "svc-1",
+				ServicePort: intstr.FromInt(443),
+				WarmupTime:  10}
+			// pools of two VirtualServers sharing the service
+			rsCfg := &ResourceConfig{Pools: []Pool{pool, pool}}
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(rsCfg.Pools[0].Members[0].Ratio).To(Equal(0), "Member of new service should warm up")
+			Expect(mockCtlr.resources.warmupUpdates).To(HaveKey("default/svc-1"))
+			pending := mockCtlr.resources.warmupUpdates["default/svc-1"]
+
+			// the scheduled update is processed and the next one is scheduled
+			delete(mockCtlr.resources.warmupUpdates, "default/svc-1")
+			mockCtlr.updatePoolMembersForCluster(rsCfg, "default")
+			Expect(mockCtlr.resources.warmupUpdates["default/svc-1"]).NotTo(BeIdenticalTo(pending))
+			Eventually(mockCtlr.resourceQueue.Len, 5*time.Second).Should(Equal(2),
+				"A single update should be scheduled at a time per service")
+			Consistently(mockCtlr.resourceQueue.Len, 2*time.Second).Should(Equal(2))
+
+			svc := test.NewService("svc-1", "1", "default", v1.ServiceTypeClusterIP, nil)
+			Expect(mockCtlr.processService(svc, nil, true)).To(BeNil())
+			Expect(mockCtlr.resources.memberWarmupStart).NotTo(HaveKey("default/svc-1"),
+				"Warm-up of deleted service should be removed")
+			Expect(mockCtlr.resources.warmupUpdates).NotTo(HaveKey("default/svc-1"))
+		})
+	})
+	Describe("Rollback history", func() {
+		tenantDecl := func(label string) as3ADC {
+			return as3ADC{"test": as3Tenant{"class": "Tenant", "label": label}}
+		}
+
+		It("retains only the configured number of declarations", func() {
+			history := newDeclarationHistory(mockCtlr.kubeClient, "kube-system/cis-checkpoint", 2)
+			for reqId := 1; reqId <= 3; reqId++ {
+				history.store(reqId, tenantDecl(fmt.Sprint(reqId)))
+			}
+			Expect(len(history.declarations)).To(Equal(2), "History should be limited to depth")
+			_, found := history.get(1)
+			Expect(found).To(BeFalse(), "Oldest declaration should be discarded")
+			tenants, found := history.get(3)
+			Expect(found).To(BeTrue(), "Latest declaration should be retained")
+			Expect(tenants["test"]["label"]).To(Equal("3"))
+
+			// history is restored from the checkpoint ConfigMap on restart
+			history = newDeclarationHistory(mockCtlr.kubeClient, "kube-system/cis-checkpoint", 2)
+			tenants, found = history.get(2)
+			Expect(found).To(BeTrue(), "Declaration should be restored from the checkpoint ConfigMap")
+			Expect(tenants["test"]["label"]).To(Equal("2"))
+		})
+
+		It("rolls back a failed post to an earlier declaration", func() {
+			mockCtlr.enableRollback = true
+			mockCtlr.requestQueue = &requestQueue{sync.Mutex{}, list.New()}
+			mockCtlr.Agent.postChan = make(chan ResourceConfigRequest, 1)
+			mockCtlr.declHistory = newDeclarationHistory(mockCtlr.kubeClient, "kube-system/cis-checkpoint", 5)
+			mockCtlr.Agent.declHistory = mockCtlr.declHistory
+			mockCtlr.declHistory.store(1, tenantDecl("posted"))
+			// declaration of request 2 failed on BIG-IP
+			mockCtlr.declHistory.store(2, tenantDecl("failed"))
+
+			vs := vrt1.DeepCopy()
+			vs.Annotations = map[string]string{RollbackToAnnotation: "1"}
+			_, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Update(context.TODO(), vs, metav1.UpdateOptions{})
+			mockCtlr.processRollback(vs)
+
+			config := <-mockCtlr.Agent.postChan
+			Expect(config.rollbackTenants["test"]["label"]).To(Equal("posted"),
+				"Declaration of request 1 should be reposted")
+			mockCtlr.Agent.createTenantAS3Declaration(config)
+			Expect(mockCtlr.Agent.incomingTenantDeclMap["test"]["label"]).To(Equal("posted"))
+			tenants, found := mockCtlr.declHistory.get(config.reqId)
+			Expect(found).To(BeTrue(), "Reposted declaration should be retained")
+			Expect(tenants["test"]["label"]).To(Equal("posted"))
+
+			vs, _ = mockCtlr.kubeCRClient.CisV1().VirtualServers(namespace).Get(context.TODO(), vs.Name, metav1.GetOptions{})
+			Expect(vs.Annotations).NotTo(HaveKey(RollbackToAnnotation), "Rollback annotation should be cleared")
+
+			vs.Annotations = map[string]string{RollbackToAnnotation: "10"}
+			mockCtlr.processRollback(vs)
+			Expect(mockCtlr.Agent.postChan).To(BeEmpty(), "Declaration not in history should not be posted")
+		})
+	})
+	Describe("Pool member health score", func() {
+		It("propagates health score to pod condition", func() {
+			pod := test.NewPod("pod1", namespace, 8080, map[string]string{"app": "test"})
+			_, _ = mockCtlr.kubeClient.CoreV1().Pods(namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+			eps := test.NewEndpoints("svc1", "1", "node1", namespace,
+				[]string{"10.244.1.2"}, nil, []v1.EndpointPort{{Name: "port0", Port: 8080}})
+			eps.Subsets[0].Addresses[0].TargetRef = &v1.ObjectReference{Kind: "Pod", Name: "pod1", Namespace: namespace}
+			_ = mockCtlr.comInformers[namespace].epsInformer.GetIndexer().Add(eps)
+
+			Expect(mockCtlr.getPodForMemberIP(namespace, "10.244.1.9")).To(BeNil(), "Unknown member IP")
+			memberPod := mockCtlr.getPodForMemberIP(namespace, "10.244.1.2")
+			Expect(memberPod).NotTo(BeNil(), "Pod should be found for member IP")
+			Expect(memberPod.Name).To(Equal("pod1"))
+
+			Expect(mockCtlr.propagateHealthScore(memberPod, 75)).To(BeNil())
+			updatedPod, _ := mockCtlr.kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), "pod1", metav1.GetOptions{})
+			var found bool
+			for _, cond := range updatedPod.Status.Conditions {
+				if cond.Type == BigIPMemberHealthCondition {
+					found = true
+					Expect(cond.Status).To(Equal(v1.ConditionTrue))
+					Expect(cond.Message).To(Equal("75"))
+				}
+			}
+			Expect(found).To(BeTrue(), "Health condition should be set on pod")
+		})
+
+		It("propagates BIG-IP pool member states to pod conditions", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/mgmt/tm/ltm/pool"))
+				Expect(r.URL.Query().Get("$filter")).To(Equal("partition eq test"))
+				_, _ = w.Write([]byte(`{"items": [{"fullPath": "/test/Shared/pool1", "membersReference": {"items": [
+					{"address": "10.244.1.2%0", "state": "up"},
+					{"address": "10.244.1.3%0", "state": "down"}]}}]}`))
+			}))
+			defer server.Close()
+			mockCtlr.Agent = newMockAgent(nil)
+			mockCtlr.Agent.PostManager = &PostManager{PostParams: PostParams{BIGIPURL: server.URL}}
+			_ = mockCtlr.Agent.PostManager.setupBIGIPRESTClient()
+
+			var members []nodeStateMember
+			for i, ip := range []string{"10.244.1.2", "10.244.1.3"} {
+				podName := fmt.Sprintf("pod%d", i+1)
+				pod := test.NewPod(podName, namespace, 8080, map[string]string{"app": "test"})
+				_, _ = mockCtlr.kubeClient.CoreV1().Pods(namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+				eps := test.NewEndpoints("svc"+podName, "1", "node1", namespace,
+					[]string{ip}, nil, []v1.EndpointPort{{Name: "port0", Port: 8080}})
+				eps.Subsets[0].Addresses[0].TargetRef = &v1.ObjectReference{Kind: "Pod", Name: podName, Namespace: namespace}
+				_ = mockCtlr.comInformers[namespace].epsInformer.GetIndexer().Add(eps)
+				members = append(members, nodeStateMember{pool: "/test/Shared/pool1", namespace: namespace, address: ip})
+			}
+			mockCtlr.propagateBigIPNodeStates(map[string][]nodeStateMember{"test": members})
+
+			for podName, score := range map[string]string{"pod1": "100", "pod2": "0"} {
+				pod, _ := mockCtlr.kubeClient.CoreV1().Pods(namespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				var message string
+				for _, cond := range pod.Status.Conditions {
+					if cond.Type == BigIPMemberHealthCondition {
+						message = cond.Message
+					}
+				}
+				Expect(message).To(Equal(score), "Health score of %v", podName)
+			}
+		})
+	})
 	Describe("Processing Custom Resources", func() {
 		var mockPM *mockPostManager
 		var policy *cisapiv1.Policy
@@ -1535,7 +2704,7 @@ var _ = Describe("Worker Tests", func() {
 								},
 								Rewrite:     "/bar",
 								Balance:     "fastest-node",
-								ServicePort: 80,
+								ServicePort: intstr.FromInt(80),
 							},
 							{
 								Path:    "/",
@@ -1548,7 +2717,7 @@ var _ = Describe("Worker Tests", func() {
 								},
 								Rewrite:     "/bar1",
 								Balance:     "fastest-node",
-								ServicePort: 81,
+								ServicePort: intstr.FromInt(81),
 							},
 						},
 						RewriteAppRoot:     "/home",
@@ -1661,7 +2830,7 @@ var _ = Describe("Worker Tests", func() {
 
 				// Update service
 				svc.Spec.Ports[0].NodePort = 30002
-				Expect(fetchPortString(intstr.IntOrString{StrVal: strconv.Itoa(int(vs.Spec.Pools[0].ServicePort))})).To(BeEquivalentTo("80"))
+				Expect(fetchPortString(intstr.IntOrString{StrVal: vs.Spec.Pools[0].ServicePort.String()})).To(BeEquivalentTo("80"))
 				mockCtlr.addService(svc)
 				mockCtlr.processResources()
 
@@ -1759,7 +2928,7 @@ var _ = Describe("Worker Tests", func() {
 
 				var key, host string
 
-				ipamCR := mockCtlr.getIPAMCR()
+				ipamCR := mockCtlr.getIPAMCR("")
 				host = "test.com"
 				key = "default/test.com_host"
 
@@ -1838,6 +3007,8 @@ var _ = Describe("Worker Tests", func() {
 				rscUpdateMeta := resourceStatusMeta{
 					0,
 					make(map[string]struct{}),
+					nil,
+					nil,
 				}
 
 				time.Sleep(10 * time.Millisecond)
@@ -1884,7 +3055,7 @@ var _ = Describe("Worker Tests", func() {
 						PolicyName:           "policy",
 						Pool: cisapiv1.Pool{
 							Service:     "svc1",
-							ServicePort: 80,
+							ServicePort: intstr.FromInt(80),
 							Monitor: cisapiv1.Monitor{
 								Type:     "tcp",
 								Timeout:  10,
@@ -1944,9 +3115,19 @@ var _ = Describe("Worker Tests", func() {
 				mockCtlr.processResources()
 				Expect(len(mockCtlr.resources.ltmConfig)).To(Equal(1), "Transport Server not processed")
 
+				// with invalid persistence profile
+				ts.Spec.PersistenceProfile = "source_addr"
+				Expect(mockCtlr.checkValidTransportServer(ts)).To(BeFalse())
+				ts.Spec.PersistenceProfile = "/Common/source_addr"
+				Expect(mockCtlr.checkValidTransportServer(ts)).To(BeTrue())
+				ts.Spec.PersistenceProfile = "source-address"
+				Expect(mockCtlr.checkValidTransportServer(ts)).To(BeTrue())
+
 				rscUpdateMeta := resourceStatusMeta{
 					0,
 					make(map[string]struct{}),
+					nil,
+					nil,
 				}
 
 				mockCtlr.Agent.respChan <- rscUpdateMeta
@@ -2014,7 +3195,7 @@ var _ = Describe("Worker Tests", func() {
 
 				var key string
 
-				ipamCR := mockCtlr.getIPAMCR()
+				ipamCR := mockCtlr.getIPAMCR("")
 				key = "default/SampleTS_ts"
 
 				ipamCR.Spec.HostSpecs = []*ficV1.HostSpec{
@@ -2190,7 +3371,7 @@ var _ = Describe("Worker Tests", func() {
 				var key, host string
 				var status int
 
-				ipamCR := mockCtlr.getIPAMCR()
+				ipamCR := mockCtlr.getIPAMCR("")
 				key = "default/ingresslink1_il"
 
 				ipamCR.Spec.HostSpecs = []*ficV1.HostSpec{
@@ -2682,6 +3863,15 @@ extendedRouteSpec:
 				delete(route1.Annotations, resource.F5ClientSslProfileAnnotation)
 
 				checkCertificateHost(route1.Spec.Host, []byte(route1.Spec.TLS.Certificate), []byte(route1.Spec.TLS.Key))
+				mockCtlr.certValidationTimeout = DefaultCertValidationTimeout
+				Expect(mockCtlr.validateCertificateHost(route1.Spec.Host, []byte(route1.Spec.TLS.Certificate),
+					[]byte(route1.Spec.TLS.Key))).To(BeTrue())
+				Expect(mockCtlr.validateCertificateHost("foo.com", []byte(route1.Spec.TLS.Certificate),
+					[]byte(route1.Spec.TLS.Key))).To(BeFalse())
+				mockCtlr.skipCertHostCheck = true
+				Expect(mockCtlr.validateCertificateHost("foo.com", []byte(route1.Spec.TLS.Certificate),
+					[]byte(route1.Spec.TLS.Key))).To(BeTrue(), "Hostname validation should be skipped")
+				mockCtlr.skipCertHostCheck = false
 
 				mockCtlr.addRoute(route1)
 				mockCtlr.resources.invertedNamespaceLabelMap[routeGroup] = routeGroup
@@ -2850,6 +4040,8 @@ extendedRouteSpec:
 				rscUpdateMeta := resourceStatusMeta{
 					0,
 					make(map[string]struct{}),
+					nil,
+					nil,
 				}
 
 				mockCtlr.routeClientV1.Routes("default").Create(context.TODO(), route1, metav1.CreateOptions{})
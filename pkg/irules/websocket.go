@@ -0,0 +1,39 @@
+/*-
+ * Copyright (c) 2016-2021, F5 Networks, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package irules holds the built-in iRules attached by CIS to the virtuals on BIG-IP
+package irules
+
+// WebSocketUpgradeIRuleName is the name of the iRule handling the WebSocket upgrade
+const WebSocketUpgradeIRuleName = "cis_ws_upgrade_irule"
+
+// WebSocketUpgradeIRule preserves the Connection: Upgrade header of WebSocket requests and
+// disables OneConnect for the upgraded connections, so that they are not reused for other clients
+const WebSocketUpgradeIRule = `
+		when HTTP_REQUEST {
+			if { [string tolower [HTTP::header value Upgrade]] equals "websocket" } {
+				HTTP::header replace Connection "Upgrade"
+				ONECONNECT::reuse disable
+				set ws_upgrade 1
+			} else {
+				unset -nocomplain ws_upgrade
+			}
+		}
+		when HTTP_RESPONSE {
+			if { [info exists ws_upgrade] && [HTTP::status] == 101 } {
+				ONECONNECT::detach disable
+			}
+		}`
@@ -0,0 +1,74 @@
+/*-
+* Copyright (c) 2016-2021, F5 Networks, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	log "github.com/F5Networks/k8s-bigip-ctlr/v2/pkg/vlogger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultMetricsPort is the default port serving the Prometheus metrics
+const DefaultMetricsPort = 8080
+
+var ResourceQueueDepth = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "cis_resourcequeue_depth",
+		Help: "Number of resources waiting in the resource queue of the BigIP k8s CTLR",
+	},
+)
+
+var ResourceProcessingDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "cis_resource_processing_duration_seconds",
+		Help: "Time taken by the BigIP k8s CTLR to process the resources of the resource queue",
+	},
+	[]string{"kind"},
+)
+
+var ResourceProcessingErrors = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "cis_resource_processing_errors_total",
+		Help: "Total count of resources failed to process and retried by the BigIP k8s CTLR",
+	},
+)
+
+var registerOnce sync.Once
+
+// RegisterMetrics registers the resource queue metrics with the default Prometheus registry,
+// they are registered once however many controllers call it
+func RegisterMetrics() {
+	registerOnce.Do(func() {
+		log.Info("[CORE] Registered resource queue Metrics")
+		prometheus.MustRegister(ResourceQueueDepth)
+		prometheus.MustRegister(ResourceProcessingDuration)
+		prometheus.MustRegister(ResourceProcessingErrors)
+	})
+}
+
+// ListenAndServe serves the metrics of the default Prometheus registry at /metrics on the port
+func ListenAndServe(port int) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	log.Infof("[CORE] Serving Prometheus metrics on port %v", port)
+	return http.ListenAndServe(fmt.Sprintf(":%d", port), mux)
+}
@@ -31,6 +31,28 @@ var CurrentErrors = prometheus.NewGaugeVec(
 	[]string{},
 )
 
+var ThrottledPosts = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "cis_post_throttled_total",
+		Help: "Total count of configuration posts dropped by the post rate limit of the BigIP k8s CTLR",
+	},
+)
+
+var ResyncTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "cis_resync_total",
+		Help: "Total count of periodic re-posts of the configuration of all partitions by the BigIP k8s CTLR",
+	},
+)
+
+var BigIPAS3Version = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "cis_bigip_as3_version",
+		Help: "AS3 version installed on the BigIP, detected by the BigIP k8s CTLR",
+	},
+	[]string{"version"},
+)
+
 // further metrics? todo think about
 // RegisterMetrics registers all Prometheus metrics defined above
 func RegisterMetrics() {
@@ -38,4 +60,7 @@ func RegisterMetrics() {
 	prometheus.MustRegister(MonitoredNodes)
 	prometheus.MustRegister(MonitoredServices)
 	prometheus.MustRegister(CurrentErrors)
+	prometheus.MustRegister(ThrottledPosts)
+	prometheus.MustRegister(ResyncTotal)
+	prometheus.MustRegister(BigIPAS3Version)
 }
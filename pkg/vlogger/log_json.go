@@ -0,0 +1,199 @@
+// Copyright (c) 2019-2021, F5 Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// log_json.go:
+//
+//	Provides structured JSON logging through the common interface.
+//	To use, create the logger object with the following syntax:
+//	  NewJSONLogger(logger)
+//	Log messages of a LogContext carry the metadata of the context as well.
+package vlogger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"sync"
+	"time"
+)
+
+type (
+	// LogContext carries the metadata of the resource being processed, which is
+	// added to the log messages of the context by the loggers supporting it.
+	LogContext struct {
+		Kind      string
+		Namespace string
+		Name      string
+	}
+
+	// ContextLogger is implemented by the loggers which add the LogContext to log messages.
+	ContextLogger interface {
+		LogWithContext(level LogLevel, ctx LogContext, msg string)
+	}
+
+	jsonLogger struct {
+		// slLogLevel uses syslog's definitions which have higher priority
+		// levels defined in descending order (0 is highest)
+		slLogLevel syslog.Priority
+		mutex      sync.Mutex
+		// base is closed along with the logger
+		base Logger
+	}
+
+	jsonLogEntry struct {
+		Level     string `json:"level"`
+		Time      string `json:"time"`
+		Message   string `json:"message"`
+		Kind      string `json:"kind,omitempty"`
+		Namespace string `json:"namespace,omitempty"`
+		Name      string `json:"name,omitempty"`
+	}
+)
+
+// NewJSONLogger creates a logger object that prints log messages as JSON to
+// stdout and stderr, which may be redirected by the base logger, e.g. FileLogger.
+func NewJSONLogger(base Logger) *jsonLogger {
+	return &jsonLogger{
+		slLogLevel: syslog.LOG_DEBUG,
+		base:       base,
+	}
+}
+
+func (jl *jsonLogger) log(level LogLevel, ctx LogContext, msg string) {
+	if jl.slLogLevel < logLevelToSyslogLevel[level] {
+		return
+	}
+	entry := jsonLogEntry{
+		Level:     level.String(),
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Message:   msg,
+		Kind:      ctx.Kind,
+		Namespace: ctx.Namespace,
+		Name:      ctx.Name,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"level":"error","message":"unable to marshal log message: %v"}`, err))
+	}
+	// informational messages go to stdout similar to the console logger
+	var out io.Writer = os.Stderr
+	if level == LL_INFO {
+		out = os.Stdout
+	}
+	jl.mutex.Lock()
+	defer jl.mutex.Unlock()
+	fmt.Fprintln(out, string(data))
+}
+
+func (jl *jsonLogger) LogWithContext(level LogLevel, ctx LogContext, msg string) {
+	jl.log(level, ctx, msg)
+}
+
+func (jl *jsonLogger) Debug(msg string) {
+	jl.log(LL_DEBUG, LogContext{}, msg)
+}
+
+func (jl *jsonLogger) Debugf(format string, params ...interface{}) {
+	jl.log(LL_DEBUG, LogContext{}, fmt.Sprintf(format, params...))
+}
+
+func (jl *jsonLogger) Info(msg string) {
+	jl.log(LL_INFO, LogContext{}, msg)
+}
+
+func (jl *jsonLogger) Infof(format string, params ...interface{}) {
+	jl.log(LL_INFO, LogContext{}, fmt.Sprintf(format, params...))
+}
+
+func (jl *jsonLogger) Warning(msg string) {
+	jl.log(LL_WARNING, LogContext{}, msg)
+}
+
+func (jl *jsonLogger) Warningf(format string, params ...interface{}) {
+	jl.log(LL_WARNING, LogContext{}, fmt.Sprintf(format, params...))
+}
+
+func (jl *jsonLogger) Error(msg string) {
+	jl.log(LL_ERROR, LogContext{}, msg)
+}
+
+func (jl *jsonLogger) Errorf(format string, params ...interface{}) {
+	jl.log(LL_ERROR, LogContext{}, fmt.Sprintf(format, params...))
+}
+
+func (jl *jsonLogger) Critical(msg string) {
+	jl.log(LL_CRITICAL, LogContext{}, msg)
+}
+
+func (jl *jsonLogger) Criticalf(format string, params ...interface{}) {
+	jl.log(LL_CRITICAL, LogContext{}, fmt.Sprintf(format, params...))
+}
+
+func (jl *jsonLogger) SetLogLevel(slLogLevel syslog.Priority) {
+	jl.slLogLevel = slLogLevel
+}
+
+func (jl *jsonLogger) GetLogLevel() syslog.Priority {
+	return jl.slLogLevel
+}
+
+func (jl *jsonLogger) Close() {
+	if jl.base != nil {
+		jl.base.Close()
+	}
+}
+
+// logf sends the message to the logger of the level, along with the context
+// when the logger supports it
+func (lc LogContext) logf(level LogLevel, format string, params ...interface{}) {
+	logger := vlog[level]
+	if cl, ok := logger.(ContextLogger); ok {
+		cl.LogWithContext(level, lc, fmt.Sprintf(format, params...))
+		return
+	}
+	switch level {
+	case LL_DEBUG:
+		logger.Debugf(format, params...)
+	case LL_INFO:
+		logger.Infof(format, params...)
+	case LL_WARNING:
+		logger.Warningf(format, params...)
+	case LL_ERROR:
+		logger.Errorf(format, params...)
+	default:
+		logger.Criticalf(format, params...)
+	}
+}
+
+// Debugf records debug/trace level statements of the context
+func (lc LogContext) Debugf(format string, params ...interface{}) {
+	lc.logf(LL_DEBUG, format, params...)
+}
+
+// Infof records informational level statements of the context
+func (lc LogContext) Infof(format string, params ...interface{}) {
+	lc.logf(LL_INFO, format, params...)
+}
+
+// Warningf records warning level statements of the context
+func (lc LogContext) Warningf(format string, params ...interface{}) {
+	lc.logf(LL_WARNING, format, params...)
+}
+
+// Errorf records error level statements of the context
+func (lc LogContext) Errorf(format string, params ...interface{}) {
+	lc.logf(LL_ERROR, format, params...)
+}